@@ -0,0 +1,402 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// Kinds of archive records.
+const (
+	ArchiveMessage ArchiveKind = "message"
+	ArchiveEcho    ArchiveKind = "echo"
+	ArchiveStatus  ArchiveKind = "status"
+)
+
+// Directions of archive records.
+const (
+	ArchiveInbound  ArchiveDirection = "inbound"
+	ArchiveOutbound ArchiveDirection = "outbound"
+)
+
+// ErrArchiveNotPurgeable is returned when the records of an Archiver are evicted or
+// forgotten and its sink is not an ArchiveStore.
+var ErrArchiveNotPurgeable = errors.New("archive sink does not support deletes")
+
+type (
+	// ArchiveKind is the kind of event an ArchiveRecord describes: an incoming message, an
+	// echo of a message sent by the client or a status of a sent message.
+	ArchiveKind string
+
+	// ArchiveDirection tells whether the message of an ArchiveRecord was sent by the customer
+	// or by the business.
+	ArchiveDirection string
+
+	// ArchiveRecord is a normalized entry of the conversation log. Every record carries the
+	// phone number ID of the business and the WhatsApp ID of the customer, which together
+	// identify the conversation.
+	//
+	// Text is the text of the message when it has one: the body of text messages, the caption
+	// of media, the title of interactive and button replies. Raw is the message or status as
+	// received from the webhook, set when ArchiverConfig.IncludeRaw is set.
+	ArchiveRecord struct {
+		Kind          ArchiveKind      `json:"kind"`
+		Direction     ArchiveDirection `json:"direction"`
+		PhoneNumberID string           `json:"phone_number_id,omitempty"`
+		Customer      string           `json:"customer"`
+		MessageID     string           `json:"message_id,omitempty"`
+		ReplyTo       string           `json:"reply_to,omitempty"`
+		Type          string           `json:"type,omitempty"`
+		Text          string           `json:"text,omitempty"`
+		MediaID       string           `json:"media_id,omitempty"`
+		TemplateName  string           `json:"template_name,omitempty"`
+		Status        string           `json:"status,omitempty"`
+		Errors        []string         `json:"errors,omitempty"`
+		Timestamp     time.Time        `json:"timestamp"`
+		Raw           json.RawMessage  `json:"raw,omitempty"`
+	}
+
+	// ArchiveSink stores the archive records, for example in a file, an object store or a
+	// columnar format for analytics. Implementations must be safe for concurrent use.
+	ArchiveSink interface {
+		Archive(ctx context.Context, record *ArchiveRecord) error
+	}
+
+	// ArchiveStore is an ArchiveSink that can delete the records it holds, which the
+	// Archiver needs to apply a retention period and to forget a customer.
+	ArchiveStore interface {
+		ArchiveSink
+
+		// DeleteBefore deletes the records with a timestamp before t and returns how many
+		// were deleted.
+		DeleteBefore(ctx context.Context, t time.Time) (int, error)
+
+		// DeleteCustomer deletes the records of the customer waID and returns how many were
+		// deleted.
+		DeleteCustomer(ctx context.Context, waID string) (int, error)
+	}
+
+	// ArchiveSinkFunc is a function that implements ArchiveSink.
+	ArchiveSinkFunc func(ctx context.Context, record *ArchiveRecord) error
+
+	// ArchiverConfig configures an Archiver. Sink is required, Clock defaults to the system
+	// clock and is used when an event has no timestamp. OnError is called with the errors of
+	// the sink when archiving an echo, which cannot be returned to the caller of the send.
+	// MaxAge is how long the records are kept, Evict deletes the older ones when the sink is
+	// an ArchiveStore, zero keeps them forever.
+	ArchiverConfig struct {
+		Sink       ArchiveSink
+		Clock      clock.Clock
+		IncludeRaw bool
+		OnError    func(ctx context.Context, err error)
+		MaxAge     time.Duration
+	}
+
+	// Archiver writes the conversations to an ArchiveSink for compliance retention and
+	// analytics. It consumes the incoming messages and statuses from the webhooks and is an
+	// AuditSink that records the messages sent by the client as echoes:
+	//
+	//	archiver := whatsapp.NewArchiver(&whatsapp.ArchiverConfig{Sink: whatsapp.NewJSONLArchiveSink(file)})
+	//	client := whatsapp.NewClient(whatsapp.WithAuditSink(archiver), ...)
+	//	listener.OnMessageReceived(archiver.OnMessageReceived)
+	//	listener.OnMessageStatusChange(archiver.OnMessageStatusChange)
+	Archiver struct {
+		sink       ArchiveSink
+		clock      clock.Clock
+		includeRaw bool
+		onError    func(ctx context.Context, err error)
+		maxAge     time.Duration
+	}
+
+	// JSONLArchiveSink writes the records to an io.Writer as JSON Lines, one record per line.
+	JSONLArchiveSink struct {
+		mu      sync.Mutex
+		encoder *json.Encoder
+	}
+
+	// MemoryArchiveStore is an in-memory ArchiveStore that keeps the records in the order
+	// they were archived.
+	MemoryArchiveStore struct {
+		mu      sync.Mutex
+		records []*ArchiveRecord
+	}
+)
+
+// Archive calls f(ctx, record).
+func (f ArchiveSinkFunc) Archive(ctx context.Context, record *ArchiveRecord) error {
+	return f(ctx, record)
+}
+
+// NewJSONLArchiveSink returns a JSONLArchiveSink writing to w. Writes to w are serialized.
+func NewJSONLArchiveSink(w io.Writer) *JSONLArchiveSink {
+	return &JSONLArchiveSink{encoder: json.NewEncoder(w)}
+}
+
+func (sink *JSONLArchiveSink) Archive(_ context.Context, record *ArchiveRecord) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if err := sink.encoder.Encode(record); err != nil {
+		return fmt.Errorf("jsonl archive: %w", err)
+	}
+
+	return nil
+}
+
+// NewMemoryArchiveStore returns an empty MemoryArchiveStore.
+func NewMemoryArchiveStore() *MemoryArchiveStore {
+	return &MemoryArchiveStore{}
+}
+
+func (store *MemoryArchiveStore) Archive(_ context.Context, record *ArchiveRecord) error {
+	stored := *record
+	store.mu.Lock()
+	store.records = append(store.records, &stored)
+	store.mu.Unlock()
+
+	return nil
+}
+
+// Records returns the records of the customer waID, or all the records when waID is empty.
+func (store *MemoryArchiveStore) Records(waID string) []*ArchiveRecord {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	var records []*ArchiveRecord
+	for _, record := range store.records {
+		if waID == "" || record.Customer == waID {
+			clone := *record
+			records = append(records, &clone)
+		}
+	}
+
+	return records
+}
+
+func (store *MemoryArchiveStore) DeleteBefore(_ context.Context, t time.Time) (int, error) {
+	return store.deleteWhere(func(record *ArchiveRecord) bool { return record.Timestamp.Before(t) }), nil
+}
+
+func (store *MemoryArchiveStore) DeleteCustomer(_ context.Context, waID string) (int, error) {
+	return store.deleteWhere(func(record *ArchiveRecord) bool { return record.Customer == waID }), nil
+}
+
+func (store *MemoryArchiveStore) deleteWhere(match func(record *ArchiveRecord) bool) int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	kept := store.records[:0]
+	for _, record := range store.records {
+		if !match(record) {
+			kept = append(kept, record)
+		}
+	}
+	deleted := len(store.records) - len(kept)
+	for i := len(kept); i < len(store.records); i++ {
+		store.records[i] = nil
+	}
+	store.records = kept
+
+	return deleted
+}
+
+// NewArchiver returns an Archiver configured with config.
+func NewArchiver(config *ArchiverConfig) *Archiver {
+	return &Archiver{
+		sink:       config.Sink,
+		clock:      clock.OrSystem(config.Clock),
+		includeRaw: config.IncludeRaw,
+		onError:    config.OnError,
+		maxAge:     config.MaxAge,
+	}
+}
+
+// Evict deletes the records older than the configured MaxAge and returns how many were
+// deleted. It does nothing when MaxAge is zero and fails with ErrArchiveNotPurgeable when
+// the sink is not an ArchiveStore.
+func (archiver *Archiver) Evict(ctx context.Context) (int, error) {
+	if archiver.maxAge <= 0 {
+		return 0, nil
+	}
+	store, ok := archiver.sink.(ArchiveStore)
+	if !ok {
+		return 0, fmt.Errorf("archiver: %w", ErrArchiveNotPurgeable)
+	}
+	deleted, err := store.DeleteBefore(ctx, archiver.clock.Now().Add(-archiver.maxAge))
+	if err != nil {
+		return deleted, fmt.Errorf("archiver: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Forget deletes the records of the customer waID and returns how many were deleted. It
+// fails with ErrArchiveNotPurgeable when the sink is not an ArchiveStore.
+func (archiver *Archiver) Forget(ctx context.Context, waID string) (int, error) {
+	store, ok := archiver.sink.(ArchiveStore)
+	if !ok {
+		return 0, fmt.Errorf("archiver: %w", ErrArchiveNotPurgeable)
+	}
+	deleted, err := store.DeleteCustomer(ctx, strings.TrimPrefix(waID, "+"))
+	if err != nil {
+		return deleted, fmt.Errorf("archiver: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Audit implements AuditSink, it archives the messages sent by the client as echoes. Failed
+// sends are archived with their error.
+func (archiver *Archiver) Audit(ctx context.Context, record AuditRecord) {
+	archived := &ArchiveRecord{
+		Kind:          ArchiveEcho,
+		Direction:     ArchiveOutbound,
+		PhoneNumberID: record.Sender,
+		Customer:      strings.TrimPrefix(record.Recipient, "+"),
+		MessageID:     record.MessageID,
+		Type:          record.Type,
+		TemplateName:  record.TemplateName,
+		Timestamp:     record.Timestamp,
+	}
+	if record.Err != nil {
+		archived.Errors = []string{record.Err.Error()}
+	}
+	if err := archiver.sink.Archive(ctx, archived); err != nil && archiver.onError != nil {
+		archiver.onError(ctx, err)
+	}
+}
+
+// OnMessageReceived is a webhooks.OnMessageReceivedHook that archives the incoming messages.
+func (archiver *Archiver) OnMessageReceived(ctx context.Context, nctx *webhooks.NotificationContext,
+	message *webhooks.Message,
+) error {
+	record := &ArchiveRecord{
+		Kind:          ArchiveMessage,
+		Direction:     ArchiveInbound,
+		PhoneNumberID: phoneNumberIDOf(nctx),
+		Customer:      message.From,
+		MessageID:     message.ID,
+		Type:          message.Type,
+		Timestamp:     archiver.timestamp(message.Timestamp),
+	}
+	switch {
+	case message.Context != nil:
+		record.ReplyTo = message.Context.ID
+	case message.Reaction != nil:
+		record.ReplyTo = message.Reaction.MessageID
+	}
+	record.Text, record.MediaID = messageContent(message)
+	for _, e := range message.Errors {
+		record.Errors = append(record.Errors, e.Error())
+	}
+	if archiver.includeRaw {
+		record.Raw, _ = json.Marshal(message) //nolint:errchkjson // decoded from JSON
+	}
+
+	return archiver.archive(ctx, record)
+}
+
+// OnMessageStatusChange is a webhooks.OnMessageStatusChangeHook that archives the statuses
+// of the messages sent by the business.
+func (archiver *Archiver) OnMessageStatusChange(ctx context.Context, nctx *webhooks.NotificationContext,
+	status *webhooks.Status,
+) error {
+	record := &ArchiveRecord{
+		Kind:          ArchiveStatus,
+		Direction:     ArchiveOutbound,
+		PhoneNumberID: phoneNumberIDOf(nctx),
+		Customer:      status.RecipientID,
+		MessageID:     status.ID,
+		Type:          status.Type,
+		Status:        status.StatusValue,
+		Timestamp:     archiver.timestamp(strconv.Itoa(status.Timestamp)),
+	}
+	for _, e := range status.Errors {
+		record.Errors = append(record.Errors, e.Error())
+	}
+	if archiver.includeRaw {
+		record.Raw, _ = json.Marshal(status) //nolint:errchkjson // decoded from JSON
+	}
+
+	return archiver.archive(ctx, record)
+}
+
+func (archiver *Archiver) archive(ctx context.Context, record *ArchiveRecord) error {
+	if err := archiver.sink.Archive(ctx, record); err != nil {
+		return fmt.Errorf("archiver: %w", err)
+	}
+
+	return nil
+}
+
+// timestamp parses the unix timestamp of a webhook, falling back to the current time.
+func (archiver *Archiver) timestamp(unix string) time.Time {
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || seconds <= 0 {
+		return archiver.clock.Now().UTC()
+	}
+
+	return time.Unix(seconds, 0).UTC()
+}
+
+func phoneNumberIDOf(nctx *webhooks.NotificationContext) string {
+	if nctx == nil || nctx.Metadata == nil {
+		return ""
+	}
+
+	return nctx.Metadata.PhoneNumberID
+}
+
+// messageContent returns the text and the media ID of an incoming message.
+func messageContent(message *webhooks.Message) (string, string) {
+	switch {
+	case message.Text != nil:
+		return message.Text.Body, ""
+	case message.Button != nil:
+		return message.Button.Text, ""
+	case message.Interactive != nil:
+		switch {
+		case message.Interactive.ButtonReply != nil:
+			return message.Interactive.ButtonReply.Title, ""
+		case message.Interactive.ListReply != nil:
+			return message.Interactive.ListReply.Title, ""
+		}
+	case message.Reaction != nil:
+		return message.Reaction.Emoji, ""
+	}
+	for _, media := range []*models.MediaInfo{
+		message.Image, message.Video, message.Document, message.Audio, message.Sticker,
+	} {
+		if media != nil {
+			return media.Caption, media.ID
+		}
+	}
+
+	return "", ""
+}
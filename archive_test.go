@@ -0,0 +1,113 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestArchiver(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.OUT"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	var (
+		buf     bytes.Buffer
+		audited int
+	)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	archiver := NewArchiver(&ArchiverConfig{
+		Sink:       NewJSONLArchiveSink(&buf),
+		Clock:      clock.NewFake(now),
+		IncludeRaw: true,
+	})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithClock(clock.NewFake(now)),
+		WithAuditSink(MultiAuditSink(archiver, AuditSinkFunc(func(context.Context, AuditRecord) { audited++ }))))
+	ctx := context.Background()
+
+	if _, err := client.SendTemplate(ctx, "+255700000001", &Template{Name: "welcome", LanguageCode: "en"}); err != nil {
+		t.Fatalf("SendTemplate() error = %v", err)
+	}
+	if audited != 1 {
+		t.Errorf("MultiAuditSink passed %d records to the second sink, want 1", audited)
+	}
+
+	body := `{"object":"whatsapp_business_account","entry":[{"id":"WABA_ID","changes":[{"value":{` +
+		`"messaging_product":"whatsapp","metadata":{"display_phone_number":"PHONE_NUMBER",` +
+		`"phone_number_id":"phone_1"},"messages":[{"context":{"from":"PHONE_NUMBER","id":"wamid.OUT"},` +
+		`"from":"255700000001","id":"wamid.IN","timestamp":"1682942460","type":"image",` +
+		`"image":{"id":"media_1","caption":"my receipt","mime_type":"image/jpeg"}}],` +
+		`"statuses":[{"id":"wamid.OUT","recipient_id":"255700000001","status":"read","timestamp":1682942470}]},` +
+		`"field":"messages"}]}]}`
+	hooks := &webhooks.Hooks{
+		OnMessageReceivedHook:     archiver.OnMessageReceived,
+		OnMessageStatusChangeHook: archiver.OnMessageStatusChange,
+		OnMediaMessageHook: func(context.Context, *webhooks.NotificationContext, *webhooks.MessageContext,
+			*models.MediaInfo,
+		) error {
+			return nil
+		},
+	}
+	h := webhooks.NotificationHandler(hooks, webhooks.NoOpNotificationErrorHandler,
+		webhooks.NoOpHooksErrorHandler, &webhooks.HandlerOptions{})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+
+	var records []*ArchiveRecord
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record ArchiveRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q is not a record: %v", scanner.Text(), err)
+		}
+		records = append(records, &record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("archived %d records, want 3", len(records))
+	}
+
+	echo, status, message := records[0], records[1], records[2]
+	if echo.Kind != ArchiveEcho || echo.Direction != ArchiveOutbound || echo.Customer != "255700000001" ||
+		echo.MessageID != "wamid.OUT" || echo.TemplateName != "welcome" || !echo.Timestamp.Equal(now) {
+		t.Errorf("echo = %+v", echo)
+	}
+	if status.Kind != ArchiveStatus || status.Status != "read" || status.PhoneNumberID != "phone_1" ||
+		!status.Timestamp.Equal(time.Unix(1682942470, 0)) || len(status.Raw) == 0 {
+		t.Errorf("status = %+v", status)
+	}
+	if message.Kind != ArchiveMessage || message.Direction != ArchiveInbound || message.Customer != "255700000001" ||
+		message.ReplyTo != "wamid.OUT" || message.Text != "my receipt" || message.MediaID != "media_1" ||
+		message.Type != "image" || !message.Timestamp.Equal(time.Unix(1682942460, 0)) {
+		t.Errorf("message = %+v", message)
+	}
+}
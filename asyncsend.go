@@ -0,0 +1,345 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+const (
+	// DefaultAsyncStatusTTL is how long the handles of the messages sent asynchronously keep
+	// receiving their status updates.
+	DefaultAsyncStatusTTL = 24 * time.Hour
+
+	// DefaultAsyncEarlyStatusTTL is how long a status received before the handle of its
+	// message is resolved is kept for the handle.
+	DefaultAsyncEarlyStatusTTL = time.Minute
+)
+
+type (
+	// AsyncSenderConfig configures an AsyncSender. Pool configures the SenderPool the
+	// messages are sent through, set RejectWhenFull for SendAsync to fail with
+	// ErrPoolSaturated instead of blocking when the queue is full. StatusTTL is how long
+	// the handles are updated from the status webhooks after the message was accepted,
+	// DefaultAsyncStatusTTL when zero. EarlyStatusTTL is how long a status that arrives
+	// before the send returned, and its handle is known, is kept for the handle,
+	// DefaultAsyncEarlyStatusTTL when zero.
+	AsyncSenderConfig struct {
+		Pool           *SenderPoolConfig
+		StatusTTL      time.Duration
+		EarlyStatusTTL time.Duration
+		Clock          clock.Clock
+	}
+
+	// AsyncSender sends messages in the background through a bounded SenderPool. SendAsync
+	// returns a SendHandle that resolves once the message is accepted by the API, the
+	// handle is then updated by the status webhooks of the message:
+	//
+	//	async := whatsapp.NewAsyncSender(client, &whatsapp.AsyncSenderConfig{
+	//		Pool: &whatsapp.SenderPoolConfig{Workers: 32, QueueSize: 1000, RejectWhenFull: true},
+	//	})
+	//	defer async.Close()
+	//	listener.OnMessageStatusChange(async.OnMessageStatusChange)
+	//
+	//	handle, err := async.SendAsync(ctx, message)
+	//	response, err := handle.Wait(ctx)
+	AsyncSender struct {
+		client         *Client
+		pool           *SenderPool
+		clock          clock.Clock
+		ttl            time.Duration
+		earlyTTL       time.Duration
+		mu             sync.Mutex
+		handles        map[string]*SendHandle
+		early          map[string]*earlyStatus
+		nextSweep      time.Time
+		nextEarlySweep time.Time
+	}
+
+	// earlyStatus is the latest status of a message whose handle is not resolved yet.
+	earlyStatus struct {
+		state      DeliveryState
+		errors     []*werrors.Error
+		receivedAt time.Time
+	}
+
+	// SendHandle is the future of a message sent with AsyncSender.SendAsync.
+	SendHandle struct {
+		accepted   chan struct{}
+		response   *ResponseMessage
+		err        error
+		mu         sync.Mutex
+		state      DeliveryState
+		errors     []*werrors.Error
+		changed    chan struct{}
+		acceptedAt time.Time
+	}
+)
+
+// NewAsyncSender returns an AsyncSender sending the messages with client, config may be nil.
+func NewAsyncSender(client *Client, config *AsyncSenderConfig) *AsyncSender {
+	if config == nil {
+		config = &AsyncSenderConfig{}
+	}
+	sender := &AsyncSender{
+		client:   client,
+		pool:     NewSenderPool(config.Pool),
+		clock:    clock.OrSystem(config.Clock),
+		ttl:      config.StatusTTL,
+		earlyTTL: config.EarlyStatusTTL,
+		handles:  make(map[string]*SendHandle),
+		early:    make(map[string]*earlyStatus),
+	}
+	if sender.ttl <= 0 {
+		sender.ttl = DefaultAsyncStatusTTL
+	}
+	if sender.earlyTTL <= 0 {
+		sender.earlyTTL = DefaultAsyncEarlyStatusTTL
+	}
+
+	return sender
+}
+
+// SendAsync queues message and returns its handle, see Client.SendMessage. It blocks while
+// the queue of the pool is full, until ctx is done, or fails with ErrPoolSaturated when the
+// pool rejects the sends when full. ctx is used to send the message, it must outlive the
+// call, for example not be the context of an HTTP request that returns right away.
+func (sender *AsyncSender) SendAsync(ctx context.Context, message *models.Message, options ...SendOption,
+) (*SendHandle, error) {
+	numberCtx := ctx
+	if opts := newSendOptions(options); opts != nil && opts.from != "" {
+		numberCtx = WithOverrides(ctx, &Overrides{PhoneNumberID: opts.from})
+	}
+	results, err := sender.pool.Submit(ctx, sender.client.context(numberCtx).phoneNumberID,
+		func(ctx context.Context) (*ResponseMessage, error) {
+			return sender.client.SendMessage(ctx, message, options...)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	handle := &SendHandle{accepted: make(chan struct{}), changed: make(chan struct{})}
+	go func() {
+		result := <-results
+		sender.resolve(handle, result)
+	}()
+
+	return handle, nil
+}
+
+// OnMessageStatusChange is a webhooks.OnMessageStatusChangeHook that updates the handles of
+// the messages the statuses are about. A handle stops being updated once its message is read
+// or failed, or after the status TTL of the sender. The status of a message that is not known
+// yet, because the webhook arrived before the send returned, is applied to its handle when it
+// resolves within the early status TTL.
+func (sender *AsyncSender) OnMessageStatusChange(_ context.Context, _ *webhooks.NotificationContext,
+	status *webhooks.Status,
+) error {
+	if status == nil || status.ID == "" || status.IsPayment() {
+		return nil
+	}
+	state := DeliveryState(status.StatusValue)
+	if _, ok := deliveryRanks[state]; !ok {
+		return nil
+	}
+
+	sender.mu.Lock()
+	handle, ok := sender.handles[status.ID]
+	if ok && (state == DeliveryRead || state == DeliveryFailed) {
+		delete(sender.handles, status.ID)
+	}
+	if !ok {
+		sender.keepEarly(status.ID, state, status.Errors)
+	}
+	sender.mu.Unlock()
+	if ok {
+		handle.update(state, status.Errors)
+	}
+
+	return nil
+}
+
+// Stats returns the number of queued sends and of sends in flight of the pool.
+func (sender *AsyncSender) Stats() SenderPoolStats {
+	return sender.pool.Stats()
+}
+
+// Close stops accepting sends and waits for the queued ones to finish.
+func (sender *AsyncSender) Close() {
+	sender.pool.Close()
+}
+
+// Shutdown stops accepting sends and waits for the queued ones to finish, until ctx is done,
+// see SenderPool.Shutdown. A SendAsync blocked on the full queue fails with ErrPoolClosed and
+// does not delay the shutdown.
+func (sender *AsyncSender) Shutdown(ctx context.Context) (int, error) {
+	return sender.pool.Shutdown(ctx)
+}
+
+// resolve completes handle with result and tracks its message for the status updates.
+func (sender *AsyncSender) resolve(handle *SendHandle, result SendResult) {
+	now := sender.clock.Now()
+	handle.response, handle.err = result.Response, result.Err
+	if result.Err == nil {
+		handle.mu.Lock()
+		handle.state, handle.acceptedAt = DeliveryAccepted, now
+		handle.mu.Unlock()
+	}
+	var early *earlyStatus
+	if id := handle.messageID(); id != "" {
+		sender.mu.Lock()
+		sender.sweep(now)
+		early = sender.early[id]
+		delete(sender.early, id)
+		if early != nil && now.Sub(early.receivedAt) > sender.earlyTTL {
+			early = nil
+		}
+		if early == nil || (early.state != DeliveryRead && early.state != DeliveryFailed) {
+			sender.handles[id] = handle
+		}
+		sender.mu.Unlock()
+	}
+	if early != nil {
+		handle.update(early.state, early.errors)
+	}
+	close(handle.accepted)
+}
+
+// keepEarly keeps the status of a message whose handle is not resolved yet, statuses received
+// out of order never move it back. It must be called with the lock held.
+func (sender *AsyncSender) keepEarly(id string, state DeliveryState, errs []*werrors.Error) {
+	now := sender.clock.Now()
+	sender.sweepEarly(now)
+	if early, ok := sender.early[id]; ok && deliveryRanks[state] <= deliveryRanks[early.state] {
+		return
+	}
+	sender.early[id] = &earlyStatus{state: state, errors: errs, receivedAt: now}
+}
+
+// sweep drops the handles accepted longer than the status TTL ago. It runs at most four
+// times per TTL, so that the handles are dropped at most a quarter of the TTL late.
+func (sender *AsyncSender) sweep(now time.Time) {
+	if now.Before(sender.nextSweep) {
+		return
+	}
+	sender.nextSweep = now.Add(sender.ttl / 4) //nolint:gomnd
+	for id, handle := range sender.handles {
+		if now.Sub(handle.acceptedAt) > sender.ttl {
+			delete(sender.handles, id)
+		}
+	}
+}
+
+// sweepEarly drops the early statuses received longer than the early status TTL ago, most of
+// them are about messages not sent by this sender. Like sweep it runs at most four times per
+// TTL.
+func (sender *AsyncSender) sweepEarly(now time.Time) {
+	if now.Before(sender.nextEarlySweep) {
+		return
+	}
+	sender.nextEarlySweep = now.Add(sender.earlyTTL / 4) //nolint:gomnd
+	for id, early := range sender.early {
+		if now.Sub(early.receivedAt) > sender.earlyTTL {
+			delete(sender.early, id)
+		}
+	}
+}
+
+// Accepted returns a channel that is closed once the message is accepted by the API or
+// failed to be sent.
+func (handle *SendHandle) Accepted() <-chan struct{} {
+	return handle.accepted
+}
+
+// Wait waits until the message is accepted by the API, or until ctx is done, and returns the
+// response of the send.
+func (handle *SendHandle) Wait(ctx context.Context) (*ResponseMessage, error) {
+	select {
+	case <-handle.accepted:
+		return handle.response, handle.err
+	case <-ctx.Done():
+		return nil, ctx.Err() //nolint:wrapcheck
+	}
+}
+
+// MessageID returns the wamid of the message, empty until it is accepted.
+func (handle *SendHandle) MessageID() string {
+	select {
+	case <-handle.accepted:
+		return handle.messageID()
+	default:
+		return ""
+	}
+}
+
+func (handle *SendHandle) messageID() string {
+	if handle.response == nil || len(handle.response.Messages) == 0 || handle.response.Messages[0] == nil {
+		return ""
+	}
+
+	return handle.response.Messages[0].ID
+}
+
+// State returns the delivery state of the message, empty until it is accepted and when the
+// send failed.
+func (handle *SendHandle) State() DeliveryState {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	return handle.state
+}
+
+// Errors returns the errors of the status webhook that reported the message failed.
+func (handle *SendHandle) Errors() []*werrors.Error {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	return handle.errors
+}
+
+// Changed returns a channel that is closed at the next change of the state of the message.
+func (handle *SendHandle) Changed() <-chan struct{} {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+
+	return handle.changed
+}
+
+// update moves the message to state, statuses received out of order never move it back.
+func (handle *SendHandle) update(state DeliveryState, errs []*werrors.Error) {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+	if deliveryRanks[state] <= deliveryRanks[handle.state] {
+		return
+	}
+	handle.state = state
+	if state == DeliveryFailed {
+		handle.errors = errs
+	}
+	close(handle.changed)
+	handle.changed = make(chan struct{})
+}
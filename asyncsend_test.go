@@ -0,0 +1,174 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestAsyncSender(t *testing.T) {
+	t.Parallel()
+	var sent int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = fmt.Fprintf(w, `{"messaging_product":"whatsapp","messages":[{"id":"wamid.%d"}]}`,
+			atomic.AddInt32(&sent, 1))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"))
+	async := NewAsyncSender(client, &AsyncSenderConfig{
+		Pool: &SenderPoolConfig{Workers: 1, QueueSize: 1, RejectWhenFull: true},
+	})
+	t.Cleanup(async.Close)
+
+	ctx := context.Background()
+	message := func() *models.Message {
+		return &models.Message{To: "255700000000", Type: "text", Text: &models.Text{Body: "hi"}}
+	}
+
+	first, err := async.SendAsync(ctx, message())
+	if err != nil {
+		t.Fatalf("SendAsync() error = %v", err)
+	}
+	// wait for the first send to reach the worker, the second one then fills the queue
+	for async.Stats().InFlight != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	second, err := async.SendAsync(ctx, message())
+	if err != nil {
+		t.Fatalf("SendAsync() error = %v", err)
+	}
+	if _, err := async.SendAsync(ctx, message()); !errors.Is(err, ErrPoolSaturated) {
+		t.Fatalf("SendAsync() on a full queue error = %v, want %v", err, ErrPoolSaturated)
+	}
+	if id := first.MessageID(); id != "" || first.State() != "" {
+		t.Errorf("pending handle = %q, %q, want no message ID nor state", id, first.State())
+	}
+
+	close(release)
+	for i, handle := range []*SendHandle{first, second} {
+		response, err := handle.Wait(ctx)
+		if err != nil || len(response.Messages) != 1 {
+			t.Fatalf("Wait() = %+v, %v", response, err)
+		}
+		if want := fmt.Sprintf("wamid.%d", i+1); handle.MessageID() != want || handle.State() != DeliveryAccepted {
+			t.Errorf("handle = %q, %q, want %q accepted", handle.MessageID(), handle.State(), want)
+		}
+	}
+
+	status := func(id, value string, errs ...*werrors.Error) {
+		t.Helper()
+		err := async.OnMessageStatusChange(ctx, nil, &webhooks.Status{ID: id, StatusValue: value, Errors: errs})
+		if err != nil {
+			t.Fatalf("OnMessageStatusChange() error = %v", err)
+		}
+	}
+
+	changed := first.Changed()
+	status("wamid.1", "delivered")
+	select {
+	case <-changed:
+	default:
+		t.Fatal("Changed() not closed by the delivered status")
+	}
+	status("wamid.1", "sent")
+	if first.State() != DeliveryDelivered {
+		t.Errorf("State() = %q, want %q", first.State(), DeliveryDelivered)
+	}
+
+	status("wamid.2", "failed", &werrors.Error{Code: werrors.CodeUndeliverable})
+	if second.State() != DeliveryFailed || len(second.Errors()) != 1 {
+		t.Errorf("failed handle = %q, %v", second.State(), second.Errors())
+	}
+	// failed is terminal, the handle is not tracked anymore
+	status("wamid.2", "read")
+	if second.State() != DeliveryFailed {
+		t.Errorf("State() after a status of an untracked message = %q, want %q", second.State(), DeliveryFailed)
+	}
+}
+
+func TestAsyncSenderEarlyStatus(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		statuses  []string
+		wait      time.Duration
+		wantState DeliveryState
+	}{
+		{name: "status before the response", statuses: []string{"delivered", "sent"}, wantState: DeliveryDelivered},
+		{name: "read before the response", statuses: []string{"read"}, wantState: DeliveryRead},
+		{name: "expired early status", statuses: []string{"delivered"}, wait: 2 * time.Minute, wantState: DeliveryAccepted},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+			var async *AsyncSender
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the webhooks of the message are processed before the send returns
+				for _, value := range tt.statuses {
+					status := &webhooks.Status{ID: "wamid.1", StatusValue: value}
+					_ = async.OnMessageStatusChange(context.TODO(), nil, status)
+				}
+				clk.Advance(tt.wait)
+				_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"))
+			async = NewAsyncSender(client, &AsyncSenderConfig{Clock: clk})
+			defer async.Close()
+
+			handle, err := async.SendAsync(context.TODO(), &models.Message{To: "255700000000", Type: "text"})
+			if err != nil {
+				t.Fatalf("SendAsync() error = %v", err)
+			}
+			if _, err = handle.Wait(context.TODO()); err != nil {
+				t.Fatalf("Wait() error = %v", err)
+			}
+			if state := handle.State(); state != tt.wantState {
+				t.Errorf("State() = %s, want %s", state, tt.wantState)
+			}
+			async.mu.Lock()
+			_, tracked := async.handles["wamid.1"]
+			early := len(async.early)
+			async.mu.Unlock()
+			if tracked == (tt.wantState == DeliveryRead) || early != 0 {
+				t.Errorf("handle tracked = %v with %d early statuses", tracked, early)
+			}
+		})
+	}
+}
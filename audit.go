@@ -0,0 +1,161 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+type (
+	// AuditRecord describes an outbound message. It is passed by value, sinks receive their
+	// own copy that the client never modifies.
+	//
+	// Sender is the phone number ID the message was sent from, Type the message type (text,
+	// template, image etc.) and TemplateName the name of the template for template messages.
+	// MessageID is the wamid returned by the API, it is empty when the send failed, in which
+	// case Err is the error returned to the caller. Initiator is the value set on the context
+	// with WithInitiator, for example the user or service that triggered the send.
+	AuditRecord struct {
+		Request      string
+		Sender       string
+		Recipient    string
+		Type         string
+		TemplateName string
+		MessageID    string
+		Initiator    string
+		Timestamp    time.Time
+		Err          error
+	}
+
+	// AuditSink receives a record of every message the client sends, whether the send
+	// succeeded or not. Audit is called synchronously after the send completes, sinks that
+	// write to slow storage should buffer. A sink cannot fail a send that already happened,
+	// it is responsible for handling its own errors.
+	AuditSink interface {
+		Audit(ctx context.Context, record AuditRecord)
+	}
+
+	// AuditSinkFunc is a function that implements AuditSink.
+	AuditSinkFunc func(ctx context.Context, record AuditRecord)
+
+	initiatorKey struct{}
+
+	// auditPayload holds the fields of a message payload that are recorded.
+	auditPayload struct {
+		To       string `json:"to"`
+		Type     string `json:"type"`
+		Template *struct {
+			Name string `json:"name"`
+		} `json:"template"`
+	}
+)
+
+// Audit calls f(ctx, record).
+func (f AuditSinkFunc) Audit(ctx context.Context, record AuditRecord) {
+	f(ctx, record)
+}
+
+// MultiAuditSink returns an AuditSink that passes the records to every sink, in order.
+func MultiAuditSink(sinks ...AuditSink) AuditSink {
+	return AuditSinkFunc(func(ctx context.Context, record AuditRecord) {
+		for _, sink := range sinks {
+			sink.Audit(ctx, record)
+		}
+	})
+}
+
+// WithInitiator returns a context that records initiator as the initiator of the messages
+// sent with it, see AuditRecord.
+func WithInitiator(ctx context.Context, initiator string) context.Context {
+	return context.WithValue(ctx, initiatorKey{}, initiator)
+}
+
+// InitiatorFromContext returns the initiator set with WithInitiator.
+func InitiatorFromContext(ctx context.Context) (string, bool) {
+	initiator, ok := ctx.Value(initiatorKey{}).(string)
+
+	return initiator, ok && initiator != ""
+}
+
+// WithAuditSink sets the AuditSink that receives a record of every outbound message.
+// Messages answered from the idempotency store are not sent again and are not recorded.
+func WithAuditSink(sink AuditSink) ClientOption {
+	return func(client *Client) {
+		client.audit = sink
+	}
+}
+
+// auditMiddleware records the message sends that go through it to sink.
+func auditMiddleware(sink AuditSink, now func() time.Time) whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			if !isMessageRequest(request) {
+				return next.Send(ctx, request, v)
+			}
+
+			err := next.Send(ctx, request, v)
+
+			record := AuditRecord{
+				Request:   request.Context.Name,
+				Sender:    request.Context.SenderID,
+				Timestamp: now(),
+				Err:       err,
+			}
+			record.Initiator, _ = InitiatorFromContext(ctx)
+			if payload, ok := auditPayloadOf(request); ok {
+				record.Recipient = payload.To
+				record.Type = payload.Type
+				if payload.Template != nil {
+					record.TemplateName = payload.Template.Name
+				}
+			}
+			if message, ok := v.(*ResponseMessage); ok && err == nil &&
+				len(message.Messages) > 0 && message.Messages[0] != nil {
+				record.MessageID = message.Messages[0].ID
+			}
+			sink.Audit(ctx, record)
+
+			return err
+		})
+	}
+}
+
+// auditPayloadOf decodes the recorded fields of the message payload. Streamed payloads are
+// not read, as that would consume them.
+func auditPayloadOf(request *whttp.Request) (*auditPayload, bool) {
+	if _, ok := request.Payload.(io.Reader); ok {
+		return nil, false
+	}
+	body, err := request.BodyBytes()
+	if err != nil {
+		return nil, false
+	}
+	var payload auditPayload
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return nil, false
+	}
+
+	return &payload, true
+}
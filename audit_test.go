@@ -0,0 +1,78 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+func TestAuditSink(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v23.0/blocked/messages" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":131026,"message":"undeliverable"}}`))
+
+			return
+		}
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	var records []AuditRecord
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithPhoneNumberID("phone_1"),
+		WithClock(clock.NewFake(now)),
+		WithAuditSink(AuditSinkFunc(func(ctx context.Context, record AuditRecord) {
+			records = append(records, record)
+		})),
+	)
+
+	ctx := WithInitiator(context.TODO(), "support-agent-7")
+	if _, err := client.SendTemplate(ctx, "255700000001", &Template{Name: "otp", LanguageCode: "en"}); err != nil {
+		t.Fatalf("SendTemplate() error = %v", err)
+	}
+	ctx = WithOverrides(ctx, &Overrides{PhoneNumberID: "blocked"})
+	if _, err := client.SendTextMessage(ctx, "255700000002", &TextMessage{Message: "hi"}); err == nil {
+		t.Fatal("SendTextMessage() error = nil, want an error")
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("records = %+v, want 2 records", records)
+	}
+	template := records[0]
+	if template.Type != "template" || template.TemplateName != "otp" || template.Recipient != "255700000001" ||
+		template.MessageID != "wamid.1" || template.Sender != "phone_1" || template.Initiator != "support-agent-7" ||
+		!template.Timestamp.Equal(now) || template.Err != nil {
+		t.Errorf("template record = %+v", template)
+	}
+	text := records[1]
+	if text.Type != "text" || text.Recipient != "255700000002" || text.MessageID != "" || text.Err == nil {
+		t.Errorf("text record = %+v", text)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// BaseURLs routes some of the requests to other base URLs than the one set by WithBaseURL,
+// for example to send the media uploads through a dedicated proxy, to use a regional
+// endpoint for the messages or to point part of the API at a mock server:
+//
+//	whatsapp.WithBaseURLs(&whatsapp.BaseURLs{
+//		Endpoints:     map[string]string{"media": "https://media-proxy.internal/graph/"},
+//		Requests:      map[string]string{"send message": "http://localhost:8080/"},
+//		MediaDownload: "http://localhost:8080/cdn/",
+//	})
+//
+// Requests maps the request names, like "send message" or "upload media", to a base URL,
+// and Endpoints maps the path segments that follow the API version, like "messages",
+// "media" or "message_templates". Requests take precedence over Endpoints. MediaDownload,
+// when set, replaces the scheme, host and leading path of the media URLs downloaded by
+// DownloadMedia, which are served by a CDN rather than the Graph API.
+//
+// A base URL set on the context with WithOverrides applies to all the requests made with
+// that context, regardless of these rules.
+type BaseURLs struct {
+	Requests      map[string]string
+	Endpoints     map[string]string
+	MediaDownload string
+}
+
+// WithBaseURLs sets per request and per endpoint base URLs, see BaseURLs.
+func WithBaseURLs(urls *BaseURLs) ClientOption {
+	return func(client *Client) {
+		client.baseURLs = urls
+	}
+}
+
+// baseURL returns the base URL the request is routed to, if any.
+func (urls *BaseURLs) baseURL(request *whttp.Request) (string, bool) {
+	if request.Context == nil {
+		return "", false
+	}
+	if baseURL, ok := urls.Requests[request.Context.Name]; ok {
+		return baseURL, true
+	}
+	for _, endpoint := range request.Context.Endpoints {
+		if baseURL, ok := urls.Endpoints[endpoint]; ok {
+			return baseURL, true
+		}
+	}
+
+	return "", false
+}
+
+// Middleware returns a middleware that replaces the base URL of the requests that match a
+// rule, unless the base URL is overridden on the context.
+func (urls *BaseURLs) Middleware() whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			if overrides, ok := OverridesFromContext(ctx); ok && overrides.BaseURL != "" {
+				return next.Send(ctx, request, v)
+			}
+			baseURL, ok := urls.baseURL(request)
+			if !ok {
+				return next.Send(ctx, request, v)
+			}
+			requestContext := *request.Context
+			requestContext.BaseURL = baseURL
+			routed := *request
+			routed.Context = &requestContext
+
+			return next.Send(ctx, &routed, v)
+		})
+	}
+}
+
+// mediaDownloadURL returns the media URL rewritten to be downloaded from MediaDownload.
+func (urls *BaseURLs) mediaDownloadURL(mediaURL string) (string, error) {
+	if urls == nil || urls.MediaDownload == "" {
+		return mediaURL, nil
+	}
+	media, err := url.Parse(mediaURL)
+	if err != nil {
+		return "", fmt.Errorf("parse media url: %w", err)
+	}
+	base, err := url.Parse(urls.MediaDownload)
+	if err != nil {
+		return "", fmt.Errorf("parse media download base url: %w", err)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/") + "/" + strings.TrimPrefix(media.Path, "/")
+	base.RawPath = ""
+	base.RawQuery = media.RawQuery
+
+	return base.String(), nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBaseURLs(t *testing.T) {
+	t.Parallel()
+	var (
+		mu   sync.Mutex
+		hits []string
+	)
+	newServer := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits = append(hits, name+" "+r.URL.RequestURI())
+			mu.Unlock()
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/media"):
+				_, _ = w.Write([]byte(`{"id":"media_1"}`))
+			case strings.HasSuffix(r.URL.Path, "/media_1"):
+				_, _ = w.Write([]byte(`{"id":"media_1","url":"https://lookaside.fbsbx.com/whatsapp/?mid=1&ext=2"}`))
+			case strings.HasPrefix(r.URL.Path, "/cdn/"):
+				_, _ = w.Write([]byte("media content"))
+			default:
+				_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.OUT"}]}`))
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		return server
+	}
+	graph, media, regional, mock := newServer("graph"), newServer("media"), newServer("regional"), newServer("mock")
+
+	client := NewClient(
+		WithBaseURL(graph.URL),
+		WithVersion("v16.0"),
+		WithPhoneNumberID("phone_1"),
+		WithBaseURLs(&BaseURLs{
+			Endpoints:     map[string]string{"media": media.URL + "/graph/", "messages": regional.URL},
+			Requests:      map[string]string{"get media": graph.URL},
+			MediaDownload: mock.URL + "/cdn",
+		}),
+	)
+	ctx := context.Background()
+
+	if _, err := client.SendText(ctx, "+255700000001", "hello"); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if _, err := client.UploadMedia(ctx, MediaTypeDocument, "a.pdf", strings.NewReader("pdf")); err != nil {
+		t.Fatalf("UploadMedia() error = %v", err)
+	}
+	download, err := client.DownloadMedia(ctx, "media_1", 0)
+	if err != nil {
+		t.Fatalf("DownloadMedia() error = %v", err)
+	}
+	if body, _ := io.ReadAll(download.Body); string(body) != "media content" {
+		t.Errorf("DownloadMedia() body = %q", body)
+	}
+	// the overrides of the context win
+	overridden := WithOverrides(ctx, &Overrides{BaseURL: mock.URL})
+	if _, err := client.SendText(overridden, "+255700000001", "hello"); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+
+	want := []string{
+		"regional /v16.0/phone_1/messages",
+		"media /graph/v16.0/phone_1/media",
+		"graph /v16.0/media_1",
+		"mock /cdn/whatsapp/?mid=1&ext=2",
+		"mock /v16.0/phone_1/messages",
+	}
+	if got := strings.Join(hits, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("hits =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
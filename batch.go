@@ -0,0 +1,206 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// MaxBatchSize is the maximum number of requests that can be sent in a single batch.
+const MaxBatchSize = 50
+
+var (
+	ErrBatchTooLarge       = fmt.Errorf("batch has more than %d requests", MaxBatchSize)
+	ErrBatchEmpty          = errors.New("batch has no requests")
+	ErrBatchResponseAbsent = errors.New("batch request did not complete")
+)
+
+type (
+	// BatchRequest is a single request in a batch. Method is the http method, RelativeURL is the
+	// path of the request relative to the Graph API version, e.g. "PHONE_NUMBER_ID/messages" and
+	// Body is the url encoded request body, see BatchBody.
+	//
+	// Name and DependsOn can be used to create dependencies between the requests in a batch, a
+	// request with DependsOn set is only executed after the named request completes.
+	// OmitResponseOnSuccess is used with named requests to include their response in the result.
+	BatchRequest struct {
+		Method                string `json:"method"`
+		RelativeURL           string `json:"relative_url"`
+		Body                  string `json:"body,omitempty"`
+		Name                  string `json:"name,omitempty"`
+		DependsOn             string `json:"depends_on,omitempty"`
+		OmitResponseOnSuccess *bool  `json:"omit_response_on_success,omitempty"`
+	}
+
+	BatchHeader struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	// BatchResponse is the response of a single request in a batch. Code is the http status code
+	// of the request and Body is its raw JSON body. Use Decode to get the decoded body or the error.
+	BatchResponse struct {
+		Code    int            `json:"code"`
+		Headers []*BatchHeader `json:"headers,omitempty"`
+		Body    string         `json:"body,omitempty"`
+	}
+)
+
+// BatchBody encodes payload into a body that can be used in a BatchRequest. The payload is
+// marshalled to a JSON object, then each of its fields becomes a url encoded parameter. String
+// values are used as they are while other values are encoded as JSON.
+func BatchBody(payload any) (string, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(payload); err != nil {
+		return "", fmt.Errorf("batch body: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		return "", fmt.Errorf("batch body: payload must be a JSON object: %w", err)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		raw := fields[key]
+		value := string(raw)
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			value = s
+		}
+		values = append(values, url.QueryEscape(key)+"="+url.QueryEscape(value))
+	}
+
+	return strings.Join(values, "&"), nil
+}
+
+// NewBatchMessageRequest creates a BatchRequest that sends the message from the phone number.
+func NewBatchMessageRequest(phoneNumberID string, message any) (*BatchRequest, error) {
+	body, err := BatchBody(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BatchRequest{
+		Method:      http.MethodPost,
+		RelativeURL: phoneNumberID + "/messages",
+		Body:        body,
+	}, nil
+}
+
+// header returns the value of the header name, the lookup is case-insensitive.
+func (response *BatchResponse) header(name string) string {
+	for _, h := range response.Headers {
+		if h != nil && strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+
+	return ""
+}
+
+// Decode decodes the body of the response into v. If the request failed, the error returned
+// by the API is returned as a *whttp.ResponseError. If the request did not complete (the Graph
+// API returns null for such requests), ErrBatchResponseAbsent is returned.
+func (response *BatchResponse) Decode(v any) error {
+	if response == nil {
+		return ErrBatchResponseAbsent
+	}
+
+	if response.Code < http.StatusOK || response.Code > http.StatusIMUsed {
+		errResponse := &whttp.ResponseError{}
+		if err := json.Unmarshal([]byte(response.Body), errResponse); err != nil {
+			return fmt.Errorf("batch response: status (%d): body (%s): %w", response.Code, response.Body, err)
+		}
+		errResponse.Code = response.Code
+		errResponse.FBTraceID = response.header(whttp.HeaderFBTraceID)
+		errResponse.RequestID = response.header(whttp.HeaderFBRequestID)
+		errResponse.BusinessUseCaseUsage = response.header(whttp.HeaderBusinessUseCaseUsage)
+
+		return errResponse
+	}
+
+	if v == nil || response.Body == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(response.Body), v); err != nil {
+		return fmt.Errorf("batch response: %w", err)
+	}
+
+	return nil
+}
+
+// Batch sends multiple independent requests in a single http request using the Graph API
+// batch requests. The responses are returned in the same order as the requests, a response
+// is nil if its request did not complete.
+//
+// Note that the whole batch counts as a single request to the API, but each of the requests
+// in it is still counted against the rate limits.
+func (client *Client) Batch(ctx context.Context, requests []*BatchRequest) ([]*BatchResponse, error) {
+	if len(requests) == 0 {
+		return nil, ErrBatchEmpty
+	}
+	if len(requests) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	batch, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("batch: %w", err)
+	}
+
+	cctx := client.context(ctx)
+	reqCtx := &whttp.RequestContext{
+		Name:       "batch",
+		BaseURL:    cctx.baseURL,
+		ApiVersion: cctx.apiVersion,
+	}
+
+	params := &whttp.Request{
+		Context: reqCtx,
+		Method:  http.MethodPost,
+		Bearer:  cctx.accessToken,
+		Form:    map[string]string{"batch": string(batch), "include_headers": "false"},
+	}
+
+	var responses []*BatchResponse
+	if err := client.sender.Send(ctx, params, &responses); err != nil {
+		return nil, fmt.Errorf("batch: %w", err)
+	}
+
+	return responses, nil
+}
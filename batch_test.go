@@ -0,0 +1,106 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestBatchBody(t *testing.T) {
+	t.Parallel()
+	message := models.NewMessage("255767001828", models.WithTemplate(&models.Template{
+		Language: &models.TemplateLanguage{Code: "en_US"},
+		Name:     "hello_world",
+	}))
+
+	body, err := BatchBody(message)
+	if err != nil {
+		t.Fatalf("BatchBody() error = %v", err)
+	}
+
+	want := "messaging_product=whatsapp&recipient_type=individual" +
+		"&template=%7B%22name%22%3A%22hello_world%22%2C%22language%22%3A%7B%22code%22%3A%22en_US%22%7D%7D" +
+		"&to=255767001828&type=template"
+	if body != want {
+		t.Errorf("BatchBody() = %s, want %s", body, want)
+	}
+}
+
+func TestClient_Batch(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requests []*BatchRequest
+		if err := json.Unmarshal([]byte(r.FormValue("batch")), &requests); err != nil || len(requests) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"code":200,"body":"{\"messages\":[{\"id\":\"wamid.1\"}]}"},
+			{"code":400,"body":"{\"error\":{\"code\":131030,\"message\":\"not allowed\"}}"},
+			null
+		]`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithAccessToken("token"))
+	requests := make([]*BatchRequest, 0, 3)
+	for i := 0; i < 3; i++ {
+		request, err := NewBatchMessageRequest("phone_id", &models.Message{Product: messagingProduct})
+		if err != nil {
+			t.Fatalf("NewBatchMessageRequest() error = %v", err)
+		}
+		requests = append(requests, request)
+	}
+
+	responses, err := client.Batch(context.TODO(), requests)
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("Batch() returned %d responses, want 3", len(responses))
+	}
+
+	var message ResponseMessage
+	if err := responses[0].Decode(&message); err != nil || message.Messages[0].ID != "wamid.1" {
+		t.Errorf("Decode() = %+v, %v", message, err)
+	}
+
+	var re *whttp.ResponseError
+	if err := responses[1].Decode(nil); !errors.As(err, &re) || re.Err.Code != 131030 {
+		t.Errorf("Decode() error = %v, want response error with code 131030", err)
+	}
+
+	if err := responses[2].Decode(nil); !errors.Is(err, ErrBatchResponseAbsent) {
+		t.Errorf("Decode() error = %v, want %v", err, ErrBatchResponseAbsent)
+	}
+
+	if _, err := client.Batch(context.TODO(), nil); !errors.Is(err, ErrBatchEmpty) {
+		t.Errorf("Batch() error = %v, want %v", err, ErrBatchEmpty)
+	}
+}
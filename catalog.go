@@ -0,0 +1,207 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+// Availability values of catalog products.
+const (
+	ProductInStock           = "in stock"
+	ProductOutOfStock        = "out of stock"
+	ProductPreorder          = "preorder"
+	ProductAvailableForOrder = "available for order"
+	ProductDiscontinued      = "discontinued"
+)
+
+const (
+	catalogProductFields = "id,retailer_id,name,price,currency,availability"
+	catalogPageSize      = 100
+)
+
+var (
+	ErrUnknownRetailerID  = errors.New("retailer id not found in catalog")
+	ErrNotProductMessage  = errors.New("not a product or product_list interactive message")
+	ErrCatalogIDRequired  = errors.New("catalog id is required")
+	ErrNoProductsInAction = errors.New("interactive action has no products")
+)
+
+type (
+	// CatalogProduct is a product of a catalog connected to the WhatsApp Business Account.
+	// Price is formatted by the API with the currency symbol, e.g. "$10.00".
+	CatalogProduct struct {
+		ID           string `json:"id"`
+		RetailerID   string `json:"retailer_id"`
+		Name         string `json:"name"`
+		Price        string `json:"price,omitempty"`
+		Currency     string `json:"currency,omitempty"`
+		Availability string `json:"availability,omitempty"`
+	}
+
+	CatalogProductsList struct {
+		Data   []*CatalogProduct `json:"data,omitempty"`
+		Paging *Paging           `json:"paging,omitempty"`
+	}
+)
+
+// IsAvailable reports whether the product can be ordered.
+func (product *CatalogProduct) IsAvailable() bool {
+	switch product.Availability {
+	case ProductInStock, ProductPreorder, ProductAvailableForOrder:
+		return true
+	default:
+		return false
+	}
+}
+
+func (client *Client) catalogProductsPage(ctx context.Context, catalogID string, query map[string]string,
+) (*CatalogProductsList, error) {
+	cctx := client.context(ctx)
+	query["fields"] = catalogProductFields
+	request := &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       "list catalog products",
+			BaseURL:    cctx.baseURL,
+			ApiVersion: cctx.apiVersion,
+			SenderID:   catalogID,
+			Endpoints:  []string{"products"},
+		},
+		Method: http.MethodGet,
+		Query:  query,
+		Bearer: cctx.accessToken,
+	}
+
+	return whttp.SendTyped[CatalogProductsList](ctx, client.sender, request)
+}
+
+// ListCatalogProducts lists all the products of the catalog catalogID, following the pages
+// of the response.
+func (client *Client) ListCatalogProducts(ctx context.Context, catalogID string) ([]*CatalogProduct, error) {
+	return client.listCatalogProducts(ctx, catalogID, map[string]string{})
+}
+
+func (client *Client) listCatalogProducts(ctx context.Context, catalogID string, query map[string]string,
+) ([]*CatalogProduct, error) {
+	if catalogID == "" {
+		return nil, ErrCatalogIDRequired
+	}
+	query["limit"] = strconv.Itoa(catalogPageSize)
+
+	var products []*CatalogProduct
+	for {
+		page, err := client.catalogProductsPage(ctx, catalogID, query)
+		if err != nil {
+			return nil, fmt.Errorf("list catalog %s products: %w", catalogID, err)
+		}
+		products = append(products, page.Data...)
+
+		if page.Paging == nil || page.Paging.Next == "" || page.Paging.Cursors == nil ||
+			page.Paging.Cursors.After == "" {
+			return products, nil
+		}
+		query["after"] = page.Paging.Cursors.After
+	}
+}
+
+// CatalogProductsByRetailerID returns the products of the catalog catalogID with the given
+// retailer IDs, keyed by retailer ID. Retailer IDs that are not in the catalog are absent
+// from the map.
+func (client *Client) CatalogProductsByRetailerID(ctx context.Context, catalogID string, retailerIDs ...string,
+) (map[string]*CatalogProduct, error) {
+	filter, err := json.Marshal(map[string]any{
+		"retailer_id": map[string][]string{"is_any": retailerIDs},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("catalog products filter: %w", err)
+	}
+
+	products, err := client.listCatalogProducts(ctx, catalogID, map[string]string{"filter": string(filter)})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*CatalogProduct, len(products))
+	for _, product := range products {
+		found[product.RetailerID] = product
+	}
+
+	return found, nil
+}
+
+// ValidateRetailerIDs returns an error wrapping ErrUnknownRetailerID that lists the retailer
+// IDs that are not in the catalog catalogID.
+func (client *Client) ValidateRetailerIDs(ctx context.Context, catalogID string, retailerIDs ...string) error {
+	if len(retailerIDs) == 0 {
+		return nil
+	}
+
+	found, err := client.CatalogProductsByRetailerID(ctx, catalogID, retailerIDs...)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, id := range retailerIDs {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownRetailerID, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// ValidateProductMessage checks that the products of a product or product_list interactive
+// message exist in its catalog, so that a message referencing a removed product fails before
+// it is sent.
+func (client *Client) ValidateProductMessage(ctx context.Context, interactive *models.Interactive) error {
+	if interactive == nil || interactive.Action == nil ||
+		(interactive.Type != models.InteractiveMessageProduct && interactive.Type != models.InteractiveMessageProductList) {
+		return ErrNotProductMessage
+	}
+
+	action := interactive.Action
+	var retailerIDs []string
+	if action.ProductRetailerID != "" {
+		retailerIDs = append(retailerIDs, action.ProductRetailerID)
+	}
+	for _, section := range action.Sections {
+		for _, item := range section.ProductItems {
+			retailerIDs = append(retailerIDs, item.RetailerID)
+		}
+	}
+	if len(retailerIDs) == 0 {
+		return ErrNoProductsInAction
+	}
+
+	return client.ValidateRetailerIDs(ctx, action.CatalogID, retailerIDs...)
+}
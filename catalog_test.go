@@ -0,0 +1,145 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestCatalogProducts(t *testing.T) {
+	t.Parallel()
+	products := []*CatalogProduct{
+		{ID: "1", RetailerID: "sku-1", Name: "Tea", Price: "$2.00", Currency: "USD", Availability: ProductInStock},
+		{ID: "2", RetailerID: "sku-2", Name: "Mug", Price: "$8.00", Currency: "USD", Availability: ProductOutOfStock},
+		{ID: "3", RetailerID: "sku-3", Name: "Pot", Price: "$20.00", Currency: "USD", Availability: ProductPreorder},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v23.0/catalog_1/products" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("fields") != catalogProductFields {
+			t.Errorf("fields = %q", query.Get("fields"))
+		}
+		list := &CatalogProductsList{}
+		if filter := query.Get("filter"); filter != "" {
+			var f struct {
+				RetailerID struct {
+					IsAny []string `json:"is_any"`
+				} `json:"retailer_id"`
+			}
+			_ = json.Unmarshal([]byte(filter), &f)
+			for _, product := range products {
+				for _, id := range f.RetailerID.IsAny {
+					if product.RetailerID == id {
+						list.Data = append(list.Data, product)
+					}
+				}
+			}
+		} else if query.Get("after") == "" {
+			// two pages of products
+			list.Data = products[:2]
+			list.Paging = &Paging{Cursors: &Cursors{After: "page2"}, Next: "https://graph.facebook.com/next"}
+		} else {
+			list.Data = products[2:]
+			list.Paging = &Paging{Cursors: &Cursors{Before: "page2"}}
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL))
+	ctx := context.Background()
+
+	all, err := client.ListCatalogProducts(ctx, "catalog_1")
+	if err != nil {
+		t.Fatalf("ListCatalogProducts() error = %v", err)
+	}
+	if len(all) != 3 || all[2].RetailerID != "sku-3" {
+		t.Errorf("ListCatalogProducts() = %+v, want all pages", all)
+	}
+	if !all[0].IsAvailable() || all[1].IsAvailable() {
+		t.Errorf("IsAvailable() of %q, %q is wrong", all[0].Availability, all[1].Availability)
+	}
+
+	if err = client.ValidateRetailerIDs(ctx, "catalog_1", "sku-1", "sku-3"); err != nil {
+		t.Errorf("ValidateRetailerIDs() error = %v", err)
+	}
+	err = client.ValidateRetailerIDs(ctx, "catalog_1", "sku-1", "sku-9", "sku-10")
+	if !errors.Is(err, ErrUnknownRetailerID) || !strings.Contains(err.Error(), "sku-9, sku-10") {
+		t.Errorf("ValidateRetailerIDs() error = %v, want %v listing the missing ids", err, ErrUnknownRetailerID)
+	}
+
+	tests := []struct {
+		name        string
+		interactive *models.Interactive
+		wantErr     error
+	}{
+		{
+			name: "single product",
+			interactive: models.NewInteractiveMessage(models.InteractiveMessageProduct,
+				models.WithInteractiveAction(&models.InteractiveAction{CatalogID: "catalog_1", ProductRetailerID: "sku-2"})),
+		},
+		{
+			name: "product list with an unknown product",
+			interactive: models.NewInteractiveMessage(models.InteractiveMessageProductList,
+				models.WithInteractiveAction(&models.InteractiveAction{
+					CatalogID: "catalog_1",
+					Sections: []*models.InteractiveSection{
+						{Title: "Drinks", ProductItems: []*models.Product{{RetailerID: "sku-1"}}},
+						{Title: "Gone", ProductItems: []*models.Product{{RetailerID: "sku-0"}}},
+					},
+				})),
+			wantErr: ErrUnknownRetailerID,
+		},
+		{
+			name: "missing catalog",
+			interactive: models.NewInteractiveMessage(models.InteractiveMessageProduct,
+				models.WithInteractiveAction(&models.InteractiveAction{ProductRetailerID: "sku-1"})),
+			wantErr: ErrCatalogIDRequired,
+		},
+		{
+			name: "reply buttons",
+			interactive: models.NewInteractiveMessage(models.InteractiveMessageButton,
+				models.WithInteractiveAction(&models.InteractiveAction{})),
+			wantErr: ErrNotProductMessage,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := client.ValidateProductMessage(ctx, tt.interactive)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("ValidateProductMessage() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
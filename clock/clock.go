@@ -0,0 +1,223 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package clock abstracts the passage of time so that the time dependent parts of the
+// library, like rate limiting, retries, circuit breaking, schedulers and token expiry
+// monitoring, can be tested without sleeping.
+//
+// Production code uses System, tests use a Fake and move it forward with Advance.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Clock tells the time and creates timers and tickers.
+	Clock interface {
+		Now() time.Time
+		NewTimer(d time.Duration) Timer
+		NewTicker(d time.Duration) Ticker
+	}
+
+	// Timer is the Clock counterpart of *time.Timer.
+	Timer interface {
+		C() <-chan time.Time
+		Stop() bool
+	}
+
+	// Ticker is the Clock counterpart of *time.Ticker.
+	Ticker interface {
+		C() <-chan time.Time
+		Stop()
+	}
+
+	systemClock struct{}
+
+	systemTimer struct{ timer *time.Timer }
+
+	systemTicker struct{ ticker *time.Ticker }
+)
+
+// System returns the Clock backed by the time package.
+func System() Clock {
+	return systemClock{}
+}
+
+// OrSystem returns c, or System if c is nil. It lets configuration structs leave the
+// clock unset.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System()
+	}
+
+	return c
+}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{timer: time.NewTimer(d)} }
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{ticker: time.NewTicker(d)}
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.timer.C }
+
+func (t systemTimer) Stop() bool { return t.timer.Stop() }
+
+func (t systemTicker) C() <-chan time.Time { return t.ticker.C }
+
+func (t systemTicker) Stop() { t.ticker.Stop() }
+
+// Fake is a Clock whose time only moves when Advance or Set is called. Timers and tickers
+// fire when the fake time reaches their deadline. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	clock    *Fake
+	deadline time.Time
+	period   time.Duration // zero for timers
+	ch       chan time.Time
+	stopped  bool
+}
+
+// NewFake returns a Fake set at now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0)
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	return fakeTicker{f.add(d, d)}
+}
+
+// Waiters returns the number of active timers and tickers. Tests use it to wait until
+// the code under test is blocked on the clock before calling Advance.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.waiters)
+}
+
+// Advance moves the time forward by d, firing the timers and tickers that are due.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the time to now, firing the timers and tickers that are due. Moving the time
+// backwards only changes what Now returns.
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = now
+	sort.Slice(f.waiters, func(i, j int) bool {
+		return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+	})
+
+	active := f.waiters[:0]
+	for _, w := range f.waiters {
+		for !w.deadline.After(now) {
+			select {
+			case w.ch <- w.deadline:
+			default: // like time.Ticker, drop ticks the receiver is not ready for
+			}
+			if w.period == 0 {
+				w.stopped = true
+
+				break
+			}
+			w.deadline = w.deadline.Add(w.period)
+		}
+		if !w.stopped {
+			active = append(active, w)
+		}
+	}
+	f.waiters = active
+}
+
+func (f *Fake) add(d, period time.Duration) *fakeWaiter {
+	f.mu.Lock()
+	w := &fakeWaiter{
+		clock:    f,
+		deadline: f.now.Add(d),
+		period:   period,
+		ch:       make(chan time.Time, 1),
+	}
+	f.waiters = append(f.waiters, w)
+	f.mu.Unlock()
+
+	if d <= 0 {
+		f.Set(f.Now())
+	}
+
+	return w
+}
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+
+	if w.stopped {
+		return false
+	}
+	w.stopped = true
+	for i, waiter := range w.clock.waiters {
+		if waiter == w {
+			w.clock.waiters = append(w.clock.waiters[:i], w.clock.waiters[i+1:]...)
+
+			break
+		}
+	}
+
+	return true
+}
+
+// fakeTicker adapts fakeWaiter to Ticker, whose Stop returns nothing.
+type fakeTicker struct{ *fakeWaiter }
+
+func (t fakeTicker) Stop() {
+	t.fakeWaiter.Stop()
+}
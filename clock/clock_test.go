@@ -0,0 +1,94 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeTimer(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		duration time.Duration
+		advance  time.Duration
+		wantFire bool
+	}{
+		{name: "before deadline", duration: time.Minute, advance: 59 * time.Second, wantFire: false},
+		{name: "at deadline", duration: time.Minute, advance: time.Minute, wantFire: true},
+		{name: "past deadline", duration: time.Minute, advance: time.Hour, wantFire: true},
+		{name: "zero duration", duration: 0, advance: 0, wantFire: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			clk := NewFake(start)
+			timer := clk.NewTimer(tt.duration)
+			clk.Advance(tt.advance)
+
+			select {
+			case at := <-timer.C():
+				if !tt.wantFire {
+					t.Fatalf("timer fired at %v", at)
+				}
+				if want := start.Add(tt.duration); !at.Equal(want) {
+					t.Errorf("timer fired with %v, want %v", at, want)
+				}
+			default:
+				if tt.wantFire {
+					t.Fatal("timer did not fire")
+				}
+			}
+		})
+	}
+}
+
+func TestFakeTickerAndStop(t *testing.T) {
+	t.Parallel()
+	clk := NewFake(time.Now())
+	ticker := clk.NewTicker(time.Second)
+	timer := clk.NewTimer(time.Second)
+	if !timer.Stop() {
+		t.Error("Stop() = false for an active timer")
+	}
+
+	for i := 0; i < 3; i++ {
+		clk.Advance(time.Second)
+		select {
+		case <-ticker.C():
+		default:
+			t.Fatalf("tick %d missing", i)
+		}
+	}
+	select {
+	case <-timer.C():
+		t.Error("stopped timer fired")
+	default:
+	}
+
+	ticker.Stop()
+	if n := clk.Waiters(); n != 0 {
+		t.Errorf("Waiters() = %d after Stop, want 0", n)
+	}
+}
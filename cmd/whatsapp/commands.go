@@ -0,0 +1,239 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/SeamPay/whatsapp"
+	"github.com/SeamPay/whatsapp/config"
+)
+
+type command struct {
+	stdout     io.Writer
+	stderr     io.Writer
+	lookup     func(string) (string, bool)
+	configPath string
+	profile    string
+}
+
+func (cmd *command) flagSet(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(cmd.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(cmd.stderr, "Usage: whatsapp %s [flags] %s\n\nFlags:\n", name, args)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+func (cmd *command) client() (*whatsapp.Client, error) {
+	var (
+		cfg *config.Config
+		err error
+	)
+	if cmd.configPath != "" {
+		cfg, err = config.LoadFile(cmd.configPath, cmd.profile, nil)
+	} else {
+		cfg, err = config.FromLookup(cmd.lookup)
+	}
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return whatsapp.NewClient(cfg.ClientOptions()...), nil
+}
+
+func (cmd *command) printJSON(v any) error {
+	encoder := json.NewEncoder(cmd.stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("print response: %w", err)
+	}
+
+	return nil
+}
+
+//nolint:cyclop
+func (cmd *command) send(ctx context.Context, args []string) error {
+	var (
+		to, text, template, language, replyTo  string
+		mediaType, mediaID, mediaLink, caption string
+		filename                               string
+		preview                                bool
+	)
+	flags := cmd.flagSet("send", "")
+	flags.StringVar(&to, "to", "", "phone number of the recipient")
+	flags.StringVar(&text, "text", "", "body of a text message")
+	flags.BoolVar(&preview, "preview", false, "render a preview of the first URL of the text")
+	flags.StringVar(&template, "template", "", "name of a template to send")
+	flags.StringVar(&language, "language", "en_US", "language code of the template")
+	flags.StringVar(&mediaType, "media-type", "", "type of a media message: image, audio, video, document or sticker")
+	flags.StringVar(&mediaID, "media-id", "", "ID of uploaded media")
+	flags.StringVar(&mediaLink, "media-link", "", "public URL of the media")
+	flags.StringVar(&caption, "caption", "", "caption of the media")
+	flags.StringVar(&filename, "filename", "", "filename of a document")
+	flags.StringVar(&replyTo, "reply-to", "", "ID of the message to reply to")
+	if err := flags.Parse(args); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	kinds := 0
+	for _, set := range []bool{text != "", template != "", mediaType != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if to == "" || kinds != 1 {
+		flags.Usage()
+
+		return fmt.Errorf("%w: send needs -to and one of -text, -template or -media-type", ErrUsage)
+	}
+
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	var options []whatsapp.SendOption
+	if replyTo != "" {
+		options = append(options, whatsapp.WithReplyTo(replyTo))
+	}
+
+	var response *whatsapp.ResponseMessage
+	switch {
+	case text != "":
+		if preview {
+			options = append(options, whatsapp.WithPreviewURL())
+		}
+		response, err = client.SendText(ctx, to, text, options...)
+	case template != "":
+		response, err = client.SendTemplate(ctx, to, &whatsapp.Template{
+			Name:         template,
+			LanguageCode: language,
+		}, options...)
+	default:
+		response, err = client.SendMedia(ctx, to, &whatsapp.MediaMessage{
+			Type:      whatsapp.MediaType(mediaType),
+			MediaID:   mediaID,
+			MediaLink: mediaLink,
+			Caption:   caption,
+			Filename:  filename,
+		}, nil, options...)
+	}
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return cmd.printJSON(response)
+}
+
+func (cmd *command) upload(ctx context.Context, args []string) error {
+	var mediaType string
+	flags := cmd.flagSet("upload", "file")
+	flags.StringVar(&mediaType, "type", "", "media type, guessed from the file extension when empty")
+	if err := flags.Parse(args); err != nil {
+		return err //nolint:wrapcheck
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+
+		return fmt.Errorf("%w: upload needs a file", ErrUsage)
+	}
+
+	path := flags.Arg(0)
+	if mediaType == "" {
+		mediaType = guessMediaType(path)
+	}
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	defer file.Close()
+
+	response, err := client.UploadMedia(ctx, whatsapp.MediaType(mediaType), filepath.Base(path), file)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	return cmd.printJSON(response)
+}
+
+// guessMediaType returns the media type of the file at path from its extension, files that
+// are not images, audio or videos are sent as documents.
+func guessMediaType(path string) string {
+	mimeType := mime.TypeByExtension(filepath.Ext(path))
+	for _, mediaType := range []whatsapp.MediaType{
+		whatsapp.MediaTypeImage, whatsapp.MediaTypeAudio, whatsapp.MediaTypeVideo,
+	} {
+		if strings.HasPrefix(mimeType, string(mediaType)+"/") {
+			if mimeType == "image/webp" {
+				return string(whatsapp.MediaTypeSticker)
+			}
+
+			return string(mediaType)
+		}
+	}
+
+	return string(whatsapp.MediaTypeDocument)
+}
+
+func (cmd *command) templates(ctx context.Context, args []string) error {
+	var asJSON bool
+	flags := cmd.flagSet("templates", "")
+	flags.BoolVar(&asJSON, "json", false, "print the templates as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	templates, err := client.ListTemplates(ctx)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	if asJSON {
+		return cmd.printJSON(templates)
+	}
+
+	writer := tabwriter.NewWriter(cmd.stdout, 0, 0, 2, ' ', 0) //nolint:gomnd
+	fmt.Fprintln(writer, "NAME\tLANGUAGE\tSTATUS\tCATEGORY\tID")
+	for _, template := range templates.Data {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+			template.Name, template.Language, template.Status, template.Category, template.ID)
+	}
+
+	return writer.Flush() //nolint:wrapcheck
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Command whatsapp is a companion tool for debugging WhatsApp Cloud API integrations. It
+// sends test messages, uploads media, lists the message templates and runs a local webhook
+// receiver that prints the notifications it receives.
+//
+// The client is configured from the environment, see the config package, or from a
+// configuration file with -config and -profile:
+//
+//	whatsapp send -to 255700000000 -text "hello"
+//	whatsapp send -to 255700000000 -template hello_world -language en_US
+//	whatsapp send -to 255700000000 -media-type image -media-link https://example.com/cat.png
+//	whatsapp upload cat.png
+//	whatsapp templates
+//	whatsapp webhook -addr :8080
+//
+// The webhook receiver listens on localhost, expose it with a tunnel such as ngrok and set
+// the public URL as the callback URL in the App Dashboard.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+// ErrUsage is returned when the command line is invalid.
+var ErrUsage = errors.New("invalid usage")
+
+const usage = `Usage: whatsapp [-config file] [-profile name] <command> [flags]
+
+Commands:
+  send       send a text, media or template message
+  upload     upload a media file
+  templates  list the message templates
+  webhook    run a local webhook receiver
+
+Run "whatsapp <command> -h" for the flags of a command.
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.LookupEnv)
+	stop()
+
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	case errors.Is(err, ErrUsage):
+		fmt.Fprintln(os.Stderr, "whatsapp:", err)
+		os.Exit(2) //nolint:gomnd
+	default:
+		fmt.Fprintln(os.Stderr, "whatsapp:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, lookup func(string) (string, bool)) error {
+	cmd := &command{stdout: stdout, stderr: stderr, lookup: lookup}
+	flags := flag.NewFlagSet("whatsapp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	flags.StringVar(&cmd.configPath, "config", "", "JSON configuration `file`, the environment is used when empty")
+	flags.StringVar(&cmd.profile, "profile", "", "profile of the configuration file")
+	if err := flags.Parse(args); err != nil {
+		return err //nolint:wrapcheck
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+
+		return fmt.Errorf("%w: missing command", ErrUsage)
+	}
+
+	name, args := flags.Arg(0), flags.Args()[1:]
+	switch name {
+	case "send":
+		return cmd.send(ctx, args)
+	case "upload":
+		return cmd.upload(ctx, args)
+	case "templates":
+		return cmd.templates(ctx, args)
+	case "webhook":
+		return cmd.webhook(ctx, args)
+	default:
+		flags.Usage()
+
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, name)
+	}
+}
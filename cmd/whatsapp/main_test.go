@@ -0,0 +1,168 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/config"
+	"github.com/SeamPay/whatsapp/whatsapptest"
+)
+
+func serverLookup(server *whatsapptest.Server) func(string) (string, bool) {
+	env := map[string]string{
+		config.EnvBaseURL:           server.URL(),
+		config.EnvAccessToken:       whatsapptest.AccessToken,
+		config.EnvPhoneNumberID:     whatsapptest.PhoneNumberID,
+		config.EnvBusinessAccountID: whatsapptest.BusinessAccountID,
+	}
+
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+
+		return value, ok
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	server := whatsapptest.NewServer()
+	t.Cleanup(server.Close)
+
+	file := filepath.Join(t.TempDir(), "cat.png")
+	if err := os.WriteFile(file, []byte("meow"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr error
+	}{
+		{name: "text", args: []string{"send", "-to", "255700000000", "-text", "hi"}, want: "wamid."},
+		{
+			name: "template",
+			args: []string{"send", "-to", "255700000000", "-template", "hello_world"},
+			want: "wamid.",
+		},
+		{
+			name: "media",
+			args: []string{"send", "-to", "255700000000", "-media-type", "image", "-media-link", "https://x.y/a.png"},
+			want: "wamid.",
+		},
+		{name: "upload", args: []string{"upload", file}, want: "media.whatsapptest."},
+		{name: "templates", args: []string{"templates"}, want: "hello_world"},
+		{name: "no command", args: nil, wantErr: ErrUsage},
+		{name: "unknown command", args: []string{"fly"}, wantErr: ErrUsage},
+		{name: "two kinds", args: []string{"send", "-to", "1", "-text", "a", "-template", "b"}, wantErr: ErrUsage},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var stdout, stderr bytes.Buffer
+			err := run(context.Background(), tt.args, &stdout, &stderr, serverLookup(server))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("run() error = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(stdout.String(), tt.want) {
+				t.Errorf("run() output = %q, want it to contain %q", stdout.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGuessMediaType(t *testing.T) {
+	t.Parallel()
+	for path, want := range map[string]string{
+		"cat.png":     "image",
+		"cat.webp":    "sticker",
+		"song.mp3":    "audio",
+		"clip.mp4":    "video",
+		"invoice.pdf": "document",
+		"notes":       "document",
+	} {
+		if got := guessMediaType(path); got != want {
+			t.Errorf("guessMediaType(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	t.Parallel()
+	var stdout bytes.Buffer
+	cmd := &command{stdout: &stdout}
+	handler := cmd.webhookHandler(&webhookOptions{verifyToken: "token", secret: "secret"})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/webhooks?hub.mode=subscribe&hub.challenge=42&hub.verify_token=token", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "42" {
+		t.Errorf("verification = %d %q, want 200 %q", recorder.Code, recorder.Body.String(), "42")
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/webhooks?hub.mode=subscribe&hub.challenge=42&hub.verify_token=wrong", nil))
+	if recorder.Code != http.StatusBadRequest || recorder.Body.Len() != 0 {
+		t.Errorf("verification with a wrong token = %d %q, want 400", recorder.Code, recorder.Body.String())
+	}
+
+	body := `{"object":"whatsapp_business_account","entry":[{"changes":[{"field":"messages","value":{` +
+		`"messages":[{"id":"wamid.1","from":"255700000000","type":"text","text":{"body":"hello"}}],` +
+		`"statuses":[{"id":"wamid.2","recipient_id":"255700000000","status":"read"}]}}]}]}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(body))
+	request := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	request.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("notification = %d, want 200", recorder.Code)
+	}
+	for _, want := range []string{
+		`message wamid.1 from 255700000000: text "hello"`,
+		"status  wamid.2 to 255700000000: read",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output = %q, want it to contain %q", stdout.String(), want)
+		}
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	request.Header.Set("X-Hub-Signature-256", "sha256=00")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("notification with a bad signature = %d, want 401", recorder.Code)
+	}
+}
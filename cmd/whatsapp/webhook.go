@@ -0,0 +1,205 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/config"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type webhookOptions struct {
+	verifyToken string
+	secret      string
+	json        bool
+}
+
+func (cmd *command) webhook(ctx context.Context, args []string) error {
+	var addr, path string
+	options := &webhookOptions{}
+	if err := cmd.webhookDefaults(options); err != nil {
+		return err
+	}
+	flags := cmd.flagSet("webhook", "")
+	flags.StringVar(&addr, "addr", "localhost:8080", "address to listen on")
+	flags.StringVar(&path, "path", "/webhooks", "path of the callback URL")
+	flags.StringVar(&options.verifyToken, "verify-token", options.verifyToken,
+		"verify token of the subscription, any token is accepted when empty")
+	flags.StringVar(&options.secret, "secret", options.secret,
+		"app secret used to validate the signatures, they are not validated when empty")
+	flags.BoolVar(&options.json, "json", false, "print the whole notifications as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, cmd.webhookHandler(options))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: shutdownTimeout}
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	fmt.Fprintf(cmd.stdout, "Listening for webhooks on http://%s%s\n", listener.Addr(), path)
+	fmt.Fprintf(cmd.stdout, "Expose it with:  ngrok http %s\n", port)
+	fmt.Fprintf(cmd.stdout, "Callback URL:    https://<your-ngrok-domain>%s\n", path)
+	if options.verifyToken != "" {
+		fmt.Fprintf(cmd.stdout, "Verify token:    %s\n", options.verifyToken)
+	}
+	if options.secret == "" {
+		fmt.Fprintln(cmd.stdout, "Signatures are not validated, set -secret to validate them")
+	}
+	fmt.Fprintln(cmd.stdout)
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.Serve(listener) }()
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("webhook: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+		if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("webhook: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// webhookDefaults sets the verify token and the secret from the configuration file or the
+// environment. Unlike the other commands, the client credentials are not needed.
+func (cmd *command) webhookDefaults(options *webhookOptions) error {
+	if cmd.configPath != "" {
+		cfg, err := config.LoadFile(cmd.configPath, cmd.profile, nil)
+		if err != nil {
+			return err //nolint:wrapcheck
+		}
+		options.verifyToken, options.secret = cfg.Webhook.VerifyToken, cfg.Webhook.Secret
+
+		return nil
+	}
+
+	for _, key := range []string{config.EnvWebhookSecret, config.EnvAppSecret} {
+		if value, ok := cmd.lookup(key); ok && value != "" {
+			options.secret = value
+
+			break
+		}
+	}
+	options.verifyToken, _ = cmd.lookup(config.EnvWebhookVerifyToken)
+
+	return nil
+}
+
+// webhookHandler answers the subscription verification requests and prints a line per
+// message and status of the notifications.
+func (cmd *command) webhookHandler(options *webhookOptions) http.Handler {
+	var mu sync.Mutex
+	printf := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(cmd.stdout, time.Now().Format("15:04:05")+" "+format+"\n", args...)
+	}
+
+	listener := webhooks.NewEventListener(
+		webhooks.WithHandlerOptions(&webhooks.HandlerOptions{
+			ValidateSignature: options.secret != "",
+			Secret:            options.secret,
+		}),
+		webhooks.WithNotificationErrorHandler(
+			func(ctx context.Context, request *http.Request, err error) *webhooks.NotificationErrHandlerResponse {
+				printf("error   %v", err)
+
+				return &webhooks.NotificationErrHandlerResponse{StatusCode: http.StatusUnauthorized}
+			}),
+		webhooks.WithSubscriptionVerifier(func(ctx context.Context, request *webhooks.VerificationRequest) error {
+			if request.Mode != "subscribe" || (options.verifyToken != "" && request.Token != options.verifyToken) {
+				printf("verify  rejected token %q", request.Token)
+
+				return config.ErrInvalidVerifyToken
+			}
+			printf("verify  subscription verified")
+
+			return nil
+		}),
+		webhooks.WithGlobalNotificationHandler(
+			func(ctx context.Context, writer http.ResponseWriter, notification *webhooks.Notification) error {
+				if options.json {
+					data, _ := json.MarshalIndent(notification, "", "  ")
+					printf("%s", data)
+
+					return nil
+				}
+				printNotification(printf, notification)
+
+				return nil
+			}),
+	)
+
+	return listener.Handler()
+}
+
+func printNotification(printf func(string, ...any), notification *webhooks.Notification) {
+	for _, entry := range notification.Entry {
+		for _, change := range entry.Changes {
+			if change.Value == nil {
+				printf("%-7s (no value)", change.Field)
+
+				continue
+			}
+			for _, message := range change.Value.Messages {
+				printf("message %s from %s: %s", message.ID, message.From, summary(message))
+			}
+			for _, status := range change.Value.Statuses {
+				printf("status  %s to %s: %s", status.ID, status.RecipientID, status.StatusValue)
+			}
+			for _, err := range change.Value.Errors {
+				printf("error   %v", err)
+			}
+		}
+	}
+}
+
+func summary(message *webhooks.Message) string {
+	switch {
+	case message.Text != nil:
+		return fmt.Sprintf("text %q", message.Text.Body)
+	case message.Reaction != nil:
+		return fmt.Sprintf("reaction %s to %s", message.Reaction.Emoji, message.Reaction.MessageID)
+	default:
+		return message.Type
+	}
+}
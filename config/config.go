@@ -0,0 +1,246 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package config loads the configuration of a whatsapp.Client and of the webhook handlers
+// from the environment.
+//
+//	cfg, err := config.FromEnv()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	client := whatsapp.NewClient(cfg.ClientOptions()...)
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/SeamPay/whatsapp"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// Environment variables read by FromEnv.
+const (
+	EnvBaseURL            = "WHATSAPP_BASE_URL"
+	EnvAPIVersion         = "WHATSAPP_API_VERSION"
+	EnvAccessToken        = "WHATSAPP_ACCESS_TOKEN"
+	EnvPhoneNumberID      = "WHATSAPP_PHONE_NUMBER_ID"
+	EnvBusinessAccountID  = "WHATSAPP_WABA_ID"
+	EnvAppSecret          = "WHATSAPP_APP_SECRET"
+	EnvWebhookVerifyToken = "WHATSAPP_WEBHOOK_VERIFY_TOKEN"
+	EnvWebhookSecret      = "WHATSAPP_WEBHOOK_SECRET"
+)
+
+var (
+	// ErrInvalidConfig is wrapped by the errors returned when the configuration is invalid.
+	ErrInvalidConfig = errors.New("invalid whatsapp configuration")
+
+	// ErrInvalidVerifyToken is returned by the SubscriptionVerifier of a Config when the
+	// verification request does not carry the configured verify token.
+	ErrInvalidVerifyToken = errors.New("invalid webhook verify token")
+)
+
+type (
+	// Config is the configuration of a client and its webhooks.
+	//
+	// BaseURL and APIVersion default to whatsapp.BaseURL and whatsapp.DefaultAPIVersion.
+	// AppSecret signs the requests with appsecret_proof, see whatsapp.WithAppSecret.
+	Config struct {
+		BaseURL           string  `json:"base_url,omitempty"            yaml:"base_url,omitempty"`
+		APIVersion        string  `json:"api_version,omitempty"         yaml:"api_version,omitempty"`
+		AccessToken       string  `json:"access_token,omitempty"        yaml:"access_token,omitempty"`
+		PhoneNumberID     string  `json:"phone_number_id,omitempty"     yaml:"phone_number_id,omitempty"`
+		BusinessAccountID string  `json:"business_account_id,omitempty" yaml:"business_account_id,omitempty"`
+		AppSecret         string  `json:"app_secret,omitempty"          yaml:"app_secret,omitempty"`
+		Webhook           Webhook `json:"webhook,omitempty"             yaml:"webhook,omitempty"`
+	}
+
+	// Webhook is the configuration of the webhook handlers. VerifyToken is the token set in
+	// the App Dashboard to verify the subscription and Secret the app secret used to check
+	// the signature of the notifications, it defaults to Config.AppSecret.
+	Webhook struct {
+		VerifyToken string `json:"verify_token,omitempty" yaml:"verify_token,omitempty"`
+		Secret      string `json:"secret,omitempty"       yaml:"secret,omitempty"`
+	}
+
+	// FieldError describes an invalid configuration value. Source is where the value was
+	// read from, for example the environment variable.
+	FieldError struct {
+		Field   string
+		Source  string
+		Problem string
+	}
+
+	// LookupFunc looks up the value of a variable, like os.LookupEnv.
+	LookupFunc func(key string) (string, bool)
+)
+
+func (e *FieldError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Problem)
+	}
+
+	return fmt.Sprintf("%s (%s): %s", e.Field, e.Source, e.Problem)
+}
+
+// Unwrap returns ErrInvalidConfig.
+func (e *FieldError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// FromEnv reads the configuration from the environment variables and validates it.
+func FromEnv() (*Config, error) {
+	return FromLookup(os.LookupEnv)
+}
+
+// FromLookup is like FromEnv but reads the variables with lookup.
+func FromLookup(lookup LookupFunc) (*Config, error) {
+	get := func(key string) string {
+		value, _ := lookup(key)
+
+		return strings.TrimSpace(value)
+	}
+	config := &Config{
+		BaseURL:           get(EnvBaseURL),
+		APIVersion:        get(EnvAPIVersion),
+		AccessToken:       get(EnvAccessToken),
+		PhoneNumberID:     get(EnvPhoneNumberID),
+		BusinessAccountID: get(EnvBusinessAccountID),
+		AppSecret:         get(EnvAppSecret),
+		Webhook: Webhook{
+			VerifyToken: get(EnvWebhookVerifyToken),
+			Secret:      get(EnvWebhookSecret),
+		},
+	}
+	config.setDefaults()
+	if err := config.validate(envSources); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// envSources maps the fields of Config to their environment variables.
+var envSources = map[string]string{ //nolint:gochecknoglobals
+	"BaseURL":           EnvBaseURL,
+	"APIVersion":        EnvAPIVersion,
+	"AccessToken":       EnvAccessToken,
+	"PhoneNumberID":     EnvPhoneNumberID,
+	"BusinessAccountID": EnvBusinessAccountID,
+}
+
+func (config *Config) setDefaults() {
+	if config.BaseURL == "" {
+		config.BaseURL = whatsapp.BaseURL
+	}
+	if config.APIVersion == "" {
+		config.APIVersion = whatsapp.DefaultAPIVersion
+	}
+	if config.Webhook.Secret == "" {
+		config.Webhook.Secret = config.AppSecret
+	}
+}
+
+// Validate checks that the required values are set and well-formed. The returned error
+// joins a *FieldError per invalid value.
+func (config *Config) Validate() error {
+	return config.validate(nil)
+}
+
+func (config *Config) validate(sources map[string]string) error {
+	var errs []error
+	invalid := func(field, problem string) {
+		errs = append(errs, &FieldError{Field: field, Source: sources[field], Problem: problem})
+	}
+
+	if config.AccessToken == "" {
+		invalid("AccessToken", "is required, use a system user access token from the Business Manager")
+	}
+	if config.PhoneNumberID == "" {
+		invalid("PhoneNumberID", "is required, find it in the WhatsApp > API Setup page of the app")
+	} else if !isNumeric(config.PhoneNumberID) {
+		invalid("PhoneNumberID", fmt.Sprintf("%q is not a phone number ID, it is a number like "+
+			"106540352242922, not the phone number itself", config.PhoneNumberID))
+	}
+	if config.BusinessAccountID != "" && !isNumeric(config.BusinessAccountID) {
+		invalid("BusinessAccountID", fmt.Sprintf("%q is not a WhatsApp Business Account ID",
+			config.BusinessAccountID))
+	}
+	if err := whatsapp.ValidateVersion(config.APIVersion); err != nil {
+		invalid("APIVersion", err.Error())
+	}
+	if u, err := url.Parse(config.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		invalid("BaseURL", fmt.Sprintf("%q is not an absolute URL", config.BaseURL))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ClientOptions returns the options that configure a client with config.
+func (config *Config) ClientOptions() []whatsapp.ClientOption {
+	options := []whatsapp.ClientOption{
+		whatsapp.WithBaseURL(config.BaseURL),
+		whatsapp.WithVersion(config.APIVersion),
+		whatsapp.WithAccessToken(config.AccessToken),
+		whatsapp.WithPhoneNumberID(config.PhoneNumberID),
+		whatsapp.WithBusinessAccountID(config.BusinessAccountID),
+	}
+	if config.AppSecret != "" {
+		options = append(options, whatsapp.WithAppSecret(config.AppSecret))
+	}
+
+	return options
+}
+
+// HandlerOptions returns the options of the webhook handlers. The signature of the
+// notifications is validated when a webhook secret is configured.
+func (config *Config) HandlerOptions() *webhooks.HandlerOptions {
+	return &webhooks.HandlerOptions{
+		ValidateSignature: config.Webhook.Secret != "",
+		Secret:            config.Webhook.Secret,
+	}
+}
+
+// SubscriptionVerifier returns a webhooks.SubscriptionVerifier that accepts the subscribe
+// requests carrying the configured verify token.
+func (config *Config) SubscriptionVerifier() webhooks.SubscriptionVerifier {
+	token := config.Webhook.VerifyToken
+
+	return func(ctx context.Context, request *webhooks.VerificationRequest) error {
+		if request.Mode != "subscribe" || token == "" || request.Token != token {
+			return ErrInvalidVerifyToken
+		}
+
+		return nil
+	}
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return s != ""
+}
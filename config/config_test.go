@@ -0,0 +1,129 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package config
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestFromLookup(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		env      map[string]string
+		want     *Config
+		wantErrs []string
+	}{
+		{
+			name: "defaults",
+			env: map[string]string{
+				EnvAccessToken:   "token",
+				EnvPhoneNumberID: "106540352242922",
+				EnvAppSecret:     "secret",
+			},
+			want: &Config{
+				BaseURL:       whatsapp.BaseURL,
+				APIVersion:    whatsapp.DefaultAPIVersion,
+				AccessToken:   "token",
+				PhoneNumberID: "106540352242922",
+				AppSecret:     "secret",
+				Webhook:       Webhook{Secret: "secret"},
+			},
+		},
+		{
+			name: "all set",
+			env: map[string]string{
+				EnvBaseURL:            "https://graph.example.com",
+				EnvAPIVersion:         "v18.0",
+				EnvAccessToken:        " token ",
+				EnvPhoneNumberID:      "106540352242922",
+				EnvBusinessAccountID:  "102290129340398",
+				EnvWebhookVerifyToken: "verify",
+				EnvWebhookSecret:      "webhook_secret",
+			},
+			want: &Config{
+				BaseURL:           "https://graph.example.com",
+				APIVersion:        "v18.0",
+				AccessToken:       "token",
+				PhoneNumberID:     "106540352242922",
+				BusinessAccountID: "102290129340398",
+				Webhook:           Webhook{VerifyToken: "verify", Secret: "webhook_secret"},
+			},
+		},
+		{
+			name: "invalid",
+			env: map[string]string{
+				EnvAPIVersion:    "18",
+				EnvPhoneNumberID: "+255 700 000 000",
+			},
+			wantErrs: []string{EnvAccessToken, EnvPhoneNumberID, EnvAPIVersion},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config, err := FromLookup(func(key string) (string, bool) {
+				value, ok := tt.env[key]
+
+				return value, ok
+			})
+			if len(tt.wantErrs) > 0 {
+				if !errors.Is(err, ErrInvalidConfig) {
+					t.Fatalf("FromLookup() error = %v, want %v", err, ErrInvalidConfig)
+				}
+				for _, want := range tt.wantErrs {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("FromLookup() error = %v, want it to mention %s", err, want)
+					}
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromLookup() error = %v", err)
+			}
+			if *config != *tt.want {
+				t.Errorf("FromLookup() = %+v, want %+v", config, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigSubscriptionVerifier(t *testing.T) {
+	t.Parallel()
+	config := &Config{Webhook: Webhook{VerifyToken: "verify"}}
+	verify := config.SubscriptionVerifier()
+
+	if err := verify(context.TODO(), &webhooks.VerificationRequest{Mode: "subscribe", Token: "verify"}); err != nil {
+		t.Errorf("verify() error = %v", err)
+	}
+	err := verify(context.TODO(), &webhooks.VerificationRequest{Mode: "subscribe", Token: "other"})
+	if !errors.Is(err, ErrInvalidVerifyToken) {
+		t.Errorf("verify() error = %v, want %v", err, ErrInvalidVerifyToken)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// EnvProfile selects the profile loaded by LoadFile when none is given.
+const EnvProfile = "WHATSAPP_PROFILE"
+
+// ErrProfileNotFound is returned when the requested profile is not in the file.
+var ErrProfileNotFound = errors.New("configuration profile not found")
+
+type (
+	// File is a configuration file with named profiles, for example one per environment or
+	// per phone number. Defaults holds the values shared by all the profiles, a profile only
+	// sets the values that differ. DefaultProfile is used when no profile is requested.
+	//
+	// String values can reference environment variables as $VAR or ${VAR}, so that the
+	// credentials do not have to be written in the file:
+	//
+	//	{
+	//	  "default_profile": "staging",
+	//	  "defaults": {"api_version": "v18.0", "access_token": "${WHATSAPP_ACCESS_TOKEN}"},
+	//	  "profiles": {
+	//	    "staging": {"phone_number_id": "106540352242922"},
+	//	    "production-tz": {"phone_number_id": "107655329012345", "webhook": {"verify_token": "${VERIFY_TOKEN}"}},
+	//	    "production-ke": {"phone_number_id": "108912345678901"}
+	//	  }
+	//	}
+	File struct {
+		DefaultProfile string            `json:"default_profile,omitempty" yaml:"default_profile,omitempty"`
+		Defaults       Config            `json:"defaults,omitempty"        yaml:"defaults,omitempty"`
+		Profiles       map[string]Config `json:"profiles,omitempty"        yaml:"profiles,omitempty"`
+	}
+
+	// UnmarshalFunc decodes a configuration file, like json.Unmarshal. Pass yaml.Unmarshal
+	// from a YAML library to load YAML files.
+	UnmarshalFunc func(data []byte, v any) error
+)
+
+// LoadFile reads the configuration file at path and returns the validated configuration of
+// profile. When profile is empty, the profile named by WHATSAPP_PROFILE is used, then the
+// default profile of the file. If unmarshal is nil, the file is decoded as JSON.
+func LoadFile(path, profile string, unmarshal UnmarshalFunc) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	file, err := ParseFile(data, unmarshal)
+	if err != nil {
+		return nil, fmt.Errorf("load config %s: %w", path, err)
+	}
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	config, err := file.Profile(profile, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("load config %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// ParseFile decodes a configuration file. If unmarshal is nil, data is decoded as JSON.
+func ParseFile(data []byte, unmarshal UnmarshalFunc) (*File, error) {
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+	var file File
+	if err := unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse config file: %w", err)
+	}
+
+	return &file, nil
+}
+
+// ProfileNames returns the names of the profiles in the file, sorted.
+func (file *File) ProfileNames() []string {
+	names := make([]string, 0, len(file.Profiles))
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Profile returns the validated configuration of the named profile, merged with the
+// defaults. References to variables are expanded with lookup, a nil lookup leaves them
+// as they are. An empty name selects the default profile.
+func (file *File) Profile(name string, lookup LookupFunc) (*Config, error) {
+	if name == "" {
+		name = file.DefaultProfile
+	}
+	profile, ok := file.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q, the file has %v", ErrProfileNotFound, name, file.ProfileNames())
+	}
+
+	config := file.Defaults
+	config.merge(&profile)
+	if lookup != nil {
+		config.expand(lookup)
+	}
+	config.setDefaults()
+	if err := config.validate(profileSources(name)); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// merge sets the values of config that are set in other.
+func (config *Config) merge(other *Config) {
+	for _, field := range []struct{ dst, src *string }{
+		{&config.BaseURL, &other.BaseURL},
+		{&config.APIVersion, &other.APIVersion},
+		{&config.AccessToken, &other.AccessToken},
+		{&config.PhoneNumberID, &other.PhoneNumberID},
+		{&config.BusinessAccountID, &other.BusinessAccountID},
+		{&config.AppSecret, &other.AppSecret},
+		{&config.Webhook.VerifyToken, &other.Webhook.VerifyToken},
+		{&config.Webhook.Secret, &other.Webhook.Secret},
+	} {
+		if *field.src != "" {
+			*field.dst = *field.src
+		}
+	}
+}
+
+// expand replaces the references to variables in the values of config.
+func (config *Config) expand(lookup LookupFunc) {
+	mapping := func(key string) string {
+		value, _ := lookup(key)
+
+		return value
+	}
+	for _, value := range []*string{
+		&config.BaseURL, &config.APIVersion, &config.AccessToken, &config.PhoneNumberID,
+		&config.BusinessAccountID, &config.AppSecret, &config.Webhook.VerifyToken, &config.Webhook.Secret,
+	} {
+		*value = os.Expand(*value, mapping)
+	}
+}
+
+// profileSources maps the fields of Config to their keys in the profile.
+func profileSources(name string) map[string]string {
+	keys := map[string]string{
+		"BaseURL":           "base_url",
+		"APIVersion":        "api_version",
+		"AccessToken":       "access_token",
+		"PhoneNumberID":     "phone_number_id",
+		"BusinessAccountID": "business_account_id",
+	}
+	sources := make(map[string]string, len(keys))
+	for field, key := range keys {
+		sources[field] = fmt.Sprintf("profile %s: %s", name, key)
+	}
+
+	return sources
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp"
+)
+
+const testFile = `{
+  "default_profile": "staging",
+  "defaults": {
+    "api_version": "v18.0",
+    "access_token": "${TEST_ACCESS_TOKEN}",
+    "app_secret": "secret"
+  },
+  "profiles": {
+    "staging": {"phone_number_id": "106540352242922"},
+    "production": {
+      "phone_number_id": "107655329012345",
+      "business_account_id": "102290129340398",
+      "webhook": {"verify_token": "$TEST_VERIFY_TOKEN"}
+    },
+    "broken": {"phone_number_id": "+255 700 000 000"}
+  }
+}`
+
+func TestFileProfile(t *testing.T) {
+	t.Parallel()
+	file, err := ParseFile([]byte(testFile), nil)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := map[string]string{
+			"TEST_ACCESS_TOKEN": "token",
+			"TEST_VERIFY_TOKEN": "verify",
+		}[key]
+
+		return value, ok
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		want    *Config
+		wantErr error
+	}{
+		{
+			name: "default profile",
+			want: &Config{
+				BaseURL:       whatsapp.BaseURL,
+				APIVersion:    "v18.0",
+				AccessToken:   "token",
+				PhoneNumberID: "106540352242922",
+				AppSecret:     "secret",
+				Webhook:       Webhook{Secret: "secret"},
+			},
+		},
+		{
+			name:    "named profile",
+			profile: "production",
+			want: &Config{
+				BaseURL:           whatsapp.BaseURL,
+				APIVersion:        "v18.0",
+				AccessToken:       "token",
+				PhoneNumberID:     "107655329012345",
+				BusinessAccountID: "102290129340398",
+				AppSecret:         "secret",
+				Webhook:           Webhook{VerifyToken: "verify", Secret: "secret"},
+			},
+		},
+		{
+			name:    "invalid profile",
+			profile: "broken",
+			wantErr: ErrInvalidConfig,
+		},
+		{
+			name:    "missing profile",
+			profile: "development",
+			wantErr: ErrProfileNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config, err := file.Profile(tt.profile, lookup)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Profile() error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Profile() error = %v", err)
+			}
+			if *config != *tt.want {
+				t.Errorf("Profile() = %+v, want %+v", config, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "whatsapp.json")
+	data := strings.ReplaceAll(testFile, "${TEST_ACCESS_TOKEN}", "token")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadFile(path, "production", nil)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if config.PhoneNumberID != "107655329012345" || config.AccessToken != "token" {
+		t.Errorf("LoadFile() = %+v", config)
+	}
+
+	_, err = LoadFile(path, "broken", nil)
+	if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), "profile broken: phone_number_id") {
+		t.Errorf("LoadFile() error = %v, want the invalid field of the profile", err)
+	}
+}
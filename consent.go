@@ -0,0 +1,314 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/SeamPay/whatsapp/clock"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+var (
+	// ErrOptedOut is returned when a marketing message is sent to a recipient that opted out.
+	ErrOptedOut = errors.New("recipient opted out of marketing messages")
+
+	// ErrConsentUnchecked is returned when the recipient of a marketing message cannot be
+	// read from its payload, for example a streamed payload, and its consent not checked.
+	ErrConsentUnchecked = errors.New("consent of the recipient of a marketing message cannot be checked")
+)
+
+// Keywords used when ConsentConfig does not set any. A message opts out or in when its whole
+// text, ignoring case and surrounding punctuation, is one of the keywords.
+var (
+	DefaultOptOutKeywords = []string{"STOP", "STOPALL", "UNSUBSCRIBE", "CANCEL", "END", "QUIT"} //nolint:gochecknoglobals
+	DefaultOptInKeywords  = []string{"START", "SUBSCRIBE", "UNSTOP"}                            //nolint:gochecknoglobals
+)
+
+type (
+	// ConsentStore stores the recipients that opted out of marketing messages.
+	// Implementations must be safe for concurrent use.
+	ConsentStore interface {
+		// OptOut records that waID opted out at the given time.
+		OptOut(ctx context.Context, waID string, at time.Time) error
+
+		// OptIn records that waID opted back in.
+		OptIn(ctx context.Context, waID string) error
+
+		// IsOptedOut reports whether waID opted out.
+		IsOptedOut(ctx context.Context, waID string) (bool, error)
+	}
+
+	// MarketingFunc reports whether a message is a marketing message. recipient is the "to"
+	// of the message, messageType its type and templateName the name of the template of
+	// template messages. They are all empty when the payload of the message cannot be read.
+	MarketingFunc func(ctx context.Context, recipient, messageType, templateName string) bool
+
+	// ConsentChangeFunc is called when a recipient opts out or back in from a keyword.
+	ConsentChangeFunc func(ctx context.Context, waID string, optedOut bool)
+
+	// ConsentConfig configures a ConsentManager.
+	//
+	// Store defaults to a MemoryConsentStore and Clock to the system clock. OptOutKeywords
+	// and OptInKeywords default to DefaultOptOutKeywords and DefaultOptInKeywords.
+	// IsMarketing defaults to MarketingFromContext, only the sends marked with WithMarketing
+	// and the sends of SendMarketingTemplate are blocked. OnChange, when set, is called after
+	// a keyword changed the consent, for example to confirm the opt-out to the customer.
+	ConsentConfig struct {
+		Store          ConsentStore
+		Clock          clock.Clock
+		OptOutKeywords []string
+		OptInKeywords  []string
+		IsMarketing    MarketingFunc
+		OnChange       ConsentChangeFunc
+	}
+
+	// ConsentManager blocks the marketing messages sent to recipients that opted out and
+	// records the opt-outs and opt-ins from the keywords customers send:
+	//
+	//	consent := whatsapp.NewConsentManager(&whatsapp.ConsentConfig{Store: store})
+	//	client := whatsapp.NewClient(whatsapp.WithConsent(consent), ...)
+	//	listener.OnMessageReceived(consent.OnMessageReceived)
+	//
+	//	_, err := client.SendTemplate(whatsapp.WithMarketing(ctx), recipient, promotion)
+	//	if errors.Is(err, whatsapp.ErrOptedOut) {
+	//		// the recipient replied STOP
+	//	}
+	ConsentManager struct {
+		store       ConsentStore
+		clock       clock.Clock
+		optOut      map[string]bool
+		optIn       map[string]bool
+		isMarketing MarketingFunc
+		onChange    ConsentChangeFunc
+	}
+
+	// MemoryConsentStore is an in-memory ConsentStore.
+	MemoryConsentStore struct {
+		mu      sync.Mutex
+		entries map[string]time.Time
+	}
+
+	marketingKey struct{}
+)
+
+// WithMarketing returns a context marking the messages sent with it as marketing messages,
+// see MarketingFromContext.
+func WithMarketing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, marketingKey{}, true)
+}
+
+// MarketingFromContext is a MarketingFunc that reports whether ctx was marked with
+// WithMarketing.
+func MarketingFromContext(ctx context.Context, _, _, _ string) bool {
+	marketing, _ := ctx.Value(marketingKey{}).(bool)
+
+	return marketing
+}
+
+// WithConsent blocks the marketing messages sent to the recipients that opted out, see
+// ConsentManager. Blocked sends fail with ErrOptedOut without reaching the API.
+func WithConsent(consent *ConsentManager) ClientOption {
+	return func(client *Client) {
+		client.consent = consent
+	}
+}
+
+// NewConsentManager returns a ConsentManager configured with config, which may be nil.
+func NewConsentManager(config *ConsentConfig) *ConsentManager {
+	if config == nil {
+		config = &ConsentConfig{}
+	}
+	consent := &ConsentManager{
+		store:       config.Store,
+		clock:       clock.OrSystem(config.Clock),
+		optOut:      keywordSet(config.OptOutKeywords, DefaultOptOutKeywords),
+		optIn:       keywordSet(config.OptInKeywords, DefaultOptInKeywords),
+		isMarketing: config.IsMarketing,
+		onChange:    config.OnChange,
+	}
+	if consent.store == nil {
+		consent.store = NewMemoryConsentStore()
+	}
+	if consent.isMarketing == nil {
+		consent.isMarketing = MarketingFromContext
+	}
+
+	return consent
+}
+
+func keywordSet(keywords, defaults []string) map[string]bool {
+	if len(keywords) == 0 {
+		keywords = defaults
+	}
+	set := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		set[normalizeKeyword(keyword)] = true
+	}
+
+	return set
+}
+
+func normalizeKeyword(s string) string {
+	return strings.ToUpper(strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}))
+}
+
+// OptOut records that waID opted out.
+func (consent *ConsentManager) OptOut(ctx context.Context, waID string) error {
+	if err := consent.store.OptOut(ctx, waID, consent.clock.Now()); err != nil {
+		return fmt.Errorf("consent: %w", err)
+	}
+
+	return nil
+}
+
+// OptIn records that waID opted back in.
+func (consent *ConsentManager) OptIn(ctx context.Context, waID string) error {
+	if err := consent.store.OptIn(ctx, waID); err != nil {
+		return fmt.Errorf("consent: %w", err)
+	}
+
+	return nil
+}
+
+// IsOptedOut reports whether waID opted out.
+func (consent *ConsentManager) IsOptedOut(ctx context.Context, waID string) (bool, error) {
+	optedOut, err := consent.store.IsOptedOut(ctx, waID)
+	if err != nil {
+		return false, fmt.Errorf("consent: %w", err)
+	}
+
+	return optedOut, nil
+}
+
+// OnMessageReceived is a webhooks.OnMessageReceivedHook that records the opt-outs and
+// opt-ins from the text messages and quick reply buttons matching the keywords.
+func (consent *ConsentManager) OnMessageReceived(ctx context.Context, _ *webhooks.NotificationContext,
+	message *webhooks.Message,
+) error {
+	if message == nil || message.From == "" {
+		return nil
+	}
+	var text string
+	switch {
+	case message.Text != nil:
+		text = message.Text.Body
+	case message.Button != nil:
+		text = message.Button.Text
+	default:
+		return nil
+	}
+
+	keyword := normalizeKeyword(text)
+	var err error
+	switch {
+	case consent.optOut[keyword]:
+		err = consent.OptOut(ctx, message.From)
+	case consent.optIn[keyword]:
+		err = consent.OptIn(ctx, message.From)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if consent.onChange != nil {
+		consent.onChange(ctx, message.From, consent.optOut[keyword])
+	}
+
+	return nil
+}
+
+// Middleware returns the middleware that blocks the marketing messages to the recipients
+// that opted out, it is installed by WithConsent. A marketing message whose recipient cannot
+// be read from the payload is blocked with ErrConsentUnchecked.
+func (consent *ConsentManager) Middleware() whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			if !isMessageRequest(request) {
+				return next.Send(ctx, request, v)
+			}
+			payload, ok := auditPayloadOf(request)
+			if !ok || payload.To == "" {
+				if isMarketingRequest(request) || consent.isMarketing(ctx, "", "", "") {
+					return ErrConsentUnchecked
+				}
+
+				return next.Send(ctx, request, v)
+			}
+			var templateName string
+			if payload.Template != nil {
+				templateName = payload.Template.Name
+			}
+			if !isMarketingRequest(request) && !consent.isMarketing(ctx, payload.To, payload.Type, templateName) {
+				return next.Send(ctx, request, v)
+			}
+
+			// the webhooks carry the wa_id without the leading + of international numbers
+			optedOut, err := consent.IsOptedOut(ctx, strings.TrimPrefix(payload.To, "+"))
+			if err != nil {
+				return err
+			}
+			if optedOut {
+				return fmt.Errorf("%w: %s", ErrOptedOut, payload.To)
+			}
+
+			return next.Send(ctx, request, v)
+		})
+	}
+}
+
+// NewMemoryConsentStore returns an empty MemoryConsentStore.
+func NewMemoryConsentStore() *MemoryConsentStore {
+	return &MemoryConsentStore{entries: make(map[string]time.Time)}
+}
+
+func (store *MemoryConsentStore) OptOut(_ context.Context, waID string, at time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.entries[waID] = at
+
+	return nil
+}
+
+func (store *MemoryConsentStore) OptIn(_ context.Context, waID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.entries, waID)
+
+	return nil
+}
+
+func (store *MemoryConsentStore) IsOptedOut(_ context.Context, waID string) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	_, ok := store.entries[waID]
+
+	return ok, nil
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestConsentManager(t *testing.T) {
+	t.Parallel()
+	var sends int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&sends, 1)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	var changes []bool
+	consent := NewConsentManager(&ConsentConfig{
+		OnChange: func(ctx context.Context, waID string, optedOut bool) {
+			changes = append(changes, optedOut)
+		},
+	})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithConsent(consent))
+	ctx := context.Background()
+	promotion := &Template{Name: "promo", LanguageCode: "en"}
+
+	receive := func(text string) {
+		t.Helper()
+		message := &webhooks.Message{From: "255700000001", Type: "text", Text: &webhooks.Text{Body: text}}
+		if err := consent.OnMessageReceived(ctx, nil, message); err != nil {
+			t.Fatalf("OnMessageReceived() error = %v", err)
+		}
+	}
+
+	receive("please stop sending me these")
+	receive(" Stop! ")
+	if optedOut, _ := consent.IsOptedOut(ctx, "255700000001"); !optedOut {
+		t.Fatal("IsOptedOut() = false after STOP, want true")
+	}
+
+	if _, err := client.SendTemplate(WithMarketing(ctx), "+255700000001", promotion); !errors.Is(err, ErrOptedOut) {
+		t.Errorf("SendTemplate(marketing) error = %v, want %v", err, ErrOptedOut)
+	}
+	if _, err := client.SendText(ctx, "255700000001", "your order shipped"); err != nil {
+		t.Errorf("SendText() error = %v, only marketing messages are blocked", err)
+	}
+	if _, err := client.SendTemplate(WithMarketing(ctx), "255700000002", promotion); err != nil {
+		t.Errorf("SendTemplate(marketing) to another recipient error = %v", err)
+	}
+
+	receive("start")
+	if _, err := client.SendTemplate(WithMarketing(ctx), "255700000001", promotion); err != nil {
+		t.Errorf("SendTemplate(marketing) after START error = %v", err)
+	}
+
+	if got := atomic.LoadInt32(&sends); got != 3 {
+		t.Errorf("sends = %d, want 3", got)
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("changes = %v, want [true false]", changes)
+	}
+}
+
+func TestConsentMiddlewareUnreadablePayload(t *testing.T) {
+	t.Parallel()
+	var sends int
+	next := whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+		sends++
+
+		return nil
+	})
+	send := NewConsentManager(nil).Middleware()(next)
+	streamed := func(endpoint string) *whttp.Request {
+		return &whttp.Request{
+			Context: &whttp.RequestContext{Name: "send", Endpoints: []string{endpoint}},
+			Method:  http.MethodPost,
+			Payload: strings.NewReader(`{"messaging_product":"whatsapp","to":"255700000001","type":"template"}`),
+		}
+	}
+
+	background := context.Background()
+	marketing := WithMarketing(background)
+	tests := []struct {
+		name      string
+		ctx       context.Context //nolint:containedctx
+		endpoint  string
+		wantErr   error
+		wantSends int
+	}{
+		{name: "marketing context", ctx: marketing, endpoint: "messages", wantErr: ErrConsentUnchecked},
+		{name: "marketing endpoint", ctx: background, endpoint: marketingMessagesEndpoint, wantErr: ErrConsentUnchecked},
+		{name: "other messages", ctx: background, endpoint: "messages", wantSends: 1},
+	}
+	for _, tt := range tests {
+		sends = 0
+		if err := send.Send(tt.ctx, streamed(tt.endpoint), nil); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Send() error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if sends != tt.wantSends {
+			t.Errorf("%s: sends = %d, want %d", tt.name, sends, tt.wantSends)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// ErrNoInboundMessage is returned by the Conversation methods that act on the last message
+// received from the customer when none was received yet.
+var ErrNoInboundMessage = errors.New("conversation: no inbound message")
+
+type (
+	// Conversation is a conversation with a customer, identified by their WhatsApp ID. It
+	// remembers the last message received from the customer, so that bot code can reply to
+	// it, react to it and mark it as read without threading the message IDs around:
+	//
+	//	listener.OnMessageReceived(func(ctx context.Context, nctx *webhooks.NotificationContext,
+	//		message *webhooks.Message,
+	//	) error {
+	//		conversation := client.Conversation(message.From, whatsapp.WithConversationWindow(tracker))
+	//		if err := conversation.Receive(ctx, message); err != nil {
+	//			return err
+	//		}
+	//		if _, err := conversation.MarkRead(ctx); err != nil {
+	//			return err
+	//		}
+	//		_, err := conversation.ReplyText(ctx, "Got it!")
+	//
+	//		return err
+	//	})
+	//
+	// The customer service window is read from the WindowTracker set with
+	// WithConversationWindow, or with WithWindowTracker on the client, which may be shared
+	// with the other conversations and instances, or else from the messages received by the
+	// conversation itself.
+	//
+	// A Conversation is safe for concurrent use.
+	Conversation struct {
+		client  *Client
+		waID    string
+		tracker *WindowTracker
+		clock   clock.Clock
+
+		mu            sync.Mutex
+		lastInbound   string
+		lastInboundAt time.Time
+	}
+
+	// ConversationOption configures a Conversation.
+	ConversationOption func(*Conversation)
+)
+
+// WithConversationWindow sets the WindowTracker the conversation records the received
+// messages in and reads its customer service window from.
+func WithConversationWindow(tracker *WindowTracker) ConversationOption {
+	return func(conversation *Conversation) {
+		conversation.tracker = tracker
+	}
+}
+
+// WithConversationClock sets the clock used to tell whether the customer service window is
+// open when the conversation has no WindowTracker.
+func WithConversationClock(clk clock.Clock) ConversationOption {
+	return func(conversation *Conversation) {
+		conversation.clock = clk
+	}
+}
+
+// Conversation returns a Conversation with the customer waID.
+func (client *Client) Conversation(waID string, options ...ConversationOption) *Conversation {
+	conversation := &Conversation{client: client, waID: waID, tracker: client.windows}
+	for _, option := range options {
+		option(conversation)
+	}
+	conversation.clock = clock.OrSystem(conversation.clock)
+
+	return conversation
+}
+
+// WaID returns the WhatsApp ID of the customer.
+func (conversation *Conversation) WaID() string {
+	return conversation.waID
+}
+
+// Receive records a message received from the customer, it becomes the last inbound message
+// unless it is older than the one already recorded. The message is also recorded in the
+// WindowTracker, if any.
+func (conversation *Conversation) Receive(ctx context.Context, message *webhooks.Message) error {
+	if message == nil {
+		return nil
+	}
+	at := receivedAt(message, conversation.clock)
+
+	conversation.mu.Lock()
+	if conversation.lastInbound == "" || !at.Before(conversation.lastInboundAt) {
+		conversation.lastInbound = message.ID
+		conversation.lastInboundAt = at
+	}
+	conversation.mu.Unlock()
+
+	if conversation.tracker != nil {
+		return conversation.tracker.OnMessageReceived(ctx, nil, message)
+	}
+
+	return nil
+}
+
+// LastInbound returns the ID of the last message received from the customer and when it was
+// sent, the ID is empty when no message was received.
+func (conversation *Conversation) LastInbound() (string, time.Time) {
+	conversation.mu.Lock()
+	defer conversation.mu.Unlock()
+
+	return conversation.lastInbound, conversation.lastInboundAt
+}
+
+// WindowRemaining returns how long the customer service window stays open, it is zero when
+// the window is closed.
+func (conversation *Conversation) WindowRemaining(ctx context.Context) (time.Duration, error) {
+	if conversation.tracker != nil {
+		return conversation.tracker.TimeRemaining(ctx, conversation.waID)
+	}
+
+	id, at := conversation.LastInbound()
+	if id == "" {
+		return 0, nil
+	}
+	remaining := at.Add(CustomerServiceWindow).Sub(conversation.clock.Now())
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// IsWindowOpen reports whether free-form messages can be sent to the customer.
+func (conversation *Conversation) IsWindowOpen(ctx context.Context) (bool, error) {
+	remaining, err := conversation.WindowRemaining(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return remaining > 0, nil
+}
+
+// Send sends message to the customer, its recipient is replaced by the WhatsApp ID of the
+// conversation.
+func (conversation *Conversation) Send(ctx context.Context, message *models.Message,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	if message == nil {
+		return nil, fmt.Errorf("conversation: %w: nil message", ErrBadRequestFormat)
+	}
+	payload := *message
+	payload.To = conversation.waID
+
+	return conversation.client.SendMessage(ctx, &payload, options...)
+}
+
+// SendText sends a text message to the customer.
+func (conversation *Conversation) SendText(ctx context.Context, text string,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	return conversation.Send(ctx, models.NewMessage(conversation.waID, models.WithText(text)), options...)
+}
+
+// Reply sends message as a reply to the last message received from the customer, it returns
+// ErrNoInboundMessage when none was received.
+func (conversation *Conversation) Reply(ctx context.Context, message *models.Message,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	id, _ := conversation.LastInbound()
+	if id == "" {
+		return nil, ErrNoInboundMessage
+	}
+	if message == nil {
+		return nil, fmt.Errorf("conversation: %w: nil message", ErrBadRequestFormat)
+	}
+	payload := *message
+	payload.Context = &models.Context{MessageID: id}
+
+	return conversation.Send(ctx, &payload, options...)
+}
+
+// ReplyText replies to the last message received from the customer with a text message.
+func (conversation *Conversation) ReplyText(ctx context.Context, text string,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	return conversation.Reply(ctx, models.NewMessage(conversation.waID, models.WithText(text)), options...)
+}
+
+// React reacts with emoji to the last message received from the customer, an empty emoji
+// removes the reaction.
+func (conversation *Conversation) React(ctx context.Context, emoji string,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	id, _ := conversation.LastInbound()
+	if id == "" {
+		return nil, ErrNoInboundMessage
+	}
+
+	return conversation.client.React(ctx, conversation.waID, &ReactMessage{MessageID: id, Emoji: emoji}, options...)
+}
+
+// MarkRead marks the last message received from the customer, and so the ones before it,
+// as read.
+func (conversation *Conversation) MarkRead(ctx context.Context) (*StatusResponse, error) {
+	id, _ := conversation.LastInbound()
+	if id == "" {
+		return nil, ErrNoInboundMessage
+	}
+
+	return conversation.client.MarkMessageRead(ctx, id)
+}
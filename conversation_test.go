@@ -0,0 +1,146 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestConversation(t *testing.T) {
+	t.Parallel()
+	var (
+		mu       sync.Mutex
+		payloads []map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.out"}],"success":true}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithAccessToken("token"))
+	conversation := client.Conversation("255700000000", WithConversationClock(clock.NewFake(now)))
+
+	if _, err := conversation.ReplyText(ctx, "hi"); !errors.Is(err, ErrNoInboundMessage) {
+		t.Fatalf("ReplyText() before any message error = %v, want %v", err, ErrNoInboundMessage)
+	}
+	if open, err := conversation.IsWindowOpen(ctx); err != nil || open {
+		t.Fatalf("IsWindowOpen() = %v, %v, want false before any message", open, err)
+	}
+
+	received := func(id string, at time.Time) {
+		t.Helper()
+		message := &webhooks.Message{
+			ID:        id,
+			From:      "255700000000",
+			Type:      "text",
+			Timestamp: strconv.FormatInt(at.Unix(), 10),
+		}
+		if err := conversation.Receive(ctx, message); err != nil {
+			t.Fatalf("Receive() error = %v", err)
+		}
+	}
+	received("wamid.2", now.Add(-time.Hour))
+	// an older message delivered late is not the last inbound message
+	received("wamid.1", now.Add(-2*time.Hour))
+
+	if id, _ := conversation.LastInbound(); id != "wamid.2" {
+		t.Fatalf("LastInbound() = %s, want wamid.2", id)
+	}
+	if remaining, err := conversation.WindowRemaining(ctx); err != nil || remaining != 23*time.Hour {
+		t.Fatalf("WindowRemaining() = %v, %v, want 23h", remaining, err)
+	}
+
+	if _, err := conversation.ReplyText(ctx, "hello"); err != nil {
+		t.Fatalf("ReplyText() error = %v", err)
+	}
+	if _, err := conversation.React(ctx, "👍"); err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+	if _, err := conversation.MarkRead(ctx); err != nil {
+		t.Fatalf("MarkRead() error = %v", err)
+	}
+	if _, err := conversation.SendText(ctx, "bye"); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(payloads) != 4 {
+		t.Fatalf("got %d requests, want 4", len(payloads))
+	}
+	reply, reaction, read, text := payloads[0], payloads[1], payloads[2], payloads[3]
+	if reply["to"] != "255700000000" || reply["context"].(map[string]any)["message_id"] != "wamid.2" {
+		t.Errorf("reply payload = %v", reply)
+	}
+	if reaction["reaction"].(map[string]any)["message_id"] != "wamid.2" {
+		t.Errorf("reaction payload = %v", reaction)
+	}
+	if read["status"] != "read" || read["message_id"] != "wamid.2" {
+		t.Errorf("mark read payload = %v", read)
+	}
+	if text["to"] != "255700000000" || text["context"] != nil {
+		t.Errorf("text payload = %v", text)
+	}
+}
+
+func TestConversation_WindowTracker(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewWindowTracker(&WindowTrackerConfig{Clock: clock.NewFake(now)})
+	client := NewClient()
+
+	// the window is shared through the tracker by the conversations with the same customer
+	first := client.Conversation("255700000000", WithConversationWindow(tracker))
+	second := client.Conversation("255700000000", WithConversationWindow(tracker))
+	message := &webhooks.Message{
+		ID:        "wamid.1",
+		From:      "255700000000",
+		Type:      "text",
+		Timestamp: strconv.FormatInt(now.Add(-30*time.Minute).Unix(), 10),
+	}
+	if err := first.Receive(ctx, message); err != nil {
+		t.Fatalf("Receive() error = %v", err)
+	}
+	if open, err := second.IsWindowOpen(ctx); err != nil || !open {
+		t.Errorf("IsWindowOpen() = %v, %v, want true", open, err)
+	}
+	if id, _ := second.LastInbound(); id != "" {
+		t.Errorf("LastInbound() = %s, want none", id)
+	}
+}
@@ -0,0 +1,426 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+type (
+	// Correlation is the metadata registered for a reply ID of an outbound message: the ID of
+	// a reply button or of a list row of an interactive message, or the payload of a quick
+	// reply button of a template message.
+	Correlation struct {
+		MessageID string
+		Recipient string
+		ReplyID   string
+		Metadata  map[string]string
+		CreatedAt time.Time
+	}
+
+	// CorrelationStore stores the correlations by message ID and reply ID. Implementations
+	// must be safe for concurrent use.
+	CorrelationStore interface {
+		// Put stores correlation, replacing the one with the same message ID and reply ID.
+		Put(ctx context.Context, correlation *Correlation) error
+
+		// Get returns the correlation of replyID in the message messageID. found is false
+		// if there is none.
+		Get(ctx context.Context, messageID, replyID string) (correlation *Correlation, found bool, err error)
+
+		// DeleteBefore deletes the correlations created before t and returns how many were
+		// deleted.
+		DeleteBefore(ctx context.Context, t time.Time) (int, error)
+
+		// DeleteRecipient deletes the correlations of the messages sent to the WhatsApp ID
+		// waID and returns how many were deleted.
+		DeleteRecipient(ctx context.Context, waID string) (int, error)
+	}
+
+	// CorrelationConfig configures a CorrelationTracker. Store defaults to a
+	// MemoryCorrelationStore and Clock to the system clock. MaxAge is how long correlations
+	// are kept, Evict deletes the older ones, zero keeps them forever.
+	CorrelationConfig struct {
+		Store  CorrelationStore
+		Clock  clock.Clock
+		MaxAge time.Duration
+	}
+
+	// CorrelatedReply is a reply of a customer to an outbound message. ReplyID is the ID of the
+	// button or row, or the quick reply payload, and Title its text. Correlation is nil when
+	// no metadata was registered for the reply.
+	CorrelatedReply struct {
+		MessageID   string
+		ReplyID     string
+		Title       string
+		Correlation *Correlation
+	}
+
+	// CorrelatedReplyHook is called with the replies to the outbound messages.
+	CorrelatedReplyHook func(ctx context.Context, nctx *webhooks.NotificationContext,
+		mctx *webhooks.MessageContext, reply *CorrelatedReply) error
+
+	// CorrelationTracker registers metadata for the reply IDs of the outbound interactive
+	// messages and template quick reply buttons, and passes it to the hooks handling the
+	// replies, so that the state needed to handle a reply does not have to be encoded in
+	// its ID:
+	//
+	//	correlations := whatsapp.NewCorrelationTracker(nil)
+	//	client := whatsapp.NewClient(whatsapp.WithCorrelationTracker(correlations), ...)
+	//	ctx = whatsapp.WithCorrelationMetadata(ctx, map[string]string{"order": "1234"})
+	//	client.SendInteractiveMessage(ctx, recipient, confirmation)
+	//	listener.OnInteractiveMessage(correlations.InteractiveHook(handleReply))
+	//
+	// Only the messages sent with a context carrying metadata are registered.
+	CorrelationTracker struct {
+		store  CorrelationStore
+		clock  clock.Clock
+		maxAge time.Duration
+	}
+
+	// MemoryCorrelationStore is an in-memory CorrelationStore.
+	MemoryCorrelationStore struct {
+		mu      sync.Mutex
+		entries map[correlationKey]*Correlation
+	}
+
+	correlationKey struct {
+		messageID string
+		replyID   string
+	}
+
+	correlationMetadataKey struct{}
+
+	// correlationPayload holds the fields of a message payload that carry reply IDs.
+	correlationPayload struct {
+		To          string `json:"to"`
+		Interactive *struct {
+			Action *struct {
+				Buttons []*struct {
+					ID    string `json:"id"`
+					Reply *struct {
+						ID string `json:"id"`
+					} `json:"reply"`
+				} `json:"buttons"`
+				Sections []*struct {
+					Rows []*struct {
+						ID string `json:"id"`
+					} `json:"rows"`
+				} `json:"sections"`
+			} `json:"action"`
+		} `json:"interactive"`
+		Template *struct {
+			Components []*struct {
+				Type       string `json:"type"`
+				SubType    string `json:"sub_type"`
+				Parameters []*struct {
+					Type    string `json:"type"`
+					Payload string `json:"payload"`
+				} `json:"parameters"`
+			} `json:"components"`
+		} `json:"template"`
+	}
+)
+
+// WithCorrelationMetadata returns a context that registers metadata for the reply IDs of the
+// messages sent with it, see CorrelationTracker.
+func WithCorrelationMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	return context.WithValue(ctx, correlationMetadataKey{}, metadata)
+}
+
+// CorrelationMetadataFromContext returns the metadata set with WithCorrelationMetadata.
+func CorrelationMetadataFromContext(ctx context.Context) (map[string]string, bool) {
+	metadata, ok := ctx.Value(correlationMetadataKey{}).(map[string]string)
+
+	return metadata, ok && metadata != nil
+}
+
+// WithCorrelationTracker registers the reply IDs of the messages sent with correlation
+// metadata in tracker.
+func WithCorrelationTracker(tracker *CorrelationTracker) ClientOption {
+	return func(client *Client) {
+		client.correlations = tracker
+	}
+}
+
+// NewCorrelationTracker returns a CorrelationTracker configured with config, which may be nil.
+func NewCorrelationTracker(config *CorrelationConfig) *CorrelationTracker {
+	if config == nil {
+		config = &CorrelationConfig{}
+	}
+	tracker := &CorrelationTracker{
+		store:  config.Store,
+		clock:  clock.OrSystem(config.Clock),
+		maxAge: config.MaxAge,
+	}
+	if tracker.store == nil {
+		tracker.store = NewMemoryCorrelationStore()
+	}
+
+	return tracker
+}
+
+// Register registers metadata for the reply IDs of the message messageID sent to recipient.
+func (tracker *CorrelationTracker) Register(ctx context.Context, messageID, recipient string,
+	metadata map[string]string, replyIDs ...string,
+) error {
+	now := tracker.clock.Now()
+	for _, replyID := range replyIDs {
+		err := tracker.store.Put(ctx, &Correlation{
+			MessageID: messageID,
+			Recipient: recipient,
+			ReplyID:   replyID,
+			Metadata:  metadata,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("correlation tracker: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Resolve returns the correlation registered for replyID in the message messageID.
+func (tracker *CorrelationTracker) Resolve(ctx context.Context, messageID, replyID string,
+) (*Correlation, bool, error) {
+	if messageID == "" || replyID == "" {
+		return nil, false, nil
+	}
+	correlation, found, err := tracker.store.Get(ctx, messageID, replyID)
+	if err != nil {
+		return nil, false, fmt.Errorf("correlation tracker: %w", err)
+	}
+
+	return correlation, found, nil
+}
+
+// Evict deletes the correlations older than the configured MaxAge and returns how many
+// were deleted.
+func (tracker *CorrelationTracker) Evict(ctx context.Context) (int, error) {
+	if tracker.maxAge <= 0 {
+		return 0, nil
+	}
+	deleted, err := tracker.store.DeleteBefore(ctx, tracker.clock.Now().Add(-tracker.maxAge))
+	if err != nil {
+		return deleted, fmt.Errorf("correlation tracker: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Forget deletes the correlations of the messages sent to waID and returns how many were
+// deleted.
+func (tracker *CorrelationTracker) Forget(ctx context.Context, waID string) (int, error) {
+	deleted, err := tracker.store.DeleteRecipient(ctx, strings.TrimPrefix(waID, "+"))
+	if err != nil {
+		return deleted, fmt.Errorf("correlation tracker: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Middleware returns the middleware that registers the reply IDs of the messages sent with
+// correlation metadata once the API accepted them. A failure to register is returned, the
+// message was sent nonetheless.
+func (tracker *CorrelationTracker) Middleware() whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			metadata, ok := CorrelationMetadataFromContext(ctx)
+			if !ok || !isMessageRequest(request) {
+				return next.Send(ctx, request, v)
+			}
+			recipient, replyIDs := replyIDsOf(request)
+			if len(replyIDs) == 0 {
+				return next.Send(ctx, request, v)
+			}
+
+			if err := next.Send(ctx, request, v); err != nil {
+				return err
+			}
+			message, ok := v.(*ResponseMessage)
+			if !ok || len(message.Messages) == 0 || message.Messages[0] == nil {
+				return nil
+			}
+
+			return tracker.Register(ctx, message.Messages[0].ID, strings.TrimPrefix(recipient, "+"),
+				metadata, replyIDs...)
+		})
+	}
+}
+
+// InteractiveHook returns a webhooks.OnInteractiveMessageHook that calls hook with the
+// replies to the interactive messages and their correlation.
+func (tracker *CorrelationTracker) InteractiveHook(hook CorrelatedReplyHook) webhooks.OnInteractiveMessageHook {
+	return func(ctx context.Context, nctx *webhooks.NotificationContext, mctx *webhooks.MessageContext,
+		interactive *webhooks.Interactive,
+	) error {
+		reply := &CorrelatedReply{ReplyID: interactive.ReplyID()}
+		switch {
+		case interactive.ButtonReply != nil:
+			reply.Title = interactive.ButtonReply.Title
+		case interactive.ListReply != nil:
+			reply.Title = interactive.ListReply.Title
+		}
+
+		return tracker.handleReply(ctx, nctx, mctx, reply, hook)
+	}
+}
+
+// ButtonHook returns a webhooks.OnButtonMessageHook that calls hook with the replies to the
+// quick reply buttons of template messages and their correlation.
+func (tracker *CorrelationTracker) ButtonHook(hook CorrelatedReplyHook) webhooks.OnButtonMessageHook {
+	return func(ctx context.Context, nctx *webhooks.NotificationContext, mctx *webhooks.MessageContext,
+		button *webhooks.Button,
+	) error {
+		return tracker.handleReply(ctx, nctx, mctx, &CorrelatedReply{ReplyID: button.Payload, Title: button.Text}, hook)
+	}
+}
+
+func (tracker *CorrelationTracker) handleReply(ctx context.Context, nctx *webhooks.NotificationContext,
+	mctx *webhooks.MessageContext, reply *CorrelatedReply, hook CorrelatedReplyHook,
+) error {
+	if mctx != nil && mctx.Ctx != nil {
+		reply.MessageID = mctx.Ctx.ID
+	}
+	correlation, found, err := tracker.Resolve(ctx, reply.MessageID, reply.ReplyID)
+	if err != nil {
+		return err
+	}
+	if found {
+		reply.Correlation = correlation
+	}
+
+	return hook(ctx, nctx, mctx, reply)
+}
+
+// replyIDsOf returns the recipient and the reply IDs of the message payload. Streamed
+// payloads are not read, as that would consume them.
+func replyIDsOf(request *whttp.Request) (string, []string) {
+	if _, ok := request.Payload.(io.Reader); ok {
+		return "", nil
+	}
+	body, err := request.BodyBytes()
+	if err != nil {
+		return "", nil
+	}
+	var payload correlationPayload
+	if err = json.Unmarshal(body, &payload); err != nil {
+		return "", nil
+	}
+
+	var ids []string
+	if payload.Interactive != nil && payload.Interactive.Action != nil {
+		for _, button := range payload.Interactive.Action.Buttons {
+			switch {
+			case button.Reply != nil && button.Reply.ID != "":
+				ids = append(ids, button.Reply.ID)
+			case button.ID != "":
+				ids = append(ids, button.ID)
+			}
+		}
+		for _, section := range payload.Interactive.Action.Sections {
+			for _, row := range section.Rows {
+				if row.ID != "" {
+					ids = append(ids, row.ID)
+				}
+			}
+		}
+	}
+	if payload.Template != nil {
+		for _, component := range payload.Template.Components {
+			if component.Type != "button" || component.SubType != "quick_reply" {
+				continue
+			}
+			for _, parameter := range component.Parameters {
+				if parameter.Type == "payload" && parameter.Payload != "" {
+					ids = append(ids, parameter.Payload)
+				}
+			}
+		}
+	}
+
+	return payload.To, ids
+}
+
+// NewMemoryCorrelationStore returns an empty MemoryCorrelationStore.
+func NewMemoryCorrelationStore() *MemoryCorrelationStore {
+	return &MemoryCorrelationStore{entries: make(map[correlationKey]*Correlation)}
+}
+
+func (store *MemoryCorrelationStore) Put(_ context.Context, correlation *Correlation) error {
+	stored := *correlation
+	store.mu.Lock()
+	store.entries[correlationKey{correlation.MessageID, correlation.ReplyID}] = &stored
+	store.mu.Unlock()
+
+	return nil
+}
+
+func (store *MemoryCorrelationStore) Get(_ context.Context, messageID, replyID string,
+) (*Correlation, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	correlation, ok := store.entries[correlationKey{messageID, replyID}]
+	if !ok {
+		return nil, false, nil
+	}
+	found := *correlation
+
+	return &found, true, nil
+}
+
+func (store *MemoryCorrelationStore) DeleteBefore(_ context.Context, t time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	deleted := 0
+	for key, correlation := range store.entries {
+		if correlation.CreatedAt.Before(t) {
+			delete(store.entries, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (store *MemoryCorrelationStore) DeleteRecipient(_ context.Context, waID string) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	deleted := 0
+	for key, correlation := range store.entries {
+		if correlation.Recipient == waID {
+			delete(store.entries, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
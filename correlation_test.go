@@ -0,0 +1,158 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestCorrelationTracker(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.OUT"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	tracker := NewCorrelationTracker(&CorrelationConfig{Clock: clk, MaxAge: 24 * time.Hour})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithCorrelationTracker(tracker))
+	ctx := context.Background()
+
+	confirmation := models.NewInteractiveMessage(models.InteractiveMessageButton,
+		models.WithInteractiveBody("Confirm your order?"),
+		models.WithInteractiveAction(&models.InteractiveAction{
+			Buttons: models.CreateInteractiveRelyButtonList(
+				&models.InteractiveReplyButton{ID: "yes", Title: "Yes"},
+				&models.InteractiveReplyButton{ID: "no", Title: "No"},
+			),
+		}))
+
+	// without metadata nothing is registered
+	if _, err := client.SendInteractiveMessage(ctx, "+255700000001", confirmation); err != nil {
+		t.Fatalf("SendInteractiveMessage() error = %v", err)
+	}
+	if _, found, _ := tracker.Resolve(ctx, "wamid.OUT", "yes"); found {
+		t.Fatalf("Resolve() found a correlation for a message sent without metadata")
+	}
+
+	ctx = WithCorrelationMetadata(ctx, map[string]string{"order": "1234"})
+	if _, err := client.SendInteractiveMessage(ctx, "+255700000001", confirmation); err != nil {
+		t.Fatalf("SendInteractiveMessage() error = %v", err)
+	}
+	correlation, found, err := tracker.Resolve(ctx, "wamid.OUT", "no")
+	if err != nil || !found {
+		t.Fatalf("Resolve() = %v, %v, want the correlation", found, err)
+	}
+	if correlation.Recipient != "255700000001" || correlation.Metadata["order"] != "1234" {
+		t.Errorf("Resolve() = %+v", correlation)
+	}
+
+	// the reply webhook carries the original metadata
+	var replies []*CorrelatedReply
+	handler := func(ctx context.Context, nctx *webhooks.NotificationContext, mctx *webhooks.MessageContext,
+		reply *CorrelatedReply,
+	) error {
+		replies = append(replies, reply)
+
+		return nil
+	}
+	hooks := &webhooks.Hooks{
+		OnInteractiveMessageHook: tracker.InteractiveHook(handler),
+		OnButtonMessageHook:      tracker.ButtonHook(handler),
+	}
+	body := `{"object":"whatsapp_business_account","entry":[{"id":"WABA_ID","changes":[{"value":{` +
+		`"messaging_product":"whatsapp","metadata":{"display_phone_number":"PHONE_NUMBER",` +
+		`"phone_number_id":"phone_1"},"messages":[{"context":{"from":"PHONE_NUMBER","id":"wamid.OUT"},` +
+		`"from":"255700000001","id":"wamid.IN","timestamp":"1683000000","type":"interactive",` +
+		`"interactive":{"type":"button_reply","button_reply":{"id":"yes","title":"Yes"}}},` +
+		`{"context":{"from":"PHONE_NUMBER","id":"wamid.OTHER"},"from":"255700000001","id":"wamid.IN2",` +
+		`"timestamp":"1683000000","type":"button","button":{"payload":"stop","text":"Stop"}}]},` +
+		`"field":"messages"}]}]}`
+	h := webhooks.NotificationHandler(hooks, webhooks.NoOpNotificationErrorHandler,
+		webhooks.NoOpHooksErrorHandler, &webhooks.HandlerOptions{})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	if len(replies) != 2 {
+		t.Fatalf("got %d replies, want 2", len(replies))
+	}
+	if reply := replies[0]; reply.ReplyID != "yes" || reply.Title != "Yes" || reply.MessageID != "wamid.OUT" ||
+		reply.Correlation == nil || reply.Correlation.Metadata["order"] != "1234" {
+		t.Errorf("interactive reply = %+v", reply)
+	}
+	if reply := replies[1]; reply.ReplyID != "stop" || reply.Correlation != nil {
+		t.Errorf("button reply = %+v, want no correlation", reply)
+	}
+
+	clk.Advance(25 * time.Hour)
+	if deleted, err := tracker.Evict(ctx); err != nil || deleted != 2 {
+		t.Errorf("Evict() = %d, %v, want 2", deleted, err)
+	}
+}
+
+func TestReplyIDsOf(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{
+			name: "list rows",
+			payload: `{"to":"1","type":"interactive","interactive":{"type":"list","action":{"sections":[` +
+				`{"rows":[{"id":"a"},{"id":"b"}]},{"rows":[{"id":"c"}]}]}}}`,
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "template quick replies",
+			payload: `{"to":"1","type":"template","template":{"components":[{"type":"body"},` +
+				`{"type":"button","sub_type":"quick_reply","index":"0","parameters":[{"type":"payload","payload":"p0"}]},` +
+				`{"type":"button","sub_type":"url","index":"1","parameters":[{"type":"text","text":"x"}]}]}}`,
+			want: []string{"p0"},
+		},
+		{
+			name:    "text",
+			payload: `{"to":"1","type":"text","text":{"body":"hi"}}`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, got := replyIDsOf(&whttp.Request{Payload: []byte(tt.payload)})
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("replyIDsOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+const (
+	ScopeBusinessMessaging  = "whatsapp_business_messaging"
+	ScopeBusinessManagement = "whatsapp_business_management"
+)
+
+var ErrMissingTokenScope = errors.New("access token is missing required scope")
+
+type (
+	// DebugTokenResponse is the response of GET /debug_token.
+	DebugTokenResponse struct {
+		Data *TokenInfo `json:"data,omitempty"`
+	}
+
+	// TokenInfo describes an access token as returned by the debug_token endpoint.
+	// ExpiresAt and DataAccessExpiresAt are unix timestamps, an ExpiresAt of 0 means the
+	// token never expires, as is the case for system user tokens.
+	TokenInfo struct {
+		AppID               string            `json:"app_id,omitempty"`
+		Type                string            `json:"type,omitempty"`
+		Application         string            `json:"application,omitempty"`
+		DataAccessExpiresAt int64             `json:"data_access_expires_at,omitempty"`
+		ExpiresAt           int64             `json:"expires_at"`
+		IsValid             bool              `json:"is_valid"`
+		IssuedAt            int64             `json:"issued_at,omitempty"`
+		Scopes              []string          `json:"scopes,omitempty"`
+		GranularScopes      []*GranularScope  `json:"granular_scopes,omitempty"`
+		UserID              string            `json:"user_id,omitempty"`
+		Error               *TokenInfoError   `json:"error,omitempty"`
+		Metadata            map[string]string `json:"metadata,omitempty"`
+	}
+
+	// GranularScope is a scope granted for specific targets, for whatsapp scopes the
+	// targets are the WhatsApp Business Account IDs the token can access.
+	GranularScope struct {
+		Scope     string   `json:"scope"`
+		TargetIDs []string `json:"target_ids,omitempty"`
+	}
+
+	// TokenInfoError is set when the inspected token is invalid.
+	TokenInfoError struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+		Subcode int    `json:"subcode,omitempty"`
+	}
+)
+
+// ExpiresAtTime returns the expiry of the token, ok is false if the token never expires.
+func (info *TokenInfo) ExpiresAtTime() (time.Time, bool) {
+	if info.ExpiresAt == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(info.ExpiresAt, 0), true
+}
+
+// HasScope reports whether the token has been granted scope.
+func (info *TokenInfo) HasScope(scope string) bool {
+	for _, s := range info.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TargetIDs returns the IDs the granular scope is granted for. For whatsapp scopes these
+// are WhatsApp Business Account IDs.
+func (info *TokenInfo) TargetIDs(scope string) []string {
+	for _, s := range info.GranularScopes {
+		if s != nil && s.Scope == scope {
+			return s.TargetIDs
+		}
+	}
+
+	return nil
+}
+
+// DebugToken inspects inputToken with GET /debug_token. If inputToken is empty the
+// access token of the client is inspected.
+func (client *Client) DebugToken(ctx context.Context, inputToken string) (*TokenInfo, error) {
+	cctx := client.context(ctx)
+	if inputToken == "" {
+		token, err := client.accessTokenFor(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("debug token: %w", err)
+		}
+		inputToken = token
+	}
+
+	reqCtx := &whttp.RequestContext{
+		Name:       "debug token",
+		BaseURL:    cctx.baseURL,
+		ApiVersion: cctx.apiVersion,
+		Endpoints:  []string{"debug_token"},
+	}
+	params := &whttp.Request{
+		Context: reqCtx,
+		Method:  http.MethodGet,
+		Query:   map[string]string{"input_token": inputToken},
+		Bearer:  cctx.accessToken,
+	}
+
+	resp, err := whttp.SendTyped[DebugTokenResponse](ctx, client.sender, params)
+	if err != nil {
+		return nil, fmt.Errorf("debug token: %w", err)
+	}
+	if resp.Data == nil {
+		return nil, fmt.Errorf("debug token: empty response")
+	}
+
+	return resp.Data, nil
+}
+
+// VerifyTokenScopes inspects the access token of the client and returns an error wrapping
+// ErrMissingTokenScope if it is invalid or lacks any of the scopes. With no scopes,
+// ScopeBusinessMessaging is required. It is meant to be called at startup, before going live.
+func (client *Client) VerifyTokenScopes(ctx context.Context, scopes ...string) error {
+	info, err := client.DebugToken(ctx, "")
+	if err != nil {
+		return err
+	}
+	if !info.IsValid {
+		return fmt.Errorf("%w: token is not valid", ErrMissingTokenScope)
+	}
+	if len(scopes) == 0 {
+		scopes = []string{ScopeBusinessMessaging}
+	}
+
+	var missing []string
+	for _, scope := range scopes {
+		if !info.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingTokenScope, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
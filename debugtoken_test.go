@@ -0,0 +1,68 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientDebugToken(t *testing.T) {
+	t.Parallel()
+	var path, inputToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		inputToken = r.URL.Query().Get("input_token")
+		_, _ = w.Write([]byte(`{"data":{"app_id":"123","type":"SYSTEM_USER","application":"shop",
+			"expires_at":1700000000,"is_valid":true,"scopes":["whatsapp_business_management"],
+			"granular_scopes":[{"scope":"whatsapp_business_management","target_ids":["waba_1"]}]}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithAccessToken("token"))
+	info, err := client.DebugToken(context.TODO(), "")
+	if err != nil {
+		t.Fatalf("DebugToken() error = %v", err)
+	}
+	if path != "/v23.0/debug_token" {
+		t.Errorf("request path = %q, want %q", path, "/v23.0/debug_token")
+	}
+	if inputToken != "token" {
+		t.Errorf("input_token = %q, want %q", inputToken, "token")
+	}
+	if expiry, ok := info.ExpiresAtTime(); !ok || !expiry.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("ExpiresAtTime() = %v, %v", expiry, ok)
+	}
+	if ids := info.TargetIDs(ScopeBusinessManagement); len(ids) != 1 || ids[0] != "waba_1" {
+		t.Errorf("TargetIDs() = %v, want [waba_1]", ids)
+	}
+
+	err = client.VerifyTokenScopes(context.TODO())
+	if !errors.Is(err, ErrMissingTokenScope) {
+		t.Errorf("VerifyTokenScopes() error = %v, want %v", err, ErrMissingTokenScope)
+	}
+	if err = client.VerifyTokenScopes(context.TODO(), ScopeBusinessManagement); err != nil {
+		t.Errorf("VerifyTokenScopes(%s) error = %v", ScopeBusinessManagement, err)
+	}
+}
@@ -0,0 +1,515 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// States of a delivery. A message is accepted when the API returns its wamid, the other
+// states are reported by the status webhooks.
+const (
+	DeliveryAccepted  DeliveryState = "accepted"
+	DeliverySent      DeliveryState = "sent"
+	DeliveryDelivered DeliveryState = "delivered"
+	DeliveryRead      DeliveryState = "read"
+	DeliveryFailed    DeliveryState = "failed"
+)
+
+type (
+	// DeliveryState is the state of an outbound message.
+	DeliveryState string
+
+	// Delivery is the delivery state of an outbound message. The times are zero until the
+	// message reaches the matching state, Errors holds the errors of a failed message.
+	Delivery struct {
+		MessageID    string
+		Sender       string
+		Recipient    string
+		Type         string
+		TemplateName string
+		State        DeliveryState
+		AcceptedAt   time.Time
+		SentAt       time.Time
+		DeliveredAt  time.Time
+		ReadAt       time.Time
+		FailedAt     time.Time
+		Errors       []*werrors.Error
+		UpdatedAt    time.Time
+
+		// IdempotencyKey is the idempotency key the message was sent with, the ID of the
+		// outbox entry for the messages sent through an outbox.
+		IdempotencyKey string
+
+		// FailureAction is the action taken when the message failed, see FailurePolicy.
+		FailureAction FailureAction
+
+		// Tag groups the deliveries of a campaign, see WithDeliveryTag.
+		Tag string
+	}
+
+	// DeliveryQuery selects deliveries. Empty fields match every delivery, UpdatedBefore
+	// selects the deliveries not updated since, for example messages stuck in the sent state.
+	DeliveryQuery struct {
+		State         DeliveryState
+		Recipient     string
+		Tag           string
+		UpdatedBefore time.Time
+	}
+
+	// DeliveryStore stores the deliveries by message ID. Implementations must be safe for
+	// concurrent use.
+	DeliveryStore interface {
+		// Get returns the delivery of messageID. found is false if it is unknown.
+		Get(ctx context.Context, messageID string) (delivery *Delivery, found bool, err error)
+
+		// Put stores delivery, replacing the delivery with the same message ID.
+		Put(ctx context.Context, delivery *Delivery) error
+
+		// Query returns the deliveries matching query.
+		Query(ctx context.Context, query *DeliveryQuery) ([]*Delivery, error)
+
+		// DeleteBefore deletes the deliveries last updated before t and returns how many
+		// were deleted.
+		DeleteBefore(ctx context.Context, t time.Time) (int, error)
+
+		// DeleteRecipient deletes the deliveries of the messages sent to the WhatsApp ID
+		// waID and returns how many were deleted.
+		DeleteRecipient(ctx context.Context, waID string) (int, error)
+	}
+
+	// DeliveryTrackerConfig configures a DeliveryTracker. Store defaults to a
+	// MemoryDeliveryStore without a size limit and Clock to the system clock. MaxAge is how
+	// long a delivery is kept after its last update, Evict deletes the older ones, zero
+	// keeps them forever. Failures, when set, is applied to the messages reported failed.
+	DeliveryTrackerConfig struct {
+		Store    DeliveryStore
+		Clock    clock.Clock
+		MaxAge   time.Duration
+		Failures *FailurePolicy
+	}
+
+	// DeliveryTracker tracks the delivery of the outbound messages. It is an AuditSink that
+	// records the wamid of every message the client sends and a status hook that updates
+	// their state from the webhooks:
+	//
+	//	tracker := whatsapp.NewDeliveryTracker(&whatsapp.DeliveryTrackerConfig{MaxAge: 7 * 24 * time.Hour})
+	//	client := whatsapp.NewClient(whatsapp.WithAuditSink(tracker), ...)
+	//	listener.OnMessageStatusChange(tracker.OnMessageStatusChange)
+	//
+	// The audit record and the statuses of a message are applied one at a time by a tracker,
+	// trackers of several processes sharing a store do not coordinate.
+	DeliveryTracker struct {
+		store    DeliveryStore
+		clock    clock.Clock
+		maxAge   time.Duration
+		failures *FailurePolicy
+		locks    keyedMutex
+	}
+
+	// MemoryDeliveryStore is an in-memory DeliveryStore. When it holds more than its
+	// maximum number of deliveries, the least recently updated ones are dropped.
+	MemoryDeliveryStore struct {
+		mu         sync.Mutex
+		maxEntries int
+		entries    map[string]*memoryDelivery
+		order      deliveryHeap
+	}
+
+	memoryDelivery struct {
+		delivery *Delivery
+		index    int
+	}
+
+	// deliveryHeap orders the deliveries of a MemoryDeliveryStore from the least to the
+	// most recently updated, so that the ones to drop are found without sorting them all.
+	deliveryHeap []*memoryDelivery
+)
+
+// deliveryRanks orders the states, a status webhook never moves a delivery back. Failed
+// ranks below read: a message that was read cannot fail anymore.
+var deliveryRanks = map[DeliveryState]int{ //nolint:gochecknoglobals
+	DeliveryAccepted:  1,
+	DeliverySent:      2, //nolint:gomnd
+	DeliveryDelivered: 3, //nolint:gomnd
+	DeliveryFailed:    4, //nolint:gomnd
+	DeliveryRead:      5, //nolint:gomnd
+}
+
+// NewDeliveryTracker returns a DeliveryTracker configured with config, which may be nil.
+func NewDeliveryTracker(config *DeliveryTrackerConfig) *DeliveryTracker {
+	if config == nil {
+		config = &DeliveryTrackerConfig{}
+	}
+	tracker := &DeliveryTracker{
+		store:    config.Store,
+		clock:    clock.OrSystem(config.Clock),
+		maxAge:   config.MaxAge,
+		failures: config.Failures,
+	}
+	if tracker.store == nil {
+		tracker.store = NewMemoryDeliveryStore(0)
+	}
+
+	return tracker
+}
+
+// Audit implements AuditSink, it records the messages accepted by the API. Failed sends
+// have no wamid and are not tracked.
+func (tracker *DeliveryTracker) Audit(ctx context.Context, record AuditRecord) {
+	if record.MessageID == "" {
+		return
+	}
+	key, _ := IdempotencyKeyFromContext(ctx)
+	tag, _ := DeliveryTagFromContext(ctx)
+	_ = tracker.Track(ctx, &Delivery{
+		MessageID:      record.MessageID,
+		Sender:         record.Sender,
+		Recipient:      record.Recipient,
+		Type:           record.Type,
+		TemplateName:   record.TemplateName,
+		State:          DeliveryAccepted,
+		AcceptedAt:     record.Timestamp,
+		IdempotencyKey: key,
+		Tag:            tag,
+	})
+}
+
+// Track starts tracking delivery. A status received before the message was tracked is kept.
+func (tracker *DeliveryTracker) Track(ctx context.Context, delivery *Delivery) error {
+	unlock := tracker.locks.Lock(delivery.MessageID)
+	defer unlock()
+
+	existing, found, err := tracker.store.Get(ctx, delivery.MessageID)
+	if err != nil {
+		return fmt.Errorf("delivery tracker: %w", err)
+	}
+	tracked := *delivery
+	if found {
+		tracked = *existing
+		tracked.Sender, tracked.Type, tracked.TemplateName = delivery.Sender, delivery.Type, delivery.TemplateName
+		tracked.AcceptedAt = delivery.AcceptedAt
+		if delivery.IdempotencyKey != "" {
+			tracked.IdempotencyKey = delivery.IdempotencyKey
+		}
+		if delivery.Tag != "" {
+			tracked.Tag = delivery.Tag
+		}
+		if tracked.Recipient == "" {
+			tracked.Recipient = delivery.Recipient
+		}
+	}
+	if tracked.State == "" {
+		tracked.State = DeliveryAccepted
+	}
+	tracked.UpdatedAt = tracker.clock.Now()
+	if err := tracker.store.Put(ctx, &tracked); err != nil {
+		return fmt.Errorf("delivery tracker: %w", err)
+	}
+
+	return nil
+}
+
+// OnMessageStatusChange is a webhooks.OnMessageStatusChangeHook that updates the delivery
+// of the message the status is about. Statuses of messages that are not tracked, for
+// example sent by another system, start tracking them. Payment statuses are ignored.
+//
+// The first failed status of a message triggers the action of the FailurePolicy of the
+// tracker, if any. The action is claimed under the lock of the message, so it runs once even
+// when the failed status is delivered several times concurrently.
+func (tracker *DeliveryTracker) OnMessageStatusChange(ctx context.Context, _ *webhooks.NotificationContext,
+	status *webhooks.Status,
+) error {
+	if status == nil || status.ID == "" || status.IsPayment() {
+		return nil
+	}
+	state := DeliveryState(status.StatusValue)
+	if _, ok := deliveryRanks[state]; !ok {
+		return nil
+	}
+
+	updated, failed, err := tracker.applyStatus(ctx, status, state)
+	if err != nil {
+		return err
+	}
+	if failed {
+		return tracker.failures.execute(ctx, updated, updated.FailureAction)
+	}
+
+	return nil
+}
+
+// applyStatus records status in the delivery of its message. failed is true when the status
+// claimed the failure action of the message.
+func (tracker *DeliveryTracker) applyStatus(ctx context.Context, status *webhooks.Status, state DeliveryState,
+) (*Delivery, bool, error) {
+	unlock := tracker.locks.Lock(status.ID)
+	defer unlock()
+
+	delivery, found, err := tracker.store.Get(ctx, status.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("delivery tracker: %w", err)
+	}
+	if !found {
+		delivery = &Delivery{MessageID: status.ID, Recipient: status.RecipientID}
+	}
+	updated := *delivery
+
+	at := tracker.clock.Now()
+	if status.Timestamp > 0 {
+		at = time.Unix(int64(status.Timestamp), 0)
+	}
+	switch state {
+	case DeliveryAccepted:
+	case DeliverySent:
+		updated.SentAt = at
+	case DeliveryDelivered:
+		updated.DeliveredAt = at
+	case DeliveryRead:
+		updated.ReadAt = at
+	case DeliveryFailed:
+		updated.FailedAt = at
+		updated.Errors = status.Errors
+	}
+	failed := state == DeliveryFailed && updated.FailureAction == "" && tracker.failures != nil
+	if deliveryRanks[state] > deliveryRanks[updated.State] {
+		updated.State = state
+	}
+	if failed {
+		updated.FailureAction = tracker.failures.Action(failureCode(&updated))
+	}
+	updated.UpdatedAt = tracker.clock.Now()
+
+	if err := tracker.store.Put(ctx, &updated); err != nil {
+		return nil, false, fmt.Errorf("delivery tracker: %w", err)
+	}
+
+	return &updated, failed, nil
+}
+
+// Get returns the delivery of messageID. found is false if the message is not tracked.
+func (tracker *DeliveryTracker) Get(ctx context.Context, messageID string) (*Delivery, bool, error) {
+	delivery, found, err := tracker.store.Get(ctx, messageID)
+	if err != nil {
+		return nil, false, fmt.Errorf("delivery tracker: %w", err)
+	}
+
+	return delivery, found, nil
+}
+
+// Query returns the deliveries matching query.
+func (tracker *DeliveryTracker) Query(ctx context.Context, query *DeliveryQuery) ([]*Delivery, error) {
+	deliveries, err := tracker.store.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("delivery tracker: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Pending returns the deliveries that are still not delivered, read or failed olderThan
+// after their last update, the messages breaching a delivery SLA.
+func (tracker *DeliveryTracker) Pending(ctx context.Context, olderThan time.Duration) ([]*Delivery, error) {
+	before := tracker.clock.Now().Add(-olderThan)
+	var pending []*Delivery
+	for _, state := range []DeliveryState{DeliveryAccepted, DeliverySent} {
+		deliveries, err := tracker.Query(ctx, &DeliveryQuery{State: state, UpdatedBefore: before})
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, deliveries...)
+	}
+
+	return pending, nil
+}
+
+// Evict deletes the deliveries not updated for longer than the MaxAge of the tracker and
+// returns how many were deleted. Run it periodically, it does nothing when MaxAge is zero.
+func (tracker *DeliveryTracker) Evict(ctx context.Context) (int, error) {
+	if tracker.maxAge <= 0 {
+		return 0, nil
+	}
+	deleted, err := tracker.store.DeleteBefore(ctx, tracker.clock.Now().Add(-tracker.maxAge))
+	if err != nil {
+		return deleted, fmt.Errorf("delivery tracker: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Forget deletes the deliveries of the messages sent to waID, for example when the customer
+// asks for their data to be erased, and returns how many were deleted.
+func (tracker *DeliveryTracker) Forget(ctx context.Context, waID string) (int, error) {
+	deleted, err := tracker.store.DeleteRecipient(ctx, strings.TrimPrefix(waID, "+"))
+	if err != nil {
+		return deleted, fmt.Errorf("delivery tracker: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// NewMemoryDeliveryStore returns an empty MemoryDeliveryStore holding at most maxEntries
+// deliveries, there is no limit when maxEntries is not positive.
+func NewMemoryDeliveryStore(maxEntries int) *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*memoryDelivery),
+	}
+}
+
+func (store *MemoryDeliveryStore) Get(_ context.Context, messageID string) (*Delivery, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	entry, ok := store.entries[messageID]
+	if !ok {
+		return nil, false, nil
+	}
+	clone := *entry.delivery
+
+	return &clone, true, nil
+}
+
+func (store *MemoryDeliveryStore) Put(_ context.Context, delivery *Delivery) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	clone := *delivery
+	if entry, ok := store.entries[delivery.MessageID]; ok {
+		entry.delivery = &clone
+		heap.Fix(&store.order, entry.index)
+	} else {
+		entry = &memoryDelivery{delivery: &clone}
+		store.entries[delivery.MessageID] = entry
+		heap.Push(&store.order, entry)
+	}
+
+	for store.maxEntries > 0 && len(store.entries) > store.maxEntries {
+		oldest := heap.Pop(&store.order).(*memoryDelivery) //nolint:forcetypeassert
+		delete(store.entries, oldest.delivery.MessageID)
+	}
+
+	return nil
+}
+
+func (store *MemoryDeliveryStore) Query(_ context.Context, query *DeliveryQuery) ([]*Delivery, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if query == nil {
+		query = &DeliveryQuery{}
+	}
+
+	var deliveries []*Delivery
+	for _, delivery := range store.sorted() {
+		if (query.State != "" && delivery.State != query.State) ||
+			(query.Recipient != "" && delivery.Recipient != query.Recipient) ||
+			(query.Tag != "" && delivery.Tag != query.Tag) ||
+			(!query.UpdatedBefore.IsZero() && !delivery.UpdatedAt.Before(query.UpdatedBefore)) {
+			continue
+		}
+		clone := *delivery
+		deliveries = append(deliveries, &clone)
+	}
+
+	return deliveries, nil
+}
+
+func (store *MemoryDeliveryStore) DeleteBefore(_ context.Context, t time.Time) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	deleted := 0
+	for store.order.Len() > 0 && store.order[0].delivery.UpdatedAt.Before(t) {
+		oldest := heap.Pop(&store.order).(*memoryDelivery) //nolint:forcetypeassert
+		delete(store.entries, oldest.delivery.MessageID)
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func (store *MemoryDeliveryStore) DeleteRecipient(_ context.Context, waID string) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	deleted := 0
+	for id, entry := range store.entries {
+		if strings.TrimPrefix(entry.delivery.Recipient, "+") == waID {
+			heap.Remove(&store.order, entry.index)
+			delete(store.entries, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// sorted returns the deliveries from the least to the most recently updated.
+func (store *MemoryDeliveryStore) sorted() []*Delivery {
+	deliveries := make([]*Delivery, 0, len(store.entries))
+	for _, entry := range store.entries {
+		deliveries = append(deliveries, entry.delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return updatedBefore(deliveries[i], deliveries[j])
+	})
+
+	return deliveries
+}
+
+// updatedBefore reports whether a was updated before b, deliveries updated at the same
+// time are ordered by message ID.
+func updatedBefore(a, b *Delivery) bool {
+	if a.UpdatedAt.Equal(b.UpdatedAt) {
+		return a.MessageID < b.MessageID
+	}
+
+	return a.UpdatedAt.Before(b.UpdatedAt)
+}
+
+func (h deliveryHeap) Len() int { return len(h) }
+
+func (h deliveryHeap) Less(i, j int) bool { return updatedBefore(h[i].delivery, h[j].delivery) }
+
+func (h deliveryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *deliveryHeap) Push(x any) {
+	entry := x.(*memoryDelivery) //nolint:forcetypeassert
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *deliveryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return entry
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestDeliveryTracker(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	tracker := NewDeliveryTracker(&DeliveryTrackerConfig{Clock: clk, MaxAge: time.Hour})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithClock(clk),
+		WithAuditSink(tracker))
+
+	if _, err := client.SendTemplate(ctx, "255700000001", &Template{Name: "otp", LanguageCode: "en"}); err != nil {
+		t.Fatalf("SendTemplate() error = %v", err)
+	}
+	delivery, found, err := tracker.Get(ctx, "wamid.1")
+	if err != nil || !found {
+		t.Fatalf("Get() = %v, %v, want the delivery", found, err)
+	}
+	if delivery.State != DeliveryAccepted || delivery.Recipient != "255700000001" ||
+		delivery.TemplateName != "otp" || !delivery.AcceptedAt.Equal(now) {
+		t.Errorf("Get() = %+v", delivery)
+	}
+
+	status := func(id, value string, at time.Time, errs ...*werrors.Error) {
+		t.Helper()
+		err := tracker.OnMessageStatusChange(ctx, nil, &webhooks.Status{
+			ID: id, RecipientID: "255700000001", StatusValue: value, Timestamp: int(at.Unix()), Errors: errs,
+		})
+		if err != nil {
+			t.Fatalf("OnMessageStatusChange() error = %v", err)
+		}
+	}
+
+	// read arrives before delivered, the delivery does not move back
+	status("wamid.1", "sent", now.Add(time.Second))
+	status("wamid.1", "read", now.Add(3*time.Second))
+	status("wamid.1", "delivered", now.Add(2*time.Second))
+	delivery, _, _ = tracker.Get(ctx, "wamid.1")
+	if delivery.State != DeliveryRead || !delivery.DeliveredAt.Equal(now.Add(2*time.Second)) ||
+		!delivery.ReadAt.Equal(now.Add(3*time.Second)) {
+		t.Errorf("Get() after the statuses = %+v", delivery)
+	}
+
+	// statuses of messages sent elsewhere start tracking them
+	status("wamid.2", "sent", now)
+	status("wamid.2", "failed", now, &werrors.Error{Code: werrors.CodeReEngagement})
+	delivery, _, _ = tracker.Get(ctx, "wamid.2")
+	if delivery.State != DeliveryFailed || len(delivery.Errors) != 1 ||
+		delivery.Errors[0].Code != werrors.CodeReEngagement {
+		t.Errorf("Get() of a failed message = %+v", delivery)
+	}
+
+	// payment statuses of order_details messages are not about their delivery
+	if err := tracker.OnMessageStatusChange(ctx, nil, &webhooks.Status{
+		ID: "wamid.1", StatusValue: "failed", Type: webhooks.StatusTypePayment,
+		Payment: &webhooks.Payment{ReferenceID: "order-7"},
+	}); err != nil {
+		t.Fatalf("OnMessageStatusChange() error = %v", err)
+	}
+	if delivery, _, _ = tracker.Get(ctx, "wamid.1"); delivery.State != DeliveryRead {
+		t.Errorf("Get() after a payment status = %+v, want it unchanged", delivery)
+	}
+
+	status("wamid.3", "sent", now)
+	clk.Advance(30 * time.Minute)
+	pending, err := tracker.Pending(ctx, 10*time.Minute)
+	if err != nil || len(pending) != 1 || pending[0].MessageID != "wamid.3" {
+		t.Errorf("Pending() = %+v, %v, want wamid.3", pending, err)
+	}
+	failed, _ := tracker.Query(ctx, &DeliveryQuery{State: DeliveryFailed})
+	if len(failed) != 1 || failed[0].MessageID != "wamid.2" {
+		t.Errorf("Query(failed) = %+v, want wamid.2", failed)
+	}
+
+	clk.Advance(time.Hour)
+	if deleted, err := tracker.Evict(ctx); err != nil || deleted != 3 {
+		t.Errorf("Evict() = %d, %v, want 3", deleted, err)
+	}
+}
+
+func TestMemoryDeliveryStoreMaxEntries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryDeliveryStore(2)
+	now := time.Now()
+	for i, id := range []string{"wamid.1", "wamid.2", "wamid.3"} {
+		_ = store.Put(ctx, &Delivery{MessageID: id, UpdatedAt: now.Add(time.Duration(i) * time.Second)})
+	}
+	if _, found, _ := store.Get(ctx, "wamid.1"); found {
+		t.Error("Get(wamid.1) found the least recently updated delivery, want it dropped")
+	}
+	if deliveries, _ := store.Query(ctx, nil); len(deliveries) != 2 {
+		t.Errorf("Query() = %d deliveries, want 2", len(deliveries))
+	}
+}
+
+func TestMemoryDeliveryStoreEvictsLeastRecentlyUpdated(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	store := NewMemoryDeliveryStore(3)
+	now := time.Now()
+	put := func(id, recipient string, at time.Duration) {
+		_ = store.Put(ctx, &Delivery{MessageID: id, Recipient: recipient, UpdatedAt: now.Add(at)})
+	}
+	put("wamid.1", "255700000001", 0)
+	put("wamid.2", "255700000002", time.Second)
+	put("wamid.3", "255700000003", 2*time.Second)
+	put("wamid.1", "255700000001", 3*time.Second) // wamid.2 is now the least recently updated
+	if deleted, _ := store.DeleteRecipient(ctx, "255700000003"); deleted != 1 {
+		t.Errorf("DeleteRecipient() = %d, want 1", deleted)
+	}
+	put("wamid.4", "255700000004", 4*time.Second)
+	put("wamid.5", "255700000005", 5*time.Second)
+
+	deliveries, _ := store.Query(ctx, nil)
+	var got []string
+	for _, delivery := range deliveries {
+		got = append(got, delivery.MessageID)
+	}
+	if want := "[wamid.1 wamid.4 wamid.5]"; fmt.Sprint(got) != want {
+		t.Errorf("Query() = %v, want %s", got, want)
+	}
+	if deleted, _ := store.DeleteBefore(ctx, now.Add(4*time.Second)); deleted != 1 {
+		t.Errorf("DeleteBefore() = %d, want 1", deleted)
+	}
+	if _, found, _ := store.Get(ctx, "wamid.1"); found {
+		t.Error("Get(wamid.1) found a delivery deleted by DeleteBefore")
+	}
+}
+
+func TestDeliveryTrackerConcurrentUpdates(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	tracker := NewDeliveryTracker(&DeliveryTrackerConfig{Store: slowDeliveryStore{NewMemoryDeliveryStore(0)}})
+
+	done := make(chan error, 2)
+	go func() {
+		done <- tracker.Track(ctx, &Delivery{MessageID: "wamid.1", Sender: "phone_1", IdempotencyKey: "entry-1"})
+	}()
+	go func() {
+		done <- tracker.OnMessageStatusChange(ctx, nil, &webhooks.Status{
+			ID: "wamid.1", StatusValue: "delivered", Timestamp: 1682942400,
+		})
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("update error = %v", err)
+		}
+	}
+
+	delivery, _, _ := tracker.Get(ctx, "wamid.1")
+	if delivery.State != DeliveryDelivered || delivery.Sender != "phone_1" || delivery.IdempotencyKey != "entry-1" {
+		t.Errorf("delivery = %+v, want the audit record and the status", delivery)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+)
+
+// Actions taken by the DeliveryTracker when a message fails, see FailurePolicy.
+const (
+	FailureRetry  FailureAction = "retry"
+	FailureNotify FailureAction = "notify"
+	FailureDrop   FailureAction = "drop"
+)
+
+// ErrNoRetry is the error passed to FailurePolicy.Notify when a failed message should be
+// retried but the policy has no Retry function.
+var ErrNoRetry = errors.New("delivery tracker: no retry function")
+
+type (
+	// FailureAction is what is done about a message reported failed by a status webhook.
+	FailureAction string
+
+	// DeliveryRetryFunc sends a failed message again, e.g. (*outbox.Outbox).Redeliver which
+	// enqueues the message again when it was sent through the outbox.
+	DeliveryRetryFunc func(ctx context.Context, delivery *Delivery) error
+
+	// DeliveryNotifyFunc is called about a failed message, err is nil for the FailureNotify
+	// action and the error of the retry when retrying the message failed.
+	DeliveryNotifyFunc func(ctx context.Context, delivery *Delivery, err error)
+
+	// FailurePolicy configures what the DeliveryTracker does when a status webhook reports
+	// that a message failed, by WhatsApp error code.
+	//
+	// Actions maps error codes to actions. The other codes are retried when the errors
+	// catalog marks them retryable, like 131000 or 130429, and notified otherwise, like 131026
+	// for undeliverable messages, unless Default is set. FailureRetry calls Retry, and Notify
+	// when the retry fails; FailureNotify calls Notify; FailureDrop does nothing. The action
+	// taken is recorded in Delivery.FailureAction.
+	//
+	//	tracker := whatsapp.NewDeliveryTracker(&whatsapp.DeliveryTrackerConfig{
+	//		Failures: &whatsapp.FailurePolicy{
+	//			Actions: map[int]whatsapp.FailureAction{131026: whatsapp.FailureDrop},
+	//			Retry:   box.Redeliver,
+	//			Notify:  alertOnCall,
+	//		},
+	//	})
+	FailurePolicy struct {
+		Actions map[int]FailureAction
+		Default FailureAction
+		Retry   DeliveryRetryFunc
+		Notify  DeliveryNotifyFunc
+	}
+)
+
+// Action returns the action for a message that failed with code.
+func (policy *FailurePolicy) Action(code int) FailureAction {
+	if action, ok := policy.Actions[code]; ok {
+		return action
+	}
+	if policy.Default != "" {
+		return policy.Default
+	}
+	if explanation, ok := werrors.Lookup(code, 0); ok && explanation.Retryable {
+		return FailureRetry
+	}
+
+	return FailureNotify
+}
+
+// failureCode returns the code of the first error of a failed delivery.
+func failureCode(delivery *Delivery) int {
+	for _, err := range delivery.Errors {
+		if err != nil {
+			return err.Code
+		}
+	}
+
+	return 0
+}
+
+// execute takes action for delivery.
+func (policy *FailurePolicy) execute(ctx context.Context, delivery *Delivery, action FailureAction) error {
+	switch action {
+	case FailureRetry:
+		err := ErrNoRetry
+		if policy.Retry != nil {
+			if err = policy.Retry(ctx, delivery); err == nil {
+				return nil
+			}
+		}
+		if policy.Notify == nil {
+			return fmt.Errorf("delivery tracker: retry %s: %w", delivery.MessageID, err)
+		}
+		policy.Notify(ctx, delivery, err)
+	case FailureNotify:
+		if policy.Notify != nil {
+			policy.Notify(ctx, delivery, nil)
+		}
+	case FailureDrop:
+	}
+
+	return nil
+}
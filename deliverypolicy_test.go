@@ -0,0 +1,158 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestDeliveryTrackerFailurePolicy(t *testing.T) {
+	t.Parallel()
+	retryErr := errors.New("outbox down")
+	tests := []struct {
+		name       string
+		code       int
+		retry      error
+		wantAction FailureAction
+		wantRetry  bool
+		wantNotify bool
+		wantErr    error
+	}{
+		{name: "generic error is retried", code: 131000, wantAction: FailureRetry, wantRetry: true},
+		{name: "undeliverable is dropped", code: 131026, wantAction: FailureDrop},
+		{name: "other codes are notified", code: werrors.CodeReEngagement, wantAction: FailureNotify, wantNotify: true},
+		{
+			name: "failed retry is notified", code: 131000, retry: retryErr,
+			wantAction: FailureRetry, wantRetry: true, wantNotify: true, wantErr: retryErr,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				retried  []*Delivery
+				notified []error
+			)
+			tracker := NewDeliveryTracker(&DeliveryTrackerConfig{Failures: &FailurePolicy{
+				Actions: map[int]FailureAction{131026: FailureDrop},
+				Retry: func(ctx context.Context, delivery *Delivery) error {
+					retried = append(retried, delivery)
+
+					return tt.retry
+				},
+				Notify: func(ctx context.Context, delivery *Delivery, err error) {
+					notified = append(notified, err)
+				},
+			}})
+
+			ctx := context.Background()
+			if err := tracker.Track(ctx, &Delivery{MessageID: "wamid.1", IdempotencyKey: "entry-1"}); err != nil {
+				t.Fatalf("Track() error = %v", err)
+			}
+			// the second failed status of the message does not run the policy again
+			for i := 0; i < 2; i++ {
+				err := tracker.OnMessageStatusChange(ctx, nil, &webhooks.Status{
+					ID: "wamid.1", StatusValue: "failed", Errors: []*werrors.Error{{Code: tt.code}},
+				})
+				if err != nil {
+					t.Fatalf("OnMessageStatusChange() error = %v", err)
+				}
+			}
+
+			delivery, _, _ := tracker.Get(ctx, "wamid.1")
+			if delivery.FailureAction != tt.wantAction {
+				t.Errorf("FailureAction = %q, want %q", delivery.FailureAction, tt.wantAction)
+			}
+			if got := len(retried) == 1; got != tt.wantRetry {
+				t.Errorf("retried %d times, want retry %v", len(retried), tt.wantRetry)
+			} else if tt.wantRetry && retried[0].IdempotencyKey != "entry-1" {
+				t.Errorf("retried delivery = %+v, want the idempotency key", retried[0])
+			}
+			if got := len(notified) == 1; got != tt.wantNotify {
+				t.Fatalf("notified %d times, want notify %v", len(notified), tt.wantNotify)
+			}
+			if tt.wantNotify && !errors.Is(notified[0], tt.wantErr) {
+				t.Errorf("Notify() error = %v, want %v", notified[0], tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeliveryTrackerFailurePolicyWithoutNotify(t *testing.T) {
+	t.Parallel()
+	tracker := NewDeliveryTracker(&DeliveryTrackerConfig{Failures: &FailurePolicy{}})
+	err := tracker.OnMessageStatusChange(context.Background(), nil, &webhooks.Status{
+		ID: "wamid.1", StatusValue: "failed", Errors: []*werrors.Error{{Code: 131000}},
+	})
+	if !errors.Is(err, ErrNoRetry) {
+		t.Errorf("OnMessageStatusChange() error = %v, want %v", err, ErrNoRetry)
+	}
+}
+
+// slowDeliveryStore widens the window between reading and storing a delivery.
+type slowDeliveryStore struct {
+	*MemoryDeliveryStore
+}
+
+func (store slowDeliveryStore) Get(ctx context.Context, messageID string) (*Delivery, bool, error) {
+	delivery, found, err := store.MemoryDeliveryStore.Get(ctx, messageID)
+	time.Sleep(10 * time.Millisecond)
+
+	return delivery, found, err
+}
+
+func TestDeliveryTrackerFailurePolicyConcurrent(t *testing.T) {
+	t.Parallel()
+	var retries atomic.Int32
+	tracker := NewDeliveryTracker(&DeliveryTrackerConfig{
+		Store: slowDeliveryStore{NewMemoryDeliveryStore(0)},
+		Failures: &FailurePolicy{Retry: func(ctx context.Context, delivery *Delivery) error {
+			retries.Add(1)
+
+			return nil
+		}},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = tracker.OnMessageStatusChange(context.Background(), nil, &webhooks.Status{
+				ID: "wamid.1", StatusValue: "failed", Errors: []*werrors.Error{{Code: 131000}},
+			})
+		}()
+	}
+	wg.Wait()
+
+	if got := retries.Load(); got != 1 {
+		t.Errorf("retried %d times, want 1", got)
+	}
+}
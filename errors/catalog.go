@@ -0,0 +1,201 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package errors
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// DocsURL is the reference of the error codes of the Cloud API.
+const DocsURL = "https://developers.facebook.com/docs/whatsapp/cloud-api/support/error-codes"
+
+// Explanation describes a Cloud API error code in plain words for the engineers handling it.
+//
+// Subcode is zero when the explanation covers every subcode of Code. Slug is a short stable
+// identifier of the explanation, like "re-engagement-window", that can name a runbook page,
+// see Link. Retryable reports whether sending the same request again later may succeed.
+type Explanation struct {
+	Code      int
+	Subcode   int
+	Title     string
+	Meaning   string
+	Action    string
+	Slug      string
+	Retryable bool
+}
+
+type catalogKey struct {
+	code    int
+	subcode int
+}
+
+//nolint:gochecknoglobals,lll
+var catalogEntries = []Explanation{
+	{Code: 0, Title: "Authentication failed", Meaning: "The app could not be authenticated, usually because the access token expired or was invalidated.", Action: "Get a new access token.", Slug: "auth-exception"},
+	{Code: 3, Title: "Capability or permission issue", Meaning: "The app lacks the capability or permission to call this endpoint.", Action: "Check the permissions of the app with the debug_token endpoint.", Slug: "api-method"},
+	{Code: 4, Title: "Too many calls", Meaning: "The app reached its API call rate limit.", Action: "Slow down and retry later, see the X-App-Usage header.", Slug: "app-rate-limit", Retryable: true},
+	{Code: 10, Title: "Permission denied", Meaning: "The permission is not granted or was removed.", Action: "Check the whatsapp_business_messaging and whatsapp_business_management permissions of the token.", Slug: "permission-denied"},
+	{Code: 33, Title: "Invalid parameter value", Meaning: "The business phone number was deleted or the ID is wrong.", Action: "Check the phone number ID.", Slug: "phone-number-deleted"},
+	{Code: 100, Title: "Invalid parameter", Meaning: "The request has an unsupported or misspelled parameter.", Action: "Check the payload against the API reference, error_data.details names the parameter.", Slug: "invalid-parameter"},
+	{Code: CodeAccessTokenInvalid, Title: "Access token expired or invalid", Meaning: "The access token expired, was revoked or is malformed.", Action: "Generate a new token, prefer a system user token for servers.", Slug: "access-token-invalid"},
+	{Code: CodeAccessTokenInvalid, Subcode: 460, Title: "Access token invalidated by a password change", Meaning: "The user changed their password or Meta reset the session for security reasons.", Action: "Generate a new token.", Slug: "access-token-password-changed"},
+	{Code: CodeAccessTokenInvalid, Subcode: 463, Title: "Access token expired", Meaning: "The access token reached its expiry time.", Action: "Generate a new token, prefer a system user token that does not expire.", Slug: "access-token-expired"},
+	{Code: 200, Title: "Permission not granted", Meaning: "The token lacks a permission required by the endpoint.", Action: "Grant the permission to the app and generate a new token.", Slug: "permission-not-granted"},
+	{Code: 368, Title: "Temporarily blocked for policy violations", Meaning: "The account is temporarily blocked from messaging for violating the WhatsApp policies.", Action: "Review the policy enforcement notice in WhatsApp Manager.", Slug: "policy-block"},
+	{Code: 80007, Title: "WhatsApp Business Account rate limit", Meaning: "The WhatsApp Business Account reached its rate limit.", Action: "Slow down and retry later, see the X-Business-Use-Case-Usage header.", Slug: "waba-rate-limit", Retryable: true},
+	{Code: 130429, Title: "Throughput limit reached", Meaning: "The phone number sends more messages per second than its throughput allows.", Action: "Pace the sends and retry later.", Slug: "throughput-limit", Retryable: true},
+	{Code: 130472, Title: "Recipient in an experiment", Meaning: "The recipient is part of a Meta experiment that limits the marketing messages they receive.", Action: "Do not retry the marketing message, use another channel.", Slug: "marketing-experiment"},
+	{Code: 130497, Title: "Country restricted", Meaning: "The business cannot message users in the country of the recipient.", Action: "Do not retry, check the messaging restrictions of the account.", Slug: "country-restricted"},
+	{Code: 131000, Title: "Something went wrong", Meaning: "An unknown error occurred on the side of Meta.", Action: "Retry later, report it with the fbtrace_id if it persists.", Slug: "unknown-error", Retryable: true},
+	{Code: 131005, Title: "Access denied", Meaning: "The permission is not granted or was removed.", Action: "Check the permissions of the token.", Slug: "access-denied"},
+	{Code: 131008, Title: "Required parameter missing", Meaning: "The request lacks a required parameter.", Action: "Add the parameter named in error_data.details.", Slug: "parameter-missing"},
+	{Code: 131009, Title: "Parameter value not valid", Meaning: "A parameter has an invalid value, often a phone number ID not registered with the Cloud API.", Action: "Fix the value named in error_data.details.", Slug: "parameter-invalid"},
+	{Code: 131016, Title: "Service unavailable", Meaning: "The service is temporarily down or overloaded.", Action: "Retry later, see the WhatsApp Business API status page.", Slug: "service-unavailable", Retryable: true},
+	{Code: 131021, Title: "Recipient cannot be sender", Meaning: "The message is sent to the business phone number itself.", Action: "Send the message to another recipient.", Slug: "recipient-is-sender"},
+	{Code: 131026, Title: "Message undeliverable", Meaning: "The recipient is not on WhatsApp, has not accepted the latest terms, or uses an old client.", Action: "Do not retry, reach the recipient through another channel.", Slug: "undeliverable"},
+	{Code: 131030, Title: "Recipient not in allowed list", Meaning: "Test phone numbers can only message the numbers added to their allowed list.", Action: "Add the recipient to the allowed list or use a production phone number.", Slug: "recipient-not-allowed"},
+	{Code: 131031, Title: "Account locked", Meaning: "The account was locked for a policy violation or because it failed two step verification.", Action: "Check WhatsApp Manager, contact support to appeal.", Slug: "account-locked"},
+	{Code: 131037, Title: "Display name approval needed", Meaning: "The display name of the phone number is not approved yet.", Action: "Wait for the display name review, or fix it in WhatsApp Manager.", Slug: "display-name-pending"},
+	{Code: 131042, Title: "Payment issue", Meaning: "The account has a problem with its payment method.", Action: "Fix the payment method in the Business Manager.", Slug: "payment-issue"},
+	{Code: 131045, Title: "Phone number not registered", Meaning: "The phone number is not registered, the message cannot be sent with its certificate.", Action: "Register the phone number.", Slug: "incorrect-certificate"},
+	{Code: CodeReEngagement, Title: "Customer service window closed", Meaning: "More than 24 hours passed since the recipient last replied, only template messages can be sent.", Action: "Send an approved template message instead.", Slug: "re-engagement-window"},
+	{Code: 131048, Title: "Spam rate limit", Meaning: "The phone number is restricted because too many of its messages were blocked or reported as spam.", Action: "Reduce the volume, review the quality rating and the content of the messages.", Slug: "spam-rate-limit", Retryable: true},
+	{Code: 131049, Title: "Not delivered to maintain engagement", Meaning: "Meta chose not to deliver the marketing message to keep the ecosystem healthy.", Action: "Do not retry immediately, retry with another message later.", Slug: "ecosystem-engagement"},
+	{Code: 131051, Title: "Unsupported message type", Meaning: "The type of the message is not supported.", Action: "Use one of the documented message types.", Slug: "unsupported-message-type"},
+	{Code: 131052, Title: "Media download error", Meaning: "The media sent by the user could not be downloaded.", Action: "Ask the user to send the media again.", Slug: "media-download"},
+	{Code: 131053, Title: "Media upload error", Meaning: "The media could not be uploaded, often an unsupported type or a link that cannot be fetched.", Action: "Check the media type and size, and that the link is public.", Slug: "media-upload"},
+	{Code: CodePairRateLimit, Title: "Pair rate limit", Meaning: "Too many messages were sent to the same recipient in a short period.", Action: "Wait before messaging this recipient again.", Slug: "pair-rate-limit", Retryable: true},
+	{Code: 131057, Title: "Account in maintenance mode", Meaning: "The account is in maintenance mode, e.g. during a throughput upgrade.", Action: "Retry after a few minutes.", Slug: "maintenance-mode", Retryable: true},
+	{Code: CodeTemplateParamMismatch, Title: "Template parameter count mismatch", Meaning: "The number of parameters does not match the number of placeholders of the template.", Action: "Send one parameter per placeholder of each component, check the template definition.", Slug: "template-parameter-count"},
+	{Code: 132001, Title: "Template does not exist", Meaning: "The template does not exist in the requested language, or is not approved.", Action: "Check the name and language code of the template.", Slug: "template-not-found"},
+	{Code: 132005, Title: "Template text too long", Meaning: "The text of the template with its parameters exceeds the length limit.", Action: "Shorten the parameters.", Slug: "template-too-long"},
+	{Code: 132007, Title: "Template format policy violated", Meaning: "The content of the template violates the WhatsApp policies.", Action: "Edit the template content.", Slug: "template-policy"},
+	{Code: 132012, Title: "Template parameter format mismatch", Meaning: "A parameter does not have the format expected by the template, e.g. a text parameter for a media header.", Action: "Check the types of the parameters against the template definition.", Slug: "template-parameter-format"},
+	{Code: 132015, Title: "Template paused", Meaning: "The template was paused because of its low quality rating.", Action: "Edit the template or wait for it to be unpaused, send another template meanwhile.", Slug: "template-paused"},
+	{Code: 132016, Title: "Template disabled", Meaning: "The template was paused too many times and is permanently disabled.", Action: "Create a new template.", Slug: "template-disabled"},
+	{Code: 132068, Title: "Flow blocked", Meaning: "The flow is in the blocked state.", Action: "Fix the flow endpoint and its health.", Slug: "flow-blocked"},
+	{Code: 132069, Title: "Flow throttled", Meaning: "The flow is throttled, only a few messages with it can be sent per hour.", Action: "Fix the flow endpoint health, send fewer messages meanwhile.", Slug: "flow-throttled", Retryable: true},
+	{Code: 133000, Title: "Incomplete deregistration", Meaning: "A previous deregistration of the phone number failed.", Action: "Deregister the phone number again before registering it.", Slug: "incomplete-deregistration"},
+	{Code: 133004, Title: "Server temporarily unavailable", Meaning: "The server is temporarily unavailable.", Action: "Retry later.", Slug: "server-unavailable", Retryable: true},
+	{Code: 133005, Title: "Two step verification PIN mismatch", Meaning: "The two step verification PIN is wrong.", Action: "Use the right PIN, or reset it in WhatsApp Manager.", Slug: "two-step-pin-mismatch"},
+	{Code: 133006, Title: "Phone number verification needed", Meaning: "The phone number must be verified before it is registered.", Action: "Verify the phone number with a code.", Slug: "verification-needed"},
+	{Code: 133008, Title: "Too many two step verification PIN guesses", Meaning: "Too many wrong PINs were tried.", Action: "Wait for the time given in the details before trying again.", Slug: "two-step-pin-guesses"},
+	{Code: 133009, Title: "Two step verification PIN guessed too fast", Meaning: "The PIN was tried too quickly.", Action: "Wait before trying again.", Slug: "two-step-pin-too-fast", Retryable: true},
+	{Code: 133010, Title: "Phone number not registered", Meaning: "The phone number is not registered with the Cloud API.", Action: "Register the phone number.", Slug: "not-registered"},
+	{Code: 133015, Title: "Registration too soon", Meaning: "The phone number was deleted recently and cannot be registered yet.", Action: "Wait a few minutes before registering it.", Slug: "registration-too-soon", Retryable: true},
+	{Code: 135000, Title: "Generic user error", Meaning: "The message failed because of an unknown error with the request parameters.", Action: "Check the request, report it with the fbtrace_id if it persists.", Slug: "generic-user-error"},
+	{Code: CodeIdentityChanged, Title: "Recipient identity changed", Meaning: "The identity of the recipient changed since the identity key hash sent with the message.", Action: "Confirm the identity of the recipient, then resend without the stale hash.", Slug: "identity-changed"},
+}
+
+//nolint:gochecknoglobals
+var catalog = func() map[catalogKey]Explanation {
+	entries := make(map[catalogKey]Explanation, len(catalogEntries))
+	for _, entry := range catalogEntries {
+		entries[catalogKey{code: entry.Code, subcode: entry.Subcode}] = entry
+	}
+
+	return entries
+}()
+
+// Lookup returns the explanation of the error code and subcode. The explanations of a code
+// apply to all its subcodes unless a subcode has its own, and the 137000 series share the
+// explanation of CodeIdentityChanged.
+func Lookup(code, subcode int) (*Explanation, bool) {
+	keys := []catalogKey{{code: code, subcode: subcode}, {code: code}}
+	if code > CodeIdentityChanged && code < CodeIdentityChanged+1000 {
+		keys = append(keys, catalogKey{code: CodeIdentityChanged})
+	}
+	for _, key := range keys {
+		if entry, ok := catalog[key]; ok {
+			entry.Code, entry.Subcode = code, subcode
+
+			return &entry, true
+		}
+	}
+
+	return nil, false
+}
+
+// Explain returns the explanation of the error from the catalog, see Lookup. The codes missing
+// from the catalog are explained with the title and message returned by the API.
+func (e *Error) Explain() *Explanation {
+	if explanation, ok := Lookup(e.Code, e.Subcode); ok {
+		return explanation
+	}
+
+	explanation := &Explanation{
+		Code:    e.Code,
+		Subcode: e.Subcode,
+		Title:   e.UserTitle,
+		Meaning: e.Message,
+		Action:  e.UserMsg,
+	}
+	if explanation.Title == "" {
+		explanation.Title = "Unknown error"
+	}
+	if e.Data != nil && e.Data.Details != "" {
+		explanation.Meaning = e.Data.Details
+	}
+	if explanation.Action == "" {
+		explanation.Action = "See " + DocsURL + "."
+	}
+
+	return explanation
+}
+
+// Explain returns the explanation of the WhatsApp error wrapped by err, see Error.Explain.
+func Explain(err error) (*Explanation, bool) {
+	var e *Error
+	if !errors.As(err, &e) {
+		return nil, false
+	}
+
+	return e.Explain(), true
+}
+
+// Link returns the link to the page of the explanation under base, like a runbook, or
+// DocsURL when the explanation has no slug.
+func (x *Explanation) Link(base string) string {
+	if x.Slug == "" || base == "" {
+		return DocsURL
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + x.Slug
+}
+
+// String returns the explanation on a single line, e.g. for logs and alerts.
+func (x *Explanation) String() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(x.Code))
+	if x.Subcode != 0 {
+		b.WriteString("/" + strconv.Itoa(x.Subcode))
+	}
+	b.WriteString(" " + x.Title)
+	if x.Meaning != "" {
+		b.WriteString(": " + x.Meaning)
+	}
+	if x.Action != "" {
+		b.WriteString(" Action: " + x.Action)
+	}
+
+	return b.String()
+}
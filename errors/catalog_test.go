@@ -0,0 +1,130 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package errors
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		err       *Error
+		title     string
+		slug      string
+		retryable bool
+	}{
+		{
+			name:  "code",
+			err:   &Error{Code: CodeReEngagement},
+			title: "Customer service window closed",
+			slug:  "re-engagement-window",
+		},
+		{
+			name:  "subcode",
+			err:   &Error{Code: CodeAccessTokenInvalid, Subcode: 463},
+			title: "Access token expired",
+			slug:  "access-token-expired",
+		},
+		{
+			name:  "unknown subcode",
+			err:   &Error{Code: CodeAccessTokenInvalid, Subcode: 999},
+			title: "Access token expired or invalid",
+			slug:  "access-token-invalid",
+		},
+		{
+			name:  "identity series",
+			err:   &Error{Code: 137002},
+			title: "Recipient identity changed",
+			slug:  "identity-changed",
+		},
+		{
+			name:      "retryable",
+			err:       &Error{Code: CodePairRateLimit},
+			title:     "Pair rate limit",
+			slug:      "pair-rate-limit",
+			retryable: true,
+		},
+		{
+			name: "unknown code",
+			err: &Error{
+				Code:      999999,
+				Message:   "(#999999) Something new",
+				UserTitle: "Something new",
+				Data:      &ErrorData{Details: "New details"},
+			},
+			title: "Something new",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			explanation, ok := Explain(fmt.Errorf("send: %w", tt.err))
+			if !ok {
+				t.Fatal("Explain() ok = false")
+			}
+			if explanation.Title != tt.title || explanation.Slug != tt.slug || explanation.Retryable != tt.retryable {
+				t.Errorf("Explain() = %+v, want title %q, slug %q, retryable %t",
+					explanation, tt.title, tt.slug, tt.retryable)
+			}
+			if explanation.Code != tt.err.Code || explanation.Subcode != tt.err.Subcode {
+				t.Errorf("Explain() code = %d/%d, want %d/%d",
+					explanation.Code, explanation.Subcode, tt.err.Code, tt.err.Subcode)
+			}
+			if explanation.Meaning == "" || explanation.Action == "" {
+				t.Errorf("Explain() = %+v, want a meaning and an action", explanation)
+			}
+		})
+	}
+
+	if _, ok := Explain(errTest); ok {
+		t.Error("Explain() of a non WhatsApp error ok = true")
+	}
+}
+
+func TestExplanation_Link(t *testing.T) {
+	t.Parallel()
+	explanation, _ := Lookup(CodeTemplateParamMismatch, 0)
+	if got, want := explanation.Link("https://runbooks.example.com/whatsapp/"),
+		"https://runbooks.example.com/whatsapp/template-parameter-count"; got != want {
+		t.Errorf("Link() = %s, want %s", got, want)
+	}
+	if got := explanation.Link(""); got != DocsURL {
+		t.Errorf("Link() = %s, want %s", got, DocsURL)
+	}
+	if s := explanation.String(); !strings.HasPrefix(s, "132000 Template parameter count mismatch: ") {
+		t.Errorf("String() = %s", s)
+	}
+}
+
+func TestCatalogSlugsAreUnique(t *testing.T) {
+	t.Parallel()
+	slugs := map[string]bool{}
+	for _, entry := range catalogEntries {
+		if entry.Slug == "" || slugs[entry.Slug] {
+			t.Errorf("entry %d/%d has an empty or duplicate slug %q", entry.Code, entry.Subcode, entry.Slug)
+		}
+		slugs[entry.Slug] = true
+	}
+}
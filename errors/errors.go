@@ -133,3 +133,74 @@ func (e *Error) String() string {
 func (e *Error) Error() string {
 	return fmt.Sprintf("whatsapp: %s", strings.ToLower(e.String()))
 }
+
+const (
+	// CodeAccessTokenInvalid is the error code returned when the access token has expired,
+	// has been revoked or is otherwise invalid.
+	CodeAccessTokenInvalid = 190
+
+	// CodeRateLimit is returned when the WhatsApp Business Account reached its rate limit.
+	CodeRateLimit = 80007
+
+	// CodeThroughputLimit is returned when a phone number sends more messages per second
+	// than its throughput allows.
+	CodeThroughputLimit = 130429
+
+	// CodeUndeliverable is returned when a message cannot be delivered to the recipient, for
+	// example because the number is not on WhatsApp.
+	CodeUndeliverable = 131026
+
+	// CodeEcosystemEngagement is returned when Meta chose not to deliver a marketing message
+	// to the recipient, typically one that receives too many of them.
+	CodeEcosystemEngagement = 131049
+
+	// CodeReEngagement is returned when a free form message is sent more than 24 hours after
+	// the recipient last replied, only template messages can be sent then.
+	CodeReEngagement = 131047
+
+	// CodePairRateLimit is returned when too many messages are sent to the same recipient
+	// in a short period of time.
+	CodePairRateLimit = 131056
+
+	// CodeTemplateParamMismatch is returned when the number of parameters does not match the
+	// number of placeholders of the template.
+	CodeTemplateParamMismatch = 132000
+
+	// CodeIdentityChanged is returned when a message is sent with a recipient identity key
+	// hash that no longer matches the identity of the recipient. The codes from 137000 to
+	// 137999 are identity errors, see IsIdentityChangeError.
+	CodeIdentityChanged = 137000
+)
+
+// Code returns the error code of the WhatsApp error wrapped by err.
+func Code(err error) (int, bool) {
+	var e *Error
+	if !errors.As(err, &e) {
+		return 0, false
+	}
+
+	return e.Code, true
+}
+
+// IsAccessTokenError reports whether err is a WhatsApp error with code CodeAccessTokenInvalid.
+func IsAccessTokenError(err error) bool {
+	var e *Error
+
+	return errors.As(err, &e) && e.Code == CodeAccessTokenInvalid
+}
+
+// IsThroughputError reports whether err is a WhatsApp error with code CodeRateLimit or
+// CodeThroughputLimit, sent when messages are sent faster than allowed.
+func IsThroughputError(err error) bool {
+	var e *Error
+
+	return errors.As(err, &e) && (e.Code == CodeRateLimit || e.Code == CodeThroughputLimit)
+}
+
+// IsIdentityChangeError reports whether err is a WhatsApp error of the 137000 series, sent
+// when the identity of the recipient changed.
+func IsIdentityChangeError(err error) bool {
+	var e *Error
+
+	return errors.As(err, &e) && e.Code >= CodeIdentityChanged && e.Code < CodeIdentityChanged+1000 //nolint:gomnd
+}
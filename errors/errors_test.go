@@ -21,6 +21,7 @@ package errors
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -68,3 +69,19 @@ func TestIsError(t *testing.T) {
 		})
 	}
 }
+
+func TestIsIdentityChangeError(t *testing.T) {
+	t.Parallel()
+	for code, want := range map[int]bool{
+		CodeIdentityChanged:    true,
+		137001:                 true,
+		138000:                 false,
+		CodeReEngagement:       false,
+		CodeAccessTokenInvalid: false,
+	} {
+		err := fmt.Errorf("send: %w", &Error{Code: code})
+		if got := IsIdentityChangeError(err); got != want {
+			t.Errorf("IsIdentityChangeError(code %d) = %v, want %v", code, got, want)
+		}
+	}
+}
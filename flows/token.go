@@ -0,0 +1,232 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package flows helps with the WhatsApp Flows that a business sends to its customers.
+//
+// A Flow is sent with a flow_token, an opaque string that WhatsApp returns in the data
+// exchange requests to the Flow endpoint and in the nfm_reply message sent when the customer
+// completes the Flow. A TokenSigner mints tokens that carry the tenant, the session and any
+// other data as HMAC-SHA256 signed claims, so that they are decoded from the token itself
+// when it comes back and a forged or altered token is rejected:
+//
+//	signer := flows.NewTokenSigner(&flows.TokenSignerConfig{Keys: [][]byte{key}})
+//	token, err := signer.Mint(&flows.TokenClaims{Tenant: "acme", Session: sessionID})
+//	// send the Flow with token
+//
+//	listener.OnMessageReceived(func(ctx context.Context, nctx *webhooks.NotificationContext,
+//		message *webhooks.Message,
+//	) error {
+//		claims, err := signer.ValidateReply(message.Interactive)
+//		...
+//	})
+package flows
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// DefaultTokenTTL is how long a flow token is valid when TokenSignerConfig.TTL is zero.
+const DefaultTokenTTL = 24 * time.Hour
+
+// tokenVersion prefixes the tokens, it changes with their format.
+const tokenVersion = "v1"
+
+var (
+	ErrNoSigningKey     = errors.New("flows: no signing key")
+	ErrMalformedToken   = errors.New("flows: malformed flow token")
+	ErrInvalidSignature = errors.New("flows: invalid flow token signature")
+	ErrTokenExpired     = errors.New("flows: flow token expired")
+	ErrNoFlowReply      = errors.New("flows: not a flow reply")
+)
+
+type (
+	// TokenClaims are the values carried by a flow token. IssuedAt and ExpiresAt are set by
+	// TokenSigner.Mint. The claims are signed, not encrypted: the customer's device can read
+	// them, so they must not hold secrets.
+	TokenClaims struct {
+		Tenant    string            `json:"t,omitempty"`
+		Session   string            `json:"s,omitempty"`
+		Data      map[string]string `json:"d,omitempty"`
+		IssuedAt  time.Time         `json:"-"`
+		ExpiresAt time.Time         `json:"-"`
+	}
+
+	// TokenSignerConfig configures a TokenSigner. Keys are the HMAC keys, tokens are signed
+	// with the first one and validated with any of them, so that a key is rotated by adding
+	// the new key first and removing the old one once its tokens expired. TTL defaults to
+	// DefaultTokenTTL and Clock to the system clock.
+	TokenSignerConfig struct {
+		Keys  [][]byte
+		TTL   time.Duration
+		Clock clock.Clock
+	}
+
+	// TokenSigner mints and validates flow tokens.
+	TokenSigner struct {
+		keys  [][]byte
+		ttl   time.Duration
+		clock clock.Clock
+	}
+
+	// DataExchangeRequest is the decrypted body of a request sent by WhatsApp to the
+	// endpoint of a Flow. Action is INIT, BACK, data_exchange or ping, the latter without
+	// flow token.
+	DataExchangeRequest struct {
+		Version   string         `json:"version"`
+		Action    string         `json:"action"`
+		Screen    string         `json:"screen,omitempty"`
+		Data      map[string]any `json:"data,omitempty"`
+		FlowToken string         `json:"flow_token,omitempty"`
+	}
+
+	// tokenPayload is the signed part of a token.
+	tokenPayload struct {
+		*TokenClaims
+		IssuedAt  int64 `json:"iat"`
+		ExpiresAt int64 `json:"exp"`
+	}
+)
+
+// NewTokenSigner returns a TokenSigner configured by config.
+func NewTokenSigner(config *TokenSignerConfig) *TokenSigner {
+	if config == nil {
+		config = &TokenSignerConfig{}
+	}
+	signer := &TokenSigner{
+		keys:  config.Keys,
+		ttl:   config.TTL,
+		clock: clock.OrSystem(config.Clock),
+	}
+	if signer.ttl <= 0 {
+		signer.ttl = DefaultTokenTTL
+	}
+
+	return signer
+}
+
+// Mint returns a flow token carrying claims, valid for the TTL of the signer.
+func (signer *TokenSigner) Mint(claims *TokenClaims) (string, error) {
+	if len(signer.keys) == 0 {
+		return "", ErrNoSigningKey
+	}
+	if claims == nil {
+		claims = &TokenClaims{}
+	}
+	now := signer.clock.Now()
+	payload, err := json.Marshal(&tokenPayload{
+		TokenClaims: claims,
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   now.Add(signer.ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("flows: mint token: %w", err)
+	}
+	signed := tokenVersion + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(signer.keys[0], signed)), nil
+}
+
+// Validate checks the signature and the expiry of token and returns its claims.
+func (signer *TokenSigner) Validate(token string) (*TokenClaims, error) {
+	if len(signer.keys) == 0 {
+		return nil, ErrNoSigningKey
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenVersion {
+		return nil, ErrMalformedToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if !signer.verify(parts[0]+"."+parts[1], signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	payload := tokenPayload{TokenClaims: &TokenClaims{}}
+	if err = json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+	claims := payload.TokenClaims
+	claims.IssuedAt = time.Unix(payload.IssuedAt, 0)
+	claims.ExpiresAt = time.Unix(payload.ExpiresAt, 0)
+	if !signer.clock.Now().Before(claims.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+// ValidateRequest validates the flow token of a data exchange request.
+func (signer *TokenSigner) ValidateRequest(request *DataExchangeRequest) (*TokenClaims, error) {
+	if request == nil || request.FlowToken == "" {
+		return nil, ErrMalformedToken
+	}
+
+	return signer.Validate(request.FlowToken)
+}
+
+// ValidateReply validates the flow token of the nfm_reply sent when the customer completes
+// a Flow. It fails with ErrNoFlowReply for the other interactive replies.
+func (signer *TokenSigner) ValidateReply(interactive *webhooks.Interactive) (*TokenClaims, error) {
+	if interactive == nil || interactive.NFMReply == nil {
+		return nil, ErrNoFlowReply
+	}
+	token, err := interactive.NFMReply.FlowToken()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedToken, err)
+	}
+	if token == "" {
+		return nil, ErrMalformedToken
+	}
+
+	return signer.Validate(token)
+}
+
+// verify reports whether signature is the signature of signed with any of the keys.
+func (signer *TokenSigner) verify(signed string, signature []byte) bool {
+	for _, key := range signer.keys {
+		if hmac.Equal(signature, sign(key, signed)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sign(key []byte, signed string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+
+	return mac.Sum(nil)
+}
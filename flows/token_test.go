@@ -0,0 +1,121 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package flows
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestTokenSigner(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	signer := NewTokenSigner(&TokenSignerConfig{Keys: [][]byte{[]byte("key-1")}, TTL: time.Hour, Clock: clk})
+	token, err := signer.Mint(&TokenClaims{Tenant: "acme", Session: "session-1", Data: map[string]string{"order": "7"}})
+	if err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+
+	claims, err := signer.Validate(token)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if claims.Tenant != "acme" || claims.Session != "session-1" || claims.Data["order"] != "7" ||
+		!claims.IssuedAt.Equal(now) || !claims.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Validate() = %+v", claims)
+	}
+
+	// a rotated signer still validates the tokens of the old key
+	rotated := NewTokenSigner(&TokenSignerConfig{Keys: [][]byte{[]byte("key-2"), []byte("key-1")}, Clock: clk})
+	if _, err = rotated.Validate(token); err != nil {
+		t.Errorf("Validate() with a rotated key error = %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	forged := NewTokenSigner(&TokenSignerConfig{Keys: [][]byte{[]byte("other")}, Clock: clk})
+	forgedToken, _ := forged.Mint(&TokenClaims{Tenant: "evil"})
+	forgedParts := strings.Split(forgedToken, ".")
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "other key", token: forgedToken, want: ErrInvalidSignature},
+		{name: "altered claims", token: parts[0] + "." + forgedParts[1] + "." + parts[2], want: ErrInvalidSignature},
+		{name: "not a token", token: "session-1", want: ErrMalformedToken},
+		{name: "other version", token: "v0." + parts[1] + "." + parts[2], want: ErrMalformedToken},
+		{name: "bad signature encoding", token: parts[0] + "." + parts[1] + ".!", want: ErrMalformedToken},
+	}
+	for _, tt := range tests {
+		if _, err = signer.Validate(tt.token); !errors.Is(err, tt.want) {
+			t.Errorf("Validate(%s) error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	clk.Advance(time.Hour)
+	if _, err = signer.Validate(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Validate() after the TTL error = %v, want %v", err, ErrTokenExpired)
+	}
+}
+
+func TestTokenSignerValidateReply(t *testing.T) {
+	t.Parallel()
+	signer := NewTokenSigner(&TokenSignerConfig{Keys: [][]byte{[]byte("key")}})
+	token, err := signer.Mint(&TokenClaims{Session: "session-1"})
+	if err != nil {
+		t.Fatalf("Mint() error = %v", err)
+	}
+
+	reply := &webhooks.Interactive{
+		Type: webhooks.InteractiveNFMReply,
+		NFMReply: &webhooks.NFMReply{
+			Name:         "flow",
+			ResponseJSON: `{"amount":"10","flow_token":"` + token + `"}`,
+		},
+	}
+	if claims, err := signer.ValidateReply(reply); err != nil || claims.Session != "session-1" {
+		t.Errorf("ValidateReply() = %+v, %v", claims, err)
+	}
+	if claims, err := signer.ValidateRequest(&DataExchangeRequest{Action: "INIT", FlowToken: token}); err != nil ||
+		claims.Session != "session-1" {
+		t.Errorf("ValidateRequest() = %+v, %v", claims, err)
+	}
+
+	buttonReply := &webhooks.Interactive{
+		Type:        webhooks.InteractiveButtonReply,
+		ButtonReply: &webhooks.ButtonReply{ID: "1"},
+	}
+	if _, err = signer.ValidateReply(buttonReply); !errors.Is(err, ErrNoFlowReply) {
+		t.Errorf("ValidateReply() of a button reply error = %v, want %v", err, ErrNoFlowReply)
+	}
+	reply.NFMReply.ResponseJSON = "{"
+	if _, err = signer.ValidateReply(reply); !errors.Is(err, ErrMalformedToken) {
+		t.Errorf("ValidateReply() of invalid JSON error = %v, want %v", err, ErrMalformedToken)
+	}
+	if _, err = NewTokenSigner(nil).Mint(nil); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("Mint() without keys error = %v, want %v", err, ErrNoSigningKey)
+	}
+}
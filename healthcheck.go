@@ -0,0 +1,130 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// PlatformTypeCloudAPI is the platform type of phone numbers registered with the Cloud API.
+const PlatformTypeCloudAPI = "CLOUD_API"
+
+// ErrUnhealthy is returned by Client.HealthCheck when one of the checks fails.
+var ErrUnhealthy = errors.New("whatsapp: unhealthy")
+
+type (
+	// HealthStatus is the result of Client.HealthCheck.
+	//
+	// Reachable is true when the API responded, Authenticated when it accepted the access
+	// token and Registered when the phone number is registered with the Cloud API. Err is
+	// the error of the check call, if any. PhoneNumber is set when the call succeeded.
+	HealthStatus struct {
+		Reachable     bool
+		Authenticated bool
+		Registered    bool
+		PhoneNumber   *PhoneNumberStatus
+		Latency       time.Duration
+		CheckedAt     time.Time
+		Err           error
+	}
+
+	// PhoneNumberStatus holds the phone number fields read by Client.HealthCheck.
+	PhoneNumberStatus struct {
+		ID                 string `json:"id"`
+		DisplayPhoneNumber string `json:"display_phone_number"`
+		VerifiedName       string `json:"verified_name"`
+		QualityRating      string `json:"quality_rating"`
+		Status             string `json:"status"`
+		PlatformType       string `json:"platform_type"`
+	}
+)
+
+// Healthy reports whether all the checks passed.
+func (status *HealthStatus) Healthy() bool {
+	return status.Reachable && status.Authenticated && status.Registered
+}
+
+// HealthCheck reads a few fields of the phone number, a cheap authenticated call, and reports
+// whether the API is reachable, the access token valid and the phone number registered. It is
+// meant for readiness probes, the returned error wraps ErrUnhealthy when a check fails and the
+// status is always returned.
+func (client *Client) HealthCheck(ctx context.Context) (*HealthStatus, error) {
+	cctx := client.context(ctx)
+	reqCtx := &whttp.RequestContext{
+		Name:       "health check",
+		BaseURL:    cctx.baseURL,
+		ApiVersion: cctx.apiVersion,
+		SenderID:   cctx.phoneNumberID,
+	}
+	request := &whttp.Request{
+		Context: reqCtx,
+		Method:  http.MethodGet,
+		Query: map[string]string{
+			"fields": "id,display_phone_number,verified_name,quality_rating,status,platform_type",
+		},
+		Bearer: cctx.accessToken,
+	}
+
+	start := client.clock.Now()
+	phoneNumber, err := whttp.SendTyped[PhoneNumberStatus](ctx, client.sender, request)
+	status := &HealthStatus{
+		CheckedAt: start,
+		Latency:   client.clock.Now().Sub(start),
+		Err:       err,
+	}
+
+	var responseErr *whttp.ResponseError
+	switch {
+	case err == nil:
+		status.Reachable = true
+		status.Authenticated = true
+		status.PhoneNumber = phoneNumber
+		status.Registered = phoneNumber.PlatformType == PlatformTypeCloudAPI
+	case errors.As(err, &responseErr):
+		status.Reachable = true
+		status.Authenticated = responseErr.Code != http.StatusUnauthorized && !werrors.IsAccessTokenError(err)
+	}
+
+	if !status.Healthy() {
+		if err == nil {
+			err = fmt.Errorf("phone number %s is not registered with the cloud api, platform type %q",
+				cctx.phoneNumberID, phoneNumber.PlatformType)
+		}
+
+		return status, fmt.Errorf("health check: %w: %w", ErrUnhealthy, err)
+	}
+
+	return status, nil
+}
+
+// Ready runs HealthCheck and returns its error, it is meant for readiness probes, see
+// webhooks.HealthConfig.
+func (client *Client) Ready(ctx context.Context) error {
+	_, err := client.HealthCheck(ctx)
+
+	return err
+}
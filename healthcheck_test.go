@@ -0,0 +1,102 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientHealthCheck(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name              string
+		status            int
+		body              string
+		wantAuthenticated bool
+		wantRegistered    bool
+		wantReachable     bool
+	}{
+		{
+			name:              "healthy",
+			status:            http.StatusOK,
+			body:              `{"id":"phone_1","status":"CONNECTED","platform_type":"CLOUD_API"}`,
+			wantReachable:     true,
+			wantAuthenticated: true,
+			wantRegistered:    true,
+		},
+		{
+			name:              "not registered",
+			status:            http.StatusOK,
+			body:              `{"id":"phone_1","platform_type":"NOT_APPLICABLE"}`,
+			wantReachable:     true,
+			wantAuthenticated: true,
+		},
+		{
+			name:          "invalid token",
+			status:        http.StatusUnauthorized,
+			body:          `{"error":{"code":190,"message":"Error validating access token"}}`,
+			wantReachable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var fields string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fields = r.URL.Query().Get("fields")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithAccessToken("token"))
+			status, err := client.HealthCheck(context.TODO())
+			healthy := tt.wantReachable && tt.wantAuthenticated && tt.wantRegistered
+			if healthy != (err == nil) || (!healthy && !errors.Is(err, ErrUnhealthy)) {
+				t.Fatalf("HealthCheck() error = %v, want healthy %v", err, healthy)
+			}
+			if status.Reachable != tt.wantReachable || status.Authenticated != tt.wantAuthenticated ||
+				status.Registered != tt.wantRegistered {
+				t.Errorf("HealthCheck() = %+v", status)
+			}
+			if fields == "" {
+				t.Error("HealthCheck() did not request the phone number fields")
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"))
+		status, err := client.HealthCheck(context.TODO())
+		if !errors.Is(err, ErrUnhealthy) || status.Reachable {
+			t.Errorf("HealthCheck() = %+v, %v, want unreachable", status, err)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// AppSecretProof returns the appsecret_proof for the access token, the hex encoded
+// HMAC-SHA256 of the token keyed with the app secret.
+//
+// See https://developers.facebook.com/docs/graph-api/securing-requests#appsecret_proof
+func AppSecretProof(accessToken, appSecret string) string {
+	mac := hmac.New(sha256.New, []byte(appSecret))
+	mac.Write([]byte(accessToken))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AccessToken returns the access token the request is sent with. It is Request.Bearer or,
+// for requests built with the token in the access_token query parameter, that parameter.
+func (request *Request) AccessToken() string {
+	if request.Bearer != "" {
+		return request.Bearer
+	}
+
+	return request.Query[accessTokenQueryKey]
+}
+
+// AppSecretProofMiddleware returns a Middleware that adds the appsecret_proof query parameter
+// to every request that carries an access token. Requests without a token are passed as is.
+func AppSecretProofMiddleware(appSecret string) Middleware {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+			token := request.AccessToken()
+			if token == "" || appSecret == "" {
+				return next.Send(ctx, request, v)
+			}
+
+			query := make(map[string]string, len(request.Query)+1)
+			for key, value := range request.Query {
+				query[key] = value
+			}
+			query["appsecret_proof"] = AppSecretProof(token, appSecret)
+
+			r := *request
+			r.Query = query
+
+			return next.Send(ctx, &r, v)
+		})
+	}
+}
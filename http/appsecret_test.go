@@ -0,0 +1,92 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAppSecretProofMiddleware(t *testing.T) {
+	t.Parallel()
+	// echo -n "token" | openssl dgst -sha256 -hmac "secret"
+	const want = "e941110e3d2bfe82621f0e3e1434730d7305d106c5f68c87165d0b27a4611a4a"
+	if got := AppSecretProof("token", "secret"); got != want {
+		t.Fatalf("AppSecretProof() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name    string
+		request *Request
+		want    string
+	}{
+		{
+			name:    "bearer",
+			request: &Request{Context: &RequestContext{}, Bearer: "token"},
+			want:    want,
+		},
+		{
+			name: "bearer preferred over query",
+			request: &Request{
+				Context: &RequestContext{},
+				Bearer:  "token",
+				Query:   map[string]string{"access_token": "stale"},
+			},
+			want: want,
+		},
+		{
+			name: "query",
+			request: &Request{
+				Context: &RequestContext{},
+				Query:   map[string]string{"access_token": "token"},
+			},
+			want: want,
+		},
+		{
+			name:    "no token",
+			request: &Request{Context: &RequestContext{}},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got string
+			sender := Chain(SenderFunc(func(ctx context.Context, request *Request, v any) error {
+				got = request.Query["appsecret_proof"]
+
+				return nil
+			}), AppSecretProofMiddleware("secret"))
+
+			original := len(tt.request.Query)
+			if err := sender.Send(context.TODO(), tt.request, nil); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("appsecret_proof = %q, want %q", got, tt.want)
+			}
+			if len(tt.request.Query) != original {
+				t.Error("middleware modified the caller's query parameters")
+			}
+		})
+	}
+}
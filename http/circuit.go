@@ -0,0 +1,304 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+const (
+	DefaultCircuitFailureThreshold = 5
+	DefaultCircuitOpenTimeout      = 30 * time.Second
+	DefaultCircuitHalfOpenProbes   = 1
+)
+
+type (
+	// CircuitState is the state of a circuit. A closed circuit lets all requests through, an open
+	// circuit rejects all requests with ErrCircuitOpen and a half-open circuit lets a limited number
+	// of probe requests through to check if the endpoint has recovered.
+	CircuitState int
+
+	// CircuitBreakerConfig configures a CircuitBreaker. Zero values are replaced by defaults.
+	//
+	// FailureThreshold is the number of consecutive failures (5xx responses, timeouts and
+	// connection errors) after which the circuit opens. OpenTimeout is how long the circuit
+	// stays open before it half-opens. HalfOpenProbes is the number of requests let through
+	// when half-open, the circuit closes after that many successful probes and opens again
+	// on the first failed one.
+	//
+	// KeyFunc returns the key that identifies the endpoint a request is sent to, each key has
+	// its own circuit. By default, the request name set by Do is used, falling back to the
+	// request method and path.
+	//
+	// OnStateChange is called every time a circuit changes its state. It is called while the
+	// breaker is locked, so it must not call back into the CircuitBreaker.
+	//
+	// Clock is used to time the open state, it defaults to clock.System.
+	CircuitBreakerConfig struct {
+		FailureThreshold int
+		OpenTimeout      time.Duration
+		HalfOpenProbes   int
+		KeyFunc          func(request *http.Request) string
+		OnStateChange    func(key string, from, to CircuitState)
+		Clock            clock.Clock
+	}
+
+	// CircuitBreaker keeps a circuit per endpoint and rejects requests to endpoints that
+	// keep failing, protecting both the caller and the downstream systems during outages.
+	// Use CircuitBreaker.Client or CircuitBreaker.Transport to put it in front of the requests.
+	CircuitBreaker struct {
+		mu       sync.Mutex
+		config   CircuitBreakerConfig
+		circuits map[string]*circuit
+	}
+
+	circuit struct {
+		state     CircuitState
+		failures  int
+		openedAt  time.Time
+		inflight  int
+		successes int
+	}
+
+	circuitTransport struct {
+		breaker *CircuitBreaker
+		next    http.RoundTripper
+	}
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// NewCircuitBreaker creates a new CircuitBreaker, if config is nil the defaults are used.
+func NewCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
+	cfg := CircuitBreakerConfig{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = DefaultCircuitFailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultCircuitOpenTimeout
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = DefaultCircuitHalfOpenProbes
+	}
+	if cfg.KeyFunc == nil {
+		cfg.KeyFunc = circuitKey
+	}
+	cfg.Clock = clock.OrSystem(cfg.Clock)
+
+	return &CircuitBreaker{
+		config:   cfg,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// circuitKey is the default CircuitBreakerConfig.KeyFunc.
+func circuitKey(request *http.Request) string {
+	if name, ok := request.Context().Value(requestNameKey("request-name")).(string); ok && name != "" {
+		return name
+	}
+
+	return request.Method + " " + request.URL.Path
+}
+
+// State returns the current state of the circuit identified by key.
+func (cb *CircuitBreaker) State(key string) CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && cb.config.Clock.Now().Sub(c.openedAt) >= cb.config.OpenTimeout {
+		return CircuitHalfOpen
+	}
+
+	return c.state
+}
+
+// Client returns a shallow copy of client whose transport is wrapped by the circuit breaker.
+// If client is nil, http.DefaultClient is used.
+func (cb *CircuitBreaker) Client(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c := *client
+	c.Transport = cb.Transport(client.Transport)
+
+	return &c
+}
+
+// Transport wraps next with the circuit breaker. If next is nil, http.DefaultTransport is used.
+func (cb *CircuitBreaker) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &circuitTransport{breaker: cb, next: next}
+}
+
+func (t *circuitTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	key := t.breaker.config.KeyFunc(request)
+	if err := t.breaker.allow(key); err != nil {
+		return nil, err
+	}
+
+	response, err := t.next.RoundTrip(request)
+	t.breaker.done(key, classifyOutcome(response, err))
+
+	return response, err //nolint:wrapcheck
+}
+
+// allow checks if a request to the endpoint identified by key can be sent.
+func (cb *CircuitBreaker) allow(key string) error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		c = &circuit{state: CircuitClosed}
+		cb.circuits[key] = c
+	}
+
+	switch c.state {
+	case CircuitClosed:
+		return nil
+	case CircuitOpen:
+		if cb.config.Clock.Now().Sub(c.openedAt) < cb.config.OpenTimeout {
+			return fmt.Errorf("%w: %s", ErrCircuitOpen, key)
+		}
+		cb.transition(key, c, CircuitHalfOpen)
+		c.inflight++
+
+		return nil
+	case CircuitHalfOpen:
+		if c.inflight >= cb.config.HalfOpenProbes {
+			return fmt.Errorf("%w: %s: waiting for probes", ErrCircuitOpen, key)
+		}
+		c.inflight++
+
+		return nil
+	default:
+		return nil
+	}
+}
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored
+)
+
+// classifyOutcome decides if a round trip counts as a failure. Server errors, timeouts
+// and connection errors are failures. Requests canceled by the caller are ignored.
+func classifyOutcome(response *http.Response, err error) outcome {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return outcomeIgnored
+		}
+
+		return outcomeFailure
+	}
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return outcomeFailure
+	}
+
+	return outcomeSuccess
+}
+
+// done records the outcome of a request sent to the endpoint identified by key.
+func (cb *CircuitBreaker) done(key string, result outcome) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	c := cb.circuits[key]
+
+	switch c.state {
+	case CircuitClosed:
+		switch result {
+		case outcomeFailure:
+			c.failures++
+			if c.failures >= cb.config.FailureThreshold {
+				cb.transition(key, c, CircuitOpen)
+			}
+		case outcomeSuccess:
+			c.failures = 0
+		case outcomeIgnored:
+		}
+	case CircuitHalfOpen:
+		c.inflight--
+		switch result {
+		case outcomeFailure:
+			cb.transition(key, c, CircuitOpen)
+		case outcomeSuccess:
+			c.successes++
+			if c.successes >= cb.config.HalfOpenProbes {
+				cb.transition(key, c, CircuitClosed)
+			}
+		case outcomeIgnored:
+		}
+	case CircuitOpen:
+		// a request that was sent before the circuit opened, nothing to do.
+	}
+}
+
+func (cb *CircuitBreaker) transition(key string, c *circuit, to CircuitState) {
+	from := c.state
+	c.state = to
+	c.failures = 0
+	c.inflight = 0
+	c.successes = 0
+	if to == CircuitOpen {
+		c.openedAt = cb.config.Clock.Now()
+	}
+
+	if cb.config.OnStateChange != nil && from != to {
+		cb.config.OnStateChange(key, from, to)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+func TestCircuitBreaker(t *testing.T) { //nolint:paralleltest
+	var (
+		hits   int32
+		status int32 = http.StatusInternalServerError
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	var transitions []CircuitState
+	clk := clock.NewFake(time.Now())
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		Clock:            clk,
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		HalfOpenProbes:   1,
+		OnStateChange: func(key string, from, to CircuitState) {
+			transitions = append(transitions, to)
+		},
+	})
+	client := breaker.Client(http.DefaultClient)
+
+	send := func() error {
+		request := &Request{
+			Context: &RequestContext{Name: "test", BaseURL: server.URL},
+			Method:  http.MethodGet,
+		}
+
+		return Do(context.TODO(), client, request, nil)
+	}
+
+	// two failures open the circuit
+	for i := 0; i < 2; i++ {
+		if err := send(); err != nil {
+			t.Fatalf("send() error = %v", err)
+		}
+	}
+	if state := breaker.State("test"); state != CircuitOpen {
+		t.Fatalf("State() = %v, want %v", state, CircuitOpen)
+	}
+
+	// requests are rejected without reaching the server
+	if err := send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("send() error = %v, want %v", err, ErrCircuitOpen)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Fatalf("server hits = %d, want 2", got)
+	}
+
+	// after the timeout a successful probe closes the circuit
+	clk.Advance(time.Minute)
+	atomic.StoreInt32(&status, http.StatusOK)
+	if err := send(); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if state := breaker.State("test"); state != CircuitClosed {
+		t.Fatalf("State() = %v, want %v", state, CircuitClosed)
+	}
+
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions = %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transitions = %v, want %v", transitions, want)
+		}
+	}
+}
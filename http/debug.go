@@ -23,7 +23,6 @@ import (
 	"context"
 	"io"
 	"net/http"
-	"net/http/httputil"
 	"os"
 	"strings"
 )
@@ -31,17 +30,28 @@ import (
 type DebugFunc func(io.Writer) Hook
 
 // DebugHook is a hook that prints the request and response to the writer, Internally it uses
-// httputil.DumpRequestOut and httputil.DumpResponse to dump the request and response. It also
-// retrieves the request name from the context using RequestNameFromContext and print it with
-// the request and response.
+// DumpRequest and DumpResponse with DefaultRedactor to dump the request and response, so the
+// access token and other credentials are masked. It also retrieves the request name from the
+// context using RequestNameFromContext and print it with the request and response. If the
+// writer is nil, os.Stdout is used.
 func DebugHook(writer io.Writer) Hook {
+	return DebugHookWithRedactor(writer, DefaultRedactor())
+}
+
+// DebugHookWithRedactor is like DebugHook but masks credentials with redactor. Pass a
+// redactor with the app secret in Redactor.Secrets when the client uses one.
+func DebugHookWithRedactor(writer io.Writer, redactor *Redactor) Hook {
+	if writer == nil {
+		writer = os.Stdout
+	}
+
 	return func(ctx context.Context, req *http.Request, resp *http.Response) {
 		var buff strings.Builder
 		name := strings.ToUpper(RequestNameFromContext(ctx))
 		buff.WriteString(name)
 		buff.WriteString("\n")
 		if req != nil {
-			b, err := httputil.DumpRequestOut(req, true)
+			b, err := DumpRequest(req, true, redactor)
 			if err == nil {
 				buff.Write(b)
 				buff.WriteString("\n")
@@ -49,17 +59,13 @@ func DebugHook(writer io.Writer) Hook {
 		}
 
 		if resp != nil {
-			b, err := httputil.DumpResponse(resp, true)
+			b, err := DumpResponse(resp, true, redactor)
 			if err == nil {
 				buff.Write(b)
 				buff.WriteString("\n")
 			}
 		}
 
-		if writer == nil {
-			_, _ = os.Stdout.Write([]byte(buff.String()))
-		}
-
 		_, _ = writer.Write([]byte(buff.String()))
 	}
 }
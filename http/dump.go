@@ -0,0 +1,191 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+)
+
+// DefaultDumpMaxBodySize is the number of body bytes kept by a Dumper when MaxBodySize is zero.
+const DefaultDumpMaxBodySize = 16 << 10
+
+// Dumper produces readable dumps of requests and responses that are safe to attach to support
+// tickets: credentials are masked with Redactor (DefaultRedactor when nil), media bodies and
+// the file parts of multipart uploads are replaced with a short description, and bodies are
+// cut after MaxBodySize bytes. MaxBodySize defaults to DefaultDumpMaxBodySize, a negative value
+// keeps the whole body. Set KeepMedia to dump media bodies as they are.
+//
+// Dumper can be used inside hooks, the request and response bodies are restored after they
+// are read:
+//
+//	dumper := &whttp.Dumper{MaxBodySize: 4096}
+//	hook := func(ctx context.Context, req *http.Request, resp *http.Response) {
+//		if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+//			dump, _ := dumper.Response(resp)
+//			ticket.Attach(dump)
+//		}
+//	}
+type Dumper struct {
+	Redactor    *Redactor
+	MaxBodySize int
+	KeepMedia   bool
+}
+
+// Request returns the dump of req, including its body.
+func (d *Dumper) Request(req *http.Request) ([]byte, error) {
+	clone, err := d.redactor().RedactRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("dump request: %w", err)
+	}
+	if clone.Body, clone.ContentLength, err = d.body(clone.Body, clone.Header); err != nil {
+		return nil, fmt.Errorf("dump request: %w", err)
+	}
+	b, err := httputil.DumpRequestOut(clone, true)
+	if err != nil {
+		return nil, fmt.Errorf("dump request: %w", err)
+	}
+
+	return b, nil
+}
+
+// Response returns the dump of resp, including its body.
+func (d *Dumper) Response(resp *http.Response) ([]byte, error) {
+	clone, err := d.redactor().RedactResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("dump response: %w", err)
+	}
+	if clone.Body, clone.ContentLength, err = d.body(clone.Body, clone.Header); err != nil {
+		return nil, fmt.Errorf("dump response: %w", err)
+	}
+	b, err := httputil.DumpResponse(clone, true)
+	if err != nil {
+		return nil, fmt.Errorf("dump response: %w", err)
+	}
+
+	return b, nil
+}
+
+func (d *Dumper) redactor() *Redactor {
+	if d.Redactor == nil {
+		return DefaultRedactor()
+	}
+
+	return d.Redactor
+}
+
+// body returns the body to dump in place of body, and its length.
+func (d *Dumper) body(body io.ReadCloser, header http.Header) (io.ReadCloser, int64, error) {
+	b, err := readBody(&body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(b) == 0 {
+		return body, int64(len(b)), nil
+	}
+
+	if !d.KeepMedia {
+		mediaType, params, _ := mime.ParseMediaType(header.Get("Content-Type"))
+		switch {
+		case isMediaType(mediaType):
+			b = []byte(elided(mediaType, len(b)))
+		case mediaType == "multipart/form-data" && params["boundary"] != "":
+			b = elideMultipartFiles(b, params["boundary"])
+		}
+	}
+
+	limit := d.MaxBodySize
+	if limit == 0 {
+		limit = DefaultDumpMaxBodySize
+	}
+	if limit > 0 && len(b) > limit {
+		b = append(b[:limit:limit], fmt.Sprintf("\n[truncated %d bytes]", len(b)-limit)...)
+	}
+
+	return io.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+}
+
+// isMediaType reports whether bodies of mediaType are binary media that should not be dumped.
+func isMediaType(mediaType string) bool {
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+
+	return mediaType == "application/octet-stream" || mediaType == "application/pdf"
+}
+
+func elided(mediaType string, size int) string {
+	if mediaType == "" {
+		mediaType = "unknown type"
+	}
+
+	return fmt.Sprintf("[%s body elided, %d bytes]", mediaType, size)
+}
+
+// elideMultipartFiles replaces the content of the file parts of a multipart body. The body is
+// returned as it is if it cannot be parsed.
+func elideMultipartFiles(body []byte, boundary string) []byte {
+	var buf bytes.Buffer
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return body
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return body
+		}
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		keys := make([]string, 0, len(part.Header))
+		for key := range part.Header {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, value := range part.Header[key] {
+				fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+			}
+		}
+		buf.WriteString("\r\n")
+		if part.FileName() != "" {
+			buf.WriteString(elided(part.Header.Get("Content-Type"), len(content)))
+		} else {
+			buf.Write(content)
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDumper(t *testing.T) {
+	t.Parallel()
+	var upload bytes.Buffer
+	writer := multipart.NewWriter(&upload)
+	_ = writer.WriteField("messaging_product", "whatsapp")
+	file, _ := writer.CreateFormFile("file", "cat.jpg")
+	_, _ = file.Write(bytes.Repeat([]byte{0xff}, 2048))
+	_ = writer.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		dumper      *Dumper
+		want        []string
+		notWant     []string
+	}{
+		{
+			name:        "json body with token",
+			contentType: "application/json",
+			body:        []byte(`{"to":"255700000000"}`),
+			dumper:      &Dumper{},
+			want:        []string{`{"to":"255700000000"}`, "Authorization: REDACTED"},
+			notWant:     []string{"secret_token"},
+		},
+		{
+			name:        "media body",
+			contentType: "image/jpeg",
+			body:        bytes.Repeat([]byte{0xff}, 1024),
+			dumper:      &Dumper{},
+			want:        []string{"[image/jpeg body elided, 1024 bytes]"},
+		},
+		{
+			name:        "multipart upload",
+			contentType: writer.FormDataContentType(),
+			body:        upload.Bytes(),
+			dumper:      &Dumper{},
+			want:        []string{"whatsapp", "[application/octet-stream body elided, 2048 bytes]"},
+			notWant:     []string{"\xff"},
+		},
+		{
+			name:        "truncated",
+			contentType: "text/plain",
+			body:        []byte(strings.Repeat("a", 100)),
+			dumper:      &Dumper{MaxBodySize: 10},
+			want:        []string{strings.Repeat("a", 10) + "\n[truncated 90 bytes]"},
+			notWant:     []string{strings.Repeat("a", 11)},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := http.NewRequest(http.MethodPost, "https://graph.facebook.com/v16.0/1/media",
+				bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Authorization", "Bearer secret_token")
+
+			dump, err := tt.dumper.Request(req)
+			if err != nil {
+				t.Fatalf("Request() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(dump), want) {
+					t.Errorf("dump %q does not contain %q", dump, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(dump), notWant) {
+					t.Errorf("dump %q contains %q", dump, notWant)
+				}
+			}
+
+			// the request body is restored
+			body, _ := io.ReadAll(req.Body)
+			if !bytes.Equal(body, tt.body) {
+				t.Errorf("request body was not restored")
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+)
+
+type (
+	// ErrorCount is the number of errors returned by the API with the same Graph error code.
+	// LastMessage and LastSeen are the message and time of the latest of them and Requests
+	// counts them by request name.
+	ErrorCount struct {
+		Code        int
+		Count       int64
+		LastMessage string
+		LastSeen    time.Time
+		Requests    map[string]int64
+	}
+
+	// ErrorCounter counts the errors returned by the API by Graph error code, so that recurring
+	// problems like 131056 (pair rate limit) or 132000 (template parameter mismatch) stand out.
+	// Feed it with ErrorCounter.EventHook. It is safe for concurrent use.
+	ErrorCounter struct {
+		mu     sync.Mutex
+		clock  clock.Clock
+		counts map[int]*ErrorCount
+	}
+)
+
+// NewErrorCounter creates an ErrorCounter. If c is nil, the system clock is used.
+func NewErrorCounter(c clock.Clock) *ErrorCounter {
+	return &ErrorCounter{
+		clock:  clock.OrSystem(c),
+		counts: make(map[int]*ErrorCount),
+	}
+}
+
+// Observe counts err if it is a WhatsApp error, name is the name of the failed request.
+func (c *ErrorCounter) Observe(name string, err error) {
+	var apiErr *werrors.Error
+	if !errors.As(err, &apiErr) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count, ok := c.counts[apiErr.Code]
+	if !ok {
+		count = &ErrorCount{Code: apiErr.Code, Requests: make(map[string]int64)}
+		c.counts[apiErr.Code] = count
+	}
+	count.Count++
+	count.Requests[name]++
+	count.LastMessage = apiErr.Message
+	count.LastSeen = c.clock.Now()
+}
+
+// EventHook returns an EventHook that counts the errors of the events.
+func (c *ErrorCounter) EventHook() EventHook {
+	return func(ctx context.Context, event *Event) {
+		c.Observe(event.Name, event.Err)
+	}
+}
+
+// Count returns the number of errors seen with code.
+func (c *ErrorCounter) Count(code int) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if count, ok := c.counts[code]; ok {
+		return count.Count
+	}
+
+	return 0
+}
+
+// Snapshot returns a copy of the counts, the most frequent codes first.
+func (c *ErrorCounter) Snapshot() []ErrorCount {
+	c.mu.Lock()
+	snapshot := make([]ErrorCount, 0, len(c.counts))
+	for _, count := range c.counts {
+		entry := *count
+		entry.Requests = make(map[string]int64, len(count.Requests))
+		for name, n := range count.Requests {
+			entry.Requests[name] = n
+		}
+		snapshot = append(snapshot, entry)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Count != snapshot[j].Count {
+			return snapshot[i].Count > snapshot[j].Count
+		}
+
+		return snapshot[i].Code < snapshot[j].Code
+	})
+
+	return snapshot
+}
+
+// Reset clears the counts.
+func (c *ErrorCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts = make(map[int]*ErrorCount)
+}
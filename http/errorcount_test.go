@@ -0,0 +1,66 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+)
+
+func TestErrorCounter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	counter := NewErrorCounter(clock.NewFake(now))
+
+	apiError := func(code int, message string) error {
+		return &ResponseError{Code: 400, Err: &werrors.Error{Code: code, Message: message}}
+	}
+	counter.Observe("send text", apiError(werrors.CodeReEngagement, "re-engagement"))
+	counter.Observe("send text", apiError(werrors.CodePairRateLimit, "pair rate limit"))
+	counter.Observe("send template", apiError(werrors.CodePairRateLimit, "pair rate limit hit"))
+	counter.Observe("send text", errors.New("connection reset"))
+	counter.Observe("send text", nil)
+
+	if got := counter.Count(werrors.CodePairRateLimit); got != 2 {
+		t.Errorf("Count(%d) = %d, want 2", werrors.CodePairRateLimit, got)
+	}
+
+	snapshot := counter.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("Snapshot() = %+v, want 2 codes", snapshot)
+	}
+	first := snapshot[0]
+	if first.Code != werrors.CodePairRateLimit || first.Count != 2 || first.LastMessage != "pair rate limit hit" ||
+		!first.LastSeen.Equal(now) || first.Requests["send text"] != 1 || first.Requests["send template"] != 1 {
+		t.Errorf("Snapshot()[0] = %+v", first)
+	}
+	if snapshot[1].Code != werrors.CodeReEngagement || snapshot[1].Count != 1 {
+		t.Errorf("Snapshot()[1] = %+v", snapshot[1])
+	}
+
+	counter.Reset()
+	if got := counter.Snapshot(); len(got) != 0 {
+		t.Errorf("Snapshot() after Reset() = %+v, want empty", got)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultETagCacheSize is the default number of responses kept by MemoryETagStore.
+const DefaultETagCacheSize = 256
+
+type (
+	// ETagEntry is a cached response. ETag is the value of the ETag header returned with the
+	// response, Header and Body are the headers and body of the response.
+	ETagEntry struct {
+		ETag   string
+		Header http.Header
+		Body   []byte
+	}
+
+	// ETagStore stores ETagEntry values by key. Implementations must be safe for concurrent use.
+	ETagStore interface {
+		Get(key string) (*ETagEntry, bool)
+		Set(key string, entry *ETagEntry)
+	}
+
+	// MemoryETagStore is an in memory ETagStore that keeps up to a fixed number of entries,
+	// evicting the least recently used ones.
+	MemoryETagStore struct {
+		mu      sync.Mutex
+		size    int
+		entries map[string]*list.Element
+		order   *list.List
+	}
+
+	memoryETagItem struct {
+		key   string
+		entry *ETagEntry
+	}
+
+	etagTransport struct {
+		store ETagStore
+		next  http.RoundTripper
+	}
+)
+
+// NewMemoryETagStore creates a MemoryETagStore that keeps up to size entries. If size is
+// not positive, DefaultETagCacheSize is used.
+func NewMemoryETagStore(size int) *MemoryETagStore {
+	if size <= 0 {
+		size = DefaultETagCacheSize
+	}
+
+	return &MemoryETagStore{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (s *MemoryETagStore) Get(key string) (*ETagEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(elem)
+
+	return elem.Value.(*memoryETagItem).entry, true //nolint:forcetypeassert
+}
+
+func (s *MemoryETagStore) Set(key string, entry *ETagEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		elem.Value.(*memoryETagItem).entry = entry //nolint:forcetypeassert
+		s.order.MoveToFront(elem)
+
+		return
+	}
+	s.entries[key] = s.order.PushFront(&memoryETagItem{key: key, entry: entry})
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryETagItem).key) //nolint:forcetypeassert
+	}
+}
+
+// ETagTransport returns a http.RoundTripper that makes GET requests conditional. Responses
+// that have an ETag header are stored in store, subsequent requests to the same URL are sent
+// with the If-None-Match header and when the API responds with 304 Not Modified, the stored
+// response is returned instead, as if it was a 200 OK response.
+//
+// Responses are cached per URL and access token, the access token is hashed and never stored.
+// If next is nil, http.DefaultTransport is used.
+func ETagTransport(next http.RoundTripper, store ETagStore) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if store == nil {
+		store = NewMemoryETagStore(DefaultETagCacheSize)
+	}
+
+	return &etagTransport{store: store, next: next}
+}
+
+func etagKey(request *http.Request) string {
+	sum := sha256.Sum256([]byte(request.Header.Get("Authorization")))
+
+	return request.URL.String() + "#" + hex.EncodeToString(sum[:8])
+}
+
+func (t *etagTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != http.MethodGet || request.Header.Get("If-None-Match") != "" {
+		return t.next.RoundTrip(request) //nolint:wrapcheck
+	}
+
+	key := etagKey(request)
+	cached, ok := t.store.Get(key)
+	if ok {
+		request = request.Clone(request.Context())
+		request.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	if ok && response.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+
+		return cachedResponse(request, cached), nil
+	}
+
+	etag := response.Header.Get("ETag")
+	if response.StatusCode != http.StatusOK || etag == "" {
+		return response, nil
+	}
+
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("etag transport: read response body: %w", err)
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	t.store.Set(key, &ETagEntry{ETag: etag, Header: response.Header.Clone(), Body: body})
+
+	return response, nil
+}
+
+func cachedResponse(request *http.Request, entry *ETagEntry) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       request,
+	}
+}
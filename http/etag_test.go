@@ -0,0 +1,85 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestETagTransport(t *testing.T) {
+	t.Parallel()
+	var notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"name":"Pius Alfred","age":77,"male":true}`))
+	}))
+	defer server.Close()
+
+	store := NewMemoryETagStore(1)
+	client := &http.Client{Transport: ETagTransport(nil, store)}
+	want := User{Name: "Pius Alfred", Age: 77, Male: true}
+
+	for i := 0; i < 3; i++ {
+		request := &Request{
+			Context: &RequestContext{Name: "etag", BaseURL: server.URL},
+			Method:  http.MethodGet,
+			Bearer:  "token",
+		}
+		var user User
+		if err := Do(context.TODO(), client, request, &user); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if user != want {
+			t.Errorf("Do() user = %+v, want %+v", user, want)
+		}
+	}
+
+	if got := atomic.LoadInt32(&notModified); got != 2 {
+		t.Errorf("not modified responses = %d, want 2", got)
+	}
+}
+
+func TestMemoryETagStore(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryETagStore(2)
+	store.Set("a", &ETagEntry{ETag: "a"})
+	store.Set("b", &ETagEntry{ETag: "b"})
+	store.Get("a")
+	store.Set("c", &ETagEntry{ETag: "c"})
+
+	if _, ok := store.Get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := store.Get(key); !ok {
+			t.Errorf("entry %q was evicted", key)
+		}
+	}
+}
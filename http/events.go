@@ -0,0 +1,190 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// OutcomeSuccess means the request was sent and a successful response received.
+	OutcomeSuccess Outcome = "success"
+
+	// OutcomeAPIError means the request was sent and the API responded with an error.
+	OutcomeAPIError Outcome = "api_error"
+
+	// OutcomeTransportError means the request was not sent or no response was received. For
+	// example on connection errors, timeouts or when the request was rejected by a middleware
+	// or a circuit breaker.
+	OutcomeTransportError Outcome = "transport_error"
+
+	// OutcomeFailure means a response was received, but it could not be processed. For example
+	// when the response body could not be decoded.
+	OutcomeFailure Outcome = "failure"
+)
+
+type (
+	// Outcome is the final result of a request.
+	Outcome string
+
+	// Event contains the details of a single request attempt. It is passed to the EventHook
+	// after the request is completed.
+	//
+	// Name is the request name, Attempt the attempt number starting from 1 (see WithAttempt),
+	// StartedAt and Duration tell when the request started and how long it took, Err is the
+	// error returned by the request, if the API responded with an error it is a *ResponseError.
+	//
+	// Request and Response are the underlying http request and response, any of them can be nil
+	// if the request failed before they were available. The response body has already been
+	// consumed and closed. Trace holds the identifiers to quote when escalating to Meta support
+	// and Usage the rate limit usage reported by the response, if any.
+	Event struct {
+		Name      string
+		Attempt   int
+		Request   *http.Request
+		Response  *http.Response
+		StartedAt time.Time
+		Duration  time.Duration
+		Err       error
+		Outcome   Outcome
+		Trace     Trace
+		Usage     *Usage
+	}
+
+	// EventHook is called with the Event of every request attempt. Use EventMiddleware to
+	// attach event hooks to a Sender.
+	EventHook func(ctx context.Context, event *Event)
+
+	// exchange is used by Do to share the http request and response with EventMiddleware.
+	exchange struct {
+		request  *http.Request
+		response *http.Response
+	}
+
+	exchangeKey struct{}
+	attemptKey  struct{}
+)
+
+// WithAttempt returns a context that carries the attempt number of the request. Middlewares
+// that retry requests should set it before every attempt, so that it is reported in the Event.
+func WithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// AttemptFromContext returns the attempt number set by WithAttempt, it defaults to 1.
+func AttemptFromContext(ctx context.Context) int {
+	attempt, ok := ctx.Value(attemptKey{}).(int)
+	if !ok || attempt < 1 {
+		return 1
+	}
+
+	return attempt
+}
+
+func withExchange(ctx context.Context, ex *exchange) context.Context {
+	return context.WithValue(ctx, exchangeKey{}, ex)
+}
+
+// exchangeFromContext returns the exchange set by EventMiddleware or nil.
+func exchangeFromContext(ctx context.Context) *exchange {
+	ex, _ := ctx.Value(exchangeKey{}).(*exchange)
+
+	return ex
+}
+
+// EventMiddleware returns a Middleware that calls the hooks with the Event of each request
+// that goes through it. Put it after the middlewares that retry requests to get an Event per
+// attempt.
+func EventMiddleware(hooks ...EventHook) Middleware {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+			ex := &exchange{}
+			start := time.Now()
+			err := next.Send(withExchange(ctx, ex), request, v)
+			event := &Event{
+				Name:      requestName(request),
+				Attempt:   AttemptFromContext(ctx),
+				Request:   ex.request,
+				Response:  ex.response,
+				StartedAt: start,
+				Duration:  time.Since(start),
+				Err:       err,
+				Outcome:   outcomeOf(ex.response, err),
+				Trace:     traceOf(ex.response, err),
+				Usage:     usageOf(ex.response),
+			}
+			for i, hook := range hooks {
+				if hook != nil {
+					runHook(ctx, HookKindEvent, i, event.Name, func() {
+						hook(ctx, event)
+					})
+				}
+			}
+
+			return err
+		})
+	}
+}
+
+func requestName(request *Request) string {
+	if request == nil || request.Context == nil {
+		return ""
+	}
+
+	return request.Context.Name
+}
+
+func traceOf(response *http.Response, err error) Trace {
+	if trace, ok := TraceFromError(err); ok {
+		return trace
+	}
+
+	return TraceFromResponse(response)
+}
+
+func usageOf(response *http.Response) *Usage {
+	if response == nil {
+		return nil
+	}
+	usage, err := ParseUsage(response.Header)
+	if err != nil {
+		return nil
+	}
+
+	return usage
+}
+
+func outcomeOf(response *http.Response, err error) Outcome {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	var re *ResponseError
+	if errors.As(err, &re) {
+		return OutcomeAPIError
+	}
+	if response == nil {
+		return OutcomeTransportError
+	}
+
+	return OutcomeFailure
+}
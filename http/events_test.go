@@ -0,0 +1,95 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestEventMiddleware(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		ctx         *Context
+		attempt     int
+		wantOutcome Outcome
+		wantStatus  int
+	}{
+		{
+			name:        "success",
+			ctx:         &Context{Method: http.MethodGet, StatusCode: http.StatusOK, Body: &User{Name: "Pius"}},
+			attempt:     1,
+			wantOutcome: OutcomeSuccess,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name: "api error on second attempt",
+			ctx: &Context{
+				Method:     http.MethodGet,
+				StatusCode: http.StatusBadRequest,
+				Body:       map[string]any{"error": map[string]any{"code": 131030, "message": "not allowed"}},
+			},
+			attempt:     2,
+			wantOutcome: OutcomeAPIError,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := testServer(t, tt.ctx)
+			defer server.Close()
+
+			var event *Event
+			sender := Chain(NewSender(http.DefaultClient), EventMiddleware(func(ctx context.Context, e *Event) {
+				event = e
+			}))
+			request := &Request{
+				Context: &RequestContext{Name: "event test", BaseURL: server.URL},
+				Method:  http.MethodGet,
+			}
+
+			var user User
+			err := sender.Send(WithAttempt(context.TODO(), tt.attempt), request, &user)
+			if event == nil {
+				t.Fatal("event hook was not called")
+			}
+
+			if !errors.Is(event.Err, err) {
+				t.Errorf("event.Err = %v, want %v", event.Err, err)
+			}
+			if event.Name != "event test" || event.Attempt != tt.attempt || event.Outcome != tt.wantOutcome {
+				t.Errorf("event = %+v, want name %q, attempt %d, outcome %q", event, "event test",
+					tt.attempt, tt.wantOutcome)
+			}
+			if event.Request == nil || event.Response == nil || event.Response.StatusCode != tt.wantStatus {
+				t.Errorf("event request/response not captured: %+v", event)
+			}
+			if event.Duration <= 0 {
+				t.Errorf("event.Duration = %v, want > 0", event.Duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultGzipMinSize is the smallest request body compressed when GzipConfig.MinSize is not set.
+const DefaultGzipMinSize = 16 * 1024
+
+// GzipConfig configures GzipTransport. Responses are always accepted gzip encoded and
+// transparently decompressed. Request bodies of at least MinSize bytes are gzip compressed
+// when CompressRequests is true, this helps with large payloads like Flow JSON uploads.
+// Level is a compress/gzip level, zero means gzip.DefaultCompression. Streamed bodies, that
+// can not be replayed, are sent as is.
+type GzipConfig struct {
+	CompressRequests bool
+	MinSize          int
+	Level            int
+}
+
+type gzipTransport struct {
+	next   http.RoundTripper
+	config GzipConfig
+}
+
+// GzipTransport returns a http.RoundTripper that negotiates gzip encoded responses and
+// optionally compresses requests, see GzipConfig. Unlike the gzip support built into
+// http.Transport it works with any next transport. A nil config only enables gzip responses.
+func GzipTransport(next http.RoundTripper, config *GzipConfig) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &gzipTransport{next: next}
+	if config != nil {
+		t.config = *config
+	}
+	if t.config.MinSize <= 0 {
+		t.config.MinSize = DefaultGzipMinSize
+	}
+	if t.config.Level == 0 {
+		t.config.Level = gzip.DefaultCompression
+	}
+
+	return t
+}
+
+func (t *gzipTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+
+	if t.config.CompressRequests {
+		if err := t.compress(request); err != nil {
+			return nil, err
+		}
+	}
+
+	decompress := false
+	if request.Header.Get("Accept-Encoding") == "" && request.Header.Get("Range") == "" {
+		request.Header.Set("Accept-Encoding", "gzip")
+		decompress = true
+	}
+
+	response, err := t.next.RoundTrip(request)
+	if err != nil || !decompress {
+		return response, err //nolint:wrapcheck
+	}
+
+	if !strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") || response.Uncompressed {
+		return response, nil
+	}
+
+	reader, err := gzip.NewReader(response.Body)
+	if err != nil {
+		_ = response.Body.Close()
+
+		return nil, fmt.Errorf("gzip response: %w", err)
+	}
+	response.Body = &gzipBody{reader: reader, body: response.Body}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+
+	return response, nil
+}
+
+// compress gzips the request body if it is large enough and not already encoded.
+func (t *gzipTransport) compress(request *http.Request) error {
+	if request.Body == nil || request.Body == http.NoBody || request.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+	// bodies that can not be replayed are streams, reading them in memory defeats the purpose
+	if request.GetBody == nil {
+		return nil
+	}
+	if request.ContentLength > 0 && request.ContentLength < int64(t.config.MinSize) {
+		return nil
+	}
+
+	body, err := io.ReadAll(request.Body)
+	_ = request.Body.Close()
+	if err != nil {
+		return fmt.Errorf("gzip request: %w", err)
+	}
+	if len(body) < t.config.MinSize {
+		request.Body = io.NopCloser(bytes.NewReader(body))
+
+		return nil
+	}
+
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, t.config.Level)
+	if err != nil {
+		return fmt.Errorf("gzip request: %w", err)
+	}
+	if _, err = writer.Write(body); err != nil {
+		return fmt.Errorf("gzip request: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return fmt.Errorf("gzip request: %w", err)
+	}
+
+	compressed := buf.Bytes()
+	request.Body = io.NopCloser(bytes.NewReader(compressed))
+	request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	request.ContentLength = int64(len(compressed))
+	request.Header.Set("Content-Encoding", "gzip")
+
+	return nil
+}
+
+// gzipBody closes both the gzip reader and the underlying response body.
+type gzipBody struct {
+	reader *gzip.Reader
+	body   io.ReadCloser
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	return b.reader.Read(p) //nolint:wrapcheck
+}
+
+func (b *gzipBody) Close() error {
+	_ = b.reader.Close()
+
+	return b.body.Close() //nolint:wrapcheck
+}
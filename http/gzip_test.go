@@ -0,0 +1,109 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, _ = writer.Write([]byte(s))
+	_ = writer.Close()
+
+	return buf.Bytes()
+}
+
+func TestGzipTransport(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		config         *GzipConfig
+		body           string
+		wantCompressed bool
+	}{
+		{
+			name: "responses only",
+			body: strings.Repeat("a", 100),
+		},
+		{
+			name:   "small request not compressed",
+			config: &GzipConfig{CompressRequests: true, MinSize: 1000},
+			body:   strings.Repeat("a", 100),
+		},
+		{
+			name:           "large request compressed",
+			config:         &GzipConfig{CompressRequests: true, MinSize: 10},
+			body:           strings.Repeat("a", 100),
+			wantCompressed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body io.Reader = r.Body
+				compressed := r.Header.Get("Content-Encoding") == "gzip"
+				if compressed != tt.wantCompressed {
+					t.Errorf("request compressed = %v, want %v", compressed, tt.wantCompressed)
+				}
+				if compressed {
+					gr, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("gzip.NewReader() error = %v", err)
+
+						return
+					}
+					body = gr
+				}
+				if b, _ := io.ReadAll(body); string(b) != tt.body {
+					t.Errorf("server received %q, want %q", b, tt.body)
+				}
+				if r.Header.Get("Accept-Encoding") != "gzip" {
+					t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(gzipped(t, `{"id":"1"}`))
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: GzipTransport(&http.Transport{DisableCompression: true}, tt.config)}
+			resp, err := client.Post(server.URL, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			if b, _ := io.ReadAll(resp.Body); string(b) != `{"id":"1"}` {
+				t.Errorf("response body = %q, want it decompressed", b)
+			}
+		})
+	}
+}
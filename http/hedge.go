@@ -0,0 +1,184 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+// DefaultHedgeDelay is the delay after which a request is hedged when HedgeConfig.Delay
+// is not set.
+const DefaultHedgeDelay = 500 * time.Millisecond
+
+type (
+	// HedgeConfig configures HedgeTransport.
+	//
+	// Delay is how long a request is waited for before another one is sent, it should be
+	// around the P95 latency of the hedged requests. MaxHedges is the number of extra requests
+	// that can be sent for a single request, 1 by default. Filter, when set, selects the
+	// requests that are hedged among the idempotent ones, e.g. by request name with
+	// RequestNameFromContext. OnHedge is called each time an extra request is sent.
+	HedgeConfig struct {
+		Delay     time.Duration
+		MaxHedges int
+		Filter    func(request *http.Request) bool
+		OnHedge   func(request *http.Request, hedge int)
+		Clock     clock.Clock
+	}
+
+	hedgeTransport struct {
+		next   http.RoundTripper
+		config HedgeConfig
+		clock  clock.Clock
+	}
+
+	hedgeResult struct {
+		response *http.Response
+		err      error
+		attempt  int
+	}
+
+	// hedgeBody cancels the context of the winning request once its body is closed.
+	hedgeBody struct {
+		io.ReadCloser
+		cancel context.CancelFunc
+	}
+)
+
+// HedgeTransport returns a http.RoundTripper that hedges the idempotent requests: the GET
+// and HEAD requests without a body, like fetching a media URL or a template. When no
+// response arrived after the configured delay, the request is sent again and the first
+// response is used, the other requests are canceled. This trades a few extra requests for
+// a lower tail latency when the API is occasionally slow.
+//
+// Errors do not trigger a hedge, a request that fails while the others are still pending
+// is ignored, the error of the last one is returned when they all fail.
+// If next is nil, http.DefaultTransport is used.
+func HedgeTransport(next http.RoundTripper, config *HedgeConfig) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &hedgeTransport{next: next}
+	if config != nil {
+		t.config = *config
+	}
+	if t.config.Delay <= 0 {
+		t.config.Delay = DefaultHedgeDelay
+	}
+	if t.config.MaxHedges <= 0 {
+		t.config.MaxHedges = 1
+	}
+	t.clock = clock.OrSystem(t.config.Clock)
+
+	return t
+}
+
+func (t *hedgeTransport) hedged(request *http.Request) bool {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return false
+	}
+	if request.Body != nil && request.Body != http.NoBody {
+		return false
+	}
+
+	return t.config.Filter == nil || t.config.Filter(request)
+}
+
+func (t *hedgeTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !t.hedged(request) {
+		return t.next.RoundTrip(request) //nolint:wrapcheck
+	}
+
+	attempts := t.config.MaxHedges + 1
+	results := make(chan hedgeResult, attempts)
+	cancels := make([]context.CancelFunc, 0, attempts)
+	send := func() {
+		ctx, cancel := context.WithCancel(request.Context())
+		cancels = append(cancels, cancel)
+		attempt := len(cancels) - 1
+		clone := request.Clone(ctx)
+		go func() {
+			response, err := t.next.RoundTrip(clone)
+			results <- hedgeResult{response: response, err: err, attempt: attempt}
+		}()
+	}
+
+	send()
+	pending := 1
+	timer := t.clock.NewTimer(t.config.Delay)
+	defer func() { timer.Stop() }()
+
+	var err error
+	for pending > 0 {
+		var hedge <-chan time.Time
+		if len(cancels) < attempts {
+			hedge = timer.C()
+		}
+		select {
+		case <-hedge:
+			if t.config.OnHedge != nil {
+				t.config.OnHedge(request, len(cancels))
+			}
+			send()
+			pending++
+			timer = t.clock.NewTimer(t.config.Delay)
+		case result := <-results:
+			pending--
+			if result.err != nil {
+				err = result.err
+				cancels[result.attempt]()
+
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != result.attempt {
+					cancel()
+				}
+			}
+			go drainHedges(results, pending)
+			result.response.Body = &hedgeBody{ReadCloser: result.response.Body, cancel: cancels[result.attempt]}
+
+			return result.response, nil
+		}
+	}
+
+	return nil, err
+}
+
+// drainHedges closes the responses of the requests that lost the race.
+func drainHedges(results <-chan hedgeResult, pending int) {
+	for i := 0; i < pending; i++ {
+		if result := <-results; result.response != nil {
+			_ = result.response.Body.Close()
+		}
+	}
+}
+
+func (body *hedgeBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+
+	return err //nolint:wrapcheck
+}
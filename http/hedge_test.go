@@ -0,0 +1,106 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+func TestHedgeTransport(t *testing.T) {
+	t.Parallel()
+	var (
+		hits     int32
+		received = make(chan struct{}, 1)
+		canceled = make(chan struct{})
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if r.Method == http.MethodGet && n == 1 {
+			// the first request is slow, it is only answered once canceled
+			received <- struct{}{}
+			<-r.Context().Done()
+			close(canceled)
+
+			return
+		}
+		_, _ = w.Write([]byte("response " + r.Method))
+	}))
+	t.Cleanup(server.Close)
+
+	clk := clock.NewFake(time.Now())
+	var hedges int32
+	client := &http.Client{Transport: HedgeTransport(nil, &HedgeConfig{
+		Delay: time.Second,
+		Clock: clk,
+		OnHedge: func(request *http.Request, hedge int) {
+			atomic.AddInt32(&hedges, 1)
+		},
+	})}
+
+	go func() {
+		<-received
+		for clk.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		clk.Advance(time.Second)
+	}()
+
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if string(body) != "response GET" {
+		t.Errorf("body = %q, want the response of the hedged request", body)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the slow request was not canceled")
+	}
+	if got := atomic.LoadInt32(&hedges); got != 1 {
+		t.Errorf("hedges = %d, want 1", got)
+	}
+
+	// requests with a body are never hedged
+	request, _ = http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, strings.NewReader("{}"))
+	response, err = client.Do(request)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	_ = response.Body.Close()
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Errorf("hits = %d, want 3", got)
+	}
+	if got := atomic.LoadInt32(&hedges); got != 1 {
+		t.Errorf("hedges = %d, want 1", got)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrRequestAborted is returned when a BeforeHook aborts a request.
+var ErrRequestAborted = errors.New("request aborted")
+
+const (
+	// HookKindResponse is the kind of the Hook functions called by Do.
+	HookKindResponse HookKind = "response"
+
+	// HookKindEvent is the kind of the EventHook functions called by EventMiddleware.
+	HookKindEvent HookKind = "event"
+
+	// HookKindUsage is the kind of the UsageHook functions called by UsageTracker.
+	HookKindUsage HookKind = "usage"
+
+	// HookKindBefore is the kind of the BeforeHook functions called by BeforeHooksMiddleware.
+	HookKindBefore HookKind = "before"
+)
+
+type (
+	// HookKind tells which type of hook failed.
+	HookKind string
+
+	// HookError is reported when a hook panics. Kind and Index identify the hook, Request is the
+	// name of the request being processed, Value is the value passed to panic and Stack the stack
+	// trace of the goroutine at the time of the panic.
+	HookError struct {
+		Kind    HookKind
+		Index   int
+		Request string
+		Value   any
+		Stack   []byte
+	}
+
+	// HookErrorHandler receives the errors of the hooks run with a context returned by
+	// WithHookErrorHandler.
+	HookErrorHandler func(ctx context.Context, err *HookError)
+
+	// BeforeHook is called before the http request is built. It can modify the request, for
+	// example its headers or payload, or return an error to abort it, in which case the request
+	// is not sent and the error is returned wrapped with ErrRequestAborted.
+	//
+	// Example of a compliance filter:
+	//
+	//	func BlockRegions(prefixes ...string) BeforeHook {
+	//		return func(ctx context.Context, request *Request) error {
+	//			message, ok := request.Payload.(*models.Message)
+	//			if !ok {
+	//				return nil
+	//			}
+	//			for _, prefix := range prefixes {
+	//				if strings.HasPrefix(message.To, prefix) {
+	//					return fmt.Errorf("recipient %s is in a blocked region", message.To)
+	//				}
+	//			}
+	//			return nil
+	//		}
+	//	}
+	BeforeHook func(ctx context.Context, request *Request) error
+
+	hookErrorHandlerKey struct{}
+)
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook %d panicked while processing %q: %v", e.Kind, e.Index, e.Request, e.Value)
+}
+
+// WithHookErrorHandler returns a context that delivers the panics of the hooks run with it
+// to handler. Hooks never take down the request, except for a BeforeHook which aborts it.
+// When no handler is set the panics are recovered and dropped.
+func WithHookErrorHandler(ctx context.Context, handler HookErrorHandler) context.Context {
+	return context.WithValue(ctx, hookErrorHandlerKey{}, handler)
+}
+
+// HookErrorHandlerMiddleware returns a Middleware that delivers the panics of the hooks run
+// for the requests that go through it to handler. See WithHookErrorHandler.
+func HookErrorHandlerMiddleware(handler HookErrorHandler) Middleware {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+			return next.Send(WithHookErrorHandler(ctx, handler), request, v)
+		})
+	}
+}
+
+// BeforeHooksMiddleware returns a Middleware that calls the hooks in order before passing
+// the request on. The first hook that returns an error aborts the request, a panicking hook
+// aborts it too and is reported as a *HookError.
+func BeforeHooksMiddleware(hooks ...BeforeHook) Middleware {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+			for i, hook := range hooks {
+				if hook == nil {
+					continue
+				}
+				if err := runBeforeHook(ctx, i, hook, request); err != nil {
+					return fmt.Errorf("%s: %w: %w", requestName(request), ErrRequestAborted, err)
+				}
+			}
+
+			return next.Send(ctx, request, v)
+		})
+	}
+}
+
+// runBeforeHook calls hook, a panic aborts the request with the *HookError it caused.
+func runBeforeHook(ctx context.Context, index int, hook BeforeHook, request *Request) error {
+	var err error
+	if hookErr := runHook(ctx, HookKindBefore, index, requestName(request), func() {
+		err = hook(ctx, request)
+	}); hookErr != nil {
+		return hookErr
+	}
+
+	return err
+}
+
+// runHook calls fn, recovering from a panic and reporting it as a *HookError to the handler
+// set in ctx. name is the name of the request the hook is called for. The *HookError is also
+// returned, it is nil when fn did not panic.
+func runHook(ctx context.Context, kind HookKind, index int, name string, fn func()) (hookErr *HookError) {
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+		hookErr = &HookError{
+			Kind:    kind,
+			Index:   index,
+			Request: name,
+			Value:   value,
+			Stack:   debug.Stack(),
+		}
+		if handler, _ := ctx.Value(hookErrorHandlerKey{}).(HookErrorHandler); handler != nil {
+			handler(ctx, hookErr)
+		}
+	}()
+	fn()
+
+	return nil
+}
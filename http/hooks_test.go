@@ -0,0 +1,153 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHookPanicRecovery(t *testing.T) {
+	t.Parallel()
+	server := testServer(t, &Context{
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{HeaderAppUsage: `{"call_count":10}`},
+		Body:       &User{Name: "Pius"},
+	})
+	defer server.Close()
+
+	var (
+		hookErrors []*HookError
+		called     bool
+	)
+	tracker := NewUsageTracker(nil, func(ctx context.Context, usage *Usage) {
+		panic("usage hook")
+	})
+	client := &http.Client{Transport: tracker.Transport(nil)}
+	panicking := func(ctx context.Context, request *http.Request, response *http.Response) {
+		panic("response hook")
+	}
+	after := func(ctx context.Context, request *http.Request, response *http.Response) {
+		called = true
+	}
+	sender := Chain(NewSender(client, panicking, after),
+		HookErrorHandlerMiddleware(func(ctx context.Context, err *HookError) {
+			hookErrors = append(hookErrors, err)
+		}),
+		EventMiddleware(func(ctx context.Context, event *Event) {
+			panic("event hook")
+		}),
+	)
+	request := &Request{
+		Context: &RequestContext{Name: "hook test", BaseURL: server.URL},
+		Method:  http.MethodGet,
+	}
+
+	var user User
+	if err := sender.Send(context.TODO(), request, &user); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if user.Name != "Pius" {
+		t.Errorf("user.Name = %q, want %q", user.Name, "Pius")
+	}
+	if !called {
+		t.Error("the hook after the panicking hook was not called")
+	}
+
+	want := []HookKind{HookKindUsage, HookKindResponse, HookKindEvent}
+	if len(hookErrors) != len(want) {
+		t.Fatalf("hook errors = %v, want kinds %v", hookErrors, want)
+	}
+	for i, kind := range want {
+		if hookErrors[i].Kind != kind || hookErrors[i].Request != "hook test" || len(hookErrors[i].Stack) == 0 {
+			t.Errorf("hook error %d = %+v, want kind %q for request %q", i, hookErrors[i], kind, "hook test")
+		}
+	}
+}
+
+func TestBeforeHooksMiddleware(t *testing.T) {
+	t.Parallel()
+	blocked := errors.New("blocked region")
+	tests := []struct {
+		name     string
+		hook     BeforeHook
+		wantErr  error
+		wantSent bool
+	}{
+		{
+			name: "mutate",
+			hook: func(ctx context.Context, request *Request) error {
+				request.Headers = map[string]string{"X-Tenant": "acme"}
+
+				return nil
+			},
+			wantSent: true,
+		},
+		{
+			name: "abort",
+			hook: func(ctx context.Context, request *Request) error {
+				return blocked
+			},
+			wantErr: blocked,
+		},
+		{
+			name: "panic",
+			hook: func(ctx context.Context, request *Request) error {
+				panic("before hook")
+			},
+			wantErr: ErrRequestAborted,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				sent   bool
+				tenant string
+			)
+			sender := Chain(SenderFunc(func(ctx context.Context, request *Request, v any) error {
+				sent = true
+				tenant = request.Headers["X-Tenant"]
+
+				return nil
+			}), BeforeHooksMiddleware(tt.hook))
+
+			err := sender.Send(context.TODO(), &Request{Context: &RequestContext{Name: "before test"}}, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrRequestAborted) {
+					t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if sent != tt.wantSent {
+				t.Errorf("sent = %v, want %v", sent, tt.wantSent)
+			}
+			if tt.wantSent && tenant != "acme" {
+				t.Errorf("X-Tenant = %q, want %q", tenant, "acme")
+			}
+		})
+	}
+}
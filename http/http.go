@@ -29,12 +29,22 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/models"
 )
 
 const BaseURL = "https://graph.facebook.com"
 
+// DefaultAPIVersion is the Graph API version of the requests that do not set one, it is
+// also the default version of the whatsapp clients, see whatsapp.DefaultAPIVersion.
+const DefaultAPIVersion = "v23.0"
+
+// accessTokenQueryKey is the query parameter the Graph API accepts the access token in.
+// Tokens passed in Request.Query under this key are sent in the Authorization header instead.
+const accessTokenQueryKey = "access_token"
+
 type (
 	// requestNameKey is a type that holds the name of a request. This is usually passed
 	// extracted from Request.Context.Name and passed down to the Do function.
@@ -92,11 +102,17 @@ func RequestNameFromContext(ctx context.Context) string {
 }
 
 // executeHooks take a Context,*http.Request, *http.Response and a slice of Hook and executes
-// each hook in the slice.
+// each hook in the slice. A panicking hook does not stop the other hooks, see WithHookErrorHandler.
 func executeHooks(ctx context.Context, request *http.Request, response *http.Response, hooks []Hook) {
 	// range over the hooks and execute each one
 	for i := 0; i < len(hooks); i++ {
-		hooks[i](ctx, request, response)
+		hook := hooks[i]
+		if hook == nil {
+			continue
+		}
+		runHook(ctx, HookKindResponse, i, RequestNameFromContext(ctx), func() {
+			hook(ctx, request, response)
+		})
 	}
 }
 
@@ -126,7 +142,7 @@ func NewRequest(ctx context.Context, options ...RequestOption) (*http.Request, e
 	request := &Request{
 		Context: &RequestContext{
 			BaseURL:    BaseURL,
-			ApiVersion: "v16.0",
+			ApiVersion: DefaultAPIVersion,
 		},
 		Method:  http.MethodPost,
 		Headers: map[string]string{"Content-Type": "application/json"},
@@ -176,22 +192,74 @@ func (request *Request) ReaderFunc() func() (io.Reader, error) {
 	}
 }
 
-// BodyBytes takes a *Request and returns a slice of bytes or an error.
+// BodyBytes takes a *Request and returns a slice of bytes or an error. A []byte payload is
+// returned as is, an io.Reader payload is read. A Stream is not read, ErrStreamedPayload is
+// returned instead.
 func (request *Request) BodyBytes() ([]byte, error) {
-	if request.Payload == nil {
+	switch payload := request.Payload.(type) {
+	case nil:
 		return nil, nil
+	case []byte:
+		return payload, nil
+	case *Stream:
+		return nil, ErrStreamedPayload
+	case io.Reader:
+		body, err := io.ReadAll(payload)
+		if err != nil {
+			return nil, fmt.Errorf("read from: %w", err)
+		}
+
+		return body, nil
+	default:
+		return encodePayload(payload)
 	}
-	body, err := request.ReaderFunc()()
-	if err != nil {
-		return nil, fmt.Errorf("reader func: %w", err)
+}
+
+// encodePayload returns the payload encoded as the body of a request.
+func encodePayload(payload any) ([]byte, error) {
+	switch p := payload.(type) {
+	case []byte:
+		return p, nil
+	case string:
+		return []byte(p), nil
+	case *models.Message:
+		// messages are the bulk of the payloads, they are encoded without reflection. Only the
+		// exact type is matched, types embedding a message are encoded with encoding/json.
+		buf := getBuffer()
+		body, err := p.AppendJSON(buf.Bytes())
+		if err != nil {
+			putBuffer(buf)
+
+			return nil, fmt.Errorf("failed to encode payload: %w", err)
+		}
+		if cap(body) > buf.Cap() {
+			buf = bytes.NewBuffer(body[:0]) // keep the grown buffer
+		}
+		body = copyBytes(body)
+		putBuffer(buf)
+
+		return body, nil
+	default:
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := json.NewEncoder(buf).Encode(p); err != nil {
+			return nil, fmt.Errorf("failed to encode payload: %w", err)
+		}
+
+		return copyBytes(buf.Bytes()), nil
 	}
-	buf := new(bytes.Buffer)
-	_, err = buf.ReadFrom(body)
-	if err != nil {
-		return nil, fmt.Errorf("read from: %w", err)
+}
+
+// withBody returns a copy of request whose payload is body, the request is sent with it
+// so that the payload is encoded only once.
+func (request *Request) withBody(body []byte) *Request {
+	if request.Payload == nil || request.Form != nil {
+		return request
 	}
+	r := *request
+	r.Payload = body
 
-	return buf.Bytes(), nil
+	return &r
 }
 
 var ErrInvalidRequestValue = errors.New("invalid request value")
@@ -204,9 +272,8 @@ func NewRequestWithContext(ctx context.Context, request *Request) (*http.Request
 		return nil, fmt.Errorf("%w: request or request context should not be nil", ErrInvalidRequestValue)
 	}
 	var (
-		body    io.Reader
-		req     *http.Request
-		headers = map[string]string{}
+		body io.Reader
+		req  *http.Request
 	)
 	if request.Form != nil {
 		form := url.Values{}
@@ -214,7 +281,6 @@ func NewRequestWithContext(ctx context.Context, request *Request) (*http.Request
 			form.Add(key, value)
 		}
 		body = strings.NewReader(form.Encode())
-		headers["Content-Type"] = "application/x-www-form-urlencoded"
 	} else if request.Payload != nil {
 		rdr, err := extractRequestBody(request.Payload)
 		if err != nil {
@@ -235,6 +301,10 @@ func NewRequestWithContext(ctx context.Context, request *Request) (*http.Request
 		return nil, fmt.Errorf("failed to create new request: %w", err)
 	}
 
+	if stream, ok := request.Payload.(*Stream); ok && stream.ContentLength > 0 {
+		req.ContentLength = stream.ContentLength
+	}
+
 	// Set the request headers
 	if request.Headers != nil {
 		for key, value := range request.Headers {
@@ -242,20 +312,23 @@ func NewRequestWithContext(ctx context.Context, request *Request) (*http.Request
 		}
 	}
 
-	// extra headers
-	for key, value := range headers {
-		req.Header.Add(key, value)
+	if request.Form != nil {
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	// Set the bearer token header
-	if request.Bearer != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", request.Bearer))
+	// Set the bearer token header. This is the only place the access token is added to
+	// a request, it is never sent in the URL where it would end up in proxy and server logs.
+	if token := request.AccessToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
 	// Add the query parameters to the request URL
 	if request.Query != nil {
 		query := req.URL.Query()
 		for key, value := range request.Query {
+			if key == accessTokenQueryKey {
+				continue
+			}
 			query.Add(key, value)
 		}
 		req.URL.RawQuery = query.Encode()
@@ -269,7 +342,7 @@ func NewRequestWithContext(ctx context.Context, request *Request) (*http.Request
 // Request to an io.Reader. The io.Reader is then used to set the body of the http.Request.
 // Only the following types are supported:
 // 1. []byte
-// 2. io.Reader
+// 2. io.Reader, including a Stream
 // 3. string
 // 4. any value that can be marshalled to json
 // 5. nil.
@@ -285,13 +358,12 @@ func extractRequestBody(payload interface{}) (io.Reader, error) {
 	case string:
 		return strings.NewReader(p), nil
 	default:
-		buf := &bytes.Buffer{}
-		err := json.NewEncoder(buf).Encode(p)
+		body, err := encodePayload(p)
 		if err != nil {
-			return nil, fmt.Errorf("failed to encode payload: %w", err)
+			return nil, err
 		}
 
-		return buf, nil
+		return bytes.NewReader(body), nil
 	}
 }
 
@@ -301,45 +373,87 @@ func extractRequestBody(payload interface{}) (io.Reader, error) {
 // the requests and responses before using them. As in some cases hooks may be called with nil values. Example when
 // http.Client.Do returns an error.
 //
-//nolint:cyclop
+// The payload is read in memory, so that the hooks can see the request body, unless it is
+// a Stream. The hooks see a streamed request without a body.
+//
+//nolint:cyclop,funlen
 func Do(ctx context.Context, client *http.Client, r *Request, v any, hooks ...Hook) error {
 	ctx = withRequestName(ctx, r.Context.Name)
+	var (
+		reqBodyBytes []byte
+		err          error
+	)
+	_, streamed := r.Payload.(*Stream)
+	if !streamed {
+		if reqBodyBytes, err = r.BodyBytes(); err != nil {
+			return fmt.Errorf("http send: %w", err)
+		}
+		r = r.withBody(reqBodyBytes)
+	}
 	request, err := NewRequestWithContext(ctx, r)
 	if err != nil {
 		return fmt.Errorf("http send: %w", err)
 	}
-	reqBodyBytes, err := r.BodyBytes()
-	if err != nil {
-		return fmt.Errorf("http send: %w", err)
+	restoreBody := func() {
+		if streamed {
+			request.Body = http.NoBody
+
+			return
+		}
+		request.Body = io.NopCloser(bytes.NewBuffer(reqBodyBytes))
+	}
+	ex := exchangeFromContext(ctx)
+	if ex != nil {
+		ex.request = request
 	}
 	response, err := client.Do(request)
+	if ex != nil {
+		ex.response = response
+	}
 	if err != nil {
 		defer executeHooks(ctx, request, response, hooks)
-		request.Body = io.NopCloser(bytes.NewBuffer(reqBodyBytes))
+		restoreBody()
 
 		return fmt.Errorf("http send: %w", err)
 	}
 	defer func() {
 		// restore the request body
-		request.Body = io.NopCloser(bytes.NewBuffer(reqBodyBytes))
+		restoreBody()
 		executeHooks(ctx, request, response, hooks)
 		_ = response.Body.Close()
 	}()
 
-	if v == nil {
+	raw := rawResponseFromContext(ctx)
+	if v == nil && raw == nil {
 		return nil
 	}
 
-	buff := new(bytes.Buffer)
+	buff := getBuffer()
+	if response.ContentLength > 0 && response.ContentLength <= maxPooledBufferSize {
+		buff.Grow(int(response.ContentLength))
+	}
 	_, err = io.Copy(buff, response.Body)
 	if err != nil && !errors.Is(err, io.EOF) {
+		putBuffer(buff)
+
 		return fmt.Errorf("http send: %w", err)
 	}
-	bodyBytes := buff.Bytes()
+	bodyBytes := copyBytes(buff.Bytes())
+	putBuffer(buff)
 
 	// restore the response body
 	response.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
+	if raw != nil {
+		raw.StatusCode = response.StatusCode
+		raw.Header = response.Header.Clone()
+		raw.Body = append([]byte(nil), bodyBytes...)
+	}
+
+	if v == nil {
+		return nil
+	}
+
 	// Sometimes when there is an error, the response body is not empty
 	// as the error description is returned in the body. So we need to
 	// check the status code and the body to determine if there is an error
@@ -347,10 +461,12 @@ func Do(ctx context.Context, client *http.Client, r *Request, v any, hooks ...Ho
 	bodyIsEmpty := len(bodyBytes) == 0
 	if !isResponseOk && !bodyIsEmpty {
 		var errResponse ResponseError
-		if err = json.NewDecoder(bytes.NewBuffer(bodyBytes)).Decode(&errResponse); err != nil {
+		if err = json.Unmarshal(bodyBytes, &errResponse); err != nil {
 			return fmt.Errorf("http send: status (%d): body (%s): %w", response.StatusCode, string(bodyBytes), err)
 		}
 		errResponse.Code = response.StatusCode
+		errResponse.setTrace(TraceFromResponse(response))
+		errResponse.RetryAfter = parseRetryAfter(response.Header.Get(HeaderRetryAfter), time.Now())
 
 		return &errResponse
 	}
@@ -358,7 +474,7 @@ func Do(ctx context.Context, client *http.Client, r *Request, v any, hooks ...Ho
 	// Response is OK and the body is available
 	if isResponseOk && !bodyIsEmpty {
 		if v != nil {
-			if err = json.NewDecoder(bytes.NewBuffer(bodyBytes)).Decode(v); err != nil {
+			if err = json.Unmarshal(bodyBytes, v); err != nil {
 				return fmt.Errorf("http send: status (%d): body (%s): %w", response.StatusCode, string(bodyBytes), err)
 			}
 
@@ -369,12 +485,37 @@ func Do(ctx context.Context, client *http.Client, r *Request, v any, hooks ...Ho
 	return nil
 }
 
+// ResponseError is returned when the API responds with an error. Besides the status code
+// and the error body, it carries the trace identifiers from the response headers, see Trace,
+// and the delay asked by the Retry-After header, if any.
 type ResponseError struct {
-	Code int            `json:"code,omitempty"`
-	Err  *werrors.Error `json:"error,omitempty"`
+	Code                 int            `json:"code,omitempty"`
+	Err                  *werrors.Error `json:"error,omitempty"`
+	FBTraceID            string         `json:"-"`
+	RequestID            string         `json:"-"`
+	BusinessUseCaseUsage string         `json:"-"`
+	RetryAfter           time.Duration  `json:"-"`
 }
 
 // Error returns the error message for ResponseError.
 func (e *ResponseError) Error() string {
-	return fmt.Sprintf("whatsapp error: http code: %d, %s", e.Code, strings.ToLower(e.Err.Error()))
+	msg := fmt.Sprintf("whatsapp error: http code: %d", e.Code)
+	if e.Err != nil {
+		msg += ", " + strings.ToLower(e.Err.Error())
+	}
+	if e.RequestID != "" {
+		msg += ", request id: " + e.RequestID
+	}
+
+	return msg
+}
+
+// Unwrap returns the WhatsApp error carried by the response, so that errors.As and
+// errors.Is can match it.
+func (e *ResponseError) Unwrap() error {
+	if e.Err == nil {
+		return nil
+	}
+
+	return e.Err
 }
@@ -24,9 +24,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
 )
 
 type Context struct {
@@ -426,3 +430,45 @@ func ExampleNewRequest() {
 
 	// Output: GET
 }
+
+// staticTransport answers every request with the same response, without a network round
+// trip, so that the benchmarks measure the client side only.
+type staticTransport string
+
+func (body staticTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		_, _ = io.Copy(io.Discard, request.Body)
+		_ = request.Body.Close()
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(string(body))),
+		Request:    request,
+	}, nil
+}
+
+func BenchmarkDo(b *testing.B) {
+	client := &http.Client{Transport: staticTransport(
+		`{"messaging_product":"whatsapp","contacts":[{"input":"255700000001","wa_id":"255700000001"}],` +
+			`"messages":[{"id":"wamid.HBgMMjU1NzAwMDAwMDAxFQIAERgSQzVBRkQ2QjE3MkU2RjA0QjM5AA=="}]}`)}
+	message := models.NewMessage("255700000001", models.WithText("Your verification code is 123456"))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		request := &Request{
+			Context: &RequestContext{
+				Name: "send message", BaseURL: BaseURL, ApiVersion: "v16.0", SenderID: "phone_1",
+				Endpoints: []string{"messages"},
+			},
+			Method:  http.MethodPost,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Bearer:  "token",
+			Payload: message,
+		}
+		var response map[string]any
+		if err := Do(context.Background(), client, request, &response); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+)
+
+type (
+	// Sender sends a Request and decodes the response body into v. It is the unit that
+	// Middleware wraps.
+	Sender interface {
+		Send(ctx context.Context, request *Request, v any) error
+	}
+
+	// SenderFunc is a function that implements Sender.
+	SenderFunc func(ctx context.Context, request *Request, v any) error
+
+	// Middleware takes a Sender and returns a new Sender that wraps it. A Middleware can
+	// inspect or modify the Request before calling next, inspect the returned error and the
+	// decoded value after, retry the call, or not call next at all.
+	//
+	// Example of a middleware that injects the bearer token:
+	//
+	//	func Auth(token string) Middleware {
+	//		return func(next Sender) Sender {
+	//			return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+	//				request.Bearer = token
+	//				return next.Send(ctx, request, v)
+	//			})
+	//		}
+	//	}
+	Middleware func(next Sender) Sender
+)
+
+// Send calls f(ctx, request, v).
+func (f SenderFunc) Send(ctx context.Context, request *Request, v any) error {
+	return f(ctx, request, v)
+}
+
+// NewSender returns a Sender that sends requests with Do using the given http client and hooks.
+func NewSender(client *http.Client, hooks ...Hook) Sender {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+		return Do(ctx, client, request, v, hooks...)
+	})
+}
+
+// Chain wraps sender with the middlewares. The middlewares are applied in the order
+// they are passed, the first one is the outermost and is the first to see the request
+// and the last to see the result.
+//
+//	Chain(sender, logging, retry, auth) // logging -> retry -> auth -> sender
+func Chain(sender Sender, middlewares ...Middleware) Sender {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			sender = middlewares[i](sender)
+		}
+	}
+
+	return sender
+}
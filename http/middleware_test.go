@@ -0,0 +1,68 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var errAborted = errors.New("aborted")
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Sender) Sender {
+			return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+				calls = append(calls, name+" before")
+				err := next.Send(ctx, request, v)
+				calls = append(calls, name+" after")
+
+				return err
+			})
+		}
+	}
+	auth := func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+			request.Bearer = "token"
+
+			return next.Send(ctx, request, v)
+		})
+	}
+	sender := SenderFunc(func(ctx context.Context, request *Request, v any) error {
+		calls = append(calls, "send "+request.Bearer)
+
+		return errAborted
+	})
+
+	chained := Chain(sender, record("first"), nil, auth, record("second"))
+	err := chained.Send(context.TODO(), &Request{Context: &RequestContext{}}, nil)
+	if !errors.Is(err, errAborted) {
+		t.Errorf("Send() error = %v, want %v", err, errAborted)
+	}
+
+	want := []string{"first before", "second before", "send token", "second after", "first after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
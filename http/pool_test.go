@@ -0,0 +1,95 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// echoTransport answers every request with its own body.
+type echoTransport struct{}
+
+func (echoTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+func TestDo_PooledBuffersAreNotShared(t *testing.T) {
+	t.Parallel()
+
+	const count = 64
+	var (
+		wg        sync.WaitGroup
+		responses = make([]*http.Response, count)
+	)
+	client := &http.Client{Transport: echoTransport{}}
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			request := &Request{
+				Context: &RequestContext{Name: "echo", BaseURL: BaseURL, Endpoints: []string{"echo"}},
+				Method:  http.MethodPost,
+				Payload: map[string]string{"text": fmt.Sprintf("message %d %s", i, bytes.Repeat([]byte("x"), i*64))},
+			}
+			hook := func(ctx context.Context, request *http.Request, response *http.Response) {
+				responses[i] = response
+			}
+			var decoded map[string]string
+			if err := Do(context.Background(), client, request, &decoded, hook); err != nil {
+				t.Errorf("Do() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// the bodies are read after all the requests completed, when their buffers have been
+	// reused by the other requests
+	for i, response := range responses {
+		if response == nil {
+			t.Fatalf("response %d not recorded", i)
+		}
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			t.Fatalf("read response %d: %v", i, err)
+		}
+		want := fmt.Sprintf(`{"text":"message %d %s"}`+"\n", i, bytes.Repeat([]byte("x"), i*64))
+		if string(body) != want {
+			t.Errorf("response %d body = %.40q..., want %.40q...", i, body, want)
+		}
+	}
+}
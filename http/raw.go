@@ -0,0 +1,53 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+)
+
+type (
+	// RawResponse is the undecoded response of a request, captured for debugging with
+	// WithRawResponse. When a request is retried, it holds the response of the last attempt.
+	RawResponse struct {
+		StatusCode int
+		Header     http.Header
+		Body       []byte
+	}
+
+	rawResponseKey struct{}
+)
+
+// WithRawResponse returns a context that makes Do copy the status code, headers and body of
+// the response to raw. The typed response is still decoded and returned as usual:
+//
+//	var raw whttp.RawResponse
+//	resp, err := client.SendTextMessage(whttp.WithRawResponse(ctx, &raw), recipient, message)
+//	log.Printf("status: %d, body: %s", raw.StatusCode, raw.Body)
+func WithRawResponse(ctx context.Context, raw *RawResponse) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, raw)
+}
+
+func rawResponseFromContext(ctx context.Context) *RawResponse {
+	raw, _ := ctx.Value(rawResponseKey{}).(*RawResponse)
+
+	return raw
+}
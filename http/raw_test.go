@@ -0,0 +1,57 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestWithRawResponse(t *testing.T) {
+	t.Parallel()
+	server := testServer(t, &Context{
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{HeaderFBRequestID: "request_1"},
+		Body:       &User{Name: "Pius", Age: 30},
+	})
+	defer server.Close()
+
+	request := &Request{
+		Context: &RequestContext{Name: "raw test", BaseURL: server.URL},
+		Method:  http.MethodGet,
+	}
+
+	var raw RawResponse
+	user, err := DoTyped[User](WithRawResponse(context.TODO(), &raw), http.DefaultClient, request)
+	if err != nil {
+		t.Fatalf("DoTyped() error = %v", err)
+	}
+	if user.Name != "Pius" || user.Age != 30 {
+		t.Errorf("DoTyped() = %+v, want the decoded user", user)
+	}
+	if raw.StatusCode != http.StatusOK || raw.Header.Get(HeaderFBRequestID) != "request_1" {
+		t.Errorf("raw = %+v, want status 200 with the response headers", raw)
+	}
+	if want := `{"name":"Pius","age":30,"male":false}`; string(raw.Body) != want {
+		t.Errorf("raw.Body = %s, want %s", raw.Body, want)
+	}
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+)
+
+// RedactedMask replaces redacted values.
+const RedactedMask = "REDACTED"
+
+// Redactor masks credentials in requests and responses before they are dumped or
+// passed to hooks.
+//
+// The values of Headers and QueryParams are replaced with Mask, and so is every occurrence
+// of those values and of Secrets anywhere else, including the body. Put the app secret
+// and any other credential the client is configured with in Secrets.
+type Redactor struct {
+	Headers     []string
+	QueryParams []string
+	Secrets     []string
+	Mask        string
+}
+
+// DefaultRedactor returns a Redactor that masks the Authorization, Proxy-Authorization and
+// cookie headers, the access_token, appsecret_proof, input_token and client_secret query
+// parameters, and the given secrets.
+func DefaultRedactor(secrets ...string) *Redactor {
+	return &Redactor{
+		Headers:     []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+		QueryParams: []string{accessTokenQueryKey, "appsecret_proof", "input_token", "client_secret"},
+		Secrets:     secrets,
+		Mask:        RedactedMask,
+	}
+}
+
+func (r *Redactor) mask() string {
+	if r.Mask == "" {
+		return RedactedMask
+	}
+
+	return r.Mask
+}
+
+// redactHeader masks the configured headers in header and returns their values.
+func (r *Redactor) redactHeader(header http.Header) []string {
+	var values []string
+	for _, name := range r.Headers {
+		name = http.CanonicalHeaderKey(name)
+		for i, value := range header[name] {
+			values = append(values, value, strings.TrimPrefix(value, "Bearer "))
+			header[name][i] = r.mask()
+		}
+	}
+
+	return values
+}
+
+// redactBytes replaces every occurrence of secrets in b with the mask.
+func (r *Redactor) redactBytes(b []byte, secrets []string) []byte {
+	for _, secret := range secrets {
+		if len(secret) < 4 || secret == r.mask() {
+			// too short to be a credential, masking it would only garble the output
+			continue
+		}
+		b = bytes.ReplaceAll(b, []byte(secret), []byte(r.mask()))
+	}
+
+	return b
+}
+
+// readBody reads and restores *body, returning its content.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(*body)
+	_ = (*body).Close()
+	*body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	return b, nil
+}
+
+// RedactRequest returns a copy of req with credentials masked. The body of req is read
+// and restored so that req can still be used.
+func (r *Redactor) RedactRequest(req *http.Request) (*http.Request, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := req.Clone(req.Context())
+	secrets := r.redactHeader(clone.Header)
+	if clone.URL != nil {
+		query := clone.URL.Query()
+		for _, key := range r.QueryParams {
+			if values, ok := query[key]; ok {
+				secrets = append(secrets, values...)
+				query.Set(key, r.mask())
+			}
+		}
+		clone.URL.RawQuery = query.Encode()
+	}
+	secrets = append(secrets, r.Secrets...)
+
+	if body != nil {
+		body = r.redactBytes(body, secrets)
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+		clone.ContentLength = int64(len(body))
+	}
+
+	return clone, nil
+}
+
+// RedactResponse returns a copy of resp with credentials masked. The body of resp is read
+// and restored so that resp can still be used.
+func (r *Redactor) RedactResponse(resp *http.Response) (*http.Response, error) {
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := *resp
+	clone.Header = resp.Header.Clone()
+	secrets := append(r.redactHeader(clone.Header), r.Secrets...)
+	if resp.Request != nil {
+		if clone.Request, err = r.RedactRequest(resp.Request); err != nil {
+			return nil, err
+		}
+	}
+	if body != nil {
+		body = r.redactBytes(body, secrets)
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+		clone.ContentLength = int64(len(body))
+	}
+
+	return &clone, nil
+}
+
+// DumpRequest is like httputil.DumpRequestOut but masks credentials with redactor.
+// A nil redactor uses DefaultRedactor. The body is dumped as described in Dumper.
+func DumpRequest(req *http.Request, body bool, redactor *Redactor) ([]byte, error) {
+	if body {
+		return (&Dumper{Redactor: redactor}).Request(req)
+	}
+	if redactor == nil {
+		redactor = DefaultRedactor()
+	}
+	clone, err := redactor.RedactRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("dump request: %w", err)
+	}
+	b, err := httputil.DumpRequestOut(clone, false)
+	if err != nil {
+		return nil, fmt.Errorf("dump request: %w", err)
+	}
+
+	return b, nil
+}
+
+// DumpResponse is like httputil.DumpResponse but masks credentials with redactor.
+// A nil redactor uses DefaultRedactor. The body is dumped as described in Dumper.
+func DumpResponse(resp *http.Response, body bool, redactor *Redactor) ([]byte, error) {
+	if body {
+		return (&Dumper{Redactor: redactor}).Response(resp)
+	}
+	if redactor == nil {
+		redactor = DefaultRedactor()
+	}
+	clone, err := redactor.RedactResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("dump response: %w", err)
+	}
+	b, err := httputil.DumpResponse(clone, false)
+	if err != nil {
+		return nil, fmt.Errorf("dump response: %w", err)
+	}
+
+	return b, nil
+}
+
+// RedactHook wraps hook so that it receives copies of the request and response with
+// credentials masked by redactor. A nil redactor uses DefaultRedactor. If the copies
+// cannot be made, hook is not called.
+func RedactHook(hook Hook, redactor *Redactor) Hook {
+	if redactor == nil {
+		redactor = DefaultRedactor()
+	}
+
+	return func(ctx context.Context, request *http.Request, response *http.Response) {
+		var err error
+		if request != nil {
+			if request, err = redactor.RedactRequest(request); err != nil {
+				return
+			}
+		}
+		if response != nil {
+			if response, err = redactor.RedactResponse(response); err != nil {
+				return
+			}
+		}
+		hook(ctx, request, response)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDumpRequest(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		redactor *Redactor
+		request  func() *http.Request
+		secrets  []string
+	}{
+		{
+			name: "authorization header",
+			request: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "https://graph.facebook.com/v16.0/1/messages",
+					strings.NewReader(`{"to":"255"}`))
+				req.Header.Set("Authorization", "Bearer EAAGtoken")
+
+				return req
+			},
+			secrets: []string{"EAAGtoken"},
+		},
+		{
+			name: "query parameters and body",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost,
+					"https://graph.facebook.com/v16.0/debug_token?input_token=EAAGinput&appsecret_proof=abcdef",
+					strings.NewReader(`input_token=EAAGinput`))
+			},
+			secrets: []string{"EAAGinput", "abcdef"},
+		},
+		{
+			name:     "app secret",
+			redactor: DefaultRedactor("s3cr3t-app"),
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "https://graph.facebook.com/oauth",
+					strings.NewReader(`{"client_secret":"s3cr3t-app"}`))
+			},
+			secrets: []string{"s3cr3t-app"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := tt.request()
+			original, _ := io.ReadAll(req.Body)
+			req.Body = io.NopCloser(bytes.NewReader(original))
+
+			dump, err := DumpRequest(req, true, tt.redactor)
+			if err != nil {
+				t.Fatalf("DumpRequest() error = %v", err)
+			}
+			for _, secret := range tt.secrets {
+				if bytes.Contains(dump, []byte(secret)) {
+					t.Errorf("dump contains %q:\n%s", secret, dump)
+				}
+			}
+			if !bytes.Contains(dump, []byte(RedactedMask)) {
+				t.Errorf("dump does not contain the mask:\n%s", dump)
+			}
+
+			body, _ := io.ReadAll(req.Body)
+			if !bytes.Equal(body, original) {
+				t.Errorf("request body = %q, want %q", body, original)
+			}
+		})
+	}
+}
+
+func TestRedactHook(t *testing.T) {
+	t.Parallel()
+	req := httptest.NewRequest(http.MethodGet, "https://graph.facebook.com/v16.0/1", nil)
+	req.Header.Set("Authorization", "Bearer EAAGtoken")
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"id":"1"}`)),
+	}
+
+	var got string
+	hook := RedactHook(func(ctx context.Context, request *http.Request, response *http.Response) {
+		got = request.Header.Get("Authorization")
+	}, nil)
+	hook(context.TODO(), req, resp)
+
+	if got != RedactedMask {
+		t.Errorf("hook got Authorization %q, want %q", got, RedactedMask)
+	}
+	if req.Header.Get("Authorization") != "Bearer EAAGtoken" {
+		t.Error("RedactHook modified the original request")
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"id":"1"}` {
+		t.Errorf("response body = %q, want it restored", body)
+	}
+}
+
+func TestDebugHookNilWriter(t *testing.T) { //nolint:paralleltest
+	req := httptest.NewRequest(http.MethodGet, "https://graph.facebook.com/v16.0/1", nil)
+	DebugHook(nil)(context.TODO(), req, nil)
+}
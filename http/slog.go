@@ -0,0 +1,161 @@
+//go:build go1.21
+
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// SlogOptions configures the records logged by SlogMiddleware.
+//
+// Level is the level of successful requests, it defaults to slog.LevelInfo, and ErrorLevel
+// the level of failed ones, it defaults to slog.LevelError. When LogBodies is set, the request
+// and response bodies are added to the records, with the credentials masked by Redactor, which
+// defaults to DefaultRedactor.
+type SlogOptions struct {
+	Level      slog.Leveler
+	ErrorLevel slog.Leveler
+	LogBodies  bool
+	Redactor   *Redactor
+}
+
+// SlogMiddleware returns a Middleware that logs every request that goes through it to logger
+// with its name, attempt, method, url, status, duration, outcome, the id of the sent message
+// (wamid) and the trace identifiers. Credentials are never logged. If options is nil, the
+// defaults are used.
+func SlogMiddleware(logger *slog.Logger, options *SlogOptions) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if options == nil {
+		options = &SlogOptions{}
+	}
+	level := options.Level
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	errorLevel := options.ErrorLevel
+	if errorLevel == nil {
+		errorLevel = slog.LevelError
+	}
+	redactor := options.Redactor
+	if redactor == nil {
+		redactor = DefaultRedactor()
+	}
+
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+			raw := rawResponseFromContext(ctx)
+			if raw == nil {
+				raw = &RawResponse{}
+				ctx = WithRawResponse(ctx, raw)
+			}
+			*raw = RawResponse{}
+			logEvent := func(ctx context.Context, event *Event) {
+				lvl := level.Level()
+				if event.Err != nil {
+					lvl = errorLevel.Level()
+				}
+				if !logger.Enabled(ctx, lvl) {
+					return
+				}
+				attrs := slogAttrs(event, raw, options.LogBodies, redactor)
+				logger.LogAttrs(ctx, lvl, "whatsapp request", attrs...)
+			}
+
+			return EventMiddleware(logEvent)(next).Send(ctx, request, v)
+		})
+	}
+}
+
+// slogAttrs returns the attributes logged for event.
+func slogAttrs(event *Event, raw *RawResponse, bodies bool, redactor *Redactor) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("request", event.Name),
+		slog.Int("attempt", event.Attempt),
+		slog.Duration("duration", event.Duration),
+		slog.String("outcome", string(event.Outcome)),
+	}
+
+	var secrets []string
+	if event.Request != nil {
+		redacted, err := redactor.RedactRequest(event.Request)
+		if err == nil {
+			attrs = append(attrs,
+				slog.String("method", redacted.Method),
+				slog.String("url", redacted.URL.String()))
+			secrets = requestSecrets(event.Request, redactor)
+			if bodies {
+				if body, err := readBody(&redacted.Body); err == nil && len(body) > 0 {
+					attrs = append(attrs, slog.String("request_body", string(body)))
+				}
+			}
+		}
+	}
+	if event.Response != nil {
+		attrs = append(attrs, slog.Int("status", event.Response.StatusCode))
+	}
+	if id := messageID(raw.Body); id != "" {
+		attrs = append(attrs, slog.String("wamid", id))
+	}
+	if bodies && len(raw.Body) > 0 {
+		body := redactor.redactBytes(raw.Body, append(secrets, redactor.Secrets...))
+		attrs = append(attrs, slog.String("response_body", string(body)))
+	}
+	if event.Trace.FBTraceID != "" {
+		attrs = append(attrs, slog.String("fbtrace_id", event.Trace.FBTraceID))
+	}
+	if event.Trace.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", event.Trace.RequestID))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", string(redactor.redactBytes([]byte(event.Err.Error()),
+			append(secrets, redactor.Secrets...)))))
+	}
+
+	return attrs
+}
+
+// requestSecrets returns the credentials carried by the headers of request.
+func requestSecrets(request *http.Request, redactor *Redactor) []string {
+	return redactor.redactHeader(request.Header.Clone())
+}
+
+// messageID returns the id of the first message in a send message response body.
+func messageID(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var response struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || len(response.Messages) == 0 {
+		return ""
+	}
+
+	return response.Messages[0].ID
+}
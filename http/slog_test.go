@@ -0,0 +1,93 @@
+//go:build go1.21
+
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSlogMiddleware(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		ctx       *Context
+		want      []string
+		wantLevel string
+	}{
+		{
+			name: "message sent",
+			ctx: &Context{
+				Method:     http.MethodPost,
+				StatusCode: http.StatusOK,
+				Body:       map[string]any{"messages": []map[string]string{{"id": "wamid.123"}}},
+			},
+			want:      []string{"request=\"send text\"", "status=200", "wamid=wamid.123", "outcome=success"},
+			wantLevel: "level=INFO",
+		},
+		{
+			name: "api error",
+			ctx: &Context{
+				Method:     http.MethodPost,
+				StatusCode: http.StatusBadRequest,
+				Headers:    map[string]string{HeaderFBRequestID: "request_1"},
+				Body:       map[string]any{"error": map[string]any{"code": 131030, "fbtrace_id": "trace_1"}},
+			},
+			want:      []string{"status=400", "fbtrace_id=trace_1", "request_id=request_1", "outcome=api_error"},
+			wantLevel: "level=ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := testServer(t, tt.ctx)
+			defer server.Close()
+
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, nil))
+			sender := Chain(NewSender(http.DefaultClient), SlogMiddleware(logger, &SlogOptions{LogBodies: true}))
+			request := &Request{
+				Context: &RequestContext{Name: "send text", BaseURL: server.URL},
+				Method:  http.MethodPost,
+				Bearer:  "secret_token",
+				Payload: map[string]string{"to": "255700000000", "token": "secret_token"},
+			}
+			_ = sender.Send(context.TODO(), request, &map[string]any{})
+
+			record := buf.String()
+			for _, want := range append(tt.want, tt.wantLevel) {
+				if !strings.Contains(record, want) {
+					t.Errorf("record %q does not contain %q", record, want)
+				}
+			}
+			if strings.Contains(record, "secret_token") {
+				t.Errorf("record %q contains the access token", record)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrStreamedPayload is returned when reading the body of a request whose payload is a Stream.
+var ErrStreamedPayload = errors.New("streamed payload can not be read")
+
+// Stream is a request payload that is sent to the server as it is read from Reader, the body
+// is never held in memory. Use it for large bodies, like media uploads.
+//
+// A stream can only be read once: the hooks see the request without a body, and the request
+// can not be retried or replayed. ContentLength is the length of the body when known, otherwise
+// the body is sent with chunked transfer encoding.
+type Stream struct {
+	Reader        io.Reader
+	ContentLength int64
+}
+
+// Read reads from the underlying reader.
+func (stream *Stream) Read(p []byte) (int, error) {
+	return stream.Reader.Read(p) //nolint:wrapcheck
+}
+
+// Close closes the underlying reader if it is an io.Closer.
+func (stream *Stream) Close() error {
+	if closer, ok := stream.Reader.(io.Closer); ok {
+		return closer.Close() //nolint:wrapcheck
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDo_Stream(t *testing.T) {
+	t.Parallel()
+
+	var (
+		received      string
+		contentLength int64
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, contentLength = string(body), r.ContentLength
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	request := &Request{
+		Context: &RequestContext{Name: "upload", BaseURL: server.URL, Endpoints: []string{"media"}},
+		Method:  http.MethodPost,
+		Payload: &Stream{Reader: strings.NewReader("streamed body"), ContentLength: 13},
+	}
+	if _, err := request.BodyBytes(); !errors.Is(err, ErrStreamedPayload) {
+		t.Fatalf("BodyBytes() error = %v, want %v", err, ErrStreamedPayload)
+	}
+
+	var hookBody []byte
+	hook := func(ctx context.Context, request *http.Request, response *http.Response) {
+		hookBody, _ = io.ReadAll(request.Body)
+	}
+	var response map[string]string
+	if err := Do(context.Background(), http.DefaultClient, request, &response, hook); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if received != "streamed body" || contentLength != 13 {
+		t.Errorf("server received %q (%d bytes), want %q (13 bytes)", received, contentLength, "streamed body")
+	}
+	if len(hookBody) != 0 {
+		t.Errorf("hook read body %q, want none", hookBody)
+	}
+	if response["id"] != "1" {
+		t.Errorf("Do() response = %v", response)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+)
+
+type (
+	// TokenSource supplies the access token used to authenticate requests. It lets long-lived
+	// system user tokens, rotating tokens and secret managers plug into the client.
+	// Token is called for every request, implementations that are expensive to call should
+	// cache the token, see NewReuseTokenSource.
+	TokenSource interface {
+		Token(ctx context.Context) (string, error)
+	}
+
+	// TokenInvalidator is implemented by a TokenSource that caches tokens. InvalidateToken
+	// is called when the API rejects a token with an invalid access token error (code 190),
+	// the next call to Token should return a fresh token.
+	TokenInvalidator interface {
+		InvalidateToken(token string)
+	}
+
+	// TokenSourceFunc is a function that implements TokenSource.
+	TokenSourceFunc func(ctx context.Context) (string, error)
+
+	// StaticTokenSource is a TokenSource that always returns the same token.
+	StaticTokenSource string
+
+	// ReuseTokenSource caches the token returned by the wrapped TokenSource until it is invalidated.
+	ReuseTokenSource struct {
+		mu     sync.Mutex
+		source TokenSource
+		token  string
+	}
+)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+func (s StaticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+// NewReuseTokenSource returns a TokenSource that calls source once and reuses the token
+// until the API rejects it.
+func NewReuseTokenSource(source TokenSource) *ReuseTokenSource {
+	return &ReuseTokenSource{source: source}
+}
+
+func (s *ReuseTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" {
+		return s.token, nil
+	}
+	token, err := s.source.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = token
+
+	return token, nil
+}
+
+// InvalidateToken drops the cached token if it is still token, so that a token refreshed
+// by a concurrent request is not thrown away.
+func (s *ReuseTokenSource) InvalidateToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = ""
+	}
+	if invalidator, ok := s.source.(TokenInvalidator); ok {
+		invalidator.InvalidateToken(token)
+	}
+}
+
+// WithAccessToken returns a shallow copy of the request authenticated with token.
+func (request *Request) WithAccessToken(token string) *Request {
+	r := *request
+	r.Bearer = token
+
+	return &r
+}
+
+// TokenSourceMiddleware returns a Middleware that authenticates each request with a token
+// from source. When the API responds with an invalid access token error and source is a
+// TokenInvalidator, the token is invalidated and the request is retried once with a fresh one.
+// A request whose payload is a Stream is not retried, its body has already been read.
+func TokenSourceMiddleware(source TokenSource) Middleware {
+	return func(next Sender) Sender {
+		return SenderFunc(func(ctx context.Context, request *Request, v any) error {
+			token, err := source.Token(ctx)
+			if err != nil {
+				return fmt.Errorf("token source: %w", err)
+			}
+
+			err = next.Send(ctx, request.WithAccessToken(token), v)
+			invalidator, ok := source.(TokenInvalidator)
+			if !ok || !werrors.IsAccessTokenError(err) {
+				return err
+			}
+
+			invalidator.InvalidateToken(token)
+			if _, streamed := request.Payload.(*Stream); streamed {
+				return err
+			}
+			fresh, ferr := source.Token(ctx)
+			if ferr != nil {
+				return fmt.Errorf("token source: %w", ferr)
+			}
+			if fresh == token {
+				return err
+			}
+
+			return next.Send(ctx, request.WithAccessToken(fresh), v)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+)
+
+func TestTokenSourceMiddleware(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		rejected   map[string]bool
+		reuse      bool
+		payload    any
+		wantTokens []string
+		wantErr    bool
+	}{
+		{
+			name:       "token fetched per request",
+			wantTokens: []string{"token-1"},
+		},
+		{
+			name:       "refresh on invalid token",
+			rejected:   map[string]bool{"token-1": true},
+			reuse:      true,
+			wantTokens: []string{"token-1", "token-2"},
+		},
+		{
+			name:       "no refresh without invalidator",
+			rejected:   map[string]bool{"token-1": true},
+			wantTokens: []string{"token-1"},
+			wantErr:    true,
+		},
+		{
+			name:       "retried only once",
+			rejected:   map[string]bool{"token-1": true, "token-2": true},
+			reuse:      true,
+			wantTokens: []string{"token-1", "token-2"},
+			wantErr:    true,
+		},
+		{
+			name:       "streamed payload not replayed",
+			rejected:   map[string]bool{"token-1": true},
+			reuse:      true,
+			payload:    &Stream{Reader: strings.NewReader("file")},
+			wantTokens: []string{"token-1"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls int
+			var source TokenSource = TokenSourceFunc(func(ctx context.Context) (string, error) {
+				calls++
+
+				return fmt.Sprintf("token-%d", calls), nil
+			})
+			if tt.reuse {
+				source = NewReuseTokenSource(source)
+			}
+
+			var got []string
+			sender := Chain(SenderFunc(func(ctx context.Context, request *Request, v any) error {
+				got = append(got, request.Bearer)
+				if tt.rejected[request.Bearer] {
+					return &ResponseError{Code: 401, Err: &werrors.Error{Code: werrors.CodeAccessTokenInvalid}}
+				}
+
+				return nil
+			}), TokenSourceMiddleware(source))
+
+			err := sender.Send(context.TODO(), &Request{Context: &RequestContext{}, Payload: tt.payload}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantTokens) {
+				t.Errorf("sent with tokens %v, want %v", got, tt.wantTokens)
+			}
+		})
+	}
+}
+
+func TestNewRequestWithContextAccessToken(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		request *Request
+	}{
+		{
+			name:    "bearer",
+			request: &Request{Bearer: "token"},
+		},
+		{
+			name:    "query",
+			request: &Request{Query: map[string]string{"access_token": "token", "fields": "id"}},
+		},
+		{
+			name: "replaced by WithAccessToken",
+			request: (&Request{Query: map[string]string{"access_token": "stale"}}).
+				WithAccessToken("token"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.request.Context = &RequestContext{BaseURL: BaseURL, ApiVersion: "v16.0", SenderID: "id"}
+			tt.request.Method = "GET"
+			req, err := NewRequestWithContext(context.TODO(), tt.request)
+			if err != nil {
+				t.Fatalf("NewRequestWithContext() error = %v", err)
+			}
+			if got := req.Header.Get("Authorization"); got != "Bearer token" {
+				t.Errorf("Authorization = %q, want %q", got, "Bearer token")
+			}
+			if req.URL.Query().Has("access_token") {
+				t.Errorf("access token sent in the url: %s", req.URL)
+			}
+		})
+	}
+}
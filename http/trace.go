@@ -0,0 +1,95 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"errors"
+	"net/http"
+)
+
+const (
+	// HeaderFBRequestID is the response header that carries the ID Meta assigns to a request.
+	HeaderFBRequestID = "X-FB-Request-Id"
+
+	// HeaderFBTraceID is the response header that carries the fbtrace_id of a request.
+	HeaderFBTraceID = "X-FB-Trace-Id"
+
+	// HeaderBusinessUseCaseUsage is the response header that reports the rate limit usage of
+	// the business use cases the request counted against.
+	HeaderBusinessUseCaseUsage = "X-Business-Use-Case-Usage"
+)
+
+// Trace contains the identifiers Meta support asks for when escalating an issue with a
+// request. Any of the fields can be empty, as not all responses carry them.
+type Trace struct {
+	FBTraceID            string
+	RequestID            string
+	BusinessUseCaseUsage string
+}
+
+// IsZero reports whether none of the trace identifiers is set.
+func (t Trace) IsZero() bool {
+	return t == Trace{}
+}
+
+// TraceFromResponse returns the trace identifiers set in the headers of response.
+func TraceFromResponse(response *http.Response) Trace {
+	if response == nil {
+		return Trace{}
+	}
+
+	return Trace{
+		FBTraceID:            response.Header.Get(HeaderFBTraceID),
+		RequestID:            response.Header.Get(HeaderFBRequestID),
+		BusinessUseCaseUsage: response.Header.Get(HeaderBusinessUseCaseUsage),
+	}
+}
+
+// TraceFromError returns the trace identifiers of the *ResponseError wrapped by err.
+func TraceFromError(err error) (Trace, bool) {
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		return Trace{}, false
+	}
+
+	return re.Trace(), true
+}
+
+// Trace returns the trace identifiers of the failed request. The fbtrace_id found in the
+// error body is used when the response did not carry it in the headers.
+func (e *ResponseError) Trace() Trace {
+	trace := Trace{
+		FBTraceID:            e.FBTraceID,
+		RequestID:            e.RequestID,
+		BusinessUseCaseUsage: e.BusinessUseCaseUsage,
+	}
+	if trace.FBTraceID == "" && e.Err != nil {
+		trace.FBTraceID = e.Err.FBTraceID
+	}
+
+	return trace
+}
+
+// setTrace copies the trace identifiers of trace to e.
+func (e *ResponseError) setTrace(trace Trace) {
+	e.FBTraceID = trace.FBTraceID
+	e.RequestID = trace.RequestID
+	e.BusinessUseCaseUsage = trace.BusinessUseCaseUsage
+}
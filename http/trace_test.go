@@ -0,0 +1,97 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    Trace
+	}{
+		{
+			name: "headers",
+			headers: map[string]string{
+				HeaderFBTraceID:            "trace_header",
+				HeaderFBRequestID:          "request_1",
+				HeaderBusinessUseCaseUsage: `{"123":[{"call_count":10}]}`,
+			},
+			want: Trace{
+				FBTraceID:            "trace_header",
+				RequestID:            "request_1",
+				BusinessUseCaseUsage: `{"123":[{"call_count":10}]}`,
+			},
+		},
+		{
+			name:    "fbtrace_id from the error body",
+			headers: map[string]string{HeaderFBRequestID: "request_2"},
+			want:    Trace{FBTraceID: "trace_body", RequestID: "request_2"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := testServer(t, &Context{
+				Method:     http.MethodGet,
+				StatusCode: http.StatusBadRequest,
+				Headers:    tt.headers,
+				Body: map[string]any{"error": map[string]any{
+					"code": 131030, "message": "not allowed", "fbtrace_id": "trace_body",
+				}},
+			})
+			defer server.Close()
+
+			var event *Event
+			sender := Chain(NewSender(http.DefaultClient), EventMiddleware(func(ctx context.Context, e *Event) {
+				event = e
+			}))
+			request := &Request{
+				Context: &RequestContext{Name: "trace test", BaseURL: server.URL},
+				Method:  http.MethodGet,
+			}
+
+			var user User
+			err := sender.Send(context.TODO(), request, &user)
+			var re *ResponseError
+			if !errors.As(err, &re) {
+				t.Fatalf("Send() error = %v, want *ResponseError", err)
+			}
+			if got, _ := TraceFromError(err); got != tt.want {
+				t.Errorf("TraceFromError() = %+v, want %+v", got, tt.want)
+			}
+			if event == nil || event.Trace != tt.want {
+				t.Errorf("event.Trace = %+v, want %+v", event, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want.RequestID) {
+				t.Errorf("Error() = %q, want it to contain %q", err.Error(), tt.want.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+)
+
+// DoTyped is like Do but decodes the response body into a new value of type T and
+// returns it, instead of requiring the caller to declare a destination and pass a pointer.
+//
+//	user, err := DoTyped[User](ctx, http.DefaultClient, request)
+func DoTyped[T any](ctx context.Context, client *http.Client, r *Request, hooks ...Hook) (*T, error) {
+	return SendTyped[T](ctx, NewSender(client, hooks...), r)
+}
+
+// SendTyped sends the request with sender and returns the response body decoded into
+// a new value of type T.
+func SendTyped[T any](ctx context.Context, sender Sender, r *Request) (*T, error) {
+	v := new(T)
+	if err := sender.Send(ctx, r, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestDoTyped(t *testing.T) { //nolint:paralleltest
+	tests := []struct {
+		name    string
+		ctx     *Context
+		want    *User
+		wantErr bool
+	}{
+		{
+			name: "decodes response",
+			ctx: &Context{
+				Method:     http.MethodGet,
+				StatusCode: http.StatusOK,
+				Body:       &User{Name: "Pius Alfred", Age: 77, Male: true},
+			},
+			want: &User{Name: "Pius Alfred", Age: 77, Male: true},
+		},
+		{
+			name: "returns response error",
+			ctx: &Context{
+				Method:     http.MethodGet,
+				StatusCode: http.StatusBadRequest,
+				Body:       map[string]any{"error": map[string]any{"message": "bad request", "code": 100}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := testServer(t, tt.ctx)
+			defer server.Close()
+
+			request := &Request{
+				Context: &RequestContext{Name: "test", BaseURL: server.URL},
+				Method:  http.MethodGet,
+			}
+
+			got, err := DoTyped[User](context.TODO(), http.DefaultClient, request)
+			if tt.wantErr {
+				var re *ResponseError
+				if !errors.As(err, &re) || got != nil {
+					t.Fatalf("DoTyped() = %v, %v, want nil, *ResponseError", got, err)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("DoTyped() error = %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("DoTyped() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+// HeaderAppUsage is the response header that reports the rate limit usage of the app.
+const HeaderAppUsage = "X-App-Usage"
+
+type (
+	// AppUsage is the content of the X-App-Usage header. The values are percentages of the
+	// app limits, the calls are throttled when any of them reaches 100.
+	AppUsage struct {
+		CallCount    int `json:"call_count"`
+		TotalCPUTime int `json:"total_cputime"`
+		TotalTime    int `json:"total_time"`
+	}
+
+	// BusinessUseCaseUsage is the usage of a business use case reported in the
+	// X-Business-Use-Case-Usage header. CallCount, TotalCPUTime and TotalTime are percentages,
+	// EstimatedTimeToRegainAccess is the number of minutes until throttled calls are accepted again.
+	BusinessUseCaseUsage struct {
+		Type                        string `json:"type"`
+		CallCount                   int    `json:"call_count"`
+		TotalCPUTime                int    `json:"total_cputime"`
+		TotalTime                   int    `json:"total_time"`
+		EstimatedTimeToRegainAccess int    `json:"estimated_time_to_regain_access"`
+	}
+
+	// Usage is the rate limit usage reported by the API. App is nil when the response did not
+	// carry the X-App-Usage header. BusinessUseCases is keyed by business ID.
+	Usage struct {
+		App              *AppUsage
+		BusinessUseCases map[string][]*BusinessUseCaseUsage
+		ObservedAt       time.Time
+	}
+
+	// UsageHook is called with the usage reported by every response that carries usage headers.
+	UsageHook func(ctx context.Context, usage *Usage)
+
+	// UsageTracker keeps the latest usage reported by the API. Use UsageTracker.Transport to
+	// observe the responses of a http client.
+	UsageTracker struct {
+		mu    sync.RWMutex
+		clock clock.Clock
+		hooks []UsageHook
+		usage *Usage
+	}
+
+	usageTransport struct {
+		tracker *UsageTracker
+		next    http.RoundTripper
+	}
+)
+
+// ParseUsage parses the X-App-Usage and X-Business-Use-Case-Usage headers. It returns nil
+// and no error when none of the headers is set.
+func ParseUsage(header http.Header) (*Usage, error) {
+	app := header.Get(HeaderAppUsage)
+	buc := header.Get(HeaderBusinessUseCaseUsage)
+	if app == "" && buc == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	usage := &Usage{}
+	if app != "" {
+		usage.App = &AppUsage{}
+		if err := json.Unmarshal([]byte(app), usage.App); err != nil {
+			return nil, fmt.Errorf("parse %s header: %w", HeaderAppUsage, err)
+		}
+	}
+	if buc != "" {
+		if err := json.Unmarshal([]byte(buc), &usage.BusinessUseCases); err != nil {
+			return nil, fmt.Errorf("parse %s header: %w", HeaderBusinessUseCaseUsage, err)
+		}
+	}
+
+	return usage, nil
+}
+
+// Max returns the highest utilization percentage across the app and the business use cases.
+func (u *Usage) Max() int {
+	if u == nil {
+		return 0
+	}
+	highest := 0
+	if u.App != nil {
+		highest = maxInt(highest, u.App.CallCount, u.App.TotalCPUTime, u.App.TotalTime)
+	}
+	for _, usages := range u.BusinessUseCases {
+		for _, usage := range usages {
+			if usage != nil {
+				highest = maxInt(highest, usage.CallCount, usage.TotalCPUTime, usage.TotalTime)
+			}
+		}
+	}
+
+	return highest
+}
+
+// RegainAccessIn returns the longest estimated time to regain access reported by the business
+// use cases, it is zero when no use case is throttled.
+func (u *Usage) RegainAccessIn() time.Duration {
+	if u == nil {
+		return 0
+	}
+	var longest int
+	for _, usages := range u.BusinessUseCases {
+		for _, usage := range usages {
+			if usage != nil {
+				longest = maxInt(longest, usage.EstimatedTimeToRegainAccess)
+			}
+		}
+	}
+
+	return time.Duration(longest) * time.Minute
+}
+
+// NewUsageTracker creates a UsageTracker that calls hooks every time a response reports usage.
+// If c is nil, the system clock is used to timestamp the observations.
+func NewUsageTracker(c clock.Clock, hooks ...UsageHook) *UsageTracker {
+	return &UsageTracker{
+		clock: clock.OrSystem(c),
+		hooks: hooks,
+	}
+}
+
+// Usage returns the latest usage observed, or nil if no response has reported usage yet.
+// The usage of business use cases is merged across responses, as a response only reports
+// the businesses it counted against.
+func (t *UsageTracker) Usage() *Usage {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.usage == nil {
+		return nil
+	}
+	usage := *t.usage
+	usage.BusinessUseCases = make(map[string][]*BusinessUseCaseUsage, len(t.usage.BusinessUseCases))
+	for id, usages := range t.usage.BusinessUseCases {
+		usage.BusinessUseCases[id] = usages
+	}
+
+	return &usage
+}
+
+// Observe records the usage reported by the headers of response and calls the hooks.
+// Responses without usage headers, or with malformed ones, are ignored.
+func (t *UsageTracker) Observe(ctx context.Context, response *http.Response) {
+	if response == nil {
+		return
+	}
+	usage, err := ParseUsage(response.Header)
+	if err != nil || usage == nil {
+		return
+	}
+	usage.ObservedAt = t.clock.Now()
+
+	t.mu.Lock()
+	if t.usage != nil {
+		if usage.App == nil {
+			usage.App = t.usage.App
+		}
+		for id, usages := range t.usage.BusinessUseCases {
+			if _, ok := usage.BusinessUseCases[id]; !ok {
+				if usage.BusinessUseCases == nil {
+					usage.BusinessUseCases = make(map[string][]*BusinessUseCaseUsage)
+				}
+				usage.BusinessUseCases[id] = usages
+			}
+		}
+	}
+	t.usage = usage
+	t.mu.Unlock()
+
+	for i, hook := range t.hooks {
+		if hook != nil {
+			runHook(ctx, HookKindUsage, i, RequestNameFromContext(ctx), func() {
+				hook(ctx, usage)
+			})
+		}
+	}
+}
+
+// Transport returns a http.RoundTripper that records the usage reported by the responses of
+// next. If next is nil, http.DefaultTransport is used.
+func (t *UsageTracker) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &usageTransport{tracker: t, next: next}
+}
+
+func (t *usageTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+	if err == nil {
+		t.tracker.Observe(request.Context(), response)
+	}
+
+	return response, err //nolint:wrapcheck
+}
+
+func maxInt(values ...int) int {
+	highest := 0
+	for _, v := range values {
+		if v > highest {
+			highest = v
+		}
+	}
+
+	return highest
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+func TestParseUsage(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantNil    bool
+		wantErr    bool
+		wantMax    int
+		wantRegain time.Duration
+	}{
+		{
+			name:    "no headers",
+			wantNil: true,
+		},
+		{
+			name:    "app usage",
+			headers: map[string]string{HeaderAppUsage: `{"call_count":28,"total_time":25,"total_cputime":12}`},
+			wantMax: 28,
+		},
+		{
+			name: "business use case usage",
+			headers: map[string]string{HeaderBusinessUseCaseUsage: `{"123":[{"type":"whatsapp_business_management",` +
+				`"call_count":95,"total_cputime":40,"total_time":30,"estimated_time_to_regain_access":5}]}`},
+			wantMax:    95,
+			wantRegain: 5 * time.Minute,
+		},
+		{
+			name:    "malformed",
+			headers: map[string]string{HeaderAppUsage: `{"call_count":`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+			usage, err := ParseUsage(header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUsage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (usage == nil) != tt.wantNil {
+				t.Fatalf("ParseUsage() = %+v, wantNil %v", usage, tt.wantNil)
+			}
+			if got := usage.Max(); got != tt.wantMax {
+				t.Errorf("Max() = %d, want %d", got, tt.wantMax)
+			}
+			if got := usage.RegainAccessIn(); got != tt.wantRegain {
+				t.Errorf("RegainAccessIn() = %v, want %v", got, tt.wantRegain)
+			}
+		})
+	}
+}
+
+func TestUsageTracker(t *testing.T) {
+	t.Parallel()
+	server := testServer(t, &Context{
+		Method:     http.MethodGet,
+		StatusCode: http.StatusOK,
+		Headers: map[string]string{
+			HeaderAppUsage:             `{"call_count":10}`,
+			HeaderBusinessUseCaseUsage: `{"123":[{"type":"whatsapp_business_messaging","call_count":60}]}`,
+		},
+		Body: &User{Name: "Pius"},
+	})
+	defer server.Close()
+
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var hooked *Usage
+	tracker := NewUsageTracker(clock.NewFake(now), func(ctx context.Context, usage *Usage) {
+		hooked = usage
+	})
+	if usage := tracker.Usage(); usage != nil {
+		t.Fatalf("Usage() = %+v, want nil", usage)
+	}
+
+	client := &http.Client{Transport: tracker.Transport(nil)}
+	request := &Request{
+		Context: &RequestContext{Name: "usage test", BaseURL: server.URL},
+		Method:  http.MethodGet,
+	}
+	var user User
+	if err := Do(context.TODO(), client, request, &user); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	usage := tracker.Usage()
+	if usage == nil || usage.Max() != 60 || !usage.ObservedAt.Equal(now) {
+		t.Fatalf("Usage() = %+v, want max 60 observed at %v", usage, now)
+	}
+	if hooked == nil || hooked.Max() != 60 {
+		t.Errorf("hook usage = %+v, want max 60", hooked)
+	}
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// DefaultIdempotencyTTL is how long MemoryIdempotencyStore remembers a key when no TTL is set.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+type (
+	// IdempotencyStore remembers the message ID (wamid) returned for an idempotency key.
+	// Implementations must be safe for concurrent use, a store shared between processes
+	// (Redis, a SQL table etc.) makes the dedupe work across instances.
+	IdempotencyStore interface {
+		// Get returns the message ID stored for key. found is false if the key is unknown.
+		Get(ctx context.Context, key string) (messageID string, found bool, err error)
+
+		// Set stores the message ID for key.
+		Set(ctx context.Context, key, messageID string) error
+	}
+
+	// IdempotencyKeyFunc generates an idempotency key for a message send that has no key
+	// in its context. Returning an empty key disables the dedupe for that send.
+	IdempotencyKeyFunc func(request *whttp.Request) (string, error)
+
+	// IdempotencyConfig configures the idempotency layer. Store is required, KeyFunc is
+	// optional and when nil only sends with a key set via WithIdempotencyKey are deduplicated.
+	IdempotencyConfig struct {
+		Store   IdempotencyStore
+		KeyFunc IdempotencyKeyFunc
+	}
+
+	// MemoryIdempotencyStore is an in-memory IdempotencyStore whose keys expire after a TTL.
+	MemoryIdempotencyStore struct {
+		mu        sync.Mutex
+		ttl       time.Duration
+		clock     clock.Clock
+		entries   map[string]idempotencyEntry
+		nextSweep time.Time
+	}
+
+	idempotencyEntry struct {
+		messageID string
+		expiresAt time.Time
+	}
+
+	idempotencyKey struct{}
+)
+
+// WithIdempotencyKey returns a context carrying the idempotency key of a logical message.
+// When the client is configured with WithIdempotency, a send whose key has already been
+// used returns the message ID of the first send instead of sending the message again.
+//
+//	ctx = whatsapp.WithIdempotencyKey(ctx, "otp:"+orderID)
+//	resp, err := client.SendTextMessage(ctx, recipient, message)
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key set with WithIdempotencyKey.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+
+	return key, ok && key != ""
+}
+
+// PayloadIdempotencyKey is an IdempotencyKeyFunc that derives the key from the sender,
+// the request URL and the message payload. Sending the exact same message to the same
+// recipient twice within the store's TTL only sends it once.
+func PayloadIdempotencyKey(request *whttp.Request) (string, error) {
+	body, err := request.BodyBytes()
+	if err != nil {
+		return "", fmt.Errorf("idempotency key: %w", err)
+	}
+	hash := sha256.New()
+	for _, part := range append([]string{request.Context.SenderID}, request.Context.Endpoints...) {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// NewMemoryIdempotencyStore returns a MemoryIdempotencyStore that keeps keys for ttl.
+// DefaultIdempotencyTTL is used when ttl is not positive.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		clock:   clock.System(),
+		entries: make(map[string]idempotencyEntry),
+	}
+}
+
+// SetClock sets the clock used to expire the keys.
+func (store *MemoryIdempotencyStore) SetClock(c clock.Clock) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.clock = clock.OrSystem(c)
+}
+
+func (store *MemoryIdempotencyStore) Get(_ context.Context, key string) (string, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		return "", false, nil
+	}
+	if store.clock.Now().After(entry.expiresAt) {
+		delete(store.entries, key)
+
+		return "", false, nil
+	}
+
+	return entry.messageID, true, nil
+}
+
+func (store *MemoryIdempotencyStore) Set(_ context.Context, key, messageID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.clock.Now()
+	store.sweep(now)
+	store.entries[key] = idempotencyEntry{messageID: messageID, expiresAt: now.Add(store.ttl)}
+
+	return nil
+}
+
+// sweep drops the expired keys. It runs at most four times per TTL, so that the keys are
+// dropped at most a quarter of the TTL late, Get never returns an expired key.
+func (store *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Before(store.nextSweep) {
+		return
+	}
+	store.nextSweep = now.Add(store.ttl / 4) //nolint:gomnd
+	for key, entry := range store.entries {
+		if now.After(entry.expiresAt) {
+			delete(store.entries, key)
+		}
+	}
+}
+
+// WithIdempotency enables deduplication of message sends. See WithIdempotencyKey.
+func WithIdempotency(config *IdempotencyConfig) ClientOption {
+	return func(client *Client) {
+		client.idempotency = config
+	}
+}
+
+// isMessageRequest reports whether request sends a message, i.e. is a POST to the
+// /{phone number id}/messages or /{phone number id}/marketing_messages endpoint.
+func isMessageRequest(request *whttp.Request) bool {
+	if request.Method != http.MethodPost || request.Context == nil {
+		return false
+	}
+	endpoints := request.Context.Endpoints
+
+	return len(endpoints) > 0 &&
+		(endpoints[len(endpoints)-1] == "messages" || endpoints[len(endpoints)-1] == marketingMessagesEndpoint)
+}
+
+// idempotencyMiddleware deduplicates message sends using the configured store. Sends that
+// share a key are serialized within the process so that concurrent retries do not race.
+func idempotencyMiddleware(config *IdempotencyConfig) whttp.Middleware {
+	var inflight keyedMutex
+
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			message, ok := v.(*ResponseMessage)
+			if !ok || !isMessageRequest(request) {
+				return next.Send(ctx, request, v)
+			}
+
+			key, ok := IdempotencyKeyFromContext(ctx)
+			if !ok && config.KeyFunc != nil {
+				var err error
+				if key, err = config.KeyFunc(request); err != nil {
+					return err
+				}
+			}
+			if key == "" {
+				return next.Send(ctx, request, v)
+			}
+
+			unlock := inflight.Lock(key)
+			defer unlock()
+
+			messageID, found, err := config.Store.Get(ctx, key)
+			if err != nil {
+				return fmt.Errorf("idempotency store get: %w", err)
+			}
+			if found {
+				*message = ResponseMessage{
+					Product:  messagingProduct,
+					Messages: []*MessageID{{ID: messageID}},
+				}
+
+				return nil
+			}
+
+			if err = next.Send(ctx, request, v); err != nil {
+				return err
+			}
+			if len(message.Messages) == 0 || message.Messages[0] == nil {
+				return nil
+			}
+			if err = config.Store.Set(ctx, key, message.Messages[0].ID); err != nil {
+				return fmt.Errorf("idempotency store set: %w", err)
+			}
+
+			return nil
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+func TestClientIdempotency(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		keyFunc   IdempotencyKeyFunc
+		keys      []string
+		texts     []string
+		wantSends int32
+	}{
+		{
+			name:      "same key sends once",
+			keys:      []string{"otp-1", "otp-1"},
+			texts:     []string{"123456", "123456"},
+			wantSends: 1,
+		},
+		{
+			name:      "different keys send twice",
+			keys:      []string{"otp-1", "otp-2"},
+			texts:     []string{"123456", "123456"},
+			wantSends: 2,
+		},
+		{
+			name:      "no key sends twice",
+			keys:      []string{"", ""},
+			texts:     []string{"123456", "123456"},
+			wantSends: 2,
+		},
+		{
+			name:      "payload key dedupes same message",
+			keyFunc:   PayloadIdempotencyKey,
+			keys:      []string{"", ""},
+			texts:     []string{"123456", "123456"},
+			wantSends: 1,
+		},
+		{
+			name:      "payload key sends different messages",
+			keyFunc:   PayloadIdempotencyKey,
+			keys:      []string{"", ""},
+			texts:     []string{"123456", "654321"},
+			wantSends: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var sends int32
+			transport := roundTripFunc(func(request *http.Request) (*http.Response, error) {
+				n := atomic.AddInt32(&sends, 1)
+				recorder := httptest.NewRecorder()
+				_, _ = fmt.Fprintf(recorder, `{"messaging_product":"whatsapp","messages":[{"id":"wamid.%d"}]}`, n)
+
+				return recorder.Result(), nil
+			})
+
+			client := NewClient(WithTransport(transport), WithIdempotency(&IdempotencyConfig{
+				Store:   NewMemoryIdempotencyStore(time.Minute),
+				KeyFunc: tt.keyFunc,
+			}))
+
+			var ids []string
+			for i, key := range tt.keys {
+				ctx := context.Background()
+				if key != "" {
+					ctx = WithIdempotencyKey(ctx, key)
+				}
+				resp, err := client.SendTextMessage(ctx, "255700000000", &TextMessage{Message: tt.texts[i]})
+				if err != nil {
+					t.Fatalf("SendTextMessage() error = %v", err)
+				}
+				ids = append(ids, resp.Messages[0].ID)
+			}
+
+			if got := atomic.LoadInt32(&sends); got != tt.wantSends {
+				t.Errorf("sent %d messages, want %d", got, tt.wantSends)
+			}
+			if tt.wantSends == 1 && ids[0] != ids[1] {
+				t.Errorf("deduplicated send returned %q, want %q", ids[1], ids[0])
+			}
+		})
+	}
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(time.Now())
+	store := NewMemoryIdempotencyStore(time.Minute)
+	store.SetClock(clk)
+	if err := store.Set(context.TODO(), "key", "wamid.1"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	clk.Advance(time.Minute + time.Second)
+	if _, found, _ := store.Get(context.TODO(), "key"); found {
+		t.Error("Get() found expired key")
+	}
+}
+
+func TestMemoryIdempotencyStoreSweep(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(time.Now())
+	store := NewMemoryIdempotencyStore(time.Minute)
+	store.SetClock(clk)
+	steps := []struct {
+		advance time.Duration
+		key     string
+		want    int
+	}{
+		{key: "key-1", want: 1},
+		{advance: 10 * time.Second, key: "key-2", want: 2},
+		{advance: 51 * time.Second, key: "key-3", want: 2}, // key-1 expired and swept
+		{advance: 10 * time.Second, key: "key-4", want: 3}, // key-2 expired, swept at most every 15s
+		{advance: 5 * time.Second, key: "key-5", want: 3},  // key-2 swept
+	}
+	for _, step := range steps {
+		clk.Advance(step.advance)
+		if err := store.Set(context.TODO(), step.key, "wamid"); err != nil {
+			t.Fatalf("Set(%s) error = %v", step.key, err)
+		}
+		store.mu.Lock()
+		got := len(store.entries)
+		store.mu.Unlock()
+		if got != step.want {
+			t.Errorf("after Set(%s) the store holds %d keys, want %d", step.key, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// ErrIdentityChanged is returned by the sends rejected because the identity of the
+// recipient changed, it wraps the error returned by the API.
+var ErrIdentityChanged = errors.New("recipient identity changed")
+
+// customerIdentityChanged is the type of the system messages sent when the identity of a
+// customer changes.
+const customerIdentityChanged = "customer_identity_changed"
+
+type (
+	// IdentityStore stores the latest identity key hash of each customer. Implementations
+	// must be safe for concurrent use.
+	IdentityStore interface {
+		// Identity returns the identity key hash of waID. found is false if it is unknown.
+		Identity(ctx context.Context, waID string) (hash string, found bool, err error)
+
+		// SetIdentity stores hash as the identity key hash of waID, seen at the given time.
+		SetIdentity(ctx context.Context, waID, hash string, at time.Time) error
+	}
+
+	// IdentityChangeFunc is called when the identity of waID changed. hash is the new
+	// identity key hash, it is empty when the change was only detected by a rejected send.
+	IdentityChangeFunc func(ctx context.Context, waID, hash string)
+
+	// IdentityConfig configures an IdentityTracker. Store defaults to a MemoryIdentityStore
+	// and Clock to the system clock. When IncludeHash is set, the known identity key hash of
+	// the recipient is sent with every message as recipient_identity_key_hash, so that the
+	// API rejects the message if the identity changed since. OnChange is called when a
+	// webhook reports a new identity or a send is rejected because of an identity change.
+	IdentityConfig struct {
+		Store       IdentityStore
+		Clock       clock.Clock
+		IncludeHash bool
+		OnChange    IdentityChangeFunc
+	}
+
+	// IdentityTracker keeps the identity key hashes of the customers, fed by the webhooks,
+	// and optionally checks them when sending:
+	//
+	//	identities := whatsapp.NewIdentityTracker(&whatsapp.IdentityConfig{IncludeHash: true})
+	//	client := whatsapp.NewClient(whatsapp.WithIdentityTracker(identities), ...)
+	//	listener.OnMessageReceived(identities.OnMessageReceived)
+	//
+	//	_, err := client.SendText(ctx, recipient, "your balance is ...")
+	//	if errors.Is(err, whatsapp.ErrIdentityChanged) {
+	//		// verify the customer again before sending sensitive information
+	//	}
+	IdentityTracker struct {
+		store       IdentityStore
+		clock       clock.Clock
+		includeHash bool
+		onChange    IdentityChangeFunc
+	}
+
+	// MemoryIdentityStore is an in-memory IdentityStore.
+	MemoryIdentityStore struct {
+		mu      sync.Mutex
+		entries map[string]string
+	}
+)
+
+// WithIdentityTracker installs the middleware of tracker, see IdentityTracker.
+func WithIdentityTracker(tracker *IdentityTracker) ClientOption {
+	return func(client *Client) {
+		client.identities = tracker
+	}
+}
+
+// NewIdentityTracker returns an IdentityTracker configured with config, which may be nil.
+func NewIdentityTracker(config *IdentityConfig) *IdentityTracker {
+	if config == nil {
+		config = &IdentityConfig{}
+	}
+	tracker := &IdentityTracker{
+		store:       config.Store,
+		clock:       clock.OrSystem(config.Clock),
+		includeHash: config.IncludeHash,
+		onChange:    config.OnChange,
+	}
+	if tracker.store == nil {
+		tracker.store = NewMemoryIdentityStore()
+	}
+
+	return tracker
+}
+
+// Identity returns the latest identity key hash of waID.
+func (tracker *IdentityTracker) Identity(ctx context.Context, waID string) (string, bool, error) {
+	hash, found, err := tracker.store.Identity(ctx, waID)
+	if err != nil {
+		return "", false, fmt.Errorf("identity tracker: %w", err)
+	}
+
+	return hash, found, nil
+}
+
+// Record stores hash as the identity key hash of waID and calls the OnChange callback when
+// it replaces a different hash.
+func (tracker *IdentityTracker) Record(ctx context.Context, waID, hash string) error {
+	previous, found, err := tracker.Identity(ctx, waID)
+	if err != nil {
+		return err
+	}
+	if found && previous == hash {
+		return nil
+	}
+	if err := tracker.store.SetIdentity(ctx, waID, hash, tracker.clock.Now()); err != nil {
+		return fmt.Errorf("identity tracker: %w", err)
+	}
+	if found && tracker.onChange != nil {
+		tracker.onChange(ctx, waID, hash)
+	}
+
+	return nil
+}
+
+// OnMessageReceived is a webhooks.OnMessageReceivedHook that records the identity key
+// hashes carried by the messages and by the customer_identity_changed system messages.
+func (tracker *IdentityTracker) OnMessageReceived(ctx context.Context, _ *webhooks.NotificationContext,
+	message *webhooks.Message,
+) error {
+	if message == nil {
+		return nil
+	}
+	if message.System != nil && message.System.Type == customerIdentityChanged && message.System.Identity != "" {
+		waID := message.System.Customer
+		if waID == "" {
+			waID = message.From
+		}
+
+		return tracker.Record(ctx, waID, message.System.Identity)
+	}
+	if message.Identity != nil && message.Identity.Hash != "" && message.From != "" {
+		return tracker.Record(ctx, message.From, message.Identity.Hash)
+	}
+
+	return nil
+}
+
+// Middleware returns the middleware that adds the identity key hashes to the message sends
+// when IncludeHash is set and turns the identity errors into ErrIdentityChanged. It is
+// installed by WithIdentityTracker.
+func (tracker *IdentityTracker) Middleware() whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			if !isMessageRequest(request) {
+				return next.Send(ctx, request, v)
+			}
+			payload, ok := auditPayloadOf(request)
+			if !ok || payload.To == "" {
+				return next.Send(ctx, request, v)
+			}
+			waID := strings.TrimPrefix(payload.To, "+")
+
+			if tracker.includeHash {
+				hash, found, err := tracker.Identity(ctx, waID)
+				if err != nil {
+					return err
+				}
+				if found {
+					if request, err = withIdentityHash(request, hash); err != nil {
+						return err
+					}
+				}
+			}
+
+			err := next.Send(ctx, request, v)
+			if err != nil && werrors.IsIdentityChangeError(err) {
+				if tracker.onChange != nil {
+					tracker.onChange(ctx, waID, "")
+				}
+
+				return fmt.Errorf("%w: %s: %w", ErrIdentityChanged, waID, err)
+			}
+
+			return err
+		})
+	}
+}
+
+// withIdentityHash returns a copy of request whose payload has the recipient identity key hash.
+func withIdentityHash(request *whttp.Request, hash string) (*whttp.Request, error) {
+	body, err := request.BodyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("identity tracker: %w", err)
+	}
+	var payload map[string]json.RawMessage
+	if err = json.Unmarshal(body, &payload); err != nil || payload == nil {
+		return nil, fmt.Errorf("identity tracker: %w: payload is not a JSON object", ErrBadRequestFormat)
+	}
+	payload["recipient_identity_key_hash"], _ = json.Marshal(hash) //nolint:errchkjson // a string
+	if body, err = json.Marshal(payload); err != nil {
+		return nil, fmt.Errorf("identity tracker: %w", err)
+	}
+	patched := *request
+	patched.Payload = body
+
+	return &patched, nil
+}
+
+// NewMemoryIdentityStore returns an empty MemoryIdentityStore.
+func NewMemoryIdentityStore() *MemoryIdentityStore {
+	return &MemoryIdentityStore{entries: make(map[string]string)}
+}
+
+func (store *MemoryIdentityStore) Identity(_ context.Context, waID string) (string, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	hash, ok := store.entries[waID]
+
+	return hash, ok, nil
+}
+
+func (store *MemoryIdentityStore) SetIdentity(_ context.Context, waID, hash string, _ time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.entries[waID] = hash
+
+	return nil
+}
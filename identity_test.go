@@ -0,0 +1,99 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestIdentityTracker(t *testing.T) {
+	t.Parallel()
+	var hashes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Hash string `json:"recipient_identity_key_hash"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &payload)
+		hashes = append(hashes, payload.Hash)
+		if payload.Hash == "old" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":137000,"message":"Identity key mismatch"}}`))
+
+			return
+		}
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	type change struct{ waID, hash string }
+	var changes []change
+	identities := NewIdentityTracker(&IdentityConfig{
+		IncludeHash: true,
+		OnChange: func(ctx context.Context, waID, hash string) {
+			changes = append(changes, change{waID, hash})
+		},
+	})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithIdentityTracker(identities))
+	ctx := context.Background()
+
+	// unknown identities are not sent
+	if _, err := client.SendText(ctx, "255700000001", "hi"); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+
+	message := &webhooks.Message{From: "255700000001", Identity: &webhooks.Identity{Hash: "old"}}
+	if err := identities.OnMessageReceived(ctx, nil, message); err != nil {
+		t.Fatalf("OnMessageReceived() error = %v", err)
+	}
+	_, err := client.SendText(ctx, "+255700000001", "hi")
+	if !errors.Is(err, ErrIdentityChanged) || !werrors.IsIdentityChangeError(err) {
+		t.Fatalf("SendText() error = %v, want %v wrapping the API error", err, ErrIdentityChanged)
+	}
+
+	system := &webhooks.Message{
+		From:   "255700000001",
+		Type:   "system",
+		System: &webhooks.System{Type: "customer_identity_changed", Identity: "new", Customer: "255700000001"},
+	}
+	if err := identities.OnMessageReceived(ctx, nil, system); err != nil {
+		t.Fatalf("OnMessageReceived() error = %v", err)
+	}
+	if _, err := client.SendText(ctx, "255700000001", "hi"); err != nil {
+		t.Fatalf("SendText() with the new identity error = %v", err)
+	}
+
+	if want := []string{"", "old", "new"}; len(hashes) != 3 || hashes[0] != want[0] || hashes[1] != want[1] ||
+		hashes[2] != want[2] {
+		t.Errorf("sent hashes = %q, want %q", hashes, want)
+	}
+	if len(changes) != 2 || changes[0] != (change{"255700000001", ""}) || changes[1] != (change{"255700000001", "new"}) {
+		t.Errorf("changes = %+v", changes)
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // decode GIF images, which are re-encoded as JPEG
+	"image/jpeg"
+	_ "image/png" // decode PNG images
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var ErrImageTooLarge = errors.New("image is too large")
+
+// maxImageScalings is the number of times an image is downscaled before giving up.
+const maxImageScalings = 10
+
+type (
+	// ImageProcessing configures the processing of the images before they are uploaded, see
+	// WithImageProcessing.
+	//
+	// MaxSize is the maximum size of an image in bytes, it defaults to MaxImageSize.
+	// MaxDimension is the maximum width and height of an image in pixels, larger images are
+	// downscaled, there is no limit when zero. Qualities are the JPEG qualities tried in turn
+	// when re-encoding an image, from 90 down to 40 by default, and ScaleStep is the factor
+	// the image is downscaled by when it is still too large at the lowest quality, 0.75 by
+	// default. Transparent images are flattened onto Background, white by default.
+	ImageProcessing struct {
+		MaxSize      int
+		MaxDimension int
+		Qualities    []int
+		ScaleStep    float64
+		Background   color.Color
+	}
+)
+
+// WithImageProcessing makes UploadMedia re-encode the images that are larger than the upload
+// limit or in a format other than JPEG and PNG as JPEG, stepping the quality down and then
+// downscaling them until they fit. The formats that can be converted are those registered
+// with the image package: JPEG, PNG and GIF, and e.g. WebP once golang.org/x/image/webp is
+// imported. Images that cannot be decoded are uploaded as is.
+func WithImageProcessing(config *ImageProcessing) ClientOption {
+	return func(client *Client) {
+		if config == nil {
+			config = &ImageProcessing{}
+		}
+		client.images = config
+	}
+}
+
+func (config *ImageProcessing) maxSize() int {
+	if config.MaxSize > 0 {
+		return config.MaxSize
+	}
+
+	return MaxImageSize
+}
+
+func (config *ImageProcessing) qualities() []int {
+	if len(config.Qualities) > 0 {
+		return config.Qualities
+	}
+
+	return []int{90, 80, 70, 60, 50, 40} //nolint:gomnd
+}
+
+func (config *ImageProcessing) scaleStep() float64 {
+	if config.ScaleStep > 0 && config.ScaleStep < 1 {
+		return config.ScaleStep
+	}
+
+	return 0.75 //nolint:gomnd
+}
+
+// Process returns the image of filename read from input ready to be uploaded, along with its
+// filename. The image is returned unchanged when it is a JPEG or PNG image within the limits,
+// or when it cannot be decoded. Otherwise, it is re-encoded as JPEG and its extension is
+// replaced by ".jpg". ErrImageTooLarge is returned when it cannot be made small enough.
+func (config *ImageProcessing) Process(ctx context.Context, filename string, input io.Reader,
+) (string, io.Reader, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return "", nil, fmt.Errorf("process image %s: %w", filename, err)
+	}
+	imageConfig, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return filename, bytes.NewReader(data), nil //nolint:nilerr // not an image we can process
+	}
+	supported := format == "jpeg" || format == "png"
+	fits := len(data) <= config.maxSize()
+	width, height := config.bounds(imageConfig.Width, imageConfig.Height)
+	if supported && fits && width == imageConfig.Width && height == imageConfig.Height {
+		return filename, bytes.NewReader(data), nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("process image %s: %w", filename, err)
+	}
+	flat := flattenImage(img, config.Background)
+	filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".jpg"
+
+	var output bytes.Buffer
+	for i := 0; i < maxImageScalings; i++ {
+		scaled := flat
+		if width != flat.Bounds().Dx() || height != flat.Bounds().Dy() {
+			scaled = downscaleImage(flat, width, height)
+		}
+		for _, quality := range config.qualities() {
+			if err := ctx.Err(); err != nil {
+				return "", nil, fmt.Errorf("process image %s: %w", filename, err)
+			}
+			output.Reset()
+			if err := jpeg.Encode(&output, scaled, &jpeg.Options{Quality: quality}); err != nil {
+				return "", nil, fmt.Errorf("process image %s: %w", filename, err)
+			}
+			if output.Len() <= config.maxSize() {
+				return filename, &output, nil
+			}
+		}
+		width = int(float64(width) * config.scaleStep())
+		height = int(float64(height) * config.scaleStep())
+		if width < 1 || height < 1 {
+			break
+		}
+	}
+
+	return "", nil, fmt.Errorf("%w: %s cannot be reduced to %d bytes", ErrImageTooLarge, filename, config.maxSize())
+}
+
+// bounds returns the size of an image of width x height downscaled to fit MaxDimension,
+// keeping its aspect ratio.
+func (config *ImageProcessing) bounds(width, height int) (int, int) {
+	longest := width
+	if height > longest {
+		longest = height
+	}
+	if config.MaxDimension <= 0 || longest <= config.MaxDimension {
+		return width, height
+	}
+	scale := float64(config.MaxDimension) / float64(longest)
+
+	return maxInt(1, int(float64(width)*scale)), maxInt(1, int(float64(height)*scale))
+}
+
+// flattenImage draws img onto an opaque background, JPEG has no transparency.
+func flattenImage(img image.Image, background color.Color) *image.RGBA {
+	if background == nil {
+		background = color.White
+	}
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+
+	return flat
+}
+
+// downscaleImage resizes src to width x height, each pixel being the average of the pixels
+// of src it covers.
+func downscaleImage(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, maxInt((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, maxInt((x+1)*srcWidth/width, x*srcWidth/width+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			pixel := dst.Pix[y*dst.Stride+x*4:]
+			for c := 0; c < 4; c++ {
+				pixel[c] = uint8(sum[c] / n)
+			}
+		}
+	}
+
+	return dst
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// noisyImage returns an image of random pixels, which compresses poorly.
+func noisyImage(width, height int) *image.RGBA {
+	random := rand.New(rand.NewSource(1)) //nolint:gosec
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	random.Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+
+	return img
+}
+
+func encodeImage(t *testing.T, format string, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("encode %s: %v", format, err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestImageProcessing_Process(t *testing.T) {
+	t.Parallel()
+	small := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	tests := []struct {
+		name         string
+		config       *ImageProcessing
+		filename     string
+		data         func(t *testing.T) []byte
+		wantFilename string
+		wantSame     bool
+		wantMaxSide  int
+		wantErr      error
+	}{
+		{
+			name:         "small png is unchanged",
+			config:       &ImageProcessing{},
+			filename:     "a.png",
+			data:         func(t *testing.T) []byte { t.Helper(); return encodeImage(t, "png", small) },
+			wantFilename: "a.png",
+			wantSame:     true,
+		},
+		{
+			name:         "not an image is unchanged",
+			config:       &ImageProcessing{MaxSize: 4},
+			filename:     "a.bin",
+			data:         func(t *testing.T) []byte { t.Helper(); return []byte("not an image") },
+			wantFilename: "a.bin",
+			wantSame:     true,
+		},
+		{
+			name:         "gif is converted to jpeg",
+			config:       &ImageProcessing{},
+			filename:     "a.gif",
+			data:         func(t *testing.T) []byte { t.Helper(); return encodeImage(t, "gif", small) },
+			wantFilename: "a.jpg",
+		},
+		{
+			name:         "large png is re-encoded and downscaled",
+			config:       &ImageProcessing{MaxSize: 20 * 1024},
+			filename:     "photo.png",
+			data:         func(t *testing.T) []byte { t.Helper(); return encodeImage(t, "png", noisyImage(400, 300)) },
+			wantFilename: "photo.jpg",
+		},
+		{
+			name:         "dimensions are limited",
+			config:       &ImageProcessing{MaxDimension: 100},
+			filename:     "photo.jpeg",
+			data:         func(t *testing.T) []byte { t.Helper(); return encodeImage(t, "jpeg", noisyImage(400, 300)) },
+			wantFilename: "photo.jpg",
+			wantMaxSide:  100,
+		},
+		{
+			name:     "cannot fit",
+			config:   &ImageProcessing{MaxSize: 10},
+			filename: "photo.png",
+			data:     func(t *testing.T) []byte { t.Helper(); return encodeImage(t, "png", noisyImage(50, 50)) },
+			wantErr:  ErrImageTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			input := tt.data(t)
+			filename, output, err := tt.config.Process(context.Background(), tt.filename, bytes.NewReader(input))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Process() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if filename != tt.wantFilename {
+				t.Errorf("Process() filename = %s, want %s", filename, tt.wantFilename)
+			}
+			data, _ := io.ReadAll(output)
+			if tt.wantSame {
+				if !bytes.Equal(data, input) {
+					t.Errorf("Process() changed the image")
+				}
+
+				return
+			}
+			if len(data) > tt.config.maxSize() {
+				t.Errorf("Process() size = %d, want at most %d", len(data), tt.config.maxSize())
+			}
+			config, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil || format != "jpeg" {
+				t.Fatalf("Process() output format = %s, %v, want jpeg", format, err)
+			}
+			if tt.wantMaxSide > 0 && (config.Width > tt.wantMaxSide || config.Height > tt.wantMaxSide) {
+				t.Errorf("Process() dimensions = %dx%d, want at most %d", config.Width, config.Height, tt.wantMaxSide)
+			}
+		})
+	}
+}
+
+func TestFlattenImage(t *testing.T) {
+	t.Parallel()
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(1, 0, color.NRGBA{R: 0xff, A: 0xff})
+	flat := flattenImage(img, nil)
+	if got := flat.RGBAAt(0, 0); got != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("transparent pixel = %v, want white", got)
+	}
+	if got := flat.RGBAAt(1, 0); got != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("opaque pixel = %v, want red", got)
+	}
+	if got := downscaleImage(flat, 1, 1).RGBAAt(0, 0); got != (color.RGBA{R: 0xff, G: 0x7f, B: 0x7f, A: 0xff}) {
+		t.Errorf("downscaled pixel = %v, want the average", got)
+	}
+}
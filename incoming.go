@@ -0,0 +1,149 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// ErrUnsupportedReply is returned when replying with a content whose message type is unknown.
+var ErrUnsupportedReply = errors.New("unsupported reply content")
+
+// IncomingMessage is a message received through the webhooks along with the notification that
+// carried it. Its replies and reactions are sent to the customer from the phone number that
+// received the message, quoting the message.
+//
+//	listener.OnMessageReceived(func(ctx context.Context, nctx *webhooks.NotificationContext,
+//		message *webhooks.Message,
+//	) error {
+//		_, err := whatsapp.NewIncomingMessage(nctx, message).Reply(ctx, client, "Thanks, we are on it")
+//
+//		return err
+//	})
+type IncomingMessage struct {
+	Notification *webhooks.NotificationContext
+	Message      *webhooks.Message
+}
+
+// NewIncomingMessage returns the IncomingMessage of message, received with the notification nctx.
+func NewIncomingMessage(nctx *webhooks.NotificationContext, message *webhooks.Message) *IncomingMessage {
+	return &IncomingMessage{Notification: nctx, Message: message}
+}
+
+// Reply sends content as a reply to the message. The message type is taken from the type of
+// content, which can be:
+//
+//   - a string or a *models.Text for a text message
+//   - a *models.Location or a *webhooks.SharedLocation for a location message
+//   - a []*models.Contact or models.Contacts for a contacts message
+//   - a *models.Interactive for an interactive message
+//   - a *models.Template for a template message
+//   - a *MediaMessage for a media message of its type
+func (incoming *IncomingMessage) Reply(ctx context.Context, client *Client, content any,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	messageType, content, err := replyContent(content)
+	if err != nil {
+		return nil, fmt.Errorf("reply to %s: %w", incoming.messageID(), err)
+	}
+	request := &ReplyMessage{
+		Context: incoming.messageID(),
+		Type:    messageType,
+		Content: content,
+	}
+
+	return client.Reply(incoming.context(ctx), incoming.sender(), request, options...)
+}
+
+// ReactTo reacts to the message with emoji, an empty emoji removes the previous reaction.
+func (incoming *IncomingMessage) ReactTo(ctx context.Context, client *Client, emoji string,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	request := &ReactMessage{
+		MessageID: incoming.messageID(),
+		Emoji:     emoji,
+	}
+
+	return client.React(incoming.context(ctx), incoming.sender(), request, options...)
+}
+
+func (incoming *IncomingMessage) messageID() string {
+	if incoming.Message == nil {
+		return ""
+	}
+
+	return incoming.Message.ID
+}
+
+func (incoming *IncomingMessage) sender() string {
+	if incoming.Message == nil {
+		return ""
+	}
+
+	return incoming.Message.From
+}
+
+// context returns ctx overriding the phone number of the client with the one that received the
+// message, when the notification has it.
+func (incoming *IncomingMessage) context(ctx context.Context) context.Context {
+	if incoming.Notification == nil || incoming.Notification.Metadata == nil ||
+		incoming.Notification.Metadata.PhoneNumberID == "" {
+		return ctx
+	}
+
+	return WithOverrides(ctx, &Overrides{PhoneNumberID: incoming.Notification.Metadata.PhoneNumberID})
+}
+
+// replyContent returns the message type of content and the content as sent in the payload.
+func replyContent(content any) (MessageType, any, error) {
+	switch content := content.(type) {
+	case string:
+		return textMessageType, &models.Text{Body: content}, nil
+	case *models.Text:
+		return textMessageType, content, nil
+	case *models.Location:
+		return locationMessageType, content, nil
+	case *webhooks.SharedLocation:
+		return locationMessageType, content.Model(), nil
+	case []*models.Contact:
+		return contactsMessageType, content, nil
+	case models.Contacts:
+		return contactsMessageType, content, nil
+	case *models.Interactive:
+		return "interactive", content, nil
+	case *models.Template:
+		return templateMessageType, content, nil
+	case *MediaMessage:
+		return MessageType(content.Type), &models.Media{
+			ID:       content.MediaID,
+			Link:     content.MediaLink,
+			Caption:  content.Caption,
+			Filename: content.Filename,
+			Provider: content.Provider,
+		}, nil
+	default:
+		return "", nil, fmt.Errorf("%w: %T", ErrUnsupportedReply, content)
+	}
+}
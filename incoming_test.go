@@ -0,0 +1,96 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestIncomingMessage(t *testing.T) {
+	t.Parallel()
+	type sent struct {
+		Path    string
+		Payload map[string]any
+	}
+	var (
+		mu       sync.Mutex
+		requests []sent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		_ = json.Unmarshal(body, &payload)
+		mu.Lock()
+		requests = append(requests, sent{Path: r.URL.Path, Payload: payload})
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.OUT"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_default"))
+	incoming := NewIncomingMessage(
+		&webhooks.NotificationContext{Metadata: &webhooks.Metadata{PhoneNumberID: "phone_received"}},
+		&webhooks.Message{From: "255700000000", ID: "wamid.IN", Type: "text"},
+	)
+
+	ctx := context.TODO()
+	if _, err := incoming.Reply(ctx, client, "on it"); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if _, err := incoming.Reply(ctx, client, &models.Location{Latitude: 1, Longitude: 2}); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if _, err := incoming.ReactTo(ctx, client, "👍"); err != nil {
+		t.Fatalf("ReactTo() error = %v", err)
+	}
+	if _, err := incoming.Reply(ctx, client, 42); !errors.Is(err, ErrUnsupportedReply) {
+		t.Fatalf("Reply() error = %v, want %v", err, ErrUnsupportedReply)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("requests = %d, want 3", len(requests))
+	}
+	for i, wantType := range []string{"text", "location", "reaction"} {
+		request := requests[i]
+		if !strings.Contains(request.Path, "phone_received") {
+			t.Errorf("request %d path = %s, want the phone number that received the message", i, request.Path)
+		}
+		if request.Payload["to"] != "255700000000" || request.Payload["type"] != wantType {
+			t.Errorf("request %d payload = %v, want a %s to 255700000000", i, request.Payload, wantType)
+		}
+	}
+	if quoted, _ := requests[0].Payload["context"].(map[string]any); quoted["message_id"] != "wamid.IN" {
+		t.Errorf("reply context = %v, want wamid.IN", requests[0].Payload["context"])
+	}
+	if reaction, _ := requests[2].Payload["reaction"].(map[string]any); reaction["message_id"] != "wamid.IN" {
+		t.Errorf("reaction = %v, want a reaction to wamid.IN", requests[2].Payload["reaction"])
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"io"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+type (
+	// MessageSender sends messages. It is implemented by *Client, depend on it instead of
+	// *Client to replace the client in tests, see package mock.
+	MessageSender interface {
+		SendMessage(ctx context.Context, message *models.Message, options ...SendOption) (*ResponseMessage, error)
+		SendText(ctx context.Context, recipient, text string, options ...SendOption) (*ResponseMessage, error)
+		SendTextMessage(ctx context.Context, recipient string, message *TextMessage, options ...SendOption) (
+			*ResponseMessage, error)
+		SendLocationMessage(ctx context.Context, recipient string, message *models.Location,
+			options ...SendOption) (*ResponseMessage, error)
+		SendMedia(ctx context.Context, recipient string, req *MediaMessage, cacheOptions *CacheOptions,
+			options ...SendOption) (*ResponseMessage, error)
+		SendContacts(ctx context.Context, recipient string, contacts []*models.Contact, options ...SendOption) (
+			*ResponseMessage, error)
+		SendInteractiveMessage(ctx context.Context, recipient string, req *models.Interactive,
+			options ...SendOption) (*ResponseMessage, error)
+		React(ctx context.Context, recipient string, req *ReactMessage, options ...SendOption) (
+			*ResponseMessage, error)
+		Reply(ctx context.Context, recipient string, req *ReplyMessage, options ...SendOption) (
+			*ResponseMessage, error)
+		MarkMessageRead(ctx context.Context, messageID string) (*StatusResponse, error)
+	}
+
+	// MediaManager uploads, downloads and deletes media. It is implemented by *Client.
+	MediaManager interface {
+		UploadMedia(ctx context.Context, mediaType MediaType, filename string, fr io.Reader) (
+			*UploadMediaResponse, error)
+		GetMediaInformation(ctx context.Context, mediaID string) (*MediaInformation, error)
+		DownloadMedia(ctx context.Context, mediaID string, retries int) (*DownloadMediaResponse, error)
+		DeleteMedia(ctx context.Context, mediaID string) (*DeleteMediaResponse, error)
+	}
+
+	// TemplateManager sends template messages. It is implemented by *Client.
+	TemplateManager interface {
+		SendTemplate(ctx context.Context, recipient string, req *Template, options ...SendOption) (
+			*ResponseMessage, error)
+		SendTextTemplate(ctx context.Context, recipient string, req *TextTemplateRequest, options ...SendOption) (
+			*ResponseMessage, error)
+		SendMediaTemplate(ctx context.Context, recipient string, req *MediaTemplateRequest, options ...SendOption) (
+			*ResponseMessage, error)
+		SendInteractiveTemplate(ctx context.Context, recipient string, req *InteractiveTemplateRequest,
+			options ...SendOption) (*ResponseMessage, error)
+		SendMarketingTemplate(ctx context.Context, recipient string, req *MarketingTemplateRequest,
+			options ...SendOption) (*ResponseMessage, error)
+	}
+)
+
+var (
+	_ MessageSender   = (*Client)(nil)
+	_ MediaManager    = (*Client)(nil)
+	_ TemplateManager = (*Client)(nil)
+)
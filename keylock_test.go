@@ -0,0 +1,56 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+package whatsapp
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	t.Parallel()
+	const keys = 4
+	var (
+		km keyedMutex
+		wg sync.WaitGroup
+		// every slot is only guarded by the keyed lock of its key, the race detector
+		// reports the writes that are not serialized
+		counts [keys]int
+	)
+	for i := 0; i < 400; i++ {
+		wg.Add(1)
+		go func(slot int) {
+			defer wg.Done()
+			unlock := km.Lock(strconv.Itoa(slot))
+			defer unlock()
+			counts[slot]++
+		}(i % keys)
+	}
+	wg.Wait()
+
+	for slot, count := range counts {
+		if count != 100 {
+			t.Errorf("key %d locked %d times, want 100", slot, count)
+		}
+	}
+	if size := km.size(); size != 0 {
+		t.Errorf("size() = %d after every key was unlocked, want 0", size)
+	}
+}
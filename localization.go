@@ -0,0 +1,298 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+var (
+	ErrMissingTranslation = errors.New("missing translation")
+	ErrLocalizationNotSet = errors.New("localization not set, see WithLocalization")
+)
+
+type (
+	// LocaleResolver returns the locale of a recipient, e.g. from the user profile kept by the
+	// application. An empty locale means that it is not known, the default locale is used.
+	LocaleResolver interface {
+		Locale(ctx context.Context, recipient string) (string, error)
+	}
+
+	LocaleResolverFunc func(ctx context.Context, recipient string) (string, error)
+
+	// PhonePrefixLocales is a LocaleResolver that picks the locale from the country calling
+	// code of the recipient, the longest matching prefix wins:
+	//
+	//	whatsapp.PhonePrefixLocales{"255": "sw_TZ", "254": "sw_KE", "1": "en_US", "1809": "es_DO"}
+	PhonePrefixLocales map[string]string
+
+	// BundleConfig configures a Bundle. DefaultLocale is the locale used when the locale of
+	// the recipient is unknown or has no translation, it defaults to "en". Resolver returns
+	// the locale of the recipients. AutoEscape and Funcs are applied to the translations, which
+	// are text/template templates, see TextTemplatesConfig.
+	BundleConfig struct {
+		DefaultLocale string
+		Resolver      LocaleResolver
+		AutoEscape    bool
+		Funcs         template.FuncMap
+	}
+
+	// Bundle holds the translations of the outgoing messages, keyed by message key and locale,
+	// so that the copy of the messages is kept out of the code:
+	//
+	//	bundle := whatsapp.NewBundle(&whatsapp.BundleConfig{
+	//		DefaultLocale: "en",
+	//		Resolver:      whatsapp.PhonePrefixLocales{"255": "sw"},
+	//	})
+	//	bundle.AddMessages("en", map[string]string{"greeting": "Hello {{.Name}}"})
+	//	bundle.AddMessages("sw", map[string]string{"greeting": "Habari {{.Name}}"})
+	//	client := whatsapp.NewClient(whatsapp.WithLocalization(bundle), ...)
+	//	client.SendLocalizedText(ctx, "255700000001", "greeting", user)
+	//
+	// The locales are normalized to the format of the template language codes, "pt-br"
+	// becomes "pt_BR". A message missing in a locale falls back to its language, then to the
+	// default locale: "pt_BR", "pt", "en".
+	Bundle struct {
+		mu            sync.RWMutex
+		locales       map[string]*TextTemplates
+		defaultLocale string
+		resolver      LocaleResolver
+		templates     *TextTemplatesConfig
+	}
+)
+
+func (fn LocaleResolverFunc) Locale(ctx context.Context, recipient string) (string, error) {
+	return fn(ctx, recipient)
+}
+
+func (locales PhonePrefixLocales) Locale(_ context.Context, recipient string) (string, error) {
+	number := strings.TrimLeft(recipient, "+0")
+	locale, longest := "", 0
+	for prefix, l := range locales {
+		if len(prefix) > longest && strings.HasPrefix(number, prefix) {
+			locale, longest = l, len(prefix)
+		}
+	}
+
+	return locale, nil
+}
+
+// NewBundle returns an empty Bundle configured with config, which may be nil.
+func NewBundle(config *BundleConfig) *Bundle {
+	if config == nil {
+		config = &BundleConfig{}
+	}
+	bundle := &Bundle{
+		locales:       make(map[string]*TextTemplates),
+		defaultLocale: NormalizeLocale(config.DefaultLocale),
+		resolver:      config.Resolver,
+		templates:     &TextTemplatesConfig{Funcs: config.Funcs, AutoEscape: config.AutoEscape},
+	}
+	if bundle.defaultLocale == "" {
+		bundle.defaultLocale = "en"
+	}
+
+	return bundle
+}
+
+// NormalizeLocale formats locale like the template language codes: the language in lower
+// case and the region in upper case separated by an underscore, "en-gb" becomes "en_GB".
+func NormalizeLocale(locale string) string {
+	locale = strings.TrimSpace(strings.ReplaceAll(locale, "-", "_"))
+	language, region, found := strings.Cut(locale, "_")
+	if !found {
+		return strings.ToLower(language)
+	}
+
+	return strings.ToLower(language) + "_" + strings.ToUpper(region)
+}
+
+// Add adds the translation of the message key in locale, replacing the existing one.
+func (bundle *Bundle) Add(locale, key, text string) error {
+	locale = NormalizeLocale(locale)
+	bundle.mu.Lock()
+	defer bundle.mu.Unlock()
+	templates, ok := bundle.locales[locale]
+	if !ok {
+		templates = NewTextTemplates(bundle.templates)
+		bundle.locales[locale] = templates
+	}
+	if err := templates.Register(key, text); err != nil {
+		return fmt.Errorf("add translation (%s): %w", locale, err)
+	}
+
+	return nil
+}
+
+// AddMessages adds the translations in locale of the message keys of messages.
+func (bundle *Bundle) AddMessages(locale string, messages map[string]string) error {
+	keys := make([]string, 0, len(messages))
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := bundle.Add(locale, key, messages[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadJSON adds the translations in locale read from r, a JSON object mapping the message
+// keys to their translation: {"greeting": "Hello {{.Name}}"}.
+func (bundle *Bundle) LoadJSON(locale string, r io.Reader) error {
+	var messages map[string]string
+	if err := json.NewDecoder(r).Decode(&messages); err != nil {
+		return fmt.Errorf("load translations (%s): %w", locale, err)
+	}
+
+	return bundle.AddMessages(locale, messages)
+}
+
+// Locales returns the locales that have at least one translation, sorted.
+func (bundle *Bundle) Locales() []string {
+	bundle.mu.RLock()
+	defer bundle.mu.RUnlock()
+	locales := make([]string, 0, len(bundle.locales))
+	for locale := range bundle.locales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// Localize renders the message key in locale with data, falling back to the language of
+// locale and then to the default locale. It returns the text and the locale it was
+// rendered in, which can be used as the language of a template message sent alongside.
+func (bundle *Bundle) Localize(locale, key string, data any) (string, string, error) {
+	for _, candidate := range bundle.fallbacks(locale) {
+		bundle.mu.RLock()
+		templates, ok := bundle.locales[candidate]
+		bundle.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		text, err := templates.Render(key, data)
+		if errors.Is(err, ErrTextTemplateNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", candidate, err
+		}
+
+		return text, candidate, nil
+	}
+
+	return "", "", fmt.Errorf("%w: %s (%s)", ErrMissingTranslation, key, locale)
+}
+
+// RecipientLocale returns the locale of recipient: the locale set on ctx by WithLocale,
+// else the one returned by the resolver, else the default locale.
+func (bundle *Bundle) RecipientLocale(ctx context.Context, recipient string) (string, error) {
+	if locale, ok := ctx.Value(localeContextKey{}).(string); ok && locale != "" {
+		return NormalizeLocale(locale), nil
+	}
+	if bundle.resolver != nil {
+		locale, err := bundle.resolver.Locale(ctx, recipient)
+		if err != nil {
+			return "", fmt.Errorf("resolve locale of %s: %w", recipient, err)
+		}
+		if locale != "" {
+			return NormalizeLocale(locale), nil
+		}
+	}
+
+	return bundle.defaultLocale, nil
+}
+
+// LocalizeFor renders the message key with data in the locale of recipient.
+func (bundle *Bundle) LocalizeFor(ctx context.Context, recipient, key string, data any) (string, string, error) {
+	locale, err := bundle.RecipientLocale(ctx, recipient)
+	if err != nil {
+		return "", "", err
+	}
+
+	return bundle.Localize(locale, key, data)
+}
+
+func (bundle *Bundle) fallbacks(locale string) []string {
+	locale = NormalizeLocale(locale)
+	candidates := make([]string, 0, 4) //nolint:gomnd
+	add := func(l string) {
+		for _, c := range candidates {
+			if c == l {
+				return
+			}
+		}
+		if l != "" {
+			candidates = append(candidates, l)
+		}
+	}
+	language, _, _ := strings.Cut(locale, "_")
+	defaultLanguage, _, _ := strings.Cut(bundle.defaultLocale, "_")
+	add(locale)
+	add(language)
+	add(bundle.defaultLocale)
+	add(defaultLanguage)
+
+	return candidates
+}
+
+type localeContextKey struct{}
+
+// WithLocale sets the locale of the messages sent with ctx, it takes precedence over the
+// LocaleResolver of the Bundle.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, locale)
+}
+
+// WithLocalization sets the bundle used by SendLocalizedText.
+func WithLocalization(bundle *Bundle) ClientOption {
+	return func(client *Client) {
+		client.localization = bundle
+	}
+}
+
+// SendLocalizedText sends the message key, rendered with data in the locale of recipient,
+// as a text message.
+func (client *Client) SendLocalizedText(ctx context.Context, recipient, key string, data any,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	if client.localization == nil {
+		return nil, ErrLocalizationNotSet
+	}
+	text, _, err := client.localization.LocalizeFor(ctx, recipient, key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.SendText(ctx, recipient, text, options...)
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testBundle(t *testing.T, config *BundleConfig) *Bundle {
+	t.Helper()
+	bundle := NewBundle(config)
+	if err := bundle.AddMessages("en", map[string]string{
+		"greeting": "Hello {{.}}",
+		"farewell": "Goodbye {{.}}",
+		"receipt":  "Payment received",
+	}); err != nil {
+		t.Fatalf("AddMessages() error = %v", err)
+	}
+	if err := bundle.AddMessages("sw", map[string]string{
+		"greeting": "Habari {{.}}",
+		"farewell": "Kwaheri {{.}}",
+	}); err != nil {
+		t.Fatalf("AddMessages() error = %v", err)
+	}
+	if err := bundle.LoadJSON("pt-br", strings.NewReader(`{"greeting": "Olá {{.}}"}`)); err != nil {
+		t.Fatalf("LoadJSON() error = %v", err)
+	}
+
+	return bundle
+}
+
+func TestBundle_Localize(t *testing.T) {
+	t.Parallel()
+	bundle := testBundle(t, nil)
+	tests := []struct {
+		name       string
+		locale     string
+		key        string
+		want       string
+		wantLocale string
+		wantErr    error
+	}{
+		{name: "exact locale", locale: "sw", key: "greeting", want: "Habari Asha", wantLocale: "sw"},
+		{name: "region falls back to language", locale: "sw_TZ", key: "farewell", want: "Kwaheri Asha", wantLocale: "sw"},
+		{name: "normalized locale", locale: "PT-br", key: "greeting", want: "Olá Asha", wantLocale: "pt_BR"},
+		{name: "missing key falls back to default", locale: "sw", key: "receipt", want: "Payment received", wantLocale: "en"},
+		{name: "unknown locale", locale: "fr", key: "greeting", want: "Hello Asha", wantLocale: "en"},
+		{name: "empty locale", locale: "", key: "farewell", want: "Goodbye Asha", wantLocale: "en"},
+		{name: "missing everywhere", locale: "sw", key: "unknown", wantErr: ErrMissingTranslation},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, locale, err := bundle.Localize(tt.locale, tt.key, "Asha")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Localize() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want || locale != tt.wantLocale {
+				t.Errorf("Localize() = %q, %q, want %q, %q", got, locale, tt.want, tt.wantLocale)
+			}
+		})
+	}
+
+	if got, want := strings.Join(bundle.Locales(), ","), "en,pt_BR,sw"; got != want {
+		t.Errorf("Locales() = %s, want %s", got, want)
+	}
+}
+
+func TestPhonePrefixLocales(t *testing.T) {
+	t.Parallel()
+	locales := PhonePrefixLocales{"1": "en_US", "1809": "es_DO", "255": "sw_TZ"}
+	tests := map[string]string{
+		"+18095550100":  "es_DO",
+		"12025550100":   "en_US",
+		"255700000001":  "sw_TZ",
+		"+447700900000": "",
+	}
+	for recipient, want := range tests {
+		if got, _ := locales.Locale(context.Background(), recipient); got != want {
+			t.Errorf("Locale(%s) = %q, want %q", recipient, got, want)
+		}
+	}
+}
+
+func TestClient_SendLocalizedText(t *testing.T) {
+	t.Parallel()
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text struct {
+				Body string `json:"body"`
+			} `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sent = append(sent, body.Text.Body)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.OUT"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	if _, err := NewClient().SendLocalizedText(ctx, "255700000001", "greeting", nil); !errors.Is(
+		err, ErrLocalizationNotSet) {
+		t.Fatalf("SendLocalizedText() error = %v, want %v", err, ErrLocalizationNotSet)
+	}
+
+	bundle := testBundle(t, &BundleConfig{Resolver: PhonePrefixLocales{"255": "sw_TZ", "55": "pt_BR"}})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithLocalization(bundle))
+	for _, recipient := range []string{"255700000001", "5511900000000", "447700900000"} {
+		if _, err := client.SendLocalizedText(ctx, recipient, "greeting", "Asha"); err != nil {
+			t.Fatalf("SendLocalizedText() error = %v", err)
+		}
+	}
+	if _, err := client.SendLocalizedText(WithLocale(ctx, "en"), "255700000001", "greeting", "Asha"); err != nil {
+		t.Fatalf("SendLocalizedText() error = %v", err)
+	}
+
+	if got, want := strings.Join(sent, "|"), "Habari Asha|Olá Asha|Hello Asha|Hello Asha"; got != want {
+		t.Errorf("sent = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build go1.21
+
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"log/slog"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// WithLogger logs every request attempt made by the client to logger, see
+// whttp.SlogMiddleware. The access token and the app secret are masked in the records.
+// If options is nil, the defaults are used.
+func WithLogger(logger *slog.Logger, options *whttp.SlogOptions) ClientOption {
+	return func(client *Client) {
+		client.logging = func(secrets ...string) whttp.Middleware {
+			opts := whttp.SlogOptions{}
+			if options != nil {
+				opts = *options
+			}
+			if opts.Redactor == nil {
+				opts.Redactor = whttp.DefaultRedactor(secrets...)
+			}
+
+			return whttp.SlogMiddleware(logger, &opts)
+		}
+	}
+}
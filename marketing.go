@@ -0,0 +1,112 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+// Statuses of the messages accepted by the Marketing Messages Lite API, see
+// MessageID.MessageStatus.
+const (
+	MarketingMessageAccepted                 = "accepted"
+	MarketingMessageHeldForQualityAssessment = "held_for_quality_assessment"
+)
+
+const marketingMessagesEndpoint = "marketing_messages"
+
+type (
+	// MarketingTemplateRequest is a marketing template sent with SendMarketingTemplate.
+	// MessageActivitySharing turns the sharing of the message activity (clicks, reads etc.)
+	// used by the lite analytics on or off for this message, nil keeps the setting of the
+	// WhatsApp Business Account.
+	MarketingTemplateRequest struct {
+		Name                   string
+		LanguageCode           string
+		LanguagePolicy         string
+		Components             []*models.TemplateComponent
+		MessageActivitySharing *bool
+	}
+
+	marketingMessage struct {
+		*models.Message
+		MessageActivitySharing *bool `json:"message_activity_sharing,omitempty"`
+	}
+)
+
+// SendMarketingTemplate sends a marketing template message through the Marketing Messages
+// Lite API, which uses the marketing_messages endpoint instead of messages. The template
+// must be an approved marketing template and the phone number must be onboarded to the
+// Marketing Messages Lite API.
+//
+// The MessageStatus of the returned message ID tells whether the message was accepted or
+// held for quality assessment. The sends are considered marketing by WithConsent.
+func (client *Client) SendMarketingTemplate(ctx context.Context, recipient string, req *MarketingTemplateRequest,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	if err := models.ValidateLanguageCode(req.LanguageCode); err != nil {
+		return nil, fmt.Errorf("send marketing template: %w", err)
+	}
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
+
+	payload := &marketingMessage{
+		Message: &models.Message{
+			Product:       messagingProduct,
+			To:            recipient,
+			RecipientType: individualRecipientType,
+			Type:          templateMessageType,
+			Template: &models.Template{
+				Language: &models.TemplateLanguage{
+					Code:   req.LanguageCode,
+					Policy: req.LanguagePolicy,
+				},
+				Name:       req.Name,
+				Components: req.Components,
+			},
+		},
+		MessageActivitySharing: req.MessageActivitySharing,
+	}
+	params := cctx.requestContext().messageRequest("send marketing template", payload)
+	params.Context.Endpoints = []string{marketingMessagesEndpoint}
+
+	message, err := whttp.SendTyped[ResponseMessage](ctx, opts.sender(client.sender), params)
+	if err != nil {
+		return nil, fmt.Errorf("send marketing template: %w", err)
+	}
+
+	return message, nil
+}
+
+// isMarketingRequest reports whether request is a send through the Marketing Messages Lite API.
+func isMarketingRequest(request *whttp.Request) bool {
+	endpoints := request.Context.Endpoints
+
+	return len(endpoints) > 0 && endpoints[len(endpoints)-1] == marketingMessagesEndpoint
+}
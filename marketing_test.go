@@ -0,0 +1,85 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendMarketingTemplate(t *testing.T) {
+	t.Parallel()
+	var (
+		path    string
+		payload map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &payload)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","contacts":[{"input":"255700000001",` +
+			`"wa_id":"255700000001"}],"messages":[{"id":"wamid.1","message_status":"accepted"}]}`))
+	}))
+	defer server.Close()
+
+	var audited AuditRecord
+	consent := NewConsentManager(nil)
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithConsent(consent),
+		WithAuditSink(AuditSinkFunc(func(ctx context.Context, record AuditRecord) { audited = record })))
+	ctx := context.Background()
+
+	sharing := false
+	response, err := client.SendMarketingTemplate(ctx, "255700000001", &MarketingTemplateRequest{
+		Name:                   "spring_sale",
+		LanguageCode:           "en_US",
+		MessageActivitySharing: &sharing,
+	}, WithCallbackData("campaign-7"))
+	if err != nil {
+		t.Fatalf("SendMarketingTemplate() error = %v", err)
+	}
+	if path != "/v23.0/phone_1/marketing_messages" {
+		t.Errorf("path = %q, want the marketing_messages endpoint", path)
+	}
+	if payload["type"] != "template" || payload["message_activity_sharing"] != false ||
+		payload["biz_opaque_callback_data"] != "campaign-7" {
+		t.Errorf("payload = %v", payload)
+	}
+	if response.Messages[0].MessageStatus != MarketingMessageAccepted {
+		t.Errorf("MessageStatus = %q, want %q", response.Messages[0].MessageStatus, MarketingMessageAccepted)
+	}
+	if audited.MessageID != "wamid.1" || audited.TemplateName != "spring_sale" {
+		t.Errorf("audit record = %+v, want the marketing send recorded", audited)
+	}
+
+	// sends through the marketing endpoint are marketing sends
+	_ = consent.OptOut(ctx, "255700000001")
+	_, err = client.SendMarketingTemplate(ctx, "255700000001", &MarketingTemplateRequest{
+		Name:         "spring_sale",
+		LanguageCode: "en_US",
+	})
+	if !errors.Is(err, ErrOptedOut) {
+		t.Errorf("SendMarketingTemplate() to an opted out recipient error = %v, want %v", err, ErrOptedOut)
+	}
+}
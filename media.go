@@ -29,7 +29,9 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
+	"os"
 	"path/filepath"
+	"strings"
 
 	whttp "github.com/SeamPay/whatsapp/http"
 )
@@ -74,22 +76,22 @@ type (
 
 // GetMediaInformation retrieve the media object by using its corresponding media ID.
 func (client *Client) GetMediaInformation(ctx context.Context, mediaID string) (*MediaInformation, error) {
+	cctx := client.context(ctx)
 	reqCtx := &whttp.RequestContext{
 		Name:       "get media",
-		BaseURL:    client.baseURL,
-		ApiVersion: client.apiVersion,
+		BaseURL:    cctx.baseURL,
+		ApiVersion: cctx.apiVersion,
 		Endpoints:  []string{mediaID},
 	}
 
 	params := &whttp.Request{
 		Context: reqCtx,
 		Method:  http.MethodGet,
-		Bearer:  client.accessToken,
+		Bearer:  cctx.accessToken,
 		Payload: nil,
 	}
 
-	media := new(MediaInformation)
-	err := whttp.Do(ctx, client.http, params, &media, client.hooks...)
+	media, err := whttp.SendTyped[MediaInformation](ctx, client.sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("get media: %w", err)
 	}
@@ -99,10 +101,11 @@ func (client *Client) GetMediaInformation(ctx context.Context, mediaID string) (
 
 // DeleteMedia delete the media by using its corresponding media ID.
 func (client *Client) DeleteMedia(ctx context.Context, mediaID string) (*DeleteMediaResponse, error) {
+	cctx := client.context(ctx)
 	reqCtx := &whttp.RequestContext{
 		Name:       "delete media",
-		BaseURL:    client.baseURL,
-		ApiVersion: client.apiVersion,
+		BaseURL:    cctx.baseURL,
+		ApiVersion: cctx.apiVersion,
 		Endpoints:  []string{mediaID},
 	}
 
@@ -110,12 +113,11 @@ func (client *Client) DeleteMedia(ctx context.Context, mediaID string) (*DeleteM
 		Context: reqCtx,
 		Method:  http.MethodDelete,
 		Headers: map[string]string{"Content-Type": "application/json"},
-		Bearer:  client.accessToken,
+		Bearer:  cctx.accessToken,
 		Payload: nil,
 	}
 
-	resp := new(DeleteMediaResponse)
-	err := whttp.Do(ctx, client.http, params, &resp, client.hooks...)
+	resp, err := whttp.SendTyped[DeleteMediaResponse](ctx, client.sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("delete media: %w", err)
 	}
@@ -123,31 +125,52 @@ func (client *Client) DeleteMedia(ctx context.Context, mediaID string) (*DeleteM
 	return resp, nil
 }
 
+// UploadMedia uploads the media of filename read from fr. When a Transcoder is set, see
+// WithTranscoder, audio that is not OGG/Opus is transcoded first. When image processing is
+// enabled, see WithImageProcessing, images are resized and re-encoded to fit the limits.
 func (client *Client) UploadMedia(ctx context.Context, mediaType MediaType, filename string,
 	fr io.Reader,
 ) (*UploadMediaResponse, error) {
-	payload, contentType, err := uploadMediaPayload(mediaType, filename, fr)
-	if err != nil {
-		return nil, err
+	var err error
+	if mediaType == MediaTypeAudio && client.transcoder != nil {
+		if filename, fr, err = client.voiceNote(ctx, filename, fr); err != nil {
+			return nil, fmt.Errorf("upload media: %w", err)
+		}
+	}
+	if mediaType == MediaTypeImage && client.images != nil {
+		if filename, fr, err = client.images.Process(ctx, filename, fr); err != nil {
+			return nil, fmt.Errorf("upload media: %w", err)
+		}
 	}
 
+	return client.uploadMedia(ctx, mediaType, filename, fr)
+}
+
+func (client *Client) uploadMedia(ctx context.Context, mediaType MediaType, filename string,
+	fr io.Reader,
+) (*UploadMediaResponse, error) {
+	// the body is streamed, closing it stops the writer when the request ends before the
+	// whole file has been read.
+	body, contentType, length := uploadMediaBody(ctx, mediaType, filename, fr)
+	defer body.Close()
+
+	cctx := client.context(ctx)
 	reqCtx := &whttp.RequestContext{
-		Name:       "upload media",
-		BaseURL:    client.baseURL,
-		ApiVersion: client.apiVersion,
-		Endpoints:  []string{client.phoneNumberID, "media"},
+		Name:       uploadMediaRequestName,
+		BaseURL:    cctx.baseURL,
+		ApiVersion: cctx.apiVersion,
+		Endpoints:  []string{cctx.phoneNumberID, "media"},
 	}
 
 	params := &whttp.Request{
 		Context: reqCtx,
 		Method:  http.MethodPost,
 		Headers: map[string]string{"Content-Type": contentType},
-		Bearer:  client.accessToken,
-		Payload: payload,
+		Bearer:  cctx.accessToken,
+		Payload: &whttp.Stream{Reader: body, ContentLength: length},
 	}
 
-	resp := new(UploadMediaResponse)
-	err = whttp.Do(ctx, client.http, params, &resp, client.hooks...)
+	resp, err := whttp.SendTyped[UploadMediaResponse](ctx, client.sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("upload media: %w", err)
 	}
@@ -172,6 +195,30 @@ type DownloadMediaResponse struct {
 // a new media URL and download it again. This will go on for an n retries. If doing so doesn't resolve the issue,
 // please try to renew the access token, then retry downloading the media.
 func (client *Client) DownloadMedia(ctx context.Context, mediaID string, retries int) (*DownloadMediaResponse, error) {
+	ctx, cancel := withTimeout(ctx, client.timeouts.Download)
+	defer cancel()
+
+	resp, err := client.openMedia(ctx, mediaID, retries)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	_, err = io.CopyN(&buf, resp.Body, MaxDocSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("media download: %w", err)
+	}
+
+	return &DownloadMediaResponse{
+		Headers: resp.Header,
+		Body:    &buf,
+	}, nil
+}
+
+// openMedia requests the media of mediaID and returns the response once its status is 200 OK, the
+// body is left for the caller to read and close. See DownloadMedia for the retries.
+func (client *Client) openMedia(ctx context.Context, mediaID string, retries int) (*http.Response, error) {
 	// create a for loop to retry the download if it fails with a 404 http status code.
 	for i := 0; i <= retries; i++ {
 		select {
@@ -179,16 +226,24 @@ func (client *Client) DownloadMedia(ctx context.Context, mediaID string, retries
 			return nil, fmt.Errorf("media download: %w", ctx.Err())
 		default:
 		}
-		media, err := client.GetMediaInformation(ctx, mediaID)
+		media, err := client.GetMediaInformation(whttp.WithAttempt(ctx, i+1), mediaID)
 		if err != nil {
 			return nil, err
 		}
 
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, media.URL, nil)
+		mediaURL, err := client.baseURLs.mediaDownloadURL(media.URL)
+		if err != nil {
+			return nil, fmt.Errorf("media download: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
 		if err != nil {
 			return nil, fmt.Errorf("media download: create a request: %w", err)
 		}
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", client.accessToken))
+		token, err := client.accessTokenFor(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("media download: %w", err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 
 		resp, err := client.http.Do(req)
 		if err != nil {
@@ -208,58 +263,150 @@ func (client *Client) DownloadMedia(ctx context.Context, mediaID string, retries
 			return nil, fmt.Errorf("%w: status %d", ErrMediaDownload, resp.StatusCode)
 		}
 
-		var buf bytes.Buffer
-		_, err = io.CopyN(&buf, resp.Body, MaxDocSize)
-		if err != nil && !errors.Is(err, io.EOF) {
-			_ = resp.Body.Close()
+		return resp, nil
+	}
 
-			return nil, fmt.Errorf("media download: %w", err)
-		}
+	return nil, fmt.Errorf("%w: retries exceeded", ErrMediaDownload)
+}
 
-		_ = resp.Body.Close()
+// uploadMediaBody returns the multipart body of an upload media request, its content type
+// and its length, zero when unknown.
+//
+// The body is written as it is read, so the file is streamed to the server instead of being
+// read in memory first. Reading from fr stops when ctx is done or when the body is closed.
+func uploadMediaBody(ctx context.Context, mediaType MediaType, filename string,
+	fr io.Reader,
+) (*io.PipeReader, string, int64) {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	length := uploadMediaLength(form.Boundary(), mediaType, filename, fr)
+	go func() {
+		_ = writer.CloseWithError(writeUploadMediaForm(ctx, form, mediaType, filename, fr))
+	}()
+
+	return reader, form.FormDataContentType(), length
+}
 
-		return &DownloadMediaResponse{
-			Headers: resp.Header,
-			Body:    &buf,
-		}, nil
+// uploadMediaLength returns the length of the multipart form of an upload media request,
+// zero when the size of fr is unknown. The form around the file does not depend on its
+// content, it is measured by writing it with an empty file.
+func uploadMediaLength(boundary string, mediaType MediaType, filename string, fr io.Reader) int64 {
+	size, ok := mediaSize(fr)
+	if !ok {
+		return 0
+	}
+	var counter byteCounter
+	form := multipart.NewWriter(&counter)
+	if err := form.SetBoundary(boundary); err != nil {
+		return 0
+	}
+	if err := writeUploadMediaForm(context.Background(), form, mediaType, filename, strings.NewReader("")); err != nil {
+		return 0
 	}
 
-	return nil, fmt.Errorf("%w: retries exceeded", ErrMediaDownload)
+	return int64(counter) + size
 }
 
-// uploadMediaPayload creates upload media request payload.
-// If nor error, payload content and request content type is returned.
-func uploadMediaPayload(mediaType MediaType, filename string, fr io.Reader) ([]byte, string, error) {
-	var payload bytes.Buffer
-	writer := multipart.NewWriter(&payload)
+// mediaSize returns the number of bytes left to read from fr when it is known: in-memory
+// readers and regular files.
+func mediaSize(fr io.Reader) (int64, bool) {
+	switch r := fr.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+
+		return info.Size() - offset, true
+	default:
+		return 0, false
+	}
+}
+
+// byteCounter counts the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
 
+	return len(p), nil
+}
+
+// writeUploadMediaForm writes the multipart form of an upload media request.
+func writeUploadMediaForm(ctx context.Context, writer *multipart.Writer, mediaType MediaType,
+	filename string, fr io.Reader,
+) error {
 	header := make(textproto.MIMEHeader)
 	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=file; filename="%s"`, filename))
 
-	contentType := mime.TypeByExtension(filepath.Ext(filename))
-	header.Set("Content-Type", contentType)
+	header.Set("Content-Type", mediaContentType(filename))
 
 	part, err := writer.CreatePart(header)
 	if err != nil {
-		return nil, "", fmt.Errorf("media upload: %w", err)
+		return fmt.Errorf("media upload: %w", err)
 	}
 
-	_, err = io.Copy(part, fr)
+	_, err = io.Copy(part, &contextReader{ctx: ctx, reader: fr})
 	if err != nil {
-		return nil, "", fmt.Errorf("media upload: %w", err)
+		return fmt.Errorf("media upload: %w", err)
 	}
 
 	err = writer.WriteField("type", string(mediaType))
 	if err != nil {
-		return nil, "", fmt.Errorf("media upload: %w", err)
+		return fmt.Errorf("media upload: %w", err)
 	}
 
 	err = writer.WriteField("messaging_product", "whatsapp")
 	if err != nil {
-		return nil, "", fmt.Errorf("media upload: %w", err)
+		return fmt.Errorf("media upload: %w", err)
+	}
+
+	if err = writer.Close(); err != nil {
+		return fmt.Errorf("media upload: %w", err)
 	}
 
-	_ = writer.Close()
+	return nil
+}
+
+// contextReader stops reading from reader once ctx is done.
+type contextReader struct {
+	ctx    context.Context //nolint:containedctx
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err //nolint:wrapcheck
+	}
+
+	return r.reader.Read(p) //nolint:wrapcheck
+}
+
+// mediaContentTypes are the content types of the media formats supported by WhatsApp that
+// are missing from the builtin table of the mime package.
+var mediaContentTypes = map[string]string{ //nolint:gochecknoglobals
+	".aac":  "audio/aac",
+	".amr":  "audio/amr",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".mp4":  "video/mp4",
+	".3gp":  "video/3gpp",
+}
+
+// mediaContentType returns the content type of filename from its extension.
+func mediaContentType(filename string) string {
+	ext := filepath.Ext(filename)
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
 
-	return payload.Bytes(), writer.FormDataContentType(), nil
+	return mediaContentTypes[strings.ToLower(ext)]
 }
@@ -21,23 +21,30 @@ package whatsapp
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestBuildPayloadForAudioMessage(t *testing.T) { //nolint:paralleltest
-	request := &SendMediaRequest{
-		Recipient: "2348123456789",
+	message := &MediaMessage{
 		Type:      "audio",
 		MediaID:   "1234567890",
 		MediaLink: "https://example.com/audio.mp3",
 		Caption:   "Audio caption",
 		Filename:  "audio.mp3",
 		Provider:  "whatsapp",
-
-		CacheOptions: nil,
 	}
 
-	payload, err := formatMediaPayload(request)
+	payload, err := formatMediaPayload("2348123456789", message)
 	if err != nil {
 		t.Errorf("formatMediaPayload() error = %v", err)
 	}
@@ -52,17 +59,15 @@ func TestBuildPayloadForAudioMessage(t *testing.T) { //nolint:paralleltest
 }
 
 func BenchmarkBuildPayloadForMediaMessage(b *testing.B) {
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
-		_, err := formatMediaPayload(&SendMediaRequest{
-			Recipient: "2348123456789",
+		_, err := formatMediaPayload("2348123456789", &MediaMessage{
 			Type:      "audio",
 			MediaID:   "1234567890",
 			MediaLink: "https://example.com/audio.mp3",
 			Caption:   "Audio caption",
 			Filename:  "audio.mp3",
 			Provider:  "whatsapp",
-
-			CacheOptions: nil,
 		})
 		if err != nil {
 			b.Errorf("formatMediaPayload() error = %v", err)
@@ -71,3 +76,161 @@ func BenchmarkBuildPayloadForMediaMessage(b *testing.B) {
 		}
 	}
 }
+
+// gatedReader returns first, then waits for gate to be closed before returning rest. It
+// fails when the whole body is read before gate is closed, i.e. when the body is buffered.
+type gatedReader struct {
+	first, rest []byte
+	gate        chan struct{}
+	reads       int
+}
+
+func (r *gatedReader) Read(p []byte) (int, error) {
+	r.reads++
+	switch r.reads {
+	case 1:
+		return copy(p, r.first), nil
+	case 2:
+		select {
+		case <-r.gate:
+		case <-time.After(5 * time.Second):
+			return 0, errors.New("the body was read in memory before being sent")
+		}
+
+		return copy(p, r.rest), nil
+	default:
+		return 0, io.EOF
+	}
+}
+
+func TestUploadMedia_Streams(t *testing.T) {
+	t.Parallel()
+
+	first := bytes.Repeat([]byte("a"), 16*1024)
+	source := &gatedReader{first: first, rest: []byte("end"), gate: make(chan struct{})}
+	var file []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Errorf("NextPart() error = %v", err)
+
+			return
+		}
+		// the beginning of the file arrives before the rest of it has been read
+		head := make([]byte, len(first))
+		if _, err = io.ReadFull(part, head); err != nil {
+			t.Errorf("read the file head: %v", err)
+
+			return
+		}
+		close(source.gate)
+		tail, _ := io.ReadAll(part)
+		file = append(head, tail...)
+		_, _ = w.Write([]byte(`{"id":"media_1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1"))
+	response, err := client.UploadMedia(context.Background(), MediaTypeDocument, "report.pdf", source)
+	if err != nil {
+		t.Fatalf("UploadMedia() error = %v", err)
+	}
+	if response.ID != "media_1" {
+		t.Errorf("UploadMedia() id = %q, want media_1", response.ID)
+	}
+	if want := string(first) + "end"; string(file) != want {
+		t.Errorf("server received %d bytes, want %d", len(file), len(want))
+	}
+}
+
+func TestUploadMedia_ContentLength(t *testing.T) {
+	t.Parallel()
+
+	file, err := os.CreateTemp(t.TempDir(), "photo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.WriteString("skipped-photo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.Seek(int64(len("skipped-")), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = file.Close() })
+
+	tests := []struct {
+		name   string
+		source io.Reader
+		want   bool
+	}{
+		{name: "in memory", source: bytes.NewReader([]byte("photo")), want: true},
+		{name: "file", source: file, want: true},
+		{name: "unknown size", source: io.MultiReader(strings.NewReader("photo")), want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var length int64
+			var read int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				length = r.ContentLength
+				body, _ := io.ReadAll(r.Body)
+				read = len(body)
+				_, _ = w.Write([]byte(`{"id":"media_1"}`))
+			}))
+			t.Cleanup(server.Close)
+
+			client := NewClient(WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1"))
+			if _, err := client.UploadMedia(context.Background(), MediaTypeImage, "photo.png", tt.source); err != nil {
+				t.Fatalf("UploadMedia() error = %v", err)
+			}
+			if !tt.want {
+				if length != -1 {
+					t.Errorf("Content-Length = %d, want it unset", length)
+				}
+
+				return
+			}
+			if length != int64(read) {
+				t.Errorf("Content-Length = %d, want %d", length, read)
+			}
+		})
+	}
+}
+
+// endlessReader never ends.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+
+	return len(p), nil
+}
+
+func TestUploadMedia_Cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.CopyN(io.Discard, r.Body, 256*1024)
+		cancel()
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1"))
+	_, err := client.UploadMedia(ctx, MediaTypeVideo, "movie.mp4", endlessReader{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("UploadMedia() error = %v, want %v", err, context.Canceled)
+	}
+	if !strings.Contains(err.Error(), "upload media") {
+		t.Errorf("UploadMedia() error = %v, want it to name the request", err)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// ErrInvalidMediaID is returned by FileMediaSink when the media ID cannot be used as a file name.
+var ErrInvalidMediaID = errors.New("invalid media id")
+
+type (
+	// MediaMeta describes a media received in a message. ID is the media ID, Type the type of
+	// the message and MimeType the content type of the media as announced by the webhook.
+	// Filename and Caption are set when the customer sent them.
+	MediaMeta struct {
+		ID            string
+		Type          MediaType
+		MimeType      string
+		Sha256        string
+		Filename      string
+		Caption       string
+		MessageID     string
+		From          string
+		PhoneNumberID string
+	}
+
+	// MediaSink stores the media downloaded by a MediaDownloader, for example on the local
+	// filesystem or in an object store. Put streams body to the storage, it is not buffered
+	// in memory. Implementations must be safe for concurrent use.
+	MediaSink interface {
+		Put(ctx context.Context, meta *MediaMeta, body io.Reader) error
+	}
+
+	// MediaSinkFunc is a function that implements MediaSink.
+	MediaSinkFunc func(ctx context.Context, meta *MediaMeta, body io.Reader) error
+
+	// MediaDownloaderConfig configures a MediaDownloader. Sink is required. Types are the
+	// media types that are downloaded, all of them when empty. Retries is the number of
+	// retries of the downloads, see Client.DownloadMedia.
+	MediaDownloaderConfig struct {
+		Sink    MediaSink
+		Types   []MediaType
+		Retries int
+	}
+
+	// MediaDownloader downloads the media of the incoming messages and streams them to a
+	// MediaSink:
+	//
+	//	downloader := whatsapp.NewMediaDownloader(client, &whatsapp.MediaDownloaderConfig{
+	//		Sink: whatsapp.NewFileMediaSink("/var/lib/attachments"),
+	//	})
+	//	listener.OnMessageReceived(downloader.OnMessageReceived)
+	MediaDownloader struct {
+		client  *Client
+		sink    MediaSink
+		types   map[MediaType]bool
+		retries int
+	}
+
+	// FileMediaSink writes the media to files named after their ID in a directory, with the
+	// extension of their file name or content type.
+	FileMediaSink struct {
+		dir string
+	}
+)
+
+// Put calls f(ctx, meta, body).
+func (f MediaSinkFunc) Put(ctx context.Context, meta *MediaMeta, body io.Reader) error {
+	return f(ctx, meta, body)
+}
+
+// NewMediaDownloader returns a MediaDownloader that downloads the media with client.
+func NewMediaDownloader(client *Client, config *MediaDownloaderConfig) *MediaDownloader {
+	downloader := &MediaDownloader{
+		client:  client,
+		sink:    config.Sink,
+		retries: config.Retries,
+	}
+	if len(config.Types) > 0 {
+		downloader.types = make(map[MediaType]bool, len(config.Types))
+		for _, mediaType := range config.Types {
+			downloader.types[mediaType] = true
+		}
+	}
+
+	return downloader
+}
+
+// Download downloads the media described by meta and streams it to the sink. The download is
+// bounded by the download timeout of the client, see Timeouts.
+func (downloader *MediaDownloader) Download(ctx context.Context, meta *MediaMeta) error {
+	ctx, cancel := withTimeout(ctx, downloader.client.timeouts.Download)
+	defer cancel()
+
+	resp, err := downloader.client.openMedia(ctx, meta.ID, downloader.retries)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if meta.MimeType == "" {
+		meta.MimeType = resp.Header.Get("Content-Type")
+	}
+	if err := downloader.sink.Put(ctx, meta, io.LimitReader(resp.Body, MaxDocSize)); err != nil {
+		return fmt.Errorf("media download: put %s: %w", meta.ID, err)
+	}
+
+	return nil
+}
+
+// OnMessageReceived is a webhooks.OnMessageReceivedHook that downloads the media of the audio,
+// video, image, document and sticker messages.
+func (downloader *MediaDownloader) OnMessageReceived(ctx context.Context, nctx *webhooks.NotificationContext,
+	message *webhooks.Message,
+) error {
+	media, ok := message.Media()
+	if !ok || media.ID == "" {
+		return nil
+	}
+	mediaType := MediaType(message.Type)
+	if downloader.types != nil && !downloader.types[mediaType] {
+		return nil
+	}
+
+	return downloader.Download(ctx, &MediaMeta{
+		ID:            media.ID,
+		Type:          mediaType,
+		MimeType:      media.MimeType,
+		Sha256:        media.Sha256,
+		Filename:      media.Filename,
+		Caption:       media.Caption,
+		MessageID:     message.ID,
+		From:          message.From,
+		PhoneNumberID: phoneNumberIDOf(nctx),
+	})
+}
+
+// NewFileMediaSink returns a FileMediaSink writing to dir, which is created when missing.
+func NewFileMediaSink(dir string) *FileMediaSink {
+	return &FileMediaSink{dir: dir}
+}
+
+// Path returns the path of the file of the media described by meta.
+func (sink *FileMediaSink) Path(meta *MediaMeta) string {
+	return filepath.Join(sink.dir, meta.ID+mediaExtension(meta))
+}
+
+// Put writes body to a temporary file that is renamed once complete, so that the files in the
+// directory are never partially written.
+func (sink *FileMediaSink) Put(ctx context.Context, meta *MediaMeta, body io.Reader) error {
+	if meta.ID == "" || filepath.Base(meta.ID) != meta.ID || meta.ID == ".." {
+		return fmt.Errorf("file media sink: %w: %q", ErrInvalidMediaID, meta.ID)
+	}
+	if err := os.MkdirAll(sink.dir, 0o750); err != nil { //nolint:gomnd
+		return fmt.Errorf("file media sink: %w", err)
+	}
+	file, err := os.CreateTemp(sink.dir, "."+meta.ID+"-*")
+	if err != nil {
+		return fmt.Errorf("file media sink: %w", err)
+	}
+	defer os.Remove(file.Name()) //nolint:errcheck // fails once renamed
+
+	_, err = io.Copy(file, &contextReader{ctx: ctx, reader: body})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("file media sink: %w", err)
+	}
+	if err := os.Rename(file.Name(), sink.Path(meta)); err != nil {
+		return fmt.Errorf("file media sink: %w", err)
+	}
+
+	return nil
+}
+
+// mediaExtensions are the preferred extensions of the content types of the media supported
+// by WhatsApp, the mime package lists several of them in no useful order.
+var mediaExtensions = map[string]string{ //nolint:gochecknoglobals
+	"audio/aac":       ".aac",
+	"audio/amr":       ".amr",
+	"audio/mp4":       ".m4a",
+	"audio/mpeg":      ".mp3",
+	"audio/ogg":       ".ogg",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"video/3gpp":      ".3gp",
+	"video/mp4":       ".mp4",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// mediaExtension returns the extension of the file name of the media, or the one of its
+// content type when it has no file name.
+func mediaExtension(meta *MediaMeta) string {
+	if ext := filepath.Ext(meta.Filename); ext != "" {
+		return ext
+	}
+	contentType, _, err := mime.ParseMediaType(meta.MimeType)
+	if err != nil {
+		return ""
+	}
+	if ext, ok := mediaExtensions[contentType]; ok {
+		return ext
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestMediaDownloader(t *testing.T) {
+	t.Parallel()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/cdn/"):
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write([]byte("content of " + strings.TrimPrefix(r.URL.Path, "/cdn/")))
+		default:
+			mediaID := filepath.Base(r.URL.Path)
+			_, _ = fmt.Fprintf(w, `{"id":%q,"url":%q}`, mediaID, server.URL+"/cdn/"+mediaID)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	sink := NewFileMediaSink(dir)
+	var puts []string
+	downloader := NewMediaDownloader(NewClient(WithBaseURL(server.URL)), &MediaDownloaderConfig{
+		Sink: MediaSinkFunc(func(ctx context.Context, meta *MediaMeta, body io.Reader) error {
+			puts = append(puts, meta.ID+" "+meta.MessageID+" "+meta.PhoneNumberID)
+
+			return sink.Put(ctx, meta, body)
+		}),
+		Types: []MediaType{MediaTypeImage, MediaTypeDocument},
+	})
+
+	nctx := &webhooks.NotificationContext{Metadata: &webhooks.Metadata{PhoneNumberID: "phone_1"}}
+	messages := []*webhooks.Message{
+		{ID: "wamid.1", Type: "image", Image: &models.MediaInfo{ID: "media_1", MimeType: "image/jpeg"}},
+		{ID: "wamid.2", Type: "document", Document: &models.MediaInfo{ID: "media_2", Filename: "report.PDF"}},
+		{ID: "wamid.3", Type: "audio", Audio: &models.MediaInfo{ID: "media_3", MimeType: "audio/ogg"}},
+		{ID: "wamid.4", Type: "text", Text: &webhooks.Text{Body: "hi"}},
+	}
+	for _, message := range messages {
+		if err := downloader.OnMessageReceived(context.TODO(), nctx, message); err != nil {
+			t.Fatalf("OnMessageReceived(%s) error = %v", message.ID, err)
+		}
+	}
+
+	want := []string{"media_1 wamid.1 phone_1", "media_2 wamid.2 phone_1"}
+	if strings.Join(puts, ",") != strings.Join(want, ",") {
+		t.Errorf("puts = %v, want %v", puts, want)
+	}
+	files := map[string]string{"media_1.jpg": "content of media_1", "media_2.PDF": "content of media_2"}
+	for file, content := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil || string(data) != content {
+			t.Errorf("file %s = %q, %v, want %q", file, data, err, content)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("directory has %d entries, want 2", len(entries))
+	}
+}
+
+func TestFileMediaSinkInvalidID(t *testing.T) {
+	t.Parallel()
+	sink := NewFileMediaSink(t.TempDir())
+	for _, id := range []string{"", "..", "../escape", "a/b"} {
+		err := sink.Put(context.TODO(), &MediaMeta{ID: id}, strings.NewReader("x"))
+		if !errors.Is(err, ErrInvalidMediaID) {
+			t.Errorf("Put(%q) error = %v, want %v", id, err, ErrInvalidMediaID)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package message builds models.Message values with a fluent API:
+//
+//	msg, err := message.New("255767001828").
+//		Text("Your order has shipped: https://example.com/track/123").
+//		PreviewURL(true).
+//		ReplyTo("wamid.HBgLMjU1NzY3MDAxODI4FQIAERgSQjdGMjU1RDQ0RjhGMTg2RTNBAA==").
+//		Build()
+//	if err != nil {
+//		return err
+//	}
+//	resp, err := client.SendMessage(ctx, msg)
+//
+// Build validates the message and returns an error wrapping ErrInvalidMessage for each
+// problem found, so that malformed messages are rejected before reaching the API.
+package message
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+// Limits enforced by the API on the content of the messages.
+const (
+	MaxTextLength    = 4096
+	MaxCaptionLength = 1024
+)
+
+// Message types, the values of models.Message.Type.
+const (
+	TypeText        = "text"
+	TypeImage       = "image"
+	TypeAudio       = "audio"
+	TypeVideo       = "video"
+	TypeDocument    = "document"
+	TypeSticker     = "sticker"
+	TypeLocation    = "location"
+	TypeContacts    = "contacts"
+	TypeReaction    = "reaction"
+	TypeInteractive = "interactive"
+	TypeTemplate    = "template"
+)
+
+// ErrInvalidMessage is wrapped by the errors returned by Build.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Builder builds a message. The zero value is not usable, create one with New. The
+// methods set the content of the message and return the builder so that calls can be
+// chained; setting a second kind of content is reported by Build.
+type Builder struct {
+	message *models.Message
+	media   *models.Media
+	errs    []error
+}
+
+// New starts a message to recipient, the phone number or WhatsApp ID of the user.
+func New(recipient string) *Builder {
+	return &Builder{message: models.NewMessage(recipient)}
+}
+
+// Text sets the body of a text message.
+func (b *Builder) Text(body string) *Builder {
+	if b.setType(TypeText) {
+		b.message.Text = &models.Text{Body: body}
+	}
+
+	return b
+}
+
+// PreviewURL renders a preview of the first URL in the body of a text message.
+func (b *Builder) PreviewURL(preview bool) *Builder {
+	if b.message.Text == nil {
+		b.invalid("preview url is only supported by text messages")
+
+		return b
+	}
+	b.message.Text.PreviewURL = preview
+
+	return b
+}
+
+// ReplyTo sends the message as a reply to the message with the given ID.
+func (b *Builder) ReplyTo(messageID string) *Builder {
+	b.message.Context = &models.Context{MessageID: messageID}
+
+	return b
+}
+
+// Image sets the image of an image message, media has either an ID or a Link.
+func (b *Builder) Image(media *models.Media) *Builder {
+	return b.setMedia(TypeImage, media, &b.message.Image)
+}
+
+// Audio sets the audio of an audio message.
+func (b *Builder) Audio(media *models.Media) *Builder {
+	return b.setMedia(TypeAudio, media, &b.message.Audio)
+}
+
+// Video sets the video of a video message.
+func (b *Builder) Video(media *models.Media) *Builder {
+	return b.setMedia(TypeVideo, media, &b.message.Video)
+}
+
+// Document sets the document of a document message.
+func (b *Builder) Document(media *models.Media) *Builder {
+	return b.setMedia(TypeDocument, media, &b.message.Document)
+}
+
+// Sticker sets the sticker of a sticker message.
+func (b *Builder) Sticker(media *models.Media) *Builder {
+	return b.setMedia(TypeSticker, media, &b.message.Sticker)
+}
+
+// Caption sets the caption of an image, video or document message.
+func (b *Builder) Caption(caption string) *Builder {
+	switch b.message.Type {
+	case TypeImage, TypeVideo, TypeDocument:
+		b.media.Caption = caption
+	default:
+		b.invalid("caption is only supported by image, video and document messages")
+	}
+
+	return b
+}
+
+// Filename sets the filename of a document message.
+func (b *Builder) Filename(filename string) *Builder {
+	if b.message.Type != TypeDocument {
+		b.invalid("filename is only supported by document messages")
+
+		return b
+	}
+	b.media.Filename = filename
+
+	return b
+}
+
+// Location sets the location of a location message.
+func (b *Builder) Location(latitude, longitude float64, name, address string) *Builder {
+	if b.setType(TypeLocation) {
+		b.message.Location = &models.Location{
+			Latitude:  latitude,
+			Longitude: longitude,
+			Name:      name,
+			Address:   address,
+		}
+	}
+
+	return b
+}
+
+// Contacts sets the contacts of a contacts message.
+func (b *Builder) Contacts(contacts ...*models.Contact) *Builder {
+	if b.setType(TypeContacts) {
+		b.message.Contacts = contacts
+	}
+
+	return b
+}
+
+// Reaction reacts with emoji to the message with the given ID, an empty emoji removes
+// a previous reaction.
+func (b *Builder) Reaction(messageID, emoji string) *Builder {
+	if b.setType(TypeReaction) {
+		b.message.Reaction = &models.Reaction{MessageID: messageID, Emoji: emoji}
+	}
+
+	return b
+}
+
+// Interactive sets the content of an interactive message.
+func (b *Builder) Interactive(interactive *models.Interactive) *Builder {
+	if b.setType(TypeInteractive) {
+		b.message.Interactive = interactive
+	}
+
+	return b
+}
+
+// Template sets the template of a template message.
+func (b *Builder) Template(template *models.Template) *Builder {
+	if b.setType(TypeTemplate) {
+		b.message.Template = template
+	}
+
+	return b
+}
+
+// Build validates the message and returns it. The returned error joins an error wrapping
+// ErrInvalidMessage per problem found.
+func (b *Builder) Build() (*models.Message, error) {
+	errs := append([]error(nil), b.errs...)
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidMessage, fmt.Sprintf(format, args...)))
+	}
+
+	message := b.message
+	if message.To == "" {
+		invalid("recipient is required")
+	}
+	if message.Context != nil && message.Context.MessageID == "" {
+		invalid("reply to requires a message id")
+	}
+
+	switch message.Type {
+	case "":
+		invalid("message has no content")
+	case TypeText:
+		if message.Text.Body == "" {
+			invalid("text body is required")
+		} else if n := utf8.RuneCountInString(message.Text.Body); n > MaxTextLength {
+			invalid("text body has %d characters, the maximum is %d", n, MaxTextLength)
+		}
+	case TypeImage, TypeAudio, TypeVideo, TypeDocument, TypeSticker:
+		switch {
+		case b.media == nil:
+			invalid("%s is required", message.Type)
+		case (b.media.ID == "") == (b.media.Link == ""):
+			invalid("%s requires either a media id or a link", message.Type)
+		case utf8.RuneCountInString(b.media.Caption) > MaxCaptionLength:
+			invalid("caption is longer than %d characters", MaxCaptionLength)
+		}
+	case TypeLocation:
+		location := message.Location
+		if location.Latitude < -90 || location.Latitude > 90 || location.Longitude < -180 || location.Longitude > 180 {
+			invalid("location %v,%v is out of range", location.Latitude, location.Longitude)
+		}
+	case TypeContacts:
+		if len(message.Contacts) == 0 {
+			invalid("contacts message requires at least one contact")
+		}
+	case TypeReaction:
+		if message.Reaction.MessageID == "" {
+			invalid("reaction requires the id of the message reacted to")
+		}
+	case TypeInteractive:
+		if message.Interactive == nil || message.Interactive.Type == "" {
+			invalid("interactive message requires a type")
+		}
+	case TypeTemplate:
+		if message.Template == nil || message.Template.Name == "" {
+			invalid("template name is required")
+		} else if message.Template.Language == nil || message.Template.Language.Code == "" {
+			invalid("template language is required")
+		} else if err := models.ValidateLanguageCode(message.Template.Language.Code); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrInvalidMessage, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// setType sets the type of the message, it reports false if the message already has a
+// different type.
+func (b *Builder) setType(messageType string) bool {
+	if b.message.Type != "" && b.message.Type != messageType {
+		b.invalid(fmt.Sprintf("cannot set %s content on a %s message", messageType, b.message.Type))
+
+		return false
+	}
+	b.message.Type = messageType
+
+	return true
+}
+
+func (b *Builder) setMedia(messageType string, media *models.Media, field **models.Media) *Builder {
+	if !b.setType(messageType) {
+		return b
+	}
+	b.media = &models.Media{}
+	if media != nil {
+		*b.media = *media
+	}
+	*field = b.media
+
+	return b
+}
+
+func (b *Builder) invalid(problem string) {
+	b.errs = append(b.errs, fmt.Errorf("%w: %s", ErrInvalidMessage, problem))
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package message
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		builder *Builder
+		check   func(t *testing.T, message *models.Message)
+		wantErr string
+	}{
+		{
+			name:    "text reply",
+			builder: New("255767001828").Text("https://example.com").PreviewURL(true).ReplyTo("wamid.1"),
+			check: func(t *testing.T, message *models.Message) {
+				t.Helper()
+				if message.Type != TypeText || !message.Text.PreviewURL || message.Context.MessageID != "wamid.1" {
+					t.Errorf("Build() = %+v", message)
+				}
+			},
+		},
+		{
+			name: "document",
+			builder: New("255767001828").
+				Document(&models.Media{Link: "https://example.com/invoice.pdf"}).
+				Caption("Invoice").
+				Filename("invoice.pdf"),
+			check: func(t *testing.T, message *models.Message) {
+				t.Helper()
+				want := models.Media{Link: "https://example.com/invoice.pdf", Caption: "Invoice", Filename: "invoice.pdf"}
+				if message.Type != TypeDocument || *message.Document != want {
+					t.Errorf("Build() = %+v", message)
+				}
+			},
+		},
+		{
+			name:    "location",
+			builder: New("255767001828").Location(-6.8, 39.28, "Office", "Dar es Salaam"),
+			check: func(t *testing.T, message *models.Message) {
+				t.Helper()
+				if message.Type != TypeLocation || message.Location.Name != "Office" {
+					t.Errorf("Build() = %+v", message)
+				}
+			},
+		},
+		{
+			name:    "no content",
+			builder: New("255767001828"),
+			wantErr: "no content",
+		},
+		{
+			name:    "two contents",
+			builder: New("255767001828").Text("hi").Image(&models.Media{ID: "1"}),
+			wantErr: "cannot set image content on a text message",
+		},
+		{
+			name:    "caption on audio",
+			builder: New("255767001828").Audio(&models.Media{ID: "1"}).Caption("song"),
+			wantErr: "caption is only supported",
+		},
+		{
+			name:    "media without source",
+			builder: New("255767001828").Image(nil),
+			wantErr: "either a media id or a link",
+		},
+		{
+			name:    "long text",
+			builder: New("255767001828").Text(strings.Repeat("a", MaxTextLength+1)),
+			wantErr: "maximum is 4096",
+		},
+		{
+			name:    "template without language",
+			builder: New("255767001828").Template(&models.Template{Name: "hello_world"}),
+			wantErr: "template language is required",
+		},
+		{
+			name: "template with a BCP-47 language tag",
+			builder: New("255767001828").Template(&models.Template{
+				Name:     "hello_world",
+				Language: &models.TemplateLanguage{Code: "en-GB"},
+			}),
+			wantErr: `use "en_GB"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			message, err := tt.builder.Build()
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidMessage) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Build() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			tt.check(t, message)
+		})
+	}
+}
@@ -24,7 +24,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
 	whttp "github.com/SeamPay/whatsapp/http"
 	"github.com/SeamPay/whatsapp/models"
@@ -47,6 +46,45 @@ type (
 	}
 )
 
+// RequestContext holds the location of the API and the credentials a message is sent with.
+// The Client builds it from its configuration, the deprecated package level send functions
+// from the fields of their request.
+type RequestContext struct {
+	BaseURL       string
+	AccessToken   string
+	PhoneNumberID string
+	ApiVersion    string //nolint: revive,stylecheck
+}
+
+func newRequestContext(baseURL, accessToken, phoneNumberID, apiVersion string) *RequestContext {
+	return &RequestContext{
+		BaseURL:       baseURL,
+		AccessToken:   accessToken,
+		PhoneNumberID: phoneNumberID,
+		ApiVersion:    apiVersion,
+	}
+}
+
+// messageRequest returns the request that sends payload to the messages endpoint.
+func (rctx *RequestContext) messageRequest(name string, payload any) *whttp.Request {
+	return &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       name,
+			BaseURL:    rctx.BaseURL,
+			ApiVersion: rctx.ApiVersion,
+			SenderID:   rctx.PhoneNumberID,
+			Endpoints:  []string{"messages"},
+		},
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Bearer:  rctx.AccessToken,
+		Payload: payload,
+	}
+}
+
+// SendTextRequest is a text message, its recipient and the credentials it is sent with.
+//
+// Deprecated: use Client.SendTextMessage with a TextMessage.
 type SendTextRequest struct {
 	BaseURL       string
 	AccessToken   string
@@ -58,47 +96,46 @@ type SendTextRequest struct {
 }
 
 // SendText sends a text message to the recipient.
+//
+// Deprecated: use Client.SendTextMessage, which takes the credentials from the client and applies its
+// middlewares.
 func SendText(ctx context.Context, client *http.Client, req *SendTextRequest,
 	hooks ...whttp.Hook,
+) (*ResponseMessage, error) {
+	rctx := newRequestContext(req.BaseURL, req.AccessToken, req.PhoneNumberID, req.ApiVersion)
+
+	message := &TextMessage{Message: req.Message, PreviewURL: req.PreviewURL}
+
+	return sendText(ctx, whttp.NewSender(client, hooks...), rctx, req.Recipient, message)
+}
+
+func sendText(ctx context.Context, sender whttp.Sender, rctx *RequestContext, recipient string,
+	message *TextMessage,
 ) (*ResponseMessage, error) {
 	text := &models.Message{
 		Product:       messagingProduct,
-		To:            req.Recipient,
+		To:            recipient,
 		RecipientType: individualRecipientType,
 		Type:          textMessageType,
 		Text: &models.Text{
-			PreviewURL: req.PreviewURL,
-			Body:       req.Message,
+			PreviewURL: message.PreviewURL,
+			Body:       message.Message,
 		},
 	}
 
-	reqCtx := &whttp.RequestContext{
-		Name:       "send text",
-		BaseURL:    req.BaseURL,
-		ApiVersion: req.ApiVersion,
-		SenderID:   req.PhoneNumberID,
-		Endpoints:  []string{"messages"},
-	}
+	params := rctx.messageRequest("send text", text)
 
-	params := &whttp.Request{
-		Context: reqCtx,
-		Method:  http.MethodPost,
-		Headers: map[string]string{"Content-Type": "application/json"},
-		Query:   nil,
-		Bearer:  req.AccessToken,
-		Form:    nil,
-		Payload: text,
-	}
-
-	var message ResponseMessage
-	err := whttp.Do(ctx, client, params, &message, hooks...)
+	response, err := whttp.SendTyped[ResponseMessage](ctx, sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("send text message: %w", err)
 	}
 
-	return &message, nil
+	return response, nil
 }
 
+// SendLocationRequest is a location message, its recipient and the credentials it is sent with.
+//
+// Deprecated: use Client.SendLocationMessage with a models.Location.
 type SendLocationRequest struct {
 	BaseURL       string
 	AccessToken   string
@@ -111,49 +148,56 @@ type SendLocationRequest struct {
 	Longitude     float64
 }
 
+// SendLocation sends a location message to the recipient.
+//
+// Deprecated: use Client.SendLocationMessage, which takes the credentials from the client and applies its
+// middlewares.
 func SendLocation(ctx context.Context, client *http.Client, req *SendLocationRequest,
 	hooks ...whttp.Hook,
+) (*ResponseMessage, error) {
+	rctx := newRequestContext(req.BaseURL, req.AccessToken, req.PhoneNumberID, req.ApiVersion)
+
+	message := &models.Location{
+		Name:      req.Name,
+		Address:   req.Address,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+	}
+
+	return sendLocation(ctx, whttp.NewSender(client, hooks...), rctx, req.Recipient, message)
+}
+
+func sendLocation(ctx context.Context, sender whttp.Sender, rctx *RequestContext, recipient string,
+	message *models.Location,
 ) (*ResponseMessage, error) {
 	location := &models.Message{
 		Product:       messagingProduct,
-		To:            req.Recipient,
+		To:            recipient,
 		RecipientType: individualRecipientType,
 		Type:          locationMessageType,
 		Location: &models.Location{
-			Name:      req.Name,
-			Address:   req.Address,
-			Latitude:  req.Latitude,
-			Longitude: req.Longitude,
+			Name:      message.Name,
+			Address:   message.Address,
+			Latitude:  message.Latitude,
+			Longitude: message.Longitude,
 		},
 		Contacts:    nil,
 		Interactive: nil,
 	}
 
-	reqCtx := &whttp.RequestContext{
-		Name:       "send location",
-		BaseURL:    req.BaseURL,
-		ApiVersion: req.ApiVersion,
-		SenderID:   req.PhoneNumberID,
-		Endpoints:  []string{"messages"},
-	}
+	params := rctx.messageRequest("send location", location)
 
-	params := &whttp.Request{
-		Context: reqCtx,
-		Method:  http.MethodPost,
-		Headers: map[string]string{"Content-Type": "application/json"},
-		Bearer:  req.AccessToken,
-		Payload: location,
-	}
-
-	var message ResponseMessage
-	err := whttp.Do(ctx, client, params, &message, hooks...)
+	response, err := whttp.SendTyped[ResponseMessage](ctx, sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("send location: %w", err)
 	}
 
-	return &message, nil
+	return response, nil
 }
 
+// ReactRequest is a reaction, its recipient and the credentials it is sent with.
+//
+// Deprecated: use Client.React with a ReactMessage.
 type ReactRequest struct {
 	BaseURL       string
 	AccessToken   string
@@ -205,43 +249,45 @@ Example response:
 	      "id": "wamid.ID",
 	    }]
 	}
+
+Deprecated: use Client.React, which takes the credentials from the client and applies its
+middlewares.
 */
 func React(ctx context.Context, client *http.Client, req *ReactRequest, hooks ...whttp.Hook) (*ResponseMessage, error) {
+	rctx := newRequestContext(req.BaseURL, req.AccessToken, req.PhoneNumberID, req.ApiVersion)
+
+	message := &ReactMessage{MessageID: req.MessageID, Emoji: req.Emoji}
+
+	return react(ctx, whttp.NewSender(client, hooks...), rctx, req.Recipient, message)
+}
+
+func react(ctx context.Context, sender whttp.Sender, rctx *RequestContext, recipient string,
+	message *ReactMessage,
+) (*ResponseMessage, error) {
 	reaction := &models.Message{
-		Product: messagingProduct,
-		To:      req.Recipient,
-		Type:    reactionMessageType,
+		Product:       messagingProduct,
+		To:            recipient,
+		RecipientType: individualRecipientType,
+		Type:          reactionMessageType,
 		Reaction: &models.Reaction{
-			MessageID: req.MessageID,
-			Emoji:     req.Emoji,
+			MessageID: message.MessageID,
+			Emoji:     message.Emoji,
 		},
 	}
 
-	reqCtx := &whttp.RequestContext{
-		Name:       "react",
-		BaseURL:    req.BaseURL,
-		ApiVersion: req.ApiVersion,
-		SenderID:   req.PhoneNumberID,
-		Endpoints:  []string{"messages"},
-	}
-
-	params := &whttp.Request{
-		Context: reqCtx,
-		Method:  http.MethodPost,
-		Headers: map[string]string{"Content-Type": "application/json"},
-		Bearer:  req.AccessToken,
-		Payload: reaction,
-	}
+	params := rctx.messageRequest("react", reaction)
 
-	var message ResponseMessage
-	err := whttp.Do(ctx, client, params, &message, hooks...)
+	response, err := whttp.SendTyped[ResponseMessage](ctx, sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("send reaction: %w", err)
 	}
 
-	return &message, nil
+	return response, nil
 }
 
+// SendContactRequest is a contacts message, its recipient and the credentials it is sent with.
+//
+// Deprecated: use Client.SendContacts with the contacts.
 type SendContactRequest struct {
 	BaseURL       string
 	AccessToken   string
@@ -251,45 +297,42 @@ type SendContactRequest struct {
 	Contacts      []*models.Contact
 }
 
+// SendContact sends a contacts message to the recipient.
+//
+// Deprecated: use Client.SendContacts, which takes the credentials from the client and applies its
+// middlewares.
 func SendContact(ctx context.Context, client *http.Client, req *SendContactRequest,
 	hooks ...whttp.Hook,
+) (*ResponseMessage, error) {
+	rctx := newRequestContext(req.BaseURL, req.AccessToken, req.PhoneNumberID, req.ApiVersion)
+
+	return sendContact(ctx, whttp.NewSender(client, hooks...), rctx, req.Recipient, req.Contacts)
+}
+
+func sendContact(ctx context.Context, sender whttp.Sender, rctx *RequestContext, recipient string,
+	contacts []*models.Contact,
 ) (*ResponseMessage, error) {
 	contact := &models.Message{
 		Product:       messagingProduct,
-		To:            req.Recipient,
+		To:            recipient,
 		RecipientType: individualRecipientType,
 		Type:          contactsMessageType,
-		Contacts:      req.Contacts,
-	}
-	reqCtx := &whttp.RequestContext{
-		Name:       "send contacts",
-		BaseURL:    req.BaseURL,
-		ApiVersion: req.ApiVersion,
-		SenderID:   req.PhoneNumberID,
-		Endpoints:  []string{"messages"},
-	}
-
-	params := &whttp.Request{
-		Context: reqCtx,
-		Method:  http.MethodPost,
-		Headers: map[string]string{"Content-Type": "application/json"},
-		Query:   nil,
-		Bearer:  req.AccessToken,
-		Form:    nil,
-		Payload: contact,
+		Contacts:      contacts,
 	}
+	params := rctx.messageRequest("send contacts", contact)
 
-	var message ResponseMessage
-
-	err := whttp.Do(ctx, client, params, &message, hooks...)
+	response, err := whttp.SendTyped[ResponseMessage](ctx, sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("send contact: %w", err)
 	}
 
-	return &message, nil
+	return response, nil
 }
 
-// ReplyRequest contains options for replying to a message.
+// ReplyRequest contains options for replying to a message and the credentials the reply is
+// sent with.
+//
+// Deprecated: use Client.Reply with a ReplyMessage.
 type ReplyRequest struct {
 	BaseURL       string
 	AccessToken   string
@@ -324,66 +367,67 @@ type ReplyRequest struct {
 //	    "body": "your-text-message-content"
 //	  }
 //	}'
+//
+// Deprecated: use Client.Reply, which takes the credentials from the client and applies its
+// middlewares.
 func Reply(ctx context.Context, client *http.Client, request *ReplyRequest,
 	hooks ...whttp.Hook,
 ) (*ResponseMessage, error) {
 	if request == nil {
 		return nil, fmt.Errorf("reply request is nil: %w", ErrBadRequestFormat)
 	}
-	payload, err := formatReplyPayload(request)
+	rctx := newRequestContext(request.BaseURL, request.AccessToken, request.PhoneNumberID, request.ApiVersion)
+	message := &ReplyMessage{Context: request.Context, Type: request.MessageType, Content: request.Content}
+
+	return reply(ctx, whttp.NewSender(client, hooks...), rctx, request.Recipient, message)
+}
+
+func reply(ctx context.Context, sender whttp.Sender, rctx *RequestContext, recipient string,
+	message *ReplyMessage,
+) (*ResponseMessage, error) {
+	if message == nil {
+		return nil, fmt.Errorf("reply request is nil: %w", ErrBadRequestFormat)
+	}
+	payload, err := formatReplyPayload(recipient, message)
 	if err != nil {
 		return nil, fmt.Errorf("reply: %w", err)
 	}
-	reqCtx := &whttp.RequestContext{
-		Name:       "reply",
-		BaseURL:    request.BaseURL,
-		ApiVersion: request.ApiVersion,
-		SenderID:   request.PhoneNumberID,
-		Endpoints:  []string{"messages"},
-	}
-
-	req := &whttp.Request{
-		Context: reqCtx,
-		Method:  http.MethodPost,
-		Headers: map[string]string{"Content-Type": "application/json"},
-		Query:   nil,
-		Bearer:  request.AccessToken,
-		Form:    nil,
-		Payload: payload,
-	}
+	req := rctx.messageRequest("reply", payload)
 
-	var message ResponseMessage
-	err = whttp.Do(ctx, client, req, &message, hooks...)
+	response, err := whttp.SendTyped[ResponseMessage](ctx, sender, req)
 	if err != nil {
 		return nil, fmt.Errorf("reply: %w", err)
 	}
 
-	return &message, nil
+	return response, nil
 }
 
-// formatReplyPayload builds the payload for a reply. It accepts ReplyRequest and returns a byte array
-// and an error. This function is used internally by Reply.
-func formatReplyPayload(options *ReplyRequest) ([]byte, error) {
-	contentByte, err := json.Marshal(options.Content)
+// formatReplyPayload builds the payload of a reply to recipient. It accepts a ReplyMessage and
+// returns a byte array and an error. This function is used internally by Reply.
+func formatReplyPayload(recipient string, message *ReplyMessage) ([]byte, error) {
+	content, err := json.Marshal(message.Content)
 	if err != nil {
 		return nil, fmt.Errorf("format reply payload: %w", err)
 	}
-	payloadBuilder := strings.Builder{}
-	payloadBuilder.WriteString(`{"messaging_product":"whatsapp","context":{"message_id":"`)
-	payloadBuilder.WriteString(options.Context)
-	payloadBuilder.WriteString(`"},"to":"`)
-	payloadBuilder.WriteString(options.Recipient)
-	payloadBuilder.WriteString(`","type":"`)
-	payloadBuilder.WriteString(string(options.MessageType))
-	payloadBuilder.WriteString(`","`)
-	payloadBuilder.WriteString(string(options.MessageType))
-	payloadBuilder.WriteString(`":`)
-	payloadBuilder.Write(contentByte)
-	payloadBuilder.WriteString(`}`)
-
-	return []byte(payloadBuilder.String()), nil
+	payload := make([]byte, 0, replyPayloadSize+len(message.Context)+len(recipient)+
+		2*len(message.Type)+len(content))
+	payload = append(payload, `{"messaging_product":"whatsapp","context":{"message_id":`...)
+	payload = models.AppendJSONString(payload, message.Context)
+	payload = append(payload, `},"to":`...)
+	payload = models.AppendJSONString(payload, recipient)
+	payload = append(payload, `,"type":`...)
+	payload = models.AppendJSONString(payload, string(message.Type))
+	payload = append(payload, ',')
+	payload = models.AppendJSONString(payload, string(message.Type))
+	payload = append(payload, ':')
+	payload = append(payload, content...)
+
+	return append(payload, '}'), nil
 }
 
+// SendTemplateRequest is a template message, its recipient and the credentials it is sent with.
+//
+// Deprecated: use Client.SendTemplate with a Template.
 type SendTemplateRequest struct {
 	BaseURL                string
 	AccessToken            string
@@ -396,46 +440,50 @@ type SendTemplateRequest struct {
 	TemplateComponents     []*models.TemplateComponent
 }
 
+// SendTemplate sends a template message to the recipient.
+//
+// Deprecated: use Client.SendTemplate, which takes the credentials from the client and applies its
+// middlewares.
 func SendTemplate(ctx context.Context, client *http.Client, req *SendTemplateRequest,
 	hooks ...whttp.Hook,
+) (*ResponseMessage, error) {
+	rctx := newRequestContext(req.BaseURL, req.AccessToken, req.PhoneNumberID, req.ApiVersion)
+
+	message := &Template{
+		LanguageCode:   req.TemplateLanguageCode,
+		LanguagePolicy: req.TemplateLanguagePolicy,
+		Name:           req.TemplateName,
+		Components:     req.TemplateComponents,
+	}
+
+	return sendTemplate(ctx, whttp.NewSender(client, hooks...), rctx, req.Recipient, message)
+}
+
+func sendTemplate(ctx context.Context, sender whttp.Sender, rctx *RequestContext, recipient string,
+	message *Template,
 ) (*ResponseMessage, error) {
 	template := &models.Message{
 		Product:       messagingProduct,
-		To:            req.Recipient,
+		To:            recipient,
 		RecipientType: individualRecipientType,
 		Type:          templateMessageType,
 		Template: &models.Template{
 			Language: &models.TemplateLanguage{
-				Code:   req.TemplateLanguageCode,
-				Policy: req.TemplateLanguagePolicy,
+				Code:   message.LanguageCode,
+				Policy: message.LanguagePolicy,
 			},
-			Name:       req.TemplateName,
-			Components: req.TemplateComponents,
-		},
-	}
-	reqCtx := &whttp.RequestContext{
-		Name:       "send template",
-		BaseURL:    req.BaseURL,
-		ApiVersion: req.ApiVersion,
-		SenderID:   req.PhoneNumberID,
-		Endpoints:  []string{"messages"},
-	}
-	params := &whttp.Request{
-		Method:  http.MethodPost,
-		Payload: template,
-		Context: reqCtx,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
+			Name:       message.Name,
+			Components: message.Components,
 		},
-		Bearer: req.AccessToken,
 	}
-	var message ResponseMessage
-	err := whttp.Do(ctx, client, params, &message, hooks...)
+	params := rctx.messageRequest("send template", template)
+
+	response, err := whttp.SendTyped[ResponseMessage](ctx, sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("send template: %w", err)
 	}
 
-	return &message, nil
+	return response, nil
 }
 
 /*
@@ -489,6 +537,9 @@ type CacheOptions struct {
 	Expires      int64  `json:"expires,omitempty"`
 }
 
+// SendMediaRequest is a media message, its recipient and the credentials it is sent with.
+//
+// Deprecated: use Client.SendMedia with a MediaMessage.
 type SendMediaRequest struct {
 	BaseURL       string
 	AccessToken   string
@@ -568,6 +619,9 @@ downloaded successfully.
 	      "id": "wamid.ID",
 	    }]
 	}
+
+Deprecated: use Client.SendMedia, which takes the credentials from the client and applies its
+middlewares.
 */
 func SendMedia(ctx context.Context, client *http.Client, req *SendMediaRequest,
 	hooks ...whttp.Hook,
@@ -575,79 +629,84 @@ func SendMedia(ctx context.Context, client *http.Client, req *SendMediaRequest,
 	if req == nil {
 		return nil, fmt.Errorf("request is nil: %w", ErrBadRequestFormat)
 	}
-
-	payload, err := formatMediaPayload(req)
-	if err != nil {
-		return nil, err
+	rctx := newRequestContext(req.BaseURL, req.AccessToken, req.PhoneNumberID, req.ApiVersion)
+	message := &MediaMessage{
+		Type:      req.Type,
+		MediaID:   req.MediaID,
+		MediaLink: req.MediaLink,
+		Caption:   req.Caption,
+		Filename:  req.Filename,
+		Provider:  req.Provider,
 	}
 
-	reqCtx := &whttp.RequestContext{
-		Name:       "send media",
-		BaseURL:    req.BaseURL,
-		ApiVersion: req.ApiVersion,
-		SenderID:   req.PhoneNumberID,
-		Endpoints:  []string{"messages"},
+	return sendMedia(ctx, whttp.NewSender(client, hooks...), rctx, req.Recipient, message, req.CacheOptions)
+}
+
+func sendMedia(ctx context.Context, sender whttp.Sender, rctx *RequestContext, recipient string,
+	message *MediaMessage, cacheOptions *CacheOptions,
+) (*ResponseMessage, error) {
+	if message == nil {
+		return nil, fmt.Errorf("request is nil: %w", ErrBadRequestFormat)
 	}
 
-	params := &whttp.Request{
-		Context: reqCtx,
-		Method:  http.MethodPost,
-		Bearer:  req.AccessToken,
-		Headers: map[string]string{"Content-Type": "application/json"},
-		Payload: payload,
+	payload, err := formatMediaPayload(recipient, message)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.CacheOptions != nil {
-		if req.CacheOptions.CacheControl != "" {
-			params.Headers["Cache-Control"] = req.CacheOptions.CacheControl
-		} else if req.CacheOptions.Expires > 0 {
-			params.Headers["Cache-Control"] = fmt.Sprintf("max-age=%d", req.CacheOptions.Expires)
+	params := rctx.messageRequest("send media", payload)
+
+	if cacheOptions != nil {
+		if cacheOptions.CacheControl != "" {
+			params.Headers["Cache-Control"] = cacheOptions.CacheControl
+		} else if cacheOptions.Expires > 0 {
+			params.Headers["Cache-Control"] = fmt.Sprintf("max-age=%d", cacheOptions.Expires)
 		}
-		if req.CacheOptions.LastModified != "" {
-			params.Headers["Last-Modified"] = req.CacheOptions.LastModified
+		if cacheOptions.LastModified != "" {
+			params.Headers["Last-Modified"] = cacheOptions.LastModified
 		}
-		if req.CacheOptions.ETag != "" {
-			params.Headers["ETag"] = req.CacheOptions.ETag
+		if cacheOptions.ETag != "" {
+			params.Headers["ETag"] = cacheOptions.ETag
 		}
 	}
 
-	var message ResponseMessage
-
-	err = whttp.Do(ctx, client, params, &message, hooks...)
+	response, err := whttp.SendTyped[ResponseMessage](ctx, sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("send media: %w", err)
 	}
 
-	return &message, nil
+	return response, nil
 }
 
-// formatMediaPayload builds the payload for a media message. It accepts SendMediaOptions
-// and returns a byte array and an error. This function is used internally by SendMedia.
-// if neither ID nor Link is specified, it returns an error.
-func formatMediaPayload(options *SendMediaRequest) ([]byte, error) {
-	media := &models.Media{
-		ID:       options.MediaID,
-		Link:     options.MediaLink,
-		Caption:  options.Caption,
-		Filename: options.Filename,
-		Provider: options.Provider,
-	}
-	mediaJSON, err := json.Marshal(media)
-	if err != nil {
-		return nil, fmt.Errorf("format media payload: %w", err)
-	}
-	recipient := options.Recipient
-	mediaType := string(options.Type)
-	payloadBuilder := strings.Builder{}
-	payloadBuilder.WriteString(`{"messaging_product":"whatsapp","recipient_type":"individual","to":"`)
-	payloadBuilder.WriteString(recipient)
-	payloadBuilder.WriteString(`","type": "`)
-	payloadBuilder.WriteString(mediaType)
-	payloadBuilder.WriteString(`","`)
-	payloadBuilder.WriteString(mediaType)
-	payloadBuilder.WriteString(`":`)
-	payloadBuilder.Write(mediaJSON)
-	payloadBuilder.WriteString(`}`)
-
-	return []byte(payloadBuilder.String()), nil
+// formatMediaPayload builds the payload of a media message to recipient. It accepts a
+// MediaMessage and returns a byte array and an error. This function is used internally by
+// SendMedia.
+func formatMediaPayload(recipient string, message *MediaMessage) ([]byte, error) {
+	media := models.Media{
+		ID:       message.MediaID,
+		Link:     message.MediaLink,
+		Caption:  message.Caption,
+		Filename: message.Filename,
+		Provider: message.Provider,
+	}
+	mediaType := string(message.Type)
+	payload := make([]byte, 0, mediaPayloadSize+len(recipient)+2*len(mediaType)+len(media.ID)+
+		len(media.Link)+len(media.Caption)+len(media.Filename)+len(media.Provider))
+	payload = append(payload, `{"messaging_product":"whatsapp","recipient_type":"individual","to":`...)
+	payload = models.AppendJSONString(payload, recipient)
+	payload = append(payload, `,"type": `...)
+	payload = models.AppendJSONString(payload, mediaType)
+	payload = append(payload, ',')
+	payload = models.AppendJSONString(payload, mediaType)
+	payload = append(payload, ':')
+	payload = media.AppendJSON(payload)
+
+	return append(payload, '}'), nil
 }
+
+// replyPayloadSize and mediaPayloadSize are the sizes of the fixed parts of the reply and
+// media payloads, they are used to allocate the payloads at once.
+const (
+	replyPayloadSize = 96
+	mediaPayloadSize = 160
+)
@@ -18,3 +18,108 @@
  */
 
 package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// benchmarkTransport answers every request without a network round trip, so that the
+// benchmarks measure the client side of the send path only.
+type benchmarkTransport struct{}
+
+func (benchmarkTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		_, _ = io.Copy(io.Discard, request.Body)
+		_ = request.Body.Close()
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body: io.NopCloser(strings.NewReader(`{"messaging_product":"whatsapp",` +
+			`"contacts":[{"input":"255700000001","wa_id":"255700000001"}],"messages":[{"id":"wamid.OUT"}]}`)),
+		Request: request,
+	}, nil
+}
+
+func BenchmarkClient_SendText(b *testing.B) {
+	client := NewClient(
+		WithHTTPClient(&http.Client{Transport: benchmarkTransport{}}),
+		WithPhoneNumberID("phone_1"),
+		WithAccessToken("token"),
+	)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.SendText(ctx, "255700000001", "Your verification code is 123456"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReactRecipientType(t *testing.T) {
+	t.Parallel()
+	var payload map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.2"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithAccessToken("token"), WithPhoneNumberID("phone_1"))
+	reaction := &ReactMessage{MessageID: "wamid.1", Emoji: "👍"}
+	if _, err := client.React(context.TODO(), "255700000000", reaction); err != nil {
+		t.Fatalf("React() error = %v", err)
+	}
+	if payload["recipient_type"] != "individual" || payload["type"] != "reaction" {
+		t.Errorf("payload = %v, want an individual reaction", payload)
+	}
+}
+
+func TestDeprecatedSendFunctions(t *testing.T) {
+	t.Parallel()
+	var path, auth string
+	var payload struct {
+		To   string `json:"to"`
+		Text struct {
+			Body string `json:"body"`
+		} `json:"text"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.2"}]}`))
+	}))
+	defer server.Close()
+
+	response, err := SendText(context.TODO(), server.Client(), &SendTextRequest{
+		BaseURL:       server.URL,
+		AccessToken:   "token",
+		PhoneNumberID: "phone_1",
+		ApiVersion:    "v19.0",
+		Recipient:     "255700000000",
+		Message:       "hello",
+	})
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if path != "/v19.0/phone_1/messages" || auth != "Bearer token" || response.Messages[0].ID != "wamid.2" {
+		t.Errorf("SendText() sent to %s with %q", path, auth)
+	}
+	if payload.To != "255700000000" || payload.Text.Body != "hello" {
+		t.Errorf("SendText() sent %+v, want the text of the request", payload)
+	}
+	if _, err = Reply(context.TODO(), server.Client(), nil); !errors.Is(err, ErrBadRequestFormat) {
+		t.Errorf("Reply(nil) error = %v, want %v", err, ErrBadRequestFormat)
+	}
+	if _, err = SendMedia(context.TODO(), server.Client(), nil); !errors.Is(err, ErrBadRequestFormat) {
+		t.Errorf("SendMedia(nil) error = %v, want %v", err, ErrBadRequestFormat)
+	}
+}
@@ -0,0 +1,360 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// DefaultMetadataTTL is how long the metadata is cached when MetadataCacheConfig.TTL is not set.
+const DefaultMetadataTTL = 5 * time.Minute
+
+const (
+	// TemplateStatusApproved is the status of the templates that can be sent.
+	TemplateStatusApproved = "APPROVED"
+
+	// TemplateStatusPaused is the status of the templates paused because of their low quality,
+	// they cannot be sent until they are unpaused.
+	TemplateStatusPaused = "PAUSED"
+)
+
+var (
+	ErrBusinessProfileNotFound = errors.New("business profile not found")
+	ErrTemplateNotFound        = errors.New("template not found")
+	ErrTemplateNotApproved     = errors.New("template not approved")
+
+	// ErrTemplateLanguageUnavailable is returned along with ErrTemplateNotFound when the
+	// template exists but not in the requested language.
+	ErrTemplateLanguageUnavailable = errors.New("template language unavailable")
+
+	// ErrTemplatePaused is returned along with ErrTemplateNotApproved when the template is
+	// paused.
+	ErrTemplatePaused = errors.New("template paused")
+)
+
+// businessProfileFields are the fields of the business profile requested by GetBusinessProfile.
+const businessProfileFields = "about,address,description,email,profile_picture_url,websites,vertical"
+
+type (
+	// BusinessProfile is the WhatsApp Business profile of a phone number.
+	BusinessProfile struct {
+		About             string   `json:"about,omitempty"`
+		Address           string   `json:"address,omitempty"`
+		Description       string   `json:"description,omitempty"`
+		Email             string   `json:"email,omitempty"`
+		MessagingProduct  string   `json:"messaging_product"`
+		ProfilePictureURL string   `json:"profile_picture_url,omitempty"`
+		Vertical          string   `json:"vertical,omitempty"`
+		Websites          []string `json:"websites,omitempty"`
+	}
+
+	// MetadataCacheConfig configures a MetadataCache. TTL is how long the metadata is cached,
+	// DefaultMetadataTTL by default. ProfileTTL, PhoneNumberTTL and TemplatesTTL override it
+	// for the business profile, the phone number and the templates.
+	MetadataCacheConfig struct {
+		Clock          clock.Clock
+		TTL            time.Duration
+		ProfileTTL     time.Duration
+		PhoneNumberTTL time.Duration
+		TemplatesTTL   time.Duration
+	}
+
+	// MetadataCache caches in memory the business profile, the phone number information and
+	// the templates fetched with a Client, so that hot paths like validating the template of
+	// every message sent do not call the management API each time:
+	//
+	//	metadata := whatsapp.NewMetadataCache(client, &whatsapp.MetadataCacheConfig{TTL: time.Minute})
+	//	if err := metadata.ValidateTemplate(ctx, "order_shipped", "en_US"); err != nil {
+	//		return err
+	//	}
+	//
+	// The metadata is cached per phone number and WhatsApp Business Account, which can be
+	// overridden with WithOverrides. Errors are not cached and concurrent calls for the same
+	// metadata share a single request. The Invalidate methods drop the cached metadata, e.g.
+	// after a template was created or the business profile updated.
+	MetadataCache struct {
+		client   *Client
+		clock    clock.Clock
+		ttls     map[metadataKind]time.Duration
+		mu       sync.Mutex
+		entries  map[metadataKey]*metadataEntry
+		inflight map[metadataKey]*metadataCall
+	}
+
+	metadataKind int
+
+	metadataKey struct {
+		kind    metadataKind
+		account string
+	}
+
+	metadataEntry struct {
+		value     any
+		expiresAt time.Time
+	}
+
+	// metadataCall is a fetch in progress, it is stale when the metadata was invalidated
+	// while it was running, its result is then not cached.
+	metadataCall struct {
+		done  chan struct{}
+		value any
+		err   error
+		stale bool
+	}
+)
+
+const (
+	metadataProfile metadataKind = iota
+	metadataPhoneNumber
+	metadataTemplates
+)
+
+// GetBusinessProfile returns the business profile of the phone number.
+func (client *Client) GetBusinessProfile(ctx context.Context) (*BusinessProfile, error) {
+	cctx := client.context(ctx)
+	request := &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       "get business profile",
+			BaseURL:    cctx.baseURL,
+			ApiVersion: cctx.apiVersion,
+			SenderID:   cctx.phoneNumberID,
+			Endpoints:  []string{"whatsapp_business_profile"},
+		},
+		Method: http.MethodGet,
+		Query:  map[string]string{"fields": businessProfileFields},
+		Bearer: cctx.accessToken,
+	}
+
+	response, err := whttp.SendTyped[struct {
+		Data []*BusinessProfile `json:"data"`
+	}](ctx, client.sender, request)
+	if err != nil {
+		return nil, fmt.Errorf("get business profile: %w", err)
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("get business profile: %w", ErrBusinessProfileNotFound)
+	}
+
+	return response.Data[0], nil
+}
+
+// NewMetadataCache returns a MetadataCache that fetches the metadata with client. config
+// may be nil.
+func NewMetadataCache(client *Client, config *MetadataCacheConfig) *MetadataCache {
+	if config == nil {
+		config = &MetadataCacheConfig{}
+	}
+	ttl := config.TTL
+	if ttl <= 0 {
+		ttl = DefaultMetadataTTL
+	}
+	or := func(d time.Duration) time.Duration {
+		if d > 0 {
+			return d
+		}
+
+		return ttl
+	}
+
+	return &MetadataCache{
+		client: client,
+		clock:  clock.OrSystem(config.Clock),
+		ttls: map[metadataKind]time.Duration{
+			metadataProfile:     or(config.ProfileTTL),
+			metadataPhoneNumber: or(config.PhoneNumberTTL),
+			metadataTemplates:   or(config.TemplatesTTL),
+		},
+		entries:  make(map[metadataKey]*metadataEntry),
+		inflight: make(map[metadataKey]*metadataCall),
+	}
+}
+
+// BusinessProfile returns the business profile of the phone number, see
+// Client.GetBusinessProfile. The returned profile must not be modified.
+func (cache *MetadataCache) BusinessProfile(ctx context.Context) (*BusinessProfile, error) {
+	value, err := cache.get(ctx, cache.key(ctx, metadataProfile), func() (any, error) {
+		return cache.client.GetBusinessProfile(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*BusinessProfile), nil //nolint:forcetypeassert
+}
+
+// PhoneNumber returns the information of the phone number, see Client.PhoneNumberByID. The
+// returned phone number must not be modified.
+func (cache *MetadataCache) PhoneNumber(ctx context.Context) (*PhoneNumber, error) {
+	value, err := cache.get(ctx, cache.key(ctx, metadataPhoneNumber), func() (any, error) {
+		return cache.client.PhoneNumberByID(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*PhoneNumber), nil //nolint:forcetypeassert
+}
+
+// Templates returns the templates of the WhatsApp Business Account, see
+// Client.ListTemplates. The returned templates must not be modified.
+func (cache *MetadataCache) Templates(ctx context.Context) ([]*TemplateInformation, error) {
+	value, err := cache.get(ctx, cache.key(ctx, metadataTemplates), func() (any, error) {
+		return cache.client.ListAllTemplates(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]*TemplateInformation), nil //nolint:forcetypeassert
+}
+
+// Template returns the template name in language, ErrTemplateNotFound when there is none.
+// The error also wraps ErrTemplateLanguageUnavailable and lists the available languages
+// when the template exists in other languages.
+func (cache *MetadataCache) Template(ctx context.Context, name, language string) (*TemplateInformation, error) {
+	templates, err := cache.Templates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var languages []string
+	for _, template := range templates {
+		if template.Name != name {
+			continue
+		}
+		if template.Language == language {
+			return template, nil
+		}
+		languages = append(languages, template.Language)
+	}
+	if len(languages) > 0 {
+		return nil, fmt.Errorf("%w: %w: %s (%s), available in %s", ErrTemplateNotFound,
+			ErrTemplateLanguageUnavailable, name, language, strings.Join(languages, ", "))
+	}
+
+	return nil, fmt.Errorf("%w: %s (%s)", ErrTemplateNotFound, name, language)
+}
+
+// ValidateTemplate checks that the template name exists in language and is approved. Paused
+// templates fail with both ErrTemplateNotApproved and ErrTemplatePaused.
+func (cache *MetadataCache) ValidateTemplate(ctx context.Context, name, language string) error {
+	template, err := cache.Template(ctx, name, language)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(template.Status, TemplateStatusPaused) {
+		return fmt.Errorf("%w: %w: %s (%s)", ErrTemplateNotApproved, ErrTemplatePaused, name, language)
+	}
+	if !strings.EqualFold(template.Status, TemplateStatusApproved) {
+		return fmt.Errorf("%w: %s (%s) is %s", ErrTemplateNotApproved, name, language, template.Status)
+	}
+
+	return nil
+}
+
+// InvalidateBusinessProfile drops the cached business profile of the phone number.
+func (cache *MetadataCache) InvalidateBusinessProfile(ctx context.Context) {
+	cache.invalidate(cache.key(ctx, metadataProfile))
+}
+
+// InvalidatePhoneNumber drops the cached information of the phone number.
+func (cache *MetadataCache) InvalidatePhoneNumber(ctx context.Context) {
+	cache.invalidate(cache.key(ctx, metadataPhoneNumber))
+}
+
+// InvalidateTemplates drops the cached templates of the WhatsApp Business Account.
+func (cache *MetadataCache) InvalidateTemplates(ctx context.Context) {
+	cache.invalidate(cache.key(ctx, metadataTemplates))
+}
+
+// Purge drops all the cached metadata.
+func (cache *MetadataCache) Purge() {
+	cache.mu.Lock()
+	cache.entries = make(map[metadataKey]*metadataEntry)
+	for _, call := range cache.inflight {
+		call.stale = true
+	}
+	cache.mu.Unlock()
+}
+
+// key returns the key of the metadata of kind for the account the calls made with ctx use.
+func (cache *MetadataCache) key(ctx context.Context, kind metadataKind) metadataKey {
+	cctx := cache.client.context(ctx)
+	account := cctx.phoneNumberID
+	if kind == metadataTemplates {
+		account = cctx.businessAccountID
+	}
+
+	return metadataKey{kind: kind, account: account}
+}
+
+func (cache *MetadataCache) invalidate(key metadataKey) {
+	cache.mu.Lock()
+	delete(cache.entries, key)
+	if call, ok := cache.inflight[key]; ok {
+		call.stale = true
+	}
+	cache.mu.Unlock()
+}
+
+// get returns the cached value of key, calling fetch when it is missing or expired.
+func (cache *MetadataCache) get(ctx context.Context, key metadataKey, fetch func() (any, error)) (any, error) {
+	cache.mu.Lock()
+	if entry, ok := cache.entries[key]; ok && cache.clock.Now().Before(entry.expiresAt) {
+		cache.mu.Unlock()
+
+		return entry.value, nil
+	}
+	if call, ok := cache.inflight[key]; ok {
+		cache.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return nil, fmt.Errorf("metadata cache: %w", ctx.Err())
+		}
+	}
+	call := &metadataCall{done: make(chan struct{})}
+	cache.inflight[key] = call
+	cache.mu.Unlock()
+
+	call.value, call.err = fetch()
+
+	cache.mu.Lock()
+	delete(cache.inflight, key)
+	if call.err == nil && !call.stale {
+		cache.entries[key] = &metadataEntry{
+			value:     call.value,
+			expiresAt: cache.clock.Now().Add(cache.ttls[key.kind]),
+		}
+	}
+	cache.mu.Unlock()
+	close(call.done)
+
+	return call.value, call.err
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+func TestMetadataCache(t *testing.T) {
+	t.Parallel()
+	var (
+		mu   sync.Mutex
+		hits = map[string]int{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/whatsapp_business_profile"):
+			_, _ = w.Write([]byte(`{"data":[{"about":"Open 24/7","messaging_product":"whatsapp"}]}`))
+		case strings.HasSuffix(r.URL.Path, "/message_templates"):
+			_, _ = w.Write([]byte(`{"data":[` +
+				`{"name":"order_shipped","language":"en_US","status":"APPROVED"},` +
+				`{"name":"promo","language":"en_US","status":"PENDING"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"id":"phone_1","display_phone_number":"+255 700 000 000"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	client := NewClient(WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1"),
+		WithBusinessAccountID("waba_1"))
+	cache := NewMetadataCache(client, &MetadataCacheConfig{Clock: clk, TTL: time.Minute, TemplatesTTL: time.Hour})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := cache.ValidateTemplate(ctx, "order_shipped", "en_US"); err != nil {
+			t.Fatalf("ValidateTemplate() error = %v", err)
+		}
+		profile, err := cache.BusinessProfile(ctx)
+		if err != nil || profile.About != "Open 24/7" {
+			t.Fatalf("BusinessProfile() = %+v, %v", profile, err)
+		}
+		phone, err := cache.PhoneNumber(ctx)
+		if err != nil || phone.DisplayPhoneNumber != "+255 700 000 000" {
+			t.Fatalf("PhoneNumber() = %+v, %v", phone, err)
+		}
+	}
+	if err := cache.ValidateTemplate(ctx, "promo", "en_US"); !errors.Is(err, ErrTemplateNotApproved) {
+		t.Errorf("ValidateTemplate() error = %v, want %v", err, ErrTemplateNotApproved)
+	}
+	if err := cache.ValidateTemplate(ctx, "order_shipped", "sw"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("ValidateTemplate() error = %v, want %v", err, ErrTemplateNotFound)
+	}
+
+	// the profile and phone number expire, the templates have a longer TTL
+	clk.Advance(time.Minute)
+	_, _ = cache.BusinessProfile(ctx)
+	_, _ = cache.PhoneNumber(ctx)
+	_, _ = cache.Templates(ctx)
+
+	// explicit invalidation
+	cache.InvalidateTemplates(ctx)
+	_, _ = cache.Templates(ctx)
+
+	// the metadata of other accounts is cached separately
+	other := WithOverrides(ctx, &Overrides{PhoneNumberID: "phone_2"})
+	_, _ = cache.BusinessProfile(other)
+	_, _ = cache.Templates(other)
+
+	want := map[string]int{
+		"/v16.0/phone_1/whatsapp_business_profile": 2,
+		"/v16.0/phone_1":                           2,
+		"/v16.0/waba_1/message_templates":          2,
+		"/v16.0/phone_2/whatsapp_business_profile": 1,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for path, n := range want {
+		if hits[path] != n {
+			t.Errorf("hits[%s] = %d, want %d", path, hits[path], n)
+		}
+	}
+	if len(hits) != len(want) {
+		t.Errorf("hits = %v, want %v", hits, want)
+	}
+}
+
+func TestMetadataCache_SharedFetch(t *testing.T) {
+	t.Parallel()
+	var (
+		mu      sync.Mutex
+		hits    int
+		release = make(chan struct{})
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		<-release
+		_, _ = w.Write([]byte(`{"id":"phone_1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cache := NewMetadataCache(NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1")), nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.PhoneNumber(context.Background()); err != nil {
+				t.Errorf("PhoneNumber() error = %v", err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+}
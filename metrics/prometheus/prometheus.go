@@ -0,0 +1,348 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package prometheus exports the metrics of the requests sent by a whatsapp.Client and of the
+// notifications processed by a webhooks.EventListener to Prometheus. It lives in its own
+// module so that the client does not depend on the Prometheus libraries.
+//
+//	metrics := prometheus.New(nil)
+//	if err := metrics.Register(registry); err != nil {
+//		return err
+//	}
+//	client := whatsapp.NewClient(whatsapp.WithEventHooks(metrics.EventHook()))
+//	listener := webhooks.NewEventListener(webhooks.WithProcessingHooks(metrics.ProcessingHook()))
+//	metrics.TrackListener(listener)
+//	metrics.TrackPool(pool)
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strconv"
+	"sync/atomic"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/SeamPay/whatsapp"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// Outcomes of the processed webhook events.
+const (
+	webhookSuccess   = "success"
+	webhookError     = "error"
+	webhookDuplicate = "duplicate"
+)
+
+// DefaultNamespace is the namespace of the metrics when Options.Namespace is empty.
+const DefaultNamespace = "whatsapp"
+
+var (
+	_ SendPool = (*whatsapp.SenderPool)(nil)
+	_ SendPool = (*whatsapp.AsyncSender)(nil)
+)
+
+type (
+	// Options configures the metrics. Namespace and Subsystem prefix the metric names, Buckets
+	// are the buckets of the latency histogram, they default to prom.DefBuckets.
+	Options struct {
+		Namespace   string
+		Subsystem   string
+		Buckets     []float64
+		ConstLabels prom.Labels
+	}
+
+	// Metrics collects the metrics of the requests sent by the client:
+	//
+	//   - requests_total{request,outcome}: the number of request attempts by outcome.
+	//   - errors_total{request,code}: the number of errors returned by the API by Graph error code.
+	//   - request_duration_seconds{request,outcome}: the latency of request attempts.
+	//   - retries_total{request}: the number of attempts after the first one.
+	//   - media_upload_bytes_total: the number of bytes sent in multipart media uploads.
+	//
+	// and of the notifications processed by the listener:
+	//
+	//   - webhook_events_total{kind,type,outcome}: the number of notifications, messages by
+	//     type, statuses by state and errors by code processed, by outcome: success, error, or
+	//     duplicate for the ones batched more than once in a notification.
+	//   - webhook_event_duration_seconds{kind,type}: the time spent in the hooks.
+	//   - webhook_notifications_in_flight: the number of notifications being processed by the
+	//     listener passed to TrackListener.
+	//
+	// and of the send pool passed to TrackPool:
+	//
+	//   - send_pool_queued: the number of sends waiting for a worker, parked sends included.
+	//   - send_pool_in_flight: the number of sends being sent.
+	Metrics struct {
+		requests        *prom.CounterVec
+		errors          *prom.CounterVec
+		latency         *prom.HistogramVec
+		retries         *prom.CounterVec
+		uploadBytes     prom.Counter
+		webhookEvents   *prom.CounterVec
+		webhookLatency  *prom.HistogramVec
+		webhookInFlight prom.GaugeFunc
+		poolQueued      prom.GaugeFunc
+		poolInFlight    prom.GaugeFunc
+		listener        atomic.Pointer[webhooks.EventListener]
+		pool            atomic.Pointer[trackedPool]
+	}
+
+	// SendPool is a pool of workers sending messages, whatsapp.SenderPool and
+	// whatsapp.AsyncSender implement it.
+	SendPool interface {
+		Stats() whatsapp.SenderPoolStats
+	}
+
+	trackedPool struct {
+		pool SendPool
+	}
+)
+
+// New creates the metrics, they still have to be registered with Register. If options is
+// nil, the defaults are used.
+func New(options *Options) *Metrics {
+	if options == nil {
+		options = &Options{}
+	}
+	namespace := options.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	buckets := options.Buckets
+	if len(buckets) == 0 {
+		buckets = prom.DefBuckets
+	}
+
+	m := &Metrics{
+		requests: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   options.Subsystem,
+			Name:        "requests_total",
+			Help:        "Number of request attempts sent to the WhatsApp Cloud API by outcome.",
+			ConstLabels: options.ConstLabels,
+		}, []string{"request", "outcome"}),
+		errors: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   options.Subsystem,
+			Name:        "errors_total",
+			Help:        "Number of errors returned by the WhatsApp Cloud API by Graph error code.",
+			ConstLabels: options.ConstLabels,
+		}, []string{"request", "code"}),
+		latency: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace:   namespace,
+			Subsystem:   options.Subsystem,
+			Name:        "request_duration_seconds",
+			Help:        "Latency of the request attempts sent to the WhatsApp Cloud API.",
+			Buckets:     buckets,
+			ConstLabels: options.ConstLabels,
+		}, []string{"request", "outcome"}),
+		retries: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   options.Subsystem,
+			Name:        "retries_total",
+			Help:        "Number of request attempts after the first one.",
+			ConstLabels: options.ConstLabels,
+		}, []string{"request"}),
+		uploadBytes: prom.NewCounter(prom.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   options.Subsystem,
+			Name:        "media_upload_bytes_total",
+			Help:        "Number of bytes sent in media uploads.",
+			ConstLabels: options.ConstLabels,
+		}),
+		webhookEvents: prom.NewCounterVec(prom.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   options.Subsystem,
+			Name:        "webhook_events_total",
+			Help:        "Number of webhook notifications, messages, statuses and errors processed by outcome.",
+			ConstLabels: options.ConstLabels,
+		}, []string{"kind", "type", "outcome"}),
+		webhookLatency: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace:   namespace,
+			Subsystem:   options.Subsystem,
+			Name:        "webhook_event_duration_seconds",
+			Help:        "Time spent processing the webhook notifications, messages, statuses and errors.",
+			Buckets:     buckets,
+			ConstLabels: options.ConstLabels,
+		}, []string{"kind", "type"}),
+	}
+	m.webhookInFlight = prom.NewGaugeFunc(prom.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   options.Subsystem,
+		Name:        "webhook_notifications_in_flight",
+		Help:        "Number of webhook notifications being processed.",
+		ConstLabels: options.ConstLabels,
+	}, m.inFlight)
+	m.poolQueued = prom.NewGaugeFunc(prom.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   options.Subsystem,
+		Name:        "send_pool_queued",
+		Help:        "Number of sends waiting for a worker of the send pool.",
+		ConstLabels: options.ConstLabels,
+	}, func() float64 { return float64(m.poolStats().Queued) })
+	m.poolInFlight = prom.NewGaugeFunc(prom.GaugeOpts{
+		Namespace:   namespace,
+		Subsystem:   options.Subsystem,
+		Name:        "send_pool_in_flight",
+		Help:        "Number of sends being sent by the workers of the send pool.",
+		ConstLabels: options.ConstLabels,
+	}, func() float64 { return float64(m.poolStats().InFlight) })
+
+	return m
+}
+
+// Register registers the metrics with registerer.
+func (m *Metrics) Register(registerer prom.Registerer) error {
+	for _, collector := range m.collectors() {
+		if err := registerer.Register(collector); err != nil {
+			return fmt.Errorf("register whatsapp metrics: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Describe implements prom.Collector, so that the metrics can also be registered as one
+// collector with MustRegister.
+func (m *Metrics) Describe(ch chan<- *prom.Desc) {
+	for _, collector := range m.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect implements prom.Collector.
+func (m *Metrics) Collect(ch chan<- prom.Metric) {
+	for _, collector := range m.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+func (m *Metrics) collectors() []prom.Collector {
+	return []prom.Collector{
+		m.requests, m.errors, m.latency, m.retries, m.uploadBytes,
+		m.webhookEvents, m.webhookLatency, m.webhookInFlight,
+		m.poolQueued, m.poolInFlight,
+	}
+}
+
+// EventHook returns a whttp.EventHook that records every request attempt. Pass it to
+// whatsapp.WithEventHooks.
+func (m *Metrics) EventHook() whttp.EventHook {
+	return func(ctx context.Context, event *whttp.Event) {
+		m.Observe(event)
+	}
+}
+
+// Middleware returns a whttp.Middleware that records the requests that go through it. Put it
+// after the middlewares that retry requests to record every attempt.
+func (m *Metrics) Middleware() whttp.Middleware {
+	return whttp.EventMiddleware(m.EventHook())
+}
+
+// Observe records the event.
+func (m *Metrics) Observe(event *whttp.Event) {
+	if event == nil {
+		return
+	}
+	outcome := string(event.Outcome)
+	m.requests.WithLabelValues(event.Name, outcome).Inc()
+	m.latency.WithLabelValues(event.Name, outcome).Observe(event.Duration.Seconds())
+	if event.Attempt > 1 {
+		m.retries.WithLabelValues(event.Name).Inc()
+	}
+
+	if code, ok := werrors.Code(event.Err); ok {
+		m.errors.WithLabelValues(event.Name, strconv.Itoa(code)).Inc()
+	}
+
+	if isUpload(event) {
+		m.uploadBytes.Add(float64(event.Request.ContentLength))
+	}
+}
+
+// ProcessingHook returns a webhooks.ProcessingHook that records every processed webhook
+// event. Pass it to webhooks.WithProcessingHooks.
+func (m *Metrics) ProcessingHook() webhooks.ProcessingHook {
+	return func(ctx context.Context, event *webhooks.ProcessingEvent) {
+		m.ObserveProcessing(event)
+	}
+}
+
+// ObserveProcessing records the webhook event.
+func (m *Metrics) ObserveProcessing(event *webhooks.ProcessingEvent) {
+	if event == nil {
+		return
+	}
+	kind := string(event.Kind)
+	switch {
+	case event.Duplicate:
+		m.webhookEvents.WithLabelValues(kind, event.Type, webhookDuplicate).Inc()
+
+		return
+	case event.Err != nil:
+		m.webhookEvents.WithLabelValues(kind, event.Type, webhookError).Inc()
+	default:
+		m.webhookEvents.WithLabelValues(kind, event.Type, webhookSuccess).Inc()
+	}
+	m.webhookLatency.WithLabelValues(kind, event.Type).Observe(event.Duration.Seconds())
+}
+
+// TrackListener reports the notifications in flight of listener, see
+// webhooks.EventListener.InFlight.
+func (m *Metrics) TrackListener(listener *webhooks.EventListener) {
+	m.listener.Store(listener)
+}
+
+func (m *Metrics) inFlight() float64 {
+	listener := m.listener.Load()
+	if listener == nil {
+		return 0
+	}
+
+	return float64(listener.InFlight())
+}
+
+// TrackPool reports the queue depth and the sends in flight of pool, see
+// whatsapp.SenderPool.Stats.
+func (m *Metrics) TrackPool(pool SendPool) {
+	m.pool.Store(&trackedPool{pool: pool})
+}
+
+func (m *Metrics) poolStats() whatsapp.SenderPoolStats {
+	tracked := m.pool.Load()
+	if tracked == nil || tracked.pool == nil {
+		return whatsapp.SenderPoolStats{}
+	}
+
+	return tracked.pool.Stats()
+}
+
+// isUpload reports whether the event is a multipart upload with a known body size.
+func isUpload(event *whttp.Event) bool {
+	if event.Request == nil || event.Request.ContentLength <= 0 {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(event.Request.Header.Get("Content-Type"))
+
+	return err == nil && mediaType == "multipart/form-data"
+}
@@ -0,0 +1,272 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/SeamPay/whatsapp"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+	registry := prom.NewRegistry()
+	metrics := New(&Options{Namespace: "test"})
+	if err := metrics.Register(registry); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	upload, _ := http.NewRequest(http.MethodPost, "https://example.com", strings.NewReader("0123456789"))
+	upload.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+
+	hook := metrics.EventHook()
+	hook(context.TODO(), &whttp.Event{
+		Name:     "send text",
+		Attempt:  1,
+		Duration: time.Second,
+		Outcome:  whttp.OutcomeSuccess,
+	})
+	hook(context.TODO(), &whttp.Event{
+		Name:     "send text",
+		Attempt:  2,
+		Duration: time.Second,
+		Outcome:  whttp.OutcomeAPIError,
+		Err:      &whttp.ResponseError{Code: http.StatusBadRequest, Err: &werrors.Error{Code: 131026}},
+	})
+	hook(context.TODO(), &whttp.Event{
+		Name:    "upload media",
+		Attempt: 1,
+		Request: upload,
+		Outcome: whttp.OutcomeSuccess,
+	})
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	got := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make([]string, 0, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetName()+"="+label.GetValue())
+			}
+			key := family.GetName() + "{" + strings.Join(labels, ",") + "}"
+			switch {
+			case metric.GetCounter() != nil:
+				got[key] = metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				got[key] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	want := map[string]float64{
+		"test_requests_total{outcome=success,request=send text}":              1,
+		"test_requests_total{outcome=api_error,request=send text}":            1,
+		"test_errors_total{code=131026,request=send text}":                    1,
+		"test_retries_total{request=send text}":                               1,
+		"test_request_duration_seconds{outcome=success,request=upload media}": 1,
+		"test_media_upload_bytes_total{}":                                     10,
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+func TestMetricsClientUpload(t *testing.T) {
+	t.Parallel()
+	registry := prom.NewRegistry()
+	metrics := New(&Options{Namespace: "test"})
+	if err := metrics.Register(registry); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	var length int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		length = r.ContentLength
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(`{"id":"media_1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := whatsapp.NewClient(whatsapp.WithBaseURL(server.URL), whatsapp.WithPhoneNumberID("phone_1"),
+		whatsapp.WithEventHooks(metrics.EventHook()))
+	_, err := client.UploadMedia(context.TODO(), whatsapp.MediaTypeImage, "photo.png",
+		bytes.NewReader([]byte("0123456789")))
+	if err != nil {
+		t.Fatalf("UploadMedia() error = %v", err)
+	}
+	if got := gather(t, registry)["test_media_upload_bytes_total{}"]; length <= 0 || got != float64(length) {
+		t.Errorf("media_upload_bytes_total = %v, want %d", got, length)
+	}
+}
+
+func TestMetricsWebhooks(t *testing.T) {
+	t.Parallel()
+	registry := prom.NewRegistry()
+	metrics := New(&Options{Namespace: "test"})
+	if err := metrics.Register(registry); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	listener := webhooks.NewEventListener(webhooks.WithProcessingHooks(metrics.ProcessingHook()))
+	listener.OnTextMessage(func(ctx context.Context, nctx *webhooks.NotificationContext,
+		mctx *webhooks.MessageContext, text *webhooks.Text,
+	) error {
+		close(started)
+		<-release
+
+		return nil
+	})
+	metrics.TrackListener(listener)
+
+	body := `{"object":"whatsapp_business_account","entry":[{"id":"waba_1","changes":[{"field":"messages","value":{
+		"messages":[{"from":"255700000001","id":"wamid.1","timestamp":"1","type":"text","text":{"body":"hi"}},
+			{"from":"255700000001","id":"wamid.1","timestamp":"1","type":"text","text":{"body":"hi"}}],
+		"errors":[{"code":131051,"title":"Unsupported message type"}]}}]}]}`
+	request := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		listener.NotificationHandler().ServeHTTP(httptest.NewRecorder(), request)
+	}()
+
+	<-started
+	if got := gather(t, registry)["test_webhook_notifications_in_flight{}"]; got != 1 {
+		t.Errorf("webhook_notifications_in_flight = %v, want 1", got)
+	}
+	close(release)
+	<-done
+
+	got := gather(t, registry)
+	want := map[string]float64{
+		"test_webhook_events_total{kind=notification,outcome=success,type=}":  1,
+		"test_webhook_events_total{kind=message,outcome=success,type=text}":   1,
+		"test_webhook_events_total{kind=message,outcome=duplicate,type=text}": 1,
+		"test_webhook_events_total{kind=error,outcome=success,type=131051}":   1,
+		"test_webhook_event_duration_seconds{kind=message,type=text}":         1,
+		"test_webhook_notifications_in_flight{}":                              0,
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+// gather returns the values of the metrics of registry by name and labels, the sample count
+// of the histograms.
+func gather(t *testing.T, registry *prom.Registry) map[string]float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	got := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make([]string, 0, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetName()+"="+label.GetValue())
+			}
+			key := family.GetName() + "{" + strings.Join(labels, ",") + "}"
+			switch {
+			case metric.GetCounter() != nil:
+				got[key] = metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				got[key] = metric.GetGauge().GetValue()
+			case metric.GetHistogram() != nil:
+				got[key] = float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	return got
+}
+
+func TestMetricsTrackPool(t *testing.T) {
+	t.Parallel()
+	registry := prom.NewRegistry()
+	metrics := New(&Options{Namespace: "test"})
+	if err := metrics.Register(registry); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if got := gather(t, registry)["test_send_pool_queued{}"]; got != 0 {
+		t.Errorf("send_pool_queued without a pool = %v, want 0", got)
+	}
+
+	pool := whatsapp.NewSenderPool(&whatsapp.SenderPoolConfig{Workers: 1, QueueSize: 3})
+	t.Cleanup(pool.Close)
+	metrics.TrackPool(pool)
+	release := make(chan struct{})
+	send := func(ctx context.Context) (*whatsapp.ResponseMessage, error) {
+		<-release
+
+		return &whatsapp.ResponseMessage{}, nil
+	}
+	results := make([]<-chan whatsapp.SendResult, 0, 3)
+	for i := 0; i < 3; i++ {
+		result, err := pool.Submit(context.TODO(), "phone_1", send)
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		results = append(results, result)
+	}
+	for pool.Stats().InFlight != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	got := gather(t, registry)
+	if got["test_send_pool_in_flight{}"] != 1 || got["test_send_pool_queued{}"] != 2 {
+		t.Errorf("send_pool_in_flight = %v, send_pool_queued = %v, want 1 and 2",
+			got["test_send_pool_in_flight{}"], got["test_send_pool_queued{}"])
+	}
+
+	close(release)
+	for _, result := range results {
+		<-result
+	}
+	// the worker is released after the result is delivered
+	for pool.Stats().InFlight != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	got = gather(t, registry)
+	if got["test_send_pool_in_flight{}"] != 0 || got["test_send_pool_queued{}"] != 0 {
+		t.Errorf("send_pool_in_flight = %v, send_pool_queued = %v after the sends, want 0",
+			got["test_send_pool_in_flight{}"], got["test_send_pool_queued{}"])
+	}
+}
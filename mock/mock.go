@@ -0,0 +1,371 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package mock provides a fake implementation of the whatsapp.MessageSender,
+// whatsapp.MediaManager and whatsapp.TemplateManager interfaces, to unit-test code
+// that uses the client without calling the Graph API.
+//
+// Every method records its call and then calls the function field of the same name,
+// when it is nil the method succeeds with a response carrying a fake message ID:
+//
+//	client := &mock.Client{
+//		SendTextFunc: func(ctx context.Context, recipient, text string, options ...whatsapp.SendOption) (
+//			*whatsapp.ResponseMessage, error,
+//		) {
+//			return nil, errors.New("boom")
+//		},
+//	}
+//	notifier := NewNotifier(client) // takes a whatsapp.MessageSender
+//	...
+//	if calls := client.CallsTo("SendText"); len(calls) != 1 {
+//		t.Errorf("SendText called %d times, want 1", len(calls))
+//	}
+package mock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/SeamPay/whatsapp"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+type (
+	// Call is a recorded call of a method of Client.
+	Call struct {
+		Method string
+		Args   []any
+	}
+
+	// Client is a fake client, it is safe for concurrent use. The function fields must
+	// be set before the client is used.
+	Client struct {
+		SendMessageFunc func(ctx context.Context, message *models.Message, options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		SendTextFunc func(ctx context.Context, recipient, text string, options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		SendTextMessageFunc func(ctx context.Context, recipient string, message *whatsapp.TextMessage,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		SendLocationMessageFunc func(ctx context.Context, recipient string, message *models.Location,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		SendMediaFunc func(ctx context.Context, recipient string, req *whatsapp.MediaMessage,
+			cacheOptions *whatsapp.CacheOptions, options ...whatsapp.SendOption) (*whatsapp.ResponseMessage, error)
+		SendContactsFunc func(ctx context.Context, recipient string, contacts []*models.Contact,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		SendInteractiveMessageFunc func(ctx context.Context, recipient string, req *models.Interactive,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		ReactFunc func(ctx context.Context, recipient string, req *whatsapp.ReactMessage,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		ReplyFunc func(ctx context.Context, recipient string, req *whatsapp.ReplyMessage,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		MarkMessageReadFunc func(ctx context.Context, messageID string) (*whatsapp.StatusResponse, error)
+
+		UploadMediaFunc func(ctx context.Context, mediaType whatsapp.MediaType, filename string,
+			fr io.Reader) (*whatsapp.UploadMediaResponse, error)
+		GetMediaInformationFunc func(ctx context.Context, mediaID string) (*whatsapp.MediaInformation, error)
+		DownloadMediaFunc       func(ctx context.Context, mediaID string, retries int) (
+			*whatsapp.DownloadMediaResponse, error)
+		DeleteMediaFunc func(ctx context.Context, mediaID string) (*whatsapp.DeleteMediaResponse, error)
+
+		SendTemplateFunc func(ctx context.Context, recipient string, req *whatsapp.Template,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		SendTextTemplateFunc func(ctx context.Context, recipient string, req *whatsapp.TextTemplateRequest,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		SendMediaTemplateFunc func(ctx context.Context, recipient string, req *whatsapp.MediaTemplateRequest,
+			options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+		SendInteractiveTemplateFunc func(ctx context.Context, recipient string,
+			req *whatsapp.InteractiveTemplateRequest, options ...whatsapp.SendOption) (*whatsapp.ResponseMessage, error)
+		SendMarketingTemplateFunc func(ctx context.Context, recipient string,
+			req *whatsapp.MarketingTemplateRequest, options ...whatsapp.SendOption) (*whatsapp.ResponseMessage, error)
+
+		mu    sync.Mutex
+		calls []Call
+	}
+)
+
+var (
+	_ whatsapp.MessageSender   = (*Client)(nil)
+	_ whatsapp.MediaManager    = (*Client)(nil)
+	_ whatsapp.TemplateManager = (*Client)(nil)
+)
+
+// Calls returns the recorded calls, in order.
+func (client *Client) Calls() []Call {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return append([]Call(nil), client.calls...)
+}
+
+// CallsTo returns the recorded calls of method, in order.
+func (client *Client) CallsTo(method string) []Call {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	var calls []Call
+	for _, call := range client.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// Reset forgets the recorded calls.
+func (client *Client) Reset() {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.calls = nil
+}
+
+// record records a call and returns its number, starting at 1.
+func (client *Client) record(method string, args ...any) int {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.calls = append(client.calls, Call{Method: method, Args: args})
+
+	return len(client.calls)
+}
+
+// sent is the default response of the message methods.
+func sent(recipient string, n int) *whatsapp.ResponseMessage {
+	return &whatsapp.ResponseMessage{
+		Product:  "whatsapp",
+		Contacts: []*whatsapp.ResponseContact{{Input: recipient, WhatsappID: strings.TrimPrefix(recipient, "+")}},
+		Messages: []*whatsapp.MessageID{{ID: fmt.Sprintf("wamid.mock.%d", n)}},
+	}
+}
+
+func (client *Client) SendMessage(ctx context.Context, message *models.Message, options ...whatsapp.SendOption) (
+	*whatsapp.ResponseMessage, error,
+) {
+	n := client.record("SendMessage", message, options)
+	if client.SendMessageFunc != nil {
+		return client.SendMessageFunc(ctx, message, options...)
+	}
+	if message == nil {
+		return sent("", n), nil
+	}
+
+	return sent(message.To, n), nil
+}
+
+func (client *Client) SendText(ctx context.Context, recipient, text string, options ...whatsapp.SendOption) (
+	*whatsapp.ResponseMessage, error,
+) {
+	n := client.record("SendText", recipient, text, options)
+	if client.SendTextFunc != nil {
+		return client.SendTextFunc(ctx, recipient, text, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendTextMessage(ctx context.Context, recipient string, message *whatsapp.TextMessage,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendTextMessage", recipient, message, options)
+	if client.SendTextMessageFunc != nil {
+		return client.SendTextMessageFunc(ctx, recipient, message, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendLocationMessage(ctx context.Context, recipient string, message *models.Location,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendLocationMessage", recipient, message, options)
+	if client.SendLocationMessageFunc != nil {
+		return client.SendLocationMessageFunc(ctx, recipient, message, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendMedia(ctx context.Context, recipient string, req *whatsapp.MediaMessage,
+	cacheOptions *whatsapp.CacheOptions, options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendMedia", recipient, req, cacheOptions, options)
+	if client.SendMediaFunc != nil {
+		return client.SendMediaFunc(ctx, recipient, req, cacheOptions, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendContacts(ctx context.Context, recipient string, contacts []*models.Contact,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendContacts", recipient, contacts, options)
+	if client.SendContactsFunc != nil {
+		return client.SendContactsFunc(ctx, recipient, contacts, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendInteractiveMessage(ctx context.Context, recipient string, req *models.Interactive,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendInteractiveMessage", recipient, req, options)
+	if client.SendInteractiveMessageFunc != nil {
+		return client.SendInteractiveMessageFunc(ctx, recipient, req, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) React(ctx context.Context, recipient string, req *whatsapp.ReactMessage,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("React", recipient, req, options)
+	if client.ReactFunc != nil {
+		return client.ReactFunc(ctx, recipient, req, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) Reply(ctx context.Context, recipient string, req *whatsapp.ReplyMessage,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("Reply", recipient, req, options)
+	if client.ReplyFunc != nil {
+		return client.ReplyFunc(ctx, recipient, req, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) MarkMessageRead(ctx context.Context, messageID string) (*whatsapp.StatusResponse, error) {
+	client.record("MarkMessageRead", messageID)
+	if client.MarkMessageReadFunc != nil {
+		return client.MarkMessageReadFunc(ctx, messageID)
+	}
+
+	return &whatsapp.StatusResponse{Success: true}, nil
+}
+
+func (client *Client) UploadMedia(ctx context.Context, mediaType whatsapp.MediaType, filename string,
+	fr io.Reader,
+) (*whatsapp.UploadMediaResponse, error) {
+	n := client.record("UploadMedia", mediaType, filename, fr)
+	if client.UploadMediaFunc != nil {
+		return client.UploadMediaFunc(ctx, mediaType, filename, fr)
+	}
+
+	return &whatsapp.UploadMediaResponse{ID: fmt.Sprintf("media.mock.%d", n)}, nil
+}
+
+func (client *Client) GetMediaInformation(ctx context.Context, mediaID string) (*whatsapp.MediaInformation, error) {
+	client.record("GetMediaInformation", mediaID)
+	if client.GetMediaInformationFunc != nil {
+		return client.GetMediaInformationFunc(ctx, mediaID)
+	}
+
+	return &whatsapp.MediaInformation{MessagingProduct: "whatsapp", ID: mediaID}, nil
+}
+
+func (client *Client) DownloadMedia(ctx context.Context, mediaID string, retries int) (
+	*whatsapp.DownloadMediaResponse, error,
+) {
+	client.record("DownloadMedia", mediaID, retries)
+	if client.DownloadMediaFunc != nil {
+		return client.DownloadMediaFunc(ctx, mediaID, retries)
+	}
+
+	return &whatsapp.DownloadMediaResponse{Body: strings.NewReader("")}, nil
+}
+
+func (client *Client) DeleteMedia(ctx context.Context, mediaID string) (*whatsapp.DeleteMediaResponse, error) {
+	client.record("DeleteMedia", mediaID)
+	if client.DeleteMediaFunc != nil {
+		return client.DeleteMediaFunc(ctx, mediaID)
+	}
+
+	return &whatsapp.DeleteMediaResponse{Success: true}, nil
+}
+
+func (client *Client) SendTemplate(ctx context.Context, recipient string, req *whatsapp.Template,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendTemplate", recipient, req, options)
+	if client.SendTemplateFunc != nil {
+		return client.SendTemplateFunc(ctx, recipient, req, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendTextTemplate(ctx context.Context, recipient string, req *whatsapp.TextTemplateRequest,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendTextTemplate", recipient, req, options)
+	if client.SendTextTemplateFunc != nil {
+		return client.SendTextTemplateFunc(ctx, recipient, req, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendMediaTemplate(ctx context.Context, recipient string, req *whatsapp.MediaTemplateRequest,
+	options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendMediaTemplate", recipient, req, options)
+	if client.SendMediaTemplateFunc != nil {
+		return client.SendMediaTemplateFunc(ctx, recipient, req, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendInteractiveTemplate(ctx context.Context, recipient string,
+	req *whatsapp.InteractiveTemplateRequest, options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendInteractiveTemplate", recipient, req, options)
+	if client.SendInteractiveTemplateFunc != nil {
+		return client.SendInteractiveTemplateFunc(ctx, recipient, req, options...)
+	}
+
+	return sent(recipient, n), nil
+}
+
+func (client *Client) SendMarketingTemplate(ctx context.Context, recipient string,
+	req *whatsapp.MarketingTemplateRequest, options ...whatsapp.SendOption,
+) (*whatsapp.ResponseMessage, error) {
+	n := client.record("SendMarketingTemplate", recipient, req, options)
+	if client.SendMarketingTemplateFunc != nil {
+		return client.SendMarketingTemplateFunc(ctx, recipient, req, options...)
+	}
+
+	return sent(recipient, n), nil
+}
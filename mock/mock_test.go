@@ -0,0 +1,70 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package mock
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/SeamPay/whatsapp"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	errSend := errors.New("send failed")
+	client := &Client{
+		SendTemplateFunc: func(ctx context.Context, recipient string, req *whatsapp.Template,
+			options ...whatsapp.SendOption,
+		) (
+			*whatsapp.ResponseMessage, error,
+		) {
+			return nil, errSend
+		},
+	}
+	var sender whatsapp.MessageSender = client
+
+	resp, err := sender.SendText(context.TODO(), "+255767001828", "hello")
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if id := resp.Messages[0].ID; id != "wamid.mock.1" {
+		t.Errorf("SendText() message id = %q, want %q", id, "wamid.mock.1")
+	}
+	if _, err := client.SendMessage(context.TODO(), &models.Message{To: "255767001828"}); err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if _, err := client.SendTemplate(context.TODO(), "255767001828", &whatsapp.Template{}); !errors.Is(err, errSend) {
+		t.Errorf("SendTemplate() error = %v, want %v", err, errSend)
+	}
+
+	calls := client.CallsTo("SendText")
+	if len(calls) != 1 || calls[0].Args[0] != "+255767001828" || calls[0].Args[1] != "hello" {
+		t.Errorf("CallsTo(SendText) = %+v", calls)
+	}
+	if n := len(client.Calls()); n != 3 {
+		t.Errorf("len(Calls()) = %d, want 3", n)
+	}
+	client.Reset()
+	if n := len(client.Calls()); n != 0 {
+		t.Errorf("len(Calls()) after Reset() = %d, want 0", n)
+	}
+}
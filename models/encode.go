@@ -0,0 +1,397 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// AppendJSON appends the JSON encoding of the message to dst, it is the same as the one of
+// json.Marshal. Text, template and media messages, the bulk of what is sent, are encoded
+// without reflection and without intermediate allocations, the other messages fall back to
+// json.Marshal.
+func (message *Message) AppendJSON(dst []byte) ([]byte, error) {
+	if !message.appendable() {
+		return appendMarshal(dst, message)
+	}
+
+	dst = append(dst, `{"messaging_product":`...)
+	dst = AppendJSONString(dst, message.Product)
+	dst = append(dst, `,"to":`...)
+	dst = AppendJSONString(dst, message.To)
+	dst = append(dst, `,"recipient_type":`...)
+	dst = AppendJSONString(dst, message.RecipientType)
+	dst = append(dst, `,"type":`...)
+	dst = AppendJSONString(dst, message.Type)
+	if message.PreviewURL {
+		dst = append(dst, `,"preview_url":true`...)
+	}
+	if message.Context != nil {
+		dst = append(dst, `,"context":{"message_id":`...)
+		dst = AppendJSONString(dst, message.Context.MessageID)
+		dst = append(dst, '}')
+	}
+	if message.Template != nil {
+		dst = append(dst, `,"template":`...)
+		dst = message.Template.appendJSON(dst)
+	}
+	if message.Text != nil {
+		dst = append(dst, `,"text":{`...)
+		if message.Text.PreviewURL {
+			dst = append(dst, `"preview_url":true`...)
+		}
+		if message.Text.Body != "" {
+			dst = appendStringField(dst, "body", message.Text.Body, !message.Text.PreviewURL)
+		}
+		dst = append(dst, '}')
+	}
+	for _, media := range [...]struct {
+		key   string
+		media *Media
+	}{
+		{`,"image":`, message.Image},
+		{`,"audio":`, message.Audio},
+		{`,"video":`, message.Video},
+		{`,"document":`, message.Document},
+		{`,"sticker":`, message.Sticker},
+	} {
+		if media.media != nil {
+			dst = append(dst, media.key...)
+			dst = media.media.AppendJSON(dst)
+		}
+	}
+	if message.BizOpaqueCallbackData != "" {
+		dst = append(dst, `,"biz_opaque_callback_data":`...)
+		dst = AppendJSONString(dst, message.BizOpaqueCallbackData)
+	}
+
+	return append(dst, '}'), nil
+}
+
+// appendable reports whether AppendJSON encodes the message itself.
+func (message *Message) appendable() bool {
+	if message.Reaction != nil || message.Location != nil || len(message.Contacts) > 0 || message.Interactive != nil {
+		return false
+	}
+	if message.Template == nil {
+		return true
+	}
+	for _, component := range message.Template.Components {
+		if component != nil && component.Index != "" && !isJSONInteger(string(component.Index)) {
+			return false // let json.Marshal report the invalid number
+		}
+	}
+
+	return true
+}
+
+// AppendJSON appends the JSON encoding of the media to dst.
+func (media *Media) AppendJSON(dst []byte) []byte {
+	if media == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, '{')
+	first := true
+	for _, field := range [...]struct{ key, value string }{
+		{"id", media.ID},
+		{"link", media.Link},
+		{"caption", media.Caption},
+		{"filename", media.Filename},
+		{"provider", media.Provider},
+	} {
+		if field.value != "" {
+			dst = appendStringField(dst, field.key, field.value, first)
+			first = false
+		}
+	}
+
+	return append(dst, '}')
+}
+
+func (template *Template) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	first := true
+	if template.Name != "" {
+		dst = appendStringField(dst, "name", template.Name, first)
+		first = false
+	}
+	if template.Namespace != "" {
+		dst = appendStringField(dst, "namespace", template.Namespace, first)
+		first = false
+	}
+	if template.Language != nil {
+		dst = appendKey(dst, "language", first)
+		first = false
+		dst = append(dst, '{')
+		languageFirst := true
+		if template.Language.Policy != "" {
+			dst = appendStringField(dst, "policy", template.Language.Policy, languageFirst)
+			languageFirst = false
+		}
+		if template.Language.Code != "" {
+			dst = appendStringField(dst, "code", template.Language.Code, languageFirst)
+		}
+		dst = append(dst, '}')
+	}
+	if len(template.Components) > 0 {
+		dst = appendKey(dst, "components", first)
+		dst = append(dst, '[')
+		for i, component := range template.Components {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = component.appendJSON(dst)
+		}
+		dst = append(dst, ']')
+	}
+
+	return append(dst, '}')
+}
+
+func (component *TemplateComponent) appendJSON(dst []byte) []byte {
+	if component == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, '{')
+	first := true
+	if component.Type != "" {
+		dst = appendStringField(dst, "type", component.Type, first)
+		first = false
+	}
+	if component.SubType != "" {
+		dst = appendStringField(dst, "sub_type", component.SubType, first)
+		first = false
+	}
+	if len(component.Parameters) > 0 {
+		dst = appendKey(dst, "parameters", first)
+		first = false
+		dst = append(dst, '[')
+		for i, parameter := range component.Parameters {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = parameter.appendJSON(dst)
+		}
+		dst = append(dst, ']')
+	}
+	if component.Index != "" {
+		dst = appendKey(dst, "index", first)
+		dst = append(dst, component.Index...)
+	}
+
+	return append(dst, '}')
+}
+
+func (parameter *TemplateParameter) appendJSON(dst []byte) []byte {
+	if parameter == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, '{')
+	first := true
+	for _, field := range [...]struct{ key, value string }{
+		{"type", parameter.Type},
+		{"text", parameter.Text},
+		{"payload", parameter.Payload},
+	} {
+		if field.value != "" {
+			dst = appendStringField(dst, field.key, field.value, first)
+			first = false
+		}
+	}
+	if currency := parameter.Currency; currency != nil {
+		dst = appendKey(dst, "currency", first)
+		first = false
+		dst = append(dst, '{')
+		currencyFirst := true
+		if currency.FallbackValue != "" {
+			dst = appendStringField(dst, "fallback_value", currency.FallbackValue, currencyFirst)
+			currencyFirst = false
+		}
+		if currency.Code != "" {
+			dst = appendStringField(dst, "code", currency.Code, currencyFirst)
+			currencyFirst = false
+		}
+		if currency.Amount1000 != 0 {
+			dst = appendIntField(dst, "amount_1000", currency.Amount1000, currencyFirst)
+		}
+		dst = append(dst, '}')
+	}
+	if dateTime := parameter.DateTime; dateTime != nil {
+		dst = appendKey(dst, "date_time", first)
+		first = false
+		dst = dateTime.appendJSON(dst)
+	}
+	for _, media := range [...]struct {
+		key   string
+		media *Media
+	}{
+		{"image", parameter.Image},
+		{"document", parameter.Document},
+		{"video", parameter.Video},
+	} {
+		if media.media != nil {
+			dst = appendKey(dst, media.key, first)
+			first = false
+			dst = media.media.AppendJSON(dst)
+		}
+	}
+
+	return append(dst, '}')
+}
+
+func (dateTime *TemplateDateTime) appendJSON(dst []byte) []byte {
+	dst = append(dst, '{')
+	first := true
+	if dateTime.FallbackValue != "" {
+		dst = appendStringField(dst, "fallback_value", dateTime.FallbackValue, first)
+		first = false
+	}
+	for _, field := range [...]struct {
+		key   string
+		value int
+	}{
+		{"day_of_week", dateTime.DayOfWeek},
+		{"year", dateTime.Year},
+		{"month", dateTime.Month},
+		{"day_of_month", dateTime.DayOfMonth},
+		{"hour", dateTime.Hour},
+		{"minute", dateTime.Minute},
+	} {
+		if field.value != 0 {
+			dst = appendIntField(dst, field.key, field.value, first)
+			first = false
+		}
+	}
+	if dateTime.Calendar != "" {
+		dst = appendStringField(dst, "calendar", dateTime.Calendar, first)
+	}
+
+	return append(dst, '}')
+}
+
+// AppendJSONString appends s to dst as a JSON string, escaped like json.Marshal does.
+func AppendJSONString(dst []byte, s string) []byte {
+	if strings.ContainsAny(s, "\b\f") {
+		// the escaping of these two changed across Go versions, match the running one
+		return appendMarshalString(dst, s)
+	}
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= ' ' && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xf])
+			}
+			i++
+			start = i
+
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+
+	return append(dst, '"')
+}
+
+func appendKey(dst []byte, key string, first bool) []byte {
+	if !first {
+		dst = append(dst, ',')
+	}
+	dst = append(dst, '"')
+	dst = append(dst, key...)
+
+	return append(dst, '"', ':')
+}
+
+func appendStringField(dst []byte, key, value string, first bool) []byte {
+	return AppendJSONString(appendKey(dst, key, first), value)
+}
+
+func appendIntField(dst []byte, key string, value int, first bool) []byte {
+	return strconv.AppendInt(appendKey(dst, key, first), int64(value), 10)
+}
+
+func appendMarshal(dst []byte, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return dst, err //nolint:wrapcheck
+	}
+
+	return append(dst, data...), nil
+}
+
+func appendMarshalString(dst []byte, s string) []byte {
+	data, _ := json.Marshal(s) //nolint:errchkjson // a string
+
+	return append(dst, data...)
+}
+
+// isJSONInteger reports whether s is a JSON integer without sign, like the template
+// component indexes.
+func isJSONInteger(s string) bool {
+	if s == "" || (s[0] == '0' && len(s) > 1) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
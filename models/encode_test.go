@@ -0,0 +1,166 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMessage_AppendJSON(t *testing.T) {
+	t.Parallel()
+	tricky := "<b>\"quoted\" & \\ \n\r\t\x01\x7f é 😀    \xff \b\f</b>"
+	tests := map[string]*Message{
+		"empty": {},
+		"text":  NewMessage("+255700000001", WithText("Your code is 123456")),
+		"text with preview": {
+			Product: "whatsapp", To: "255", Type: "text", PreviewURL: true,
+			Text: &Text{PreviewURL: true, Body: "see https://example.com"},
+		},
+		"empty text": {Type: "text", Text: &Text{}},
+		"tricky strings": {
+			Product: tricky, To: tricky, Context: &Context{MessageID: tricky},
+			Text: &Text{Body: tricky}, BizOpaqueCallbackData: tricky,
+		},
+		"media": {
+			Type:     "image",
+			Image:    &Media{ID: "1", Caption: "caption"},
+			Audio:    &Media{Link: "https://example.com/a.ogg"},
+			Video:    &Media{},
+			Document: &Media{ID: "2", Filename: "a.pdf", Provider: "p"},
+			Sticker:  &Media{ID: "3"},
+		},
+		"template": {
+			Product: "whatsapp", To: "255700000001", RecipientType: "individual", Type: "template",
+			Template: &Template{
+				Name:      "otp",
+				Namespace: "ns",
+				Language:  &TemplateLanguage{Policy: "deterministic", Code: "en_US"},
+				Components: []*TemplateComponent{
+					{Type: "body", Parameters: []*TemplateParameter{
+						{Type: "text", Text: "123456"},
+						{Type: "currency", Currency: &TemplateCurrency{FallbackValue: "$1", Code: "USD", Amount1000: 1000}},
+						{Type: "date_time", DateTime: &TemplateDateTime{
+							FallbackValue: "today", DayOfWeek: 1, Year: 2023, Month: 5, DayOfMonth: 1,
+							Hour: 12, Minute: 30, Calendar: "GREGORIAN",
+						}},
+						{Type: "currency", Currency: &TemplateCurrency{}},
+						nil,
+					}},
+					{Type: "header", Parameters: []*TemplateParameter{
+						{Type: "image", Image: &Media{Link: "https://example.com/a.png"}},
+						{Type: "document", Document: &Media{ID: "1"}},
+						{Type: "video", Video: &Media{ID: "2"}},
+					}},
+					{Type: "button", SubType: "url", Index: "0", Parameters: []*TemplateParameter{
+						{Type: "payload", Payload: "p"},
+					}},
+					{Type: "button", SubType: "quick_reply", Index: "12"},
+					nil,
+				},
+			},
+		},
+		"empty template": {Template: &Template{Language: &TemplateLanguage{}, Components: []*TemplateComponent{}}},
+		"interactive falls back": {
+			Type:        "interactive",
+			Interactive: &Interactive{Type: "button", Body: &InteractiveBody{Text: "Pick one"}},
+		},
+		"location falls back":      {Type: "location", Location: &Location{Latitude: 1.5, Longitude: -2}},
+		"reaction falls back":      {Type: "reaction", Reaction: &Reaction{MessageID: "wamid", Emoji: "👍"}},
+		"invalid index falls back": {Template: &Template{Components: []*TemplateComponent{{Index: "1.5e3"}}}},
+	}
+	for name, message := range tests {
+		name, message := name, message
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			want, err := json.Marshal(message)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			got, err := message.AppendJSON([]byte("prefix"))
+			if err != nil {
+				t.Fatalf("AppendJSON() error = %v", err)
+			}
+			if string(got) != "prefix"+string(want) {
+				t.Errorf("AppendJSON() =\n%s\nwant\n%s", got[len("prefix"):], want)
+			}
+		})
+	}
+
+	invalid := &Message{Template: &Template{Components: []*TemplateComponent{{Index: "one"}}}}
+	if _, err := invalid.AppendJSON(nil); err == nil {
+		t.Errorf("AppendJSON() error = nil, want the error of json.Marshal for an invalid index")
+	}
+}
+
+// TestMessage_AppendJSONFields fails when a field is added to a type encoded by AppendJSON,
+// the encoder must be updated along with the expected number of fields.
+func TestMessage_AppendJSONFields(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		value  any
+		fields int
+	}{
+		{Message{}, 18},
+		{Text{}, 2},
+		{Context{}, 1},
+		{Media{}, 5},
+		{Template{}, 4},
+		{TemplateLanguage{}, 2},
+		{TemplateComponent{}, 4},
+		{TemplateParameter{}, 8},
+		{TemplateCurrency{}, 3},
+		{TemplateDateTime{}, 8},
+	}
+	for _, tt := range tests {
+		if got := reflect.TypeOf(tt.value).NumField(); got != tt.fields {
+			t.Errorf("%T has %d fields, AppendJSON encodes %d", tt.value, got, tt.fields)
+		}
+	}
+}
+
+func BenchmarkMessage_AppendJSON(b *testing.B) {
+	message := NewMessage("+255700000001", WithTemplate(&Template{
+		Name:     "otp",
+		Language: &TemplateLanguage{Code: "en_US"},
+		Components: []*TemplateComponent{
+			{Type: "body", Parameters: []*TemplateParameter{{Type: "text", Text: "123456"}}},
+			{Type: "button", SubType: "url", Index: "0", Parameters: []*TemplateParameter{{Type: "text", Text: "123456"}}},
+		},
+	}))
+	b.Run("json.Marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(message); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("AppendJSON", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := make([]byte, 0, 512)
+		for i := 0; i < b.N; i++ {
+			if _, err := message.AppendJSON(buf[:0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Language codes supported by message templates.
+const (
+	LanguageAfrikaans          = "af"
+	LanguageAlbanian           = "sq"
+	LanguageArabic             = "ar"
+	LanguageAzerbaijani        = "az"
+	LanguageBengali            = "bn"
+	LanguageBulgarian          = "bg"
+	LanguageCatalan            = "ca"
+	LanguageChineseChina       = "zh_CN"
+	LanguageChineseHongKong    = "zh_HK"
+	LanguageChineseTaiwan      = "zh_TW"
+	LanguageCroatian           = "hr"
+	LanguageCzech              = "cs"
+	LanguageDanish             = "da"
+	LanguageDutch              = "nl"
+	LanguageEnglish            = "en"
+	LanguageEnglishUK          = "en_GB"
+	LanguageEnglishUS          = "en_US"
+	LanguageEstonian           = "et"
+	LanguageFilipino           = "fil"
+	LanguageFinnish            = "fi"
+	LanguageFrench             = "fr"
+	LanguageGeorgian           = "ka"
+	LanguageGerman             = "de"
+	LanguageGreek              = "el"
+	LanguageGujarati           = "gu"
+	LanguageHausa              = "ha"
+	LanguageHebrew             = "he"
+	LanguageHindi              = "hi"
+	LanguageHungarian          = "hu"
+	LanguageIndonesian         = "id"
+	LanguageIrish              = "ga"
+	LanguageItalian            = "it"
+	LanguageJapanese           = "ja"
+	LanguageKannada            = "kn"
+	LanguageKazakh             = "kk"
+	LanguageKinyarwanda        = "rw_RW"
+	LanguageKorean             = "ko"
+	LanguageKyrgyz             = "ky_KG"
+	LanguageLao                = "lo"
+	LanguageLatvian            = "lv"
+	LanguageLithuanian         = "lt"
+	LanguageMacedonian         = "mk"
+	LanguageMalay              = "ms"
+	LanguageMalayalam          = "ml"
+	LanguageMarathi            = "mr"
+	LanguageNorwegian          = "nb"
+	LanguagePersian            = "fa"
+	LanguagePolish             = "pl"
+	LanguagePortugueseBrazil   = "pt_BR"
+	LanguagePortuguesePortugal = "pt_PT"
+	LanguagePunjabi            = "pa"
+	LanguageRomanian           = "ro"
+	LanguageRussian            = "ru"
+	LanguageSerbian            = "sr"
+	LanguageSlovak             = "sk"
+	LanguageSlovenian          = "sl"
+	LanguageSpanish            = "es"
+	LanguageSpanishArgentina   = "es_AR"
+	LanguageSpanishSpain       = "es_ES"
+	LanguageSpanishMexico      = "es_MX"
+	LanguageSwahili            = "sw"
+	LanguageSwedish            = "sv"
+	LanguageTamil              = "ta"
+	LanguageTelugu             = "te"
+	LanguageThai               = "th"
+	LanguageTurkish            = "tr"
+	LanguageUkrainian          = "uk"
+	LanguageUrdu               = "ur"
+	LanguageUzbek              = "uz"
+	LanguageVietnamese         = "vi"
+	LanguageZulu               = "zu"
+)
+
+// ErrInvalidLanguageCode is returned for language codes that templates do not support.
+var ErrInvalidLanguageCode = errors.New("invalid template language code")
+
+// supportedLanguages is the set of the Language constants.
+var supportedLanguages = map[string]bool{ //nolint:gochecknoglobals
+	LanguageAfrikaans: true, LanguageAlbanian: true, LanguageArabic: true, LanguageAzerbaijani: true,
+	LanguageBengali: true, LanguageBulgarian: true, LanguageCatalan: true, LanguageChineseChina: true,
+	LanguageChineseHongKong: true, LanguageChineseTaiwan: true, LanguageCroatian: true, LanguageCzech: true,
+	LanguageDanish: true, LanguageDutch: true, LanguageEnglish: true, LanguageEnglishUK: true,
+	LanguageEnglishUS: true, LanguageEstonian: true, LanguageFilipino: true, LanguageFinnish: true,
+	LanguageFrench: true, LanguageGeorgian: true, LanguageGerman: true, LanguageGreek: true,
+	LanguageGujarati: true, LanguageHausa: true, LanguageHebrew: true, LanguageHindi: true,
+	LanguageHungarian: true, LanguageIndonesian: true, LanguageIrish: true, LanguageItalian: true,
+	LanguageJapanese: true, LanguageKannada: true, LanguageKazakh: true, LanguageKinyarwanda: true,
+	LanguageKorean: true, LanguageKyrgyz: true, LanguageLao: true, LanguageLatvian: true,
+	LanguageLithuanian: true, LanguageMacedonian: true, LanguageMalay: true, LanguageMalayalam: true,
+	LanguageMarathi: true, LanguageNorwegian: true, LanguagePersian: true, LanguagePolish: true,
+	LanguagePortugueseBrazil: true, LanguagePortuguesePortugal: true, LanguagePunjabi: true,
+	LanguageRomanian: true, LanguageRussian: true, LanguageSerbian: true, LanguageSlovak: true,
+	LanguageSlovenian: true, LanguageSpanish: true, LanguageSpanishArgentina: true, LanguageSpanishSpain: true,
+	LanguageSpanishMexico: true, LanguageSwahili: true, LanguageSwedish: true, LanguageTamil: true,
+	LanguageTelugu: true, LanguageThai: true, LanguageTurkish: true, LanguageUkrainian: true,
+	LanguageUrdu: true, LanguageUzbek: true, LanguageVietnamese: true, LanguageZulu: true,
+}
+
+// languageAliases maps the BCP-47 tags that have no direct template language code: the
+// deprecated and macrolanguage tags, the Chinese scripts and the languages only supported
+// in one region.
+var languageAliases = map[string]string{ //nolint:gochecknoglobals
+	"iw":      LanguageHebrew,
+	"in":      LanguageIndonesian,
+	"tl":      LanguageFilipino,
+	"no":      LanguageNorwegian,
+	"nb_NO":   LanguageNorwegian,
+	"zh":      LanguageChineseChina,
+	"zh_Hans": LanguageChineseChina,
+	"zh_Hant": LanguageChineseTaiwan,
+	"zh_SG":   LanguageChineseChina,
+	"zh_MO":   LanguageChineseHongKong,
+	"pt":      LanguagePortugueseBrazil,
+	"rw":      LanguageKinyarwanda,
+	"ky":      LanguageKyrgyz,
+}
+
+// SupportedLanguages returns the sorted language codes supported by templates.
+func SupportedLanguages() []string {
+	codes := make([]string, 0, len(supportedLanguages))
+	for code := range supportedLanguages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+// IsSupportedLanguage reports whether code is a template language code, for example en_US.
+func IsSupportedLanguage(code string) bool {
+	return supportedLanguages[code]
+}
+
+// ValidateLanguageCode returns an error wrapping ErrInvalidLanguageCode when code is not a
+// template language code. The error suggests the code to use for BCP-47 tags like en-GB.
+func ValidateLanguageCode(code string) error {
+	if IsSupportedLanguage(code) {
+		return nil
+	}
+	if suggestion, err := LanguageCodeFromTag(code); err == nil {
+		return fmt.Errorf("%w: %q, use %q", ErrInvalidLanguageCode, code, suggestion)
+	}
+
+	return fmt.Errorf("%w: %q", ErrInvalidLanguageCode, code)
+}
+
+// LanguageCodeFromTag returns the template language code of the BCP-47 tag, for example
+// en-GB becomes en_GB and zh-Hant-TW zh_TW. Regions without their own code fall back to the
+// language, en-AU becomes en.
+func LanguageCodeFromTag(tag string) (string, error) {
+	subtags := strings.FieldsFunc(tag, func(r rune) bool { return r == '-' || r == '_' })
+	if len(subtags) == 0 {
+		return "", fmt.Errorf("%w: empty language tag", ErrInvalidLanguageCode)
+	}
+
+	language := strings.ToLower(subtags[0])
+	var script, region string
+	for _, subtag := range subtags[1:] {
+		switch {
+		case len(subtag) == 4 && script == "" && region == "": //nolint:gomnd
+			script = strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		case (len(subtag) == 2 || len(subtag) == 3) && region == "": //nolint:gomnd
+			region = strings.ToUpper(subtag)
+		}
+	}
+
+	candidates := []string{language}
+	if script != "" {
+		candidates = append([]string{language + "_" + script}, candidates...)
+	}
+	if region != "" {
+		candidates = append([]string{language + "_" + region}, candidates...)
+	}
+	for _, candidate := range candidates {
+		if IsSupportedLanguage(candidate) {
+			return candidate, nil
+		}
+		if code, ok := languageAliases[candidate]; ok {
+			return code, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: no template language for %q", ErrInvalidLanguageCode, tag)
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLanguageCodeFromTag(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: "en-GB", want: LanguageEnglishUK},
+		{tag: "en_us", want: LanguageEnglishUS},
+		{tag: "en-AU", want: LanguageEnglish},
+		{tag: "sw-TZ", want: LanguageSwahili},
+		{tag: "fil", want: LanguageFilipino},
+		{tag: "zh-Hant-TW", want: LanguageChineseTaiwan},
+		{tag: "zh-Hant", want: LanguageChineseTaiwan},
+		{tag: "zh-Hans-CN", want: LanguageChineseChina},
+		{tag: "pt", want: LanguagePortugueseBrazil},
+		{tag: "pt-PT", want: LanguagePortuguesePortugal},
+		{tag: "iw-IL", want: LanguageHebrew},
+		{tag: "rw", want: LanguageKinyarwanda},
+		{tag: "xx-YY", wantErr: true},
+		{tag: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.tag, func(t *testing.T) {
+			t.Parallel()
+			got, err := LanguageCodeFromTag(tt.tag)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLanguageCode) {
+					t.Errorf("LanguageCodeFromTag(%q) error = %v, want %v", tt.tag, err, ErrInvalidLanguageCode)
+				}
+
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("LanguageCodeFromTag(%q) = %q, %v, want %q", tt.tag, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLanguageCode(t *testing.T) {
+	t.Parallel()
+	for _, code := range SupportedLanguages() {
+		if err := ValidateLanguageCode(code); err != nil {
+			t.Errorf("ValidateLanguageCode(%q) error = %v", code, err)
+		}
+	}
+	for _, code := range []string{"", "en-US", "EN_us", "klingon"} {
+		if err := ValidateLanguageCode(code); !errors.Is(err, ErrInvalidLanguageCode) {
+			t.Errorf("ValidateLanguageCode(%q) error = %v, want %v", code, err, ErrInvalidLanguageCode)
+		}
+	}
+}
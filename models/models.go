@@ -42,11 +42,14 @@ type (
 		Body       string `json:"body,omitempty"`
 	}
 
+	// Location is a location. URL is only set on the locations received from customers,
+	// for the places picked from the map.
 	Location struct {
 		Longitude float64 `json:"longitude"`
 		Latitude  float64 `json:"latitude"`
 		Name      string  `json:"name"`
 		Address   string  `json:"address"`
+		URL       string  `json:"url,omitempty"`
 	}
 
 	Address struct {
@@ -226,7 +229,7 @@ type (
 
 	// TemplateDateTime contains information about a date_time parameter.
 	// FallbackValue, fallback_value. Required. Default text if localization fails.
-	// DayOfWeek, day_of_week. Required. Day of the week, where 0 is Sunday and 6 is Saturday.
+	// DayOfWeek, day_of_week. Required. Day of the week, where 1 is Monday and 7 is Sunday.
 	// Year, year. Required. Year.
 	// Month, month. Required. Month, where 1 is January and 12 is December.
 	// DayOfMonth, day_of_month. Required. Day of the month.
@@ -592,6 +595,9 @@ type (
 		Location      *Location    `json:"location,omitempty"`
 		Contacts      Contacts     `json:"contacts,omitempty"`
 		Interactive   *Interactive `json:"interactive,omitempty"`
+
+		// BizOpaqueCallbackData is returned in the status webhooks of the message.
+		BizOpaqueCallbackData string `json:"biz_opaque_callback_data,omitempty"`
 	}
 
 	MessageOption func(*Message)
@@ -875,6 +881,27 @@ func WithTemplate(template *Template) MessageOption {
 	}
 }
 
+// WithText sets the text of a text message.
+func WithText(body string) MessageOption {
+	return func(m *Message) {
+		m.Type = "text"
+		if m.Text == nil {
+			m.Text = &Text{}
+		}
+		m.Text.Body = body
+	}
+}
+
+// WithPreviewURL renders a preview of the first URL in the text of a text message.
+func WithPreviewURL() MessageOption {
+	return func(m *Message) {
+		if m.Text == nil {
+			m.Text = &Text{}
+		}
+		m.Text.PreviewURL = true
+	}
+}
+
 // SetTemplate sets the template of the message.
 func (m *Message) SetTemplate(template *Template) {
 	m.Type = "template"
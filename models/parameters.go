@@ -0,0 +1,209 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of template parameters.
+const (
+	TemplateParameterText     = "text"
+	TemplateParameterCurrency = "currency"
+	TemplateParameterDateTime = "date_time"
+	TemplateParameterPayload  = "payload"
+)
+
+// CalendarGregorian is the only calendar supported by date_time parameters.
+const CalendarGregorian = "GREGORIAN"
+
+// currencyExponents are the ISO 4217 minor units of the currencies that do not have 2.
+var currencyExponents = map[string]int{ //nolint:gochecknoglobals
+	"BHD": 3, "CLP": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "LYD": 3,
+	"OMR": 3, "PYG": 0, "RWF": 0, "TND": 3, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+}
+
+// currencySymbols are the symbols used in the fallback values, other currencies are written
+// with their code.
+var currencySymbols = map[string]string{ //nolint:gochecknoglobals
+	"BRL": "R$", "EUR": "€", "GBP": "£", "IDR": "Rp", "INR": "₹", "JPY": "¥", "NGN": "₦",
+	"USD": "$", "ZAR": "R",
+}
+
+// commaDecimalLanguages are the languages that write 1.234,56 rather than 1,234.56 and put the
+// currency after the amount.
+var commaDecimalLanguages = map[string]bool{ //nolint:gochecknoglobals
+	"de": true, "es": true, "fr": true, "id": true, "it": true, "nl": true, "pt": true,
+	"ru": true, "tr": true, "sv": true, "da": true, "nb": true, "fi": true, "pl": true,
+}
+
+// CurrencyExponent returns the number of decimal digits of the minor unit of the currency
+// with the ISO 4217 code, e.g. 2 for USD (cents) and 0 for JPY.
+func CurrencyExponent(code string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(code)]; ok {
+		return exponent
+	}
+
+	return 2 //nolint:gomnd
+}
+
+// Amount1000 converts an amount in the currency unit to the amount_1000 of a currency
+// parameter, rounding to the nearest integer: 12.34 is 12340.
+func Amount1000(amount float64) int {
+	return int(math.Round(amount * 1000)) //nolint:gomnd
+}
+
+// Amount1000FromMinor converts an amount in the minor unit of the currency, e.g. cents, to
+// the amount_1000 of a currency parameter: 1234 USD cents is 12340.
+func Amount1000FromMinor(minor int64, code string) int {
+	exponent := CurrencyExponent(code)
+	if exponent >= 3 { //nolint:gomnd
+		return int(minor)
+	}
+
+	return int(minor * int64(math.Pow10(3-exponent)))
+}
+
+// FormatCurrency formats amount1000 in the currency with the ISO 4217 code for the template
+// language locale, e.g. "$12.34" for en_US and "12,34 €" for de. It is used as the fallback
+// value of currency parameters.
+func FormatCurrency(amount1000 int, code, locale string) string {
+	code = strings.ToUpper(code)
+	exponent := CurrencyExponent(code)
+	value := float64(amount1000) / 1000 //nolint:gomnd
+	number := strconv.FormatFloat(math.Abs(value), 'f', exponent, 64)
+
+	integer, fraction, _ := strings.Cut(number, ".")
+	groupSep, decimalSep := ",", "."
+	comma := commaDecimalLanguages[languageOf(locale)]
+	if comma {
+		groupSep, decimalSep = ".", ","
+	}
+	formatted := groupDigits(integer, groupSep)
+	if fraction != "" {
+		formatted += decimalSep + fraction
+	}
+	sign := ""
+	if value < 0 {
+		sign = "-"
+	}
+
+	symbol, ok := currencySymbols[code]
+	switch {
+	case !ok:
+		return sign + formatted + " " + code
+	case comma:
+		return sign + formatted + " " + symbol
+	default:
+		return sign + symbol + formatted
+	}
+}
+
+// NewCurrencyParameter returns a currency parameter of amount, in the currency unit, with
+// the fallback value formatted for the template language locale.
+func NewCurrencyParameter(amount float64, code, locale string) *TemplateParameter {
+	amount1000 := Amount1000(amount)
+
+	return &TemplateParameter{
+		Type: TemplateParameterCurrency,
+		Currency: &TemplateCurrency{
+			FallbackValue: FormatCurrency(amount1000, code, locale),
+			Code:          strings.ToUpper(code),
+			Amount1000:    amount1000,
+		},
+	}
+}
+
+// FormatDateTime formats t for the template language locale, e.g. "February 25, 1977 3:33 PM"
+// for en_US and "25.02.1977 15:33" for de. It is used as the fallback value of date_time
+// parameters. English locales spell the month, the others use digits.
+func FormatDateTime(t time.Time, locale string) string {
+	switch language := languageOf(locale); {
+	case locale == LanguageEnglishUS || locale == LanguageEnglish:
+		return t.Format("January 2, 2006 3:04 PM")
+	case language == LanguageEnglish:
+		return t.Format("2 January 2006 15:04")
+	case language == LanguageGerman || language == LanguageRussian || language == LanguageTurkish ||
+		language == LanguagePolish || language == LanguageFinnish:
+		return t.Format("02.01.2006 15:04")
+	case commaDecimalLanguages[language] || language == LanguageHindi:
+		return t.Format("02/01/2006 15:04")
+	default:
+		return t.Format("2006-01-02 15:04")
+	}
+}
+
+// NewDateTimeParameter returns a date_time parameter of t, in its location, with the fallback
+// value formatted for the template language locale.
+func NewDateTimeParameter(t time.Time, locale string) *TemplateParameter {
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7 // Sunday
+	}
+
+	return &TemplateParameter{
+		Type: TemplateParameterDateTime,
+		DateTime: &TemplateDateTime{
+			FallbackValue: FormatDateTime(t, locale),
+			DayOfWeek:     weekday,
+			Year:          t.Year(),
+			Month:         int(t.Month()),
+			DayOfMonth:    t.Day(),
+			Hour:          t.Hour(),
+			Minute:        t.Minute(),
+			Calendar:      CalendarGregorian,
+		},
+	}
+}
+
+// NewTextParameter returns a text parameter.
+func NewTextParameter(text string) *TemplateParameter {
+	return &TemplateParameter{Type: TemplateParameterText, Text: text}
+}
+
+// languageOf returns the language of a template language code, e.g. "pt" for "pt_BR".
+func languageOf(locale string) string {
+	language, _, _ := strings.Cut(locale, "_")
+
+	return strings.ToLower(language)
+}
+
+// groupDigits inserts sep between the groups of 3 digits of integer.
+func groupDigits(integer, sep string) string {
+	if len(integer) <= 3 { //nolint:gomnd
+		return integer
+	}
+	var b strings.Builder
+	head := len(integer) % 3 //nolint:gomnd
+	if head > 0 {
+		b.WriteString(integer[:head])
+	}
+	for i := head; i < len(integer); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(integer[i : i+3])
+	}
+
+	return b.String()
+}
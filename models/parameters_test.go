@@ -0,0 +1,152 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAmount1000(t *testing.T) {
+	t.Parallel()
+	if got := Amount1000(12.34); got != 12340 {
+		t.Errorf("Amount1000(12.34) = %d, want 12340", got)
+	}
+	if got := Amount1000(0.1 + 0.2); got != 300 {
+		t.Errorf("Amount1000(0.1+0.2) = %d, want 300", got)
+	}
+
+	tests := []struct {
+		minor int64
+		code  string
+		want  int
+	}{
+		{minor: 1234, code: "USD", want: 12340},
+		{minor: 1500, code: "jpy", want: 1500000},
+		{minor: 1234, code: "KWD", want: 1234},
+	}
+	for _, tt := range tests {
+		if got := Amount1000FromMinor(tt.minor, tt.code); got != tt.want {
+			t.Errorf("Amount1000FromMinor(%d, %s) = %d, want %d", tt.minor, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		amount1000 int
+		code       string
+		locale     string
+		want       string
+	}{
+		{name: "dollars", amount1000: 1234560, code: "USD", locale: LanguageEnglishUS, want: "$1,234.56"},
+		{name: "euros in german", amount1000: 1234560, code: "EUR", locale: LanguageGerman, want: "1.234,56 €"},
+		{name: "reais", amount1000: 99900, code: "BRL", locale: LanguagePortugueseBrazil, want: "99,90 R$"},
+		{name: "yen", amount1000: 1500000, code: "JPY", locale: LanguageEnglish, want: "¥1,500"},
+		{name: "dinars", amount1000: 1234, code: "KWD", locale: LanguageEnglish, want: "1.234 KWD"},
+		{name: "shillings", amount1000: 25000000, code: "tzs", locale: LanguageSwahili, want: "25,000.00 TZS"},
+		{name: "refund", amount1000: -5000, code: "GBP", locale: LanguageEnglishUK, want: "-£5.00"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := FormatCurrency(tt.amount1000, tt.code, tt.locale); got != tt.want {
+				t.Errorf("FormatCurrency() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCurrencyParameter(t *testing.T) {
+	t.Parallel()
+	parameter := NewCurrencyParameter(19.99, "usd", LanguageEnglishUS)
+	got, _ := json.Marshal(parameter)
+	want := `{"type":"currency","currency":{"fallback_value":"$19.99","code":"USD","amount_1000":19990}}`
+	if string(got) != want {
+		t.Errorf("NewCurrencyParameter() = %s, want %s", got, want)
+	}
+}
+
+func TestNewDateTimeParameter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		time     time.Time
+		locale   string
+		fallback string
+		weekday  int
+	}{
+		{
+			name:     "us english",
+			time:     time.Date(1977, 2, 25, 15, 33, 0, 0, time.UTC),
+			locale:   LanguageEnglishUS,
+			fallback: "February 25, 1977 3:33 PM",
+			weekday:  5,
+		},
+		{
+			name:     "british english on a sunday",
+			time:     time.Date(2023, 5, 7, 9, 5, 0, 0, time.UTC),
+			locale:   LanguageEnglishUK,
+			fallback: "7 May 2023 09:05",
+			weekday:  7,
+		},
+		{
+			name:     "german",
+			time:     time.Date(2023, 5, 7, 9, 5, 0, 0, time.UTC),
+			locale:   LanguageGerman,
+			fallback: "07.05.2023 09:05",
+			weekday:  7,
+		},
+		{
+			name:     "french",
+			time:     time.Date(2023, 5, 8, 18, 0, 0, 0, time.UTC),
+			locale:   LanguageFrench,
+			fallback: "08/05/2023 18:00",
+			weekday:  1,
+		},
+		{
+			name:     "japanese",
+			time:     time.Date(2023, 5, 8, 18, 0, 0, 0, time.UTC),
+			locale:   LanguageJapanese,
+			fallback: "2023-05-08 18:00",
+			weekday:  1,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			parameter := NewDateTimeParameter(tt.time, tt.locale)
+			if parameter.Type != TemplateParameterDateTime {
+				t.Errorf("Type = %q, want %q", parameter.Type, TemplateParameterDateTime)
+			}
+			dt := parameter.DateTime
+			if dt.FallbackValue != tt.fallback || dt.DayOfWeek != tt.weekday ||
+				dt.Year != tt.time.Year() || dt.Month != int(tt.time.Month()) || dt.DayOfMonth != tt.time.Day() ||
+				dt.Hour != tt.time.Hour() || dt.Minute != tt.time.Minute() || dt.Calendar != CalendarGregorian {
+				t.Errorf("NewDateTimeParameter() = %+v, want fallback %q and day of week %d", dt, tt.fallback, tt.weekday)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package models
+
+// Statuses of an order_details payment, reported by the payment status webhooks and by the
+// payment status endpoint.
+const (
+	PaymentStatusPending  = "pending"
+	PaymentStatusCaptured = "captured"
+	PaymentStatusFailed   = "failed"
+)
+
+// Statuses of a single payment transaction.
+const (
+	TransactionStatusPending = "pending"
+	TransactionStatusSuccess = "success"
+	TransactionStatusFailed  = "failed"
+)
+
+type (
+	// PaymentAmount is an amount of money, Value divided by Offset gives the amount in the
+	// currency, e.g. {Value: 21000, Offset: 100} is 210.00.
+	PaymentAmount struct {
+		Value  int64 `json:"value"`
+		Offset int64 `json:"offset"`
+	}
+
+	PaymentMethod struct {
+		Type string `json:"type"`
+	}
+
+	PaymentError struct {
+		Code   string `json:"code"`
+		Reason string `json:"reason"`
+	}
+
+	// PaymentTransaction is an attempt by the customer to pay an order, a payment can have
+	// several of them when the first ones fail.
+	PaymentTransaction struct {
+		ID               string         `json:"id"`
+		Type             string         `json:"type"`
+		Status           string         `json:"status"`
+		CreatedTimestamp int64          `json:"created_timestamp,omitempty"`
+		UpdatedTimestamp int64          `json:"updated_timestamp,omitempty"`
+		Amount           *PaymentAmount `json:"amount,omitempty"`
+		Currency         string         `json:"currency,omitempty"`
+		Method           *PaymentMethod `json:"method,omitempty"`
+		Error            *PaymentError  `json:"error,omitempty"`
+	}
+
+	PaymentRefund struct {
+		ID               string         `json:"id"`
+		Amount           *PaymentAmount `json:"amount,omitempty"`
+		Currency         string         `json:"currency,omitempty"`
+		Status           string         `json:"status"`
+		CreatedTimestamp int64          `json:"created_timestamp,omitempty"`
+		UpdatedTimestamp int64          `json:"updated_timestamp,omitempty"`
+	}
+)
+
+// Float returns the amount in the currency unit.
+func (amount *PaymentAmount) Float() float64 {
+	if amount == nil || amount.Offset == 0 {
+		return 0
+	}
+
+	return float64(amount.Value) / float64(amount.Offset)
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// RecipientTypeIndividual is the default recipient type of the messages.
+const RecipientTypeIndividual = individualRecipientType
+
+type (
+	// SendOption sets an uncommon field of a message for a single call of a send method,
+	// for example WithReplyTo. The options are applied to the encoded payload of the message,
+	// after the fields set from the request struct, so they take precedence.
+	SendOption func(*sendOptions)
+
+	sendOptions struct {
+		replyTo       string
+		previewURL    bool
+		ttl           time.Duration
+		callbackData  string
+		recipientType string
+		from          string
+	}
+)
+
+// WithReplyTo sends the message as a reply to the message with the given ID, the recipient
+// sees it quoted above the new message.
+func WithReplyTo(messageID string) SendOption {
+	return func(options *sendOptions) {
+		options.replyTo = messageID
+	}
+}
+
+// WithPreviewURL renders a preview of the first URL in the body of a text message.
+func WithPreviewURL() SendOption {
+	return func(options *sendOptions) {
+		options.previewURL = true
+	}
+}
+
+// WithTTL bounds the time the call may take to send the message, including the waits for
+// the rate limiter and the retries. A message that could not be sent within ttl is dropped
+// and the call fails with context.DeadlineExceeded, use it for messages that are worthless
+// when late, like one-time passwords.
+func WithTTL(ttl time.Duration) SendOption {
+	return func(options *sendOptions) {
+		options.ttl = ttl
+	}
+}
+
+// WithCallbackData sets the biz_opaque_callback_data of the message, an arbitrary string
+// of up to 512 characters that is returned in the status webhooks of the message.
+func WithCallbackData(data string) SendOption {
+	return func(options *sendOptions) {
+		options.callbackData = data
+	}
+}
+
+// WithRecipientTypeOverride replaces the recipient type of the message, RecipientTypeIndividual
+// by default.
+func WithRecipientTypeOverride(recipientType string) SendOption {
+	return func(options *sendOptions) {
+		options.recipientType = recipientType
+	}
+}
+
+// newSendOptions applies options, it returns nil when there are none.
+func newSendOptions(options []SendOption) *sendOptions {
+	if len(options) == 0 {
+		return nil
+	}
+	opts := &sendOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
+	return opts
+}
+
+// context returns ctx bounded by the TTL of the message, sending from the number selected
+// with FromNumber.
+func (opts *sendOptions) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if opts == nil {
+		return ctx, func() {}
+	}
+	if opts.from != "" {
+		ctx = WithOverrides(ctx, &Overrides{PhoneNumberID: opts.from})
+	}
+	if opts.ttl <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, opts.ttl)
+}
+
+// sender returns a sender that applies the options to the payloads sent through next.
+func (opts *sendOptions) sender(next whttp.Sender) whttp.Sender {
+	if opts == nil || !opts.patchesPayload() {
+		return next
+	}
+
+	return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+		patched, err := opts.apply(request)
+		if err != nil {
+			return err
+		}
+
+		return next.Send(ctx, patched, v)
+	})
+}
+
+func (opts *sendOptions) patchesPayload() bool {
+	return opts.replyTo != "" || opts.previewURL || opts.callbackData != "" || opts.recipientType != ""
+}
+
+// apply returns a copy of request whose payload has the fields set by the options.
+func (opts *sendOptions) apply(request *whttp.Request) (*whttp.Request, error) {
+	body, err := request.BodyBytes()
+	if err != nil {
+		return nil, fmt.Errorf("send options: %w", err)
+	}
+	var payload map[string]json.RawMessage
+	if err = json.Unmarshal(body, &payload); err != nil || payload == nil {
+		return nil, fmt.Errorf("send options: %w: payload is not a JSON object", ErrBadRequestFormat)
+	}
+
+	set := func(key string, value any) {
+		payload[key], _ = json.Marshal(value) //nolint:errchkjson // strings and maps of strings
+	}
+	if opts.replyTo != "" {
+		set("context", map[string]string{"message_id": opts.replyTo})
+	}
+	if opts.callbackData != "" {
+		set("biz_opaque_callback_data", opts.callbackData)
+	}
+	if opts.recipientType != "" {
+		set("recipient_type", opts.recipientType)
+	}
+	if opts.previewURL {
+		var text map[string]any
+		if err = json.Unmarshal(payload["text"], &text); err != nil || text == nil {
+			return nil, fmt.Errorf("send options: %w: preview url is only supported by text messages",
+				ErrBadRequestFormat)
+		}
+		text["preview_url"] = true
+		set("text", text)
+	}
+
+	if body, err = json.Marshal(payload); err != nil {
+		return nil, fmt.Errorf("send options: %w", err)
+	}
+	patched := *request
+	patched.Payload = body
+
+	return &patched, nil
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestSendOptions(t *testing.T) {
+	t.Parallel()
+	payloads := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		payloads <- payload
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithAccessToken("token"))
+
+	tests := []struct {
+		name string
+		send func(options ...SendOption) error
+	}{
+		{
+			name: "text message",
+			send: func(options ...SendOption) error {
+				_, err := client.SendTextMessage(context.TODO(), "255700000000",
+					&TextMessage{Message: "https://example.com"}, options...)
+
+				return err
+			},
+		},
+		{
+			name: "reply",
+			send: func(options ...SendOption) error {
+				_, err := client.Reply(context.TODO(), "255700000000", &ReplyMessage{
+					Context: "wamid.0",
+					Type:    MessageType("text"),
+					Content: &models.Text{Body: "https://example.com"},
+				}, options...)
+
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests { //nolint:paralleltest // the subtests share the server
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.send(WithReplyTo("wamid.2"), WithCallbackData("order-1"),
+				WithRecipientTypeOverride("group"), WithPreviewURL())
+			if err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			payload := <-payloads
+			if got := payload["context"].(map[string]any)["message_id"]; got != "wamid.2" {
+				t.Errorf("context.message_id = %v, want wamid.2", got)
+			}
+			if got := payload["biz_opaque_callback_data"]; got != "order-1" {
+				t.Errorf("biz_opaque_callback_data = %v, want order-1", got)
+			}
+			if got := payload["recipient_type"]; got != "group" {
+				t.Errorf("recipient_type = %v, want group", got)
+			}
+			if got := payload["text"].(map[string]any)["preview_url"]; got != true {
+				t.Errorf("text.preview_url = %v, want true", got)
+			}
+		})
+	}
+}
+
+func TestSendOptionsTTL(t *testing.T) {
+	t.Parallel()
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithAccessToken("token"))
+
+	_, err := client.SendText(context.TODO(), "255700000000", "Your code is 123456", WithTTL(50*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SendText() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrDeadLetterNotFound = errors.New("outbox: dead letter not found")
+
+type (
+	// DeadLetter is an entry that exhausted its retries or failed with a permanent error.
+	// Entry holds the original message and the attempt history, Err is the error of the
+	// last attempt with its chain intact, it is only available in the process that failed
+	// the entry, stores keep Entry.History instead.
+	DeadLetter struct {
+		Entry    *Entry    `json:"entry"`
+		Err      error     `json:"-"`
+		FailedAt time.Time `json:"failed_at"`
+	}
+
+	// DeadLetterQueue keeps dead letters for operators to inspect and requeue with
+	// Outbox.Requeue. Implementations must be safe for concurrent use.
+	DeadLetterQueue interface {
+		Put(ctx context.Context, letter *DeadLetter) error
+		Get(ctx context.Context, id string) (*DeadLetter, error)
+		List(ctx context.Context) ([]*DeadLetter, error)
+		Remove(ctx context.Context, id string) error
+	}
+
+	// MemoryDeadLetterQueue is an in-memory DeadLetterQueue.
+	MemoryDeadLetterQueue struct {
+		mu      sync.Mutex
+		letters map[string]*DeadLetter
+	}
+)
+
+// NewMemoryDeadLetterQueue returns an empty MemoryDeadLetterQueue.
+func NewMemoryDeadLetterQueue() *MemoryDeadLetterQueue {
+	return &MemoryDeadLetterQueue{letters: make(map[string]*DeadLetter)}
+}
+
+func (queue *MemoryDeadLetterQueue) Put(_ context.Context, letter *DeadLetter) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	queue.letters[letter.Entry.ID] = letter
+
+	return nil
+}
+
+func (queue *MemoryDeadLetterQueue) Get(_ context.Context, id string) (*DeadLetter, error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	letter, ok := queue.letters[id]
+	if !ok {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return letter, nil
+}
+
+// List returns the dead letters, oldest first.
+func (queue *MemoryDeadLetterQueue) List(_ context.Context) ([]*DeadLetter, error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	letters := make([]*DeadLetter, 0, len(queue.letters))
+	for _, letter := range queue.letters {
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].FailedAt.Before(letters[j].FailedAt)
+	})
+
+	return letters, nil
+}
+
+func (queue *MemoryDeadLetterQueue) Remove(_ context.Context, id string) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if _, ok := queue.letters[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	delete(queue.letters, id)
+
+	return nil
+}
+
+// Requeue moves the dead letter with the given entry ID back to the outbox, it is sent
+// again with a fresh attempt budget. The attempt history is kept.
+func (box *Outbox) Requeue(ctx context.Context, id string) error {
+	if box.config.DeadLetters == nil {
+		return fmt.Errorf("outbox requeue %s: %w", id, ErrDeadLetterNotFound)
+	}
+	letter, err := box.config.DeadLetters.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("outbox requeue %s: %w", id, err)
+	}
+
+	entry := *letter.Entry
+	entry.Status = StatusPending
+	entry.Attempts = 0
+	entry.NextAttemptAt = box.config.Clock.Now()
+	entry.UpdatedAt = entry.NextAttemptAt
+	if err = box.store.Update(ctx, &entry); err != nil {
+		return fmt.Errorf("outbox requeue %s: %w", id, err)
+	}
+	box.notify()
+	if err = box.config.DeadLetters.Remove(ctx, id); err != nil {
+		return fmt.Errorf("outbox requeue %s: %w", id, err)
+	}
+
+	return nil
+}
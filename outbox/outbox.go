@@ -0,0 +1,426 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package outbox implements a transactional outbox for WhatsApp messages. Messages are
+// written to a Store by Enqueue and sent by a dispatcher that drains the store through the
+// client, retrying failed sends and recording their results. As long as the Store is
+// durable, a message enqueued before a crash is sent after the restart: delivery to the
+// API is at least once.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"time"
+
+	"github.com/SeamPay/whatsapp"
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+const (
+	DefaultMaxAttempts  = 5
+	DefaultBatchSize    = 50
+	DefaultPollInterval = time.Second
+	DefaultLease        = time.Minute
+	DefaultBaseBackoff  = time.Second
+	DefaultMaxBackoff   = 5 * time.Minute
+	DefaultJitter       = 0.2
+
+	DefaultMaxRedeliveries = 3
+)
+
+const (
+	StatusPending Status = "pending"
+	StatusSent    Status = "sent"
+	StatusFailed  Status = "failed"
+)
+
+var ErrNilMessage = errors.New("outbox: nil message")
+
+type (
+	// Status is the delivery status of an Entry.
+	Status string
+
+	// Entry is a message in the outbox.
+	//
+	// A pending entry is sent once NextAttemptAt is reached. MessageID is the ID (wamid)
+	// returned by the API once the message is sent, and LastError the error of the last
+	// failed attempt. The entries created by Redeliver have the ID of the first entry in
+	// RedeliveryOf and their number in Redeliveries.
+	Entry struct {
+		ID            string          `json:"id"`
+		Message       *models.Message `json:"message"`
+		Status        Status          `json:"status"`
+		Attempts      int             `json:"attempts"`
+		NextAttemptAt time.Time       `json:"next_attempt_at"`
+		CreatedAt     time.Time       `json:"created_at"`
+		UpdatedAt     time.Time       `json:"updated_at"`
+		MessageID     string          `json:"message_id,omitempty"`
+		LastError     string          `json:"last_error,omitempty"`
+		History       []*Attempt      `json:"history,omitempty"`
+		RedeliveryOf  string          `json:"redelivery_of,omitempty"`
+		Redeliveries  int             `json:"redeliveries,omitempty"`
+	}
+
+	// Attempt records one failed send of an Entry. Code is the HTTP status code of the
+	// response and ErrorCode the WhatsApp error code, both are zero for transport errors.
+	Attempt struct {
+		Number    int       `json:"number"`
+		At        time.Time `json:"at"`
+		Error     string    `json:"error"`
+		Code      int       `json:"code,omitempty"`
+		ErrorCode int       `json:"error_code,omitempty"`
+	}
+
+	// Store persists outbox entries. Implementations must be safe for concurrent use.
+	// MemoryStore is provided, SQL or Redis backed stores give durability across restarts.
+	Store interface {
+		// Add stores a new entry.
+		Add(ctx context.Context, entry *Entry) error
+
+		// Claim returns up to limit pending entries whose NextAttemptAt is not after now,
+		// and moves their NextAttemptAt to now+lease so that no other dispatcher claims them
+		// meanwhile. An entry whose dispatcher dies is claimed again when the lease expires.
+		Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Entry, error)
+
+		// Update replaces the stored entry with the same ID.
+		Update(ctx context.Context, entry *Entry) error
+
+		// Get returns the entry with the given ID.
+		Get(ctx context.Context, id string) (*Entry, error)
+	}
+
+	// Scheduler is implemented by the stores that know when their next entry is due. The
+	// dispatcher then sleeps until that time instead of waking up every PollInterval, so
+	// that the retries are sent right when their backoff ends, including after a restart.
+	Scheduler interface {
+		// NextAttemptAt returns the earliest NextAttemptAt of the pending entries, found is
+		// false when there are none.
+		NextAttemptAt(ctx context.Context) (at time.Time, found bool, err error)
+	}
+
+	// Sender sends a message, *whatsapp.Client implements it.
+	Sender interface {
+		SendMessage(ctx context.Context, message *models.Message, options ...whatsapp.SendOption) (
+			*whatsapp.ResponseMessage, error)
+	}
+
+	// Config configures an Outbox, zero values are replaced by the defaults.
+	//
+	// Retryable decides whether a failed send is retried, by default transport errors,
+	// 429 and 5xx responses are. Backoff returns the delay before the next attempt, by
+	// default it doubles from BaseBackoff up to MaxBackoff and is shortened by a random
+	// fraction of up to Jitter of it, so that the entries failed together are not retried
+	// together. A negative Jitter disables it, Random replaces math/rand.Float64 as the
+	// source of the jitter. The delays end in the NextAttemptAt of the entries, which the
+	// Store persists.
+	//
+	// PollInterval is the longest the dispatcher sleeps, it bounds the delay before the
+	// entries added by other processes are sent, see Scheduler. OnResult is called after each
+	// attempt with the updated entry. Entries that fail for good are put in DeadLetters
+	// when it is set. MaxRedeliveries limits how many times Redeliver sends a message again
+	// after a failed status. Clock defaults to clock.System.
+	Config struct {
+		MaxAttempts     int
+		BatchSize       int
+		PollInterval    time.Duration
+		Lease           time.Duration
+		BaseBackoff     time.Duration
+		MaxBackoff      time.Duration
+		Jitter          float64
+		Random          func() float64
+		Retryable       func(err error) bool
+		Backoff         func(attempt int) time.Duration
+		OnResult        func(ctx context.Context, entry *Entry)
+		DeadLetters     DeadLetterQueue
+		MaxRedeliveries int
+		Clock           clock.Clock
+	}
+
+	// Outbox enqueues messages and dispatches them.
+	//
+	//	box := outbox.New(outbox.NewMemoryStore(), client, nil)
+	//	go box.Run(ctx)
+	//
+	//	entry, err := box.Enqueue(ctx, &models.Message{To: recipient, Type: "text", Text: &models.Text{Body: "hi"}})
+	Outbox struct {
+		store  Store
+		sender Sender
+		config Config
+		wake   chan struct{}
+	}
+)
+
+// New returns an Outbox that stores entries in store and sends them with sender.
+func New(store Store, sender Sender, config *Config) *Outbox {
+	box := &Outbox{store: store, sender: sender, wake: make(chan struct{}, 1)}
+	if config != nil {
+		box.config = *config
+	}
+	if box.config.MaxAttempts <= 0 {
+		box.config.MaxAttempts = DefaultMaxAttempts
+	}
+	if box.config.BatchSize <= 0 {
+		box.config.BatchSize = DefaultBatchSize
+	}
+	if box.config.PollInterval <= 0 {
+		box.config.PollInterval = DefaultPollInterval
+	}
+	if box.config.Lease <= 0 {
+		box.config.Lease = DefaultLease
+	}
+	if box.config.BaseBackoff <= 0 {
+		box.config.BaseBackoff = DefaultBaseBackoff
+	}
+	if box.config.MaxBackoff <= 0 {
+		box.config.MaxBackoff = DefaultMaxBackoff
+	}
+	if box.config.Jitter == 0 {
+		box.config.Jitter = DefaultJitter
+	}
+	if box.config.Random == nil {
+		box.config.Random = mathrand.Float64 //nolint:gosec // jitter
+	}
+	if box.config.MaxRedeliveries <= 0 {
+		box.config.MaxRedeliveries = DefaultMaxRedeliveries
+	}
+	if box.config.Retryable == nil {
+		box.config.Retryable = IsRetryable
+	}
+	if box.config.Backoff == nil {
+		box.config.Backoff = box.backoff
+	}
+	box.config.Clock = clock.OrSystem(box.config.Clock)
+
+	return box
+}
+
+// Enqueue writes the message to the store, it is sent by the dispatcher.
+func (box *Outbox) Enqueue(ctx context.Context, message *models.Message) (*Entry, error) {
+	if message == nil {
+		return nil, ErrNilMessage
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := box.config.Clock.Now()
+	entry := &Entry{
+		ID:            id,
+		Message:       message,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err = box.store.Add(ctx, entry); err != nil {
+		return nil, fmt.Errorf("outbox enqueue: %w", err)
+	}
+	box.notify()
+
+	return entry, nil
+}
+
+// Run dispatches the due entries until ctx is done. It returns ctx.Err(). Between two
+// dispatches it sleeps until the next entry is due when the store is a Scheduler, at most
+// PollInterval, and wakes up as soon as an entry is enqueued by this Outbox. Errors of the
+// store are retried on the next dispatch.
+func (box *Outbox) Run(ctx context.Context) error {
+	for {
+		for {
+			n, err := box.Dispatch(ctx)
+			if err != nil || n < box.config.BatchSize {
+				break
+			}
+		}
+
+		timer := box.config.Clock.NewTimer(box.sleep(ctx))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return ctx.Err()
+		case <-box.wake:
+			timer.Stop()
+		case <-timer.C():
+		}
+	}
+}
+
+// sleep returns how long the dispatcher sleeps before the next dispatch.
+func (box *Outbox) sleep(ctx context.Context) time.Duration {
+	scheduler, ok := box.store.(Scheduler)
+	if !ok {
+		return box.config.PollInterval
+	}
+	at, found, err := scheduler.NextAttemptAt(ctx)
+	if err != nil || !found {
+		return box.config.PollInterval
+	}
+	delay := at.Sub(box.config.Clock.Now())
+	switch {
+	case delay < 0:
+		return 0
+	case delay > box.config.PollInterval:
+		return box.config.PollInterval
+	default:
+		return delay
+	}
+}
+
+// notify wakes the dispatcher up, an entry was added or made due.
+func (box *Outbox) notify() {
+	select {
+	case box.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Dispatch claims one batch of due entries, sends them and records the results. It returns
+// the number of entries claimed. When ctx is done it stops and returns ctx.Err(), the entries
+// it did not send stay pending and are claimed again once their lease expires.
+func (box *Outbox) Dispatch(ctx context.Context) (int, error) {
+	entries, err := box.store.Claim(ctx, box.config.Clock.Now(), box.config.BatchSize, box.config.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("outbox claim: %w", err)
+	}
+	for _, entry := range entries {
+		if err = ctx.Err(); err != nil {
+			return len(entries), err
+		}
+		if err = box.send(ctx, entry); err != nil {
+			return len(entries), err
+		}
+	}
+
+	return len(entries), nil
+}
+
+// send sends the entry and records the result. The entry ID is used as the idempotency key,
+// so a client configured with whatsapp.WithIdempotency does not send it twice. A send that
+// failed because ctx is done is not an attempt, the entry is left to its lease.
+func (box *Outbox) send(ctx context.Context, entry *Entry) error {
+	response, err := box.sender.SendMessage(whatsapp.WithIdempotencyKey(ctx, entry.ID), entry.Message)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	now := box.config.Clock.Now()
+	entry.Attempts++
+	entry.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		entry.Status = StatusSent
+		entry.LastError = ""
+		if response != nil && len(response.Messages) > 0 && response.Messages[0] != nil {
+			entry.MessageID = response.Messages[0].ID
+		}
+	case entry.Attempts >= box.config.MaxAttempts || !box.config.Retryable(err):
+		entry.Status = StatusFailed
+		entry.LastError = err.Error()
+		entry.History = append(entry.History, newAttempt(entry.Attempts, now, err))
+	default:
+		entry.LastError = err.Error()
+		entry.NextAttemptAt = now.Add(box.config.Backoff(entry.Attempts))
+		entry.History = append(entry.History, newAttempt(entry.Attempts, now, err))
+	}
+
+	if uerr := box.store.Update(ctx, entry); uerr != nil {
+		return fmt.Errorf("outbox update: %w", uerr)
+	}
+	if entry.Status == StatusFailed && box.config.DeadLetters != nil {
+		letter := &DeadLetter{Entry: entry, Err: err, FailedAt: now}
+		if derr := box.config.DeadLetters.Put(ctx, letter); derr != nil {
+			return fmt.Errorf("outbox dead letter: %w", derr)
+		}
+	}
+	if box.config.OnResult != nil {
+		box.config.OnResult(ctx, entry)
+	}
+
+	return nil
+}
+
+func newAttempt(number int, at time.Time, err error) *Attempt {
+	attempt := &Attempt{Number: number, At: at, Error: err.Error()}
+	var re *whttp.ResponseError
+	if errors.As(err, &re) {
+		attempt.Code = re.Code
+		if re.Err != nil {
+			attempt.ErrorCode = re.Err.Code
+		}
+	}
+
+	return attempt
+}
+
+func (box *Outbox) backoff(attempt int) time.Duration {
+	delay := box.config.BaseBackoff
+	for i := 1; i < attempt && delay < box.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > box.config.MaxBackoff {
+		delay = box.config.MaxBackoff
+	}
+	if box.config.Jitter > 0 {
+		delay -= time.Duration(box.config.Random() * box.config.Jitter * float64(delay))
+	}
+
+	return delay
+}
+
+// IsRetryable reports whether a failed send may succeed if retried. The Graph error code
+// decides first: the codes the errors catalog marks retryable, like the throughput and pair
+// rate limits the API returns with HTTP 400, are retryable and the other known codes are not.
+// Otherwise transport errors, rate limiting (429) and server errors (5xx) are retryable, other
+// API errors are not.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var we *werrors.Error
+	if errors.As(err, &we) {
+		if explanation, ok := werrors.Lookup(we.Code, we.Subcode); ok {
+			return explanation.Retryable
+		}
+	}
+	var re *whttp.ResponseError
+	if errors.As(err, &re) {
+		return re.Code == http.StatusTooManyRequests || re.Code >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("outbox id: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,457 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp"
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+type senderFunc func(ctx context.Context, message *models.Message) (*whatsapp.ResponseMessage, error)
+
+func (f senderFunc) SendMessage(ctx context.Context, message *models.Message, _ ...whatsapp.SendOption) (
+	*whatsapp.ResponseMessage, error,
+) {
+	return f(ctx, message)
+}
+
+func TestOutboxDispatch(t *testing.T) {
+	t.Parallel()
+	apiError := &whttp.ResponseError{Code: http.StatusBadRequest, Err: &werrors.Error{Code: 131026}}
+	serverError := &whttp.ResponseError{Code: http.StatusInternalServerError, Err: &werrors.Error{Code: 1}}
+	tests := []struct {
+		name         string
+		errs         []error
+		wantStatus   Status
+		wantAttempts int
+	}{
+		{
+			name:         "sent on first attempt",
+			errs:         nil,
+			wantStatus:   StatusSent,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retried after server error",
+			errs:         []error{serverError},
+			wantStatus:   StatusSent,
+			wantAttempts: 2,
+		},
+		{
+			name:         "not retried after client error",
+			errs:         []error{apiError},
+			wantStatus:   StatusFailed,
+			wantAttempts: 1,
+		},
+		{
+			name:         "failed after max attempts",
+			errs:         []error{serverError, serverError, serverError},
+			wantStatus:   StatusFailed,
+			wantAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var calls int
+			sender := senderFunc(func(ctx context.Context, message *models.Message) (*whatsapp.ResponseMessage, error) {
+				calls++
+				if key, _ := whatsapp.IdempotencyKeyFromContext(ctx); key == "" {
+					t.Error("send without idempotency key")
+				}
+				if calls <= len(tt.errs) {
+					return nil, tt.errs[calls-1]
+				}
+
+				return &whatsapp.ResponseMessage{Messages: []*whatsapp.MessageID{{ID: fmt.Sprintf("wamid.%d", calls)}}}, nil
+			})
+
+			store := NewMemoryStore()
+			box := New(store, sender, &Config{
+				MaxAttempts: 3,
+				Backoff:     func(int) time.Duration { return 0 },
+			})
+			entry, err := box.Enqueue(context.TODO(), &models.Message{To: "255700000000", Type: "text"})
+			if err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+
+			for i := 0; i < 5; i++ {
+				if _, err = box.Dispatch(context.TODO()); err != nil {
+					t.Fatalf("Dispatch() error = %v", err)
+				}
+			}
+
+			got, err := store.Get(context.TODO(), entry.ID)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got.Status != tt.wantStatus || got.Attempts != tt.wantAttempts {
+				t.Errorf("entry status = %s after %d attempts, want %s after %d",
+					got.Status, got.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if tt.wantStatus == StatusSent && got.MessageID == "" {
+				t.Error("sent entry has no message id")
+			}
+			if tt.wantStatus == StatusFailed && got.LastError == "" {
+				t.Error("failed entry has no error")
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+	apiError := func(status, code int) error {
+		return fmt.Errorf("send: %w", &whttp.ResponseError{Code: status, Err: &werrors.Error{Code: code}})
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "throughput limit", err: apiError(http.StatusBadRequest, werrors.CodeThroughputLimit), want: true},
+		{name: "waba rate limit", err: apiError(http.StatusBadRequest, werrors.CodeRateLimit), want: true},
+		{name: "pair rate limit", err: apiError(http.StatusBadRequest, werrors.CodePairRateLimit), want: true},
+		{name: "unknown error", err: apiError(http.StatusBadRequest, 131000), want: true},
+		{name: "service unavailable", err: apiError(http.StatusBadRequest, 131016), want: true},
+		{name: "undeliverable", err: apiError(http.StatusBadRequest, 131026), want: false},
+		{name: "uncatalogued server error", err: apiError(http.StatusInternalServerError, 1), want: true},
+		{name: "uncatalogued client error", err: apiError(http.StatusBadRequest, 1), want: false},
+		{name: "too many requests", err: &whttp.ResponseError{Code: http.StatusTooManyRequests}, want: true},
+		{name: "transport error", err: errors.New("connection reset"), want: true},
+		{name: "canceled", err: context.Canceled, want: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreClaimLease(t *testing.T) {
+	t.Parallel()
+	store := NewMemoryStore()
+	now := time.Now()
+	if err := store.Add(context.TODO(), &Entry{ID: "1", Status: StatusPending, NextAttemptAt: now}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	claimed, _ := store.Claim(context.TODO(), now, 10, time.Minute)
+	if len(claimed) != 1 {
+		t.Fatalf("Claim() returned %d entries, want 1", len(claimed))
+	}
+	if claimed, _ = store.Claim(context.TODO(), now, 10, time.Minute); len(claimed) != 0 {
+		t.Errorf("leased entry claimed again")
+	}
+	if claimed, _ = store.Claim(context.TODO(), now.Add(2*time.Minute), 10, time.Minute); len(claimed) != 1 {
+		t.Errorf("entry not claimed after its lease expired")
+	}
+
+	if err := store.Add(context.TODO(), &Entry{ID: "1"}); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("Add() duplicate error = %v, want %v", err, ErrEntryExists)
+	}
+}
+
+func TestOutboxDeadLetters(t *testing.T) {
+	t.Parallel()
+	apiError := &whttp.ResponseError{Code: http.StatusBadRequest, Err: &werrors.Error{Code: 131026}}
+	fail := true
+	sender := senderFunc(func(ctx context.Context, message *models.Message) (*whatsapp.ResponseMessage, error) {
+		if fail {
+			return nil, fmt.Errorf("send: %w", apiError)
+		}
+
+		return &whatsapp.ResponseMessage{Messages: []*whatsapp.MessageID{{ID: "wamid.1"}}}, nil
+	})
+
+	store := NewMemoryStore()
+	dead := NewMemoryDeadLetterQueue()
+	box := New(store, sender, &Config{DeadLetters: dead})
+	entry, err := box.Enqueue(context.TODO(), &models.Message{To: "255700000000", Type: "text"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if _, err = box.Dispatch(context.TODO()); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+
+	letters, _ := dead.List(context.TODO())
+	if len(letters) != 1 {
+		t.Fatalf("dead letters = %d, want 1", len(letters))
+	}
+	letter := letters[0]
+	if !errors.Is(letter.Err, apiError) {
+		t.Errorf("dead letter error = %v, want chain with %v", letter.Err, apiError)
+	}
+	if letter.Entry.Message == nil || len(letter.Entry.History) != 1 || letter.Entry.History[0].ErrorCode != 131026 {
+		t.Errorf("dead letter entry = %+v, want message and one attempt with code 131026", letter.Entry)
+	}
+
+	fail = false
+	if err = box.Requeue(context.TODO(), entry.ID); err != nil {
+		t.Fatalf("Requeue() error = %v", err)
+	}
+	if _, err = box.Dispatch(context.TODO()); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	got, _ := store.Get(context.TODO(), entry.ID)
+	if got.Status != StatusSent {
+		t.Errorf("requeued entry status = %s, want %s", got.Status, StatusSent)
+	}
+	if letters, _ = dead.List(context.TODO()); len(letters) != 0 {
+		t.Errorf("dead letters after requeue = %d, want 0", len(letters))
+	}
+}
+
+func TestOutboxDispatchCancelled(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var sent []string
+	sender := senderFunc(func(ctx context.Context, message *models.Message) (*whatsapp.ResponseMessage, error) {
+		if len(sent) == 0 {
+			// the dispatcher is shut down while the first send is in flight
+			cancel()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("send: %w", err)
+		}
+		sent = append(sent, message.To)
+
+		return &whatsapp.ResponseMessage{Messages: []*whatsapp.MessageID{{ID: "wamid.1"}}}, nil
+	})
+
+	store := NewMemoryStore()
+	dead := NewMemoryDeadLetterQueue()
+	box := New(store, sender, &Config{Clock: clk, DeadLetters: dead})
+	var ids []string
+	for _, to := range []string{"255700000001", "255700000002", "255700000003"} {
+		entry, err := box.Enqueue(context.TODO(), &models.Message{To: to, Type: "text"})
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	if n, err := box.Dispatch(ctx); n != 3 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Dispatch() = %d, %v, want 3, %v", n, err, context.Canceled)
+	}
+	for _, id := range ids {
+		entry, _ := store.Get(context.TODO(), id)
+		if entry.Status != StatusPending || entry.Attempts != 0 || len(entry.History) != 0 {
+			t.Errorf("entry %s = %s after %d attempts, want pending without attempts", id, entry.Status, entry.Attempts)
+		}
+	}
+	if letters, _ := dead.List(context.TODO()); len(letters) != 0 {
+		t.Errorf("dead letters = %d, want 0", len(letters))
+	}
+
+	// the entries are leased to the cancelled dispatcher until the lease expires
+	if n, err := box.Dispatch(context.TODO()); n != 0 || err != nil {
+		t.Fatalf("Dispatch() during the lease = %d, %v, want 0, nil", n, err)
+	}
+	clk.Advance(DefaultLease)
+	if n, err := box.Dispatch(context.TODO()); n != 3 || err != nil {
+		t.Fatalf("Dispatch() after the lease = %d, %v, want 3, nil", n, err)
+	}
+	for _, id := range ids {
+		entry, _ := store.Get(context.TODO(), id)
+		if entry.Status != StatusSent || entry.Attempts != 1 {
+			t.Errorf("entry %s = %s after %d attempts, want sent after 1", id, entry.Status, entry.Attempts)
+		}
+	}
+}
+
+func TestOutboxRedeliver(t *testing.T) {
+	t.Parallel()
+	var keys []string
+	sender := senderFunc(func(ctx context.Context, message *models.Message) (*whatsapp.ResponseMessage, error) {
+		key, _ := whatsapp.IdempotencyKeyFromContext(ctx)
+		keys = append(keys, key)
+
+		return &whatsapp.ResponseMessage{Messages: []*whatsapp.MessageID{{ID: fmt.Sprintf("wamid.%d", len(keys))}}}, nil
+	})
+
+	store := NewMemoryStore()
+	box := New(store, sender, &Config{MaxRedeliveries: 1, Backoff: func(int) time.Duration { return 0 }})
+	entry, err := box.Enqueue(context.TODO(), &models.Message{To: "255700000000", Type: "text"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if _, err = box.Dispatch(context.TODO()); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+
+	delivery := &whatsapp.Delivery{MessageID: "wamid.1", IdempotencyKey: entry.ID}
+	if err = box.Redeliver(context.TODO(), delivery); err != nil {
+		t.Fatalf("Redeliver() error = %v", err)
+	}
+	if _, err = box.Dispatch(context.TODO()); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	if len(keys) != 2 || keys[1] == entry.ID {
+		t.Fatalf("idempotency keys = %v, want a new key for the redelivery", keys)
+	}
+	redelivered, err := store.Get(context.TODO(), keys[1])
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if redelivered.RedeliveryOf != entry.ID || redelivered.Redeliveries != 1 || redelivered.Status != StatusSent {
+		t.Errorf("redelivered entry = %+v", redelivered)
+	}
+
+	delivery = &whatsapp.Delivery{MessageID: "wamid.2", IdempotencyKey: redelivered.ID}
+	if err = box.Redeliver(context.TODO(), delivery); !errors.Is(err, ErrRedeliveryLimit) {
+		t.Errorf("Redeliver() error = %v, want %v", err, ErrRedeliveryLimit)
+	}
+	delivery = &whatsapp.Delivery{MessageID: "wamid.3", IdempotencyKey: "unknown"}
+	if err = box.Redeliver(context.TODO(), delivery); !errors.Is(err, ErrNotOutboxMessage) {
+		t.Errorf("Redeliver() error = %v, want %v", err, ErrNotOutboxMessage)
+	}
+}
+
+func TestOutboxBackoffJitter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		jitter  float64
+		random  float64
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first attempt", jitter: -1, attempt: 1, want: time.Second},
+		{name: "doubles", jitter: -1, attempt: 3, want: 4 * time.Second},
+		{name: "capped", jitter: -1, attempt: 20, want: time.Minute},
+		{name: "default jitter", random: 1, attempt: 3, want: 3200 * time.Millisecond},
+		{name: "half jitter", jitter: 0.5, random: 0.5, attempt: 2, want: 1500 * time.Millisecond},
+		{name: "jitter of the cap", jitter: 0.5, random: 1, attempt: 20, want: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			box := New(NewMemoryStore(), nil, &Config{
+				BaseBackoff: time.Second,
+				MaxBackoff:  time.Minute,
+				Jitter:      tt.jitter,
+				Random:      func() float64 { return tt.random },
+			})
+			if got := box.config.Backoff(tt.attempt); got != tt.want {
+				t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutboxRunSchedule(t *testing.T) {
+	t.Parallel()
+	serverError := &whttp.ResponseError{Code: http.StatusInternalServerError, Err: &werrors.Error{Code: 1}}
+	clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	start := clk.Now()
+	sent := make(chan time.Time, 10)
+	var calls int
+	sender := senderFunc(func(ctx context.Context, message *models.Message) (*whatsapp.ResponseMessage, error) {
+		calls++
+		if calls == 1 {
+			return nil, serverError
+		}
+		sent <- clk.Now()
+
+		return &whatsapp.ResponseMessage{Messages: []*whatsapp.MessageID{{ID: fmt.Sprintf("wamid.%d", calls)}}}, nil
+	})
+	config := &Config{
+		Clock:        clk,
+		PollInterval: time.Hour,
+		BaseBackoff:  10 * time.Second,
+		Random:       func() float64 { return 0.5 },
+	}
+	waitForTimer := func() {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for clk.Waiters() != 1 {
+			if time.Now().After(deadline) {
+				t.Fatal("the dispatcher did not go to sleep")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	store := NewMemoryStore()
+	entry, err := New(store, sender, config).Enqueue(context.TODO(), &models.Message{To: "255700000000"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if _, err = New(store, sender, config).Dispatch(context.TODO()); err != nil {
+		t.Fatalf("Dispatch() error = %v", err)
+	}
+	// the backoff of 10s is shortened by half of the default jitter
+	stored, _ := store.Get(context.TODO(), entry.ID)
+	if want := start.Add(9 * time.Second); !stored.NextAttemptAt.Equal(want) {
+		t.Fatalf("NextAttemptAt = %v, want %v", stored.NextAttemptAt, want)
+	}
+
+	// a restarted dispatcher sleeps until the persisted attempt time, not the poll interval
+	ctx, cancel := context.WithCancel(context.Background())
+	box := New(store, sender, config)
+	done := make(chan error, 1)
+	go func() { done <- box.Run(ctx) }()
+
+	waitForTimer()
+	clk.Advance(8 * time.Second)
+	if clk.Waiters() != 1 {
+		t.Fatal("the dispatcher woke up before the entry was due")
+	}
+	clk.Advance(time.Second)
+	if at := <-sent; !at.Equal(start.Add(9 * time.Second)) {
+		t.Errorf("retry sent at %v, want %v", at, start.Add(9*time.Second))
+	}
+
+	// an enqueued entry wakes the dispatcher up
+	waitForTimer()
+	if _, err = box.Enqueue(context.TODO(), &models.Message{To: "255700000001"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if at := <-sent; !at.Equal(start.Add(9 * time.Second)) {
+		t.Errorf("enqueued entry sent at %v, want right away", at)
+	}
+
+	cancel()
+	if err = <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SeamPay/whatsapp"
+)
+
+var (
+	ErrRedeliveryLimit  = errors.New("outbox: redelivery limit reached")
+	ErrNotOutboxMessage = errors.New("outbox: message not sent through the outbox")
+)
+
+// Redeliver enqueues again a message sent through the outbox that a status webhook reported
+// failed, it is a whatsapp.DeliveryRetryFunc for the FailurePolicy of a DeliveryTracker. The
+// message is found by the idempotency key of the delivery, the ID of its entry, and sent
+// after the backoff of the number of redeliveries under a new ID, the old one being already
+// used by the idempotency layer. It fails with ErrRedeliveryLimit after MaxRedeliveries.
+func (box *Outbox) Redeliver(ctx context.Context, delivery *whatsapp.Delivery) error {
+	if delivery == nil || delivery.IdempotencyKey == "" {
+		return ErrNotOutboxMessage
+	}
+	original, err := box.store.Get(ctx, delivery.IdempotencyKey)
+	if errors.Is(err, ErrEntryNotFound) {
+		return fmt.Errorf("outbox redeliver %s: %w", delivery.MessageID, ErrNotOutboxMessage)
+	}
+	if err != nil {
+		return fmt.Errorf("outbox redeliver %s: %w", delivery.MessageID, err)
+	}
+	if original.Redeliveries >= box.config.MaxRedeliveries {
+		return fmt.Errorf("outbox redeliver %s: %w", delivery.MessageID, ErrRedeliveryLimit)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	now := box.config.Clock.Now()
+	entry := &Entry{
+		ID:            id,
+		Message:       original.Message,
+		Status:        StatusPending,
+		NextAttemptAt: now.Add(box.config.Backoff(original.Redeliveries + 1)),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		RedeliveryOf:  original.ID,
+		Redeliveries:  original.Redeliveries + 1,
+	}
+	if original.RedeliveryOf != "" {
+		entry.RedeliveryOf = original.RedeliveryOf
+	}
+	if err = box.store.Add(ctx, entry); err != nil {
+		return fmt.Errorf("outbox redeliver %s: %w", delivery.MessageID, err)
+	}
+	box.notify()
+
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrEntryNotFound = errors.New("outbox: entry not found")
+	ErrEntryExists   = errors.New("outbox: entry already exists")
+)
+
+// MemoryStore is an in-memory Store. Entries do not survive a restart, it is meant for
+// tests and for processes that only need the retries of the dispatcher.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Entry)}
+}
+
+func (store *MemoryStore) Add(_ context.Context, entry *Entry) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.entries[entry.ID]; ok {
+		return ErrEntryExists
+	}
+	e := *entry
+	store.entries[entry.ID] = &e
+
+	return nil
+}
+
+func (store *MemoryStore) Claim(_ context.Context, now time.Time, limit int, lease time.Duration) ([]*Entry, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var due []*Entry
+	for _, entry := range store.entries {
+		if entry.Status == StatusPending && !entry.NextAttemptAt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*Entry, 0, len(due))
+	for _, entry := range due {
+		entry.NextAttemptAt = now.Add(lease)
+		e := *entry
+		claimed = append(claimed, &e)
+	}
+
+	return claimed, nil
+}
+
+func (store *MemoryStore) Update(_ context.Context, entry *Entry) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.entries[entry.ID]; !ok {
+		return ErrEntryNotFound
+	}
+	e := *entry
+	store.entries[entry.ID] = &e
+
+	return nil
+}
+
+func (store *MemoryStore) NextAttemptAt(_ context.Context) (time.Time, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var (
+		next  time.Time
+		found bool
+	)
+	for _, entry := range store.entries {
+		if entry.Status == StatusPending && (!found || entry.NextAttemptAt.Before(next)) {
+			next, found = entry.NextAttemptAt, true
+		}
+	}
+
+	return next, found, nil
+}
+
+func (store *MemoryStore) Get(_ context.Context, id string) (*Entry, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	entry, ok := store.entries[id]
+	if !ok {
+		return nil, ErrEntryNotFound
+	}
+	e := *entry
+
+	return &e, nil
+}
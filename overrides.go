@@ -0,0 +1,135 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+type (
+	// Overrides replace the client configuration for the calls made with a context
+	// returned by WithOverrides. Empty fields keep the client configuration. PhoneNumberID
+	// may be an alias registered with WithSenderNumbers.
+	//
+	// Multi-number gateways use it to send from many phone numbers, possibly of different
+	// businesses, with a single client:
+	//
+	//	ctx = whatsapp.WithOverrides(ctx, &whatsapp.Overrides{
+	//		PhoneNumberID: tenant.PhoneNumberID,
+	//		AccessToken:   tenant.AccessToken,
+	//	})
+	//	resp, err := client.SendTextMessage(ctx, recipient, message)
+	Overrides struct {
+		BaseURL           string
+		APIVersion        string
+		AccessToken       string
+		PhoneNumberID     string
+		BusinessAccountID string
+	}
+
+	overridesKey struct{}
+
+	// overrideTokenSource returns the access token override from the context, if any,
+	// before falling back to the wrapped source.
+	overrideTokenSource struct {
+		source whttp.TokenSource
+	}
+)
+
+// WithOverrides returns a context that overrides the client configuration for the calls
+// made with it. Overrides set on a parent context are merged, the innermost wins.
+func WithOverrides(ctx context.Context, overrides *Overrides) context.Context {
+	if overrides == nil {
+		return ctx
+	}
+	merged := *overrides
+	if parent, ok := OverridesFromContext(ctx); ok {
+		merged = parent.merge(overrides)
+	}
+
+	return context.WithValue(ctx, overridesKey{}, &merged)
+}
+
+// OverridesFromContext returns the overrides set with WithOverrides.
+func OverridesFromContext(ctx context.Context) (*Overrides, bool) {
+	overrides, ok := ctx.Value(overridesKey{}).(*Overrides)
+
+	return overrides, ok
+}
+
+// merge returns o with the non-empty fields of other applied.
+func (o *Overrides) merge(other *Overrides) Overrides {
+	merged := *o
+	if other.BaseURL != "" {
+		merged.BaseURL = other.BaseURL
+	}
+	if other.APIVersion != "" {
+		merged.APIVersion = other.APIVersion
+	}
+	if other.AccessToken != "" {
+		merged.AccessToken = other.AccessToken
+	}
+	if other.PhoneNumberID != "" {
+		merged.PhoneNumberID = other.PhoneNumberID
+	}
+	if other.BusinessAccountID != "" {
+		merged.BusinessAccountID = other.BusinessAccountID
+	}
+
+	return merged
+}
+
+// apply replaces the fields of cctx with the non-empty overrides.
+func (o *Overrides) apply(cctx *clientContext) {
+	if o.BaseURL != "" {
+		cctx.baseURL = o.BaseURL
+	}
+	if o.APIVersion != "" {
+		cctx.apiVersion = o.APIVersion
+	}
+	if o.AccessToken != "" {
+		cctx.accessToken = o.AccessToken
+	}
+	if o.PhoneNumberID != "" {
+		cctx.phoneNumberID = o.PhoneNumberID
+	}
+	if o.BusinessAccountID != "" {
+		cctx.businessAccountID = o.BusinessAccountID
+	}
+}
+
+func (s overrideTokenSource) Token(ctx context.Context) (string, error) {
+	if overrides, ok := OverridesFromContext(ctx); ok && overrides.AccessToken != "" {
+		return overrides.AccessToken, nil
+	}
+
+	return s.source.Token(ctx) //nolint:wrapcheck
+}
+
+// InvalidateToken forwards to the wrapped source, so that TokenSourceMiddleware still
+// refreshes its tokens. Overridden tokens are never refreshed as the invalidation leaves
+// them unchanged.
+func (s overrideTokenSource) InvalidateToken(token string) {
+	if invalidator, ok := s.source.(whttp.TokenInvalidator); ok {
+		invalidator.InvalidateToken(token)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+func TestClientOverrides(t *testing.T) {
+	t.Parallel()
+	var path, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		authorization = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		overrides *Overrides
+		options   []ClientOption
+		wantPath  string
+		wantAuth  string
+	}{
+		{
+			name:     "no overrides",
+			wantPath: "/v23.0/phone_1/messages",
+			wantAuth: "Bearer token_1",
+		},
+		{
+			name: "phone number and token",
+			overrides: &Overrides{
+				PhoneNumberID: "phone_2",
+				AccessToken:   "token_2",
+			},
+			wantPath: "/v23.0/phone_2/messages",
+			wantAuth: "Bearer token_2",
+		},
+		{
+			name:      "api version",
+			overrides: &Overrides{APIVersion: "v18.0"},
+			wantPath:  "/v18.0/phone_1/messages",
+			wantAuth:  "Bearer token_1",
+		},
+		{
+			name:      "token wins over token source",
+			overrides: &Overrides{AccessToken: "token_2"},
+			options:   []ClientOption{WithTokenSource(whttp.StaticTokenSource("source_token"))},
+			wantPath:  "/v23.0/phone_1/messages",
+			wantAuth:  "Bearer token_2",
+		},
+	}
+
+	for _, tt := range tests { //nolint:paralleltest
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]ClientOption{
+				WithBaseURL(server.URL),
+				WithAccessToken("token_1"),
+				WithPhoneNumberID("phone_1"),
+			}, tt.options...)
+			client := NewClient(options...)
+
+			ctx := WithOverrides(context.TODO(), tt.overrides)
+			_, err := client.SendTextMessage(ctx, "255700000000", &TextMessage{Message: "hello"})
+			if err != nil {
+				t.Fatalf("SendTextMessage() error = %v", err)
+			}
+			if path != tt.wantPath {
+				t.Errorf("request path = %q, want %q", path, tt.wantPath)
+			}
+			if authorization != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", authorization, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestWithOverridesMerge(t *testing.T) {
+	t.Parallel()
+	ctx := WithOverrides(context.TODO(), &Overrides{AccessToken: "token", PhoneNumberID: "phone_1"})
+	ctx = WithOverrides(ctx, &Overrides{PhoneNumberID: "phone_2"})
+
+	overrides, ok := OverridesFromContext(ctx)
+	if !ok {
+		t.Fatal("OverridesFromContext() ok = false")
+	}
+	if overrides.AccessToken != "token" || overrides.PhoneNumberID != "phone_2" {
+		t.Errorf("OverridesFromContext() = %+v", overrides)
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package partner implements the Business Management endpoints used by Tech Providers and
+// Solution Partners to onboard their clients: creating and listing the WhatsApp Business
+// Accounts (WABAs) of the clients, adding phone numbers to them, subscribing the app to
+// their webhooks and sharing the partner's credit line with them.
+//
+// The requests are made with a system user access token of the partner business:
+//
+//	partners := client.Partner(ctx, businessID)
+//	waba, err := partners.CreateBusinessAccount(ctx, &partner.CreateBusinessAccountRequest{
+//		Name:       "Jasper's Market",
+//		Currency:   "USD",
+//		TimezoneID: "1",
+//	})
+//	phone, err := partners.AddPhoneNumber(ctx, waba.ID, &partner.AddPhoneNumberRequest{...})
+//	err = partners.SubscribeApp(ctx, waba.ID)
+//	allocation, err := partners.ShareCreditLine(ctx, creditLineID, waba.ID, "USD")
+package partner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+type (
+	// RequestContext holds the credentials of the partner. BusinessID is the ID of the
+	// partner business in the Business Manager.
+	RequestContext struct {
+		BaseURL     string `json:"-"`
+		ApiVersion  string `json:"-"` //nolint: revive,stylecheck
+		AccessToken string `json:"-"`
+		BusinessID  string `json:"-"`
+	}
+
+	BusinessAccount struct {
+		ID                       string `json:"id"`
+		Name                     string `json:"name,omitempty"`
+		Currency                 string `json:"currency,omitempty"`
+		TimezoneID               string `json:"timezone_id,omitempty"`
+		MessageTemplateNamespace string `json:"message_template_namespace,omitempty"`
+		AccountReviewStatus      string `json:"account_review_status,omitempty"`
+	}
+
+	Cursors struct {
+		Before string `json:"before,omitempty"`
+		After  string `json:"after,omitempty"`
+	}
+
+	Paging struct {
+		Cursors *Cursors `json:"cursors,omitempty"`
+		Next    string   `json:"next,omitempty"`
+	}
+
+	BusinessAccountsList struct {
+		Data   []*BusinessAccount `json:"data,omitempty"`
+		Paging *Paging            `json:"paging,omitempty"`
+	}
+
+	// CreateBusinessAccountRequest creates a WABA for a client. TimezoneID is one of the
+	// time zone IDs of the Graph API, Currency an ISO 4217 code.
+	CreateBusinessAccountRequest struct {
+		Name       string
+		Currency   string
+		TimezoneID string
+	}
+
+	// AddPhoneNumberRequest adds a phone number to a WABA. CountryCode is the calling code
+	// without the leading +, PhoneNumber the number without it and VerifiedName the display
+	// name to review.
+	AddPhoneNumberRequest struct {
+		CountryCode  string
+		PhoneNumber  string
+		VerifiedName string
+	}
+
+	PhoneNumber struct {
+		ID string `json:"id"`
+	}
+
+	// CreditLine is an extended credit line of the partner business.
+	CreditLine struct {
+		ID              string `json:"id"`
+		LegalEntityName string `json:"legal_entity_name,omitempty"`
+	}
+
+	CreditLinesList struct {
+		Data   []*CreditLine `json:"data,omitempty"`
+		Paging *Paging       `json:"paging,omitempty"`
+	}
+
+	// CreditAllocation is the sharing of a credit line with a WABA, its ID is needed to
+	// revoke it.
+	CreditAllocation struct {
+		AllocationConfigID string `json:"allocation_config_id"`
+		WabaID             string `json:"waba_id"`
+	}
+
+	SuccessResponse struct {
+		Success bool `json:"success"`
+	}
+)
+
+// Client sends the partner requests through a whttp.Sender, so that the middlewares that
+// wrap the sender apply to them as well.
+type Client struct {
+	sender whttp.Sender
+	rctx   *RequestContext
+}
+
+// NewClient creates a new Client that sends the requests using sender with the credentials
+// of rctx.
+func NewClient(sender whttp.Sender, rctx *RequestContext) *Client {
+	return &Client{sender: sender, rctx: rctx}
+}
+
+func (c *Client) request(name, method, nodeID string, endpoints ...string) *whttp.Request {
+	return &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       name,
+			BaseURL:    c.rctx.BaseURL,
+			ApiVersion: c.rctx.ApiVersion,
+			SenderID:   nodeID,
+			Endpoints:  endpoints,
+		},
+		Method: method,
+		Bearer: c.rctx.AccessToken,
+	}
+}
+
+// ListClientBusinessAccounts lists the WABAs of the clients shared with the partner business.
+func (c *Client) ListClientBusinessAccounts(ctx context.Context) (*BusinessAccountsList, error) {
+	req := c.request("list client business accounts", http.MethodGet, c.rctx.BusinessID,
+		"client_whatsapp_business_accounts")
+
+	resp, err := whttp.SendTyped[BusinessAccountsList](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("list client business accounts: %w", err)
+	}
+
+	return resp, nil
+}
+
+// ListOwnedBusinessAccounts lists the WABAs owned by the partner business.
+func (c *Client) ListOwnedBusinessAccounts(ctx context.Context) (*BusinessAccountsList, error) {
+	req := c.request("list owned business accounts", http.MethodGet, c.rctx.BusinessID,
+		"owned_whatsapp_business_accounts")
+
+	resp, err := whttp.SendTyped[BusinessAccountsList](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("list owned business accounts: %w", err)
+	}
+
+	return resp, nil
+}
+
+// CreateBusinessAccount creates a WABA owned by the partner business on behalf of a client.
+func (c *Client) CreateBusinessAccount(ctx context.Context, request *CreateBusinessAccountRequest,
+) (*BusinessAccount, error) {
+	req := c.request("create business account", http.MethodPost, c.rctx.BusinessID,
+		"whatsapp_business_accounts")
+	req.Form = map[string]string{
+		"name":        request.Name,
+		"currency":    request.Currency,
+		"timezone_id": request.TimezoneID,
+	}
+
+	resp, err := whttp.SendTyped[BusinessAccount](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("create business account: %w", err)
+	}
+
+	return resp, nil
+}
+
+// AddPhoneNumber adds a phone number to the WABA wabaID. The number must then be verified
+// and registered before it can send messages.
+func (c *Client) AddPhoneNumber(ctx context.Context, wabaID string, request *AddPhoneNumberRequest,
+) (*PhoneNumber, error) {
+	req := c.request("add phone number", http.MethodPost, wabaID, "phone_numbers")
+	req.Form = map[string]string{
+		"cc":            request.CountryCode,
+		"phone_number":  request.PhoneNumber,
+		"verified_name": request.VerifiedName,
+	}
+
+	resp, err := whttp.SendTyped[PhoneNumber](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("add phone number to %s: %w", wabaID, err)
+	}
+
+	return resp, nil
+}
+
+// SubscribeApp subscribes the app of the access token to the webhooks of the WABA wabaID.
+func (c *Client) SubscribeApp(ctx context.Context, wabaID string) (*SuccessResponse, error) {
+	req := c.request("subscribe app", http.MethodPost, wabaID, "subscribed_apps")
+
+	resp, err := whttp.SendTyped[SuccessResponse](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe app to %s: %w", wabaID, err)
+	}
+
+	return resp, nil
+}
+
+// ListCreditLines lists the extended credit lines of the partner business.
+func (c *Client) ListCreditLines(ctx context.Context) (*CreditLinesList, error) {
+	req := c.request("list credit lines", http.MethodGet, c.rctx.BusinessID, "extendedcredits")
+	req.Query = map[string]string{"fields": "id,legal_entity_name"}
+
+	resp, err := whttp.SendTyped[CreditLinesList](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("list credit lines: %w", err)
+	}
+
+	return resp, nil
+}
+
+// ShareCreditLine shares the credit line creditLineID with the WABA wabaID, whose messages
+// are then billed to the partner in currency.
+func (c *Client) ShareCreditLine(ctx context.Context, creditLineID, wabaID, currency string,
+) (*CreditAllocation, error) {
+	req := c.request("share credit line", http.MethodPost, creditLineID, "whatsapp_credit_sharing_and_attach")
+	req.Query = map[string]string{
+		"waba_id":       wabaID,
+		"waba_currency": currency,
+	}
+
+	resp, err := whttp.SendTyped[CreditAllocation](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("share credit line with %s: %w", wabaID, err)
+	}
+
+	return resp, nil
+}
+
+// RevokeCreditLine revokes the sharing of a credit line, allocationConfigID is the
+// CreditAllocation.AllocationConfigID returned by ShareCreditLine.
+func (c *Client) RevokeCreditLine(ctx context.Context, allocationConfigID string) (*SuccessResponse, error) {
+	req := c.request("revoke credit line", http.MethodDelete, allocationConfigID)
+
+	resp, err := whttp.SendTyped[SuccessResponse](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("revoke credit line %s: %w", allocationConfigID, err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package partner_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/partner"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		method string
+		path   string
+		query  string
+		form   map[string]string
+	}
+	var requests []received
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		_ = r.ParseForm()
+		form := map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		requests = append(requests, received{r.Method, r.URL.Path, r.URL.RawQuery, form})
+
+		var response any
+		switch r.URL.Path {
+		case "/v16.0/business/whatsapp_business_accounts":
+			response = map[string]string{"id": "waba", "name": r.PostForm.Get("name")}
+		case "/v16.0/waba/phone_numbers":
+			response = map[string]string{"id": "phone"}
+		case "/v16.0/credit/whatsapp_credit_sharing_and_attach":
+			response = map[string]string{"allocation_config_id": "allocation", "waba_id": r.URL.Query().Get("waba_id")}
+		case "/v16.0/business/client_whatsapp_business_accounts":
+			response = map[string]any{"data": []map[string]string{{"id": "waba"}}}
+		default:
+			response = map[string]bool{"success": true}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.TODO()
+	client := partner.NewClient(whttp.NewSender(http.DefaultClient), &partner.RequestContext{
+		BaseURL:     server.URL,
+		ApiVersion:  "v16.0",
+		AccessToken: "token",
+		BusinessID:  "business",
+	})
+
+	waba, err := client.CreateBusinessAccount(ctx, &partner.CreateBusinessAccountRequest{
+		Name:       "Jasper's Market",
+		Currency:   "USD",
+		TimezoneID: "1",
+	})
+	if err != nil {
+		t.Fatalf("CreateBusinessAccount() error = %v", err)
+	}
+	if waba.ID != "waba" || waba.Name != "Jasper's Market" {
+		t.Errorf("CreateBusinessAccount() = %+v", waba)
+	}
+
+	phone, err := client.AddPhoneNumber(ctx, waba.ID, &partner.AddPhoneNumberRequest{
+		CountryCode:  "1",
+		PhoneNumber:  "5555555555",
+		VerifiedName: "Jasper's Market",
+	})
+	if err != nil || phone.ID != "phone" {
+		t.Fatalf("AddPhoneNumber() = %+v, %v", phone, err)
+	}
+
+	if resp, err := client.SubscribeApp(ctx, waba.ID); err != nil || !resp.Success {
+		t.Fatalf("SubscribeApp() = %+v, %v", resp, err)
+	}
+
+	allocation, err := client.ShareCreditLine(ctx, "credit", waba.ID, "USD")
+	if err != nil || allocation.AllocationConfigID != "allocation" || allocation.WabaID != "waba" {
+		t.Fatalf("ShareCreditLine() = %+v, %v", allocation, err)
+	}
+
+	if resp, err := client.RevokeCreditLine(ctx, allocation.AllocationConfigID); err != nil || !resp.Success {
+		t.Fatalf("RevokeCreditLine() = %+v, %v", resp, err)
+	}
+
+	list, err := client.ListClientBusinessAccounts(ctx)
+	if err != nil || len(list.Data) != 1 || list.Data[0].ID != "waba" {
+		t.Fatalf("ListClientBusinessAccounts() = %+v, %v", list, err)
+	}
+
+	want := []received{
+		{http.MethodPost, "/v16.0/business/whatsapp_business_accounts", "", map[string]string{
+			"name": "Jasper's Market", "currency": "USD", "timezone_id": "1",
+		}},
+		{http.MethodPost, "/v16.0/waba/phone_numbers", "", map[string]string{
+			"cc": "1", "phone_number": "5555555555", "verified_name": "Jasper's Market",
+		}},
+		{http.MethodPost, "/v16.0/waba/subscribed_apps", "", map[string]string{}},
+		{http.MethodPost, "/v16.0/credit/whatsapp_credit_sharing_and_attach", "waba_currency=USD&waba_id=waba",
+			map[string]string{}},
+		{http.MethodDelete, "/v16.0/allocation", "", map[string]string{}},
+		{http.MethodGet, "/v16.0/business/client_whatsapp_business_accounts", "", map[string]string{}},
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(requests), len(want))
+	}
+	for i, w := range want {
+		got := requests[i]
+		if got.method != w.method || got.path != w.path || got.query != w.query {
+			t.Errorf("request %d = %s %s?%s, want %s %s?%s", i, got.method, got.path, got.query,
+				w.method, w.path, w.query)
+		}
+		for key, value := range w.form {
+			if got.form[key] != value {
+				t.Errorf("request %d form[%s] = %q, want %q", i, key, got.form[key], value)
+			}
+		}
+	}
+}
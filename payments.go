@@ -0,0 +1,247 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+// Payment configurations link a payment gateway or UPI account to the WhatsApp Business
+// Account, order_details messages sent in India reference them by name. They are managed on
+// the account set with WithBusinessAccountID.
+const (
+	PaymentProviderRazorpay = "razorpay"
+	PaymentProviderPayU     = "payu"
+	PaymentProviderZaakpay  = "zaakpay"
+	PaymentProviderUPIVPA   = "upi_vpa"
+)
+
+const (
+	PaymentConfigurationActive       = "Active"
+	PaymentConfigurationNeedsConnect = "Needs_Connecting"
+	PaymentConfigurationNeedsTesting = "Needs_Testing"
+)
+
+var (
+	ErrPaymentConfigurationNotFound = errors.New("payment configuration not found")
+	ErrPaymentNotFound              = errors.New("payment not found")
+)
+
+type (
+	// PaymentCode is a merchant category code or a purpose code of a payment configuration.
+	PaymentCode struct {
+		Code        string `json:"code"`
+		Description string `json:"description,omitempty"`
+	}
+
+	// PaymentConfiguration is a payment configuration of the WhatsApp Business Account.
+	PaymentConfiguration struct {
+		ConfigurationName    string       `json:"configuration_name"`
+		MerchantCategoryCode *PaymentCode `json:"merchant_category_code,omitempty"`
+		PurposeCode          *PaymentCode `json:"purpose_code,omitempty"`
+		Status               string       `json:"status,omitempty"`
+		ProviderMID          string       `json:"provider_mid,omitempty"`
+		ProviderName         string       `json:"provider_name,omitempty"`
+		MerchantVPA          string       `json:"merchant_vpa,omitempty"`
+		CreatedTimestamp     int64        `json:"created_timestamp,omitempty"`
+		UpdatedTimestamp     int64        `json:"updated_timestamp,omitempty"`
+	}
+
+	PaymentConfigurationsList struct {
+		Data []*struct {
+			PaymentConfigurations []*PaymentConfiguration `json:"payment_configurations"`
+		} `json:"data"`
+	}
+
+	// CreatePaymentConfigurationRequest creates a payment configuration. Payment gateways
+	// need a RedirectURL the merchant is sent to after completing the onboarding at the
+	// provider, UPI configurations (PaymentProviderUPIVPA) need the MerchantVPA instead.
+	CreatePaymentConfigurationRequest struct {
+		ConfigurationName    string `json:"configuration_name"`
+		PurposeCode          string `json:"purpose_code"`
+		MerchantCategoryCode string `json:"merchant_category_code"`
+		ProviderName         string `json:"provider_name"`
+		RedirectURL          string `json:"redirect_url,omitempty"`
+		MerchantVPA          string `json:"merchant_vpa,omitempty"`
+	}
+
+	// PaymentConfigurationOAuthLink is the link the merchant follows to connect the payment
+	// gateway account, Expiration is a unix timestamp.
+	PaymentConfigurationOAuthLink struct {
+		OAuthURL   string `json:"oauth_url"`
+		Expiration int64  `json:"expiration"`
+		Success    bool   `json:"success"`
+	}
+
+	// PaymentStatus is the status of the payment of an order_details message, Status is one
+	// of the models.PaymentStatus values.
+	PaymentStatus struct {
+		ReferenceID  string                       `json:"reference_id"`
+		Status       string                       `json:"status"`
+		Currency     string                       `json:"currency,omitempty"`
+		Amount       *models.PaymentAmount        `json:"amount,omitempty"`
+		Transactions []*models.PaymentTransaction `json:"transactions,omitempty"`
+		Refunds      []*models.PaymentRefund      `json:"refunds,omitempty"`
+	}
+
+	paymentStatusResponse struct {
+		Payments []*PaymentStatus `json:"payments"`
+	}
+
+	paymentConfigurationRequest struct {
+		ConfigurationName string `json:"configuration_name"`
+		RedirectURL       string `json:"redirect_url,omitempty"`
+	}
+)
+
+func (client *Client) paymentConfigurationRequest(ctx context.Context, name, method string,
+	endpoints ...string,
+) *whttp.Request {
+	cctx := client.context(ctx)
+
+	return &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       name,
+			BaseURL:    cctx.baseURL,
+			ApiVersion: cctx.apiVersion,
+			SenderID:   cctx.businessAccountID,
+			Endpoints:  endpoints,
+		},
+		Method: method,
+		Bearer: cctx.accessToken,
+	}
+}
+
+// ListPaymentConfigurations lists the payment configurations of the WhatsApp Business Account.
+func (client *Client) ListPaymentConfigurations(ctx context.Context) ([]*PaymentConfiguration, error) {
+	request := client.paymentConfigurationRequest(ctx, "list payment configurations", http.MethodGet,
+		"payment_configurations")
+
+	list, err := whttp.SendTyped[PaymentConfigurationsList](ctx, client.sender, request)
+	if err != nil {
+		return nil, fmt.Errorf("list payment configurations: %w", err)
+	}
+
+	var configurations []*PaymentConfiguration
+	for _, data := range list.Data {
+		configurations = append(configurations, data.PaymentConfigurations...)
+	}
+
+	return configurations, nil
+}
+
+// GetPaymentConfiguration returns the payment configuration named name.
+func (client *Client) GetPaymentConfiguration(ctx context.Context, name string) (*PaymentConfiguration, error) {
+	request := client.paymentConfigurationRequest(ctx, "get payment configuration", http.MethodGet,
+		"payment_configuration", name)
+
+	list, err := whttp.SendTyped[PaymentConfigurationsList](ctx, client.sender, request)
+	if err != nil {
+		return nil, fmt.Errorf("get payment configuration %s: %w", name, err)
+	}
+
+	for _, data := range list.Data {
+		if len(data.PaymentConfigurations) > 0 {
+			return data.PaymentConfigurations[0], nil
+		}
+	}
+
+	return nil, fmt.Errorf("get payment configuration %s: %w", name, ErrPaymentConfigurationNotFound)
+}
+
+// CreatePaymentConfiguration creates a payment configuration. For payment gateways the
+// returned link must be followed by the merchant to connect the gateway account before the
+// configuration becomes active.
+func (client *Client) CreatePaymentConfiguration(ctx context.Context, req *CreatePaymentConfigurationRequest,
+) (*PaymentConfigurationOAuthLink, error) {
+	request := client.paymentConfigurationRequest(ctx, "create payment configuration", http.MethodPost,
+		"payment_configuration")
+	request.Payload = req
+
+	link, err := whttp.SendTyped[PaymentConfigurationOAuthLink](ctx, client.sender, request)
+	if err != nil {
+		return nil, fmt.Errorf("create payment configuration %s: %w", req.ConfigurationName, err)
+	}
+
+	return link, nil
+}
+
+// RegeneratePaymentConfigurationOAuthLink generates a new link to connect the payment gateway
+// account of the configuration named name, e.g. after the previous one expired.
+func (client *Client) RegeneratePaymentConfigurationOAuthLink(ctx context.Context, name, redirectURL string,
+) (*PaymentConfigurationOAuthLink, error) {
+	request := client.paymentConfigurationRequest(ctx, "regenerate payment configuration oauth link",
+		http.MethodPost, "generate_payment_configuration_oauth_link")
+	request.Payload = &paymentConfigurationRequest{ConfigurationName: name, RedirectURL: redirectURL}
+
+	link, err := whttp.SendTyped[PaymentConfigurationOAuthLink](ctx, client.sender, request)
+	if err != nil {
+		return nil, fmt.Errorf("regenerate payment configuration %s oauth link: %w", name, err)
+	}
+
+	return link, nil
+}
+
+// DeletePaymentConfiguration deletes the payment configuration named name.
+func (client *Client) DeletePaymentConfiguration(ctx context.Context, name string) error {
+	request := client.paymentConfigurationRequest(ctx, "delete payment configuration", http.MethodDelete,
+		"payment_configuration")
+	request.Payload = &paymentConfigurationRequest{ConfigurationName: name}
+
+	if _, err := whttp.SendTyped[StatusResponse](ctx, client.sender, request); err != nil {
+		return fmt.Errorf("delete payment configuration %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// GetPaymentStatus returns the status of the payment of the order_details message sent with
+// the reference ID referenceID and the payment configuration named configurationName.
+func (client *Client) GetPaymentStatus(ctx context.Context, configurationName, referenceID string,
+) (*PaymentStatus, error) {
+	cctx := client.context(ctx)
+	request := &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       "get payment status",
+			BaseURL:    cctx.baseURL,
+			ApiVersion: cctx.apiVersion,
+			SenderID:   cctx.phoneNumberID,
+			Endpoints:  []string{"payments", configurationName, referenceID},
+		},
+		Method: http.MethodGet,
+		Bearer: cctx.accessToken,
+	}
+
+	response, err := whttp.SendTyped[paymentStatusResponse](ctx, client.sender, request)
+	if err != nil {
+		return nil, fmt.Errorf("get payment status %s: %w", referenceID, err)
+	}
+	if len(response.Payments) == 0 {
+		return nil, fmt.Errorf("get payment status %s: %w", referenceID, ErrPaymentNotFound)
+	}
+
+	return response.Payments[0], nil
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestPaymentConfigurations(t *testing.T) {
+	t.Parallel()
+	var (
+		requests []string
+		payloads []map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		var payload map[string]any
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &payload)
+		payloads = append(payloads, payload)
+
+		switch r.URL.Path {
+		case "/v23.0/waba_1/payment_configurations", "/v23.0/waba_1/payment_configuration/shop":
+			_, _ = w.Write([]byte(`{"data":[{"payment_configurations":[{"configuration_name":"shop",` +
+				`"merchant_category_code":{"code":"0000","description":"Test MCC Code"},` +
+				`"purpose_code":{"code":"00","description":"Test Purpose Code"},"status":"Active",` +
+				`"provider_name":"razorpay","created_timestamp":1720763701}]}]}`))
+		case "/v23.0/waba_1/payment_configuration/missing":
+			_, _ = w.Write([]byte(`{"data":[]}`))
+		case "/v23.0/waba_1/payment_configuration", "/v23.0/waba_1/generate_payment_configuration_oauth_link":
+			if r.Method == http.MethodDelete {
+				_, _ = w.Write([]byte(`{"success":true}`))
+
+				return
+			}
+			_, _ = w.Write([]byte(`{"oauth_url":"https://api.razorpay.com/oauth","expiration":1720773701,` +
+				`"success":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithBusinessAccountID("waba_1"))
+	ctx := context.Background()
+
+	configurations, err := client.ListPaymentConfigurations(ctx)
+	if err != nil {
+		t.Fatalf("ListPaymentConfigurations() error = %v", err)
+	}
+	if len(configurations) != 1 || configurations[0].ConfigurationName != "shop" ||
+		configurations[0].Status != PaymentConfigurationActive ||
+		configurations[0].MerchantCategoryCode.Code != "0000" {
+		t.Errorf("ListPaymentConfigurations() = %+v", configurations)
+	}
+
+	configuration, err := client.GetPaymentConfiguration(ctx, "shop")
+	if err != nil || configuration.ProviderName != PaymentProviderRazorpay {
+		t.Errorf("GetPaymentConfiguration() = %+v, %v", configuration, err)
+	}
+	if _, err = client.GetPaymentConfiguration(ctx, "missing"); !errors.Is(err, ErrPaymentConfigurationNotFound) {
+		t.Errorf("GetPaymentConfiguration() error = %v, want %v", err, ErrPaymentConfigurationNotFound)
+	}
+
+	link, err := client.CreatePaymentConfiguration(ctx, &CreatePaymentConfigurationRequest{
+		ConfigurationName:    "shop",
+		PurposeCode:          "00",
+		MerchantCategoryCode: "0000",
+		ProviderName:         PaymentProviderRazorpay,
+		RedirectURL:          "https://example.com/payments",
+	})
+	if err != nil || link.OAuthURL != "https://api.razorpay.com/oauth" || link.Expiration != 1720773701 {
+		t.Errorf("CreatePaymentConfiguration() = %+v, %v", link, err)
+	}
+
+	if _, err = client.RegeneratePaymentConfigurationOAuthLink(ctx, "shop", "https://example.com"); err != nil {
+		t.Errorf("RegeneratePaymentConfigurationOAuthLink() error = %v", err)
+	}
+
+	if err = client.DeletePaymentConfiguration(ctx, "shop"); err != nil {
+		t.Errorf("DeletePaymentConfiguration() error = %v", err)
+	}
+
+	want := []string{
+		"GET /v23.0/waba_1/payment_configurations",
+		"GET /v23.0/waba_1/payment_configuration/shop",
+		"GET /v23.0/waba_1/payment_configuration/missing",
+		"POST /v23.0/waba_1/payment_configuration",
+		"POST /v23.0/waba_1/generate_payment_configuration_oauth_link",
+		"DELETE /v23.0/waba_1/payment_configuration",
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("requests = %v, want %v", requests, want)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("request %d = %q, want %q", i, requests[i], want[i])
+		}
+	}
+	if payloads[3]["provider_name"] != "razorpay" || payloads[3]["redirect_url"] != "https://example.com/payments" {
+		t.Errorf("create payload = %v", payloads[3])
+	}
+	if payloads[4]["configuration_name"] != "shop" || payloads[5]["configuration_name"] != "shop" {
+		t.Errorf("payloads = %v, want the configuration name sent", payloads[4:])
+	}
+}
+
+func TestGetPaymentStatus(t *testing.T) {
+	t.Parallel()
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if strings.HasSuffix(path, "/unknown") {
+			_, _ = w.Write([]byte(`{"payments":[]}`))
+
+			return
+		}
+		_, _ = w.Write([]byte(`{"payments":[{"reference_id":"order-7","status":"captured","currency":"INR",` +
+			`"amount":{"value":21000,"offset":100},"transactions":[{"id":"pay_1","type":"upi",` +
+			`"status":"failed","error":{"code":"U30","reason":"debit failed"}},{"id":"pay_2","type":"upi",` +
+			`"status":"success","method":{"type":"upi"}}]}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"))
+	ctx := context.Background()
+
+	payment, err := client.GetPaymentStatus(ctx, "shop", "order-7")
+	if err != nil {
+		t.Fatalf("GetPaymentStatus() error = %v", err)
+	}
+	if path != "/v23.0/phone_1/payments/shop/order-7" {
+		t.Errorf("path = %q", path)
+	}
+	if payment.Status != models.PaymentStatusCaptured || payment.Amount.Float() != 210 ||
+		len(payment.Transactions) != 2 || payment.Transactions[0].Error.Code != "U30" ||
+		payment.Transactions[1].Status != models.TransactionStatusSuccess {
+		t.Errorf("GetPaymentStatus() = %+v", payment)
+	}
+
+	if _, err = client.GetPaymentStatus(ctx, "shop", "unknown"); !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("GetPaymentStatus() error = %v, want %v", err, ErrPaymentNotFound)
+	}
+}
@@ -0,0 +1,328 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	DefaultPoolWorkers   = 16
+	DefaultPoolQueueSize = 1024
+)
+
+var ErrPoolClosed = errors.New("sender pool is closed")
+
+// ErrPoolSaturated is returned by SenderPool.Ready when the queue is full.
+var ErrPoolSaturated = errors.New("sender pool queue is full")
+
+type (
+	// SendFunc sends one message, typically a closure over one of the Client Send methods.
+	SendFunc func(ctx context.Context) (*ResponseMessage, error)
+
+	// SendResult is the outcome of a SendFunc run by a SenderPool.
+	SendResult struct {
+		Response *ResponseMessage
+		Err      error
+	}
+
+	// SenderPoolConfig configures a SenderPool.
+	//
+	// Workers is the maximum number of sends in flight and QueueSize the number of sends
+	// that can wait for a worker before Submit blocks, or fails with ErrPoolSaturated when
+	// RejectWhenFull is set. PerNumberConcurrency caps the sends in flight for a single phone
+	// number ID, zero means no cap other than Workers. The sends of a number at its cap wait
+	// aside without holding a worker, so a busy number does not hold up the others, and
+	// count against QueueSize until they run.
+	SenderPoolConfig struct {
+		Workers              int
+		QueueSize            int
+		PerNumberConcurrency int
+		RejectWhenFull       bool
+	}
+
+	// SenderPoolStats is a snapshot of the gauges of a SenderPool.
+	SenderPoolStats struct {
+		Queued   int
+		InFlight int
+	}
+
+	// SenderPool fans out sends across a bounded number of workers, for high throughput
+	// senders like OTP and alert services.
+	//
+	//	pool := whatsapp.NewSenderPool(&whatsapp.SenderPoolConfig{Workers: 32, PerNumberConcurrency: 8})
+	//	defer pool.Close()
+	//
+	//	results, err := pool.Submit(ctx, phoneNumberID, func(ctx context.Context) (*whatsapp.ResponseMessage, error) {
+	//		return client.SendTextMessage(ctx, recipient, message)
+	//	})
+	//	result := <-results
+	SenderPool struct {
+		config   SenderPoolConfig
+		queue    chan *poolTask
+		pending  chan struct{}
+		closing  chan struct{}
+		closeMu  sync.RWMutex
+		closed   bool
+		mu       sync.Mutex
+		inflight map[string]int
+		parked   map[string][]*poolTask
+		active   int64
+		wg       sync.WaitGroup
+		abort    chan struct{}
+		aborted  sync.Once
+		stopped  sync.Once
+	}
+
+	poolTask struct {
+		ctx           context.Context //nolint:containedctx
+		phoneNumberID string
+		send          SendFunc
+		result        chan SendResult
+	}
+)
+
+// NewSenderPool returns a started SenderPool. Zero config values are replaced by
+// DefaultPoolWorkers and DefaultPoolQueueSize.
+func NewSenderPool(config *SenderPoolConfig) *SenderPool {
+	pool := &SenderPool{
+		inflight: make(map[string]int),
+		parked:   make(map[string][]*poolTask),
+		closing:  make(chan struct{}),
+		abort:    make(chan struct{}),
+	}
+	if config != nil {
+		pool.config = *config
+	}
+	if pool.config.Workers <= 0 {
+		pool.config.Workers = DefaultPoolWorkers
+	}
+	if pool.config.QueueSize <= 0 {
+		pool.config.QueueSize = DefaultPoolQueueSize
+	}
+	pool.queue = make(chan *poolTask, pool.config.QueueSize)
+	pool.pending = make(chan struct{}, pool.config.QueueSize)
+
+	pool.wg.Add(pool.config.Workers)
+	for i := 0; i < pool.config.Workers; i++ {
+		go pool.work()
+	}
+
+	return pool
+}
+
+// Submit queues send for the phone number ID and returns a channel that receives its
+// result. It blocks while the queue is full, until ctx is done or the pool is closed, unless
+// the pool rejects the sends when full. ctx is passed to send, a send whose ctx is done
+// before it reaches a worker is not run.
+func (pool *SenderPool) Submit(ctx context.Context, phoneNumberID string, send SendFunc) (<-chan SendResult, error) {
+	task := &poolTask{
+		ctx:           ctx,
+		phoneNumberID: phoneNumberID,
+		send:          send,
+		result:        make(chan SendResult, 1),
+	}
+
+	// submitters hold the read lock while waiting for space in the queue so that Close,
+	// which takes the write lock, never closes the queue under them. Close wakes them up
+	// through closing before taking the lock.
+	pool.closeMu.RLock()
+	defer pool.closeMu.RUnlock()
+	if pool.closed {
+		return nil, ErrPoolClosed
+	}
+
+	// a send is pending from its submission until it runs, parked or not. The queue has room
+	// for every pending send, so sending to it never blocks.
+	if pool.config.RejectWhenFull {
+		select {
+		case pool.pending <- struct{}{}:
+		default:
+			return nil, ErrPoolSaturated
+		}
+	} else {
+		select {
+		case pool.pending <- struct{}{}:
+		case <-pool.closing:
+			return nil, ErrPoolClosed
+		case <-ctx.Done():
+			return nil, ctx.Err() //nolint:wrapcheck
+		}
+	}
+	pool.queue <- task
+
+	return task.result, nil
+}
+
+// Stats returns the number of queued sends and of sends in flight. The sends waiting for
+// the cap of their phone number are counted as queued.
+func (pool *SenderPool) Stats() SenderPoolStats {
+	return SenderPoolStats{
+		Queued:   len(pool.pending),
+		InFlight: int(atomic.LoadInt64(&pool.active)),
+	}
+}
+
+// Ready reports whether the pool accepts sends without blocking: it returns ErrPoolClosed once
+// the pool is closed and ErrPoolSaturated while the queue, parked sends included, is full. It is meant for readiness
+// probes, see webhooks.HealthConfig.
+func (pool *SenderPool) Ready(_ context.Context) error {
+	// the pool is being closed when the lock is held for writing.
+	if !pool.closeMu.TryRLock() {
+		return ErrPoolClosed
+	}
+	defer pool.closeMu.RUnlock()
+	if pool.closed {
+		return ErrPoolClosed
+	}
+	if len(pool.pending) == cap(pool.pending) {
+		return ErrPoolSaturated
+	}
+
+	return nil
+}
+
+// InFlight returns the number of sends in flight for the phone number ID.
+func (pool *SenderPool) InFlight(phoneNumberID string) int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return pool.inflight[phoneNumberID]
+}
+
+// Close stops accepting sends and waits for the queued ones to finish.
+func (pool *SenderPool) Close() {
+	pool.stop()
+	pool.wg.Wait()
+}
+
+// Shutdown stops accepting sends and waits for the queued ones to finish, until ctx is done.
+// Then the sends still queued or waiting for the cap of their number are not run, they fail
+// with ErrPoolClosed, and the number of sends that were queued or in flight is returned along
+// with the error of ctx. The submitters blocked on the full queue fail with ErrPoolClosed.
+func (pool *SenderPool) Shutdown(ctx context.Context) (int, error) {
+	pool.stop()
+
+	done := make(chan struct{})
+	go func() {
+		pool.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+		stats := pool.Stats()
+		pool.aborted.Do(func() { close(pool.abort) })
+
+		return stats.Queued + stats.InFlight, ctx.Err() //nolint:wrapcheck
+	}
+}
+
+// stop closes the queue, the sends already queued are still run.
+func (pool *SenderPool) stop() {
+	pool.stopped.Do(func() { close(pool.closing) })
+	pool.closeMu.Lock()
+	defer pool.closeMu.Unlock()
+	if pool.closed {
+		return
+	}
+	pool.closed = true
+	close(pool.queue)
+}
+
+func (pool *SenderPool) work() {
+	defer pool.wg.Done()
+	for task := range pool.queue {
+		if !pool.acquire(task) {
+			continue
+		}
+		// a send that finishes hands its slot over to the next parked send of the number
+		for task != nil {
+			<-pool.pending
+			task.result <- pool.run(task)
+			task = pool.release(task.phoneNumberID)
+		}
+	}
+}
+
+func (pool *SenderPool) run(task *poolTask) SendResult {
+	if err := task.ctx.Err(); err != nil {
+		return SendResult{Err: err}
+	}
+	select {
+	case <-pool.abort:
+		return SendResult{Err: ErrPoolClosed}
+	default:
+	}
+
+	response, err := task.send(task.ctx)
+
+	return SendResult{Response: response, Err: err}
+}
+
+// acquire takes a slot of the phone number of task. When the number is at its cap, the task
+// is parked until one of the sends in flight for the number releases its slot, and false is
+// returned so that the worker moves on to the next queued send.
+func (pool *SenderPool) acquire(task *poolTask) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	id := task.phoneNumberID
+	if pool.config.PerNumberConcurrency > 0 && pool.inflight[id] >= pool.config.PerNumberConcurrency {
+		pool.parked[id] = append(pool.parked[id], task)
+
+		return false
+	}
+	pool.inflight[id]++
+	atomic.AddInt64(&pool.active, 1)
+
+	return true
+}
+
+// release releases the slot of the phone number ID. It returns the next parked send of the
+// number, if any, which takes the slot over.
+func (pool *SenderPool) release(phoneNumberID string) *poolTask {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if parked := pool.parked[phoneNumberID]; len(parked) > 0 {
+		next := parked[0]
+		parked[0] = nil
+		if len(parked) == 1 {
+			delete(pool.parked, phoneNumberID)
+		} else {
+			pool.parked[phoneNumberID] = parked[1:]
+		}
+
+		return next
+	}
+	atomic.AddInt64(&pool.active, -1)
+	pool.inflight[phoneNumberID]--
+	if pool.inflight[phoneNumberID] == 0 {
+		delete(pool.inflight, phoneNumberID)
+	}
+
+	return nil
+}
@@ -0,0 +1,382 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSenderPoolConcurrency(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		config       *SenderPoolConfig
+		numbers      []string
+		wantMax      int64
+		wantMaxPerID int64
+	}{
+		{
+			name:         "bounded by workers",
+			config:       &SenderPoolConfig{Workers: 3},
+			numbers:      []string{"a"},
+			wantMax:      3,
+			wantMaxPerID: 3,
+		},
+		{
+			name:         "bounded per number",
+			config:       &SenderPoolConfig{Workers: 4, PerNumberConcurrency: 1},
+			numbers:      []string{"a", "b"},
+			wantMax:      2,
+			wantMaxPerID: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pool := NewSenderPool(tt.config)
+
+			var (
+				mu       sync.Mutex
+				active   int64
+				max      int64
+				perID    = map[string]int64{}
+				maxPerID int64
+			)
+			send := func(id string) SendFunc {
+				return func(ctx context.Context) (*ResponseMessage, error) {
+					mu.Lock()
+					active++
+					perID[id]++
+					if active > max {
+						max = active
+					}
+					if perID[id] > maxPerID {
+						maxPerID = perID[id]
+					}
+					mu.Unlock()
+
+					time.Sleep(5 * time.Millisecond)
+
+					mu.Lock()
+					active--
+					perID[id]--
+					mu.Unlock()
+
+					return &ResponseMessage{Messages: []*MessageID{{ID: id}}}, nil
+				}
+			}
+
+			var results []<-chan SendResult
+			for i := 0; i < 12; i++ {
+				id := tt.numbers[i%len(tt.numbers)]
+				result, err := pool.Submit(context.TODO(), id, send(id))
+				if err != nil {
+					t.Fatalf("Submit() error = %v", err)
+				}
+				results = append(results, result)
+			}
+			for _, result := range results {
+				if r := <-result; r.Err != nil || r.Response == nil {
+					t.Errorf("result = %+v", r)
+				}
+			}
+			pool.Close()
+
+			if max > tt.wantMax {
+				t.Errorf("max in flight = %d, want %d", max, tt.wantMax)
+			}
+			if maxPerID > tt.wantMaxPerID {
+				t.Errorf("max in flight per number = %d, want %d", maxPerID, tt.wantMaxPerID)
+			}
+		})
+	}
+}
+
+func TestSenderPoolStatsAndClose(t *testing.T) {
+	t.Parallel()
+	pool := NewSenderPool(&SenderPoolConfig{Workers: 1, QueueSize: 2})
+	release := make(chan struct{})
+	var sent int32
+	send := func(ctx context.Context) (*ResponseMessage, error) {
+		<-release
+		atomic.AddInt32(&sent, 1)
+
+		return &ResponseMessage{}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := pool.Submit(context.TODO(), "a", send); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+
+	// wait for the worker to pick up the first send
+	for pool.Stats().InFlight != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := pool.Stats(); stats.Queued != 2 {
+		t.Errorf("Stats().Queued = %d, want 2", stats.Queued)
+	}
+	if got := pool.InFlight("a"); got != 1 {
+		t.Errorf("InFlight(a) = %d, want 1", got)
+	}
+
+	if err := pool.Ready(context.TODO()); !errors.Is(err, ErrPoolSaturated) {
+		t.Errorf("Ready() on full queue error = %v, want %v", err, ErrPoolSaturated)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Submit(ctx, "a", send); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit() on full queue error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	pool.Close()
+	if got := atomic.LoadInt32(&sent); got != 3 {
+		t.Errorf("sent %d messages, want 3 queued before Close", got)
+	}
+	if _, err := pool.Submit(context.TODO(), "a", send); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Submit() after Close error = %v, want %v", err, ErrPoolClosed)
+	}
+	if err := pool.Ready(context.TODO()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Ready() after Close error = %v, want %v", err, ErrPoolClosed)
+	}
+}
+
+func TestSenderPoolShutdown(t *testing.T) {
+	t.Parallel()
+	pool := NewSenderPool(&SenderPoolConfig{Workers: 1, QueueSize: 3})
+	release := make(chan struct{})
+	send := func(ctx context.Context) (*ResponseMessage, error) {
+		<-release
+
+		return &ResponseMessage{}, nil
+	}
+
+	results := make([]<-chan SendResult, 0, 3)
+	for i := 0; i < 3; i++ {
+		result, err := pool.Submit(context.TODO(), "a", send)
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		results = append(results, result)
+	}
+	for pool.Stats().InFlight != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the deadline passes with one send in flight and two queued
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	pending, err := pool.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || pending != 3 {
+		t.Errorf("Shutdown() = %d, %v, want 3, %v", pending, err, context.DeadlineExceeded)
+	}
+	if _, err = pool.Submit(context.TODO(), "a", send); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Submit() after Shutdown error = %v, want %v", err, ErrPoolClosed)
+	}
+
+	// the send in flight completes, the queued ones are not run
+	close(release)
+	if result := <-results[0]; result.Err != nil {
+		t.Errorf("in flight send error = %v", result.Err)
+	}
+	for _, results := range results[1:] {
+		if result := <-results; !errors.Is(result.Err, ErrPoolClosed) {
+			t.Errorf("queued send error = %v, want %v", result.Err, ErrPoolClosed)
+		}
+	}
+	if pending, err = pool.Shutdown(context.Background()); pending != 0 || err != nil {
+		t.Errorf("Shutdown() once drained = %d, %v, want 0, nil", pending, err)
+	}
+}
+
+func TestSenderPoolShutdownBlockedSubmit(t *testing.T) {
+	t.Parallel()
+	pool := NewSenderPool(&SenderPoolConfig{Workers: 2, QueueSize: 2, PerNumberConcurrency: 1})
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	releaseAll := func() { releaseOnce.Do(func() { close(release) }) }
+	defer releaseAll()
+	send := func(ctx context.Context) (*ResponseMessage, error) {
+		<-release
+
+		return &ResponseMessage{}, nil
+	}
+	submit := func(phoneNumberID string, until func(stats SenderPoolStats) bool) <-chan SendResult {
+		t.Helper()
+		result, err := pool.Submit(context.TODO(), phoneNumberID, send)
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		for !until(pool.Stats()) {
+			time.Sleep(time.Millisecond)
+		}
+
+		return result
+	}
+
+	// both workers busy, a send of a parked behind its cap and another one filling the queue
+	sent := submit("a", func(stats SenderPoolStats) bool { return stats.InFlight == 1 })
+	parked := submit("a", func(stats SenderPoolStats) bool { return stats.Queued == 1 && len(pool.queue) == 0 })
+	other := submit("b", func(stats SenderPoolStats) bool { return stats.InFlight == 2 })
+	queued := submit("a", func(stats SenderPoolStats) bool { return len(pool.queue) == 1 })
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := pool.Submit(context.Background(), "b", send)
+		blocked <- err
+	}()
+	// the submitter holds the read lock of the pool while it waits for the queue
+	for pool.closeMu.TryLock() {
+		pool.closeMu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := pool.Shutdown(ctx)
+		shutdown <- err
+	}()
+	select {
+	case err := <-shutdown:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown() is blocked by the submitter waiting on the full queue")
+	}
+	if err := <-blocked; !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("blocked Submit() error = %v, want %v", err, ErrPoolClosed)
+	}
+
+	// the sends in flight complete, the parked and the queued ones are not run
+	releaseAll()
+	for _, result := range []<-chan SendResult{sent, other} {
+		if got := <-result; got.Err != nil {
+			t.Errorf("in flight send error = %v", got.Err)
+		}
+	}
+	for _, result := range []<-chan SendResult{parked, queued} {
+		if got := <-result; !errors.Is(got.Err, ErrPoolClosed) {
+			t.Errorf("pending send error = %v, want %v", got.Err, ErrPoolClosed)
+		}
+	}
+}
+
+func TestSenderPoolBusyNumber(t *testing.T) {
+	t.Parallel()
+	pool := NewSenderPool(&SenderPoolConfig{Workers: 2, PerNumberConcurrency: 1})
+	defer pool.Close()
+	release := make(chan struct{})
+	var releaseOnce sync.Once
+	releaseAll := func() { releaseOnce.Do(func() { close(release) }) }
+	defer releaseAll()
+	busy := func(ctx context.Context) (*ResponseMessage, error) {
+		<-release
+
+		return &ResponseMessage{}, nil
+	}
+
+	results := make([]<-chan SendResult, 0, 3)
+	for i := 0; i < 3; i++ {
+		result, err := pool.Submit(context.TODO(), "busy", busy)
+		if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		results = append(results, result)
+	}
+	other, err := pool.Submit(context.TODO(), "other", func(ctx context.Context) (*ResponseMessage, error) {
+		return &ResponseMessage{}, nil
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	// the sends waiting for the busy number do not hold the second worker
+	select {
+	case result := <-other:
+		if result.Err != nil {
+			t.Errorf("other number send error = %v", result.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the send of the other number is stuck behind the busy number")
+	}
+	if stats := pool.Stats(); stats.InFlight != 1 || stats.Queued != 2 {
+		t.Errorf("Stats() = %+v, want 1 in flight and 2 queued", stats)
+	}
+
+	releaseAll()
+	for _, result := range results {
+		if r := <-result; r.Err != nil {
+			t.Errorf("busy number send error = %v", r.Err)
+		}
+	}
+}
+
+func TestSenderPoolParkedBackpressure(t *testing.T) {
+	t.Parallel()
+	pool := NewSenderPool(&SenderPoolConfig{
+		Workers: 2, QueueSize: 2, PerNumberConcurrency: 1, RejectWhenFull: true,
+	})
+	defer pool.Close()
+	release := make(chan struct{})
+	defer close(release)
+	busy := func(ctx context.Context) (*ResponseMessage, error) {
+		<-release
+
+		return &ResponseMessage{}, nil
+	}
+
+	if _, err := pool.Submit(context.TODO(), "busy", busy); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	for pool.Stats().InFlight != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	// the parked sends of the busy number fill the queue
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		if _, err := pool.Submit(context.TODO(), "busy", busy); errors.Is(err, ErrPoolSaturated) {
+			break
+		} else if err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+		accepted++
+	}
+	if accepted != 2 {
+		t.Errorf("accepted %d sends behind the busy one, want 2", accepted)
+	}
+	if stats := pool.Stats(); stats.Queued != 2 || stats.InFlight != 1 {
+		t.Errorf("Stats() = %+v, want 2 queued and 1 in flight", stats)
+	}
+	if err := pool.Ready(context.TODO()); !errors.Is(err, ErrPoolSaturated) {
+		t.Errorf("Ready() error = %v, want %v", err, ErrPoolSaturated)
+	}
+}
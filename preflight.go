@@ -0,0 +1,102 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+type (
+	// templatePayload holds the fields of a template message checked by the preflight.
+	templatePayload struct {
+		Type     string           `json:"type"`
+		Template *models.Template `json:"template"`
+	}
+
+	skipPreflightKey struct{}
+)
+
+// WithTemplatePreflight checks, before a template message is sent, that its template exists
+// in the requested language and is approved, and that its components match the definition of
+// the template, using the templates cached by cache. The sends of missing, paused or otherwise
+// unapproved templates, and of templates with mismatched parameters, fail without reaching the
+// API with the errors returned by MetadataCache.ValidateTemplateMessage, instead of being
+// accepted and failing later in a status webhook.
+//
+// A nil cache is replaced by a MetadataCache of the client with the default configuration.
+// Use MetadataCache.InvalidateTemplates after a template is created or edited, or a short
+// MetadataCacheConfig.TemplatesTTL, so that the preflight sees the changes.
+func WithTemplatePreflight(cache *MetadataCache) ClientOption {
+	return func(client *Client) {
+		client.preflight = true
+		client.templates = cache
+	}
+}
+
+// WithoutTemplatePreflight returns a context whose template sends are not checked by the
+// preflight, e.g. to send a template created a moment ago.
+func WithoutTemplatePreflight(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipPreflightKey{}, true)
+}
+
+// templatePreflightMiddleware validates the templates of the template messages with cache.
+func templatePreflightMiddleware(cache *MetadataCache) whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			if skip, _ := ctx.Value(skipPreflightKey{}).(bool); skip || !isMessageRequest(request) {
+				return next.Send(ctx, request, v)
+			}
+			template, ok := templateOf(request)
+			if !ok {
+				return next.Send(ctx, request, v)
+			}
+			if err := cache.ValidateTemplateMessage(ctx, template); err != nil {
+				return fmt.Errorf("template preflight: %w", err)
+			}
+
+			return next.Send(ctx, request, v)
+		})
+	}
+}
+
+// templateOf returns the template of a template message. Streamed payloads are not read, as
+// that would consume them.
+func templateOf(request *whttp.Request) (*models.Template, bool) {
+	if _, ok := request.Payload.(io.Reader); ok {
+		return nil, false
+	}
+	body, err := request.BodyBytes()
+	if err != nil {
+		return nil, false
+	}
+	var payload templatePayload
+	if err = json.Unmarshal(body, &payload); err != nil || payload.Type != templateMessageType ||
+		payload.Template == nil || payload.Template.Language == nil {
+		return nil, false
+	}
+
+	return payload.Template, true
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestTemplatePreflight(t *testing.T) {
+	t.Parallel()
+	var (
+		lists int32
+		sends int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/message_templates") && r.URL.Query().Get("after") == "":
+			atomic.AddInt32(&lists, 1)
+			_, _ = w.Write([]byte(`{"data":[` +
+				`{"name":"order_shipped","language":"en_US","status":"APPROVED"},` +
+				`{"name":"order_shipped","language":"fr","status":"APPROVED"},` +
+				`{"name":"welcome","language":"en_US","status":"APPROVED",` +
+				`"components":[{"type":"BODY","text":"Hi {{1}}"}]}],` +
+				`"paging":{"cursors":{"after":"page2"},"next":"https://graph.facebook.com/next"}}`))
+		case strings.HasSuffix(r.URL.Path, "/message_templates"):
+			atomic.AddInt32(&lists, 1)
+			_, _ = w.Write([]byte(`{"data":[` +
+				`{"name":"sale","language":"en_US","status":"PAUSED"},` +
+				`{"name":"promo","language":"en_US","status":"PENDING"}]}`))
+		default:
+			atomic.AddInt32(&sends, 1)
+			_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1"),
+		WithBusinessAccountID("waba_1"), WithTemplatePreflight(nil))
+	send := func(ctx context.Context, name, language string) error {
+		_, err := client.SendTemplate(ctx, "255700000000", &Template{Name: name, LanguageCode: language})
+
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		language string
+		want     []error
+	}{
+		{name: "approved", template: "order_shipped", language: "en_US"},
+		{name: "second page", template: "sale", language: "en_US", want: []error{ErrTemplateNotApproved, ErrTemplatePaused}},
+		{name: "pending", template: "promo", language: "en_US", want: []error{ErrTemplateNotApproved}},
+		{name: "missing", template: "welcome_back", language: "en_US", want: []error{ErrTemplateNotFound}},
+		{
+			name:     "language unavailable",
+			template: "order_shipped",
+			language: "sw",
+			want:     []error{ErrTemplateNotFound, ErrTemplateLanguageUnavailable},
+		},
+	}
+	for _, tt := range tests {
+		before := atomic.LoadInt32(&sends)
+		err := send(context.TODO(), tt.template, tt.language)
+		for _, want := range tt.want {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: SendTemplate() error = %v, want %v", tt.name, err, want)
+			}
+		}
+		sent := atomic.LoadInt32(&sends) - before
+		if len(tt.want) == 0 && (err != nil || sent != 1) {
+			t.Errorf("%s: SendTemplate() error = %v, sent %d, want sent", tt.name, err, sent)
+		}
+		if len(tt.want) > 0 && sent != 0 {
+			t.Errorf("%s: sent %d messages, want none", tt.name, sent)
+		}
+	}
+
+	// the components are checked against the definition of the template
+	welcome := &Template{Name: "welcome", LanguageCode: "en_US"}
+	_, err := client.SendTemplate(context.TODO(), "255700000000", welcome)
+	if !errors.Is(err, ErrTemplateParameterMismatch) {
+		t.Errorf("SendTemplate() error = %v, want %v", err, ErrTemplateParameterMismatch)
+	}
+	welcome.Components = []*models.TemplateComponent{
+		{Type: "body", Parameters: []*models.TemplateParameter{{Type: "text", Text: "Jane"}}},
+	}
+	if _, err := client.SendTemplate(context.TODO(), "255700000000", welcome); err != nil {
+		t.Errorf("SendTemplate() error = %v", err)
+	}
+
+	if err := send(WithoutTemplatePreflight(context.TODO()), "welcome_back", "en_US"); err != nil {
+		t.Errorf("SendTemplate() without preflight error = %v", err)
+	}
+	// the other messages are not checked
+	if _, err := client.SendMessage(context.TODO(), models.NewMessage("255700000000", models.WithText("hi"))); err != nil {
+		t.Errorf("SendMessage() error = %v", err)
+	}
+	// both pages were fetched once and cached
+	if got := atomic.LoadInt32(&lists); got != 2 {
+		t.Errorf("listed templates %d times, want 2", got)
+	}
+}
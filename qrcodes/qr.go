@@ -64,13 +64,27 @@ type (
 	}
 )
 
+// Client sends qr code requests through a whttp.Sender, so that the middlewares that wrap
+// the sender apply to them as well.
+type Client struct {
+	sender whttp.Sender
+}
+
+// NewClient creates a new Client that sends the requests using sender.
+func NewClient(sender whttp.Sender) *Client {
+	return &Client{sender: sender}
+}
+
 func Create(ctx context.Context, client *http.Client, rtx *RequestContext,
 	req *CreateRequest, hooks ...whttp.Hook,
 ) (*CreateResponse, error) {
+	return NewClient(whttp.NewSender(client, hooks...)).Create(ctx, rtx, req)
+}
+
+func (c *Client) Create(ctx context.Context, rtx *RequestContext, req *CreateRequest) (*CreateResponse, error) {
 	queryParams := map[string]string{
 		"prefilled_message": req.PrefilledMessage,
 		"generate_qr_image": string(req.ImageFormat),
-		"access_token":      rtx.AccessToken,
 	}
 	reqCtx := &whttp.RequestContext{
 		Name:       "create qr code",
@@ -83,19 +97,22 @@ func Create(ctx context.Context, client *http.Client, rtx *RequestContext,
 		Context: reqCtx,
 		Method:  http.MethodPost,
 		Query:   queryParams,
+		Bearer:  rtx.AccessToken,
 	}
 
-	var response CreateResponse
-
-	err := whttp.Do(ctx, client, params, &response, hooks...)
+	response, err := whttp.SendTyped[CreateResponse](ctx, c.sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("qr code create: %w", err)
 	}
 
-	return &response, nil
+	return response, nil
 }
 
 func List(ctx context.Context, client *http.Client, rctx *RequestContext, hooks ...whttp.Hook) (*ListResponse, error) {
+	return NewClient(whttp.NewSender(client, hooks...)).List(ctx, rctx)
+}
+
+func (c *Client) List(ctx context.Context, rctx *RequestContext) (*ListResponse, error) {
 	reqCtx := &whttp.RequestContext{
 		Name:       "list qr codes",
 		BaseURL:    rctx.BaseURL,
@@ -107,16 +124,15 @@ func List(ctx context.Context, client *http.Client, rctx *RequestContext, hooks
 	req := &whttp.Request{
 		Context: reqCtx,
 		Method:  http.MethodGet,
-		Query:   map[string]string{"access_token": rctx.AccessToken},
+		Bearer:  rctx.AccessToken,
 	}
 
-	var response ListResponse
-	err := whttp.Do(ctx, client, req, &response, hooks...)
+	response, err := whttp.SendTyped[ListResponse](ctx, c.sender, req)
 	if err != nil {
 		return nil, fmt.Errorf("qr code list: %w", err)
 	}
 
-	return &response, nil
+	return response, nil
 }
 
 type RequestContext struct {
@@ -131,6 +147,10 @@ var ErrNoDataFound = fmt.Errorf("no data found")
 func Get(ctx context.Context, client *http.Client, rctx *RequestContext, qrCodeID string,
 	hooks ...whttp.Hook,
 ) (*Information, error) {
+	return NewClient(whttp.NewSender(client, hooks...)).Get(ctx, rctx, qrCodeID)
+}
+
+func (c *Client) Get(ctx context.Context, rctx *RequestContext, qrCodeID string) (*Information, error) {
 	var (
 		list ListResponse
 		resp Information
@@ -146,10 +166,10 @@ func Get(ctx context.Context, client *http.Client, rctx *RequestContext, qrCodeI
 	req := &whttp.Request{
 		Context: reqCtx,
 		Method:  http.MethodGet,
-		Query:   map[string]string{"access_token": rctx.AccessToken},
+		Bearer:  rctx.AccessToken,
 	}
 
-	err := whttp.Do(ctx, client, req, &list, hooks...)
+	err := c.sender.Send(ctx, req, &list)
 	if err != nil {
 		return nil, fmt.Errorf("qr code get: %w", err)
 	}
@@ -166,6 +186,12 @@ func Get(ctx context.Context, client *http.Client, rctx *RequestContext, qrCodeI
 func Update(ctx context.Context, client *http.Client, rtx *RequestContext, qrCodeID string,
 	req *CreateRequest, hooks ...whttp.Hook) (*SuccessResponse, error,
 ) {
+	return NewClient(whttp.NewSender(client, hooks...)).Update(ctx, rtx, qrCodeID, req)
+}
+
+func (c *Client) Update(ctx context.Context, rtx *RequestContext, qrCodeID string,
+	req *CreateRequest,
+) (*SuccessResponse, error) {
 	reqCtx := &whttp.RequestContext{
 		Name:       "update qr code",
 		BaseURL:    rtx.BaseURL,
@@ -180,22 +206,25 @@ func Update(ctx context.Context, client *http.Client, rtx *RequestContext, qrCod
 		Query: map[string]string{
 			"prefilled_message": req.PrefilledMessage,
 			"generate_qr_image": string(req.ImageFormat),
-			"access_token":      rtx.AccessToken,
 		},
+		Bearer: rtx.AccessToken,
 	}
 
-	var resp SuccessResponse
-	err := whttp.Do(ctx, client, request, &resp, hooks...)
+	resp, err := whttp.SendTyped[SuccessResponse](ctx, c.sender, request)
 	if err != nil {
 		return nil, fmt.Errorf("qr code update (%s): %w", qrCodeID, err)
 	}
 
-	return &resp, nil
+	return resp, nil
 }
 
 func Delete(ctx context.Context, client *http.Client, rtx *RequestContext, qrCodeID string,
 	hooks ...whttp.Hook,
 ) (*SuccessResponse, error) {
+	return NewClient(whttp.NewSender(client, hooks...)).Delete(ctx, rtx, qrCodeID)
+}
+
+func (c *Client) Delete(ctx context.Context, rtx *RequestContext, qrCodeID string) (*SuccessResponse, error) {
 	reqCtx := &whttp.RequestContext{
 		Name:       "delete qr code",
 		BaseURL:    rtx.BaseURL,
@@ -207,13 +236,12 @@ func Delete(ctx context.Context, client *http.Client, rtx *RequestContext, qrCod
 	req := &whttp.Request{
 		Context: reqCtx,
 		Method:  http.MethodDelete,
-		Query:   map[string]string{"access_token": rtx.AccessToken},
+		Bearer:  rtx.AccessToken,
 	}
-	var resp SuccessResponse
-	err := whttp.Do(ctx, client, req, &resp, hooks...)
+	resp, err := whttp.SendTyped[SuccessResponse](ctx, c.sender, req)
 	if err != nil {
 		return nil, fmt.Errorf("qr code delete: %w", err)
 	}
 
-	return &resp, nil
+	return resp, nil
 }
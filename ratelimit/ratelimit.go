@@ -0,0 +1,366 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package ratelimit provides a client side rate limiter that is aware of the limits
+// enforced by the WhatsApp Cloud API.
+//
+// The Cloud API enforces two kinds of limits when sending messages:
+//
+//   - Throughput: the number of messages per second a business phone number can send. By default,
+//     a phone number can send up to 80 messages per second, this can be upgraded to 250 and then to
+//     1000 messages per second. Exceeding it results in error 130429.
+//   - Pair rate limit: the number of messages a business phone number can send to the same WhatsApp
+//     user. A business can send 1 message every 6 seconds to the same user, with short bursts allowed.
+//     Exceeding it results in error 131056.
+//
+// Instead of letting bursts fail with the errors above, Limiter queues them locally until they can
+// be sent without breaching the limits. When the API reports that the throughput was exceeded
+// anyway, for example because other systems send from the same phone number, Limiter.Throttle
+// slows the phone number down for a cool-down period and then ramps it back up.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+const (
+	// DefaultMessagesPerSecond is the default throughput of a business phone number.
+	DefaultMessagesPerSecond = 80
+
+	// MaxMessagesPerSecond is the throughput ceiling of a business phone number that has been
+	// upgraded to the higher throughput tier.
+	MaxMessagesPerSecond = 250
+
+	// DefaultPairInterval is the minimum interval between messages sent from the same business
+	// phone number to the same WhatsApp user.
+	DefaultPairInterval = 6 * time.Second
+
+	// DefaultPairBurst is the number of messages that can be sent to the same user in a short
+	// burst before the pair interval kicks in.
+	DefaultPairBurst = 45
+
+	// DefaultCoolDown is how long the throughput of a phone number stays reduced after it
+	// was throttled.
+	DefaultCoolDown = 30 * time.Second
+
+	// DefaultRampUp is how long the throughput takes to go back to its configured value once
+	// the cool-down period ended.
+	DefaultRampUp = time.Minute
+
+	// DefaultThrottleFactor is the factor applied to the throughput each time a phone number
+	// is throttled.
+	DefaultThrottleFactor = 0.5
+
+	// DefaultMinMessagesPerSecond is the throughput a phone number is never throttled below.
+	DefaultMinMessagesPerSecond = 1
+)
+
+type (
+	// Waiter blocks until a message from the phone number identified by phoneNumberID can be sent
+	// to the recipient, or until the context is done.
+	Waiter interface {
+		Wait(ctx context.Context, phoneNumberID, recipient string) error
+	}
+
+	// Throttler is told when the API rejected a message of the phone number because the
+	// throughput was exceeded, retryAfter is the delay asked by the response, zero if none.
+	Throttler interface {
+		Throttle(phoneNumberID string, retryAfter time.Duration)
+	}
+
+	// Config contains the limits applied by the Limiter. Zero values are replaced by the defaults.
+	//
+	// MessagesPerSecond is the throughput allowed per business phone number, Burst is the number of
+	// messages that can be sent at once by a phone number. PairInterval and PairBurst control the
+	// limits applied to a phone number and recipient pair. Clock defaults to clock.System.
+	//
+	// A throttled phone number sends at ThrottleFactor times its current throughput, but
+	// not below MinMessagesPerSecond, for CoolDown, then its throughput grows linearly back
+	// to MessagesPerSecond during RampUp.
+	Config struct {
+		MessagesPerSecond    float64
+		Burst                int
+		PairInterval         time.Duration
+		PairBurst            int
+		CoolDown             time.Duration
+		RampUp               time.Duration
+		ThrottleFactor       float64
+		MinMessagesPerSecond float64
+		Clock                clock.Clock
+	}
+
+	// Limiter implements Waiter using token buckets per phone number and per phone number and
+	// recipient pair.
+	Limiter struct {
+		mu        sync.Mutex
+		config    Config
+		numbers   map[string]*bucket
+		pairs     map[pair]*bucket
+		throttles map[string]*throttle
+		sweep     time.Time
+	}
+
+	// throttle is the reduced throughput of a phone number, rate until the end of the
+	// cool-down period.
+	throttle struct {
+		rate  float64
+		until time.Time
+	}
+
+	pair struct {
+		phoneNumberID string
+		recipient     string
+	}
+)
+
+// NewLimiter creates a new Limiter. If config is nil the default configuration is used.
+func NewLimiter(config *Config) *Limiter {
+	cfg := Config{}
+	if config != nil {
+		cfg = *config
+	}
+	if cfg.MessagesPerSecond <= 0 {
+		cfg.MessagesPerSecond = DefaultMessagesPerSecond
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = int(cfg.MessagesPerSecond)
+	}
+	if cfg.PairInterval <= 0 {
+		cfg.PairInterval = DefaultPairInterval
+	}
+	if cfg.PairBurst <= 0 {
+		cfg.PairBurst = DefaultPairBurst
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = DefaultCoolDown
+	}
+	if cfg.RampUp <= 0 {
+		cfg.RampUp = DefaultRampUp
+	}
+	if cfg.ThrottleFactor <= 0 || cfg.ThrottleFactor >= 1 {
+		cfg.ThrottleFactor = DefaultThrottleFactor
+	}
+	if cfg.MinMessagesPerSecond <= 0 {
+		cfg.MinMessagesPerSecond = DefaultMinMessagesPerSecond
+	}
+	cfg.Clock = clock.OrSystem(cfg.Clock)
+
+	return &Limiter{
+		config:    cfg,
+		numbers:   make(map[string]*bucket),
+		pairs:     make(map[pair]*bucket),
+		throttles: make(map[string]*throttle),
+	}
+}
+
+// Wait blocks until both the throughput of the phone number and the pair rate limit allow the
+// message to be sent. If the context is done before that, the reserved tokens are returned and
+// the context error is returned.
+func (l *Limiter) Wait(ctx context.Context, phoneNumberID, recipient string) error {
+	now := l.config.Clock.Now()
+	l.mu.Lock()
+	nb := l.numberBucket(phoneNumberID, now)
+	l.pace(phoneNumberID, nb, now)
+	pb := l.pairBucket(phoneNumberID, recipient, now)
+	delay := nb.reserve(now)
+	if pd := pb.reserve(now); pd > delay {
+		delay = pd
+	}
+	l.prune(now)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := l.config.Clock.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		nb.cancel()
+		pb.cancel()
+		l.mu.Unlock()
+
+		return fmt.Errorf("rate limit wait: %w", ctx.Err())
+	case <-timer.C():
+		return nil
+	}
+}
+
+// Throttle reduces the throughput of the phone number after the API rejected one of its
+// messages with error 130429 or 80007. The tokens of its bucket are dropped, and when
+// retryAfter is set no message is allowed before it elapsed. Throttling a number again
+// during its cool-down or ramp up reduces its current throughput further.
+func (l *Limiter) Throttle(phoneNumberID string, retryAfter time.Duration) {
+	now := l.config.Clock.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rate := l.rate(phoneNumberID, now) * l.config.ThrottleFactor
+	if rate < l.config.MinMessagesPerSecond {
+		rate = l.config.MinMessagesPerSecond
+	}
+	coolDown := l.config.CoolDown
+	if retryAfter > coolDown {
+		coolDown = retryAfter
+	}
+	l.throttles[phoneNumberID] = &throttle{rate: rate, until: now.Add(coolDown)}
+
+	b := l.numberBucket(phoneNumberID, now)
+	l.pace(phoneNumberID, b, now)
+	if b.tokens > 0 {
+		b.tokens = 0
+	}
+	if retryAfter > 0 {
+		b.tokens -= retryAfter.Seconds() * b.rate
+	}
+}
+
+// Rate returns the current throughput of the phone number in messages per second.
+func (l *Limiter) Rate(phoneNumberID string) float64 {
+	now := l.config.Clock.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate(phoneNumberID, now)
+}
+
+// rate returns the throughput of the phone number at now, it forgets the throttles that
+// ended.
+func (l *Limiter) rate(phoneNumberID string, now time.Time) float64 {
+	full := l.config.MessagesPerSecond
+	t, ok := l.throttles[phoneNumberID]
+	if !ok {
+		return full
+	}
+	if now.Before(t.until) {
+		return t.rate
+	}
+	elapsed := now.Sub(t.until)
+	if elapsed >= l.config.RampUp {
+		delete(l.throttles, phoneNumberID)
+
+		return full
+	}
+
+	return t.rate + (full-t.rate)*float64(elapsed)/float64(l.config.RampUp)
+}
+
+// pace sets the rate of the bucket of the phone number to its current throughput, the
+// tokens accumulated so far are refilled at the previous rate.
+func (l *Limiter) pace(phoneNumberID string, b *bucket, now time.Time) {
+	b.refill(now)
+	b.rate = l.rate(phoneNumberID, now)
+}
+
+func (l *Limiter) numberBucket(phoneNumberID string, now time.Time) *bucket {
+	b, ok := l.numbers[phoneNumberID]
+	if !ok {
+		b = newBucket(l.config.MessagesPerSecond, l.config.Burst, now)
+		l.numbers[phoneNumberID] = b
+	}
+
+	return b
+}
+
+func (l *Limiter) pairBucket(phoneNumberID, recipient string, now time.Time) *bucket {
+	key := pair{phoneNumberID: phoneNumberID, recipient: recipient}
+	b, ok := l.pairs[key]
+	if !ok {
+		b = newBucket(float64(time.Second)/float64(l.config.PairInterval), l.config.PairBurst, now)
+		l.pairs[key] = b
+	}
+
+	return b
+}
+
+// prune removes the pair buckets that have been refilled completely, they hold no state
+// that differs from a newly created bucket. It runs at most once per pair interval.
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.sweep) < l.config.PairInterval {
+		return
+	}
+	l.sweep = now
+	for key, b := range l.pairs {
+		if b.full(now) {
+			delete(l.pairs, key)
+		}
+	}
+}
+
+// bucket is a token bucket. Tokens are allowed to go negative, which represents
+// reservations that are waiting to be fulfilled.
+type bucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int, now time.Time) *bucket {
+	return &bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now,
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// reserve takes a token from the bucket and returns how long the caller has to wait
+// before the token is available.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token back to the bucket.
+func (b *bucket) cancel() {
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+func (b *bucket) full(now time.Time) bool {
+	b.refill(now)
+
+	return b.tokens >= b.burst
+}
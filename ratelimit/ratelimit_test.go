@@ -0,0 +1,163 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+func TestLimiter_Wait(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		config     *Config
+		recipients []string
+		minElapsed time.Duration
+		maxElapsed time.Duration
+	}{
+		{
+			name:       "within burst",
+			config:     &Config{MessagesPerSecond: 10, Burst: 3, PairInterval: time.Second, PairBurst: 3},
+			recipients: []string{"a", "a", "a"},
+			minElapsed: 0,
+			maxElapsed: 50 * time.Millisecond,
+		},
+		{
+			name:       "pair limit exceeded",
+			config:     &Config{MessagesPerSecond: 100, Burst: 100, PairInterval: 100 * time.Millisecond, PairBurst: 1},
+			recipients: []string{"a", "a"},
+			minElapsed: 80 * time.Millisecond,
+			maxElapsed: 300 * time.Millisecond,
+		},
+		{
+			name:       "pair limit is per recipient",
+			config:     &Config{MessagesPerSecond: 100, Burst: 100, PairInterval: time.Second, PairBurst: 1},
+			recipients: []string{"a", "b", "c"},
+			minElapsed: 0,
+			maxElapsed: 50 * time.Millisecond,
+		},
+		{
+			name:       "throughput exceeded",
+			config:     &Config{MessagesPerSecond: 10, Burst: 1, PairInterval: time.Millisecond, PairBurst: 10},
+			recipients: []string{"a", "b"},
+			minElapsed: 80 * time.Millisecond,
+			maxElapsed: 300 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			limiter := NewLimiter(tt.config)
+			start := time.Now()
+			for _, recipient := range tt.recipients {
+				if err := limiter.Wait(context.TODO(), "phone", recipient); err != nil {
+					t.Fatalf("Wait() error = %v", err)
+				}
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.minElapsed || elapsed > tt.maxElapsed {
+				t.Errorf("Wait() took %v, want between %v and %v", elapsed, tt.minElapsed, tt.maxElapsed)
+			}
+		})
+	}
+}
+
+func TestLimiter_WaitContextDone(t *testing.T) {
+	t.Parallel()
+	limiter := NewLimiter(&Config{PairInterval: time.Hour, PairBurst: 1})
+	if err := limiter.Wait(context.TODO(), "phone", "a"); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	err := limiter.Wait(ctx, "phone", "a")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// the cancelled reservation must have been returned to the bucket
+	if tokens := limiter.pairs[pair{"phone", "a"}].tokens; tokens < -0.5 {
+		t.Errorf("tokens = %v, want the reservation to be returned", tokens)
+	}
+}
+
+func TestLimiter_Throttle(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(&Config{
+		MessagesPerSecond: 80,
+		CoolDown:          10 * time.Second,
+		RampUp:            10 * time.Second,
+		Clock:             clk,
+	})
+
+	rates := []struct {
+		advance time.Duration
+		want    float64
+	}{
+		{advance: 0, want: 40},
+		{advance: 5 * time.Second, want: 40},
+		{advance: 10 * time.Second, want: 60},
+		{advance: 5 * time.Second, want: 80},
+	}
+	limiter.Throttle("phone", 0)
+	for _, rate := range rates {
+		clk.Advance(rate.advance)
+		if got := limiter.Rate("phone"); got != rate.want {
+			t.Errorf("Rate() after %v = %v, want %v", rate.advance, got, rate.want)
+		}
+	}
+	if got := limiter.Rate("other"); got != 80 {
+		t.Errorf("Rate() of another phone number = %v, want 80", got)
+	}
+
+	// throttling again during the cool-down reduces the throughput further, down to the minimum
+	for i := 0; i < 10; i++ {
+		limiter.Throttle("phone", 0)
+	}
+	if got := limiter.Rate("phone"); got != DefaultMinMessagesPerSecond {
+		t.Errorf("Rate() after repeated throttles = %v, want %v", got, DefaultMinMessagesPerSecond)
+	}
+}
+
+func TestLimiter_ThrottleRetryAfter(t *testing.T) {
+	t.Parallel()
+	limiter := NewLimiter(&Config{MessagesPerSecond: 100, PairInterval: time.Millisecond, PairBurst: 10})
+	limiter.Throttle("phone", 100*time.Millisecond)
+
+	start := time.Now()
+	if err := limiter.Wait(context.TODO(), "phone", "a"); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Wait() took %v, want to wait for the retry after delay", elapsed)
+	}
+	if err := limiter.Wait(context.TODO(), "other", "a"); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+}
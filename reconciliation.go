@@ -0,0 +1,109 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// DeliveryReport summarizes the deliveries of a tag. Sent counts every tracked message,
+	// Delivered the ones delivered, including those that were read, and Failed the failed
+	// ones, by error code in FailureCodes. A failed message without error is counted under
+	// code 0. Pending counts the messages still waiting for a delivered, read or failed
+	// status, and Overdue holds those accepted longer than the timeout of the report ago.
+	DeliveryReport struct {
+		Tag          string
+		Sent         int
+		Delivered    int
+		Read         int
+		Failed       int
+		Pending      int
+		FailureCodes map[int]int
+		Overdue      []*Delivery
+	}
+
+	deliveryTagKey struct{}
+)
+
+// WithDeliveryTag returns a context carrying the tag of the messages sent with it, for
+// example the name of a campaign. The DeliveryTracker records the tag along with the
+// deliveries, see DeliveryTracker.Report.
+//
+//	ctx = whatsapp.WithDeliveryTag(ctx, "black-friday")
+//	resp, err := client.SendTemplate(ctx, recipient, template)
+func WithDeliveryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, deliveryTagKey{}, tag)
+}
+
+// DeliveryTagFromContext returns the tag set with WithDeliveryTag.
+func DeliveryTagFromContext(ctx context.Context) (string, bool) {
+	tag, ok := ctx.Value(deliveryTagKey{}).(string)
+
+	return tag, ok && tag != ""
+}
+
+// Report reconciles the deliveries of tag, all the deliveries when tag is empty. The pending
+// messages accepted longer than timeout ago are reported overdue, even when a sent status
+// arrived since, none are when timeout is zero.
+func (tracker *DeliveryTracker) Report(ctx context.Context, tag string, timeout time.Duration,
+) (*DeliveryReport, error) {
+	deliveries, err := tracker.Query(ctx, &DeliveryQuery{Tag: tag})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DeliveryReport{Tag: tag, FailureCodes: make(map[int]int)}
+	overdue := tracker.clock.Now().Add(-timeout)
+	for _, delivery := range deliveries {
+		report.Sent++
+		switch delivery.State {
+		case DeliveryRead:
+			report.Read++
+			report.Delivered++
+		case DeliveryDelivered:
+			report.Delivered++
+		case DeliveryFailed:
+			report.Failed++
+			report.FailureCodes[failureCode(delivery)]++
+		case DeliveryAccepted, DeliverySent:
+			report.Pending++
+			if timeout > 0 && acceptedAt(delivery).Before(overdue) {
+				report.Overdue = append(report.Overdue, delivery)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// acceptedAt returns when the API accepted delivery. A delivery only known from its status
+// webhooks has no acceptance time, the time of its first status is used instead.
+func acceptedAt(delivery *Delivery) time.Time {
+	switch {
+	case !delivery.AcceptedAt.IsZero():
+		return delivery.AcceptedAt
+	case !delivery.SentAt.IsZero():
+		return delivery.SentAt
+	default:
+		return delivery.UpdatedAt
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestDeliveryTrackerReport(t *testing.T) {
+	t.Parallel()
+	var sent int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"messaging_product":"whatsapp","messages":[{"id":"wamid.%d"}]}`,
+			atomic.AddInt32(&sent, 1))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	tracker := NewDeliveryTracker(&DeliveryTrackerConfig{Clock: clk})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithClock(clk),
+		WithAuditSink(tracker))
+
+	campaign := WithDeliveryTag(ctx, "promo")
+	for i := 0; i < 5; i++ {
+		if _, err := client.SendText(campaign, "255700000001", "sale"); err != nil {
+			t.Fatalf("SendText() error = %v", err)
+		}
+	}
+	if _, err := client.SendText(ctx, "255700000001", "untagged"); err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+
+	statuses := []struct {
+		id, value string
+		errs      []*werrors.Error
+	}{
+		{id: "wamid.1", value: "delivered"},
+		{id: "wamid.2", value: "read"},
+		{id: "wamid.3", value: "failed", errs: []*werrors.Error{{Code: werrors.CodeUndeliverable}}},
+		{id: "wamid.4", value: "sent"},
+		{id: "wamid.6", value: "failed", errs: []*werrors.Error{{Code: werrors.CodeReEngagement}}},
+	}
+	for _, status := range statuses {
+		if err := tracker.OnMessageStatusChange(ctx, nil, &webhooks.Status{
+			ID: status.id, StatusValue: status.value, Errors: status.errs,
+		}); err != nil {
+			t.Fatalf("OnMessageStatusChange(%s) error = %v", status.id, err)
+		}
+		if status.id == "wamid.4" {
+			clk.Advance(time.Hour)
+		}
+	}
+
+	report, err := tracker.Report(ctx, "promo", 30*time.Minute)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if report.Sent != 5 || report.Delivered != 2 || report.Read != 1 || report.Failed != 1 || report.Pending != 2 {
+		t.Errorf("Report() = %+v", report)
+	}
+	if len(report.FailureCodes) != 1 || report.FailureCodes[werrors.CodeUndeliverable] != 1 {
+		t.Errorf("Report() failure codes = %v, want one %d", report.FailureCodes, werrors.CodeUndeliverable)
+	}
+	// wamid.4 got stuck in the sent state, wamid.5 never got a status
+	overdue := make([]string, 0, len(report.Overdue))
+	for _, delivery := range report.Overdue {
+		overdue = append(overdue, delivery.MessageID)
+	}
+	if fmt.Sprint(overdue) != "[wamid.4 wamid.5]" {
+		t.Errorf("Report() overdue = %v, want [wamid.4 wamid.5]", overdue)
+	}
+
+	all, err := tracker.Report(ctx, "", 0)
+	if err != nil || all.Sent != 6 || all.Failed != 2 || len(all.Overdue) != 0 {
+		t.Errorf("Report() of all the deliveries = %+v, %v", all, err)
+	}
+}
+
+func TestDeliveryTrackerReportOverdueSinceAccepted(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	tracker := NewDeliveryTracker(&DeliveryTrackerConfig{Clock: clk})
+	for _, id := range []string{"wamid.1", "wamid.2"} {
+		if err := tracker.Track(ctx, &Delivery{MessageID: id, Recipient: "255700000001", AcceptedAt: now}); err != nil {
+			t.Fatalf("Track(%s) error = %v", id, err)
+		}
+	}
+
+	// wamid.1 is sent partway through the timeout and never delivered, wamid.2 is delivered
+	clk.Advance(20 * time.Minute)
+	for id, value := range map[string]string{"wamid.1": "sent", "wamid.2": "delivered"} {
+		if err := tracker.OnMessageStatusChange(ctx, nil, &webhooks.Status{ID: id, StatusValue: value}); err != nil {
+			t.Fatalf("OnMessageStatusChange(%s) error = %v", id, err)
+		}
+	}
+
+	clk.Advance(20 * time.Minute)
+	report, err := tracker.Report(ctx, "", 30*time.Minute)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(report.Overdue) != 1 || report.Overdue[0].MessageID != "wamid.1" {
+		t.Errorf("Report() overdue = %+v, want wamid.1", report.Overdue)
+	}
+}
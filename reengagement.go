@@ -0,0 +1,134 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+type (
+	// ReEngagementTemplateFunc returns the template sent in place of message, a free-form
+	// message to recipient rejected because the customer service window is closed. The
+	// template must be approved, its parameters are typically derived from message or from
+	// what the application knows about recipient. Returning a nil template, or an error,
+	// skips the fallback and the send fails with the original error.
+	ReEngagementTemplateFunc func(ctx context.Context, recipient string, message *models.Message) (*models.Template, error)
+
+	// ReEngagementConfig configures the fallback installed by WithReEngagementFallback.
+	// Template is required. OnFallback, when set, is called after the fallback template was
+	// sent, err is the error of that send.
+	ReEngagementConfig struct {
+		Template   ReEngagementTemplateFunc
+		OnFallback func(ctx context.Context, recipient string, fallback *ReEngagementFallback, err error)
+	}
+
+	// ReEngagementFallback reports that a template was sent in place of a free-form message,
+	// see WithReEngagementFallback. Cause is the error the free-form message failed with.
+	ReEngagementFallback struct {
+		Template *models.Template
+		Cause    error
+	}
+)
+
+// WithReEngagementFallback sends an approved template in place of the free-form messages
+// rejected with the error 131047, because more than 24 hours passed since the recipient last
+// replied. The template is returned by config.Template. When the fallback is taken, the
+// ResponseMessage of the send has its Fallback field set and holds the ID of the template
+// message.
+//
+//	client := whatsapp.NewClient(whatsapp.WithReEngagementFallback(&whatsapp.ReEngagementConfig{
+//		Template: func(ctx context.Context, recipient string, message *models.Message) (*models.Template, error) {
+//			return models.NewTextTemplate("follow_up", &models.TemplateLanguage{Code: "en_US"}, nil), nil
+//		},
+//	}))
+func WithReEngagementFallback(config *ReEngagementConfig) ClientOption {
+	return func(client *Client) {
+		client.reEngagement = config
+	}
+}
+
+// reEngagementMiddleware sends the fallback template of the free-form messages rejected
+// because the customer service window is closed.
+func reEngagementMiddleware(config *ReEngagementConfig) whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			response, ok := v.(*ResponseMessage)
+			if !ok || !isMessageRequest(request) || isMarketingRequest(request) {
+				return next.Send(ctx, request, v)
+			}
+			err := next.Send(ctx, request, v)
+			if code, _ := werrors.Code(err); code != werrors.CodeReEngagement {
+				return err
+			}
+			message, ok := freeFormMessageOf(request)
+			if !ok {
+				return err
+			}
+			template, terr := config.Template(ctx, message.To, message)
+			if terr != nil || template == nil {
+				return err
+			}
+
+			fallback := &ReEngagementFallback{Template: template, Cause: err}
+			payload := models.NewMessage(message.To, models.WithTemplate(template))
+			payload.BizOpaqueCallbackData = message.BizOpaqueCallbackData
+			reqCtx := *request.Context
+			reqCtx.Name = "send re-engagement template"
+			fallbackRequest := *request
+			fallbackRequest.Context = &reqCtx
+			fallbackRequest.Payload = payload
+
+			*response = ResponseMessage{}
+			err = next.Send(ctx, &fallbackRequest, response)
+			if err == nil {
+				response.Fallback = fallback
+			}
+			if config.OnFallback != nil {
+				config.OnFallback(ctx, message.To, fallback, err)
+			}
+
+			return err
+		})
+	}
+}
+
+// freeFormMessageOf decodes the message sent by request, it returns false for template
+// messages, which the fallback does not apply to, and for streamed payloads.
+func freeFormMessageOf(request *whttp.Request) (*models.Message, bool) {
+	if _, ok := request.Payload.(io.Reader); ok {
+		return nil, false
+	}
+	body, err := request.BodyBytes()
+	if err != nil {
+		return nil, false
+	}
+	var message models.Message
+	if err = json.Unmarshal(body, &message); err != nil || message.To == "" || message.Type == templateMessageType {
+		return nil, false
+	}
+
+	return &message, true
+}
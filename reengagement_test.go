@@ -0,0 +1,113 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestReEngagementFallback(t *testing.T) {
+	t.Parallel()
+	var (
+		mu       sync.Mutex
+		messages []*models.Message
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message models.Message
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		mu.Lock()
+		messages = append(messages, &message)
+		mu.Unlock()
+		if message.Type != templateMessageType && message.To != "255700000001" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Re-engagement message","type":"OAuthException","code":131047}}`))
+
+			return
+		}
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	var fallbacks []string
+	client := NewClient(WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1"),
+		WithReEngagementFallback(&ReEngagementConfig{
+			Template: func(ctx context.Context, recipient string, message *models.Message) (*models.Template, error) {
+				if recipient == "255700000002" {
+					return nil, nil
+				}
+				body := []*models.TemplateParameter{{Type: "text", Text: message.Text.Body}}
+
+				return models.NewTextTemplate("follow_up", &models.TemplateLanguage{Code: "en_US"}, body), nil
+			},
+			OnFallback: func(ctx context.Context, recipient string, fallback *ReEngagementFallback, err error) {
+				fallbacks = append(fallbacks, recipient)
+			},
+		}))
+
+	// the free-form message is replaced by the template
+	response, err := client.SendText(context.TODO(), "255700000000", "Your order shipped", WithCallbackData("order-1"))
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if response.Fallback == nil || response.Fallback.Template.Name != "follow_up" {
+		t.Fatalf("SendText() Fallback = %+v, want the follow_up template", response.Fallback)
+	}
+	if code, _ := werrors.Code(response.Fallback.Cause); code != werrors.CodeReEngagement {
+		t.Errorf("Fallback.Cause = %v, want code %d", response.Fallback.Cause, werrors.CodeReEngagement)
+	}
+	if len(response.Messages) != 1 || response.Messages[0].ID != "wamid.1" {
+		t.Errorf("SendText() = %+v, want the template message ID", response)
+	}
+
+	// messages sent within the window do not fall back
+	response, err = client.SendText(context.TODO(), "255700000001", "Hi")
+	if err != nil || response.Fallback != nil {
+		t.Fatalf("SendText() = %+v, %v, want no fallback", response, err)
+	}
+
+	// without a template the original error is returned
+	_, err = client.SendText(context.TODO(), "255700000002", "Hi")
+	if code, _ := werrors.Code(err); code != werrors.CodeReEngagement {
+		t.Fatalf("SendText() error = %v, want code %d", err, werrors.CodeReEngagement)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(messages) != 4 {
+		t.Fatalf("got %d messages, want 4", len(messages))
+	}
+	template := messages[1]
+	if template.Type != templateMessageType || template.To != "255700000000" ||
+		template.BizOpaqueCallbackData != "order-1" ||
+		template.Template.Components[0].Parameters[0].Text != "Your order shipped" {
+		t.Errorf("fallback message = %+v", template)
+	}
+	if len(fallbacks) != 1 || fallbacks[0] != "255700000000" {
+		t.Errorf("OnFallback calls = %v, want 255700000000", fallbacks)
+	}
+}
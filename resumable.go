@@ -0,0 +1,400 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+const (
+	DefaultUploadChunkSize   = 4 * 1024 * 1024
+	DefaultUploadParallelism = 4
+	DefaultUploadRetries     = 3
+	DefaultUploadRetryDelay  = 500 * time.Millisecond
+)
+
+var (
+	ErrUploadAppIDMissing = errors.New("resumable upload: app id is not set")
+	ErrUploadNoHandle     = errors.New("resumable upload: the session completed without a file handle")
+)
+
+type (
+	// ResumableUploadConfig configures UploadResumable. AppID is the ID of the app that owns
+	// the upload sessions, it is required.
+	//
+	// The file is split in chunks of ChunkSize bytes, up to Parallelism of them are uploaded
+	// at the same time. A chunk that fails is retried up to Retries times, a negative value
+	// disables the retries, waiting RetryDelay before the first retry and doubling it for
+	// every other. OnProgress, when set, is called
+	// after every uploaded chunk with the number of bytes of the file uploaded so far.
+	ResumableUploadConfig struct {
+		AppID       string
+		ChunkSize   int64
+		Parallelism int
+		Retries     int
+		RetryDelay  time.Duration
+		Clock       clock.Clock
+		OnProgress  func(uploaded, total int64)
+	}
+
+	// ResumableUpload is a file uploaded with UploadResumable. Reader is read concurrently, at
+	// the offsets of the chunks. Set SessionID to resume an upload session that failed, see
+	// ResumableUploadError, the chunks the server already has are not uploaded again.
+	ResumableUpload struct {
+		SessionID string
+		Filename  string
+		FileType  string
+		Size      int64
+		Reader    io.ReaderAt
+	}
+
+	// UploadSession is a resumable upload session. FileOffset is the number of bytes of the
+	// file the server has received.
+	UploadSession struct {
+		ID         string `json:"id"`
+		FileOffset int64  `json:"file_offset"`
+	}
+
+	// ResumableUploadError is returned when an upload fails after its session was created.
+	// The upload can be resumed by setting ResumableUpload.SessionID to SessionID.
+	ResumableUploadError struct {
+		SessionID string
+		Err       error
+	}
+
+	uploadHandleResponse struct {
+		Handle string `json:"h"`
+	}
+
+	uploadChunk struct {
+		offset, size int64
+	}
+)
+
+func (e *ResumableUploadError) Error() string {
+	return fmt.Sprintf("resumable upload (session %s): %v", e.SessionID, e.Err)
+}
+
+func (e *ResumableUploadError) Unwrap() error {
+	return e.Err
+}
+
+// CreateUploadSession starts a resumable upload session for a file of length bytes.
+func (client *Client) CreateUploadSession(ctx context.Context, appID, filename, fileType string,
+	length int64,
+) (*UploadSession, error) {
+	if appID == "" {
+		return nil, ErrUploadAppIDMissing
+	}
+	cctx := client.context(ctx)
+	params := &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       "create upload session",
+			BaseURL:    cctx.baseURL,
+			ApiVersion: cctx.apiVersion,
+			Endpoints:  []string{appID, "uploads"},
+		},
+		Method: http.MethodPost,
+		Query: map[string]string{
+			"file_name":   filename,
+			"file_length": strconv.FormatInt(length, 10),
+			"file_type":   fileType,
+		},
+		Bearer: cctx.accessToken,
+	}
+
+	session, err := whttp.SendTyped[UploadSession](ctx, client.sender, params)
+	if err != nil {
+		return nil, fmt.Errorf("create upload session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetUploadSession returns the upload session sessionID, its FileOffset is where the upload
+// resumes.
+func (client *Client) GetUploadSession(ctx context.Context, sessionID string) (*UploadSession, error) {
+	cctx := client.context(ctx)
+	params := &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       "get upload session",
+			BaseURL:    cctx.baseURL,
+			ApiVersion: cctx.apiVersion,
+			Endpoints:  []string{sessionID},
+		},
+		Method: http.MethodGet,
+		Bearer: cctx.accessToken,
+	}
+
+	session, err := whttp.SendTyped[UploadSession](ctx, client.sender, params)
+	if err != nil {
+		return nil, fmt.Errorf("get upload session: %w", err)
+	}
+
+	return session, nil
+}
+
+// UploadResumable uploads the file with the resumable upload API and returns its handle,
+// that is used to reference the file, e.g. in a template header.
+//
+// The chunks are uploaded concurrently, except for the last one which completes the session:
+// it is only uploaded once all the others succeeded. If the upload fails after the session
+// was created, the returned error is a *ResumableUploadError.
+func (client *Client) UploadResumable(ctx context.Context, config *ResumableUploadConfig,
+	upload *ResumableUpload,
+) (string, error) {
+	uploader := newResumableUploader(client, config)
+	if uploader.config.AppID == "" && upload.SessionID == "" {
+		return "", ErrUploadAppIDMissing
+	}
+
+	offset := int64(0)
+	sessionID := upload.SessionID
+	if sessionID == "" {
+		session, err := client.CreateUploadSession(ctx, uploader.config.AppID, upload.Filename,
+			upload.FileType, upload.Size)
+		if err != nil {
+			return "", err
+		}
+		sessionID = session.ID
+	} else {
+		session, err := client.GetUploadSession(ctx, sessionID)
+		if err != nil {
+			return "", &ResumableUploadError{SessionID: sessionID, Err: err}
+		}
+		offset = session.FileOffset
+	}
+
+	handle, err := uploader.upload(ctx, sessionID, upload, offset)
+	if err != nil {
+		return "", &ResumableUploadError{SessionID: sessionID, Err: err}
+	}
+
+	return handle, nil
+}
+
+type resumableUploader struct {
+	client *Client
+	config ResumableUploadConfig
+
+	mu       sync.Mutex
+	uploaded int64
+}
+
+func newResumableUploader(client *Client, config *ResumableUploadConfig) *resumableUploader {
+	uploader := &resumableUploader{client: client}
+	if config != nil {
+		uploader.config = *config
+	}
+	if uploader.config.ChunkSize <= 0 {
+		uploader.config.ChunkSize = DefaultUploadChunkSize
+	}
+	if uploader.config.Parallelism <= 0 {
+		uploader.config.Parallelism = DefaultUploadParallelism
+	}
+	if uploader.config.Retries == 0 {
+		uploader.config.Retries = DefaultUploadRetries
+	} else if uploader.config.Retries < 0 {
+		uploader.config.Retries = 0
+	}
+	if uploader.config.RetryDelay <= 0 {
+		uploader.config.RetryDelay = DefaultUploadRetryDelay
+	}
+	uploader.config.Clock = clock.OrSystem(uploader.config.Clock)
+
+	return uploader
+}
+
+// chunks splits the bytes of the file from offset to size in chunks.
+func (uploader *resumableUploader) chunks(offset, size int64) []uploadChunk {
+	var chunks []uploadChunk
+	for ; offset < size; offset += uploader.config.ChunkSize {
+		chunk := uploadChunk{offset: offset, size: uploader.config.ChunkSize}
+		if offset+chunk.size > size {
+			chunk.size = size - offset
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+func (uploader *resumableUploader) upload(ctx context.Context, sessionID string, upload *ResumableUpload,
+	offset int64,
+) (string, error) {
+	uploader.uploaded = offset
+	chunks := uploader.chunks(offset, upload.Size)
+	if len(chunks) == 0 {
+		return "", ErrUploadNoHandle
+	}
+	last := chunks[len(chunks)-1]
+
+	if err := uploader.uploadConcurrently(ctx, sessionID, upload, chunks[:len(chunks)-1]); err != nil {
+		return "", err
+	}
+
+	handle, err := uploader.uploadChunk(ctx, sessionID, upload, last)
+	if err != nil {
+		return "", err
+	}
+	if handle == "" {
+		return "", ErrUploadNoHandle
+	}
+
+	return handle, nil
+}
+
+// uploadConcurrently uploads the chunks, Parallelism at a time. The first error cancels the
+// chunks in flight and is returned.
+func (uploader *resumableUploader) uploadConcurrently(ctx context.Context, sessionID string,
+	upload *ResumableUpload, chunks []uploadChunk,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, uploader.config.Parallelism)
+	)
+	for _, chunk := range chunks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(chunk uploadChunk) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if _, err := uploader.uploadChunk(ctx, sessionID, upload, chunk); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(chunk)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err() //nolint:wrapcheck
+}
+
+// uploadChunk uploads chunk, retrying it when it fails with a retryable error.
+func (uploader *resumableUploader) uploadChunk(ctx context.Context, sessionID string, upload *ResumableUpload,
+	chunk uploadChunk,
+) (string, error) {
+	delay := uploader.config.RetryDelay
+	for attempt := 0; ; attempt++ {
+		handle, err := uploader.sendChunk(ctx, sessionID, upload, chunk)
+		if err == nil {
+			uploader.progress(chunk.size, upload.Size)
+
+			return handle, nil
+		}
+		if attempt >= uploader.config.Retries || !isRetryableUploadError(err) {
+			return "", fmt.Errorf("chunk at offset %d: %w", chunk.offset, err)
+		}
+
+		timer := uploader.config.Clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return "", fmt.Errorf("chunk at offset %d: %w", chunk.offset, ctx.Err())
+		case <-timer.C():
+		}
+		delay *= 2
+	}
+}
+
+func (uploader *resumableUploader) sendChunk(ctx context.Context, sessionID string, upload *ResumableUpload,
+	chunk uploadChunk,
+) (string, error) {
+	cctx := uploader.client.context(ctx)
+	params := &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       "upload chunk",
+			BaseURL:    cctx.baseURL,
+			ApiVersion: cctx.apiVersion,
+			Endpoints:  []string{sessionID},
+		},
+		Method: http.MethodPost,
+		Headers: map[string]string{
+			"Content-Type": "application/octet-stream",
+			"file_offset":  strconv.FormatInt(chunk.offset, 10),
+		},
+		Bearer: cctx.accessToken,
+		Payload: &whttp.Stream{
+			Reader:        io.NewSectionReader(upload.Reader, chunk.offset, chunk.size),
+			ContentLength: chunk.size,
+		},
+	}
+
+	response, err := whttp.SendTyped[uploadHandleResponse](ctx, uploader.client.sender, params)
+	if err != nil {
+		return "", err //nolint:wrapcheck
+	}
+
+	return response.Handle, nil
+}
+
+func (uploader *resumableUploader) progress(size, total int64) {
+	if uploader.config.OnProgress == nil {
+		return
+	}
+	uploader.mu.Lock()
+	defer uploader.mu.Unlock()
+	uploader.uploaded += size
+	uploader.config.OnProgress(uploader.uploaded, total)
+}
+
+// isRetryableUploadError reports whether a chunk that failed with err can be uploaded again:
+// network errors, throttling and server errors are retried, other API errors are not.
+func isRetryableUploadError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var responseErr *whttp.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.Code == http.StatusTooManyRequests || responseErr.Code >= http.StatusInternalServerError
+	}
+
+	return true
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// uploadServer is a resumable upload API that fails the chunks at the offsets in failures as
+// many times as set, and rejects the last chunk if the others have not all been received.
+type uploadServer struct {
+	t           *testing.T
+	mu          sync.Mutex
+	file        []byte
+	received    map[int64]int64
+	failures    map[int64]int
+	sessions    int
+	inflight    int
+	maxInflight int
+}
+
+func newUploadServer(t *testing.T, size int, failures map[int64]int) *uploadServer {
+	t.Helper()
+	server := &uploadServer{
+		t:        t,
+		file:     make([]byte, size),
+		received: make(map[int64]int64),
+		failures: failures,
+	}
+
+	return server
+}
+
+// offset returns the number of contiguous bytes received from the beginning of the file.
+func (server *uploadServer) offset() int64 {
+	var offset int64
+	for server.received[offset] > 0 {
+		offset += server.received[offset]
+	}
+
+	return offset
+}
+
+func (server *uploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/v16.0/app_1/uploads":
+		server.mu.Lock()
+		server.sessions++
+		server.mu.Unlock()
+		if r.URL.Query().Get("file_length") != strconv.Itoa(len(server.file)) {
+			server.t.Errorf("file_length = %s, want %d", r.URL.Query().Get("file_length"), len(server.file))
+		}
+		_, _ = w.Write([]byte(`{"id":"upload:1"}`))
+	case r.URL.Path == "/v16.0/upload:1" && r.Method == http.MethodGet:
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(UploadSession{ID: "upload:1", FileOffset: server.offset()})
+	case r.URL.Path == "/v16.0/upload:1" && r.Method == http.MethodPost:
+		server.chunk(w, r)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (server *uploadServer) chunk(w http.ResponseWriter, r *http.Request) {
+	offset, _ := strconv.ParseInt(r.Header.Get("file_offset"), 10, 64)
+	server.mu.Lock()
+	server.inflight++
+	if server.inflight > server.maxInflight {
+		server.maxInflight = server.inflight
+	}
+	fail := server.failures[offset] > 0
+	server.failures[offset]--
+	server.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond) // let the other chunks overlap
+	body, _ := io.ReadAll(r.Body)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.inflight--
+	if fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error":{"message":"try again","code":2}}`))
+
+		return
+	}
+	copy(server.file[offset:], body)
+	server.received[offset] = int64(len(body))
+	if offset+int64(len(body)) < int64(len(server.file)) {
+		_, _ = w.Write([]byte(`{}`))
+
+		return
+	}
+	if server.offset() != int64(len(server.file)) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"the session completed with missing chunks","code":100}}`))
+
+		return
+	}
+	_, _ = w.Write([]byte(`{"h":"4::aW1hZ2U="}`))
+}
+
+func TestClient_UploadResumable(t *testing.T) {
+	t.Parallel()
+
+	file := bytes.Repeat([]byte("0123456789"), 1000)
+	api := newUploadServer(t, len(file), map[int64]int{2000: 1, 4000: 2})
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1"))
+	var progress []int64
+	config := &ResumableUploadConfig{
+		AppID:       "app_1",
+		ChunkSize:   1000,
+		Parallelism: 3,
+		Retries:     2,
+		RetryDelay:  time.Millisecond,
+		OnProgress: func(uploaded, total int64) {
+			progress = append(progress, uploaded)
+		},
+	}
+	handle, err := client.UploadResumable(context.Background(), config, &ResumableUpload{
+		Filename: "video.mp4",
+		FileType: "video/mp4",
+		Size:     int64(len(file)),
+		Reader:   bytes.NewReader(file),
+	})
+	if err != nil {
+		t.Fatalf("UploadResumable() error = %v", err)
+	}
+	if handle != "4::aW1hZ2U=" {
+		t.Errorf("UploadResumable() handle = %q", handle)
+	}
+	if !bytes.Equal(api.file, file) {
+		t.Errorf("the server received a different file")
+	}
+	if api.maxInflight < 2 || api.maxInflight > 3 {
+		t.Errorf("max chunks in flight = %d, want 2 or 3", api.maxInflight)
+	}
+	if len(progress) != 10 || progress[9] != int64(len(file)) {
+		t.Errorf("progress = %v, want 10 calls ending at %d", progress, len(file))
+	}
+}
+
+func TestClient_UploadResumable_Resume(t *testing.T) {
+	t.Parallel()
+
+	file := bytes.Repeat([]byte("abcdefghij"), 500)
+	// the chunk at 2000 fails more times than the retries allow
+	api := newUploadServer(t, len(file), map[int64]int{2000: 2})
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	client := NewClient(WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1"))
+	config := &ResumableUploadConfig{AppID: "app_1", ChunkSize: 1000, Parallelism: 1, Retries: 1,
+		RetryDelay: time.Millisecond}
+	upload := &ResumableUpload{Filename: "doc.pdf", FileType: "application/pdf", Size: int64(len(file)),
+		Reader: bytes.NewReader(file)}
+
+	_, err := client.UploadResumable(context.Background(), config, upload)
+	var uploadErr *ResumableUploadError
+	if !errors.As(err, &uploadErr) || uploadErr.SessionID != "upload:1" {
+		t.Fatalf("UploadResumable() error = %v, want a *ResumableUploadError", err)
+	}
+
+	upload.SessionID = uploadErr.SessionID
+	handle, err := client.UploadResumable(context.Background(), config, upload)
+	if err != nil {
+		t.Fatalf("UploadResumable() resumed error = %v", err)
+	}
+	if handle == "" || !bytes.Equal(api.file, file) {
+		t.Errorf("UploadResumable() resumed handle = %q, file received = %v", handle, bytes.Equal(api.file, file))
+	}
+	if api.sessions != 1 {
+		t.Errorf("sessions created = %d, want 1", api.sessions)
+	}
+}
+
+func TestClient_UploadResumable_NoAppID(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient()
+	upload := &ResumableUpload{Size: 1, Reader: bytes.NewReader([]byte("a"))}
+	_, err := client.UploadResumable(context.Background(), nil, upload)
+	if !errors.Is(err, ErrUploadAppIDMissing) {
+		t.Fatalf("UploadResumable() error = %v, want %v", err, ErrUploadAppIDMissing)
+	}
+}
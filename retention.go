@@ -0,0 +1,139 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+// DefaultRetentionInterval is the time between two evictions when RetentionConfig.Interval
+// is zero.
+const DefaultRetentionInterval = time.Hour
+
+var (
+	_ Retainer     = (*DeliveryTracker)(nil)
+	_ Retainer     = (*CorrelationTracker)(nil)
+	_ Retainer     = (*Archiver)(nil)
+	_ ArchiveStore = (*MemoryArchiveStore)(nil)
+)
+
+type (
+	// Retainer holds data about the customers that is subject to a retention policy. Evict
+	// deletes the data older than its retention period and Forget the data of a customer.
+	// DeliveryTracker, CorrelationTracker and Archiver implement it.
+	Retainer interface {
+		Evict(ctx context.Context) (int, error)
+		Forget(ctx context.Context, waID string) (int, error)
+	}
+
+	// RetentionConfig configures a Retention.
+	//
+	// Interval is the time between two evictions, DefaultRetentionInterval by default.
+	// OnError is called with the errors of the periodic evictions. Clock defaults to
+	// clock.System.
+	RetentionConfig struct {
+		Interval time.Duration
+		OnError  func(ctx context.Context, err error)
+		Clock    clock.Clock
+	}
+
+	// Retention applies the retention periods of several stores and erases the data of a
+	// customer from all of them, to meet data minimization requirements. The retention
+	// period of every store is its MaxAge:
+	//
+	//	deliveries := whatsapp.NewDeliveryTracker(&whatsapp.DeliveryTrackerConfig{MaxAge: 90 * 24 * time.Hour})
+	//	archiver := whatsapp.NewArchiver(&whatsapp.ArchiverConfig{Sink: store, MaxAge: 90 * 24 * time.Hour})
+	//	retention := whatsapp.NewRetention(nil, deliveries, correlations, archiver)
+	//	go retention.Run(ctx)
+	//	...
+	//	retention.Forget(ctx, waID)
+	Retention struct {
+		retainers []Retainer
+		config    RetentionConfig
+	}
+)
+
+// NewRetention returns a Retention of retainers configured with config, which may be nil.
+func NewRetention(config *RetentionConfig, retainers ...Retainer) *Retention {
+	retention := &Retention{retainers: retainers}
+	if config != nil {
+		retention.config = *config
+	}
+	if retention.config.Interval <= 0 {
+		retention.config.Interval = DefaultRetentionInterval
+	}
+	retention.config.Clock = clock.OrSystem(retention.config.Clock)
+
+	return retention
+}
+
+// Run evicts the expired data immediately and then every Interval until ctx is done. It
+// returns ctx.Err().
+func (retention *Retention) Run(ctx context.Context) error {
+	ticker := retention.config.Clock.NewTicker(retention.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := retention.Evict(ctx); err != nil && retention.config.OnError != nil {
+			retention.config.OnError(ctx, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+// Evict evicts the expired data of every retainer and returns how many entries were
+// deleted. A failing retainer does not stop the others, the errors are joined.
+func (retention *Retention) Evict(ctx context.Context) (int, error) {
+	return retention.each(func(retainer Retainer) (int, error) {
+		return retainer.Evict(ctx)
+	})
+}
+
+// Forget deletes the data of the customer waID from every retainer and returns how many
+// entries were deleted. A failing retainer does not stop the others, the errors are joined.
+func (retention *Retention) Forget(ctx context.Context, waID string) (int, error) {
+	return retention.each(func(retainer Retainer) (int, error) {
+		return retainer.Forget(ctx, waID)
+	})
+}
+
+func (retention *Retention) each(f func(retainer Retainer) (int, error)) (int, error) {
+	var (
+		total int
+		errs  []error
+	)
+	for _, retainer := range retention.retainers {
+		deleted, err := f(retainer)
+		total += deleted
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return total, errors.Join(errs...)
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+func TestRetention(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	const maxAge = 90 * 24 * time.Hour
+
+	deliveries := NewDeliveryTracker(&DeliveryTrackerConfig{Clock: clk, MaxAge: maxAge})
+	correlations := NewCorrelationTracker(&CorrelationConfig{Clock: clk, MaxAge: maxAge})
+	archive := NewMemoryArchiveStore()
+	archiver := NewArchiver(&ArchiverConfig{Sink: archive, Clock: clk, MaxAge: maxAge})
+
+	record := func(messageID, recipient string) {
+		t.Helper()
+		if err := deliveries.Track(ctx, &Delivery{MessageID: messageID, Recipient: recipient}); err != nil {
+			t.Fatalf("Track() error = %v", err)
+		}
+		if err := correlations.Register(ctx, messageID, recipient, nil, "yes", "no"); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+		archiver.Audit(ctx, AuditRecord{MessageID: messageID, Recipient: "+" + recipient, Timestamp: clk.Now()})
+	}
+	record("wamid.1", "255700000001")
+	record("wamid.2", "255700000002")
+	clk.Advance(60 * 24 * time.Hour)
+	record("wamid.3", "255700000001")
+	record("wamid.4", "255700000002")
+
+	retention := NewRetention(nil, deliveries, correlations, archiver)
+
+	// nothing is older than 90 days yet
+	if deleted, err := retention.Evict(ctx); err != nil || deleted != 0 {
+		t.Fatalf("Evict() = %d, %v, want 0", deleted, err)
+	}
+
+	// the first two messages expire in every store: 2 deliveries, 4 correlations, 2 records
+	clk.Advance(31 * 24 * time.Hour)
+	if deleted, err := retention.Evict(ctx); err != nil || deleted != 8 {
+		t.Fatalf("Evict() = %d, %v, want 8", deleted, err)
+	}
+	if _, found, _ := deliveries.Get(ctx, "wamid.1"); found {
+		t.Error("the expired delivery was not evicted")
+	}
+
+	// forgetting a customer deletes their data everywhere, whatever the format of the wa_id
+	if deleted, err := retention.Forget(ctx, "+255700000001"); err != nil || deleted != 4 {
+		t.Fatalf("Forget() = %d, %v, want 4", deleted, err)
+	}
+	if _, found, _ := deliveries.Get(ctx, "wamid.3"); found {
+		t.Error("the delivery of the forgotten customer was kept")
+	}
+	if _, found, _ := correlations.Resolve(ctx, "wamid.3", "yes"); found {
+		t.Error("the correlation of the forgotten customer was kept")
+	}
+	if records := archive.Records("255700000001"); len(records) != 0 {
+		t.Errorf("Records() = %d records of the forgotten customer, want 0", len(records))
+	}
+	if records := archive.Records(""); len(records) != 1 || records[0].MessageID != "wamid.4" {
+		t.Errorf("Records() = %+v, want the record of wamid.4", records)
+	}
+
+	// a sink that cannot delete fails without stopping the other retainers
+	jsonl := NewArchiver(&ArchiverConfig{Sink: NewJSONLArchiveSink(&bytes.Buffer{}), MaxAge: maxAge})
+	retention = NewRetention(nil, jsonl, deliveries)
+	deleted, err := retention.Forget(ctx, "255700000002")
+	if !errors.Is(err, ErrArchiveNotPurgeable) || deleted != 1 {
+		t.Errorf("Forget() = %d, %v, want 1, %v", deleted, err, ErrArchiveNotPurgeable)
+	}
+}
+
+func TestRetentionRun(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	deliveries := NewDeliveryTracker(&DeliveryTrackerConfig{Clock: clk, MaxAge: time.Hour})
+	errs := make(chan error, 10)
+	jsonl := NewArchiver(&ArchiverConfig{Sink: NewJSONLArchiveSink(&bytes.Buffer{}), MaxAge: time.Hour})
+	retention := NewRetention(&RetentionConfig{
+		Interval: time.Minute,
+		Clock:    clk,
+		OnError:  func(ctx context.Context, err error) { errs <- err },
+	}, deliveries, jsonl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := deliveries.Track(ctx, &Delivery{MessageID: "wamid.1", Recipient: "255700000001"}); err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- retention.Run(ctx) }()
+
+	// the first eviction runs immediately and reports the sink that cannot delete
+	if err := <-errs; !errors.Is(err, ErrArchiveNotPurgeable) {
+		t.Fatalf("OnError() error = %v, want %v", err, ErrArchiveNotPurgeable)
+	}
+	if _, found, _ := deliveries.Get(ctx, "wamid.1"); !found {
+		t.Fatal("the delivery was evicted before it expired")
+	}
+
+	clk.Advance(time.Hour + time.Minute)
+	<-errs
+	if _, found, _ := deliveries.Get(ctx, "wamid.1"); found {
+		t.Error("the expired delivery was not evicted")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package router dispatches the incoming text messages to handlers by slash command or
+// keyword, the usual entry point of a bot:
+//
+//	r := router.New()
+//	r.OnCommand("/balance", func(ctx context.Context, request *router.Request) error {
+//		return reply(ctx, request.From, balanceOf(request.From))
+//	})
+//	r.OnCommand("/send", func(ctx context.Context, request *router.Request) error {
+//		amount, recipient := request.Arg(0), request.Arg(1) // /send 5000 "Jane Doe"
+//		...
+//	})
+//	r.OnKeyword("help", help)
+//	r.Fallback(machine.OnMessageReceived) // anything else
+//	listener.OnTextMessage(r.OnTextMessage)
+//
+// Commands and keywords are matched case-insensitively.
+package router
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// CommandPrefix starts the slash commands.
+const CommandPrefix = "/"
+
+var ErrUnterminatedQuote = errors.New("router: unterminated quote in arguments")
+
+type (
+	// Request is a text message matched by a command or keyword. Command is the matched
+	// command or keyword as registered, Args the arguments that follow it, split on spaces
+	// with quoted arguments kept whole, and RawArgs the text that follows it.
+	Request struct {
+		Notification *webhooks.NotificationContext
+		Message      *webhooks.MessageContext
+		From         string
+		Text         string
+		Command      string
+		Args         []string
+		RawArgs      string
+	}
+
+	// Handler handles a matched text message.
+	Handler func(ctx context.Context, request *Request) error
+
+	// FallbackHandler handles the text messages matched by no command or keyword.
+	FallbackHandler func(ctx context.Context, nctx *webhooks.NotificationContext, message *webhooks.Message) error
+
+	// Router dispatches the text messages to the handlers of the commands and keywords. It is
+	// safe for concurrent use, handlers can be registered while messages are dispatched.
+	Router struct {
+		mu       sync.RWMutex
+		commands map[string]route
+		keywords []route
+		fallback FallbackHandler
+	}
+
+	route struct {
+		name    string
+		words   []string
+		handler Handler
+	}
+)
+
+// Arg returns the argument at index i, or an empty string.
+func (request *Request) Arg(i int) string {
+	if i < 0 || i >= len(request.Args) {
+		return ""
+	}
+
+	return request.Args[i]
+}
+
+// New returns a Router without routes.
+func New() *Router {
+	return &Router{commands: make(map[string]route)}
+}
+
+// OnCommand registers the handler of the slash command name, e.g. "/balance". The leading
+// slash is optional.
+func (router *Router) OnCommand(name string, handler Handler) {
+	key := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), CommandPrefix))
+	router.mu.Lock()
+	router.commands[key] = route{name: name, handler: handler}
+	router.mu.Unlock()
+}
+
+// OnKeyword registers the handler of the messages that are keyword, or start with it followed
+// by arguments. Keywords can have several words, e.g. "opt out", the longest matching keyword
+// wins.
+func (router *Router) OnKeyword(keyword string, handler Handler) {
+	words := strings.Fields(strings.ToLower(keyword))
+	if len(words) == 0 {
+		return
+	}
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	for i, existing := range router.keywords {
+		if equalWords(existing.words, words) {
+			router.keywords[i] = route{name: keyword, words: words, handler: handler}
+
+			return
+		}
+	}
+	router.keywords = append(router.keywords, route{name: keyword, words: words, handler: handler})
+}
+
+// Fallback sets the handler of the text messages matched by no command or keyword, for
+// example a session.Machine.
+func (router *Router) Fallback(handler FallbackHandler) {
+	router.mu.Lock()
+	router.fallback = handler
+	router.mu.Unlock()
+}
+
+// OnTextMessage is a webhooks.OnTextMessageHook that dispatches the text message to the
+// handler of its command or keyword, or to the fallback handler.
+func (router *Router) OnTextMessage(ctx context.Context, nctx *webhooks.NotificationContext,
+	mctx *webhooks.MessageContext, text *webhooks.Text,
+) error {
+	handled, err := router.Dispatch(ctx, nctx, mctx, text)
+	if handled || err != nil {
+		return err
+	}
+
+	router.mu.RLock()
+	fallback := router.fallback
+	router.mu.RUnlock()
+	if fallback == nil {
+		return nil
+	}
+
+	return fallback(ctx, nctx, &webhooks.Message{
+		From:      mctx.From,
+		ID:        mctx.ID,
+		Timestamp: mctx.Timestamp,
+		Type:      mctx.Type,
+		Context:   mctx.Ctx,
+		Text:      text,
+	})
+}
+
+// Dispatch calls the handler of the command or keyword of the text message. handled is false
+// when no route matches. The arguments of a command that cannot be parsed are reported as an
+// error wrapping ErrUnterminatedQuote.
+func (router *Router) Dispatch(ctx context.Context, nctx *webhooks.NotificationContext,
+	mctx *webhooks.MessageContext, text *webhooks.Text,
+) (bool, error) {
+	if text == nil {
+		return false, nil
+	}
+	body := strings.TrimSpace(text.Body)
+	matched, raw, ok := router.match(body)
+	if !ok {
+		return false, nil
+	}
+	args, err := SplitArgs(raw)
+	if err != nil {
+		return true, err
+	}
+
+	request := &Request{
+		Notification: nctx,
+		Message:      mctx,
+		Text:         text.Body,
+		Command:      matched.name,
+		Args:         args,
+		RawArgs:      raw,
+	}
+	if mctx != nil {
+		request.From = mctx.From
+	}
+
+	return true, matched.handler(ctx, request)
+}
+
+// match returns the route of body and the text following the command or keyword.
+func (router *Router) match(body string) (route, string, bool) {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+
+	if command, ok := strings.CutPrefix(body, CommandPrefix); ok {
+		fields := strings.Fields(command)
+		if len(fields) == 0 || !strings.HasPrefix(command, fields[0]) {
+			return route{}, "", false
+		}
+		matched, ok := router.commands[strings.ToLower(fields[0])]
+
+		return matched, restAfterWords(command, 1), ok
+	}
+
+	words := strings.Fields(body)
+	var (
+		best     route
+		bestRest string
+		found    bool
+	)
+	for _, keyword := range router.keywords {
+		if len(keyword.words) > len(words) || (found && len(keyword.words) <= len(best.words)) {
+			continue
+		}
+		if !equalWords(keyword.words, lowerAll(words[:len(keyword.words)])) {
+			continue
+		}
+		best, bestRest, found = keyword, restAfterWords(body, len(keyword.words)), true
+	}
+
+	return best, bestRest, found
+}
+
+// SplitArgs splits s on spaces, keeping the text between double or single quotes whole:
+// `5000 "Jane Doe"` is ["5000", "Jane Doe"].
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		quote   rune
+		inArg   bool
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'' || r == '“' || r == '”':
+			quote, inArg = r, true
+			if r == '“' {
+				quote = '”'
+			}
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, ErrUnterminatedQuote
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// restAfterWords returns the text of s after its first n words.
+func restAfterWords(s string, n int) string {
+	rest := s
+	for i := 0; i < n; i++ {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			return ""
+		}
+		rest = rest[end:]
+	}
+
+	return strings.TrimSpace(rest)
+}
+
+func lowerAll(words []string) []string {
+	lowered := make([]string, len(words))
+	for i, word := range words {
+		lowered[i] = strings.ToLower(word)
+	}
+
+	return lowered
+}
+
+func equalWords(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
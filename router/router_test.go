@@ -0,0 +1,155 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestSplitArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr error
+	}{
+		{in: "", want: nil},
+		{in: "5000  Jane", want: []string{"5000", "Jane"}},
+		{in: `5000 "Jane Doe" 'note x'`, want: []string{"5000", "Jane Doe", "note x"}},
+		{in: `to “Jane Doe”`, want: []string{"to", "Jane Doe"}},
+		{in: `""`, want: []string{""}},
+		{in: `"Jane`, wantErr: ErrUnterminatedQuote},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.in)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SplitArgs(%q) error = %v, want %v", tt.in, err, tt.wantErr)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	t.Parallel()
+	var got *Request
+	handler := func(ctx context.Context, request *Request) error {
+		got = request
+
+		return nil
+	}
+	var fallback *webhooks.Message
+
+	r := New()
+	r.OnCommand("/balance", handler)
+	r.OnCommand("send", handler)
+	r.OnKeyword("help", handler)
+	r.OnKeyword("opt out", handler)
+	r.Fallback(func(ctx context.Context, nctx *webhooks.NotificationContext, message *webhooks.Message) error {
+		fallback = message
+
+		return nil
+	})
+
+	tests := []struct {
+		body     string
+		command  string
+		args     []string
+		rawArgs  string
+		fallback bool
+	}{
+		{body: "/balance", command: "/balance"},
+		{body: "  /BALANCE  ", command: "/balance"},
+		{body: `/send 5000 "Jane Doe"`, command: "send", args: []string{"5000", "Jane Doe"}, rawArgs: `5000 "Jane Doe"`},
+		{body: "Help", command: "help"},
+		{body: "help me please", command: "help", args: []string{"me", "please"}, rawArgs: "me please"},
+		{body: "OPT  OUT now", command: "opt out", args: []string{"now"}, rawArgs: "now"},
+		{body: "opt", fallback: true},
+		{body: "helpful", fallback: true},
+		{body: "/unknown", fallback: true},
+		{body: "/ balance", fallback: true},
+		{body: "", fallback: true},
+	}
+	for _, tt := range tests {
+		got, fallback = nil, nil
+		mctx := &webhooks.MessageContext{From: "255700000001", ID: "wamid.1", Type: "text"}
+		if err := r.OnTextMessage(context.Background(), nil, mctx, &webhooks.Text{Body: tt.body}); err != nil {
+			t.Fatalf("OnTextMessage(%q) error = %v", tt.body, err)
+		}
+		if tt.fallback {
+			if got != nil || fallback == nil || fallback.Text.Body != tt.body || fallback.From != "255700000001" {
+				t.Errorf("OnTextMessage(%q) = %+v, want the fallback called", tt.body, got)
+			}
+
+			continue
+		}
+		if got == nil {
+			t.Errorf("OnTextMessage(%q) matched nothing, want %q", tt.body, tt.command)
+
+			continue
+		}
+		if got.Command != tt.command || got.RawArgs != tt.rawArgs || got.From != "255700000001" ||
+			strings.Join(got.Args, "|") != strings.Join(tt.args, "|") {
+			t.Errorf("OnTextMessage(%q) request = %+v, want %q %q", tt.body, got, tt.command, tt.args)
+		}
+	}
+
+	// a handler error and a malformed command are reported to the webhook handler
+	errBalance := errors.New("balance unavailable")
+	r.OnCommand("/balance", func(ctx context.Context, request *Request) error { return errBalance })
+	_, err := r.Dispatch(context.Background(), nil, nil, &webhooks.Text{Body: "/balance"})
+	if !errors.Is(err, errBalance) {
+		t.Errorf("Dispatch() error = %v, want %v", err, errBalance)
+	}
+	handled, err := r.Dispatch(context.Background(), nil, nil, &webhooks.Text{Body: `/send "5000`})
+	if !handled || !errors.Is(err, ErrUnterminatedQuote) {
+		t.Errorf("Dispatch() = %v, %v, want %v", handled, err, ErrUnterminatedQuote)
+	}
+}
+
+func TestRouterWebhook(t *testing.T) {
+	t.Parallel()
+	var args []string
+	r := New()
+	r.OnCommand("/pay", func(ctx context.Context, request *Request) error {
+		args = request.Args
+
+		return nil
+	})
+
+	body := `{"object":"whatsapp_business_account","entry":[{"id":"WABA_ID","changes":[{"value":{` +
+		`"messaging_product":"whatsapp","messages":[{"from":"255700000001","id":"wamid.1","timestamp":"1",` +
+		`"type":"text","text":{"body":"/pay 100 \"Jane Doe\""}}]},"field":"messages"}]}]}`
+	h := webhooks.NotificationHandler(&webhooks.Hooks{OnTextMessageHook: r.OnTextMessage},
+		webhooks.NoOpNotificationErrorHandler, webhooks.NoOpHooksErrorHandler, &webhooks.HandlerOptions{})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+
+	if strings.Join(args, "|") != "100|Jane Doe" {
+		t.Errorf("args = %q, want [100 Jane Doe]", args)
+	}
+}
@@ -0,0 +1,390 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// ErrSandboxWebhook is returned when the webhook handler of a Sandbox rejects a notification.
+var ErrSandboxWebhook = errors.New("sandbox webhook rejected")
+
+type (
+	// SandboxConfig configures a Sandbox.
+	//
+	// WebhookHandler receives the notifications injected with Sandbox.Deliver, usually the
+	// handler of a webhooks.EventListener. When WebhookSecret is set the notifications are
+	// signed with it like the Graph API does. PhoneNumberID and DisplayPhoneNumber are set
+	// in the metadata of the notifications built by the sandbox.
+	SandboxConfig struct {
+		Clock              clock.Clock
+		WebhookHandler     http.Handler
+		WebhookSecret      string
+		PhoneNumberID      string
+		DisplayPhoneNumber string
+	}
+
+	// SandboxMessage is a message accepted by a Sandbox. Payload is the request body as
+	// sent by the client.
+	SandboxMessage struct {
+		ID            string
+		PhoneNumberID string
+		Recipient     string
+		Type          string
+		Message       *models.Message
+		Payload       json.RawMessage
+		SentAt        time.Time
+	}
+
+	// Sandbox is an in-process fake of the Graph API, set it on a client with WithSandbox to
+	// run integration tests without network. The messages sent through it are validated,
+	// assigned a synthetic wamid and recorded; invalid messages are rejected with the error
+	// the API would return. Media uploads are accepted and assigned a synthetic ID, marking
+	// a message as read succeeds, other endpoints fail with an unsupported request error.
+	//
+	// The test side of the conversation is played with Deliver, ReceiveText and SendStatus,
+	// which post webhook notifications to the configured handler.
+	Sandbox struct {
+		config SandboxConfig
+		clock  clock.Clock
+
+		mu       sync.Mutex
+		seq      int
+		messages []SandboxMessage
+	}
+)
+
+// WithSandbox sends all the requests of the client to sandbox instead of the Graph API.
+func WithSandbox(sandbox *Sandbox) ClientOption {
+	return func(client *Client) {
+		client.transport.transport = sandbox
+	}
+}
+
+// NewSandbox creates a Sandbox, config may be nil.
+func NewSandbox(config *SandboxConfig) *Sandbox {
+	sandbox := &Sandbox{}
+	if config != nil {
+		sandbox.config = *config
+	}
+	sandbox.clock = clock.OrSystem(sandbox.config.Clock)
+
+	return sandbox
+}
+
+// Messages returns the messages accepted so far, in order.
+func (sandbox *Sandbox) Messages() []SandboxMessage {
+	sandbox.mu.Lock()
+	defer sandbox.mu.Unlock()
+
+	return append([]SandboxMessage(nil), sandbox.messages...)
+}
+
+// MessagesTo returns the messages accepted for recipient, in order.
+func (sandbox *Sandbox) MessagesTo(recipient string) []SandboxMessage {
+	sandbox.mu.Lock()
+	defer sandbox.mu.Unlock()
+	var messages []SandboxMessage
+	for _, message := range sandbox.messages {
+		if message.Recipient == recipient {
+			messages = append(messages, message)
+		}
+	}
+
+	return messages
+}
+
+// Reset forgets the recorded messages.
+func (sandbox *Sandbox) Reset() {
+	sandbox.mu.Lock()
+	defer sandbox.mu.Unlock()
+	sandbox.messages = nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (sandbox *Sandbox) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(request.Body); err != nil {
+			return nil, fmt.Errorf("sandbox: read request body: %w", err)
+		}
+		_ = request.Body.Close()
+	}
+
+	if !strings.HasPrefix(request.Header.Get("Authorization"), "Bearer ") ||
+		strings.TrimSpace(strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")) == "" {
+		return sandboxError(request, http.StatusUnauthorized, werrors.CodeAccessTokenInvalid,
+			"OAuthException", "An access token is required to request this resource.")
+	}
+
+	segments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+	if request.Method == http.MethodPost && len(segments) >= 2 {
+		phoneNumberID := segments[len(segments)-2]
+		switch segments[len(segments)-1] {
+		case "messages":
+			return sandbox.message(request, phoneNumberID, body)
+		case "media":
+			return sandboxJSON(request, http.StatusOK, map[string]string{"id": "media.sandbox." + sandbox.next()})
+		}
+	}
+
+	return sandboxError(request, http.StatusBadRequest, 100, "GraphMethodException",
+		fmt.Sprintf("Unsupported %s request to %s in the sandbox", request.Method, request.URL.Path))
+}
+
+func (sandbox *Sandbox) message(request *http.Request, phoneNumberID string, body []byte) (*http.Response, error) {
+	var fields map[string]json.RawMessage
+	var message models.Message
+	if json.Unmarshal(body, &fields) != nil || json.Unmarshal(body, &message) != nil {
+		return sandboxError(request, http.StatusBadRequest, 100, "OAuthException", "Invalid JSON payload")
+	}
+
+	var status struct {
+		Status    string `json:"status"`
+		MessageID string `json:"message_id"`
+	}
+	_ = json.Unmarshal(body, &status)
+	if status.Status != "" {
+		if status.MessageID == "" {
+			return sandboxError(request, http.StatusBadRequest, 100, "OAuthException",
+				"The parameter message_id is required.")
+		}
+
+		return sandboxJSON(request, http.StatusOK, &StatusResponse{Success: true})
+	}
+
+	if problem := validateSandboxMessage(fields, &message); problem != "" {
+		return sandboxError(request, http.StatusBadRequest, 100, "OAuthException", problem)
+	}
+
+	sandbox.mu.Lock()
+	sandbox.seq++
+	id := fmt.Sprintf("wamid.sandbox.%d", sandbox.seq)
+	sandbox.messages = append(sandbox.messages, SandboxMessage{
+		ID:            id,
+		PhoneNumberID: phoneNumberID,
+		Recipient:     message.To,
+		Type:          message.Type,
+		Message:       &message,
+		Payload:       append(json.RawMessage(nil), body...),
+		SentAt:        sandbox.clock.Now(),
+	})
+	sandbox.mu.Unlock()
+
+	return sandboxJSON(request, http.StatusOK, &ResponseMessage{
+		Product:  messagingProduct,
+		Contacts: []*ResponseContact{{Input: message.To, WhatsappID: strings.TrimPrefix(message.To, "+")}},
+		Messages: []*MessageID{{ID: id}},
+	})
+}
+
+// validateSandboxMessage returns the problem with the message, if any.
+func validateSandboxMessage(fields map[string]json.RawMessage, message *models.Message) string {
+	switch {
+	case message.Product != messagingProduct:
+		return "The parameter messaging_product is required and must be whatsapp."
+	case message.To == "":
+		return "The parameter to is required."
+	case message.Type == "":
+		return "The parameter type is required."
+	case len(fields[message.Type]) == 0 || string(fields[message.Type]) == "null":
+		return fmt.Sprintf("The parameter %s is required for messages of type %s.", message.Type, message.Type)
+	}
+
+	var media *models.Media
+	switch message.Type {
+	case textMessageType:
+		if message.Text == nil || message.Text.Body == "" {
+			return "The parameter text['body'] is required."
+		}
+	case "image":
+		media = message.Image
+	case "audio":
+		media = message.Audio
+	case "video":
+		media = message.Video
+	case "document":
+		media = message.Document
+	case "sticker":
+		media = message.Sticker
+	case templateMessageType:
+		if message.Template.Name == "" {
+			return "The parameter template['name'] is required."
+		}
+		if message.Template.Language == nil || message.Template.Language.Code == "" {
+			return "The parameter template['language']['code'] is required."
+		}
+	case reactionMessageType:
+		if message.Reaction.MessageID == "" {
+			return "The parameter reaction['message_id'] is required."
+		}
+	}
+	if media != nil && media.ID == "" && media.Link == "" {
+		return fmt.Sprintf("The parameter %s['id'] or %s['link'] is required.", message.Type, message.Type)
+	}
+
+	return ""
+}
+
+func (sandbox *Sandbox) next() string {
+	sandbox.mu.Lock()
+	defer sandbox.mu.Unlock()
+	sandbox.seq++
+
+	return strconv.Itoa(sandbox.seq)
+}
+
+// Deliver posts notification to the webhook handler, signed when a secret is configured.
+// It fails with ErrSandboxWebhook when the handler does not answer with a 2xx status.
+func (sandbox *Sandbox) Deliver(ctx context.Context, notification *webhooks.Notification) error {
+	if sandbox.config.WebhookHandler == nil {
+		return fmt.Errorf("%w: no webhook handler configured", ErrSandboxWebhook)
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("sandbox: encode notification: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, "/webhooks", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if sandbox.config.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(sandbox.config.WebhookSecret))
+		_, _ = mac.Write(body)
+		request.Header.Set(webhooks.SignatureHeaderKey, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	recorder := httptest.NewRecorder()
+	sandbox.config.WebhookHandler.ServeHTTP(recorder, request)
+	if recorder.Code < http.StatusOK || recorder.Code >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: status %d: %s", ErrSandboxWebhook, recorder.Code,
+			strings.TrimSpace(recorder.Body.String()))
+	}
+
+	return nil
+}
+
+// ReceiveText delivers a text message sent by the user from to the business, and returns
+// the ID of the incoming message.
+func (sandbox *Sandbox) ReceiveText(ctx context.Context, from, text string) (string, error) {
+	id := "wamid.sandbox.in." + sandbox.next()
+	value := sandbox.value()
+	value.Contacts = []*webhooks.Contact{{WaID: from}}
+	value.Messages = []*webhooks.Message{{
+		From:      from,
+		ID:        id,
+		Timestamp: strconv.FormatInt(sandbox.clock.Now().Unix(), 10),
+		Type:      textMessageType,
+		Text:      &webhooks.Text{Body: text},
+	}}
+
+	return id, sandbox.Deliver(ctx, sandbox.notification(value))
+}
+
+// SendStatus delivers a status update, like "delivered" or "read", of a message sent by
+// the business.
+func (sandbox *Sandbox) SendStatus(ctx context.Context, messageID, status string) error {
+	var recipient string
+	for _, message := range sandbox.Messages() {
+		if message.ID == messageID {
+			recipient = message.Recipient
+		}
+	}
+	value := sandbox.value()
+	value.Statuses = []*webhooks.Status{{
+		ID:          messageID,
+		RecipientID: recipient,
+		StatusValue: status,
+		Timestamp:   int(sandbox.clock.Now().Unix()),
+	}}
+
+	return sandbox.Deliver(ctx, sandbox.notification(value))
+}
+
+func (sandbox *Sandbox) value() *webhooks.Value {
+	return &webhooks.Value{
+		MessagingProduct: messagingProduct,
+		Metadata: &webhooks.Metadata{
+			DisplayPhoneNumber: sandbox.config.DisplayPhoneNumber,
+			PhoneNumberID:      sandbox.config.PhoneNumberID,
+		},
+	}
+}
+
+func (sandbox *Sandbox) notification(value *webhooks.Value) *webhooks.Notification {
+	return &webhooks.Notification{
+		Object: "whatsapp_business_account",
+		Entry: []*webhooks.Entry{{
+			ID:      "sandbox",
+			Changes: []*webhooks.Change{{Field: "messages", Value: value}},
+		}},
+	}
+}
+
+func sandboxJSON(request *http.Request, status int, v any) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: encode response: %w", err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+func sandboxError(request *http.Request, status, code int, errorType, message string) (*http.Response, error) {
+	return sandboxJSON(request, status, map[string]any{
+		"error": &werrors.Error{
+			Message:   message,
+			Type:      errorType,
+			Code:      code,
+			FBTraceID: "sandbox",
+		},
+	})
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestSandbox(t *testing.T) {
+	t.Parallel()
+	listener := webhooks.NewEventListener(webhooks.WithHandlerOptions(&webhooks.HandlerOptions{
+		ValidateSignature: true,
+		Secret:            "secret",
+	}))
+
+	// an echo bot answering the incoming text messages
+	var (
+		client   *Client
+		statuses []string
+	)
+	listener.OnTextMessage(func(ctx context.Context, nctx *webhooks.NotificationContext,
+		mctx *webhooks.MessageContext, text *webhooks.Text,
+	) error {
+		_, err := client.SendText(ctx, mctx.From, "echo: "+text.Body, WithReplyTo(mctx.ID))
+
+		return err
+	})
+	listener.OnMessageStatusChange(func(ctx context.Context, nctx *webhooks.NotificationContext,
+		status *webhooks.Status,
+	) error {
+		statuses = append(statuses, status.StatusValue)
+
+		return nil
+	})
+
+	sandbox := NewSandbox(&SandboxConfig{
+		WebhookHandler: listener.NotificationHandler(),
+		WebhookSecret:  "secret",
+		PhoneNumberID:  "phone_1",
+	})
+	client = NewClient(WithSandbox(sandbox), WithPhoneNumberID("phone_1"), WithAccessToken("token"))
+
+	incoming, err := sandbox.ReceiveText(context.TODO(), "255700000000", "hello")
+	if err != nil {
+		t.Fatalf("ReceiveText() error = %v", err)
+	}
+	messages := sandbox.MessagesTo("255700000000")
+	if len(messages) != 1 {
+		t.Fatalf("MessagesTo() = %+v, want 1 message", messages)
+	}
+	sent := messages[0]
+	if sent.Type != "text" || sent.Message.Text.Body != "echo: hello" || sent.PhoneNumberID != "phone_1" ||
+		sent.Message.Context == nil || sent.Message.Context.MessageID != incoming {
+		t.Errorf("message = %+v", sent)
+	}
+
+	if err = sandbox.SendStatus(context.TODO(), sent.ID, "delivered"); err != nil {
+		t.Fatalf("SendStatus() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0] != "delivered" {
+		t.Errorf("statuses = %v, want [delivered]", statuses)
+	}
+
+	// invalid messages are rejected like the API would
+	_, err = client.SendMedia(context.TODO(), "255700000000", &MediaMessage{Type: MediaTypeImage}, nil)
+	var responseErr *whttp.ResponseError
+	if !errors.As(err, &responseErr) || responseErr.Code != 400 {
+		t.Errorf("SendMedia() error = %v, want a 400 response error", err)
+	}
+	if n := len(sandbox.Messages()); n != 1 {
+		t.Errorf("len(Messages()) = %d, want 1", n)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+// WithSenderNumbers registers the phone numbers the client can send from, by alias. The
+// sender of a call is selected with FromNumber or WithOverrides, by alias or by phone number
+// ID, and defaults to the phone number set with WithPhoneNumberID. The access token, the
+// hooks, the rate limiter and the metrics of the client are shared by all the numbers.
+//
+//	client := whatsapp.NewClient(
+//		whatsapp.WithPhoneNumberID(salesID),
+//		whatsapp.WithSenderNumbers(map[string]string{"sales": salesID, "support": supportID}),
+//	)
+//	resp, err := client.SendText(ctx, recipient, "How can we help?", whatsapp.FromNumber("support"))
+func WithSenderNumbers(numbers map[string]string) ClientOption {
+	return func(client *Client) {
+		for alias, phoneNumberID := range numbers {
+			client.setSenderNumber(alias, phoneNumberID)
+		}
+	}
+}
+
+// FromNumber sends the message from the phone number with the given alias, see
+// WithSenderNumbers, or with the given phone number ID.
+func FromNumber(aliasOrID string) SendOption {
+	return func(options *sendOptions) {
+		options.from = aliasOrID
+	}
+}
+
+// SetSenderNumber registers the phone number phoneNumberID under alias, replacing the number
+// previously registered under it. An empty phoneNumberID removes the alias.
+func (client *Client) SetSenderNumber(alias, phoneNumberID string) {
+	client.rwm.Lock()
+	defer client.rwm.Unlock()
+	client.setSenderNumber(alias, phoneNumberID)
+}
+
+// SenderNumber returns the phone number ID registered under alias.
+func (client *Client) SenderNumber(alias string) (string, bool) {
+	client.rwm.RLock()
+	defer client.rwm.RUnlock()
+	phoneNumberID, ok := client.senderNumbers[alias]
+
+	return phoneNumberID, ok
+}
+
+// SenderNumbers returns the phone number IDs registered by alias.
+func (client *Client) SenderNumbers() map[string]string {
+	client.rwm.RLock()
+	defer client.rwm.RUnlock()
+	numbers := make(map[string]string, len(client.senderNumbers))
+	for alias, phoneNumberID := range client.senderNumbers {
+		numbers[alias] = phoneNumberID
+	}
+
+	return numbers
+}
+
+// setSenderNumber replaces the map of the sender numbers instead of modifying it, the
+// client contexts read it without holding the lock.
+func (client *Client) setSenderNumber(alias, phoneNumberID string) {
+	numbers := make(map[string]string, len(client.senderNumbers)+1)
+	for a, id := range client.senderNumbers {
+		numbers[a] = id
+	}
+	if phoneNumberID == "" {
+		delete(numbers, alias)
+	} else {
+		numbers[alias] = phoneNumberID
+	}
+	client.senderNumbers = numbers
+}
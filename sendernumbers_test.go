@@ -0,0 +1,111 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type waitRecorder struct {
+	mu      sync.Mutex
+	numbers []string
+}
+
+func (recorder *waitRecorder) Wait(_ context.Context, phoneNumberID, _ string) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.numbers = append(recorder.numbers, phoneNumberID)
+
+	return nil
+}
+
+func TestSenderNumbers(t *testing.T) {
+	t.Parallel()
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	limiter := &waitRecorder{}
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithVersion("v16.0"),
+		WithPhoneNumberID("phone_sales"),
+		WithSenderNumbers(map[string]string{"sales": "phone_sales", "support": "phone_support"}),
+		WithRateLimiter(limiter),
+	)
+	client.SetSenderNumber("alerts", "phone_alerts")
+
+	tests := []struct {
+		name    string
+		ctx     context.Context //nolint:containedctx
+		options []SendOption
+		want    string
+	}{
+		{name: "default number", ctx: context.TODO(), want: "phone_sales"},
+		{name: "alias", ctx: context.TODO(), options: []SendOption{FromNumber("support")}, want: "phone_support"},
+		{name: "explicit id", ctx: context.TODO(), options: []SendOption{FromNumber("phone_9")}, want: "phone_9"},
+		{name: "alias set later", ctx: context.TODO(), options: []SendOption{FromNumber("alerts")}, want: "phone_alerts"},
+		{
+			name: "alias in overrides",
+			ctx:  WithOverrides(context.TODO(), &Overrides{PhoneNumberID: "support"}),
+			want: "phone_support",
+		},
+		{
+			name:    "option wins over overrides",
+			ctx:     WithOverrides(context.TODO(), &Overrides{PhoneNumberID: "support"}),
+			options: []SendOption{FromNumber("sales")},
+			want:    "phone_sales",
+		},
+	}
+	for _, tt := range tests {
+		if _, err := client.SendText(tt.ctx, "255700000000", "hi", tt.options...); err != nil {
+			t.Fatalf("%s: SendText() error = %v", tt.name, err)
+		}
+	}
+
+	for i, tt := range tests {
+		if want := "/v16.0/" + tt.want + "/messages"; paths[i] != want {
+			t.Errorf("%s: path = %s, want %s", tt.name, paths[i], want)
+		}
+		if limiter.numbers[i] != tt.want {
+			t.Errorf("%s: rate limited %s, want %s", tt.name, limiter.numbers[i], tt.want)
+		}
+	}
+
+	client.SetSenderNumber("alerts", "")
+	if _, ok := client.SenderNumber("alerts"); ok {
+		t.Error("SenderNumber(alerts) found after its removal")
+	}
+	if numbers := client.SenderNumbers(); len(numbers) != 2 || numbers["support"] != "phone_support" {
+		t.Errorf("SenderNumbers() = %v", numbers)
+	}
+}
@@ -0,0 +1,358 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package session keeps the conversational state of each customer, identified by their
+// WhatsApp ID, and runs multi-step flows such as menus and forms over the incoming messages.
+//
+// A Manager loads and saves the Session of a customer in a Store, sessions expire after a
+// period of inactivity. A Machine is a state machine whose states are handlers of the
+// messages received in that state, each returning the next state:
+//
+//	machine := session.NewMachine(session.NewManager(nil), "menu")
+//	machine.Handle("menu", func(ctx context.Context, input *session.Input) (string, error) {
+//		switch input.Text {
+//		case "1":
+//			return "ask_amount", reply(ctx, input, "How much?")
+//		default:
+//			return "menu", reply(ctx, input, "1. Send money\n2. Balance")
+//		}
+//	})
+//	machine.Handle("ask_amount", func(ctx context.Context, input *session.Input) (string, error) {
+//		input.Session.Set("amount", input.Text)
+//
+//		return session.End, reply(ctx, input, "Done")
+//	})
+//	listener.OnMessageReceived(machine.OnMessageReceived)
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// DefaultTTL is how long a session is kept after the last message of the customer.
+const DefaultTTL = 24 * time.Hour
+
+// End is the state returned by a handler to end the flow, the session is then deleted.
+const End = ""
+
+// lockStripes is the number of locks serializing the messages of the customers.
+const lockStripes = 64
+
+var (
+	ErrUnknownState = errors.New("session: no handler for state")
+	ErrNoInitial    = errors.New("session: initial state is required")
+)
+
+type (
+	// Session is the state of the conversation with a customer. State is the current step of
+	// the flow, Data the values collected so far.
+	Session struct {
+		WaID      string
+		State     string
+		Data      map[string]string
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		ExpiresAt time.Time
+	}
+
+	// Store stores the sessions by WhatsApp ID. Implementations must be safe for concurrent
+	// use, Get must not return expired sessions.
+	Store interface {
+		// Get returns the session of waID. found is false if there is none or it expired.
+		Get(ctx context.Context, waID string, now time.Time) (session *Session, found bool, err error)
+
+		// Put stores session, replacing the session with the same WhatsApp ID.
+		Put(ctx context.Context, session *Session) error
+
+		// Delete deletes the session of waID, it is not an error if there is none.
+		Delete(ctx context.Context, waID string) error
+	}
+
+	// ManagerConfig configures a Manager. Store defaults to a MemoryStore, Clock to the
+	// system clock and TTL to DefaultTTL.
+	ManagerConfig struct {
+		Store Store
+		Clock clock.Clock
+		TTL   time.Duration
+	}
+
+	// Manager loads and saves the sessions.
+	Manager struct {
+		store Store
+		clock clock.Clock
+		ttl   time.Duration
+	}
+
+	// Input is a message received from a customer during a flow. Text is the text of the
+	// message: the body of text messages, the title of replies and the caption of media.
+	// ReplyID is the ID of the button or list row of interactive replies and the payload of
+	// template quick replies.
+	Input struct {
+		Session      *Session
+		Notification *webhooks.NotificationContext
+		Message      *webhooks.Message
+		Text         string
+		ReplyID      string
+	}
+
+	// Handler handles a message received in a state and returns the next state. The session
+	// is saved with the next state, even when an error is returned.
+	Handler func(ctx context.Context, input *Input) (next string, err error)
+
+	// Machine runs the flows. Messages of a customer are handled one at a time, in the order
+	// they are received by OnMessageReceived.
+	Machine struct {
+		manager  *Manager
+		initial  string
+		mu       sync.RWMutex
+		handlers map[string]Handler
+		locks    [lockStripes]sync.Mutex
+	}
+)
+
+// Get returns the value of key, or an empty string.
+func (session *Session) Get(key string) string {
+	return session.Data[key]
+}
+
+// Set sets the value of key.
+func (session *Session) Set(key, value string) {
+	if session.Data == nil {
+		session.Data = make(map[string]string)
+	}
+	session.Data[key] = value
+}
+
+// NewManager returns a Manager configured with config, which may be nil.
+func NewManager(config *ManagerConfig) *Manager {
+	if config == nil {
+		config = &ManagerConfig{}
+	}
+	manager := &Manager{
+		store: config.Store,
+		clock: clock.OrSystem(config.Clock),
+		ttl:   config.TTL,
+	}
+	if manager.store == nil {
+		manager.store = NewMemoryStore()
+	}
+	if manager.ttl <= 0 {
+		manager.ttl = DefaultTTL
+	}
+
+	return manager
+}
+
+// Load returns the session of waID. A new session, with an empty State, is returned when
+// there is none or it expired.
+func (manager *Manager) Load(ctx context.Context, waID string) (*Session, error) {
+	now := manager.clock.Now()
+	session, found, err := manager.store.Get(ctx, waID, now)
+	if err != nil {
+		return nil, fmt.Errorf("load session: %w", err)
+	}
+	if !found {
+		session = &Session{WaID: waID, CreatedAt: now}
+	}
+
+	return session, nil
+}
+
+// Save stores session and extends its expiry by the TTL.
+func (manager *Manager) Save(ctx context.Context, session *Session) error {
+	now := manager.clock.Now()
+	session.UpdatedAt = now
+	session.ExpiresAt = now.Add(manager.ttl)
+	if err := manager.store.Put(ctx, session); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+
+	return nil
+}
+
+// Reset deletes the session of waID, the next message starts a new flow.
+func (manager *Manager) Reset(ctx context.Context, waID string) error {
+	if err := manager.store.Delete(ctx, waID); err != nil {
+		return fmt.Errorf("reset session: %w", err)
+	}
+
+	return nil
+}
+
+// NewMachine returns a Machine whose flows start in the initial state.
+func NewMachine(manager *Manager, initial string) *Machine {
+	return &Machine{
+		manager:  manager,
+		initial:  initial,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle registers the handler of the messages received in state and returns the machine.
+func (machine *Machine) Handle(state string, handler Handler) *Machine {
+	machine.mu.Lock()
+	machine.handlers[state] = handler
+	machine.mu.Unlock()
+
+	return machine
+}
+
+// OnMessageReceived is a webhooks.OnMessageReceivedHook that passes the message to the handler
+// of the state of the sender's session. System messages are ignored.
+func (machine *Machine) OnMessageReceived(ctx context.Context, nctx *webhooks.NotificationContext,
+	message *webhooks.Message,
+) error {
+	if message == nil || message.From == "" || message.Type == string(webhooks.SystemMessageType) {
+		return nil
+	}
+	if machine.initial == End {
+		return ErrNoInitial
+	}
+
+	lock := machine.lock(message.From)
+	lock.Lock()
+	defer lock.Unlock()
+
+	session, err := machine.manager.Load(ctx, message.From)
+	if err != nil {
+		return err
+	}
+	if session.State == End {
+		session.State = machine.initial
+	}
+
+	machine.mu.RLock()
+	handler, ok := machine.handlers[session.State]
+	machine.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownState, session.State)
+	}
+
+	input := &Input{Session: session, Notification: nctx, Message: message}
+	input.Text, input.ReplyID = contentOf(message)
+	next, handlerErr := handler(ctx, input)
+
+	if next == End {
+		err = machine.manager.Reset(ctx, session.WaID)
+	} else {
+		session.State = next
+		err = machine.manager.Save(ctx, session)
+	}
+
+	return errors.Join(handlerErr, err)
+}
+
+// lock returns the lock serializing the messages of waID.
+func (machine *Machine) lock(waID string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(waID))
+
+	return &machine.locks[h.Sum32()%lockStripes]
+}
+
+// contentOf returns the text and the reply ID of a message.
+func contentOf(message *webhooks.Message) (string, string) {
+	switch {
+	case message.Text != nil:
+		return message.Text.Body, ""
+	case message.Interactive != nil:
+		switch {
+		case message.Interactive.ButtonReply != nil:
+			return message.Interactive.ButtonReply.Title, message.Interactive.ButtonReply.ID
+		case message.Interactive.ListReply != nil:
+			return message.Interactive.ListReply.Title, message.Interactive.ListReply.ID
+		}
+	case message.Button != nil:
+		return message.Button.Text, message.Button.Payload
+	}
+	for _, media := range []*models.MediaInfo{
+		message.Image, message.Video, message.Document, message.Audio, message.Sticker,
+	} {
+		if media != nil {
+			return media.Caption, ""
+		}
+	}
+
+	return "", ""
+}
+
+// MemoryStore is an in-memory Store, sessions do not survive a restart.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]*Session)}
+}
+
+func (store *MemoryStore) Get(_ context.Context, waID string, now time.Time) (*Session, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	session, ok := store.sessions[waID]
+	if !ok {
+		return nil, false, nil
+	}
+	if !now.Before(session.ExpiresAt) {
+		delete(store.sessions, waID)
+
+		return nil, false, nil
+	}
+
+	return copySession(session), true, nil
+}
+
+func (store *MemoryStore) Put(_ context.Context, session *Session) error {
+	store.mu.Lock()
+	store.sessions[session.WaID] = copySession(session)
+	store.mu.Unlock()
+
+	return nil
+}
+
+func (store *MemoryStore) Delete(_ context.Context, waID string) error {
+	store.mu.Lock()
+	delete(store.sessions, waID)
+	store.mu.Unlock()
+
+	return nil
+}
+
+// copySession returns a copy of session that does not share its data.
+func copySession(session *Session) *Session {
+	copied := *session
+	if session.Data != nil {
+		copied.Data = make(map[string]string, len(session.Data))
+		for key, value := range session.Data {
+			copied.Data[key] = value
+		}
+	}
+
+	return &copied
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func text(from, body string) *webhooks.Message {
+	return &webhooks.Message{From: from, ID: "wamid." + body, Type: "text", Text: &webhooks.Text{Body: body}}
+}
+
+func TestMachine(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	manager := NewManager(&ManagerConfig{Clock: clk, TTL: 10 * time.Minute})
+	ctx := context.Background()
+
+	var (
+		replies   []string
+		completed map[string]string
+	)
+	machine := NewMachine(manager, "menu")
+	machine.Handle("menu", func(ctx context.Context, input *Input) (string, error) {
+		if input.Text == "1" || input.ReplyID == "send" {
+			replies = append(replies, "How much?")
+
+			return "amount", nil
+		}
+		replies = append(replies, "1. Send money")
+
+		return "menu", nil
+	}).Handle("amount", func(ctx context.Context, input *Input) (string, error) {
+		input.Session.Set("amount", input.Text)
+		replies = append(replies, "To whom?")
+
+		return "recipient", nil
+	}).Handle("recipient", func(ctx context.Context, input *Input) (string, error) {
+		input.Session.Set("recipient", input.Text)
+		completed = input.Session.Data
+
+		return End, nil
+	})
+
+	send := func(message *webhooks.Message) {
+		t.Helper()
+		if err := machine.OnMessageReceived(ctx, nil, message); err != nil {
+			t.Fatalf("OnMessageReceived(%q) error = %v", message.ID, err)
+		}
+	}
+
+	send(text("255700000001", "hi"))
+	send(&webhooks.Message{From: "255700000001", Type: "interactive", Interactive: &webhooks.Interactive{
+		Type: webhooks.InteractiveButtonReply, ButtonReply: &webhooks.ButtonReply{ID: "send", Title: "Send money"},
+	}})
+	send(text("255700000001", "5000"))
+
+	session, _ := manager.Load(ctx, "255700000001")
+	if session.State != "recipient" || session.Get("amount") != "5000" {
+		t.Errorf("Load() = %+v, want the recipient step with the amount", session)
+	}
+
+	send(text("255700000001", "Jane"))
+	if completed["amount"] != "5000" || completed["recipient"] != "Jane" {
+		t.Errorf("collected data = %v", completed)
+	}
+	if session, _ = manager.Load(ctx, "255700000001"); session.State != End || session.Data != nil {
+		t.Errorf("Load() after the end = %+v, want a new session", session)
+	}
+
+	// an idle session expires and the flow starts over
+	send(text("255700000002", "1"))
+	clk.Advance(11 * time.Minute)
+	send(text("255700000002", "5000"))
+	want := []string{"1. Send money", "How much?", "To whom?", "How much?", "1. Send money"}
+	if len(replies) != len(want) {
+		t.Fatalf("replies = %v, want %v", replies, want)
+	}
+	for i := range want {
+		if replies[i] != want[i] {
+			t.Errorf("replies = %v, want %v", replies, want)
+
+			break
+		}
+	}
+
+	// system messages are not part of the flows
+	send(&webhooks.Message{From: "255700000003", Type: "system", System: &webhooks.System{}})
+	if session, _ = manager.Load(ctx, "255700000003"); !session.UpdatedAt.IsZero() {
+		t.Errorf("Load() after a system message = %+v, want no session", session)
+	}
+}
+
+func TestMachineErrors(t *testing.T) {
+	t.Parallel()
+	manager := NewManager(nil)
+	ctx := context.Background()
+	errHandler := errors.New("handler failed")
+
+	machine := NewMachine(manager, "start").Handle("start", func(ctx context.Context, input *Input) (string, error) {
+		return "missing", errHandler
+	})
+	err := machine.OnMessageReceived(ctx, nil, text("255700000001", "hi"))
+	if !errors.Is(err, errHandler) {
+		t.Errorf("OnMessageReceived() error = %v, want %v", err, errHandler)
+	}
+	if session, _ := manager.Load(ctx, "255700000001"); session.State != "missing" {
+		t.Errorf("Load() = %+v, want the next state saved despite the error", session)
+	}
+	if err = machine.OnMessageReceived(ctx, nil, text("255700000001", "hi")); !errors.Is(err, ErrUnknownState) {
+		t.Errorf("OnMessageReceived() error = %v, want %v", err, ErrUnknownState)
+	}
+	if err = NewMachine(manager, End).OnMessageReceived(ctx, nil, text("1", "hi")); !errors.Is(err, ErrNoInitial) {
+		t.Errorf("OnMessageReceived() error = %v, want %v", err, ErrNoInitial)
+	}
+}
+
+func TestMachineSerializesCustomerMessages(t *testing.T) {
+	t.Parallel()
+	manager := NewManager(nil)
+	ctx := context.Background()
+	machine := NewMachine(manager, "count").Handle("count", func(ctx context.Context, input *Input) (string, error) {
+		input.Session.Set("n", input.Session.Get("n")+"x")
+
+		return "count", nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = machine.OnMessageReceived(ctx, nil, text("255700000001", "hi"))
+		}()
+	}
+	wg.Wait()
+
+	if session, _ := manager.Load(ctx, "255700000001"); len(session.Get("n")) != 50 {
+		t.Errorf("handled %d messages, want 50", len(session.Get("n")))
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+// ErrWindowClosed is the cause reported by SendSmart when it sent the template because the
+// customer service window of the recipient is closed.
+var ErrWindowClosed = errors.New("customer service window is closed")
+
+// SmartMessage is a free-form message paired with the template sent in its place when the
+// customer service window is closed, see Client.SendSmart. The recipient of Message is
+// replaced by the recipient of the send.
+type SmartMessage struct {
+	Message  *models.Message
+	Template *models.Template
+}
+
+// WithWindowTracker sets the WindowTracker consulted by SendSmart, and by the conversations
+// created without WithConversationWindow.
+func WithWindowTracker(tracker *WindowTracker) ClientOption {
+	return func(client *Client) {
+		client.windows = tracker
+	}
+}
+
+// SendSmart sends the free-form message of message when the customer service window of the
+// recipient is open and its template otherwise, so that the application has a single call
+// path whatever the state of the session.
+//
+// The window is read from the WindowTracker set with WithWindowTracker. Without a tracker,
+// or when the tracker was not fed the latest messages, the free-form message is sent and
+// the template is sent in its place if the API rejects it with the error 131047. When the
+// template is sent in place of the free-form message, the Fallback field of the response is
+// set, with ErrWindowClosed or the error of the free-form message as its cause.
+func (client *Client) SendSmart(ctx context.Context, recipient string, message *SmartMessage,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	if message == nil || (message.Message == nil && message.Template == nil) {
+		return nil, fmt.Errorf("send smart: %w: no message nor template", ErrBadRequestFormat)
+	}
+	if message.Message == nil {
+		return client.SendMessage(ctx, models.NewMessage(recipient, models.WithTemplate(message.Template)), options...)
+	}
+
+	cause := ErrWindowClosed
+	open := true
+	if client.windows != nil && message.Template != nil {
+		var err error
+		// the webhooks carry the wa_id without the leading + of international numbers
+		if open, err = client.windows.IsWindowOpen(ctx, strings.TrimPrefix(recipient, "+")); err != nil {
+			return nil, fmt.Errorf("send smart: %w", err)
+		}
+	}
+	if open {
+		payload := *message.Message
+		payload.To = recipient
+		response, err := client.SendMessage(ctx, &payload, options...)
+		if code, _ := werrors.Code(err); code != werrors.CodeReEngagement || message.Template == nil {
+			return response, err
+		}
+		cause = err
+	}
+
+	response, err := client.SendMessage(ctx, models.NewMessage(recipient, models.WithTemplate(message.Template)),
+		options...)
+	if err != nil {
+		return nil, err
+	}
+	response.Fallback = &ReEngagementFallback{Template: message.Template, Cause: cause}
+
+	return response, nil
+}
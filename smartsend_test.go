@@ -0,0 +1,122 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestSendSmart(t *testing.T) {
+	t.Parallel()
+	var (
+		mu    sync.Mutex
+		types []string
+	)
+	// the API only accepts free-form messages to 255700000001
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message models.Message
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		mu.Lock()
+		types = append(types, message.To+":"+message.Type)
+		mu.Unlock()
+		if message.Type != templateMessageType && strings.TrimPrefix(message.To, "+") != "255700000001" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Re-engagement message","type":"OAuthException","code":131047}}`))
+
+			return
+		}
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewWindowTracker(&WindowTrackerConfig{Clock: clock.NewFake(now)})
+	if err := tracker.Record(ctx, "255700000001", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	options := []ClientOption{WithBaseURL(server.URL), WithVersion("v16.0"), WithPhoneNumberID("phone_1")}
+	client := NewClient(append(options, WithWindowTracker(tracker))...)
+	message := &SmartMessage{
+		Message:  models.NewMessage("", models.WithText("Your order shipped")),
+		Template: models.NewTextTemplate("order_shipped", &models.TemplateLanguage{Code: "en_US"}, nil),
+	}
+
+	// open window: the free-form message is sent
+	response, err := client.SendSmart(ctx, "+255700000001", message)
+	if err != nil || response.Fallback != nil {
+		t.Fatalf("SendSmart() = %+v, %v, want the free-form message sent", response, err)
+	}
+
+	// closed window: the template is sent without trying the free-form message
+	response, err = client.SendSmart(ctx, "255700000002", message)
+	if err != nil {
+		t.Fatalf("SendSmart() error = %v", err)
+	}
+	if response.Fallback == nil || !errors.Is(response.Fallback.Cause, ErrWindowClosed) {
+		t.Fatalf("SendSmart() Fallback = %+v, want cause %v", response.Fallback, ErrWindowClosed)
+	}
+
+	// without a tracker the template is sent after the API rejected the free-form message
+	response, err = NewClient(options...).SendSmart(ctx, "255700000003", message)
+	if err != nil {
+		t.Fatalf("SendSmart() error = %v", err)
+	}
+	if code, _ := werrors.Code(response.Fallback.Cause); code != werrors.CodeReEngagement {
+		t.Fatalf("SendSmart() Fallback = %+v, want code %d", response.Fallback, werrors.CodeReEngagement)
+	}
+
+	// without a template the error of the free-form message is returned
+	_, err = client.SendSmart(ctx, "255700000002", &SmartMessage{Message: message.Message})
+	if code, _ := werrors.Code(err); code != werrors.CodeReEngagement {
+		t.Fatalf("SendSmart() error = %v, want code %d", err, werrors.CodeReEngagement)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"+255700000001:text",
+		"255700000002:template",
+		"255700000003:text", "255700000003:template",
+		"255700000002:text",
+	}
+	if len(types) != len(want) {
+		t.Fatalf("sent %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("sent %v, want %v", types, want)
+
+			break
+		}
+	}
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package subscriptions reads and sets the webhook fields an app is subscribed to, with the
+// subscriptions endpoint of the app. The requests are made with an app access token, the
+// app ID and secret joined by a pipe:
+//
+//	ctx = whatsapp.WithOverrides(ctx, &whatsapp.Overrides{AccessToken: appID + "|" + appSecret})
+//	err := client.Subscriptions(ctx, appID).Check(ctx, listener.Fields(), func(field string) {
+//		log.Printf("the listener handles the %s webhook field but the app is not subscribed to it", field)
+//	})
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// ObjectWhatsAppBusinessAccount is the object of the WhatsApp webhooks.
+const ObjectWhatsAppBusinessAccount = "whatsapp_business_account"
+
+type (
+	// RequestContext holds the credentials of the app, AccessToken is an app access token.
+	RequestContext struct {
+		BaseURL     string `json:"-"`
+		ApiVersion  string `json:"-"` //nolint: revive,stylecheck
+		AccessToken string `json:"-"`
+		AppID       string `json:"-"`
+	}
+
+	// Field is a subscribed webhook field and the API version of its payloads.
+	Field struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+
+	// Subscription is the webhooks subscription of the app to the notifications about an
+	// object.
+	Subscription struct {
+		Object      string   `json:"object"`
+		CallbackURL string   `json:"callback_url,omitempty"`
+		Active      bool     `json:"active"`
+		Fields      []*Field `json:"fields,omitempty"`
+	}
+
+	SubscriptionsList struct {
+		Data []*Subscription `json:"data,omitempty"`
+	}
+
+	// SubscribeRequest subscribes the app to the WhatsApp webhooks fields Fields, in addition
+	// to the fields it is already subscribed to. CallbackURL and VerifyToken are the URL of
+	// the listener and the token it verifies the subscription with, see
+	// webhooks.VerifySubscriptionHandler.
+	SubscribeRequest struct {
+		CallbackURL   string
+		VerifyToken   string
+		Fields        []string
+		IncludeValues bool
+	}
+
+	SuccessResponse struct {
+		Success bool `json:"success"`
+	}
+)
+
+// Client sends the subscriptions requests through a whttp.Sender, so that the middlewares
+// that wrap the sender apply to them as well.
+type Client struct {
+	sender whttp.Sender
+	rctx   *RequestContext
+}
+
+// NewClient creates a new Client that sends the requests using sender with the credentials
+// of rctx.
+func NewClient(sender whttp.Sender, rctx *RequestContext) *Client {
+	return &Client{sender: sender, rctx: rctx}
+}
+
+func (c *Client) request(name, method string) *whttp.Request {
+	return &whttp.Request{
+		Context: &whttp.RequestContext{
+			Name:       name,
+			BaseURL:    c.rctx.BaseURL,
+			ApiVersion: c.rctx.ApiVersion,
+			SenderID:   c.rctx.AppID,
+			Endpoints:  []string{"subscriptions"},
+		},
+		Method: method,
+		Bearer: c.rctx.AccessToken,
+	}
+}
+
+// List lists the webhooks subscriptions of the app, to every object.
+func (c *Client) List(ctx context.Context) (*SubscriptionsList, error) {
+	resp, err := whttp.SendTyped[SubscriptionsList](ctx, c.sender, c.request("list subscriptions", http.MethodGet))
+	if err != nil {
+		return nil, fmt.Errorf("list subscriptions: %w", err)
+	}
+
+	return resp, nil
+}
+
+// Fields returns the WhatsApp webhooks fields the app is subscribed to, none when the
+// subscription is missing or inactive.
+func (c *Client) Fields(ctx context.Context) ([]string, error) {
+	list, err := c.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	for _, subscription := range list.Data {
+		if subscription == nil || subscription.Object != ObjectWhatsAppBusinessAccount || !subscription.Active {
+			continue
+		}
+		for _, field := range subscription.Fields {
+			if field != nil {
+				fields = append(fields, field.Name)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// Subscribe subscribes the app to the WhatsApp webhooks fields of request.
+func (c *Client) Subscribe(ctx context.Context, request *SubscribeRequest) (*SuccessResponse, error) {
+	req := c.request("subscribe", http.MethodPost)
+	req.Form = map[string]string{
+		"object":         ObjectWhatsAppBusinessAccount,
+		"callback_url":   request.CallbackURL,
+		"verify_token":   request.VerifyToken,
+		"fields":         strings.Join(request.Fields, ","),
+		"include_values": strconv.FormatBool(request.IncludeValues),
+	}
+
+	resp, err := whttp.SendTyped[SuccessResponse](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to %s: %w", strings.Join(request.Fields, ","), err)
+	}
+
+	return resp, nil
+}
+
+// Unsubscribe unsubscribes the app from the WhatsApp webhooks fields, from all of them when
+// fields is empty.
+func (c *Client) Unsubscribe(ctx context.Context, fields ...string) (*SuccessResponse, error) {
+	req := c.request("unsubscribe", http.MethodDelete)
+	req.Query = map[string]string{"object": ObjectWhatsAppBusinessAccount}
+	if len(fields) > 0 {
+		req.Query["fields"] = strings.Join(fields, ",")
+	}
+
+	resp, err := whttp.SendTyped[SuccessResponse](ctx, c.sender, req)
+	if err != nil {
+		return nil, fmt.Errorf("unsubscribe: %w", err)
+	}
+
+	return resp, nil
+}
+
+// Check calls warn with each of the handled fields the app is not subscribed to, their
+// notifications are never sent to the listener. Run it at startup with the fields of the
+// listener, see webhooks.EventListener.Fields.
+func (c *Client) Check(ctx context.Context, handled []string, warn func(field string)) error {
+	subscribed, err := c.Fields(ctx)
+	if err != nil {
+		return fmt.Errorf("check subscriptions: %w", err)
+	}
+	for _, field := range Missing(subscribed, handled) {
+		warn(field)
+	}
+
+	return nil
+}
+
+// Missing returns the fields of handled that are not in subscribed.
+func Missing(subscribed, handled []string) []string {
+	set := make(map[string]bool, len(subscribed))
+	for _, field := range subscribed {
+		set[field] = true
+	}
+	var missing []string
+	for _, field := range handled {
+		if !set[field] {
+			missing = append(missing, field)
+			set[field] = true
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package subscriptions_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/subscriptions"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		method string
+		query  string
+		form   map[string]string
+	}
+	var requests []received
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer app|secret" || r.URL.Path != "/v16.0/app/subscriptions" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+		_ = r.ParseForm()
+		form := map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		requests = append(requests, received{r.Method, r.URL.RawQuery, form})
+
+		var response any = map[string]bool{"success": true}
+		if r.Method == http.MethodGet {
+			response = map[string]any{"data": []map[string]any{
+				{"object": "page", "active": true, "fields": []map[string]string{{"name": "feed"}}},
+				{"object": "whatsapp_business_account", "active": true, "callback_url": "https://example.com/hook",
+					"fields": []map[string]string{{"name": "messages", "version": "v16.0"}, {"name": "account_update"}}},
+			}}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.TODO()
+	client := subscriptions.NewClient(whttp.NewSender(http.DefaultClient), &subscriptions.RequestContext{
+		BaseURL:     server.URL,
+		ApiVersion:  "v16.0",
+		AccessToken: "app|secret",
+		AppID:       "app",
+	})
+
+	fields, err := client.Fields(ctx)
+	if err != nil || !reflect.DeepEqual(fields, []string{"messages", "account_update"}) {
+		t.Fatalf("Fields() = %v, %v", fields, err)
+	}
+
+	var warned []string
+	handled := []string{webhooks.FieldMessages, webhooks.FieldFlows, webhooks.FieldSecurity}
+	if err := client.Check(ctx, handled, func(field string) { warned = append(warned, field) }); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !reflect.DeepEqual(warned, []string{"flows", "security"}) {
+		t.Errorf("Check() warned about %v, want [flows security]", warned)
+	}
+
+	if resp, err := client.Subscribe(ctx, &subscriptions.SubscribeRequest{
+		CallbackURL: "https://example.com/hook",
+		VerifyToken: "verify",
+		Fields:      []string{webhooks.FieldFlows, webhooks.FieldSecurity},
+	}); err != nil || !resp.Success {
+		t.Fatalf("Subscribe() = %+v, %v", resp, err)
+	}
+	if resp, err := client.Unsubscribe(ctx, webhooks.FieldAccountUpdate); err != nil || !resp.Success {
+		t.Fatalf("Unsubscribe() = %+v, %v", resp, err)
+	}
+
+	want := []received{
+		{http.MethodGet, "", map[string]string{}},
+		{http.MethodGet, "", map[string]string{}},
+		{http.MethodPost, "", map[string]string{
+			"object": "whatsapp_business_account", "callback_url": "https://example.com/hook",
+			"verify_token": "verify", "fields": "flows,security", "include_values": "false",
+		}},
+		{http.MethodDelete, "fields=account_update&object=whatsapp_business_account", map[string]string{}},
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %+v, want %+v", requests, want)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		subscribed []string
+		handled    []string
+		want       []string
+	}{
+		{name: "all subscribed", subscribed: []string{"messages", "flows"}, handled: []string{"messages"}},
+		{name: "none subscribed", handled: []string{"messages", "messages"}, want: []string{"messages"}},
+		{name: "nothing handled", subscribed: []string{"messages"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := subscriptions.Missing(tt.subscribed, tt.handled); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Missing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
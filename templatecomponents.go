@@ -0,0 +1,234 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+// ErrTemplateParameterMismatch is returned when the components of a template message do not
+// match the definition of the template, which the API rejects with the error 132000.
+var ErrTemplateParameterMismatch = errors.New("template parameters do not match the template")
+
+// templatePlaceholder matches the positional, {{1}}, and named, {{name}}, placeholders.
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`) //nolint:gochecknoglobals
+
+// templateButtonSubTypes maps the types of the buttons of a template definition to the sub
+// types of the button components that set their parameters.
+var templateButtonSubTypes = map[string]string{ //nolint:gochecknoglobals
+	"QUICK_REPLY": "quick_reply",
+	"URL":         "url",
+	"COPY_CODE":   "copy_code",
+	"OTP":         "url",
+	"FLOW":        "flow",
+}
+
+// ValidateComponents checks the components of a template message against the definition of
+// the template: the number of parameters of the header and the body, the type of the header
+// parameter of media headers, and the indexes and sub types of the buttons. It returns an
+// error wrapping ErrTemplateParameterMismatch that lists all the mismatches, or nil when the
+// template has no component definitions to check against.
+func (template *TemplateInformation) ValidateComponents(components []*models.TemplateComponent) error {
+	if len(template.Components) == 0 {
+		return nil
+	}
+	definitions := make(map[string]*TemplateDefinitionComponent, len(template.Components))
+	for _, definition := range template.Components {
+		if definition != nil {
+			definitions[strings.ToUpper(definition.Type)] = definition
+		}
+	}
+
+	var (
+		header, body *models.TemplateComponent
+		buttons      []*models.TemplateComponent
+		problems     []string
+	)
+	for _, component := range components {
+		if component == nil {
+			continue
+		}
+		switch strings.ToLower(component.Type) {
+		case string(models.TemplateComponentTypeHeader):
+			header = component
+		case string(models.TemplateComponentTypeBody):
+			body = component
+		case "button":
+			buttons = append(buttons, component)
+		default:
+			problems = append(problems, fmt.Sprintf("unknown component type %q", component.Type))
+		}
+	}
+
+	problems = append(problems, checkTemplateHeader(definitions["HEADER"], parametersOf(header))...)
+	if want, got := countPlaceholders(definitions["BODY"]), len(parametersOf(body)); want != got {
+		problems = append(problems, fmt.Sprintf("body: want %d parameters, got %d", want, got))
+	}
+	var definedButtons []*TemplateDefinitionButton
+	if definition := definitions["BUTTONS"]; definition != nil {
+		definedButtons = definition.Buttons
+	}
+	problems = append(problems, checkTemplateButtons(definedButtons, buttons)...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s (%s): %s", ErrTemplateParameterMismatch, template.Name, template.Language,
+			strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// ValidateTemplateMessage checks that the template of a template message exists in its
+// language and is approved, see ValidateTemplate, and that its components match the
+// definition of the template, see TemplateInformation.ValidateComponents.
+func (cache *MetadataCache) ValidateTemplateMessage(ctx context.Context, template *models.Template) error {
+	var language string
+	if template.Language != nil {
+		language = template.Language.Code
+	}
+	if err := cache.ValidateTemplate(ctx, template.Name, language); err != nil {
+		return err
+	}
+	definition, err := cache.Template(ctx, template.Name, language)
+	if err != nil {
+		return err
+	}
+
+	return definition.ValidateComponents(template.Components)
+}
+
+func parametersOf(component *models.TemplateComponent) []*models.TemplateParameter {
+	if component == nil {
+		return nil
+	}
+
+	return component.Parameters
+}
+
+// countPlaceholders returns the number of distinct placeholders in the text of definition.
+func countPlaceholders(definition *TemplateDefinitionComponent) int {
+	if definition == nil {
+		return 0
+	}
+
+	return countTextPlaceholders(definition.Text)
+}
+
+func countTextPlaceholders(text string) int {
+	names := make(map[string]bool)
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(text, -1) {
+		names[match[1]] = true
+	}
+
+	return len(names)
+}
+
+func checkTemplateHeader(definition *TemplateDefinitionComponent, parameters []*models.TemplateParameter) []string {
+	if definition == nil {
+		if len(parameters) > 0 {
+			return []string{fmt.Sprintf("header: the template has no header, got %d parameters", len(parameters))}
+		}
+
+		return nil
+	}
+
+	format := strings.ToUpper(definition.Format)
+	if format == "" || format == "TEXT" {
+		var problems []string
+		if want := countPlaceholders(definition); want != len(parameters) {
+			problems = append(problems, fmt.Sprintf("header: want %d parameters, got %d", want, len(parameters)))
+		}
+		for i, parameter := range parameters {
+			if parameter != nil && !strings.EqualFold(parameter.Type, "text") {
+				problems = append(problems, fmt.Sprintf("header: parameter %d is %s, want text", i+1, parameter.Type))
+			}
+		}
+
+		return problems
+	}
+
+	want := strings.ToLower(format)
+	if len(parameters) != 1 || parameters[0] == nil {
+		return []string{fmt.Sprintf("header: want a %s parameter, got %d parameters", want, len(parameters))}
+	}
+	if !strings.EqualFold(parameters[0].Type, want) {
+		return []string{fmt.Sprintf("header: got a %s parameter, want %s", parameters[0].Type, want)}
+	}
+
+	return nil
+}
+
+func checkTemplateButtons(definitions []*TemplateDefinitionButton, buttons []*models.TemplateComponent) []string {
+	var problems []string
+	seen := make(map[int]bool, len(buttons))
+	for _, button := range buttons {
+		index, err := strconv.Atoi(button.Index.String())
+		if err != nil || index < 0 || index >= len(definitions) || definitions[index] == nil {
+			problems = append(problems, fmt.Sprintf("button index %q: the template has %d buttons",
+				button.Index.String(), len(definitions)))
+
+			continue
+		}
+		if seen[index] {
+			problems = append(problems, fmt.Sprintf("button %d: set more than once", index))
+
+			continue
+		}
+		seen[index] = true
+
+		definition := definitions[index]
+		subType, ok := templateButtonSubTypes[strings.ToUpper(definition.Type)]
+		switch {
+		case strings.EqualFold(definition.Type, "PHONE_NUMBER"):
+			problems = append(problems, fmt.Sprintf("button %d: phone number buttons take no parameters", index))
+		case ok && !strings.EqualFold(button.SubType, subType):
+			problems = append(problems, fmt.Sprintf("button %d: sub_type %q, want %q for a %s button",
+				index, button.SubType, subType, definition.Type))
+		}
+	}
+
+	for index, definition := range definitions {
+		if definition != nil && !seen[index] && buttonNeedsParameter(definition) {
+			problems = append(problems, fmt.Sprintf("button %d: the %s button needs a parameter", index, definition.Type))
+		}
+	}
+
+	return problems
+}
+
+// buttonNeedsParameter reports whether the button must be set by a button component: URL
+// buttons with a dynamic suffix, and the buttons carrying a code.
+func buttonNeedsParameter(definition *TemplateDefinitionButton) bool {
+	switch strings.ToUpper(definition.Type) {
+	case "URL":
+		return countTextPlaceholders(definition.URL) > 0
+	case "COPY_CODE", "OTP":
+		return true
+	default:
+		return false
+	}
+}
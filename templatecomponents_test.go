@@ -0,0 +1,146 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestTemplateInformation_ValidateComponents(t *testing.T) {
+	t.Parallel()
+	shipped := &TemplateInformation{
+		Name:     "order_shipped",
+		Language: "en_US",
+		Components: []*TemplateDefinitionComponent{
+			{Type: "HEADER", Format: "IMAGE"},
+			{Type: "BODY", Text: "Hi {{1}}, order {{2}} ships on {{3}}. Thanks {{1}}!"},
+			{Type: "FOOTER", Text: "Reply STOP to opt out"},
+			{Type: "BUTTONS", Buttons: []*TemplateDefinitionButton{
+				{Type: "URL", Text: "Track", URL: "https://example.com/track/{{1}}"},
+				{Type: "QUICK_REPLY", Text: "Stop"},
+				{Type: "PHONE_NUMBER", Text: "Call us", PhoneNumber: "+255700000000"},
+			}},
+		},
+	}
+	text := func(s string) *models.TemplateParameter { return &models.TemplateParameter{Type: "text", Text: s} }
+	image := &models.TemplateParameter{Type: "image", Image: &models.Media{Link: "https://example.com/box.png"}}
+	header := &models.TemplateComponent{Type: "header", Parameters: []*models.TemplateParameter{image}}
+	body := &models.TemplateComponent{Type: "body", Parameters: []*models.TemplateParameter{
+		text("Jane"), text("1234"), text("Monday"),
+	}}
+	track := &models.TemplateComponent{
+		Type: "button", SubType: "url", Index: "0", Parameters: []*models.TemplateParameter{text("1234")},
+	}
+
+	tests := []struct {
+		name       string
+		template   *TemplateInformation
+		components []*models.TemplateComponent
+		problems   []string
+	}{
+		{
+			name:       "valid",
+			template:   shipped,
+			components: []*models.TemplateComponent{header, body, track},
+		},
+		{
+			name:       "quick reply payload",
+			template:   shipped,
+			components: []*models.TemplateComponent{header, body, track, {Type: "button", SubType: "quick_reply", Index: "1"}},
+		},
+		{
+			name:     "missing parameters",
+			template: shipped,
+			components: []*models.TemplateComponent{
+				{Type: "body", Parameters: []*models.TemplateParameter{text("Jane")}},
+			},
+			problems: []string{
+				"header: want a image parameter, got 0 parameters",
+				"body: want 3 parameters, got 1",
+				"button 0: the URL button needs a parameter",
+			},
+		},
+		{
+			name:     "wrong header type",
+			template: shipped,
+			components: []*models.TemplateComponent{
+				{Type: "header", Parameters: []*models.TemplateParameter{{Type: "video"}}}, body, track,
+			},
+			problems: []string{"header: got a video parameter, want image"},
+		},
+		{
+			name:     "bad buttons",
+			template: shipped,
+			components: []*models.TemplateComponent{
+				header, body, track,
+				{Type: "button", SubType: "url", Index: "1"},
+				{Type: "button", SubType: "url", Index: "2"},
+				{Type: "button", SubType: "url", Index: "3"},
+				{Type: "button", SubType: "url", Index: "0"},
+			},
+			problems: []string{
+				`button 1: sub_type "url", want "quick_reply"`,
+				"button 2: phone number buttons take no parameters",
+				`button index "3": the template has 3 buttons`,
+				"button 0: set more than once",
+			},
+		},
+		{
+			name:       "no definitions",
+			template:   &TemplateInformation{Name: "legacy", Language: "en_US"},
+			components: []*models.TemplateComponent{body},
+		},
+		{
+			name: "text header",
+			template: &TemplateInformation{Name: "welcome", Language: "en_US", Components: []*TemplateDefinitionComponent{
+				{Type: "HEADER", Format: "TEXT", Text: "Welcome {{name}}"},
+				{Type: "BODY", Text: "Glad to have you."},
+			}},
+			components: []*models.TemplateComponent{body},
+			problems:   []string{"header: want 1 parameters, got 0", "body: want 0 parameters, got 3"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.template.ValidateComponents(tt.components)
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateComponents() error = %v", err)
+				}
+
+				return
+			}
+			if !errors.Is(err, ErrTemplateParameterMismatch) {
+				t.Fatalf("ValidateComponents() error = %v, want %v", err, ErrTemplateParameterMismatch)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("ValidateComponents() error = %v, want it to contain %q", err, problem)
+				}
+			}
+		})
+	}
+}
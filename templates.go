@@ -0,0 +1,120 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+type (
+	// TemplateInformation is a message template of the WhatsApp Business Account.
+	TemplateInformation struct {
+		ID             string `json:"id"`
+		Name           string `json:"name"`
+		Language       string `json:"language"`
+		Status         string `json:"status"`
+		Category       string `json:"category"`
+		RejectedReason string `json:"rejected_reason,omitempty"`
+
+		Components []*TemplateDefinitionComponent `json:"components,omitempty"`
+	}
+
+	// TemplateDefinitionComponent is a component of the definition of a template, as opposed to
+	// models.TemplateComponent which holds the parameters of a component of a template message.
+	// Type is HEADER, BODY, FOOTER or BUTTONS. Format is the format of HEADER components: TEXT,
+	// IMAGE, VIDEO, DOCUMENT or LOCATION. Text may contain placeholders like {{1}}.
+	TemplateDefinitionComponent struct {
+		Type    string                      `json:"type"`
+		Format  string                      `json:"format,omitempty"`
+		Text    string                      `json:"text,omitempty"`
+		Buttons []*TemplateDefinitionButton `json:"buttons,omitempty"`
+	}
+
+	// TemplateDefinitionButton is a button of the BUTTONS component of a template definition.
+	// Type is QUICK_REPLY, URL, PHONE_NUMBER, COPY_CODE, OTP or FLOW.
+	TemplateDefinitionButton struct {
+		Type        string `json:"type"`
+		Text        string `json:"text,omitempty"`
+		URL         string `json:"url,omitempty"`
+		PhoneNumber string `json:"phone_number,omitempty"`
+	}
+
+	TemplatesList struct {
+		Data   []*TemplateInformation `json:"data,omitempty"`
+		Paging *Paging                `json:"paging,omitempty"`
+	}
+)
+
+// ListTemplates lists the message templates of the WhatsApp Business Account set with
+// WithBusinessAccountID. Only the first page is returned, see ListAllTemplates.
+func (client *Client) ListTemplates(ctx context.Context) (*TemplatesList, error) {
+	return client.listTemplates(ctx, "")
+}
+
+// ListAllTemplates lists the message templates of the WhatsApp Business Account, following
+// the pages of the list.
+func (client *Client) ListAllTemplates(ctx context.Context) ([]*TemplateInformation, error) {
+	var (
+		templates []*TemplateInformation
+		after     string
+	)
+	for {
+		list, err := client.listTemplates(ctx, after)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, list.Data...)
+		if list.Paging == nil || list.Paging.Next == "" || list.Paging.Cursors == nil ||
+			list.Paging.Cursors.After == "" || list.Paging.Cursors.After == after {
+			return templates, nil
+		}
+		after = list.Paging.Cursors.After
+	}
+}
+
+func (client *Client) listTemplates(ctx context.Context, after string) (*TemplatesList, error) {
+	cctx := client.context(ctx)
+	reqCtx := &whttp.RequestContext{
+		Name:       "list templates",
+		BaseURL:    cctx.baseURL,
+		ApiVersion: cctx.apiVersion,
+		SenderID:   cctx.businessAccountID,
+		Endpoints:  []string{"message_templates"},
+	}
+	request := &whttp.Request{
+		Context: reqCtx,
+		Method:  http.MethodGet,
+		Bearer:  cctx.accessToken,
+	}
+	if after != "" {
+		request.Query = map[string]string{"after": after}
+	}
+
+	templates, err := whttp.SendTyped[TemplatesList](ctx, client.sender, request)
+	if err != nil {
+		return nil, fmt.Errorf("list templates: %w", err)
+	}
+
+	return templates, nil
+}
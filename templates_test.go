@@ -0,0 +1,59 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+func TestSendTemplateLanguageCode(t *testing.T) {
+	t.Parallel()
+	var sends int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&sends, 1)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"))
+	ctx := context.Background()
+
+	_, err := client.SendTemplate(ctx, "255700000001", &Template{Name: "otp", LanguageCode: "en-GB"})
+	if !errors.Is(err, models.ErrInvalidLanguageCode) {
+		t.Errorf("SendTemplate(en-GB) error = %v, want %v", err, models.ErrInvalidLanguageCode)
+	}
+	_, err = client.SendTextTemplate(ctx, "255700000001", &TextTemplateRequest{Name: "otp", LanguageCode: "english"})
+	if !errors.Is(err, models.ErrInvalidLanguageCode) {
+		t.Errorf("SendTextTemplate(english) error = %v, want %v", err, models.ErrInvalidLanguageCode)
+	}
+	if got := atomic.LoadInt32(&sends); got != 0 {
+		t.Errorf("sends = %d, want invalid codes rejected before the API", got)
+	}
+
+	if _, err := client.SendTemplate(ctx, "255700000001", &Template{Name: "otp", LanguageCode: "en_GB"}); err != nil {
+		t.Errorf("SendTemplate(en_GB) error = %v", err)
+	}
+}
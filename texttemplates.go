@@ -0,0 +1,238 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/SeamPay/whatsapp/message"
+)
+
+var (
+	ErrTextTemplateNotFound = errors.New("text template not found")
+	ErrTextTemplatesNotSet  = errors.New("text templates not set, see WithTextTemplates")
+	ErrTextTooLong          = errors.New("rendered text is too long")
+)
+
+// formattingLookalikes replaces the characters of the WhatsApp formatting syntax by
+// lookalikes that are not interpreted, so that a value cannot make the rest of the message
+// bold, italic, strikethrough or monospace.
+var formattingLookalikes = strings.NewReplacer( //nolint:gochecknoglobals
+	"*", "∗", // U+2217 ASTERISK OPERATOR
+	"_", "＿", // U+FF3F FULLWIDTH LOW LINE
+	"~", "∼", // U+223C TILDE OPERATOR
+	"`", "ˋ", // U+02CB MODIFIER LETTER GRAVE ACCENT
+)
+
+type (
+	// TextTemplatesConfig configures TextTemplates. Funcs are added to the functions of the
+	// templates. When AutoEscape is set, every value printed by a template is passed through
+	// the plain function, unless its pipeline ends with raw: {{.Note | raw}}. MaxLength is
+	// the maximum number of characters of a rendered text, it defaults to the limit of the
+	// API for text messages.
+	TextTemplatesConfig struct {
+		Funcs      template.FuncMap
+		AutoEscape bool
+		MaxLength  int
+	}
+
+	// TextTemplates renders the text messages from Go text/template templates, so that the
+	// wording of the messages is kept apart from the code sending them:
+	//
+	//	templates := whatsapp.NewTextTemplates(&whatsapp.TextTemplatesConfig{AutoEscape: true})
+	//	templates.MustRegister("receipt", "Hi {{.Name}}, we received *{{.Amount}}*. Thank you!")
+	//	client := whatsapp.NewClient(whatsapp.WithTextTemplates(templates), ...)
+	//	client.SendRenderedText(ctx, recipient, "receipt", customer)
+	//
+	// Besides the builtin functions, the templates can use plain, which neutralizes the
+	// WhatsApp formatting characters (* _ ~ `) and removes the control characters of a value,
+	// and raw, which prints a value as is.
+	TextTemplates struct {
+		mu         sync.RWMutex
+		templates  map[string]*template.Template
+		funcs      template.FuncMap
+		autoEscape bool
+		maxLength  int
+	}
+)
+
+// NewTextTemplates returns TextTemplates configured with config, which may be nil.
+func NewTextTemplates(config *TextTemplatesConfig) *TextTemplates {
+	if config == nil {
+		config = &TextTemplatesConfig{}
+	}
+	funcs := template.FuncMap{
+		"plain": PlainText,
+		"raw":   func(v any) any { return v },
+	}
+	for name, f := range config.Funcs {
+		funcs[name] = f
+	}
+	templates := &TextTemplates{
+		templates:  make(map[string]*template.Template),
+		funcs:      funcs,
+		autoEscape: config.AutoEscape,
+		maxLength:  config.MaxLength,
+	}
+	if templates.maxLength <= 0 {
+		templates.maxLength = message.MaxTextLength
+	}
+
+	return templates
+}
+
+// PlainText formats v as text that WhatsApp displays literally: the formatting characters
+// are replaced by lookalikes and the control characters, other than new lines and tabs, are
+// removed.
+func PlainText(v any) string {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+
+		return r
+	}, fmt.Sprint(v))
+
+	return formattingLookalikes.Replace(s)
+}
+
+// Register parses text as the template name, replacing the template with the same name.
+// Referencing a missing key of a map is an error when rendering.
+func (templates *TextTemplates) Register(name, text string) error {
+	tmpl, err := template.New(name).Funcs(templates.funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("register text template %s: %w", name, err)
+	}
+	if templates.autoEscape {
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				escapeNode(t.Tree, t.Tree.Root)
+			}
+		}
+	}
+
+	templates.mu.Lock()
+	templates.templates[name] = tmpl
+	templates.mu.Unlock()
+
+	return nil
+}
+
+// MustRegister is like Register but panics if the template cannot be parsed.
+func (templates *TextTemplates) MustRegister(name, text string) {
+	if err := templates.Register(name, text); err != nil {
+		panic(err)
+	}
+}
+
+// Render renders the template name with data. The rendered text is trimmed, an error
+// wrapping ErrTextTooLong is returned when it is longer than the maximum length.
+func (templates *TextTemplates) Render(name string, data any) (string, error) {
+	templates.mu.RLock()
+	tmpl, ok := templates.templates[name]
+	templates.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrTextTemplateNotFound, name)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render text template %s: %w", name, err)
+	}
+	text := strings.TrimSpace(b.String())
+	if n := utf8.RuneCountInString(text); n > templates.maxLength {
+		return "", fmt.Errorf("%w: template %s rendered %d characters, the maximum is %d",
+			ErrTextTooLong, name, n, templates.maxLength)
+	}
+
+	return text, nil
+}
+
+// WithTextTemplates sets the templates rendered by SendRenderedText.
+func WithTextTemplates(templates *TextTemplates) ClientOption {
+	return func(client *Client) {
+		client.textTemplates = templates
+	}
+}
+
+// SendRenderedText renders the text template name with data and sends the result to
+// recipient as a text message. Nothing is sent when the template cannot be rendered.
+func (client *Client) SendRenderedText(ctx context.Context, recipient, name string, data any,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	if client.textTemplates == nil {
+		return nil, ErrTextTemplatesNotSet
+	}
+	text, err := client.textTemplates.Render(name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.SendText(ctx, recipient, text, options...)
+}
+
+// escapeNode appends plain to the pipelines of the actions printing a value under node,
+// unless they already end with plain or raw.
+func escapeNode(tree *parse.Tree, node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			escapeNode(tree, child)
+		}
+	case *parse.ActionNode:
+		pipe := node.Pipe
+		if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) == 0 {
+			return
+		}
+		last := pipe.Cmds[len(pipe.Cmds)-1]
+		if len(last.Args) > 0 {
+			ident, ok := last.Args[0].(*parse.IdentifierNode)
+			if ok && (ident.Ident == "plain" || ident.Ident == "raw") {
+				return
+			}
+		}
+		plain := parse.NewIdentifier("plain").SetTree(tree).SetPos(pipe.Pos)
+		pipe.Cmds = append(pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      pipe.Pos,
+			Args:     []parse.Node{plain},
+		})
+	case *parse.IfNode:
+		escapeNode(tree, node.List)
+		escapeNode(tree, node.ElseList)
+	case *parse.RangeNode:
+		escapeNode(tree, node.List)
+		escapeNode(tree, node.ElseList)
+	case *parse.WithNode:
+		escapeNode(tree, node.List)
+		escapeNode(tree, node.ElseList)
+	}
+}
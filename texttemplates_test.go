@@ -0,0 +1,180 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTextTemplates_Render(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		autoEscape bool
+		text       string
+		data       any
+		want       string
+		wantErr    bool
+		errIs      error
+	}{
+		{
+			name: "plain values",
+			text: "Hi {{.Name}}, your order {{.Order}} has shipped.",
+			data: map[string]any{"Name": "Asha", "Order": 1042},
+			want: "Hi Asha, your order 1042 has shipped.",
+		},
+		{
+			name: "values are printed as is without auto escape",
+			text: "Hi {{.Name}}",
+			data: map[string]any{"Name": "*Asha*"},
+			want: "Hi *Asha*",
+		},
+		{
+			name:       "auto escape neutralizes formatting",
+			autoEscape: true,
+			text:       "Hi *{{.Name}}*",
+			data:       map[string]any{"Name": "_Asha_ ~`x`~\x07"},
+			want:       "Hi *＿Asha＿ ∼ˋxˋ∼*",
+		},
+		{
+			name:       "auto escape in range and if",
+			autoEscape: true,
+			text:       "{{range .Items}}{{if .}}- {{.}}\n{{end}}{{end}}",
+			data:       map[string]any{"Items": []string{"a*b", "", "c_d"}},
+			want:       "- a∗b\n- c＿d",
+		},
+		{
+			name:       "raw opts out of auto escape",
+			autoEscape: true,
+			text:       "{{.Name}} {{.Note | raw}}",
+			data:       map[string]any{"Name": "*a*", "Note": "*b*"},
+			want:       "∗a∗ *b*",
+		},
+		{
+			name: "explicit plain",
+			text: "{{plain .Name}}",
+			data: map[string]any{"Name": "*a*"},
+			want: "∗a∗",
+		},
+		{
+			name:    "missing key",
+			text:    "Hi {{.Name}}",
+			data:    map[string]any{},
+			wantErr: true,
+		},
+		{
+			name:    "too long",
+			text:    `{{.Text}}`,
+			data:    map[string]any{"Text": strings.Repeat("é", 4097)},
+			wantErr: true,
+			errIs:   ErrTextTooLong,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			templates := NewTextTemplates(&TextTemplatesConfig{AutoEscape: tt.autoEscape})
+			templates.MustRegister("test", tt.text)
+			got, err := templates.Render("test", tt.data)
+			if tt.wantErr {
+				if err == nil || (tt.errIs != nil && !errors.Is(err, tt.errIs)) {
+					t.Fatalf("Render() error = %v, want an error matching %v", err, tt.errIs)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextTemplates_Errors(t *testing.T) {
+	t.Parallel()
+	templates := NewTextTemplates(&TextTemplatesConfig{
+		Funcs: template.FuncMap{"upper": strings.ToUpper},
+	})
+	if err := templates.Register("broken", "{{.Name"); err == nil {
+		t.Fatalf("Register() error = nil, want a parse error")
+	}
+	if _, err := templates.Render("unknown", nil); !errors.Is(err, ErrTextTemplateNotFound) {
+		t.Fatalf("Render() error = %v, want %v", err, ErrTextTemplateNotFound)
+	}
+	templates.MustRegister("shout", "{{upper .}}")
+	if got, err := templates.Render("shout", "hello"); err != nil || got != "HELLO" {
+		t.Fatalf("Render() = %q, %v, want %q", got, err, "HELLO")
+	}
+}
+
+func TestClient_SendRenderedText(t *testing.T) {
+	t.Parallel()
+	var (
+		hits int
+		body struct {
+			Text struct {
+				Body string `json:"body"`
+			} `json:"text"`
+		}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.OUT"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	if _, err := NewClient(WithBaseURL(server.URL)).SendRenderedText(ctx, "+255700000001", "receipt", nil); !errors.Is(
+		err, ErrTextTemplatesNotSet) {
+		t.Fatalf("SendRenderedText() error = %v, want %v", err, ErrTextTemplatesNotSet)
+	}
+
+	templates := NewTextTemplates(&TextTemplatesConfig{AutoEscape: true, MaxLength: 40})
+	templates.MustRegister("receipt", "Hi {{.Name}}, we received *{{.Amount}}*.")
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithTextTemplates(templates))
+
+	data := map[string]any{"Name": "Asha_", "Amount": "TZS 5,000"}
+	if _, err := client.SendRenderedText(ctx, "+255700000001", "receipt", data); err != nil {
+		t.Fatalf("SendRenderedText() error = %v", err)
+	}
+	if want := "Hi Asha＿, we received *TZS 5,000*."; body.Text.Body != want {
+		t.Errorf("sent text = %q, want %q", body.Text.Body, want)
+	}
+
+	data["Name"] = strings.Repeat("A", 40)
+	if _, err := client.SendRenderedText(ctx, "+255700000001", "receipt", data); !errors.Is(err, ErrTextTooLong) {
+		t.Fatalf("SendRenderedText() error = %v, want %v", err, ErrTextTooLong)
+	}
+	if hits != 1 {
+		t.Errorf("server hits = %d, want 1", hits)
+	}
+}
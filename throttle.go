@@ -0,0 +1,57 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/ratelimit"
+)
+
+// throttleMiddleware tells the throttler about the messages rejected because the
+// throughput of their phone number was exceeded, with error 130429 or 80007 or a 429
+// response, so that the rate limiter slows the phone number down.
+func throttleMiddleware(throttler ratelimit.Throttler) whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			err := next.Send(ctx, request, v)
+			if err != nil && isMessageRequest(request) && isThrottled(err) {
+				retryAfter, _ := whttp.RetryAfter(err)
+				throttler.Throttle(request.Context.SenderID, retryAfter)
+			}
+
+			return err
+		})
+	}
+}
+
+// isThrottled reports whether err tells that messages are sent faster than allowed.
+func isThrottled(err error) bool {
+	if werrors.IsThroughputError(err) {
+		return true
+	}
+	var re *whttp.ResponseError
+
+	return errors.As(err, &re) && re.Code == http.StatusTooManyRequests
+}
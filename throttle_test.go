@@ -0,0 +1,83 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type throttleRecorder struct {
+	mu        sync.Mutex
+	throttled map[string]time.Duration
+}
+
+func (recorder *throttleRecorder) Wait(context.Context, string, string) error {
+	return nil
+}
+
+func (recorder *throttleRecorder) Throttle(phoneNumberID string, retryAfter time.Duration) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.throttled[phoneNumberID] = retryAfter
+}
+
+func TestThrottleMiddleware(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "phone_throughput"):
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Rate limit hit","code":130429}}`))
+		case strings.Contains(r.URL.Path, "phone_waba"):
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Rate limit issues","code":80007}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"Message undeliverable","code":131026}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	recorder := &throttleRecorder{throttled: make(map[string]time.Duration)}
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithRateLimiter(recorder))
+	for _, phoneNumberID := range []string{"phone_throughput", "phone_waba", "phone_undeliverable"} {
+		ctx := WithOverrides(context.TODO(), &Overrides{PhoneNumberID: phoneNumberID})
+		if _, err := client.SendText(ctx, "255700000000", "hi"); err == nil {
+			t.Fatalf("SendText() from %s error = nil", phoneNumberID)
+		}
+	}
+
+	want := map[string]time.Duration{"phone_throughput": 3 * time.Second, "phone_waba": 0}
+	if len(recorder.throttled) != len(want) {
+		t.Fatalf("throttled = %v, want %v", recorder.throttled, want)
+	}
+	for phoneNumberID, retryAfter := range want {
+		if got, ok := recorder.throttled[phoneNumberID]; !ok || got != retryAfter {
+			t.Errorf("throttled[%s] = %v, %v, want %v", phoneNumberID, got, ok, retryAfter)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+const (
+	DefaultSendTimeout     = 30 * time.Second
+	DefaultUploadTimeout   = 5 * time.Minute
+	DefaultDownloadTimeout = 5 * time.Minute
+)
+
+// uploadMediaRequestName is the name of the media upload request, it is used to apply
+// Timeouts.Upload instead of Timeouts.Send.
+const uploadMediaRequestName = "upload media"
+
+// Timeouts are the default timeouts applied to the operations of the client when the
+// context passed by the caller has no deadline. A zero value disables the timeout.
+//
+// Send applies to messaging and management API calls, Upload to media uploads and Download
+// to media downloads, these two get longer budgets as they transfer up to 100MB of data.
+type Timeouts struct {
+	Send     time.Duration
+	Upload   time.Duration
+	Download time.Duration
+}
+
+// DefaultTimeouts returns the timeouts used when WithTimeouts is not set.
+func DefaultTimeouts() *Timeouts {
+	return &Timeouts{
+		Send:     DefaultSendTimeout,
+		Upload:   DefaultUploadTimeout,
+		Download: DefaultDownloadTimeout,
+	}
+}
+
+// WithTimeouts sets the default timeouts of the client. Pass a zero Timeouts to disable them.
+func WithTimeouts(timeouts *Timeouts) ClientOption {
+	return func(client *Client) {
+		if timeouts == nil {
+			timeouts = &Timeouts{}
+		}
+		client.timeouts = *timeouts
+	}
+}
+
+// withTimeout returns a context with the timeout applied, unless ctx already has a deadline
+// or the timeout is zero.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeoutMiddleware applies the Send or Upload timeout to each request.
+func timeoutMiddleware(timeouts Timeouts) whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			timeout := timeouts.Send
+			if request.Context != nil && request.Context.Name == uploadMediaRequestName {
+				timeout = timeouts.Upload
+			}
+			ctx, cancel := withTimeout(ctx, timeout)
+			defer cancel()
+
+			return next.Send(ctx, request, v)
+		})
+	}
+}
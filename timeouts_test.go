@@ -0,0 +1,119 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientTimeouts(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		timeouts    *Timeouts
+		deadline    time.Duration
+		wantTimeout time.Duration
+	}{
+		{
+			name:        "send timeout applied",
+			timeouts:    &Timeouts{Send: time.Minute},
+			wantTimeout: time.Minute,
+		},
+		{
+			name:        "caller deadline kept",
+			timeouts:    &Timeouts{Send: time.Minute},
+			deadline:    time.Hour,
+			wantTimeout: time.Hour,
+		},
+		{
+			name:     "timeouts disabled",
+			timeouts: &Timeouts{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				deadline time.Time
+				ok       bool
+			)
+			transport := roundTripFunc(func(request *http.Request) (*http.Response, error) {
+				deadline, ok = request.Context().Deadline()
+				recorder := httptest.NewRecorder()
+				_, _ = recorder.WriteString(`{"id":"phone_id"}`)
+
+				return recorder.Result(), nil
+			})
+
+			client := NewClient(WithTransport(transport), WithTimeouts(tt.timeouts))
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			start := time.Now()
+			if _, err := client.PhoneNumberByID(ctx); err != nil {
+				t.Fatalf("PhoneNumberByID() error = %v", err)
+			}
+
+			if tt.wantTimeout == 0 {
+				if ok {
+					t.Errorf("request has deadline %v, want none", deadline)
+				}
+
+				return
+			}
+			if !ok {
+				t.Fatal("request has no deadline")
+			}
+			if got := deadline.Sub(start); got < tt.wantTimeout-time.Second || got > tt.wantTimeout+time.Second {
+				t.Errorf("request timeout = %v, want %v", got, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestDownloadMediaTimeout(t *testing.T) {
+	t.Parallel()
+	var hasDeadline bool
+	transport := roundTripFunc(func(request *http.Request) (*http.Response, error) {
+		_, hasDeadline = request.Context().Deadline()
+		recorder := httptest.NewRecorder()
+		_, _ = recorder.WriteString(`{"url":"http://media.example.com/file"}`)
+
+		return recorder.Result(), nil
+	})
+
+	client := NewClient(WithTransport(transport), WithTimeouts(&Timeouts{Download: time.Minute}))
+	if _, err := client.DownloadMedia(context.Background(), "media_id", 0); err != nil {
+		t.Fatalf("DownloadMedia() error = %v", err)
+	}
+	if !hasDeadline {
+		t.Error("media download has no deadline")
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+const (
+	DefaultTokenWatchInterval  = time.Hour
+	DefaultTokenWatchThreshold = 7 * 24 * time.Hour
+)
+
+type (
+	// TokenInspector inspects an access token, *Client implements it with DebugToken.
+	TokenInspector interface {
+		DebugToken(ctx context.Context, inputToken string) (*TokenInfo, error)
+	}
+
+	// TokenWatchConfig configures a TokenWatcher.
+	//
+	// Interval is the time between two inspections and Threshold the remaining lifetime below
+	// which OnExpiring is called. OnExpiring is also called with a zero remaining duration
+	// when the token is reported invalid. OnError is called when the inspection fails.
+	// Clock defaults to clock.System.
+	TokenWatchConfig struct {
+		Interval   time.Duration
+		Threshold  time.Duration
+		OnExpiring func(ctx context.Context, info *TokenInfo, remaining time.Duration)
+		OnError    func(ctx context.Context, err error)
+		Clock      clock.Clock
+	}
+
+	// TokenWatcher periodically inspects the access token and reports when it is about to
+	// expire, so that an expired token does not silently stop all messaging.
+	//
+	//	watcher := whatsapp.NewTokenWatcher(client, &whatsapp.TokenWatchConfig{
+	//		OnExpiring: func(ctx context.Context, info *whatsapp.TokenInfo, remaining time.Duration) {
+	//			log.Printf("access token expires in %s", remaining)
+	//		},
+	//	})
+	//	go watcher.Run(ctx)
+	TokenWatcher struct {
+		inspector TokenInspector
+		config    TokenWatchConfig
+	}
+)
+
+// NewTokenWatcher returns a TokenWatcher that inspects the token with inspector. Zero
+// Interval and Threshold are replaced by their defaults.
+func NewTokenWatcher(inspector TokenInspector, config *TokenWatchConfig) *TokenWatcher {
+	watcher := &TokenWatcher{inspector: inspector}
+	if config != nil {
+		watcher.config = *config
+	}
+	if watcher.config.Interval <= 0 {
+		watcher.config.Interval = DefaultTokenWatchInterval
+	}
+	if watcher.config.Threshold <= 0 {
+		watcher.config.Threshold = DefaultTokenWatchThreshold
+	}
+	watcher.config.Clock = clock.OrSystem(watcher.config.Clock)
+
+	return watcher
+}
+
+// Run checks the token immediately and then every Interval until ctx is done. It returns
+// ctx.Err().
+func (watcher *TokenWatcher) Run(ctx context.Context) error {
+	ticker := watcher.config.Clock.NewTicker(watcher.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := watcher.Check(ctx); err != nil && watcher.config.OnError != nil {
+			watcher.config.OnError(ctx, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C():
+		}
+	}
+}
+
+// Check inspects the token once and calls OnExpiring if it is invalid or expires within
+// Threshold. It returns the inspected token info.
+func (watcher *TokenWatcher) Check(ctx context.Context) (*TokenInfo, error) {
+	info, err := watcher.inspector.DebugToken(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	remaining, expiring := watcher.remaining(info)
+	if expiring && watcher.config.OnExpiring != nil {
+		watcher.config.OnExpiring(ctx, info, remaining)
+	}
+
+	return info, nil
+}
+
+func (watcher *TokenWatcher) remaining(info *TokenInfo) (time.Duration, bool) {
+	if !info.IsValid {
+		return 0, true
+	}
+	expiresAt, ok := info.ExpiresAtTime()
+	if !ok {
+		return 0, false
+	}
+	remaining := expiresAt.Sub(watcher.config.Clock.Now())
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return remaining, remaining < watcher.config.Threshold
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+)
+
+type inspectorFunc func(ctx context.Context, inputToken string) (*TokenInfo, error)
+
+func (f inspectorFunc) DebugToken(ctx context.Context, inputToken string) (*TokenInfo, error) {
+	return f(ctx, inputToken)
+}
+
+func TestTokenWatcherCheck(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		info     *TokenInfo
+		wantCall bool
+	}{
+		{
+			name:     "never expires",
+			info:     &TokenInfo{IsValid: true},
+			wantCall: false,
+		},
+		{
+			name:     "expires after threshold",
+			info:     &TokenInfo{IsValid: true, ExpiresAt: time.Now().Add(30 * 24 * time.Hour).Unix()},
+			wantCall: false,
+		},
+		{
+			name:     "expires within threshold",
+			info:     &TokenInfo{IsValid: true, ExpiresAt: time.Now().Add(time.Hour).Unix()},
+			wantCall: true,
+		},
+		{
+			name:     "invalid token",
+			info:     &TokenInfo{IsValid: false},
+			wantCall: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var called bool
+			watcher := NewTokenWatcher(inspectorFunc(func(context.Context, string) (*TokenInfo, error) {
+				return tt.info, nil
+			}), &TokenWatchConfig{
+				OnExpiring: func(context.Context, *TokenInfo, time.Duration) { called = true },
+			})
+
+			if _, err := watcher.Check(context.TODO()); err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if called != tt.wantCall {
+				t.Errorf("OnExpiring called = %v, want %v", called, tt.wantCall)
+			}
+		})
+	}
+}
+
+func TestTokenWatcherRun(t *testing.T) {
+	t.Parallel()
+	checks := make(chan struct{}, 10)
+	clk := clock.NewFake(time.Now())
+	watcher := NewTokenWatcher(inspectorFunc(func(context.Context, string) (*TokenInfo, error) {
+		checks <- struct{}{}
+
+		return &TokenInfo{IsValid: true}, nil
+	}), &TokenWatchConfig{Interval: time.Hour, Clock: clk})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- watcher.Run(ctx) }()
+
+	<-checks
+	for clk.Waiters() == 0 {
+		runtime.Gosched()
+	}
+	clk.Advance(time.Hour)
+	<-checks
+	cancel()
+	if err := <-done; err != context.Canceled { //nolint:errorlint
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotVoiceNote   = errors.New("audio is not OGG/Opus and no transcoder is set, see WithTranscoder")
+	ErrTranscodeAudio = errors.New("transcode audio")
+)
+
+// oggOpusHeaderSize is the number of bytes read to detect an OGG/Opus stream: the header of
+// the first OGG page, with up to 255 segments, followed by the magic of the Opus header.
+const oggOpusHeaderSize = 27 + 255 + 8
+
+type (
+	// Transcoder converts audio to OGG/Opus, the format of the WhatsApp voice notes. Transcode
+	// reads the audio of filename from input and returns the converted audio.
+	Transcoder interface {
+		Transcode(ctx context.Context, filename string, input io.Reader) (io.Reader, error)
+	}
+
+	TranscoderFunc func(ctx context.Context, filename string, input io.Reader) (io.Reader, error)
+
+	// FFmpegTranscoder is a Transcoder that runs ffmpeg, which must be installed. Path is the
+	// path of the ffmpeg binary, it is looked up in PATH when empty. Bitrate is the bitrate of
+	// the output, e.g. "32k", the default. The audio is converted to mono at 48kHz.
+	FFmpegTranscoder struct {
+		Path    string
+		Bitrate string
+	}
+)
+
+func (fn TranscoderFunc) Transcode(ctx context.Context, filename string, input io.Reader) (io.Reader, error) {
+	return fn(ctx, filename, input)
+}
+
+func (transcoder *FFmpegTranscoder) Transcode(ctx context.Context, filename string, input io.Reader,
+) (io.Reader, error) {
+	path := transcoder.Path
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath("ffmpeg"); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrTranscodeAudio, filename, err)
+		}
+	}
+	bitrate := transcoder.Bitrate
+	if bitrate == "" {
+		bitrate = "32k"
+	}
+
+	var output, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, //nolint:gosec
+		"-hide_banner", "-loglevel", "error",
+		"-i", "pipe:0",
+		"-vn", "-ac", "1", "-ar", "48000",
+		"-c:a", "libopus", "-b:a", bitrate,
+		"-f", "ogg", "pipe:1",
+	)
+	cmd.Stdin = input
+	cmd.Stdout = &output
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w: %s", ErrTranscodeAudio, filename, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return &output, nil
+}
+
+// WithTranscoder sets the Transcoder used by UploadMedia and SendVoiceNote to convert the
+// audio that is not OGG/Opus, so that any audio can be sent as a voice note.
+func WithTranscoder(transcoder Transcoder) ClientOption {
+	return func(client *Client) {
+		client.transcoder = transcoder
+	}
+}
+
+// IsOggOpus reports whether header, the first bytes of an audio file, is the start of an
+// OGG stream carrying Opus audio.
+func IsOggOpus(header []byte) bool {
+	const (
+		magic          = "OggS"
+		opusHead       = "OpusHead"
+		segmentsOffset = 26
+	)
+	if len(header) <= segmentsOffset || string(header[:len(magic)]) != magic {
+		return false
+	}
+	// the payload of the page follows its segment table
+	payload := segmentsOffset + 1 + int(header[segmentsOffset])
+	if len(header) < payload+len(opusHead) {
+		return false
+	}
+
+	return string(header[payload:payload+len(opusHead)]) == opusHead
+}
+
+// voiceNote returns the OGG/Opus audio of filename read from input and its new filename,
+// transcoding it when needed.
+func (client *Client) voiceNote(ctx context.Context, filename string, input io.Reader,
+) (string, io.Reader, error) {
+	reader := bufio.NewReader(input)
+	header, err := reader.Peek(oggOpusHeaderSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", nil, fmt.Errorf("read audio %s: %w", filename, err)
+	}
+	if IsOggOpus(header) {
+		return filename, reader, nil
+	}
+	if client.transcoder == nil {
+		return "", nil, fmt.Errorf("%s: %w", filename, ErrNotVoiceNote)
+	}
+	output, err := client.transcoder.Transcode(ctx, filename, reader)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".ogg", output, nil
+}
+
+// SendVoiceNote uploads the audio of filename read from input and sends it to recipient as
+// a voice note. The audio is transcoded to OGG/Opus with the Transcoder of the client when
+// it is in another format, ErrNotVoiceNote is returned when no Transcoder is set.
+func (client *Client) SendVoiceNote(ctx context.Context, recipient, filename string, input io.Reader,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	filename, audio, err := client.voiceNote(ctx, filename, input)
+	if err != nil {
+		return nil, fmt.Errorf("send voice note: %w", err)
+	}
+	media, err := client.uploadMedia(ctx, MediaTypeAudio, filename, audio)
+	if err != nil {
+		return nil, fmt.Errorf("send voice note: %w", err)
+	}
+
+	return client.SendMedia(ctx, recipient, &MediaMessage{
+		Type:    MediaTypeAudio,
+		MediaID: media.ID,
+	}, nil, options...)
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// oggOpus returns the start of an OGG/Opus stream whose first page has one segment.
+func oggOpus() []byte {
+	header := append([]byte("OggS"), make([]byte, 22)...)
+	header = append(header, 1, 19)
+
+	return append(header, []byte("OpusHead\x01\x01")...)
+}
+
+func TestIsOggOpus(t *testing.T) {
+	t.Parallel()
+	vorbis := append(append([]byte("OggS"), make([]byte, 22)...), 1, 30)
+	vorbis = append(vorbis, []byte("\x01vorbis")...)
+	tests := []struct {
+		name   string
+		header []byte
+		want   bool
+	}{
+		{name: "ogg opus", header: oggOpus(), want: true},
+		{name: "ogg vorbis", header: vorbis, want: false},
+		{name: "mp3", header: []byte("ID3\x03\x00\x00\x00\x00\x00\x00"), want: false},
+		{name: "truncated", header: oggOpus()[:30], want: false},
+		{name: "empty", header: nil, want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsOggOpus(tt.header); got != tt.want {
+				t.Errorf("IsOggOpus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_SendVoiceNote(t *testing.T) {
+	t.Parallel()
+	type upload struct {
+		filename, contentType string
+		body                  []byte
+	}
+	var (
+		uploads []upload
+		sent    []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/media") {
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("FormFile() error = %v", err)
+
+				return
+			}
+			body, _ := io.ReadAll(file)
+			uploads = append(uploads, upload{header.Filename, header.Header.Get("Content-Type"), body})
+			_, _ = w.Write([]byte(`{"id":"media_1"}`))
+
+			return
+		}
+		var message struct {
+			Type  string `json:"type"`
+			Audio struct {
+				ID string `json:"id"`
+			} `json:"audio"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&message)
+		sent = append(sent, message.Type+":"+message.Audio.ID)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.OUT"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	mp3 := []byte("ID3\x03\x00\x00\x00\x00\x00\x00mp3 audio")
+
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"))
+	if _, err := client.SendVoiceNote(ctx, "+255700000001", "note.mp3", bytes.NewReader(mp3)); !errors.Is(
+		err, ErrNotVoiceNote) {
+		t.Fatalf("SendVoiceNote() error = %v, want %v", err, ErrNotVoiceNote)
+	}
+	// OGG/Opus is sent without transcoding
+	if _, err := client.SendVoiceNote(ctx, "+255700000001", "note.ogg", bytes.NewReader(oggOpus())); err != nil {
+		t.Fatalf("SendVoiceNote() error = %v", err)
+	}
+
+	var transcoded []string
+	client = NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"),
+		WithTranscoder(TranscoderFunc(func(ctx context.Context, filename string, input io.Reader) (io.Reader, error) {
+			data, err := io.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(data, mp3) {
+				t.Errorf("transcoder input = %q, want %q", data, mp3)
+			}
+			transcoded = append(transcoded, filename)
+
+			return bytes.NewReader(oggOpus()), nil
+		})))
+	if _, err := client.SendVoiceNote(ctx, "+255700000001", "note.mp3", bytes.NewReader(mp3)); err != nil {
+		t.Fatalf("SendVoiceNote() error = %v", err)
+	}
+	if _, err := client.UploadMedia(ctx, MediaTypeAudio, "memo.mp3", bytes.NewReader(mp3)); err != nil {
+		t.Fatalf("UploadMedia() error = %v", err)
+	}
+	// other media types are uploaded as is
+	if _, err := client.UploadMedia(ctx, MediaTypeImage, "photo.png", strings.NewReader("png")); err != nil {
+		t.Fatalf("UploadMedia() error = %v", err)
+	}
+
+	if got, want := strings.Join(transcoded, ","), "note.mp3,memo.mp3"; got != want {
+		t.Errorf("transcoded = %s, want %s", got, want)
+	}
+	wantUploads := []upload{
+		{"note.ogg", "audio/ogg", oggOpus()},
+		{"note.ogg", "audio/ogg", oggOpus()},
+		{"memo.ogg", "audio/ogg", oggOpus()},
+		{"photo.png", "image/png", []byte("png")},
+	}
+	if len(uploads) != len(wantUploads) {
+		t.Fatalf("uploads = %d, want %d", len(uploads), len(wantUploads))
+	}
+	for i, want := range wantUploads {
+		got := uploads[i]
+		if got.filename != want.filename || got.contentType != want.contentType || !bytes.Equal(got.body, want.body) {
+			t.Errorf("upload %d = %s (%s), want %s (%s)", i, got.filename, got.contentType, want.filename,
+				want.contentType)
+		}
+	}
+	if got, want := strings.Join(sent, ","), "audio:media_1,audio:media_1"; got != want {
+		t.Errorf("sent = %s, want %s", got, want)
+	}
+}
+
+func TestFFmpegTranscoder(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	// one second of silence as a WAV file
+	const rate = 8000
+	wav := bytes.NewBuffer(nil)
+	wav.WriteString("RIFF")
+	writeLE(wav, 36+rate*2, 4)
+	wav.WriteString("WAVEfmt ")
+	writeLE(wav, 16, 4)
+	writeLE(wav, 1, 2)
+	writeLE(wav, 1, 2)
+	writeLE(wav, rate, 4)
+	writeLE(wav, rate*2, 4)
+	writeLE(wav, 2, 2)
+	writeLE(wav, 16, 2)
+	wav.WriteString("data")
+	writeLE(wav, rate*2, 4)
+	wav.Write(make([]byte, rate*2))
+
+	output, err := (&FFmpegTranscoder{}).Transcode(context.Background(), "silence.wav", wav)
+	if err != nil {
+		t.Fatalf("Transcode() error = %v", err)
+	}
+	data, _ := io.ReadAll(output)
+	if !IsOggOpus(data) {
+		t.Errorf("Transcode() output is not OGG/Opus")
+	}
+}
+
+func writeLE(w *bytes.Buffer, v, size int) {
+	for i := 0; i < size; i++ {
+		w.WriteByte(byte(v >> (8 * i)))
+	}
+}
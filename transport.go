@@ -0,0 +1,144 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+type transportOptions struct {
+	transport http.RoundTripper
+	proxy     *url.URL
+	tlsConfig *tls.Config
+	gzip      *whttp.GzipConfig
+	hedge     *whttp.HedgeConfig
+}
+
+// WithTransport sets the http.RoundTripper used to send the requests, it replaces the transport
+// of the http client set by WithHTTPClient, the http client itself is not modified.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(client *Client) {
+		client.transport.transport = transport
+	}
+}
+
+// WithProxy sends all the requests through the proxy at proxyURL, e.g. a corporate egress proxy.
+//
+// The proxy is set on a clone of the transport in use, it only takes effect when that transport
+// is a *http.Transport, which is the case for the default transport.
+func WithProxy(proxyURL *url.URL) ClientOption {
+	return func(client *Client) {
+		client.transport.proxy = proxyURL
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used when connecting to the API, for example to
+// present a client certificate in mTLS setups or to trust a private CA used by a proxy.
+//
+// Like WithProxy, it only takes effect when the transport in use is a *http.Transport.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(client *Client) {
+		client.transport.tlsConfig = config
+	}
+}
+
+// WithGzip enables gzip encoded responses and, when config.CompressRequests is set,
+// compression of large request bodies. See whttp.GzipConfig.
+func WithGzip(config *whttp.GzipConfig) ClientOption {
+	return func(client *Client) {
+		if config == nil {
+			config = &whttp.GzipConfig{}
+		}
+		client.transport.gzip = config
+	}
+}
+
+// WithHedging hedges the idempotent requests, like fetching media or templates: when one is
+// slower than config.Delay, it is sent again and the first response wins. The hedged
+// requests go through the other transport layers only once. See whttp.HedgeTransport.
+func WithHedging(config *whttp.HedgeConfig) ClientOption {
+	return func(client *Client) {
+		if config == nil {
+			config = &whttp.HedgeConfig{}
+		}
+		client.transport.hedge = config
+	}
+}
+
+// configureHTTPClient returns the http client used by the client. The http client set by
+// WithHTTPClient is copied and its transport replaced by the configured transport, which is
+// then wrapped with hedging, gzip handling, usage tracking, the ETag cache and the circuit
+// breaker.
+func (client *Client) configureHTTPClient() *http.Client {
+	base := client.http
+	if base == nil {
+		base = http.DefaultClient
+	}
+	httpClient := *base
+
+	transport := httpClient.Transport
+	if client.transport.transport != nil {
+		transport = client.transport.transport
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if client.transport.proxy != nil || client.transport.tlsConfig != nil {
+		if t, ok := transport.(*http.Transport); ok {
+			t = t.Clone()
+			if client.transport.proxy != nil {
+				t.Proxy = http.ProxyURL(client.transport.proxy)
+			}
+			if client.transport.tlsConfig != nil {
+				t.TLSClientConfig = client.transport.tlsConfig.Clone()
+			}
+			transport = t
+		}
+	}
+
+	if client.transport.hedge != nil {
+		transport = whttp.HedgeTransport(transport, client.transport.hedge)
+	}
+
+	if client.transport.gzip != nil {
+		transport = whttp.GzipTransport(transport, client.transport.gzip)
+	}
+
+	if client.usage != nil {
+		transport = client.usage.Transport(transport)
+	}
+
+	if client.etags != nil {
+		transport = whttp.ETagTransport(transport, client.etags)
+	}
+
+	if client.breaker != nil {
+		transport = client.breaker.Transport(transport)
+	}
+
+	httpClient.Transport = transport
+
+	return &httpClient
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestClientTransport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("proxy", func(t *testing.T) {
+		t.Parallel()
+		var host string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host = r.URL.Host
+			_, _ = w.Write([]byte(`{"id":"phone_id"}`))
+		}))
+		defer proxy.Close()
+
+		proxyURL, _ := url.Parse(proxy.URL)
+		client := NewClient(WithBaseURL("http://graph.example.com"), WithProxy(proxyURL),
+			WithPhoneNumberID("phone_id"))
+		if _, err := client.PhoneNumberByID(context.TODO()); err != nil {
+			t.Fatalf("PhoneNumberByID() error = %v", err)
+		}
+		if host != "graph.example.com" {
+			t.Errorf("proxy received request for %q, want %q", host, "graph.example.com")
+		}
+	})
+
+	t.Run("custom transport", func(t *testing.T) {
+		t.Parallel()
+		var called bool
+		transport := roundTripFunc(func(request *http.Request) (*http.Response, error) {
+			called = true
+			recorder := httptest.NewRecorder()
+			_, _ = recorder.WriteString(`{"id":"phone_id"}`)
+
+			return recorder.Result(), nil
+		})
+
+		httpClient := &http.Client{}
+		client := NewClient(WithHTTPClient(httpClient), WithTransport(transport))
+		if _, err := client.PhoneNumberByID(context.TODO()); err != nil {
+			t.Fatalf("PhoneNumberByID() error = %v", err)
+		}
+		if !called {
+			t.Error("custom transport was not used")
+		}
+		if httpClient.Transport != nil {
+			t.Error("http client passed by the caller was modified")
+		}
+	})
+}
@@ -0,0 +1,303 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	whttp "github.com/SeamPay/whatsapp/http"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// DefaultUndeliverableWindow is how long a recipient stays undeliverable when
+// UndeliverableConfig.Window is zero.
+const DefaultUndeliverableWindow = 24 * time.Hour
+
+// ErrUndeliverable is returned when a message is sent to a recipient that recently could not
+// be reached, see UndeliverableRegistry.
+var ErrUndeliverable = errors.New("recipient recently undeliverable")
+
+// DefaultUndeliverableCodes are the error codes that mark a recipient undeliverable when
+// UndeliverableConfig.Codes is empty.
+var DefaultUndeliverableCodes = []int{ //nolint:gochecknoglobals
+	werrors.CodeUndeliverable,
+	werrors.CodeEcosystemEngagement,
+}
+
+type (
+	// Undeliverable is a recipient that could not be reached. Code is the error code of the
+	// last failure, At its time and Failures the number of failures recorded in a row.
+	Undeliverable struct {
+		WaID     string
+		Code     int
+		At       time.Time
+		Failures int
+	}
+
+	// UndeliverableStore stores the undeliverable recipients. Implementations must be safe
+	// for concurrent use.
+	UndeliverableStore interface {
+		// Put stores the recipient, replacing the previous record of the same wa_id.
+		Put(ctx context.Context, recipient *Undeliverable) error
+
+		// Get returns the record of waID, false when there is none.
+		Get(ctx context.Context, waID string) (*Undeliverable, bool, error)
+
+		// Delete deletes the record of waID, if any.
+		Delete(ctx context.Context, waID string) error
+	}
+
+	// UndeliverableFunc is called when a message is sent to a recipient that is
+	// undeliverable, blocked tells whether the send was stopped.
+	UndeliverableFunc func(ctx context.Context, recipient *Undeliverable, blocked bool)
+
+	// UndeliverableConfig configures an UndeliverableRegistry.
+	//
+	// Store defaults to a MemoryUndeliverableStore and Clock to the system clock. Codes are
+	// the error codes of the permanent failures, DefaultUndeliverableCodes by default. A
+	// recipient stays undeliverable for Window after its last failure, it defaults to
+	// DefaultUndeliverableWindow. When WarnOnly is set the sends to undeliverable
+	// recipients are not stopped, OnRepeat is still called for them.
+	UndeliverableConfig struct {
+		Store    UndeliverableStore
+		Clock    clock.Clock
+		Codes    []int
+		Window   time.Duration
+		WarnOnly bool
+		OnRepeat UndeliverableFunc
+	}
+
+	// UndeliverableRegistry remembers the recipients that recently produced permanent
+	// failures, reported by the status webhooks or the API responses, and stops the messages
+	// sent to them again within the window, they fail with ErrUndeliverable without reaching
+	// the API. A message received from a recipient makes it deliverable again.
+	//
+	//	registry := whatsapp.NewUndeliverableRegistry(nil)
+	//	client := whatsapp.NewClient(whatsapp.WithUndeliverableRegistry(registry), ...)
+	//	listener.OnMessageStatusChange(registry.OnMessageStatusChange)
+	//	listener.OnMessageReceived(registry.OnMessageReceived)
+	UndeliverableRegistry struct {
+		store    UndeliverableStore
+		clock    clock.Clock
+		codes    map[int]bool
+		window   time.Duration
+		warnOnly bool
+		onRepeat UndeliverableFunc
+	}
+
+	// MemoryUndeliverableStore is an in-memory UndeliverableStore.
+	MemoryUndeliverableStore struct {
+		mu         sync.Mutex
+		recipients map[string]*Undeliverable
+	}
+)
+
+// WithUndeliverableRegistry checks the recipients of the messages against the registry, see
+// UndeliverableRegistry.
+func WithUndeliverableRegistry(registry *UndeliverableRegistry) ClientOption {
+	return func(client *Client) {
+		client.undeliverable = registry
+	}
+}
+
+// NewUndeliverableRegistry returns an UndeliverableRegistry configured with config, which
+// may be nil.
+func NewUndeliverableRegistry(config *UndeliverableConfig) *UndeliverableRegistry {
+	if config == nil {
+		config = &UndeliverableConfig{}
+	}
+	codes := config.Codes
+	if len(codes) == 0 {
+		codes = DefaultUndeliverableCodes
+	}
+	registry := &UndeliverableRegistry{
+		store:    config.Store,
+		clock:    clock.OrSystem(config.Clock),
+		codes:    make(map[int]bool, len(codes)),
+		window:   config.Window,
+		warnOnly: config.WarnOnly,
+		onRepeat: config.OnRepeat,
+	}
+	for _, code := range codes {
+		registry.codes[code] = true
+	}
+	if registry.store == nil {
+		registry.store = NewMemoryUndeliverableStore()
+	}
+	if registry.window <= 0 {
+		registry.window = DefaultUndeliverableWindow
+	}
+
+	return registry
+}
+
+// Record records that a message to waID failed with the error code. Codes that are not
+// permanent failures are ignored.
+func (registry *UndeliverableRegistry) Record(ctx context.Context, waID string, code int) error {
+	if !registry.codes[code] || waID == "" {
+		return nil
+	}
+	waID = strings.TrimPrefix(waID, "+")
+	recipient := &Undeliverable{WaID: waID, Code: code, At: registry.clock.Now(), Failures: 1}
+	previous, found, err := registry.Lookup(ctx, waID)
+	if err != nil {
+		return err
+	}
+	if found {
+		recipient.Failures = previous.Failures + 1
+	}
+	if err = registry.store.Put(ctx, recipient); err != nil {
+		return fmt.Errorf("undeliverable registry: %w", err)
+	}
+
+	return nil
+}
+
+// Lookup returns the record of waID when it failed within the window.
+func (registry *UndeliverableRegistry) Lookup(ctx context.Context, waID string) (*Undeliverable, bool, error) {
+	recipient, found, err := registry.store.Get(ctx, strings.TrimPrefix(waID, "+"))
+	if err != nil {
+		return nil, false, fmt.Errorf("undeliverable registry: %w", err)
+	}
+	if !found || registry.clock.Now().Sub(recipient.At) >= registry.window {
+		return nil, false, nil
+	}
+
+	return recipient, true, nil
+}
+
+// Clear makes waID deliverable again.
+func (registry *UndeliverableRegistry) Clear(ctx context.Context, waID string) error {
+	if err := registry.store.Delete(ctx, strings.TrimPrefix(waID, "+")); err != nil {
+		return fmt.Errorf("undeliverable registry: %w", err)
+	}
+
+	return nil
+}
+
+// OnMessageStatusChange is a webhooks.OnMessageStatusChangeHook that records the recipients
+// of the messages that failed with a permanent failure.
+func (registry *UndeliverableRegistry) OnMessageStatusChange(ctx context.Context,
+	_ *webhooks.NotificationContext, status *webhooks.Status,
+) error {
+	if status == nil || status.StatusValue != "failed" || status.IsPayment() {
+		return nil
+	}
+	for _, err := range status.Errors {
+		if err != nil && registry.codes[err.Code] {
+			return registry.Record(ctx, status.RecipientID, err.Code)
+		}
+	}
+
+	return nil
+}
+
+// OnMessageReceived is a webhooks.OnMessageReceivedHook that clears the recipients that
+// sent a message, they are evidently reachable.
+func (registry *UndeliverableRegistry) OnMessageReceived(ctx context.Context, _ *webhooks.NotificationContext,
+	message *webhooks.Message,
+) error {
+	if message == nil || message.From == "" {
+		return nil
+	}
+
+	return registry.Clear(ctx, message.From)
+}
+
+// Middleware returns the middleware that stops the messages sent to the undeliverable
+// recipients and records the permanent failures returned by the API, it is installed by
+// WithUndeliverableRegistry.
+func (registry *UndeliverableRegistry) Middleware() whttp.Middleware {
+	return func(next whttp.Sender) whttp.Sender {
+		return whttp.SenderFunc(func(ctx context.Context, request *whttp.Request, v any) error {
+			if !isMessageRequest(request) {
+				return next.Send(ctx, request, v)
+			}
+			payload, ok := auditPayloadOf(request)
+			if !ok || payload.To == "" {
+				return next.Send(ctx, request, v)
+			}
+
+			recipient, found, err := registry.Lookup(ctx, payload.To)
+			if err != nil {
+				return err
+			}
+			if found {
+				if registry.onRepeat != nil {
+					registry.onRepeat(ctx, recipient, !registry.warnOnly)
+				}
+				if !registry.warnOnly {
+					return fmt.Errorf("%w: %s failed with code %d at %s", ErrUndeliverable, payload.To,
+						recipient.Code, recipient.At.Format(time.RFC3339))
+				}
+			}
+
+			err = next.Send(ctx, request, v)
+			if code, ok := werrors.Code(err); ok {
+				if rerr := registry.Record(ctx, payload.To, code); rerr != nil {
+					return errors.Join(err, rerr)
+				}
+			}
+
+			return err
+		})
+	}
+}
+
+// NewMemoryUndeliverableStore returns an empty MemoryUndeliverableStore.
+func NewMemoryUndeliverableStore() *MemoryUndeliverableStore {
+	return &MemoryUndeliverableStore{recipients: make(map[string]*Undeliverable)}
+}
+
+func (store *MemoryUndeliverableStore) Put(_ context.Context, recipient *Undeliverable) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	stored := *recipient
+	store.recipients[recipient.WaID] = &stored
+
+	return nil
+}
+
+func (store *MemoryUndeliverableStore) Get(_ context.Context, waID string) (*Undeliverable, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	recipient, ok := store.recipients[waID]
+	if !ok {
+		return nil, false, nil
+	}
+	stored := *recipient
+
+	return &stored, true, nil
+}
+
+func (store *MemoryUndeliverableStore) Delete(_ context.Context, waID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.recipients, waID)
+
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestUndeliverableRegistry(t *testing.T) {
+	t.Parallel()
+	var sends int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&sends, 1)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	clk := clock.NewFake(time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC))
+	var repeats []bool
+	registry := NewUndeliverableRegistry(&UndeliverableConfig{
+		Clock:  clk,
+		Window: time.Hour,
+		OnRepeat: func(ctx context.Context, recipient *Undeliverable, blocked bool) {
+			repeats = append(repeats, blocked)
+		},
+	})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithUndeliverableRegistry(registry))
+
+	failed := func(recipient string, code int) {
+		t.Helper()
+		err := registry.OnMessageStatusChange(ctx, nil, &webhooks.Status{
+			ID: "wamid.1", RecipientID: recipient, StatusValue: "failed", Errors: []*werrors.Error{{Code: code}},
+		})
+		if err != nil {
+			t.Fatalf("OnMessageStatusChange() error = %v", err)
+		}
+	}
+	failed("255700000001", werrors.CodeUndeliverable)
+	failed("255700000002", werrors.CodeReEngagement)
+
+	if _, err := client.SendText(ctx, "+255700000001", "hi"); !errors.Is(err, ErrUndeliverable) {
+		t.Fatalf("SendText() to an undeliverable recipient error = %v, want %v", err, ErrUndeliverable)
+	}
+	if _, err := client.SendText(ctx, "255700000002", "hi"); err != nil {
+		t.Fatalf("SendText() after a temporary failure error = %v", err)
+	}
+	if len(repeats) != 1 || !repeats[0] || atomic.LoadInt32(&sends) != 1 {
+		t.Errorf("repeats = %v after %d sends, want one blocked send", repeats, sends)
+	}
+
+	failed("255700000001", werrors.CodeUndeliverable)
+	if recipient, found, _ := registry.Lookup(ctx, "255700000001"); !found || recipient.Failures != 2 {
+		t.Errorf("Lookup() = %+v, %v, want two failures", recipient, found)
+	}
+
+	// the recipient is deliverable again after the window
+	clk.Advance(time.Hour)
+	if _, err := client.SendText(ctx, "255700000001", "hi"); err != nil {
+		t.Fatalf("SendText() after the window error = %v", err)
+	}
+
+	// or once it sent a message
+	failed("255700000001", werrors.CodeEcosystemEngagement)
+	if err := registry.OnMessageReceived(ctx, nil, &webhooks.Message{From: "255700000001"}); err != nil {
+		t.Fatalf("OnMessageReceived() error = %v", err)
+	}
+	if _, found, _ := registry.Lookup(ctx, "255700000001"); found {
+		t.Error("Lookup() after a received message found the recipient")
+	}
+}
+
+func TestUndeliverableRegistryWarnOnly(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"Message undeliverable","code":131026}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	var repeats []bool
+	registry := NewUndeliverableRegistry(&UndeliverableConfig{
+		WarnOnly: true,
+		OnRepeat: func(ctx context.Context, recipient *Undeliverable, blocked bool) {
+			repeats = append(repeats, blocked)
+		},
+	})
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithUndeliverableRegistry(registry))
+
+	// the failures returned by the API are recorded too
+	for i := 0; i < 2; i++ {
+		_, err := client.SendText(context.TODO(), "255700000001", "hi")
+		if code, _ := werrors.Code(err); code != werrors.CodeUndeliverable {
+			t.Fatalf("SendText() error = %v, want code %d", err, werrors.CodeUndeliverable)
+		}
+	}
+	if len(repeats) != 1 || repeats[0] {
+		t.Errorf("repeats = %v, want one send that was not blocked", repeats)
+	}
+}
@@ -0,0 +1,337 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package vcr records the interactions with the Graph API to cassette files and replays
+// them, so that integration tests can run in CI without live credentials.
+//
+// Record the cassette once against the real API:
+//
+//	recorder, err := vcr.New("testdata/send_text.json", &vcr.Options{Mode: vcr.ModeRecord})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer recorder.Stop() // writes the cassette
+//	client := whatsapp.NewClient(whatsapp.WithTransport(recorder), ...)
+//
+// Later runs use the default ModeReplay and never reach the network, a request that is not
+// in the cassette fails with ErrInteractionNotFound. Credentials are scrubbed before the
+// cassette is written: the Authorization header, the access_token, appsecret_proof,
+// input_token and client_secret query parameters, every occurrence of the bearer token and
+// of Options.Secrets anywhere in the requests and responses.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// Modes of a Recorder.
+const (
+	// ModeReplay answers the requests from the cassette, it is the default.
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests to the API and records them, replacing the cassette.
+	ModeRecord
+	// ModeAuto replays the cassette when it exists and records it otherwise.
+	ModeAuto
+)
+
+var (
+	// ErrInteractionNotFound is returned in ModeReplay for requests that are not in the cassette.
+	ErrInteractionNotFound = errors.New("vcr: interaction not found in cassette")
+	// ErrCassetteNotFound is returned by New in ModeReplay when the cassette file does not exist.
+	ErrCassetteNotFound = errors.New("vcr: cassette not found")
+)
+
+type (
+	// Mode selects whether a Recorder records or replays.
+	Mode int
+
+	// Options configures a Recorder.
+	//
+	// Transport sends the requests in ModeRecord, http.DefaultTransport by default. Secrets
+	// are scrubbed from the cassette in addition to the credentials found in the requests,
+	// add the app secret and any other value that must not be committed. Redactor replaces
+	// the default whttp.DefaultRedactor.
+	//
+	// By default a request matches an interaction with the same method, URL and body, the
+	// interactions being consumed in order. IgnoreBody matches on the method and URL only.
+	Options struct {
+		Mode       Mode
+		Transport  http.RoundTripper
+		Secrets    []string
+		Redactor   *whttp.Redactor
+		IgnoreBody bool
+	}
+
+	// Cassette is the content of a cassette file.
+	Cassette struct {
+		Interactions []*Interaction `json:"interactions"`
+	}
+
+	// Interaction is a recorded request and its response.
+	Interaction struct {
+		Request  *Request  `json:"request"`
+		Response *Response `json:"response"`
+	}
+
+	// Request is a recorded request, with credentials scrubbed.
+	Request struct {
+		Method string      `json:"method"`
+		URL    string      `json:"url"`
+		Header http.Header `json:"header,omitempty"`
+		Body   string      `json:"body,omitempty"`
+	}
+
+	// Response is a recorded response, with credentials scrubbed.
+	Response struct {
+		StatusCode int         `json:"status_code"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       string      `json:"body,omitempty"`
+	}
+
+	// Recorder is a http.RoundTripper that records or replays a cassette. It is safe for
+	// concurrent use.
+	Recorder struct {
+		path       string
+		mode       Mode
+		transport  http.RoundTripper
+		redactor   *whttp.Redactor
+		ignoreBody bool
+
+		mu       sync.Mutex
+		cassette *Cassette
+		used     []bool
+	}
+)
+
+// New creates a Recorder for the cassette at path, options may be nil.
+func New(path string, options *Options) (*Recorder, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	recorder := &Recorder{
+		path:       path,
+		mode:       options.Mode,
+		transport:  options.Transport,
+		redactor:   options.Redactor,
+		ignoreBody: options.IgnoreBody,
+		cassette:   &Cassette{},
+	}
+	if recorder.transport == nil {
+		recorder.transport = http.DefaultTransport
+	}
+	if recorder.redactor == nil {
+		recorder.redactor = whttp.DefaultRedactor()
+	}
+	redactor := *recorder.redactor
+	redactor.Secrets = append(append([]string(nil), redactor.Secrets...), options.Secrets...)
+	recorder.redactor = &redactor
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && recorder.mode == ModeReplay:
+		return nil, fmt.Errorf("%w: %s", ErrCassetteNotFound, path)
+	case errors.Is(err, os.ErrNotExist) && recorder.mode == ModeAuto:
+		recorder.mode = ModeRecord
+	case err != nil && recorder.mode != ModeRecord:
+		return nil, fmt.Errorf("vcr: read cassette: %w", err)
+	case err == nil && recorder.mode != ModeRecord:
+		recorder.mode = ModeReplay
+		if err = json.Unmarshal(data, recorder.cassette); err != nil {
+			return nil, fmt.Errorf("vcr: decode cassette %s: %w", path, err)
+		}
+		recorder.used = make([]bool, len(recorder.cassette.Interactions))
+	}
+
+	return recorder, nil
+}
+
+// Mode returns the mode the recorder runs in, ModeAuto resolved to ModeReplay or ModeRecord.
+func (recorder *Recorder) Mode() Mode {
+	return recorder.mode
+}
+
+// Cassette returns the interactions recorded or loaded so far.
+func (recorder *Recorder) Cassette() *Cassette {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	return &Cassette{Interactions: append([]*Interaction(nil), recorder.cassette.Interactions...)}
+}
+
+// Stop writes the cassette in ModeRecord, it does nothing in ModeReplay.
+func (recorder *Recorder) Stop() error {
+	if recorder.mode != ModeRecord {
+		return nil
+	}
+	recorder.mu.Lock()
+	data, err := json.MarshalIndent(recorder.cassette, "", "  ")
+	recorder.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("vcr: encode cassette: %w", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(recorder.path), 0o755); err != nil { //nolint:gomnd,gofumpt
+		return fmt.Errorf("vcr: %w", err)
+	}
+	if err = os.WriteFile(recorder.path, append(data, '\n'), 0o644); err != nil { //nolint:gosec,gomnd
+		return fmt.Errorf("vcr: write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, redactor, err := recorder.record(req)
+	if err != nil {
+		return nil, err
+	}
+	if recorder.mode == ModeReplay {
+		return recorder.replay(req, recorded)
+	}
+
+	resp, err := recorder.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // returned as is, like the transport would
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("vcr: read response: %w", err)
+	}
+
+	header := resp.Header.Clone()
+	for _, name := range redactor.Headers {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; ok {
+			header.Set(name, mask(redactor))
+		}
+	}
+	recorder.mu.Lock()
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, &Interaction{
+		Request: recorded,
+		Response: &Response{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       scrub(string(body), redactor),
+		},
+	})
+	recorder.mu.Unlock()
+
+	return resp, nil
+}
+
+// record returns the scrubbed form of req and the redactor that scrubbed it, which also
+// masks the credentials found in req.
+func (recorder *Recorder) record(req *http.Request) (*Request, *whttp.Redactor, error) {
+	redactor := *recorder.redactor
+	redactor.Secrets = append(append([]string(nil), redactor.Secrets...), credentials(req, &redactor)...)
+
+	clone, err := redactor.RedactRequest(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("vcr: %w", err)
+	}
+	var body []byte
+	if clone.Body != nil {
+		if body, err = io.ReadAll(clone.Body); err != nil {
+			return nil, nil, fmt.Errorf("vcr: read request: %w", err)
+		}
+	}
+
+	return &Request{
+		Method: clone.Method,
+		URL:    clone.URL.String(),
+		Header: clone.Header,
+		Body:   string(body),
+	}, &redactor, nil
+}
+
+// credentials returns the bearer token and the query parameter credentials of req.
+func credentials(req *http.Request, redactor *whttp.Redactor) []string {
+	var secrets []string
+	if token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "); token != "" {
+		secrets = append(secrets, token)
+	}
+	query := req.URL.Query()
+	for _, key := range redactor.QueryParams {
+		secrets = append(secrets, query[key]...)
+	}
+
+	return secrets
+}
+
+func (recorder *Recorder) replay(req *http.Request, recorded *Request) (*http.Response, error) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for i, interaction := range recorder.cassette.Interactions {
+		if recorder.used[i] || !recorder.matches(interaction.Request, recorded) {
+			continue
+		}
+		recorder.used[i] = true
+		resp := interaction.Response
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			StatusCode:    resp.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        resp.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(resp.Body)),
+			ContentLength: int64(len(resp.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, recorded.Method, recorded.URL)
+}
+
+func (recorder *Recorder) matches(interaction, req *Request) bool {
+	return interaction.Method == req.Method && interaction.URL == req.URL &&
+		(recorder.ignoreBody || interaction.Body == req.Body)
+}
+
+// scrub replaces the secrets of redactor in s with its mask.
+func scrub(s string, redactor *whttp.Redactor) string {
+	for _, secret := range redactor.Secrets {
+		if len(secret) >= 4 && secret != mask(redactor) { //nolint:gomnd // same threshold as whttp.Redactor
+			s = strings.ReplaceAll(s, secret, mask(redactor))
+		}
+	}
+
+	return s
+}
+
+func mask(redactor *whttp.Redactor) string {
+	if redactor.Mask == "" {
+		return whttp.RedactedMask
+	}
+
+	return redactor.Mask
+}
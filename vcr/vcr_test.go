@@ -0,0 +1,102 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package vcr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a response leaking the token must not end up in the cassette
+		_, _ = w.Write([]byte(`{"messages":[{"id":"wamid.1"}],"debug":"` + r.Header.Get("Authorization") + `"}`))
+	}))
+	path := filepath.Join(t.TempDir(), "cassettes", "send_text.json")
+
+	send := func(recorder *Recorder, token, text string) error {
+		client := whatsapp.NewClient(
+			whatsapp.WithTransport(recorder),
+			whatsapp.WithBaseURL(server.URL),
+			whatsapp.WithPhoneNumberID("phone_1"),
+			whatsapp.WithAccessToken(token),
+			whatsapp.WithAppSecret("app-secret"),
+		)
+		resp, err := client.SendText(context.TODO(), "255700000000", text)
+		if err == nil && resp.Messages[0].ID != "wamid.1" {
+			t.Errorf("SendText() = %+v", resp)
+		}
+
+		return err
+	}
+
+	recorder, err := New(path, &Options{Mode: ModeAuto, Secrets: []string{"app-secret"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if recorder.Mode() != ModeRecord {
+		t.Fatalf("Mode() = %v, want ModeRecord", recorder.Mode())
+	}
+	if err = send(recorder, "live-token", "hello"); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if err = recorder.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"live-token", "app-secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+
+	recorder, err = New(path, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err = send(recorder, "fake-token", "hello"); err != nil {
+		t.Errorf("replay send() error = %v", err)
+	}
+	if err = send(recorder, "fake-token", "hello"); !errors.Is(err, ErrInteractionNotFound) {
+		t.Errorf("replay send() error = %v, want %v", err, ErrInteractionNotFound)
+	}
+}
+
+func TestNewMissingCassette(t *testing.T) {
+	t.Parallel()
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), nil)
+	if !errors.Is(err, ErrCassetteNotFound) {
+		t.Errorf("New() error = %v, want %v", err, ErrCassetteNotFound)
+	}
+}
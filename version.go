@@ -0,0 +1,120 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	whttp "github.com/SeamPay/whatsapp/http"
+)
+
+// DefaultAPIVersion is the Graph API version used by clients created without WithVersion.
+// It is kept on a version that VersionDeprecations does not mark deprecated.
+const DefaultAPIVersion = whttp.DefaultAPIVersion
+
+var (
+	ErrInvalidAPIVersion    = errors.New("invalid graph api version")
+	ErrDeprecatedAPIVersion = errors.New("graph api version is deprecated")
+)
+
+// VersionDeprecations maps Graph API versions to the date after which Meta stops serving
+// them. It is a variable so that applications can add versions released after this package
+// or correct a date once Meta publishes it.
+//
+// The versions still served at the time of writing have no published date yet. Meta serves
+// a version for about two years after the release of the next one, their dates are set to
+// the start of that month so that the warning comes early rather than late.
+//
+// See https://developers.facebook.com/docs/graph-api/changelog
+var VersionDeprecations = map[string]time.Time{ //nolint:gochecknoglobals
+	"v16.0": time.Date(2025, time.May, 14, 0, 0, 0, 0, time.UTC),
+	"v17.0": time.Date(2025, time.September, 12, 0, 0, 0, 0, time.UTC),
+	"v18.0": time.Date(2026, time.January, 26, 0, 0, 0, 0, time.UTC),
+	"v19.0": time.Date(2026, time.May, 21, 0, 0, 0, 0, time.UTC),
+	"v20.0": time.Date(2026, time.September, 24, 0, 0, 0, 0, time.UTC),
+	"v21.0": time.Date(2027, time.February, 1, 0, 0, 0, 0, time.UTC),
+	"v22.0": time.Date(2027, time.May, 1, 0, 0, 0, 0, time.UTC),
+	"v23.0": time.Date(2027, time.October, 1, 0, 0, 0, 0, time.UTC),
+}
+
+var versionPattern = regexp.MustCompile(`^v(\d+)\.(\d+)$`)
+
+// VersionWarningFunc is called by NewClient when the configured API version is invalid
+// or past its deprecation date. See WithVersionWarning.
+type VersionWarningFunc func(version string, err error)
+
+// ParseVersion parses a version string like "v16.0" into its major and minor numbers.
+func ParseVersion(version string) (int, int, error) {
+	matches := versionPattern.FindStringSubmatch(version)
+	if matches == nil {
+		return 0, 0, fmt.Errorf("%w: %q, expected a version like %q", ErrInvalidAPIVersion,
+			version, DefaultAPIVersion)
+	}
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+
+	return major, minor, nil
+}
+
+// ValidateVersion returns an error if version is not a valid Graph API version string
+// or is older than LowestSupportedVersion.
+func ValidateVersion(version string) error {
+	major, minor, err := ParseVersion(version)
+	if err != nil {
+		return err
+	}
+	lowestMajor, lowestMinor, _ := ParseVersion(LowestSupportedVersion)
+	if major < lowestMajor || (major == lowestMajor && minor < lowestMinor) {
+		return fmt.Errorf("%w: %s is older than the lowest supported version %s",
+			ErrInvalidAPIVersion, version, LowestSupportedVersion)
+	}
+
+	return nil
+}
+
+// CheckVersion validates version and returns an error wrapping ErrDeprecatedAPIVersion if
+// it is past its deprecation date at now according to VersionDeprecations.
+func CheckVersion(version string, now time.Time) error {
+	if err := ValidateVersion(version); err != nil {
+		return err
+	}
+	if deprecatedAt, ok := VersionDeprecations[version]; ok && !now.Before(deprecatedAt) {
+		return fmt.Errorf("%w: %s is not available since %s", ErrDeprecatedAPIVersion,
+			version, deprecatedAt.Format(time.DateOnly))
+	}
+
+	return nil
+}
+
+// WithVersionWarning enables a check of the API version when the client is created, warn
+// is called if the version is invalid or deprecated. The client is created either way.
+//
+//	whatsapp.WithVersionWarning(func(version string, err error) {
+//		log.Printf("whatsapp: %v", err)
+//	})
+func WithVersionWarning(warn VersionWarningFunc) ClientOption {
+	return func(client *Client) {
+		client.versionWarning = warn
+	}
+}
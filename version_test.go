@@ -0,0 +1,73 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckVersion(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		version string
+		wantErr error
+	}{
+		{name: "supported", version: "v18.0", wantErr: nil},
+		{name: "unknown future version", version: "v99.0", wantErr: nil},
+		{name: "missing prefix", version: "16.0", wantErr: ErrInvalidAPIVersion},
+		{name: "missing minor", version: "v16", wantErr: ErrInvalidAPIVersion},
+		{name: "older than lowest supported", version: "v15.0", wantErr: ErrInvalidAPIVersion},
+		{name: "deprecated", version: "v16.0", wantErr: ErrDeprecatedAPIVersion},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := CheckVersion(tt.version, now)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("CheckVersion(%q) error = %v, want %v", tt.version, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithVersionWarning(t *testing.T) {
+	t.Parallel()
+	var warned string
+	NewClient(WithVersion("16"), WithVersionWarning(func(version string, err error) {
+		warned = version
+	}))
+	if warned != "16" {
+		t.Errorf("version warning called with %q, want %q", warned, "16")
+	}
+}
+
+func TestDefaultAPIVersionSupported(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+	if err := CheckVersion(DefaultAPIVersion, now); err != nil {
+		t.Errorf("CheckVersion(DefaultAPIVersion) error = %v, want a supported version", err)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var ErrInvalidAllowlistEntry = errors.New("invalid ip allowlist entry")
+
+type (
+	// IPAllowlistConfig configures an IPAllowlist. Allowed and TrustedProxies are IP addresses
+	// or CIDR ranges, e.g. "157.240.0.0/16".
+	//
+	// When the webhooks are served behind a load balancer or a reverse proxy, list the proxies
+	// in TrustedProxies: for requests coming from them the client IP is taken from the
+	// X-Forwarded-For header, the address closest to the server that is not a trusted proxy.
+	// The header is ignored for requests from any other address, as it can be forged.
+	//
+	// OnReject, when set, is called with each rejected request and the client IP it was
+	// rejected for, the IP is invalid when it could not be determined.
+	IPAllowlistConfig struct {
+		Allowed        []string
+		TrustedProxies []string
+		OnReject       func(request *http.Request, ip netip.Addr)
+	}
+
+	// IPAllowlist rejects the webhook notifications sent from IP addresses outside a list of
+	// allowed ranges, as a defense in depth alongside the signature validation. Create it with
+	// NewIPAllowlist and wrap the notification handler with Middleware.
+	IPAllowlist struct {
+		allowed  []netip.Prefix
+		proxies  []netip.Prefix
+		onReject func(request *http.Request, ip netip.Addr)
+	}
+)
+
+// NewIPAllowlist returns an IPAllowlist, it fails with ErrInvalidAllowlistEntry if one of the
+// addresses or ranges of config can not be parsed.
+func NewIPAllowlist(config *IPAllowlistConfig) (*IPAllowlist, error) {
+	if config == nil {
+		config = &IPAllowlistConfig{}
+	}
+	allowed, err := parsePrefixes(config.Allowed)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := parsePrefixes(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IPAllowlist{
+		allowed:  allowed,
+		proxies:  proxies,
+		onReject: config.OnReject,
+	}, nil
+}
+
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q: %w", ErrInvalidAllowlistEntry, entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidAllowlistEntry, entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+func contains(prefixes []netip.Prefix, ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allows reports whether ip is in one of the allowed ranges.
+func (list *IPAllowlist) Allows(ip netip.Addr) bool {
+	return ip.IsValid() && contains(list.allowed, ip)
+}
+
+// ClientIP returns the IP address of the client that sent the request. The X-Forwarded-For
+// header is only used when the request comes from a trusted proxy, ok is false when the
+// address can not be determined.
+func (list *IPAllowlist) ClientIP(request *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	ip = ip.Unmap()
+	if !contains(list.proxies, ip) {
+		return ip, true
+	}
+
+	// walk the forwarded addresses from the closest to the farthest, the first one that is
+	// not a trusted proxy is the client
+	var forwarded []string
+	for _, header := range request.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		ip = hop.Unmap()
+		if !contains(list.proxies, ip) {
+			return ip, true
+		}
+	}
+
+	return ip, true
+}
+
+// Middleware returns a http.Handler that responds with http.StatusForbidden to the POST
+// requests, i.e. the notifications, whose client IP is not allowed and passes the others
+// to next. The subscription verification requests are not filtered, they are protected by
+// the verify token.
+func (list *IPAllowlist) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			next.ServeHTTP(writer, request)
+
+			return
+		}
+		ip, ok := list.ClientIP(request)
+		if !ok || !list.Allows(ip) {
+			if list.onReject != nil {
+				list.onReject(request, ip)
+			}
+			writer.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
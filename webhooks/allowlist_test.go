@@ -0,0 +1,131 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestNewIPAllowlist(t *testing.T) {
+	t.Parallel()
+
+	configs := []*IPAllowlistConfig{
+		{Allowed: []string{"10.0.0.0/33"}},
+		{TrustedProxies: []string{"proxy"}},
+	}
+	for _, config := range configs {
+		if _, err := NewIPAllowlist(config); !errors.Is(err, ErrInvalidAllowlistEntry) {
+			t.Errorf("NewIPAllowlist(%+v) error = %v, want %v", config, err, ErrInvalidAllowlistEntry)
+		}
+	}
+}
+
+func TestIPAllowlist_Middleware(t *testing.T) {
+	t.Parallel()
+
+	var rejected []netip.Addr
+	list, err := NewIPAllowlist(&IPAllowlistConfig{
+		Allowed:        []string{"157.240.0.0/16", "2a03:2880::/32", "192.0.2.7"},
+		TrustedProxies: []string{"10.0.0.0/8"},
+		OnReject: func(request *http.Request, ip netip.Addr) {
+			rejected = append(rejected, ip)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewIPAllowlist() error = %v", err)
+	}
+	handler := list.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		remoteAddr string
+		forwarded  []string
+		want       int
+	}{
+		{name: "allowed range", remoteAddr: "157.240.1.2:443", want: http.StatusOK},
+		{name: "allowed ipv6 range", remoteAddr: "[2a03:2880:f0ff::1]:443", want: http.StatusOK},
+		{name: "allowed address", remoteAddr: "192.0.2.7:1234", want: http.StatusOK},
+		{name: "ipv4 mapped ipv6", remoteAddr: "[::ffff:157.240.1.2]:443", want: http.StatusOK},
+		{name: "outside the allowlist", remoteAddr: "203.0.113.9:443", want: http.StatusForbidden},
+		{
+			name:       "forwarded by a trusted proxy",
+			remoteAddr: "10.1.2.3:80",
+			forwarded:  []string{"157.240.1.2"},
+			want:       http.StatusOK,
+		},
+		{
+			name:       "forwarded through several trusted proxies",
+			remoteAddr: "10.1.2.3:80",
+			forwarded:  []string{"157.240.1.2, 10.9.9.9", "10.8.8.8"},
+			want:       http.StatusOK,
+		},
+		{
+			name:       "spoofed header before the trusted proxy",
+			remoteAddr: "10.1.2.3:80",
+			forwarded:  []string{"157.240.1.2, 203.0.113.9"},
+			want:       http.StatusForbidden,
+		},
+		{
+			name:       "header from an untrusted client",
+			remoteAddr: "203.0.113.9:443",
+			forwarded:  []string{"157.240.1.2"},
+			want:       http.StatusForbidden,
+		},
+		{
+			name:       "malformed header",
+			remoteAddr: "10.1.2.3:80",
+			forwarded:  []string{"unknown"},
+			want:       http.StatusForbidden,
+		},
+		{
+			name:       "verification request",
+			method:     http.MethodGet,
+			remoteAddr: "203.0.113.9:443",
+			want:       http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		method := tt.method
+		if method == "" {
+			method = http.MethodPost
+		}
+		request := httptest.NewRequest(method, "/webhooks", nil)
+		request.RemoteAddr = tt.remoteAddr
+		for _, value := range tt.forwarded {
+			request.Header.Add("X-Forwarded-For", value)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, recorder.Code, tt.want)
+		}
+	}
+
+	if len(rejected) != 4 || rejected[0] != netip.MustParseAddr("203.0.113.9") || rejected[3].IsValid() {
+		t.Errorf("rejected = %v, want 3 times 203.0.113.9 and an invalid address", rejected)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxBodySize is the largest notification body read when HandlerOptions.MaxBodySize
+	// is not set, it is well above the size of the largest batched notifications.
+	DefaultMaxBodySize = 3 * 1024 * 1024
+
+	// DefaultBodyReadTimeout is the time allowed to read a notification body when
+	// HandlerOptions.ReadTimeout is not set.
+	DefaultBodyReadTimeout = 10 * time.Second
+)
+
+var (
+	ErrBodyTooLarge           = errors.New("notification body is too large")
+	ErrBodyReadTimeout        = errors.New("notification body read timed out")
+	ErrUnsupportedContentType = errors.New("notification content type is not json")
+)
+
+// readNotificationBody reads the body of a notification request within the limits set by
+// options, see HandlerOptions. It returns the body, or the error and the status code to
+// respond with.
+func readNotificationBody(writer http.ResponseWriter, request *http.Request,
+	options *HandlerOptions,
+) ([]byte, int, error) {
+	maxSize, timeout := int64(DefaultMaxBodySize), DefaultBodyReadTimeout
+	if options != nil {
+		if options.MaxBodySize != 0 {
+			maxSize = options.MaxBodySize
+		}
+		if options.ReadTimeout != 0 {
+			timeout = options.ReadTimeout
+		}
+	}
+
+	if contentType := request.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("%w: %q", ErrUnsupportedContentType, contentType)
+		}
+	}
+	if maxSize > 0 && request.ContentLength > maxSize {
+		return nil, http.StatusRequestEntityTooLarge, ErrBodyTooLarge
+	}
+
+	if timeout > 0 {
+		// not every http.ResponseWriter supports deadlines, the limit is then the server's
+		_ = http.NewResponseController(writer).SetReadDeadline(time.Now().Add(timeout))
+	}
+	body := request.Body
+	if maxSize > 0 {
+		body = http.MaxBytesReader(writer, body, maxSize)
+	}
+
+	var buff bytes.Buffer
+	if _, err := io.Copy(&buff, body); err != nil && !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			return nil, http.StatusRequestEntityTooLarge, ErrBodyTooLarge
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			return nil, http.StatusRequestTimeout, ErrBodyReadTimeout
+		default:
+			return nil, http.StatusInternalServerError, fmt.Errorf("read notification body: %w", err)
+		}
+	}
+
+	return buff.Bytes(), http.StatusOK, nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationHandler_BodyLimits(t *testing.T) {
+	t.Parallel()
+
+	var handled int
+	listener := NewEventListener(WithBodyLimits(64, time.Second))
+	listener.GenericNotificationHandler(func(ctx context.Context, w http.ResponseWriter, n *Notification) error {
+		handled++
+
+		return nil
+	})
+
+	small := `{"object":"whatsapp_business_account"}`
+	large := `{"object":"whatsapp_business_account","entry":[` + strings.Repeat(`{"id":"1"},`, 10) + `{}]}`
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		chunked     bool
+		want        int
+	}{
+		{name: "json", body: small, contentType: "application/json", want: http.StatusOK},
+		{name: "json with charset", body: small, contentType: "application/json; charset=utf-8", want: http.StatusOK},
+		{name: "no content type", body: small, want: http.StatusOK},
+		{name: "form", body: small, contentType: "application/x-www-form-urlencoded", want: 415},
+		{name: "too large", body: large, contentType: "application/json", want: 413},
+		{name: "too large without length", body: large, contentType: "application/json", chunked: true, want: 413},
+	}
+	for _, handler := range []http.Handler{listener.NotificationHandler(), listener.GlobalHandler()} {
+		for _, tt := range tests {
+			request := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.chunked {
+				request.ContentLength = -1
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.want {
+				t.Errorf("%s: status = %d, want %d", tt.name, recorder.Code, tt.want)
+			}
+		}
+	}
+	if handled != 3 {
+		t.Errorf("handled %d notifications, want 3", handled)
+	}
+}
+
+func TestNotificationHandler_ReadTimeout(t *testing.T) {
+	t.Parallel()
+
+	listener := NewEventListener(WithBodyLimits(0, 50*time.Millisecond))
+	server := httptest.NewServer(listener.NotificationHandler())
+	t.Cleanup(server.Close)
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	// the body is announced but never fully sent
+	_, _ = io.WriteString(conn, "POST /webhooks HTTP/1.1\r\nHost: example.com\r\n"+
+		"Content-Type: application/json\r\nContent-Length: 100\r\n\r\n{\"object\":")
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("ReadResponse() error = %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusRequestTimeout)
+	}
+}
@@ -115,5 +115,12 @@ The WhatsApp Business Platform sends notifications to inform you of the status o
 When a message is sent successfully, you receive a notification when the message is sent, delivered, and read.
 The order of these notifications in your app may not reflect the actual timing of the message status. View the
 timestamp to determine the timing, if necessary.
+
+# Batched Deliveries
+
+A single notification can batch several entries, each with several changes, and the same message or status
+can appear in more than one of them. The hooks are called once per message and status in a notification.
+To process a notification yourself, e.g. in a GlobalNotificationHandler, use Notification.EachChange,
+Notification.EachMessage and Notification.EachStatus, which give the same guarantee.
 */
 package webhooks
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+// Fields of the whatsapp_business_account webhooks an app can subscribe to, Change.Field is
+// one of them.
+const (
+	FieldMessages                     = "messages"
+	FieldMessageTemplateStatusUpdate  = "message_template_status_update"
+	FieldMessageTemplateQualityUpdate = "message_template_quality_update"
+	FieldTemplateCategoryUpdate       = "template_category_update"
+	FieldPhoneNumberQualityUpdate     = "phone_number_quality_update"
+	FieldPhoneNumberNameUpdate        = "phone_number_name_update"
+	FieldAccountUpdate                = "account_update"
+	FieldAccountReviewUpdate          = "account_review_update"
+	FieldAccountAlerts                = "account_alerts"
+	FieldBusinessCapabilityUpdate     = "business_capability_update"
+	FieldSecurity                     = "security"
+	FieldFlows                        = "flows"
+)
+
+// Fields returns the webhook fields the listener handles, the fields the app must be
+// subscribed to for its hooks to be called. The hooks of the listener all handle the
+// messages field, it is returned once a hook or a GlobalNotificationHandler is set.
+func (ls *EventListener) Fields() []string {
+	if ls.g == nil && ls.h == nil {
+		return nil
+	}
+
+	return []string{FieldMessages}
+}
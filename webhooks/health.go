@@ -0,0 +1,209 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultLivenessPath       = "/healthz"
+	DefaultReadinessPath      = "/readyz"
+	DefaultHealthCheckTimeout = 5 * time.Second
+)
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+type (
+	// HealthCheckFunc checks a dependency of the service, it returns nil when the dependency is
+	// healthy. EventListener.Ready, whatsapp.SenderPool.Ready and whatsapp.Client.Ready are
+	// HealthCheckFuncs, a store is typically checked with a ping.
+	HealthCheckFunc func(ctx context.Context) error
+
+	// HealthConfig configures the liveness and readiness probes served by ListenAndServeWebhook
+	// and returned by LivenessHandler and ReadinessHandler.
+	//
+	// The liveness probe only reports that the server is serving. The readiness probe runs the
+	// Checks concurrently, each with Timeout, DefaultHealthCheckTimeout by default, and responds
+	// with http.StatusServiceUnavailable when one of them fails so that the pod is taken out of
+	// the service until it recovers.
+	//
+	//	health := &webhooks.HealthConfig{
+	//		Checks: map[string]webhooks.HealthCheckFunc{
+	//			"listener": listener.Ready,
+	//			"pool":     pool.Ready,
+	//			"whatsapp": client.Ready,
+	//			"store":    db.PingContext,
+	//		},
+	//	}
+	HealthConfig struct {
+		Checks        map[string]HealthCheckFunc
+		Timeout       time.Duration
+		LivenessPath  string
+		ReadinessPath string
+	}
+
+	// HealthReport is the JSON body of the readiness probe responses.
+	HealthReport struct {
+		Status string                       `json:"status"`
+		Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+	}
+
+	// HealthCheckResult is the result of a check of the readiness probe.
+	HealthCheckResult struct {
+		Status   string `json:"status"`
+		Error    string `json:"error,omitempty"`
+		Duration string `json:"duration"`
+	}
+)
+
+// LivenessHandler returns a http.Handler that always responds with http.StatusOK, the server
+// being able to respond is all that the liveness probe checks.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(HealthStatusOK))
+	})
+}
+
+// ReadinessHandler returns a http.Handler that runs the checks of config and responds with a
+// HealthReport, with http.StatusOK when all of them pass and http.StatusServiceUnavailable
+// otherwise.
+func ReadinessHandler(config *HealthConfig) http.Handler {
+	if config == nil {
+		config = &HealthConfig{}
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		report := config.Check(request.Context())
+		status := http.StatusOK
+		if report.Status != HealthStatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Cache-Control", "no-store")
+		writer.WriteHeader(status)
+		_ = json.NewEncoder(writer).Encode(report)
+	})
+}
+
+// Check runs the checks concurrently and returns their results.
+func (config *HealthConfig) Check(ctx context.Context) *HealthReport {
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+
+	names := make([]string, 0, len(config.Checks))
+	for name := range config.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]HealthCheckResult, len(names))
+	var wg sync.WaitGroup
+	wg.Add(len(names))
+	for i, name := range names {
+		go func(i int, check HealthCheckFunc) {
+			defer wg.Done()
+			results[i] = runHealthCheck(ctx, check, timeout)
+		}(i, config.Checks[name])
+	}
+	wg.Wait()
+
+	report := &HealthReport{Status: HealthStatusOK}
+	if len(names) > 0 {
+		report.Checks = make(map[string]HealthCheckResult, len(names))
+	}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != HealthStatusOK {
+			report.Status = HealthStatusUnavailable
+		}
+	}
+
+	return report
+}
+
+// runHealthCheck runs check with timeout, a check that panics or does not return in time fails.
+func runHealthCheck(ctx context.Context, check HealthCheckFunc, timeout time.Duration) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errc <- fmt.Errorf("health check panicked: %v", r) //nolint:goerr113
+			}
+		}()
+		errc <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := HealthCheckResult{Status: HealthStatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = HealthStatusUnavailable
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Ready reports whether the listener accepts notifications, it returns ErrListenerClosed once
+// Shutdown is called. It is a HealthCheckFunc.
+func (ls *EventListener) Ready(_ context.Context) error {
+	if ls.deliveries.isClosed() {
+		return ErrListenerClosed
+	}
+
+	return nil
+}
+
+// mount registers the liveness and readiness probes on mux.
+func (config *HealthConfig) mount(mux *http.ServeMux) {
+	liveness, readiness := config.LivenessPath, config.ReadinessPath
+	if liveness == "" {
+		liveness = DefaultLivenessPath
+	}
+	if readiness == "" {
+		readiness = DefaultReadinessPath
+	}
+	mux.Handle(liveness, LivenessHandler())
+	mux.Handle(readiness, ReadinessHandler(config))
+}
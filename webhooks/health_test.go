@@ -0,0 +1,160 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	t.Parallel()
+	errStore := errors.New("store unreachable")
+	tests := []struct {
+		name   string
+		checks map[string]HealthCheckFunc
+		status int
+		failed []string
+	}{
+		{
+			name:   "no checks",
+			status: http.StatusOK,
+		},
+		{
+			name: "all pass",
+			checks: map[string]HealthCheckFunc{
+				"store": func(context.Context) error { return nil },
+				"pool":  func(context.Context) error { return nil },
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "one fails",
+			checks: map[string]HealthCheckFunc{
+				"store": func(context.Context) error { return errStore },
+				"pool":  func(context.Context) error { return nil },
+			},
+			status: http.StatusServiceUnavailable,
+			failed: []string{"store"},
+		},
+		{
+			name: "timeout and panic",
+			checks: map[string]HealthCheckFunc{
+				"slow": func(ctx context.Context) error {
+					<-ctx.Done()
+					time.Sleep(50 * time.Millisecond)
+
+					return nil
+				},
+				"broken": func(context.Context) error { panic("broken") },
+			},
+			status: http.StatusServiceUnavailable,
+			failed: []string{"broken", "slow"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			config := &HealthConfig{Checks: tt.checks, Timeout: 20 * time.Millisecond}
+			recorder := httptest.NewRecorder()
+			ReadinessHandler(config).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.status)
+			}
+			var report HealthReport
+			if err := json.NewDecoder(recorder.Body).Decode(&report); err != nil {
+				t.Fatalf("decode report: %v", err)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("report.Checks = %v, want %d checks", report.Checks, len(tt.checks))
+			}
+			failed := map[string]bool{}
+			for _, name := range tt.failed {
+				failed[name] = true
+			}
+			for name, result := range report.Checks {
+				if ok := result.Status == HealthStatusOK; ok == failed[name] {
+					t.Errorf("check %s = %+v, failed = %t", name, result, failed[name])
+				}
+				if failed[name] && result.Error == "" {
+					t.Errorf("check %s has no error", name)
+				}
+			}
+		})
+	}
+}
+
+func TestListenAndServeWebhook_Health(t *testing.T) {
+	t.Parallel()
+	listener := NewEventListener()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ListenAndServeWebhook(ctx, listener.Handler(), &ServerConfig{
+			Listener: ln,
+			Health:   &HealthConfig{Checks: map[string]HealthCheckFunc{"listener": listener.Ready}},
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("ListenAndServeWebhook() error = %v", err)
+		}
+	})
+
+	get := func(path string) int {
+		t.Helper()
+		response, err := http.Get("http://" + ln.Addr().String() + path) //nolint:noctx
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		response.Body.Close()
+
+		return response.StatusCode
+	}
+
+	if status := get(DefaultLivenessPath); status != http.StatusOK {
+		t.Errorf("GET %s = %d, want %d", DefaultLivenessPath, status, http.StatusOK)
+	}
+	if status := get(DefaultReadinessPath); status != http.StatusOK {
+		t.Errorf("GET %s = %d, want %d", DefaultReadinessPath, status, http.StatusOK)
+	}
+
+	if _, err := listener.Shutdown(context.TODO()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if status := get(DefaultReadinessPath); status != http.StatusServiceUnavailable {
+		t.Errorf("GET %s after Shutdown = %d, want %d", DefaultReadinessPath, status, http.StatusServiceUnavailable)
+	}
+	if status := get(DefaultLivenessPath); status != http.StatusOK {
+		t.Errorf("GET %s after Shutdown = %d, want %d", DefaultLivenessPath, status, http.StatusOK)
+	}
+}
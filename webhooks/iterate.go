@@ -0,0 +1,167 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+// A single delivery, i.e. a Notification, can batch several entries, each with several
+// changes. When a delivery is retried or the API batches updates, the same message or
+// status can appear in more than one of them. The helpers below iterate over all of them
+// in order and yield each message and each status once per delivery.
+
+// delivery records the messages and statuses already seen in a delivery.
+type delivery struct {
+	messages map[string]struct{}
+	statuses map[string]struct{}
+
+	// onDuplicate, when set, is called with the messages and statuses already seen.
+	onDuplicate func(kind ProcessingKind, typ string)
+}
+
+func newDelivery() *delivery {
+	return &delivery{
+		messages: make(map[string]struct{}),
+		statuses: make(map[string]struct{}),
+	}
+}
+
+// firstMessage reports whether message is seen for the first time in the delivery. Messages
+// without an ID can not be told apart and are always reported as first seen.
+func (d *delivery) firstMessage(message *Message) bool {
+	if message == nil || message.ID == "" {
+		return true
+	}
+	if _, ok := d.messages[message.ID]; ok {
+		return false
+	}
+	d.messages[message.ID] = struct{}{}
+
+	return true
+}
+
+// firstStatus reports whether status is seen for the first time in the delivery. Statuses
+// of the same message are different when their status or type differ, e.g. sent and
+// delivered are both reported.
+func (d *delivery) firstStatus(status *Status) bool {
+	if status == nil || status.ID == "" {
+		return true
+	}
+	key := status.ID + "\x00" + status.Type + "\x00" + status.StatusValue
+	if _, ok := d.statuses[key]; ok {
+		return false
+	}
+	d.statuses[key] = struct{}{}
+
+	return true
+}
+
+// newMessages returns the messages that have not been seen in the delivery.
+func (d *delivery) newMessages(messages []*Message) []*Message {
+	fresh := messages[:0:0]
+	for _, message := range messages {
+		if d.firstMessage(message) {
+			fresh = append(fresh, message)
+		} else if d.onDuplicate != nil {
+			d.onDuplicate(ProcessingMessage, message.Type)
+		}
+	}
+
+	return fresh
+}
+
+// newStatuses returns the statuses that have not been seen in the delivery.
+func (d *delivery) newStatuses(statuses []*Status) []*Status {
+	fresh := statuses[:0:0]
+	for _, status := range statuses {
+		if d.firstStatus(status) {
+			fresh = append(fresh, status)
+		} else if d.onDuplicate != nil {
+			d.onDuplicate(ProcessingStatus, status.StatusValue)
+		}
+	}
+
+	return fresh
+}
+
+func newNotificationContext(entryID string, value *Value) *NotificationContext {
+	return &NotificationContext{
+		ID:       entryID,
+		Contacts: value.Contacts,
+		Metadata: value.Metadata,
+	}
+}
+
+// EachChange calls fn with every change of the notification, in the order they were
+// delivered, along with the ID of the entry it belongs to. Changes without a value are
+// skipped. The iteration stops at the first error returned by fn, which is returned.
+func (notification *Notification) EachChange(fn func(entryID string, change *Change) error) error {
+	if notification == nil {
+		return nil
+	}
+	for _, entry := range notification.Entry {
+		if entry == nil {
+			continue
+		}
+		for _, change := range entry.Changes {
+			if change == nil || change.Value == nil {
+				continue
+			}
+			if err := fn(entry.ID, change); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// EachMessage calls fn with every message of the notification, across all its entries and
+// changes. A message that appears more than once in the notification is passed to fn once.
+// The iteration stops at the first error returned by fn, which is returned.
+func (notification *Notification) EachMessage(fn func(nctx *NotificationContext, message *Message) error) error {
+	seen := newDelivery()
+
+	return notification.EachChange(func(entryID string, change *Change) error {
+		nctx := newNotificationContext(entryID, change.Value)
+		for _, message := range seen.newMessages(change.Value.Messages) {
+			if err := fn(nctx, message); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// EachStatus calls fn with every status of the notification, across all its entries and
+// changes. A status that appears more than once in the notification is passed to fn once.
+// The iteration stops at the first error returned by fn, which is returned.
+func (notification *Notification) EachStatus(fn func(nctx *NotificationContext, status *Status) error) error {
+	seen := newDelivery()
+
+	return notification.EachChange(func(entryID string, change *Change) error {
+		nctx := newNotificationContext(entryID, change.Value)
+		for _, status := range seen.newStatuses(change.Value.Statuses) {
+			if err := fn(nctx, status); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// mixedDelivery batches two entries: the second repeats a message and a status of the first,
+// and has a change without a value and one for another field.
+const mixedDelivery = `{
+  "object": "whatsapp_business_account",
+  "entry": [
+    {
+      "id": "waba_1",
+      "changes": [
+        {
+          "field": "messages",
+          "value": {
+            "messaging_product": "whatsapp",
+            "metadata": {"display_phone_number": "15550001111", "phone_number_id": "phone_1"},
+            "messages": [
+              {"from": "255700000001", "id": "wamid.1", "timestamp": "1", "type": "text", "text": {"body": "one"}},
+              {"from": "255700000002", "id": "wamid.2", "timestamp": "2", "type": "text", "text": {"body": "two"}}
+            ],
+            "statuses": [
+              {"id": "wamid.OUT1", "status": "sent", "timestamp": 1, "recipient_id": "255700000001"},
+              {"id": "wamid.OUT1", "status": "delivered", "timestamp": 2, "recipient_id": "255700000001"}
+            ]
+          }
+        },
+        {"field": "messages"}
+      ]
+    },
+    {
+      "id": "waba_2",
+      "changes": [
+        {
+          "field": "messages",
+          "value": {
+            "messaging_product": "whatsapp",
+            "metadata": {"display_phone_number": "15550002222", "phone_number_id": "phone_2"},
+            "messages": [
+              {"from": "255700000002", "id": "wamid.2", "timestamp": "2", "type": "text", "text": {"body": "two"}},
+              {"from": "255700000003", "id": "wamid.3", "timestamp": "3", "type": "text", "text": {"body": "three"}}
+            ],
+            "statuses": [
+              {"id": "wamid.OUT1", "status": "delivered", "timestamp": 2, "recipient_id": "255700000001"},
+              {"id": "wamid.OUT2", "status": "read", "timestamp": 3, "recipient_id": "255700000003"}
+            ]
+          }
+        },
+        {
+          "field": "account_update",
+          "value": {"messaging_product": "whatsapp"}
+        }
+      ]
+    }
+  ]
+}`
+
+func decodeMixedDelivery(t *testing.T) *Notification {
+	t.Helper()
+	var notification Notification
+	if err := json.Unmarshal([]byte(mixedDelivery), &notification); err != nil {
+		t.Fatalf("decode notification: %v", err)
+	}
+
+	return &notification
+}
+
+func TestNotification_EachChange(t *testing.T) {
+	t.Parallel()
+
+	var changes []string
+	err := decodeMixedDelivery(t).EachChange(func(entryID string, change *Change) error {
+		changes = append(changes, entryID+"/"+change.Field)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachChange() error = %v", err)
+	}
+	want := []string{"waba_1/messages", "waba_2/messages", "waba_2/account_update"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("EachChange() changes = %v, want %v", changes, want)
+	}
+}
+
+func TestNotification_EachMessage(t *testing.T) {
+	t.Parallel()
+
+	var messages []string
+	err := decodeMixedDelivery(t).EachMessage(func(nctx *NotificationContext, message *Message) error {
+		messages = append(messages, nctx.Metadata.PhoneNumberID+"/"+message.ID)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachMessage() error = %v", err)
+	}
+	want := []string{"phone_1/wamid.1", "phone_1/wamid.2", "phone_2/wamid.3"}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("EachMessage() messages = %v, want %v", messages, want)
+	}
+
+	stop := errors.New("stop")
+	var count int
+	err = decodeMixedDelivery(t).EachMessage(func(nctx *NotificationContext, message *Message) error {
+		count++
+
+		return stop
+	})
+	if !errors.Is(err, stop) || count != 1 {
+		t.Errorf("EachMessage() error = %v after %d calls, want %v after 1", err, count, stop)
+	}
+}
+
+func TestNotification_EachStatus(t *testing.T) {
+	t.Parallel()
+
+	var statuses []string
+	err := decodeMixedDelivery(t).EachStatus(func(nctx *NotificationContext, status *Status) error {
+		statuses = append(statuses, status.ID+"/"+status.StatusValue)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachStatus() error = %v", err)
+	}
+	want := []string{"wamid.OUT1/sent", "wamid.OUT1/delivered", "wamid.OUT2/read"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("EachStatus() statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestAttachHooksToNotification_DispatchesOnce(t *testing.T) {
+	t.Parallel()
+
+	var (
+		received = map[string]int{}
+		texts    = map[string]int{}
+		statuses = map[string]int{}
+	)
+	hooks := &Hooks{
+		OnMessageReceivedHook: func(ctx context.Context, nctx *NotificationContext, message *Message) error {
+			received[message.ID]++
+
+			return nil
+		},
+		OnTextMessageHook: func(ctx context.Context, nctx *NotificationContext, mctx *MessageContext,
+			text *Text,
+		) error {
+			texts[mctx.ID]++
+
+			return nil
+		},
+		OnMessageStatusChangeHook: func(ctx context.Context, nctx *NotificationContext, status *Status) error {
+			statuses[status.ID+"/"+status.StatusValue]++
+
+			return nil
+		},
+	}
+
+	err := AttachHooksToNotification(context.Background(), decodeMixedDelivery(t), hooks, NoOpHooksErrorHandler)
+	if err != nil {
+		t.Fatalf("AttachHooksToNotification() error = %v", err)
+	}
+	wantMessages := map[string]int{"wamid.1": 1, "wamid.2": 1, "wamid.3": 1}
+	if !reflect.DeepEqual(received, wantMessages) || !reflect.DeepEqual(texts, wantMessages) {
+		t.Errorf("messages dispatched = %v and texts = %v, want %v", received, texts, wantMessages)
+	}
+	wantStatuses := map[string]int{"wamid.OUT1/sent": 1, "wamid.OUT1/delivered": 1, "wamid.OUT2/read": 1}
+	if !reflect.DeepEqual(statuses, wantStatuses) {
+		t.Errorf("statuses dispatched = %v, want %v", statuses, wantStatuses)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
 // EventListener wraps all the parts needed to listen and respond to incoming events
@@ -73,6 +74,9 @@ type EventListener struct {
 	v       SubscriptionVerifier
 	options *HandlerOptions
 	g       GlobalNotificationHandler
+
+	deliveries    deliveries
+	shutdownHooks []ShutdownHook
 }
 
 type ListenerOption func(*EventListener)
@@ -88,8 +92,14 @@ func NewEventListener(options ...ListenerOption) *EventListener {
 			AfterFunc:         nil,
 			ValidateSignature: false,
 			Secret:            "",
+			Secrets:           nil,
+			OnSignatureMatch:  nil,
+			MaxBodySize:       0,
+			ReadTimeout:       0,
+			ProcessingHooks:   nil,
 		},
-		g: nil,
+		g:             nil,
+		shutdownHooks: nil,
 	}
 
 	for _, option := range options {
@@ -227,6 +237,13 @@ func (ls *EventListener) OnMessageStatusChange(hook OnMessageStatusChangeHook) {
 	ls.h.OnMessageStatusChangeHook = hook
 }
 
+func (ls *EventListener) OnPaymentStatusChange(hook OnPaymentStatusChangeHook) {
+	if ls.h == nil {
+		ls.h = &Hooks{}
+	}
+	ls.h.OnPaymentStatusChangeHook = hook
+}
+
 func (ls *EventListener) OnMessageReceived(hook OnMessageReceivedHook) {
 	if ls.h == nil {
 		ls.h = &Hooks{}
@@ -288,29 +305,69 @@ func WithAfterFunc(afterFunc AfterFunc) ListenerOption {
 	}
 }
 
+// WithSignatureValidation validates the signatures of the notifications with secret, the
+// current app secret, and the previous secrets still accepted during a rotation.
+func WithSignatureValidation(secret string, previous ...string) ListenerOption {
+	return func(ls *EventListener) {
+		if ls.options == nil {
+			ls.options = &HandlerOptions{}
+		}
+		ls.options.ValidateSignature = true
+		ls.options.Secret = secret
+		ls.options.Secrets = previous
+	}
+}
+
+// WithSignatureMatchHook sets the hook called with the index of the secret that matched the
+// signature of each notification, see OnSignatureMatch.
+func WithSignatureMatchHook(hook OnSignatureMatch) ListenerOption {
+	return func(ls *EventListener) {
+		if ls.options == nil {
+			ls.options = &HandlerOptions{}
+		}
+		ls.options.OnSignatureMatch = hook
+	}
+}
+
+// WithBodyLimits sets the largest notification body read and the time allowed to read it,
+// see HandlerOptions.
+func WithBodyLimits(maxSize int64, readTimeout time.Duration) ListenerOption {
+	return func(ls *EventListener) {
+		if ls.options == nil {
+			ls.options = &HandlerOptions{}
+		}
+		ls.options.MaxBodySize = maxSize
+		ls.options.ReadTimeout = readTimeout
+	}
+}
+
 // NotificationHandler returns a http.Handler that can be used to handle the notification.
+//
+// Once the listener is shut down, see Shutdown, the notifications are rejected.
 func (ls *EventListener) NotificationHandler() http.Handler {
-	return NotificationHandler(ls.h, ls.neh, ls.hef, ls.options)
+	return ls.deliveries.track(NotificationHandler(ls.h, ls.neh, ls.hef, ls.options))
 }
 
 // GlobalHandler returns a http.Handler that handles all type of notification in one function.
 // It  calls GlobalNotificationHandler. So before using this function, you should set GlobalNotificationHandler
 // with WithGlobalNotificationHandler.
 //
+// Once the listener is shut down, see Shutdown, the notifications are rejected.
+//
 //nolint:cyclop
 func (ls *EventListener) GlobalHandler() http.Handler {
-	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-		var buff bytes.Buffer
-		if _, err := io.Copy(&buff, request.Body); err != nil && !errors.Is(err, io.EOF) {
-			writer.WriteHeader(http.StatusInternalServerError)
+	return ls.deliveries.track(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		payload, status, err := readNotificationBody(writer, request, ls.options)
+		if err != nil {
+			writer.WriteHeader(status)
 
 			return
 		}
-		request.Body = io.NopCloser(&buff)
+		buff := bytes.NewBuffer(payload)
+		request.Body = io.NopCloser(buff)
 
 		if ls.options != nil && ls.options.ValidateSignature {
-			signature, _ := ExtractSignatureFromHeader(request.Header)
-			if !ValidateSignature(buff.Bytes(), signature, ls.options.Secret) {
+			if !ls.options.validateSignature(request.Context(), payload, request.Header) {
 				if handleError(
 					request.Context(), writer, request,
 					ls.neh, ErrInvalidSignature) {
@@ -321,7 +378,7 @@ func (ls *EventListener) GlobalHandler() http.Handler {
 
 		// Construct the notification
 		var notification Notification
-		if err := json.NewDecoder(&buff).Decode(&notification); err != nil && !errors.Is(err, io.EOF) {
+		if err := json.NewDecoder(buff).Decode(&notification); err != nil && !errors.Is(err, io.EOF) {
 			writer.WriteHeader(http.StatusInternalServerError)
 
 			return
@@ -336,7 +393,7 @@ func (ls *EventListener) GlobalHandler() http.Handler {
 		}
 
 		writer.WriteHeader(http.StatusOK)
-	})
+	}))
 }
 
 // SubscriptionVerificationHandler returns a http.Handler that can be used to verify the subscription.
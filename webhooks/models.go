@@ -20,6 +20,9 @@
 package webhooks
 
 import (
+	"encoding/json"
+	"fmt"
+
 	werrors "github.com/SeamPay/whatsapp/errors"
 	"github.com/SeamPay/whatsapp/models"
 )
@@ -121,7 +124,19 @@ type (
 		Timestamp    int              `json:"timestamp,omitempty"`
 		Conversation *Conversation    `json:"conversation,omitempty"`
 		Pricing      *Pricing         `json:"pricing,omitempty"`
-		Errors       []*werrors.Error `json:"werrors,omitempty"`
+		Errors       []*werrors.Error `json:"errors,omitempty"`
+		Type         string           `json:"type,omitempty"`
+		Payment      *Payment         `json:"payment,omitempty"`
+	}
+
+	// Payment is the payment of an order_details message carried by a payment status
+	// notification, Status.ID is then the ID of the order_details message and
+	// Status.StatusValue one of the models.PaymentStatus values.
+	Payment struct {
+		ReferenceID string                     `json:"reference_id"`
+		Amount      *models.PaymentAmount      `json:"amount,omitempty"`
+		Currency    string                     `json:"currency,omitempty"`
+		Transaction *models.PaymentTransaction `json:"transaction,omitempty"`
 	}
 
 	// Event is the type of event that occurred and leads to the notification being sent.
@@ -159,7 +174,7 @@ type (
 		Button      *Button           `json:"button,omitempty"`
 		Context     *Context          `json:"context,omitempty"`
 		Document    *models.MediaInfo `json:"document,omitempty"`
-		Errors      []*werrors.Error  `json:"werrors,omitempty"`
+		Errors      []*werrors.Error  `json:"errors,omitempty"`
 		From        string            `json:"from,omitempty"`
 		ID          string            `json:"id,omitempty"`
 		Identity    *Identity         `json:"identity,omitempty"`
@@ -204,16 +219,23 @@ type (
 		Body string `json:"body,omitempty"`
 	}
 
-	// Interactive represent the interactive template.
+	// Interactive is the reply of a customer to an interactive message. Type is either
+	// InteractiveButtonReply, with ButtonReply set to the reply button the customer tapped, or
+	// InteractiveListReply, with ListReply set to the row the customer selected, or
+	// InteractiveNFMReply, with NFMReply set to the response of a Flow the customer completed.
 	Interactive struct {
-		Type *InteractiveType `json:"type,omitempty"`
+		Type        InteractiveReply `json:"type,omitempty"`
+		ButtonReply *ButtonReply     `json:"button_reply,omitempty"`
+		ListReply   *ListReply       `json:"list_reply,omitempty"`
+		NFMReply    *NFMReply        `json:"nfm_reply,omitempty"`
 	}
 
-	// InteractiveType represent an item sent to user. It can be a reply button
-	// (ButtonReply) or a list reply containing a list of items (ListReply).
-	InteractiveType struct {
-		ButtonReply *ButtonReply `json:"button_reply,omitempty"`
-		ListReply   *ListReply   `json:"list_reply,omitempty"`
+	// NFMReply is the response of a Flow. ResponseJSON is the JSON object of the values
+	// submitted by the customer, with the flow_token the Flow was sent with.
+	NFMReply struct {
+		Name         string `json:"name,omitempty"`
+		Body         string `json:"body,omitempty"`
+		ResponseJSON string `json:"response_json,omitempty"`
 	}
 
 	ButtonReply struct {
@@ -320,11 +342,11 @@ type (
 	//	  	- CatalogID, catalog_id — String. Unique identifier of the Meta catalog linked to the WhatsApp Business Account.
 	//      - ProductRetailerID,product_retailer_id — String. Unique identifier of the product in a catalog.
 	Context struct {
-		Forwarded           bool   `json:"forwarded,omitempty"`
-		FrequentlyForwarded bool   `json:"frequently_forwarded,omitempty"`
-		From                string `json:"from,omitempty"`
-		ID                  string `json:"id,omitempty"`
-		ReferredProduct     *ReferredProduct
+		Forwarded           bool             `json:"forwarded,omitempty"`
+		FrequentlyForwarded bool             `json:"frequently_forwarded,omitempty"`
+		From                string           `json:"from,omitempty"`
+		ID                  string           `json:"id,omitempty"`
+		ReferredProduct     *ReferredProduct `json:"referred_product,omitempty"`
 	}
 
 	// ReferredProduct ,Referred product object describing the product the user is
@@ -362,7 +384,7 @@ type (
 	Value struct {
 		MessagingProduct string           `json:"messaging_product,omitempty"`
 		Metadata         *Metadata        `json:"metadata,omitempty"`
-		Errors           []*werrors.Error `json:"werrors,omitempty"`
+		Errors           []*werrors.Error `json:"errors,omitempty"`
 		Contacts         []*Contact       `json:"contacts,omitempty"`
 		Messages         []*Message       `json:"messages,omitempty"`
 		Statuses         []*Status        `json:"statuses,omitempty"`
@@ -383,3 +405,129 @@ type (
 		Entry  []*Entry `json:"entry,omitempty"`
 	}
 )
+
+// IsPayment reports whether the status is about the payment of an order_details message
+// rather than about the delivery of a message.
+func (status *Status) IsPayment() bool {
+	return status != nil && status.Type == StatusTypePayment && status.Payment != nil
+}
+
+// ReplyID returns the ID of the button or row the customer replied with, that is the ID set
+// when the interactive message was sent.
+func (interactive *Interactive) ReplyID() string {
+	switch {
+	case interactive == nil:
+		return ""
+	case interactive.ButtonReply != nil:
+		return interactive.ButtonReply.ID
+	case interactive.ListReply != nil:
+		return interactive.ListReply.ID
+	default:
+		return ""
+	}
+}
+
+// FlowToken returns the flow_token of the Flow response, empty when there is none.
+func (reply *NFMReply) FlowToken() (string, error) {
+	if reply == nil || reply.ResponseJSON == "" {
+		return "", nil
+	}
+	var response struct {
+		FlowToken string `json:"flow_token"`
+	}
+	if err := json.Unmarshal([]byte(reply.ResponseJSON), &response); err != nil {
+		return "", fmt.Errorf("nfm reply: %w", err)
+	}
+
+	return response.FlowToken, nil
+}
+
+// Forwarding returns whether the message the context belongs to was forwarded, and whether
+// it was forwarded more than 5 times.
+func (ctx *Context) Forwarding() Forwarding {
+	switch {
+	case ctx == nil:
+		return NotForwarded
+	case ctx.FrequentlyForwarded:
+		return FrequentlyForwarded
+	case ctx.Forwarded:
+		return Forwarded
+	default:
+		return NotForwarded
+	}
+}
+
+// IsForwarded reports whether the message was forwarded, frequently or not.
+func (ctx *Context) IsForwarded() bool {
+	return ctx.Forwarding() != NotForwarded
+}
+
+// IsFrequentlyForwarded reports whether the message was forwarded more than 5 times, which
+// WhatsApp flags as a possible chain message.
+func (ctx *Context) IsFrequentlyForwarded() bool {
+	return ctx.Forwarding() == FrequentlyForwarded
+}
+
+// Forwarding returns the forwarding state of the message.
+func (message *Message) Forwarding() Forwarding {
+	if message == nil {
+		return NotForwarded
+	}
+
+	return message.Context.Forwarding()
+}
+
+// IsForwarded reports whether the message was forwarded, frequently or not.
+func (message *Message) IsForwarded() bool {
+	return message.Forwarding() != NotForwarded
+}
+
+// IsFrequentlyForwarded reports whether the message was forwarded more than 5 times.
+func (message *Message) IsFrequentlyForwarded() bool {
+	return message.Forwarding() == FrequentlyForwarded
+}
+
+// Forwarding returns the forwarding state of the message the hook is called for.
+func (mctx *MessageContext) Forwarding() Forwarding {
+	if mctx == nil {
+		return NotForwarded
+	}
+
+	return mctx.Ctx.Forwarding()
+}
+
+// IsForwarded reports whether the message was forwarded, frequently or not.
+func (mctx *MessageContext) IsForwarded() bool {
+	return mctx.Forwarding() != NotForwarded
+}
+
+// IsFrequentlyForwarded reports whether the message was forwarded more than 5 times.
+func (mctx *MessageContext) IsFrequentlyForwarded() bool {
+	return mctx.Forwarding() == FrequentlyForwarded
+}
+
+// Media returns the media of an audio, video, image, document or sticker message.
+func (message *Message) Media() (*models.MediaInfo, bool) {
+	if message == nil {
+		return nil, false
+	}
+	media := message.media()
+
+	return media, media != nil
+}
+
+// media returns the media of an audio, video, image, document or sticker message.
+func (message *Message) media() *models.MediaInfo {
+	switch ParseMessageType(message.Type) {
+	case VideoMessageType:
+		return message.Video
+	case ImageMessageType:
+		return message.Image
+	case DocumentMessageType:
+		return message.Document
+	case StickerMessageType:
+		return message.Sticker
+	default:
+		return message.Audio
+	}
+}
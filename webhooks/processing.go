@@ -0,0 +1,118 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Kinds of ProcessingEvent.
+const (
+	ProcessingNotification ProcessingKind = "notification"
+	ProcessingMessage      ProcessingKind = "message"
+	ProcessingStatus       ProcessingKind = "status"
+	ProcessingPayment      ProcessingKind = "payment"
+	ProcessingError        ProcessingKind = "error"
+)
+
+type (
+	// ProcessingKind is what a ProcessingEvent is about.
+	ProcessingKind string
+
+	// ProcessingEvent describes the processing of a notification or of one of the messages,
+	// statuses and errors it carries. It is passed to the ProcessingHook set by
+	// WithProcessingHooks, for example to export metrics.
+	//
+	// Type is the type of the message, the status value of the status and payment statuses,
+	// and the error code of the errors, it is empty for notifications. Duration is the time
+	// spent in the hooks and Err the error they returned. Duplicate is set for the messages
+	// and statuses batched more than once in a notification, which are dispatched once:
+	// the hooks are not called for the duplicates.
+	ProcessingEvent struct {
+		Kind      ProcessingKind
+		Type      string
+		Duration  time.Duration
+		Err       error
+		Duplicate bool
+	}
+
+	// ProcessingHook is called with the ProcessingEvent of every notification, message,
+	// status and error processed.
+	ProcessingHook func(ctx context.Context, event *ProcessingEvent)
+
+	processingHooksKey struct{}
+)
+
+// WithProcessingHooks adds hooks called with the ProcessingEvent of every notification handled
+// by the NotificationHandler and of every message, status and error they carry, see
+// HandlerOptions.ProcessingHooks.
+func WithProcessingHooks(hooks ...ProcessingHook) ListenerOption {
+	return func(ls *EventListener) {
+		ls.options.ProcessingHooks = append(ls.options.ProcessingHooks, hooks...)
+	}
+}
+
+// InFlight returns the number of notifications being processed by the listener.
+func (ls *EventListener) InFlight() int {
+	return ls.deliveries.pending()
+}
+
+// withProcessingHooks returns a context carrying the hooks, they are called by the functions
+// processing the notification.
+func withProcessingHooks(ctx context.Context, hooks []ProcessingHook) context.Context {
+	if len(hooks) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, processingHooksKey{}, hooks)
+}
+
+// startProcessing starts the processing of a part of a notification, the returned function
+// ends it and reports the ProcessingEvent to the hooks set in ctx, if any.
+func startProcessing(ctx context.Context, kind ProcessingKind, typ string) func(err error) {
+	hooks, _ := ctx.Value(processingHooksKey{}).([]ProcessingHook)
+	if len(hooks) == 0 {
+		return func(error) {}
+	}
+	start := time.Now()
+
+	return func(err error) {
+		event := &ProcessingEvent{Kind: kind, Type: typ, Duration: time.Since(start), Err: err}
+		for _, hook := range hooks {
+			hook(ctx, event)
+		}
+	}
+}
+
+// reportDuplicate reports a message or status skipped because it was already processed.
+func reportDuplicate(ctx context.Context, kind ProcessingKind, typ string) {
+	hooks, _ := ctx.Value(processingHooksKey{}).([]ProcessingHook)
+	event := &ProcessingEvent{Kind: kind, Type: typ, Duplicate: true}
+	for _, hook := range hooks {
+		hook(ctx, event)
+	}
+}
+
+// errorType returns the Type of the ProcessingEvent of a notification error.
+func errorType(code int) string {
+	return strconv.Itoa(code)
+}
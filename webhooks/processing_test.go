@@ -0,0 +1,95 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProcessingHooks(t *testing.T) {
+	t.Parallel()
+	var (
+		mu     sync.Mutex
+		events []ProcessingEvent
+	)
+	hookErr := errors.New("hook failed")
+	listener := NewEventListener(WithProcessingHooks(func(ctx context.Context, event *ProcessingEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, *event)
+	}))
+	listener.OnTextMessage(func(ctx context.Context, nctx *NotificationContext, mctx *MessageContext, text *Text) error {
+		if text.Body == "three" {
+			return hookErr
+		}
+
+		return nil
+	})
+	listener.OnMessageStatusChange(func(ctx context.Context, nctx *NotificationContext, status *Status) error {
+		return nil
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(mixedDelivery))
+	request.Header.Set("Content-Type", "application/json")
+	listener.NotificationHandler().ServeHTTP(httptest.NewRecorder(), request)
+
+	counts := map[string]int{}
+	var failed []string
+	for _, event := range events {
+		key := string(event.Kind) + "/" + event.Type
+		if event.Duplicate {
+			key += "/duplicate"
+		}
+		counts[key]++
+		if event.Err != nil {
+			failed = append(failed, key)
+		}
+	}
+	want := map[string]int{
+		"notification/":              1,
+		"message/text":               3,
+		"message/text/duplicate":     1,
+		"status/sent":                1,
+		"status/delivered":           1,
+		"status/read":                1,
+		"status/delivered/duplicate": 1,
+	}
+	if len(counts) != len(want) {
+		t.Errorf("events = %v, want %v", counts, want)
+	}
+	for key, n := range want {
+		if counts[key] != n {
+			t.Errorf("events[%s] = %d, want %d", key, counts[key], n)
+		}
+	}
+	// the notification is reported last, with the error of the hooks
+	if len(failed) != 2 || failed[0] != "message/text" || failed[1] != "notification/" {
+		t.Errorf("failed events = %v, want the last message and the notification", failed)
+	}
+	if listener.InFlight() != 0 {
+		t.Errorf("InFlight() = %d, want 0", listener.InFlight())
+	}
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultWebhookPath is the path of the callback URL when ServerConfig.Path is not set.
+	DefaultWebhookPath = "/webhooks"
+
+	DefaultServerReadHeaderTimeout = 10 * time.Second
+	DefaultServerShutdownTimeout   = 10 * time.Second
+)
+
+type (
+	// CertManager obtains and renews the TLS certificates of the server, e.g. from Let's Encrypt.
+	// It is implemented by *autocert.Manager of golang.org/x/crypto/acme/autocert:
+	//
+	//	manager := &autocert.Manager{
+	//		Prompt:     autocert.AcceptTOS,
+	//		HostPolicy: autocert.HostWhitelist("hooks.example.com"),
+	//		Cache:      autocert.DirCache("/var/lib/webhooks/certs"),
+	//	}
+	//	err := webhooks.ListenAndServeWebhook(ctx, listener.Handler(), &webhooks.ServerConfig{
+	//		Addr:          ":443",
+	//		CertManager:   manager,
+	//		ChallengeAddr: ":80",
+	//	})
+	CertManager interface {
+		TLSConfig() *tls.Config
+		HTTPHandler(fallback http.Handler) http.Handler
+	}
+
+	// ServerConfig configures ListenAndServeWebhook.
+	//
+	// The server listens on Addr, or uses Listener when set. The handler is served at Path,
+	// DefaultWebhookPath by default. TLS is terminated by the server when one of CertFile and
+	// KeyFile, TLSConfig or CertManager is set. With a CertManager, the certificates are
+	// obtained on the first connections; when ChallengeAddr is set, a plain HTTP server also
+	// listens there to answer the HTTP-01 challenges and redirect the other requests to HTTPS.
+	//
+	// When Health is set, the liveness and readiness probes are served as well, at the paths
+	// DefaultLivenessPath and DefaultReadinessPath unless configured otherwise, see HealthConfig.
+	//
+	// When the context passed to ListenAndServeWebhook is done, the server stops accepting
+	// connections and waits up to ShutdownTimeout for the requests in flight.
+	ServerConfig struct {
+		Addr              string
+		Listener          net.Listener
+		Path              string
+		CertFile          string
+		KeyFile           string
+		TLSConfig         *tls.Config
+		CertManager       CertManager
+		ChallengeAddr     string
+		ReadHeaderTimeout time.Duration
+		ShutdownTimeout   time.Duration
+		Health            *HealthConfig
+	}
+)
+
+// Handler returns a http.Handler that answers the subscription verification requests (GET)
+// and handles the notifications (POST), with the GlobalNotificationHandler when one is set
+// or with the hooks otherwise.
+func (ls *EventListener) Handler() http.Handler {
+	verifications := ls.SubscriptionVerificationHandler()
+	notifications := ls.NotificationHandler()
+	if ls.g != nil {
+		notifications = ls.GlobalHandler()
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.Method {
+		case http.MethodGet:
+			verifications.ServeHTTP(writer, request)
+		case http.MethodPost:
+			notifications.ServeHTTP(writer, request)
+		default:
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func (config *ServerConfig) tls() bool {
+	return config.CertFile != "" || config.KeyFile != "" || config.TLSConfig != nil || config.CertManager != nil
+}
+
+func (config *ServerConfig) tlsConfig() *tls.Config {
+	if config.CertManager == nil {
+		if config.TLSConfig == nil {
+			return nil
+		}
+
+		return config.TLSConfig.Clone()
+	}
+	managed := config.CertManager.TLSConfig()
+	if config.TLSConfig != nil {
+		tlsConfig := config.TLSConfig.Clone()
+		tlsConfig.GetCertificate = managed.GetCertificate
+		tlsConfig.NextProtos = append(tlsConfig.NextProtos, managed.NextProtos...)
+
+		return tlsConfig
+	}
+
+	return managed
+}
+
+// ListenAndServeWebhook serves handler, typically EventListener.Handler, at the callback URL
+// path until ctx is done, terminating TLS itself when configured so that no reverse proxy is
+// needed, see ServerConfig. It returns nil once the server has shut down after ctx is done.
+func ListenAndServeWebhook(ctx context.Context, handler http.Handler, config *ServerConfig) error {
+	if config == nil {
+		config = &ServerConfig{}
+	}
+	path, readHeaderTimeout, shutdownTimeout := config.Path, config.ReadHeaderTimeout, config.ShutdownTimeout
+	if path == "" {
+		path = DefaultWebhookPath
+	}
+	if readHeaderTimeout <= 0 {
+		readHeaderTimeout = DefaultServerReadHeaderTimeout
+	}
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultServerShutdownTimeout
+	}
+
+	listener := config.Listener
+	if listener == nil {
+		addr := config.Addr
+		if addr == "" {
+			addr = ":http"
+			if config.tls() {
+				addr = ":https"
+			}
+		}
+		var err error
+		if listener, err = net.Listen("tcp", addr); err != nil {
+			return fmt.Errorf("webhook server: %w", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	if config.Health != nil {
+		config.Health.mount(mux)
+	}
+	servers := []*http.Server{{
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+		TLSConfig:         config.tlsConfig(),
+	}}
+	serve := []func() error{func() error {
+		if config.tls() {
+			return servers[0].ServeTLS(listener, config.CertFile, config.KeyFile) //nolint:wrapcheck
+		}
+
+		return servers[0].Serve(listener) //nolint:wrapcheck
+	}}
+	if config.CertManager != nil && config.ChallengeAddr != "" {
+		challenges := &http.Server{
+			Addr:              config.ChallengeAddr,
+			Handler:           config.CertManager.HTTPHandler(nil),
+			ReadHeaderTimeout: readHeaderTimeout,
+		}
+		servers = append(servers, challenges)
+		serve = append(serve, challenges.ListenAndServe)
+	}
+
+	errc := make(chan error, len(servers))
+	for _, fn := range serve {
+		go func(fn func() error) { errc <- fn() }(fn)
+	}
+
+	select {
+	case err := <-errc:
+		_ = shutdown(servers, shutdownTimeout)
+
+		return fmt.Errorf("webhook server: %w", err)
+	case <-ctx.Done():
+		if err := shutdown(servers, shutdownTimeout); err != nil {
+			return fmt.Errorf("webhook server: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// shutdown gracefully shuts the servers down, waiting up to timeout for the requests in flight.
+func shutdown(servers []*http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// selfSignedCertificate returns a certificate for 127.0.0.1 and a pool that trusts it.
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "webhooks test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate() error = %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// fakeCertManager serves a fixed certificate, like autocert would once it obtained one.
+type fakeCertManager struct {
+	certificate tls.Certificate
+}
+
+func (m *fakeCertManager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &m.certificate, nil
+		},
+		NextProtos: []string{"h2", "http/1.1", "acme-tls/1"},
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+func (m *fakeCertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return fallback
+}
+
+func TestListenAndServeWebhook(t *testing.T) {
+	t.Parallel()
+
+	certificate, pool := selfSignedCertificate(t)
+	manager := &fakeCertManager{certificate: certificate}
+	tests := []struct {
+		name   string
+		config func(listener net.Listener) *ServerConfig
+		scheme string
+	}{
+		{
+			name: "plain http",
+			config: func(listener net.Listener) *ServerConfig {
+				return &ServerConfig{Listener: listener}
+			},
+			scheme: "http",
+		},
+		{
+			name: "tls config",
+			config: func(listener net.Listener) *ServerConfig {
+				return &ServerConfig{
+					Listener:  listener,
+					Path:      "/hooks",
+					TLSConfig: &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12},
+				}
+			},
+			scheme: "https",
+		},
+		{
+			name: "cert manager",
+			config: func(listener net.Listener) *ServerConfig {
+				return &ServerConfig{Listener: listener, CertManager: manager}
+			},
+			scheme: "https",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			config := tt.config(listener)
+			path := config.Path
+			if path == "" {
+				path = DefaultWebhookPath
+			}
+
+			events := NewEventListener(WithSubscriptionVerifier(func(context.Context, *VerificationRequest) error {
+				return nil
+			}))
+			ctx, cancel := context.WithCancel(context.Background())
+			errc := make(chan error, 1)
+			go func() { errc <- ListenAndServeWebhook(ctx, events.Handler(), config) }()
+
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+			}}
+			url := tt.scheme + "://" + listener.Addr().String() + path
+			response, err := client.Get(url + "?hub.mode=subscribe&hub.challenge=42&hub.verify_token=token")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			body, _ := io.ReadAll(response.Body)
+			_ = response.Body.Close()
+			if response.StatusCode != http.StatusOK || string(body) != "42" {
+				t.Errorf("verification = %d %q, want 200 %q", response.StatusCode, body, "42")
+			}
+
+			response, err = client.Post(url, "application/json", strings.NewReader(`{"object":"whatsapp_business_account"}`))
+			if err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			_ = response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("notification status = %d, want 200", response.StatusCode)
+			}
+
+			cancel()
+			select {
+			case err = <-errc:
+				if err != nil {
+					t.Errorf("ListenAndServeWebhook() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("ListenAndServeWebhook() did not return after the context was done")
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"strings"
+	"time"
+
+	"github.com/SeamPay/whatsapp/models"
+)
+
+// birthdayLayout is the layout of the birthdays of the contact cards.
+const birthdayLayout = "2006-01-02"
+
+type (
+	// SharedLocation is a location shared by a customer. Name and Address are set for the
+	// places picked from the map, along with their URL when they have a website.
+	SharedLocation struct {
+		Latitude  float64
+		Longitude float64
+		Name      string
+		Address   string
+		URL       string
+	}
+
+	// SharedContact is a contact card shared by a customer. The fields follow the vCard
+	// structure of the card, Birthday is zero when the card has none. The phones of the
+	// contacts that are on WhatsApp carry their wa_id.
+	SharedContact struct {
+		Name      *models.Name
+		Birthday  time.Time
+		Org       *models.Org
+		Phones    []*models.Phone
+		Emails    []*models.Email
+		Addresses []*models.Address
+		URLs      []*models.Url
+	}
+
+	// SharedContacts are the contact cards of a contacts message.
+	SharedContacts []*SharedContact
+)
+
+// SharedLocation returns the location of a location message.
+func (message *Message) SharedLocation() (*SharedLocation, bool) {
+	if message == nil || message.Location == nil {
+		return nil, false
+	}
+	location := message.Location
+
+	return &SharedLocation{
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+		Name:      location.Name,
+		Address:   location.Address,
+		URL:       location.URL,
+	}, true
+}
+
+// SharedContacts returns the contact cards of a contacts message.
+func (message *Message) SharedContacts() (SharedContacts, bool) {
+	if message == nil || message.Contacts == nil || len(*message.Contacts) == 0 {
+		return nil, false
+	}
+	contacts := make(SharedContacts, 0, len(*message.Contacts))
+	for _, contact := range *message.Contacts {
+		if contact != nil {
+			contacts = append(contacts, newSharedContact(contact))
+		}
+	}
+
+	return contacts, len(contacts) > 0
+}
+
+// Model returns the location as sent by Client.SendLocationMessage.
+func (location *SharedLocation) Model() *models.Location {
+	return &models.Location{
+		Latitude:  location.Latitude,
+		Longitude: location.Longitude,
+		Name:      location.Name,
+		Address:   location.Address,
+	}
+}
+
+func newSharedContact(contact *models.Contact) *SharedContact {
+	shared := &SharedContact{
+		Name:      contact.Name,
+		Org:       contact.Org,
+		Phones:    contact.Phones,
+		Emails:    contact.Emails,
+		Addresses: contact.Addresses,
+		URLs:      contact.Urls,
+	}
+	if birthday, err := time.Parse(birthdayLayout, contact.Birthday); err == nil {
+		shared.Birthday = birthday
+	}
+
+	return shared
+}
+
+// FormattedName returns the formatted name of the contact, built from the parts of the name
+// when the card has none.
+func (contact *SharedContact) FormattedName() string {
+	name := contact.Name
+	if name == nil {
+		return ""
+	}
+	if name.FormattedName != "" {
+		return name.FormattedName
+	}
+	parts := make([]string, 0, 5) //nolint:gomnd
+	for _, part := range []string{name.Prefix, name.FirstName, name.MiddleName, name.LastName, name.Suffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// WaIDs returns the WhatsApp IDs of the phones of the contact that are on WhatsApp.
+func (contact *SharedContact) WaIDs() []string {
+	var ids []string
+	for _, phone := range contact.Phones {
+		if phone != nil && phone.WaID != "" {
+			ids = append(ids, phone.WaID)
+		}
+	}
+
+	return ids
+}
+
+// Model returns a copy of the contact card that can be shared again with Client.SendContacts.
+// The formatted name, required when sending, is filled in from the parts of the name.
+func (contact *SharedContact) Model() *models.Contact {
+	model := &models.Contact{Name: &models.Name{FormattedName: contact.FormattedName()}}
+	if contact.Name != nil {
+		name := *contact.Name
+		name.FormattedName = model.Name.FormattedName
+		model.Name = &name
+	}
+	if !contact.Birthday.IsZero() {
+		model.Birthday = contact.Birthday.Format(birthdayLayout)
+	}
+	if contact.Org != nil {
+		org := *contact.Org
+		model.Org = &org
+	}
+	model.Phones = cloneAll(contact.Phones)
+	model.Emails = cloneAll(contact.Emails)
+	model.Addresses = cloneAll(contact.Addresses)
+	model.Urls = cloneAll(contact.URLs)
+
+	return model
+}
+
+// Model returns copies of the contact cards that can be shared again with
+// Client.SendContacts.
+func (contacts SharedContacts) Model() models.Contacts {
+	model := make(models.Contacts, 0, len(contacts))
+	for _, contact := range contacts {
+		model = append(model, contact.Model())
+	}
+
+	return model
+}
+
+// cloneAll returns shallow copies of the non nil values.
+func cloneAll[T any](values []*T) []*T {
+	if len(values) == 0 {
+		return nil
+	}
+	clones := make([]*T, 0, len(values))
+	for _, value := range values {
+		if value != nil {
+			clone := *value
+			clones = append(clones, &clone)
+		}
+	}
+
+	return clones
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMessageSharedLocation(t *testing.T) {
+	t.Parallel()
+	payload := `{"from":"255700000000","id":"wamid.1","type":"location","location":{
+		"latitude":-6.8,"longitude":39.28,"name":"Cafe","address":"Main St","url":"https://cafe.example"}}`
+	var message Message
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	location, ok := message.SharedLocation()
+	if !ok {
+		t.Fatal("SharedLocation() ok = false, want true")
+	}
+	want := SharedLocation{
+		Latitude: -6.8, Longitude: 39.28, Name: "Cafe", Address: "Main St", URL: "https://cafe.example",
+	}
+	if *location != want {
+		t.Errorf("SharedLocation() = %+v, want %+v", *location, want)
+	}
+	if model := location.Model(); model.Latitude != want.Latitude || model.Name != want.Name {
+		t.Errorf("Model() = %+v", model)
+	}
+
+	if _, ok := (&Message{Type: "text"}).SharedLocation(); ok {
+		t.Error("SharedLocation() ok = true for a text message")
+	}
+}
+
+func TestMessageSharedContacts(t *testing.T) {
+	t.Parallel()
+	payload := `{"from":"255700000000","id":"wamid.2","type":"contacts","contacts":[{
+		"birthday":"1990-04-12",
+		"name":{"first_name":"Jane","last_name":"Doe"},
+		"org":{"company":"Acme"},
+		"phones":[{"phone":"+255 711 000 000","type":"CELL","wa_id":"255711000000"},{"phone":"+1 555 0100"}],
+		"emails":[{"email":"jane@example.com","type":"WORK"}]}]}`
+	var message Message
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	contacts, ok := message.SharedContacts()
+	if !ok || len(contacts) != 1 {
+		t.Fatalf("SharedContacts() = %v, %v, want one contact", contacts, ok)
+	}
+	contact := contacts[0]
+	if got := contact.FormattedName(); got != "Jane Doe" {
+		t.Errorf("FormattedName() = %q, want %q", got, "Jane Doe")
+	}
+	if want := time.Date(1990, 4, 12, 0, 0, 0, 0, time.UTC); !contact.Birthday.Equal(want) {
+		t.Errorf("Birthday = %v, want %v", contact.Birthday, want)
+	}
+	if ids := contact.WaIDs(); len(ids) != 1 || ids[0] != "255711000000" {
+		t.Errorf("WaIDs() = %v, want [255711000000]", ids)
+	}
+
+	model := contacts.Model()
+	if len(model) != 1 {
+		t.Fatalf("Model() = %v, want one contact", model)
+	}
+	if model[0].Name.FormattedName != "Jane Doe" || model[0].Birthday != "1990-04-12" {
+		t.Errorf("Model() name = %+v, birthday = %q", model[0].Name, model[0].Birthday)
+	}
+	if model[0].Org.Company != "Acme" || len(model[0].Phones) != 2 || len(model[0].Emails) != 1 {
+		t.Errorf("Model() = %+v", model[0])
+	}
+
+	// the model is a copy, changing it leaves the received contact untouched
+	model[0].Phones[0].Phone = "changed"
+	model[0].Name.FirstName = "changed"
+	if contact.Phones[0].Phone == "changed" || contact.Name.FirstName == "changed" {
+		t.Error("Model() shares values with the received contact")
+	}
+}
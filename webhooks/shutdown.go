@@ -0,0 +1,139 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+var ErrListenerClosed = errors.New("event listener is shut down")
+
+// ShutdownHook is called by EventListener.Shutdown once the notifications in flight are
+// handled, to flush what the hooks persist, e.g. a buffered store.
+type ShutdownHook func(ctx context.Context) error
+
+// deliveries tracks the notifications being handled by an EventListener.
+type deliveries struct {
+	mu     sync.Mutex
+	closed bool
+	count  int
+	idle   chan struct{}
+}
+
+// enter records a notification, it returns false once the listener is shut down.
+func (d *deliveries) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return false
+	}
+	d.count++
+
+	return true
+}
+
+func (d *deliveries) leave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.count--
+	if d.closed && d.count == 0 {
+		close(d.idle)
+	}
+}
+
+// close stops accepting notifications and returns a channel closed once none is in flight.
+func (d *deliveries) close() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.closed {
+		d.closed = true
+		d.idle = make(chan struct{})
+		if d.count == 0 {
+			close(d.idle)
+		}
+	}
+
+	return d.idle
+}
+
+func (d *deliveries) isClosed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.closed
+}
+
+func (d *deliveries) pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.count
+}
+
+// track returns a handler that records the notifications handled by next, and responds with
+// http.StatusServiceUnavailable once the listener is shut down so that they are redelivered.
+func (d *deliveries) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !d.enter() {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		defer d.leave()
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// WithShutdownHook adds a hook called by Shutdown after the notifications in flight are handled.
+func WithShutdownHook(hook ShutdownHook) ListenerOption {
+	return func(ls *EventListener) {
+		ls.shutdownHooks = append(ls.shutdownHooks, hook)
+	}
+}
+
+// Shutdown stops accepting notifications, they are answered with http.StatusServiceUnavailable
+// so that WhatsApp delivers them again later, and waits for the ones in flight to be handled
+// until ctx is done. Then it calls the shutdown hooks, see WithShutdownHook.
+//
+// It returns the number of notifications still in flight when ctx was done, along with the
+// error of ctx and the errors of the hooks.
+func (ls *EventListener) Shutdown(ctx context.Context) (int, error) {
+	var (
+		pending int
+		errs    []error
+	)
+	select {
+	case <-ls.deliveries.close():
+	case <-ctx.Done():
+		pending = ls.deliveries.pending()
+		errs = append(errs, ctx.Err())
+	}
+
+	for _, hook := range ls.shutdownHooks {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return pending, errors.Join(errs...)
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventListener_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+		flushed int32
+	)
+	listener := NewEventListener(
+		WithGlobalNotificationHandler(func(ctx context.Context, w http.ResponseWriter, n *Notification) error {
+			close(started)
+			<-release
+
+			return nil
+		}),
+		WithShutdownHook(func(ctx context.Context) error {
+			atomic.AddInt32(&flushed, 1)
+
+			return nil
+		}),
+	)
+	handler := listener.Handler()
+	notify := func() int {
+		request := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"object":"x"}`))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder.Code
+	}
+
+	inflight := make(chan int, 1)
+	go func() { inflight <- notify() }()
+	<-started
+
+	// the deadline passes with one notification in flight
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	pending, err := listener.Shutdown(ctx)
+	if pending != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() = %d, %v, want 1, %v", pending, err, context.DeadlineExceeded)
+	}
+	if code := notify(); code != http.StatusServiceUnavailable {
+		t.Errorf("notification after Shutdown status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	if code := <-inflight; code != http.StatusOK {
+		t.Errorf("notification in flight status = %d, want %d", code, http.StatusOK)
+	}
+	if pending, err = listener.Shutdown(context.Background()); pending != 0 || err != nil {
+		t.Errorf("Shutdown() once drained = %d, %v, want 0, nil", pending, err)
+	}
+	if got := atomic.LoadInt32(&flushed); got != 2 {
+		t.Errorf("shutdown hook called %d times, want 2", got)
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	werrors "github.com/SeamPay/whatsapp/errors"
 	"github.com/SeamPay/whatsapp/models"
@@ -46,6 +47,9 @@ const (
 	MessageStatusSent      MessageStatus = "sent"
 )
 
+// StatusTypePayment is the Status.Type of payment status notifications.
+const StatusTypePayment = "payment"
+
 const (
 	AudioMessageType       MessageType = "audio"
 	ButtonMessageType      MessageType = "button"
@@ -63,15 +67,28 @@ const (
 	ContactMessageType     MessageType = "contacts"
 )
 
+// Forwarding states of incoming messages.
+const (
+	NotForwarded        Forwarding = "not_forwarded"
+	Forwarded           Forwarding = "forwarded"
+	FrequentlyForwarded Forwarding = "frequently_forwarded"
+)
+
 const (
 	InteractiveListReply   InteractiveReply = "list_reply"
 	InteractiveButtonReply InteractiveReply = "button_reply"
+	InteractiveNFMReply    InteractiveReply = "nfm_reply"
 )
 
 type (
 
+	// Forwarding tells whether an incoming message was forwarded by the customer, built from
+	// the forwarded and frequently_forwarded flags of its context. Bots can use it to treat
+	// chain messages differently, e.g. not to act on a frequently forwarded message.
+	Forwarding string
+
 	// InteractiveReply is the type of interactive reply. It can be one of the following:
-	// list_reply,or button_reply.
+	// list_reply, button_reply or nfm_reply.
 	InteractiveReply string
 
 	// MessageType is type of message that has been received by the business that has subscribed
@@ -150,10 +167,16 @@ type (
 	// is received in a message, that is handled by NotificationHooks.OnMessageErrors.
 	OnNotificationErrorHook func(ctx context.Context, nctx *NotificationContext, errors *werrors.Error) error
 
-	// OnMessageStatusChangeHook is a hook that is called when a there is a notification about a message status change.
+	// OnMessageStatusChangeHook is a hook that is called when there is a notification about a message status change.
 	// This is called when a message status changes. For example, when a message is delivered or read.
 	OnMessageStatusChangeHook func(ctx context.Context, nctx *NotificationContext, status *Status) error
 
+	// OnPaymentStatusChangeHook is a hook that is called when there is a notification about the
+	// payment of an order_details message, status.StatusValue is one of the models.PaymentStatus values.
+	// Payment status notifications are passed to the OnMessageStatusChangeHook as well.
+	OnPaymentStatusChangeHook func(ctx context.Context, nctx *NotificationContext, status *Status,
+		payment *Payment) error
+
 	// OnMessageReceivedHook is a hook that is called when a message is received. A notification
 	// can contain a lot of things like errors status changes etc. This is called when a
 	// notification contains a message. This work with the
@@ -165,7 +188,7 @@ type (
 	// OnNotificationErrorHook is the OnNotificationErrorHook called when an error is received
 	// in a notification.
 	//
-	// OnMessageStatusChangeHook is the OnMessageStatusChangeHook called when a there is a
+	// OnMessageStatusChangeHook is the OnMessageStatusChangeHook called when there is a
 	// notification about a message status change.
 	// M is the OnMessageReceivedHook called when a message is received.
 	// H is the MessageHooks called when a message is received.
@@ -186,6 +209,7 @@ type (
 		OnMediaMessageHook        OnMediaMessageHook
 		OnNotificationErrorHook   OnNotificationErrorHook
 		OnMessageStatusChangeHook OnMessageStatusChangeHook
+		OnPaymentStatusChangeHook OnPaymentStatusChangeHook
 		OnMessageReceivedHook     OnMessageReceivedHook
 	}
 
@@ -278,13 +302,37 @@ type (
 
 	// HandlerOptions is a struct that contains the options that can be passed to the NotificationHandler. Note that
 	// the options are optional. NotificationHandler can be used without any options set.
+	//
+	// To rotate the app secret without rejecting notifications, set Secret to the new secret and
+	// keep the old one in Secrets until the rotation is complete: the signatures are validated
+	// with Secret first, then with each of Secrets in order. OnSignatureMatch, when set, is
+	// called with the index of the secret that matched, see SignatureMatch.
+	//
+	// MaxBodySize and ReadTimeout limit the reading of the notification bodies, so that a
+	// misbehaving sender can not exhaust the memory or the connections of the service. They
+	// default to DefaultMaxBodySize and DefaultBodyReadTimeout, negative values disable them.
+	// Requests with a Content-Type other than JSON are rejected.
+	//
+	// ProcessingHooks are called with the ProcessingEvent of each notification and of each
+	// message, status and error it carries.
 	HandlerOptions struct {
 		BeforeFunc        BeforeFunc
 		AfterFunc         AfterFunc
 		ValidateSignature bool
 		Secret            string
+		Secrets           []string
+		OnSignatureMatch  OnSignatureMatch
+		MaxBodySize       int64
+		ReadTimeout       time.Duration
+		ProcessingHooks   []ProcessingHook
 	}
 
+	// OnSignatureMatch is called after the signature of a notification is validated. index is
+	// 0 when it matched HandlerOptions.Secret, i+1 when it matched HandlerOptions.Secrets[i]
+	// and NoSignatureMatch when none did. Use it to record which secret is in use, e.g. to know
+	// when an old secret can be removed.
+	OnSignatureMatch func(ctx context.Context, index int)
+
 	// VerificationRequest contains details sent by the whatsapp server during the verification process.
 	VerificationRequest struct {
 		Mode      string `json:"hub.mode"`
@@ -354,37 +402,20 @@ func AttachHooksToNotification(ctx context.Context, notification *Notification,
 		return nil
 	}
 
-	entries := notification.Entry
-	for _, entry := range entries {
-		entry := entry
-		if err := attachHooksToEntry(ctx, entry, hooks, heh); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func attachHooksToEntry(ctx context.Context, entry *Entry, hooks *Hooks, heh HooksErrorHandler) error {
-	eid := entry.ID
-	changes := entry.Changes
-	for _, change := range changes {
-		change := change
-		value := change.Value
-		if value == nil {
-			continue
-		}
-
-		if err := attachHooksToValue(ctx, eid, value, hooks, heh); err != nil {
-			return err
-		}
+	// a message or status batched more than once in the notification is dispatched once
+	seen := newDelivery()
+	seen.onDuplicate = func(kind ProcessingKind, typ string) {
+		reportDuplicate(ctx, kind, typ)
 	}
 
-	return nil
+	return notification.EachChange(func(entryID string, change *Change) error {
+		return attachHooksToValue(ctx, entryID, change.Value, hooks, heh, seen)
+	})
 }
 
 var (
 	ErrOnMessageStatusChangeHook = errors.New("on message status change hook error")
+	ErrOnPaymentStatusChangeHook = errors.New("on payment status change hook error")
 	ErrOnMessageHooks            = errors.New("on specific message hooks error")
 	ErrOnNotificationErrorHook   = errors.New("on notification error hook error")
 	ErrOnGlobalMessageHook       = errors.New("on global message hook error")
@@ -392,59 +423,93 @@ var (
 
 //nolint:cyclop
 func attachHooksToValue(ctx context.Context, id string, value *Value, hooks *Hooks,
-	hooksErrorHandler HooksErrorHandler,
+	hooksErrorHandler HooksErrorHandler, seen *delivery,
 ) error {
 	if hooks == nil || value == nil {
 		return nil
 	}
 
-	notificationCtx := &NotificationContext{
-		ID:       id,
-		Contacts: value.Contacts,
-		Metadata: value.Metadata,
-	}
+	notificationCtx := newNotificationContext(id, value)
+	statuses := seen.newStatuses(value.Statuses)
+	messages := seen.newMessages(value.Messages)
 
 	// nonFatalErrors is a slice of non-fatal errors that are collected from the hooks.
-	// can contain a maximum of 4 errors.
-	nonFatalErrors := make([]error, 0, 4) //nolint:gomnd
-
-	// call the Hooks
-	if hooks.OnNotificationErrorHook != nil {
-		for _, ev := range value.Errors {
-			ev := ev
-			if err := hooks.OnNotificationErrorHook(ctx, notificationCtx, ev); err != nil {
-				if IsFatalError(hooksErrorHandler(err)) {
-					return err
-				}
-				nonFatalErrors = append(nonFatalErrors, ErrOnNotificationErrorHook)
+	// can contain a maximum of 5 errors.
+	nonFatalErrors := make([]error, 0, 5) //nolint:gomnd
+
+	// call the Hooks, each error, status and message is reported to the processing hooks
+	for _, ev := range value.Errors {
+		ev := ev
+		var err error
+		done := startProcessing(ctx, ProcessingError, errorType(ev.Code))
+		if hooks.OnNotificationErrorHook != nil {
+			err = hooks.OnNotificationErrorHook(ctx, notificationCtx, ev)
+		}
+		done(err)
+		if err != nil {
+			if IsFatalError(hooksErrorHandler(err)) {
+				return err
 			}
+			nonFatalErrors = append(nonFatalErrors, ErrOnNotificationErrorHook)
 		}
 	}
 
-	if hooks.OnMessageStatusChangeHook != nil {
-		for _, sv := range value.Statuses {
-			sv := sv
-			if err := hooks.OnMessageStatusChangeHook(ctx, notificationCtx, sv); err != nil {
-				if IsFatalError(hooksErrorHandler(err)) {
-					return err
-				}
-				nonFatalErrors = append(nonFatalErrors, ErrOnMessageStatusChangeHook)
+	for _, sv := range statuses {
+		sv := sv
+		// payment statuses are reported as payments below
+		done := func(error) {}
+		if !sv.IsPayment() {
+			done = startProcessing(ctx, ProcessingStatus, sv.StatusValue)
+		}
+		var err error
+		if hooks.OnMessageStatusChangeHook != nil {
+			err = hooks.OnMessageStatusChangeHook(ctx, notificationCtx, sv)
+		}
+		done(err)
+		if err != nil {
+			if IsFatalError(hooksErrorHandler(err)) {
+				return err
 			}
+			nonFatalErrors = append(nonFatalErrors, ErrOnMessageStatusChangeHook)
 		}
 	}
 
-	for _, mv := range value.Messages {
+	for _, sv := range statuses {
+		sv := sv
+		if !sv.IsPayment() {
+			continue
+		}
+		var err error
+		done := startProcessing(ctx, ProcessingPayment, sv.StatusValue)
+		if hooks.OnPaymentStatusChangeHook != nil {
+			err = hooks.OnPaymentStatusChangeHook(ctx, notificationCtx, sv, sv.Payment)
+		}
+		done(err)
+		if err != nil {
+			if IsFatalError(hooksErrorHandler(err)) {
+				return err
+			}
+			nonFatalErrors = append(nonFatalErrors, ErrOnPaymentStatusChangeHook)
+		}
+	}
+
+	for _, mv := range messages {
 		mv := mv
+		done := startProcessing(ctx, ProcessingMessage, mv.Type)
 		if hooks.OnMessageReceivedHook != nil {
 			if err := hooks.OnMessageReceivedHook(ctx, notificationCtx, mv); err != nil {
 				if IsFatalError(hooksErrorHandler(err)) {
+					done(err)
+
 					return err
 				}
 				nonFatalErrors = append(nonFatalErrors, ErrOnGlobalMessageHook)
 			}
 		}
 
-		if err := attachHooksToMessage(ctx, notificationCtx, hooks, mv); err != nil {
+		err := attachHooksToMessage(ctx, notificationCtx, hooks, mv)
+		done(err)
+		if err != nil {
 			if IsFatalError(hooksErrorHandler(err)) {
 				return err
 			}
@@ -494,7 +559,7 @@ func attachHooksToMessage(ctx context.Context, nctx *NotificationContext, hooks
 		return hooks.OnButtonMessageHook(ctx, nctx, mctx, message.Button)
 
 	case AudioMessageType, VideoMessageType, ImageMessageType, DocumentMessageType, StickerMessageType:
-		return hooks.OnMediaMessageHook(ctx, nctx, mctx, message.Audio)
+		return hooks.OnMediaMessageHook(ctx, nctx, mctx, message.media())
 
 	case InteractiveMessageType:
 		return hooks.OnInteractiveMessageHook(ctx, nctx, mctx, message.Interactive)
@@ -509,7 +574,7 @@ func attachHooksToMessage(ctx context.Context, nctx *NotificationContext, hooks
 		if message.Referral != nil {
 			return hooks.OnReferralMessageHook(ctx, nctx, mctx, message.Text, message.Referral)
 		}
-		if mctx.Ctx != nil {
+		if mctx.Ctx != nil && mctx.Ctx.ReferredProduct != nil {
 			return hooks.OnProductEnquiryHook(ctx, nctx, mctx, message.Text)
 		}
 
@@ -565,9 +630,14 @@ func NotificationHandler(
 			notification = &Notification{}
 		)
 		ctx := request.Context()
+		if options != nil {
+			ctx = withProcessingHooks(ctx, options.ProcessingHooks)
+		}
+		done := startProcessing(ctx, ProcessingNotification, "")
 
 		defer func() {
 			buff.Reset()
+			done(err)
 			if options != nil {
 				if options.AfterFunc != nil {
 					options.AfterFunc(ctx, notification, err)
@@ -575,11 +645,13 @@ func NotificationHandler(
 			}
 		}()
 
-		if _, err = io.Copy(&buff, request.Body); err != nil && !errors.Is(err, io.EOF) {
-			writer.WriteHeader(http.StatusInternalServerError)
+		payload, status, err := readNotificationBody(writer, request, options)
+		if err != nil {
+			writer.WriteHeader(status)
 
 			return
 		}
+		buff.Write(payload)
 		request.Body = io.NopCloser(&buff)
 
 		if err = json.NewDecoder(&buff).Decode(notification); err != nil && !errors.Is(err, io.EOF) {
@@ -598,8 +670,7 @@ func NotificationHandler(
 		}
 
 		if options != nil && options.ValidateSignature {
-			signature, _ := ExtractSignatureFromHeader(request.Header)
-			if !ValidateSignature(buff.Bytes(), signature, options.Secret) {
+			if !options.validateSignature(ctx, payload, request.Header) {
 				if handleError(ctx, writer, request, neh, ErrInvalidSignature) {
 					return
 				}
@@ -678,6 +749,8 @@ func VerifySubscriptionHandler(verifier SubscriptionVerifier) http.Handler {
 			Token:     token,
 		}); err != nil {
 			writer.WriteHeader(http.StatusBadRequest)
+
+			return
 		}
 		writer.WriteHeader(http.StatusOK)
 		_, _ = writer.Write([]byte(challenge))
@@ -697,21 +770,51 @@ func VerifySubscriptionHandler(verifier SubscriptionVerifier) http.Handler {
 // you will end up with a different signature.
 // For example, the string äöå should be escaped to \u00e4\u00f6\u00e5.
 func ValidateSignature(payload []byte, signature, secret string) bool {
+	return SignatureMatch(payload, signature, secret) == 0
+}
+
+// NoSignatureMatch is returned by SignatureMatch when the signature matches none of the secrets.
+const NoSignatureMatch = -1
+
+// SignatureMatch validates the signature of the payload with each of the secrets, in order,
+// and returns the index of the first one that matches or NoSignatureMatch. It is used to
+// accept the signatures made with either the new or the old app secret during a rotation.
+// See ValidateSignature.
+func SignatureMatch(payload []byte, signature string, secrets ...string) int {
 	decodeSig, err := hex.DecodeString(signature)
 	if err != nil {
-		return false
+		return NoSignatureMatch
 	}
 
-	// Calculate the expected signature using the payload and secret
-	mac := hmac.New(sha256.New, []byte(secret))
-	_, err = mac.Write(payload)
-	if err != nil {
-		return false
+	for i, secret := range secrets {
+		// Calculate the expected signature using the payload and secret
+		mac := hmac.New(sha256.New, []byte(secret))
+		if _, err = mac.Write(payload); err != nil {
+			return NoSignatureMatch
+		}
+
+		// Compare the expected and actual signatures
+		if hmac.Equal(decodeSig, mac.Sum(nil)) {
+			return i
+		}
+	}
+
+	return NoSignatureMatch
+}
+
+// validateSignature validates the signature found in header with the secrets of the options
+// and reports the secret that matched to OnSignatureMatch.
+func (options *HandlerOptions) validateSignature(ctx context.Context, payload []byte, header http.Header) bool {
+	signature, _ := ExtractSignatureFromHeader(header)
+	secrets := make([]string, 0, len(options.Secrets)+1)
+	secrets = append(secrets, options.Secret)
+	secrets = append(secrets, options.Secrets...)
+	index := SignatureMatch(payload, signature, secrets...)
+	if options.OnSignatureMatch != nil {
+		options.OnSignatureMatch(ctx, index)
 	}
-	expectedSignature := mac.Sum(nil)
 
-	// Compare the expected and actual signatures
-	return hmac.Equal(decodeSig, expectedSignature)
+	return index != NoSignatureMatch
 }
 
 var ErrSignatureNotFound = errors.New("signature not found")
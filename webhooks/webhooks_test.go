@@ -22,11 +22,19 @@ package webhooks
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
 )
 
 func Example_newEventListener() {
@@ -57,6 +65,7 @@ func Example_newEventListener() {
 			OnMediaMessageHook:        nil,
 			OnNotificationErrorHook:   nil,
 			OnMessageStatusChangeHook: nil,
+			OnPaymentStatusChangeHook: nil,
 			OnMessageReceivedHook:     nil,
 		}),
 		WithSubscriptionVerifier(func(ctx context.Context, request *VerificationRequest) error {
@@ -313,6 +322,59 @@ func TestNotificationHandler_Options(t *testing.T) {
 	}
 }
 
+func TestPaymentStatusHook(t *testing.T) {
+	t.Parallel()
+	body := `{"object":"whatsapp_business_account","entry":[{"id":"WABA_ID","changes":[{"value":{` +
+		`"messaging_product":"whatsapp","metadata":{"display_phone_number":"PHONE_NUMBER",` +
+		`"phone_number_id":"PHONE_NUMBER_ID"},"statuses":[{"id":"wamid.ORDER","recipient_id":"919999999999",` +
+		`"status":"captured","timestamp":1700000000,"type":"payment","payment":{"reference_id":"order-7",` +
+		`"amount":{"value":21000,"offset":100},"currency":"INR","transaction":{"id":"pay_1","type":"upi",` +
+		`"status":"success","created_timestamp":1700000000,"updated_timestamp":1700000010}}},` +
+		`{"id":"wamid.TEXT","recipient_id":"919999999999","status":"delivered","timestamp":1700000000}]},` +
+		`"field":"messages"}]}]}`
+
+	var (
+		payments []*Payment
+		statuses int
+	)
+	hooks := &Hooks{
+		OnMessageStatusChangeHook: func(ctx context.Context, nctx *NotificationContext, status *Status) error {
+			statuses++
+
+			return nil
+		},
+		OnPaymentStatusChangeHook: func(ctx context.Context, nctx *NotificationContext, status *Status,
+			payment *Payment,
+		) error {
+			if status.StatusValue != models.PaymentStatusCaptured {
+				t.Errorf("status = %q, want %q", status.StatusValue, models.PaymentStatusCaptured)
+			}
+			payments = append(payments, payment)
+
+			return nil
+		},
+	}
+	h := NotificationHandler(hooks, NoOpNotificationErrorHandler, NoOpHooksErrorHandler, &HandlerOptions{})
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if statuses != 2 {
+		t.Errorf("OnMessageStatusChangeHook called %d times, want 2", statuses)
+	}
+	if len(payments) != 1 {
+		t.Fatalf("OnPaymentStatusChangeHook called %d times, want 1", len(payments))
+	}
+	payment := payments[0]
+	if payment.ReferenceID != "order-7" || payment.Amount.Float() != 210 ||
+		payment.Transaction.Status != models.TransactionStatusSuccess {
+		t.Errorf("payment = %+v", payment)
+	}
+}
+
 func TestExtractSignatureFromHeader(t *testing.T) {
 	t.Parallel()
 	type args struct {
@@ -361,3 +423,387 @@ func TestExtractSignatureFromHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestNotificationHandlerSignature(t *testing.T) {
+	t.Parallel()
+	const secret = "app-secret"
+	body := `{"object":"whatsapp_business_account","entry":[{"id":"WABA_ID","changes":[{"value":{` +
+		`"messaging_product":"whatsapp","messages":[{"from":"1","id":"wamid.1","type":"text",` +
+		`"text":{"body":"hi"}}]},"field":"messages"}]}]}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		wantErr   error
+	}{
+		{name: "valid signature", body: body, signature: signature},
+		{
+			name:      "tampered body",
+			body:      strings.Replace(body, "hi", "ho", 1),
+			signature: signature,
+			wantErr:   ErrInvalidSignature,
+		},
+		{name: "missing signature", body: body, wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var (
+				received bool
+				handled  error
+			)
+			hooks := &Hooks{
+				OnMessageReceivedHook: func(ctx context.Context, nctx *NotificationContext, message *Message) error {
+					received = true
+
+					return nil
+				},
+				OnTextMessageHook: func(ctx context.Context, nctx *NotificationContext, mctx *MessageContext,
+					text *Text,
+				) error {
+					return nil
+				},
+			}
+			neh := func(ctx context.Context, request *http.Request, err error) *NotificationErrHandlerResponse {
+				handled = err
+
+				return &NotificationErrHandlerResponse{StatusCode: http.StatusUnauthorized}
+			}
+			h := NotificationHandler(hooks, neh, NoOpHooksErrorHandler, &HandlerOptions{
+				ValidateSignature: true,
+				Secret:            secret,
+			})
+			request := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(tt.body))
+			if tt.signature != "" {
+				request.Header.Set(SignatureHeaderKey, tt.signature)
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, request)
+
+			if tt.wantErr == nil {
+				if handled != nil || !received || recorder.Code != http.StatusOK {
+					t.Errorf("error = %v, received = %v, status = %d, want the notification accepted",
+						handled, received, recorder.Code)
+				}
+
+				return
+			}
+			if !errors.Is(handled, tt.wantErr) || received || recorder.Code != http.StatusUnauthorized {
+				t.Errorf("error = %v, received = %v, status = %d, want %v and the notification rejected",
+					handled, received, recorder.Code, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySubscriptionHandler(t *testing.T) {
+	t.Parallel()
+	verifier := func(ctx context.Context, request *VerificationRequest) error {
+		if request.Mode != "subscribe" || request.Token != "verify-token" {
+			return errors.New("invalid verify token")
+		}
+
+		return nil
+	}
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "valid token", token: "verify-token", wantStatus: http.StatusOK, wantBody: "1158201444"},
+		{name: "bad token", token: "guess", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			target := "/webhook?hub.mode=subscribe&hub.challenge=1158201444&hub.verify_token=" + tt.token
+			recorder := httptest.NewRecorder()
+			VerifySubscriptionHandler(verifier).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+			if recorder.Code != tt.wantStatus || recorder.Body.String() != tt.wantBody {
+				t.Errorf("response = %d %q, want %d %q", recorder.Code, recorder.Body.String(),
+					tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestMediaMessageHook(t *testing.T) {
+	t.Parallel()
+	for _, typ := range []string{"audio", "video", "image", "document", "sticker"} {
+		typ := typ
+		t.Run(typ, func(t *testing.T) {
+			t.Parallel()
+			body := `{"object":"whatsapp_business_account","entry":[{"id":"WABA_ID","changes":[{"value":{` +
+				`"messaging_product":"whatsapp","messages":[{"from":"1","id":"wamid.1","type":"` + typ + `",` +
+				`"` + typ + `":{"id":"media_` + typ + `"}}]},"field":"messages"}]}]}`
+
+			var got *models.MediaInfo
+			hooks := &Hooks{
+				OnMediaMessageHook: func(ctx context.Context, nctx *NotificationContext, mctx *MessageContext,
+					media *models.MediaInfo,
+				) error {
+					got = media
+
+					return nil
+				},
+			}
+			h := NotificationHandler(hooks, NoOpNotificationErrorHandler, NoOpHooksErrorHandler, &HandlerOptions{})
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+
+			if got == nil || got.ID != "media_"+typ {
+				t.Errorf("OnMediaMessageHook() media = %+v, want media_%s", got, typ)
+			}
+		})
+	}
+}
+
+func TestProductEnquiryHook(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		context     string
+		wantEnquiry bool
+	}{
+		{name: "plain text"},
+		{name: "reply", context: `"context":{"from":"2","id":"wamid.0"},`},
+		{
+			name: "product enquiry",
+			context: `"context":{"from":"2","id":"wamid.0","referred_product":` +
+				`{"catalog_id":"catalog_1","product_retailer_id":"sku_1"}},`,
+			wantEnquiry: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			body := `{"object":"whatsapp_business_account","entry":[{"id":"WABA_ID","changes":[{"value":{` +
+				`"messaging_product":"whatsapp","messages":[{` + tt.context + `"from":"1","id":"wamid.1",` +
+				`"type":"text","text":{"body":"hi"}}]},"field":"messages"}]}]}`
+
+			var (
+				text    bool
+				product *ReferredProduct
+			)
+			hooks := &Hooks{
+				OnTextMessageHook: func(ctx context.Context, nctx *NotificationContext, mctx *MessageContext,
+					_ *Text,
+				) error {
+					text = true
+
+					return nil
+				},
+				OnProductEnquiryHook: func(ctx context.Context, nctx *NotificationContext, mctx *MessageContext,
+					_ *Text,
+				) error {
+					product = mctx.Ctx.ReferredProduct
+
+					return nil
+				},
+			}
+			h := NotificationHandler(hooks, NoOpNotificationErrorHandler, NoOpHooksErrorHandler, &HandlerOptions{})
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+
+			if tt.wantEnquiry {
+				if product == nil || product.ProductRetailerID != "sku_1" || text {
+					t.Errorf("referred product = %+v, text hook called = %v, want the enquiry about sku_1",
+						product, text)
+				}
+
+				return
+			}
+			if product != nil || !text {
+				t.Errorf("referred product = %+v, text hook called = %v, want a text message", product, text)
+			}
+		})
+	}
+}
+
+func TestMessageForwarding(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		message    string
+		want       Forwarding
+		forwarded  bool
+		frequently bool
+	}{
+		{
+			name:    "not forwarded",
+			message: `{"from":"1","id":"wamid.1","type":"text","text":{"body":"hi"}}`,
+			want:    NotForwarded,
+		},
+		{
+			name:      "forwarded",
+			message:   `{"context":{"forwarded":true},"from":"1","id":"wamid.2","type":"text","text":{"body":"hi"}}`,
+			want:      Forwarded,
+			forwarded: true,
+		},
+		{
+			name: "frequently forwarded",
+			message: `{"context":{"forwarded":true,"frequently_forwarded":true},"from":"1","id":"wamid.3",` +
+				`"type":"image","image":{"id":"media"}}`,
+			want:       FrequentlyForwarded,
+			forwarded:  true,
+			frequently: true,
+		},
+		{
+			name:    "reply",
+			message: `{"context":{"from":"2","id":"wamid.0"},"from":"1","id":"wamid.4","type":"text","text":{"body":"hi"}}`,
+			want:    NotForwarded,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			body := `{"object":"whatsapp_business_account","entry":[{"id":"WABA_ID","changes":[{"value":{` +
+				`"messaging_product":"whatsapp","messages":[` + tt.message + `]},"field":"messages"}]}]}`
+
+			var (
+				message *Message
+				mctx    *MessageContext
+			)
+			hooks := &Hooks{
+				OnMessageReceivedHook: func(ctx context.Context, nctx *NotificationContext, m *Message) error {
+					message = m
+
+					return nil
+				},
+				OnTextMessageHook: func(ctx context.Context, nctx *NotificationContext, m *MessageContext,
+					text *Text,
+				) error {
+					mctx = m
+
+					return nil
+				},
+				OnMediaMessageHook: func(ctx context.Context, nctx *NotificationContext, m *MessageContext,
+					media *models.MediaInfo,
+				) error {
+					if media == nil || media.ID != "media" {
+						t.Errorf("media = %+v, want the image", media)
+					}
+					mctx = m
+
+					return nil
+				},
+			}
+			h := NotificationHandler(hooks, NoOpNotificationErrorHandler, NoOpHooksErrorHandler, &HandlerOptions{})
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body)))
+
+			if message == nil || mctx == nil {
+				t.Fatalf("hooks not called")
+			}
+			if got := message.Forwarding(); got != tt.want {
+				t.Errorf("Message.Forwarding() = %q, want %q", got, tt.want)
+			}
+			if message.IsForwarded() != tt.forwarded || message.IsFrequentlyForwarded() != tt.frequently {
+				t.Errorf("Message.IsForwarded() = %v, IsFrequentlyForwarded() = %v, want %v, %v",
+					message.IsForwarded(), message.IsFrequentlyForwarded(), tt.forwarded, tt.frequently)
+			}
+			if mctx.IsForwarded() != tt.forwarded || mctx.IsFrequentlyForwarded() != tt.frequently {
+				t.Errorf("MessageContext.IsForwarded() = %v, IsFrequentlyForwarded() = %v, want %v, %v",
+					mctx.IsForwarded(), mctx.IsFrequentlyForwarded(), tt.forwarded, tt.frequently)
+			}
+		})
+	}
+}
+
+func sign(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignatureMatch(t *testing.T) {
+	t.Parallel()
+	payload := []byte(`{"object":"whatsapp_business_account"}`)
+	tests := []struct {
+		name      string
+		signature string
+		secrets   []string
+		want      int
+	}{
+		{name: "current secret", signature: sign(payload, "new"), secrets: []string{"new", "old"}, want: 0},
+		{name: "previous secret", signature: sign(payload, "old"), secrets: []string{"new", "old"}, want: 1},
+		{name: "unknown secret", signature: sign(payload, "other"), secrets: []string{"new", "old"}, want: -1},
+		{name: "malformed signature", signature: "not hex", secrets: []string{"new"}, want: -1},
+		{name: "no secrets", signature: sign(payload, "new"), want: -1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := SignatureMatch(payload, tt.signature, tt.secrets...); got != tt.want {
+				t.Errorf("SignatureMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventListener_SecretRotation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu      sync.Mutex
+		matches []int
+	)
+	listener := NewEventListener(
+		WithSignatureValidation("new", "old"),
+		WithSignatureMatchHook(func(ctx context.Context, index int) {
+			mu.Lock()
+			defer mu.Unlock()
+			matches = append(matches, index)
+		}),
+		WithNotificationErrorHandler(func(ctx context.Context, r *http.Request, err error) *NotificationErrHandlerResponse {
+			return &NotificationErrHandlerResponse{StatusCode: http.StatusUnauthorized}
+		}),
+	)
+	listener.GenericNotificationHandler(func(ctx context.Context, w http.ResponseWriter, n *Notification) error {
+		return nil
+	})
+
+	payload := []byte(`{"object":"whatsapp_business_account","entry":[]}`)
+	for _, handler := range []http.Handler{listener.NotificationHandler(), listener.GlobalHandler()} {
+		for secret, want := range map[string]int{"new": http.StatusOK, "old": http.StatusOK, "leaked": 401} {
+			request := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewReader(payload))
+			request.Header.Set(SignatureHeaderKey, "sha256="+sign(payload, secret))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != want {
+				t.Errorf("signed with %q: status = %d, want %d", secret, recorder.Code, want)
+			}
+		}
+	}
+
+	counts := map[int]int{}
+	for _, index := range matches {
+		counts[index]++
+	}
+	if want := map[int]int{0: 2, 1: 2, NoSignatureMatch: 2}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("signature matches = %v, want %v", counts, want)
+	}
+}
+
+func TestEventListenerFields(t *testing.T) {
+	t.Parallel()
+	listener := NewEventListener()
+	if fields := listener.Fields(); len(fields) != 0 {
+		t.Errorf("Fields() without hooks = %v, want none", fields)
+	}
+	listener.OnTextMessage(func(context.Context, *NotificationContext, *MessageContext, *Text) error {
+		return nil
+	})
+	if fields := listener.Fields(); len(fields) != 1 || fields[0] != FieldMessages {
+		t.Errorf("Fields() = %v, want [%s]", fields, FieldMessages)
+	}
+}
@@ -28,9 +28,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/SeamPay/whatsapp/clock"
 	whttp "github.com/SeamPay/whatsapp/http"
 	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/partner"
 	"github.com/SeamPay/whatsapp/qrcodes"
+	"github.com/SeamPay/whatsapp/ratelimit"
+	"github.com/SeamPay/whatsapp/subscriptions"
 )
 
 var ErrBadRequestFormat = errors.New("bad request")
@@ -94,9 +98,16 @@ type (
 		Product  string             `json:"messaging_product,omitempty"`
 		Contacts []*ResponseContact `json:"contacts,omitempty"`
 		Messages []*MessageID       `json:"messages,omitempty"`
+
+		// Fallback is set when a template was sent in place of the message, see
+		// WithReEngagementFallback.
+		Fallback *ReEngagementFallback `json:"-"`
 	}
+	// MessageID is the ID (wamid) of a sent message. MessageStatus is only returned by the
+	// Marketing Messages Lite API, see SendMarketingTemplate.
 	MessageID struct {
-		ID string `json:"id,omitempty"`
+		ID            string `json:"id,omitempty"`
+		MessageStatus string `json:"message_status,omitempty"`
 	}
 
 	ResponseContact struct {
@@ -113,27 +124,23 @@ type (
 
 	// Client includes the http client, base url, apiVersion, access token, phone number id,
 	// and whatsapp business account id.
-	// which are used to make requests to the whatsapp api.
+	// which are used to make requests to the whatsapp api. It is created once and shared,
+	// the calls only take the message content, see WithOverrides to change the credentials
+	// or the phone number of a single call.
 	// Example:
 	// 	client := whatsapp.NewClient(
 	// 		whatsapp.WithHTTPClient(http.DefaultClient),
 	// 		whatsapp.WithBaseURL(whatsapp.BaseURL),
-	// 		whatsapp.WithVersion(whatsapp.LowestSupportedVersion),
+	// 		whatsapp.WithVersion(whatsapp.DefaultAPIVersion),
 	// 		whatsapp.WithAccessToken("access_token"),
 	// 		whatsapp.WithPhoneNumberID("phone_number_id"),
 	// 		whatsapp.WithBusinessAccountID("whatsapp_business_account_id"),
 	// 	)
-	//  // create a text message
-	//  message := whatsapp.TextMessage{
-	//  	Recipient: "<phone_number>",
-	//  	Message:   "Hello World",
-	//      PreviewURL: false,
-	//  }
-	// // send the text message
-	//  _, err := client.SendTextMessage(context.Background(), message)
-	//  if err != nil {
-	//  	log.Fatal(err)
-	//  }
+	//	// send a text message
+	//	_, err := client.SendText(context.Background(), "<phone_number>", "Hello World")
+	//	if err != nil {
+	//		log.Fatal(err)
+	//	}
 	Client struct {
 		rwm               *sync.RWMutex
 		http              *http.Client
@@ -142,8 +149,42 @@ type (
 		apiVersion        string
 		accessToken       string
 		phoneNumberID     string
+		senderNumbers     map[string]string
 		businessAccountID string
 		hooks             []whttp.Hook
+		limiter           ratelimit.Waiter
+		breaker           *whttp.CircuitBreaker
+		etags             whttp.ETagStore
+		transport         transportOptions
+		timeouts          Timeouts
+		idempotency       *IdempotencyConfig
+		appSecret         string
+		tokenSource       whttp.TokenSource
+		versionWarning    VersionWarningFunc
+		clock             clock.Clock
+		middlewares       []whttp.Middleware
+		eventHooks        []whttp.EventHook
+		usageHooks        []whttp.UsageHook
+		hookErrorHandler  whttp.HookErrorHandler
+		beforeHooks       []whttp.BeforeHook
+		logging           func(secrets ...string) whttp.Middleware
+		audit             AuditSink
+		consent           *ConsentManager
+		undeliverable     *UndeliverableRegistry
+		identities        *IdentityTracker
+		correlations      *CorrelationTracker
+		textTemplates     *TextTemplates
+		localization      *Bundle
+		transcoder        Transcoder
+		images            *ImageProcessing
+		baseURLs          *BaseURLs
+		errorCounter      *whttp.ErrorCounter
+		preflight         bool
+		templates         *MetadataCache
+		reEngagement      *ReEngagementConfig
+		windows           *WindowTracker
+		usage             *whttp.UsageTracker
+		sender            whttp.Sender
 	}
 
 	ClientOption func(*Client)
@@ -155,6 +196,32 @@ func WithHTTPClient(http *http.Client) ClientOption {
 	}
 }
 
+// WithTokenSource sets the source of the access token. The token is fetched for every
+// request and takes precedence over the token set with WithAccessToken. Wrap sources that
+// are expensive to call with whttp.NewReuseTokenSource, the cached token is then refreshed
+// when the API rejects it.
+func WithTokenSource(source whttp.TokenSource) ClientOption {
+	return func(client *Client) {
+		client.tokenSource = source
+	}
+}
+
+// WithClock sets the clock used by the client. Components passed to the client, like the
+// rate limiter and the circuit breaker, take their own clock in their configuration.
+func WithClock(c clock.Clock) ClientOption {
+	return func(client *Client) {
+		client.clock = clock.OrSystem(c)
+	}
+}
+
+// WithAppSecret sets the app secret used to sign every request with appsecret_proof.
+// Apps with "Require App Secret" enabled reject server calls without it.
+func WithAppSecret(appSecret string) ClientOption {
+	return func(client *Client) {
+		client.appSecret = appSecret
+	}
+}
+
 func WithBaseURL(baseURL string) ClientOption {
 	return func(client *Client) {
 		client.baseURL = baseURL
@@ -191,26 +258,227 @@ func WithHooks(hooks ...whttp.Hook) ClientOption {
 	}
 }
 
+// WithRateLimiter sets a ratelimit.Waiter that is consulted before each message is sent.
+// Sends that would exceed the throughput or pair rate limits of the phone number are
+// queued locally until they are allowed. See ratelimit.NewLimiter.
+//
+// When the limiter is also a ratelimit.Throttler, like *ratelimit.Limiter, it is throttled
+// each time a message is rejected because the throughput was exceeded.
+func WithRateLimiter(limiter ratelimit.Waiter) ClientOption {
+	return func(client *Client) {
+		client.limiter = limiter
+	}
+}
+
+// WithCircuitBreaker puts the whttp.CircuitBreaker in front of all the requests sent by the
+// client. It wraps the transport of the http client set by WithHTTPClient, the http client
+// passed by the caller is not modified.
+func WithCircuitBreaker(breaker *whttp.CircuitBreaker) ClientOption {
+	return func(client *Client) {
+		client.breaker = breaker
+	}
+}
+
+// WithMiddlewares sets the middlewares that wrap every request sent by the client. They are
+// applied in the given order, the first middleware is the outermost. See whttp.Chain.
+func WithMiddlewares(middlewares ...whttp.Middleware) ClientOption {
+	return func(client *Client) {
+		client.middlewares = middlewares
+	}
+}
+
+// WithEventHooks sets hooks that receive a whttp.Event for every request attempt made by the
+// client, with its name, attempt number, duration, error and outcome.
+func WithEventHooks(hooks ...whttp.EventHook) ClientOption {
+	return func(client *Client) {
+		client.eventHooks = hooks
+	}
+}
+
+// WithErrorCounter counts the errors returned by the API by Graph error code in counter.
+// Use Client.ErrorCounts to read them, see whttp.ErrorCounter.
+func WithErrorCounter(counter *whttp.ErrorCounter) ClientOption {
+	return func(client *Client) {
+		client.errorCounter = counter
+	}
+}
+
+// WithUsageHooks sets hooks that are called with the rate limit usage reported in the
+// X-App-Usage and X-Business-Use-Case-Usage headers of every response, so that senders can
+// slow down before the API starts rejecting calls. See also Client.Usage.
+func WithUsageHooks(hooks ...whttp.UsageHook) ClientOption {
+	return func(client *Client) {
+		client.usageHooks = hooks
+	}
+}
+
+// WithBeforeHooks sets hooks that are called before every request is built. They can modify
+// the request or abort it by returning an error, see whttp.BeforeHook. They run before the
+// idempotency check, so changes to the payload are reflected in the idempotency key.
+func WithBeforeHooks(hooks ...whttp.BeforeHook) ClientOption {
+	return func(client *Client) {
+		client.beforeHooks = hooks
+	}
+}
+
+// WithHookErrorHandler sets the handler that receives the panics of the hooks of the client
+// as *whttp.HookError. Panicking hooks never take down the caller, only a panicking before hook
+// fails its request. Without a handler the panics are silently recovered.
+func WithHookErrorHandler(handler whttp.HookErrorHandler) ClientOption {
+	return func(client *Client) {
+		client.hookErrorHandler = handler
+	}
+}
+
+// WithETagCache makes the GET requests sent by the client conditional, the responses are cached
+// in store and served from it when the API responds with 304 Not Modified. See whttp.ETagTransport.
+// Use whttp.NewMemoryETagStore for an in memory store.
+func WithETagCache(store whttp.ETagStore) ClientOption {
+	return func(client *Client) {
+		client.etags = store
+	}
+}
+
 func NewClient(opts ...ClientOption) *Client {
 	client := &Client{
 		rwm:               &sync.RWMutex{},
 		http:              http.DefaultClient,
 		debug:             true,
 		baseURL:           BaseURL,
-		apiVersion:        "v16.0",
+		apiVersion:        DefaultAPIVersion,
 		accessToken:       "",
 		phoneNumberID:     "",
+		senderNumbers:     nil,
 		businessAccountID: "",
 		hooks:             nil,
+		limiter:           nil,
+		breaker:           nil,
+		etags:             nil,
+		transport:         transportOptions{},
+		timeouts:          *DefaultTimeouts(),
+		idempotency:       nil,
+		appSecret:         "",
+		tokenSource:       nil,
+		versionWarning:    nil,
+		clock:             clock.System(),
+		middlewares:       nil,
+		eventHooks:        nil,
+		usageHooks:        nil,
+		hookErrorHandler:  nil,
+		beforeHooks:       nil,
+		logging:           nil,
+		audit:             nil,
+		consent:           nil,
+		undeliverable:     nil,
+		identities:        nil,
+		correlations:      nil,
+		textTemplates:     nil,
+		localization:      nil,
+		transcoder:        nil,
+		images:            nil,
+		baseURLs:          nil,
+		errorCounter:      nil,
+		preflight:         false,
+		templates:         nil,
+		reEngagement:      nil,
+		windows:           nil,
+		usage:             nil,
+		sender:            nil,
 	}
 
 	for _, opt := range opts {
 		opt(client)
 	}
 
+	if client.versionWarning != nil {
+		if err := CheckVersion(client.apiVersion, client.clock.Now()); err != nil {
+			client.versionWarning(client.apiVersion, err)
+		}
+	}
+
+	client.usage = whttp.NewUsageTracker(client.clock, client.usageHooks...)
+	client.http = client.configureHTTPClient()
+
+	middlewares := make([]whttp.Middleware, 0, len(client.middlewares)+17)
+	if client.hookErrorHandler != nil {
+		middlewares = append(middlewares, whttp.HookErrorHandlerMiddleware(client.hookErrorHandler))
+	}
+	middlewares = append(middlewares, timeoutMiddleware(client.timeouts))
+	if client.baseURLs != nil {
+		middlewares = append(middlewares, client.baseURLs.Middleware())
+	}
+	if len(client.beforeHooks) > 0 {
+		middlewares = append(middlewares, whttp.BeforeHooksMiddleware(client.beforeHooks...))
+	}
+	if client.reEngagement != nil && client.reEngagement.Template != nil {
+		middlewares = append(middlewares, reEngagementMiddleware(client.reEngagement))
+	}
+	if client.preflight {
+		if client.templates == nil {
+			client.templates = NewMetadataCache(client, nil)
+		}
+		middlewares = append(middlewares, templatePreflightMiddleware(client.templates))
+	}
+	if client.idempotency != nil && client.idempotency.Store != nil {
+		middlewares = append(middlewares, idempotencyMiddleware(client.idempotency))
+	}
+	if client.audit != nil {
+		middlewares = append(middlewares, auditMiddleware(client.audit, client.clock.Now))
+	}
+	if client.consent != nil {
+		middlewares = append(middlewares, client.consent.Middleware())
+	}
+	if client.undeliverable != nil {
+		middlewares = append(middlewares, client.undeliverable.Middleware())
+	}
+	if client.identities != nil {
+		middlewares = append(middlewares, client.identities.Middleware())
+	}
+	if client.correlations != nil {
+		middlewares = append(middlewares, client.correlations.Middleware())
+	}
+	if throttler, ok := client.limiter.(ratelimit.Throttler); ok {
+		middlewares = append(middlewares, throttleMiddleware(throttler))
+	}
+	middlewares = append(middlewares, client.middlewares...)
+	if client.tokenSource != nil {
+		middlewares = append(middlewares, whttp.TokenSourceMiddleware(overrideTokenSource{client.tokenSource}))
+	}
+	if client.appSecret != "" {
+		middlewares = append(middlewares, whttp.AppSecretProofMiddleware(client.appSecret))
+	}
+	eventHooks := client.eventHooks
+	if client.errorCounter != nil {
+		eventHooks = append(eventHooks[:len(eventHooks):len(eventHooks)], client.errorCounter.EventHook())
+	}
+	if len(eventHooks) > 0 {
+		middlewares = append(middlewares, whttp.EventMiddleware(eventHooks...))
+	}
+	if client.logging != nil {
+		middlewares = append(middlewares, client.logging(client.appSecret))
+	}
+
+	client.sender = whttp.Chain(whttp.NewSender(client.http, client.hooks...), middlewares...)
+
 	return client
 }
 
+// ErrorCounts returns the errors returned by the API by Graph error code, the most frequent
+// first. It is empty unless the client was created with WithErrorCounter.
+func (client *Client) ErrorCounts() []whttp.ErrorCount {
+	if client.errorCounter == nil {
+		return nil
+	}
+
+	return client.errorCounter.Snapshot()
+}
+
+// Usage returns the latest rate limit usage reported by the API, or nil if no response
+// has reported it yet.
+func (client *Client) Usage() *whttp.Usage {
+	return client.usage.Usage()
+}
+
 type clientContext struct {
 	baseURL           string
 	apiVersion        string
@@ -219,17 +487,66 @@ type clientContext struct {
 	businessAccountID string
 }
 
-func (client *Client) context() *clientContext {
+// context returns the configuration used for a call made with ctx, that is the client
+// configuration with the Overrides set on ctx applied.
+func (client *Client) context(ctx context.Context) *clientContext {
 	client.rwm.RLock()
-	defer client.rwm.RUnlock()
-
-	return &clientContext{
+	cctx := &clientContext{
 		baseURL:           client.baseURL,
 		apiVersion:        client.apiVersion,
 		accessToken:       client.accessToken,
 		phoneNumberID:     client.phoneNumberID,
 		businessAccountID: client.businessAccountID,
 	}
+	numbers := client.senderNumbers
+	client.rwm.RUnlock()
+
+	if overrides, ok := OverridesFromContext(ctx); ok {
+		overrides.apply(cctx)
+	}
+	if phoneNumberID, ok := numbers[cctx.phoneNumberID]; ok {
+		cctx.phoneNumberID = phoneNumberID
+	}
+
+	return cctx
+}
+
+// requestContext returns the RequestContext of the messages sent with cctx.
+func (cctx *clientContext) requestContext() *RequestContext {
+	return &RequestContext{
+		BaseURL:       cctx.baseURL,
+		AccessToken:   cctx.accessToken,
+		PhoneNumberID: cctx.phoneNumberID,
+		ApiVersion:    cctx.apiVersion,
+	}
+}
+
+// waitRateLimit blocks until the rate limiter, if set, allows a message to be sent
+// from the phone number to the recipient.
+func (client *Client) waitRateLimit(ctx context.Context, phoneNumberID, recipient string) error {
+	if client.limiter == nil {
+		return nil
+	}
+
+	if err := client.limiter.Wait(ctx, phoneNumberID, recipient); err != nil {
+		return fmt.Errorf("client: %w", err)
+	}
+
+	return nil
+}
+
+// accessTokenFor returns the access token to use for a request, it is fetched from the
+// token source when one is set.
+func (client *Client) accessTokenFor(ctx context.Context) (string, error) {
+	if client.tokenSource == nil {
+		return client.context(ctx).accessToken, nil
+	}
+	token, err := overrideTokenSource{client.tokenSource}.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("token source: %w", err)
+	}
+
+	return token, nil
 }
 
 func (client *Client) SetAccessToken(accessToken string) {
@@ -257,19 +574,16 @@ type TextMessage struct {
 
 // SendTextMessage sends a text message to a WhatsApp Business Account.
 func (client *Client) SendTextMessage(ctx context.Context, recipient string,
-	message *TextMessage,
+	message *TextMessage, options ...SendOption,
 ) (*ResponseMessage, error) {
-	cctx := client.context()
-	request := &SendTextRequest{
-		BaseURL:       cctx.baseURL,
-		AccessToken:   cctx.accessToken,
-		PhoneNumberID: cctx.phoneNumberID,
-		ApiVersion:    cctx.apiVersion,
-		Recipient:     recipient,
-		Message:       message.Message,
-		PreviewURL:    message.PreviewURL,
-	}
-	resp, err := SendText(ctx, client.http, request, client.hooks...)
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
+	resp, err := sendText(ctx, opts.sender(client.sender), cctx.requestContext(), recipient, message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send text message: %w", err)
 	}
@@ -277,23 +591,26 @@ func (client *Client) SendTextMessage(ctx context.Context, recipient string,
 	return resp, nil
 }
 
+// SendText sends a text message to recipient. The options set the other fields of the
+// message, for example WithPreviewURL.
+func (client *Client) SendText(ctx context.Context, recipient, text string,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	return client.SendMessage(ctx, models.NewMessage(recipient, models.WithText(text)), options...)
+}
+
 // SendLocationMessage sends a location message to a WhatsApp Business Account.
 func (client *Client) SendLocationMessage(ctx context.Context, recipient string,
-	message *models.Location,
+	message *models.Location, options ...SendOption,
 ) (*ResponseMessage, error) {
-	request := &SendLocationRequest{
-		BaseURL:       client.baseURL,
-		AccessToken:   client.accessToken,
-		PhoneNumberID: client.phoneNumberID,
-		ApiVersion:    client.apiVersion,
-		Recipient:     recipient,
-		Name:          message.Name,
-		Address:       message.Address,
-		Latitude:      message.Latitude,
-		Longitude:     message.Longitude,
-	}
-
-	resp, err := SendLocation(ctx, client.http, request, client.hooks...)
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
+	resp, err := sendLocation(ctx, opts.sender(client.sender), cctx.requestContext(), recipient, message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send location message: %w", err)
 	}
@@ -306,19 +623,17 @@ type ReactMessage struct {
 	Emoji     string
 }
 
-func (client *Client) React(ctx context.Context, recipient string, req *ReactMessage) (*ResponseMessage, error) {
-	cctx := client.context()
-	request := &ReactRequest{
-		BaseURL:       cctx.baseURL,
-		AccessToken:   cctx.accessToken,
-		PhoneNumberID: cctx.phoneNumberID,
-		ApiVersion:    cctx.apiVersion,
-		Recipient:     recipient,
-		MessageID:     req.MessageID,
-		Emoji:         req.Emoji,
-	}
-
-	resp, err := React(ctx, client.http, request, client.hooks...)
+func (client *Client) React(ctx context.Context, recipient string, req *ReactMessage,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
+	resp, err := react(ctx, opts.sender(client.sender), cctx.requestContext(), recipient, req)
 	if err != nil {
 		return nil, fmt.Errorf("react: %w", err)
 	}
@@ -339,25 +654,16 @@ type MediaMessage struct {
 // first upload your media asset to our servers and capture the returned media ID. If using link, your asset must
 // be on a publicly accessible server or the message will fail to send.
 func (client *Client) SendMedia(ctx context.Context, recipient string, req *MediaMessage,
-	cacheOptions *CacheOptions,
+	cacheOptions *CacheOptions, options ...SendOption,
 ) (*ResponseMessage, error) {
-	cctx := client.context()
-	request := &SendMediaRequest{
-		BaseURL:       cctx.baseURL,
-		AccessToken:   cctx.accessToken,
-		PhoneNumberID: cctx.phoneNumberID,
-		ApiVersion:    cctx.apiVersion,
-		Recipient:     recipient,
-		Type:          req.Type,
-		MediaID:       req.MediaID,
-		MediaLink:     req.MediaLink,
-		Caption:       req.Caption,
-		Filename:      req.Filename,
-		Provider:      req.Provider,
-		CacheOptions:  cacheOptions,
-	}
-
-	resp, err := SendMedia(ctx, client.http, request, client.hooks...)
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
+	resp, err := sendMedia(ctx, opts.sender(client.sender), cctx.requestContext(), recipient, req, cacheOptions)
 	if err != nil {
 		return nil, fmt.Errorf("client send media: %w", err)
 	}
@@ -366,7 +672,7 @@ func (client *Client) SendMedia(ctx context.Context, recipient string, req *Medi
 }
 
 // ReplyMessage is a message that is sent as a reply to a previous message. The previous message's ID
-// is needed and is set as Context in ReplyRequest.
+// is needed and is set as Context.
 // Content is the message content. It can be a Text, Location, MediaInformation, Template, or Contact.
 type ReplyMessage struct {
 	Context string
@@ -374,20 +680,17 @@ type ReplyMessage struct {
 	Content any
 }
 
-func (client *Client) Reply(ctx context.Context, recipient string, req *ReplyMessage) (*ResponseMessage, error) {
-	cctx := client.context()
-	request := &ReplyRequest{
-		BaseURL:       cctx.baseURL,
-		AccessToken:   cctx.accessToken,
-		PhoneNumberID: cctx.phoneNumberID,
-		ApiVersion:    cctx.apiVersion,
-		Recipient:     recipient,
-		Context:       req.Context,
-		MessageType:   req.Type,
-		Content:       req.Content,
-	}
-
-	resp, err := Reply(ctx, client.http, request, client.hooks...)
+func (client *Client) Reply(ctx context.Context, recipient string, req *ReplyMessage,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
+	resp, err := reply(ctx, opts.sender(client.sender), cctx.requestContext(), recipient, req)
 	if err != nil {
 		return nil, fmt.Errorf("client reply: %w", err)
 	}
@@ -395,20 +698,19 @@ func (client *Client) Reply(ctx context.Context, recipient string, req *ReplyMes
 	return resp, nil
 }
 
-func (client *Client) SendContacts(ctx context.Context, recipient string, contacts []*models.Contact) (
+func (client *Client) SendContacts(ctx context.Context, recipient string, contacts []*models.Contact,
+	options ...SendOption,
+) (
 	*ResponseMessage, error,
 ) {
-	cctx := client.context()
-	req := &SendContactRequest{
-		BaseURL:       cctx.baseURL,
-		AccessToken:   cctx.accessToken,
-		PhoneNumberID: cctx.phoneNumberID,
-		ApiVersion:    cctx.apiVersion,
-		Recipient:     recipient,
-		Contacts:      contacts,
-	}
-
-	resp, err := SendContact(ctx, client.http, req, client.hooks...)
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
+	resp, err := sendContact(ctx, opts.sender(client.sender), cctx.requestContext(), recipient, contacts)
 	if err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
@@ -424,7 +726,7 @@ func (client *Client) MarkMessageRead(ctx context.Context, messageID string) (*S
 		MessageID:        messageID,
 	}
 
-	cctx := client.context()
+	cctx := client.context(ctx)
 
 	reqCtx := &whttp.RequestContext{
 		Name:       "mark read",
@@ -442,13 +744,12 @@ func (client *Client) MarkMessageRead(ctx context.Context, messageID string) (*S
 		Payload: reqBody,
 	}
 
-	var success StatusResponse
-	err := whttp.Do(ctx, client.http, params, &success, client.hooks...)
+	success, err := whttp.SendTyped[StatusResponse](ctx, client.sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
 
-	return &success, nil
+	return success, nil
 }
 
 type Template struct {
@@ -477,10 +778,21 @@ type InteractiveTemplateRequest struct {
 //
 // These buttons can be attached to text messages or media messages. Once your interactive message templates have been
 // created and approved, you can use them in notification messages as well as customer service/care messages.
-func (client *Client) SendInteractiveTemplate(ctx context.Context, recipient string, req *InteractiveTemplateRequest) (
+func (client *Client) SendInteractiveTemplate(ctx context.Context, recipient string,
+	req *InteractiveTemplateRequest, options ...SendOption,
+) (
 	*ResponseMessage, error,
 ) {
-	cctx := client.context()
+	if err := models.ValidateLanguageCode(req.LanguageCode); err != nil {
+		return nil, fmt.Errorf("send template: %w", err)
+	}
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
 	tmpLanguage := &models.TemplateLanguage{
 		Policy: req.LanguagePolicy,
 		Code:   req.LanguageCode,
@@ -509,13 +821,12 @@ func (client *Client) SendInteractiveTemplate(ctx context.Context, recipient str
 		},
 		Bearer: cctx.accessToken,
 	}
-	var message ResponseMessage
-	err := whttp.Do(ctx, client.http, params, &message, client.hooks...)
+	message, err := whttp.SendTyped[ResponseMessage](ctx, opts.sender(client.sender), params)
 	if err != nil {
 		return nil, fmt.Errorf("send template: %w", err)
 	}
 
-	return &message, nil
+	return message, nil
 }
 
 type MediaTemplateRequest struct {
@@ -528,10 +839,21 @@ type MediaTemplateRequest struct {
 
 // SendMediaTemplate sends a media template message to the recipient. This kind of template message has a media
 // message as a header. This is its main distinguishing feature from the text based template message.
-func (client *Client) SendMediaTemplate(ctx context.Context, recipient string, req *MediaTemplateRequest) (
+func (client *Client) SendMediaTemplate(ctx context.Context, recipient string, req *MediaTemplateRequest,
+	options ...SendOption,
+) (
 	*ResponseMessage, error,
 ) {
-	cctx := client.context()
+	if err := models.ValidateLanguageCode(req.LanguageCode); err != nil {
+		return nil, fmt.Errorf("client: send media template: %w", err)
+	}
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
 	tmpLanguage := &models.TemplateLanguage{
 		Policy: req.LanguagePolicy,
 		Code:   req.LanguageCode,
@@ -563,13 +885,12 @@ func (client *Client) SendMediaTemplate(ctx context.Context, recipient string, r
 		Bearer: cctx.accessToken,
 	}
 
-	var message ResponseMessage
-	err := whttp.Do(ctx, client.http, params, &message, client.hooks...)
+	message, err := whttp.SendTyped[ResponseMessage](ctx, opts.sender(client.sender), params)
 	if err != nil {
 		return nil, fmt.Errorf("client: send media template: %w", err)
 	}
 
-	return &message, nil
+	return message, nil
 }
 
 type TextTemplateRequest struct {
@@ -581,10 +902,21 @@ type TextTemplateRequest struct {
 
 // SendTextTemplate sends a text template message to the recipient. This kind of template message has a text
 // message as a header. This is its main distinguishing feature from the media based template message.
-func (client *Client) SendTextTemplate(ctx context.Context, recipient string, req *TextTemplateRequest) (
+func (client *Client) SendTextTemplate(ctx context.Context, recipient string, req *TextTemplateRequest,
+	options ...SendOption,
+) (
 	*ResponseMessage, error,
 ) {
-	cctx := client.context()
+	if err := models.ValidateLanguageCode(req.LanguageCode); err != nil {
+		return nil, fmt.Errorf("client: send text template: %w", err)
+	}
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
 	tmpLanguage := &models.TemplateLanguage{
 		Policy: req.LanguagePolicy,
 		Code:   req.LanguageCode,
@@ -609,13 +941,12 @@ func (client *Client) SendTextTemplate(ctx context.Context, recipient string, re
 		Bearer: cctx.accessToken,
 	}
 
-	var message ResponseMessage
-	err := whttp.Do(ctx, client.http, params, &message, client.hooks...)
+	message, err := whttp.SendTyped[ResponseMessage](ctx, opts.sender(client.sender), params)
 	if err != nil {
 		return nil, fmt.Errorf("client: send text template: %w", err)
 	}
 
-	return &message, nil
+	return message, nil
 }
 
 // SendTemplate sends a template message to the recipient. There are at the moment three types of templates messages
@@ -624,21 +955,20 @@ func (client *Client) SendTextTemplate(ctx context.Context, recipient string, re
 // can have any of the above as a Header and also have a list of buttons that the user can interact with.
 // You can use models.NewTextTemplate, models.NewMediaTemplate and models.NewInteractiveTemplate to create a Template.
 // These are helper functions that will make your life easier.
-func (client *Client) SendTemplate(ctx context.Context, recipient string, req *Template) (*ResponseMessage, error) {
-	cctx := client.context()
-	request := &SendTemplateRequest{
-		BaseURL:                cctx.baseURL,
-		AccessToken:            cctx.accessToken,
-		PhoneNumberID:          cctx.phoneNumberID,
-		ApiVersion:             cctx.apiVersion,
-		Recipient:              recipient,
-		TemplateLanguageCode:   req.LanguageCode,
-		TemplateLanguagePolicy: req.LanguagePolicy,
-		TemplateName:           req.Name,
-		TemplateComponents:     req.Components,
-	}
-
-	resp, err := SendTemplate(ctx, client.http, request, client.hooks...)
+func (client *Client) SendTemplate(ctx context.Context, recipient string, req *Template,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	if err := models.ValidateLanguageCode(req.LanguageCode); err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
+	resp, err := sendTemplate(ctx, opts.sender(client.sender), cctx.requestContext(), recipient, req)
 	if err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
@@ -647,10 +977,18 @@ func (client *Client) SendTemplate(ctx context.Context, recipient string, req *T
 }
 
 // SendInteractiveMessage sends an interactive message to the recipient.
-func (client *Client) SendInteractiveMessage(ctx context.Context, recipient string, req *models.Interactive) (
+func (client *Client) SendInteractiveMessage(ctx context.Context, recipient string, req *models.Interactive,
+	options ...SendOption,
+) (
 	*ResponseMessage, error,
 ) {
-	cctx := client.context()
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, recipient); err != nil {
+		return nil, err
+	}
 	template := &models.Message{
 		Product:       messagingProduct,
 		To:            recipient,
@@ -674,13 +1012,56 @@ func (client *Client) SendInteractiveMessage(ctx context.Context, recipient stri
 		},
 		Bearer: cctx.accessToken,
 	}
-	var message ResponseMessage
-	err := whttp.Do(ctx, client.http, params, &message, client.hooks...)
+	message, err := whttp.SendTyped[ResponseMessage](ctx, opts.sender(client.sender), params)
 	if err != nil {
 		return nil, fmt.Errorf("send interactive: %w", err)
 	}
 
-	return &message, nil
+	return message, nil
+}
+
+// SendMessage sends a message built with the models package. It is the most general send
+// method, the messaging product and recipient type are set when empty.
+func (client *Client) SendMessage(ctx context.Context, message *models.Message,
+	options ...SendOption,
+) (*ResponseMessage, error) {
+	if message == nil {
+		return nil, fmt.Errorf("send message: %w: nil message", ErrBadRequestFormat)
+	}
+	opts := newSendOptions(options)
+	ctx, cancel := opts.context(ctx)
+	defer cancel()
+	cctx := client.context(ctx)
+	if err := client.waitRateLimit(ctx, cctx.phoneNumberID, message.To); err != nil {
+		return nil, err
+	}
+	payload := *message
+	if payload.Product == "" {
+		payload.Product = messagingProduct
+	}
+	if payload.RecipientType == "" {
+		payload.RecipientType = individualRecipientType
+	}
+	reqCtx := &whttp.RequestContext{
+		Name:       "send message",
+		BaseURL:    cctx.baseURL,
+		ApiVersion: cctx.apiVersion,
+		SenderID:   cctx.phoneNumberID,
+		Endpoints:  []string{"messages"},
+	}
+	params := &whttp.Request{
+		Context: reqCtx,
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Bearer:  cctx.accessToken,
+		Payload: &payload,
+	}
+	response, err := whttp.SendTyped[ResponseMessage](ctx, opts.sender(client.sender), params)
+	if err != nil {
+		return nil, fmt.Errorf("send message: %w", err)
+	}
+
+	return response, nil
 }
 
 ////////////// QrCode
@@ -692,14 +1073,14 @@ func (client *Client) CreateQrCode(ctx context.Context, message *qrcodes.CreateR
 		PrefilledMessage: message.PrefilledMessage,
 		ImageFormat:      message.ImageFormat,
 	}
-	cctx := client.context()
+	cctx := client.context(ctx)
 	rctx := &qrcodes.RequestContext{
 		BaseURL:     cctx.baseURL,
 		PhoneID:     cctx.phoneNumberID,
 		ApiVersion:  cctx.apiVersion,
-		AccessToken: client.accessToken,
+		AccessToken: cctx.accessToken,
 	}
-	resp, err := qrcodes.Create(ctx, client.http, rctx, request)
+	resp, err := qrcodes.NewClient(client.sender).Create(ctx, rctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
@@ -708,7 +1089,7 @@ func (client *Client) CreateQrCode(ctx context.Context, message *qrcodes.CreateR
 }
 
 func (client *Client) ListQrCodes(ctx context.Context) (*qrcodes.ListResponse, error) {
-	cctx := client.context()
+	cctx := client.context(ctx)
 	rctx := &qrcodes.RequestContext{
 		BaseURL:     cctx.baseURL,
 		PhoneID:     cctx.phoneNumberID,
@@ -716,7 +1097,7 @@ func (client *Client) ListQrCodes(ctx context.Context) (*qrcodes.ListResponse, e
 		AccessToken: cctx.accessToken,
 	}
 
-	resp, err := qrcodes.List(ctx, client.http, rctx)
+	resp, err := qrcodes.NewClient(client.sender).List(ctx, rctx)
 	if err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
@@ -725,7 +1106,7 @@ func (client *Client) ListQrCodes(ctx context.Context) (*qrcodes.ListResponse, e
 }
 
 func (client *Client) GetQrCode(ctx context.Context, qrCodeID string) (*qrcodes.Information, error) {
-	cctx := client.context()
+	cctx := client.context(ctx)
 	rctx := &qrcodes.RequestContext{
 		BaseURL:     cctx.baseURL,
 		PhoneID:     cctx.phoneNumberID,
@@ -733,7 +1114,7 @@ func (client *Client) GetQrCode(ctx context.Context, qrCodeID string) (*qrcodes.
 		AccessToken: cctx.accessToken,
 	}
 
-	resp, err := qrcodes.Get(ctx, client.http, rctx, qrCodeID)
+	resp, err := qrcodes.NewClient(client.sender).Get(ctx, rctx, qrCodeID)
 	if err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
@@ -743,7 +1124,7 @@ func (client *Client) GetQrCode(ctx context.Context, qrCodeID string) (*qrcodes.
 
 func (client *Client) UpdateQrCode(ctx context.Context, qrCodeID string, request *qrcodes.CreateRequest,
 ) (*qrcodes.SuccessResponse, error) {
-	cctx := client.context()
+	cctx := client.context(ctx)
 	rctx := &qrcodes.RequestContext{
 		BaseURL:     cctx.baseURL,
 		PhoneID:     cctx.phoneNumberID,
@@ -751,7 +1132,7 @@ func (client *Client) UpdateQrCode(ctx context.Context, qrCodeID string, request
 		AccessToken: cctx.accessToken,
 	}
 
-	resp, err := qrcodes.Update(ctx, client.http, rctx, qrCodeID, request)
+	resp, err := qrcodes.NewClient(client.sender).Update(ctx, rctx, qrCodeID, request)
 	if err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
@@ -760,7 +1141,7 @@ func (client *Client) UpdateQrCode(ctx context.Context, qrCodeID string, request
 }
 
 func (client *Client) DeleteQrCode(ctx context.Context, qrCodeID string) (*qrcodes.SuccessResponse, error) {
-	cctx := client.context()
+	cctx := client.context(ctx)
 	rctx := &qrcodes.RequestContext{
 		BaseURL:     cctx.baseURL,
 		PhoneID:     cctx.phoneNumberID,
@@ -768,7 +1149,7 @@ func (client *Client) DeleteQrCode(ctx context.Context, qrCodeID string) (*qrcod
 		AccessToken: cctx.accessToken,
 	}
 
-	resp, err := qrcodes.Delete(ctx, client.http, rctx, qrCodeID)
+	resp, err := qrcodes.NewClient(client.sender).Delete(ctx, rctx, qrCodeID)
 	if err != nil {
 		return nil, fmt.Errorf("client: %w", err)
 	}
@@ -802,6 +1183,7 @@ type (
 
 	Paging struct {
 		Cursors *Cursors `json:"cursors,omitempty"`
+		Next    string   `json:"next,omitempty"`
 	}
 
 	Cursors struct {
@@ -839,7 +1221,7 @@ type (
 func (client *Client) RequestVerificationCode(ctx context.Context,
 	codeMethod VerificationMethod, language string,
 ) error {
-	cctx := client.context()
+	cctx := client.context(ctx)
 	reqCtx := &whttp.RequestContext{
 		Name:       "request code",
 		BaseURL:    cctx.baseURL,
@@ -857,7 +1239,7 @@ func (client *Client) RequestVerificationCode(ctx context.Context,
 		Form:    map[string]string{"code_method": string(codeMethod), "language": language},
 		Payload: nil,
 	}
-	err := whttp.Do(ctx, client.http, params, nil, client.hooks...)
+	err := client.sender.Send(ctx, params, nil)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -867,7 +1249,7 @@ func (client *Client) RequestVerificationCode(ctx context.Context,
 
 // VerifyCode should be run to verify the code retrieved by RequestVerificationCode.
 func (client *Client) VerifyCode(ctx context.Context, code string) (*StatusResponse, error) {
-	cctx := client.context()
+	cctx := client.context(ctx)
 	reqCtx := &whttp.RequestContext{
 		Name:       "verify code",
 		BaseURL:    cctx.baseURL,
@@ -884,13 +1266,12 @@ func (client *Client) VerifyCode(ctx context.Context, code string) (*StatusRespo
 		Form:    map[string]string{"code": code},
 	}
 
-	var resp StatusResponse
-	err := whttp.Do(ctx, client.http, params, &resp, client.hooks...)
+	resp, err := whttp.SendTyped[StatusResponse](ctx, client.sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	return &resp, nil
+	return resp, nil
 }
 
 // ListPhoneNumbers returns a list of phone numbers that are associated with the business account.
@@ -959,7 +1340,7 @@ func (client *Client) VerifyCode(ctx context.Context, code string) (*StatusRespo
 //	   }
 //	}
 func (client *Client) ListPhoneNumbers(ctx context.Context, filters []*FilterParams) (*PhoneNumbersList, error) {
-	cctx := client.context()
+	cctx := client.context(ctx)
 	reqCtx := &whttp.RequestContext{
 		Name:       "list phone numbers",
 		BaseURL:    cctx.baseURL,
@@ -971,7 +1352,8 @@ func (client *Client) ListPhoneNumbers(ctx context.Context, filters []*FilterPar
 	params := &whttp.Request{
 		Context: reqCtx,
 		Method:  http.MethodGet,
-		Query:   map[string]string{"access_token": cctx.accessToken},
+		Query:   map[string]string{},
+		Bearer:  cctx.accessToken,
 	}
 	if filters != nil {
 		p := filters
@@ -981,18 +1363,17 @@ func (client *Client) ListPhoneNumbers(ctx context.Context, filters []*FilterPar
 		}
 		params.Query["filtering"] = string(jsonParams)
 	}
-	var phoneNumbersList PhoneNumbersList
-	err := whttp.Do(ctx, client.http, params, &phoneNumbersList, client.hooks...)
+	phoneNumbersList, err := whttp.SendTyped[PhoneNumbersList](ctx, client.sender, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	return &phoneNumbersList, nil
+	return phoneNumbersList, nil
 }
 
 // PhoneNumberByID returns the phone number associated with the given ID.
 func (client *Client) PhoneNumberByID(ctx context.Context) (*PhoneNumber, error) {
-	cctx := client.context()
+	cctx := client.context(ctx)
 	reqCtx := &whttp.RequestContext{
 		Name:       "get phone number by id",
 		BaseURL:    cctx.baseURL,
@@ -1002,14 +1383,39 @@ func (client *Client) PhoneNumberByID(ctx context.Context) (*PhoneNumber, error)
 	request := &whttp.Request{
 		Context: reqCtx,
 		Method:  http.MethodGet,
-		Headers: map[string]string{
-			"Authorization": "Bearer " + cctx.accessToken,
-		},
+		Bearer:  cctx.accessToken,
 	}
-	var phoneNumber PhoneNumber
-	if err := whttp.Do(ctx, client.http, request, &phoneNumber, client.hooks...); err != nil {
+	phoneNumber, err := whttp.SendTyped[PhoneNumber](ctx, client.sender, request)
+	if err != nil {
 		return nil, fmt.Errorf("get phone muber by id: %w", err)
 	}
 
-	return &phoneNumber, nil
+	return phoneNumber, nil
+}
+
+// Partner returns a client for the onboarding endpoints of Tech Providers and Solution
+// Partners, authenticated with the access token of the client. businessID is the ID of the
+// partner business.
+func (client *Client) Partner(ctx context.Context, businessID string) *partner.Client {
+	cctx := client.context(ctx)
+
+	return partner.NewClient(client.sender, &partner.RequestContext{
+		BaseURL:     cctx.baseURL,
+		ApiVersion:  cctx.apiVersion,
+		AccessToken: cctx.accessToken,
+		BusinessID:  businessID,
+	})
+}
+
+// Subscriptions returns a client for the webhooks subscriptions of the app appID. The
+// subscriptions endpoints require an app access token, set it with WithOverrides.
+func (client *Client) Subscriptions(ctx context.Context, appID string) *subscriptions.Client {
+	cctx := client.context(ctx)
+
+	return subscriptions.NewClient(client.sender, &subscriptions.RequestContext{
+		BaseURL:     cctx.baseURL,
+		ApiVersion:  cctx.apiVersion,
+		AccessToken: cctx.accessToken,
+		AppID:       appID,
+	})
 }
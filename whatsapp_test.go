@@ -21,9 +21,13 @@ package whatsapp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/SeamPay/whatsapp/models"
 )
 
 func ExampleNewClient() {
@@ -41,7 +45,7 @@ func ExampleNewClient() {
 	client.SetPhoneNumberID("myexamplephoneid")
 	client.SetBusinessAccountID("businessaccountID")
 
-	cctx := client.context()
+	cctx := client.context(context.Background())
 
 	fmt.Printf("base url: %s\napi version: %s\ntoken: %s\nphone id: %s\nbusiness id: %s\n",
 		cctx.baseURL, cctx.apiVersion, cctx.accessToken, cctx.phoneNumberID, cctx.businessAccountID)
@@ -113,3 +117,26 @@ func TestMediaMaxAllowedSize(t *testing.T) {
 		})
 	}
 }
+
+func TestClientSendText(t *testing.T) {
+	t.Parallel()
+	var payload models.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithPhoneNumberID("phone_1"), WithAccessToken("token"))
+	resp, err := client.SendText(context.TODO(), "255700000000", "https://example.com", WithPreviewURL())
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if len(resp.Messages) != 1 || resp.Messages[0].ID != "wamid.1" {
+		t.Errorf("SendText() = %+v", resp)
+	}
+	if payload.To != "255700000000" || payload.Type != "text" || payload.Product != "whatsapp" ||
+		payload.Text == nil || payload.Text.Body != "https://example.com" || !payload.Text.PreviewURL {
+		t.Errorf("payload = %+v, text = %+v", payload, payload.Text)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SeamPay/whatsapp"
+)
+
+// EnvUpdateGolden is the environment variable that makes AssertGolden write the golden
+// files instead of comparing against them:
+//
+//	WHATSAPPTEST_UPDATE_GOLDEN=1 go test ./...
+const EnvUpdateGolden = "WHATSAPPTEST_UPDATE_GOLDEN"
+
+var ErrNoPayload = errors.New("no request payload was sent")
+
+// CanonicalJSON re-encodes data with sorted object keys and two spaces indentation, so that
+// payloads that only differ in the order of their keys or in spacing compare equal.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	var v any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("canonical json: %w", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, fmt.Errorf("canonical json: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// RenderPayload calls send with a client connected to a new Server and returns the
+// canonical JSON of the last request body the client sent.
+func RenderPayload(ctx context.Context, send func(ctx context.Context, client *whatsapp.Client) error) ([]byte, error) {
+	server := NewServer()
+	defer server.Close()
+
+	if err := send(ctx, whatsapp.NewClient(server.ClientOptions()...)); err != nil {
+		return nil, fmt.Errorf("render payload: %w", err)
+	}
+
+	requests := server.Requests()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if len(requests[i].Body) > 0 {
+			return CanonicalJSON(requests[i].Body)
+		}
+	}
+
+	return nil, fmt.Errorf("render payload: %w", ErrNoPayload)
+}
+
+// AssertGolden compares got with the golden file testdata/name.golden and reports a
+// line diff when they differ. Both are compared as canonical JSON when they are valid JSON.
+// When EnvUpdateGolden is set the golden file is written instead.
+func AssertGolden(t testing.TB, name string, got []byte) {
+	t.Helper()
+
+	if canonical, err := CanonicalJSON(got); err == nil {
+		got = canonical
+	}
+
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv(EnvUpdateGolden) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { //nolint:gomnd
+			t.Fatalf("update golden file: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil { //nolint:gomnd,gosec
+			t.Fatalf("update golden file: %v", err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (set %s=1 to create it): %v", EnvUpdateGolden, err)
+	}
+	if canonical, err := CanonicalJSON(want); err == nil {
+		want = canonical
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Errorf("%s mismatch (-want +got):\n%s", path, Diff(want, got))
+	}
+}
+
+// AssertGoldenPayload renders the payload sent by send with RenderPayload and compares it
+// with the golden file testdata/name.golden using AssertGolden.
+func AssertGoldenPayload(t testing.TB, name string, send func(ctx context.Context, client *whatsapp.Client) error) {
+	t.Helper()
+
+	got, err := RenderPayload(context.Background(), send)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	AssertGolden(t, name, got)
+}
+
+// Diff returns a line diff of want and got, lines only in want are prefixed with "-" and
+// lines only in got with "+".
+func Diff(want, got []byte) string {
+	a := strings.Split(strings.TrimSuffix(string(want), "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var buf strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			buf.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			buf.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			buf.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapptest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SeamPay/whatsapp"
+	"github.com/SeamPay/whatsapp/models"
+	"github.com/SeamPay/whatsapp/whatsapptest"
+)
+
+const recipient = "255700000000"
+
+func TestGoldenPayloads(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		send func(ctx context.Context, client *whatsapp.Client) error
+	}{
+		{
+			name: "text",
+			send: func(ctx context.Context, client *whatsapp.Client) error {
+				_, err := client.SendText(ctx, recipient, "Hello, \"world\" <3", whatsapp.WithPreviewURL())
+
+				return err
+			},
+		},
+		{
+			name: "location",
+			send: func(ctx context.Context, client *whatsapp.Client) error {
+				_, err := client.SendLocationMessage(ctx, recipient, &models.Location{
+					Name: "Kariakoo", Address: "Dar es Salaam", Latitude: -6.8161, Longitude: 39.2763,
+				})
+
+				return err
+			},
+		},
+		{
+			name: "reaction",
+			send: func(ctx context.Context, client *whatsapp.Client) error {
+				_, err := client.React(ctx, recipient, &whatsapp.ReactMessage{MessageID: "wamid.1", Emoji: "\U0001F600"})
+
+				return err
+			},
+		},
+		{
+			name: "media",
+			send: func(ctx context.Context, client *whatsapp.Client) error {
+				_, err := client.SendMedia(ctx, recipient, &whatsapp.MediaMessage{
+					Type: whatsapp.MediaTypeDocument, MediaLink: "https://example.com/invoice.pdf",
+					Caption: "Your \"invoice\"", Filename: "invoice.pdf",
+				}, nil)
+
+				return err
+			},
+		},
+		{
+			name: "contacts",
+			send: func(ctx context.Context, client *whatsapp.Client) error {
+				_, err := client.SendContacts(ctx, recipient, []*models.Contact{{
+					Name: &models.Name{FormattedName: "Pius Alfred", FirstName: "Pius", LastName: "Alfred"},
+				}})
+
+				return err
+			},
+		},
+		{
+			name: "template",
+			send: func(ctx context.Context, client *whatsapp.Client) error {
+				_, err := client.SendTemplate(ctx, recipient, &whatsapp.Template{
+					LanguageCode: "en_US",
+					Name:         "order_update",
+					Components: []*models.TemplateComponent{{
+						Type:       "body",
+						Parameters: []*models.TemplateParameter{{Type: "text", Text: "#1234"}},
+					}},
+				})
+
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			whatsapptest.AssertGoldenPayload(t, tt.name, tt.send)
+		})
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	t.Parallel()
+	a, err := whatsapptest.CanonicalJSON([]byte(`{"b":1,"a":{"d":2.50,"c":"x"}}`))
+	if err != nil {
+		t.Fatalf("CanonicalJSON() error = %v", err)
+	}
+	b, err := whatsapptest.CanonicalJSON([]byte(`{ "a": { "c": "x", "d": 2.50 }, "b": 1 }`))
+	if err != nil {
+		t.Fatalf("CanonicalJSON() error = %v", err)
+	}
+	if string(a) != string(b) {
+		t.Errorf("CanonicalJSON() = %s and %s, want equal", a, b)
+	}
+	if _, err := whatsapptest.CanonicalJSON([]byte(`{"a":`)); err == nil {
+		t.Error("CanonicalJSON() of invalid json, want an error")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	got := whatsapptest.Diff([]byte("a\nb\nc\n"), []byte("a\nx\nc\n"))
+	want := "  a\n- b\n+ x\n  c\n"
+	if got != want {
+		t.Errorf("Diff() = %q, want %q", got, want)
+	}
+}
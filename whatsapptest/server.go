@@ -0,0 +1,543 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+// Package whatsapptest provides a mock of the WhatsApp Cloud API for tests, built on
+// httptest. The Server emulates the messages, media, message templates and business
+// profile endpoints with realistic bodies, captures the requests it receives and can be
+// told to fail or to slow down:
+//
+//	server := whatsapptest.NewServer()
+//	defer server.Close()
+//	client := whatsapp.NewClient(server.ClientOptions()...)
+//
+//	server.FailNext("messages", whatsapptest.FailurePairRateLimit)
+//	_, err := client.SendText(ctx, "255700000000", "hello") // fails with code 131056
+//	_, err = client.SendText(ctx, "255700000000", "hello")  // succeeds
+//
+//	if messages := server.Messages(); len(messages) != 1 {
+//		t.Errorf("sent %d messages, want 1", len(messages))
+//	}
+package whatsapptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/models"
+)
+
+// Identifiers and credentials the server is set up with, see Server.ClientOptions.
+const (
+	AccessToken       = "whatsapptest-token"
+	PhoneNumberID     = "106540352242922"
+	BusinessAccountID = "102290129340398"
+)
+
+// Endpoints, used to select the requests that FailNext applies to.
+const (
+	EndpointMessages          = "messages"
+	EndpointMarketingMessages = "marketing_messages"
+	EndpointMedia             = "media"
+	EndpointTemplates         = "message_templates"
+	EndpointProfile           = "whatsapp_business_profile"
+)
+
+// Failures commonly returned by the API.
+var (
+	FailureInvalidToken = &Failure{ //nolint:gochecknoglobals
+		Status: http.StatusUnauthorized, Code: werrors.CodeAccessTokenInvalid, Subcode: 463, //nolint:gomnd
+		Type: "OAuthException", Message: "Error validating access token: Session has expired.",
+	}
+	FailurePairRateLimit = &Failure{ //nolint:gochecknoglobals
+		Status: http.StatusBadRequest, Code: werrors.CodePairRateLimit, Type: "OAuthException",
+		Message: "(#131056) (Business Account, Consumer Account) pair rate limit hit",
+	}
+	FailureReEngagement = &Failure{ //nolint:gochecknoglobals
+		Status: http.StatusBadRequest, Code: werrors.CodeReEngagement, Type: "OAuthException",
+		Message: "Re-engagement message",
+	}
+	FailureTemplateParams = &Failure{ //nolint:gochecknoglobals
+		Status: http.StatusBadRequest, Code: werrors.CodeTemplateParamMismatch, Type: "OAuthException",
+		Message: "(#132000) Number of parameters does not match the expected number of params",
+	}
+	FailureServerError = &Failure{ //nolint:gochecknoglobals
+		Status: http.StatusInternalServerError, Code: 1, Type: "OAuthException", //nolint:gomnd
+		Message: "An unknown error occurred",
+	}
+)
+
+type (
+	// Failure is an error response of the API.
+	Failure struct {
+		Status  int
+		Code    int
+		Subcode int
+		Type    string
+		Message string
+	}
+
+	// Request is a request received by the server. Endpoint is the last segment of the
+	// path, for example "messages" or a media ID.
+	Request struct {
+		Method   string
+		Path     string
+		Endpoint string
+		Query    url.Values
+		Header   http.Header
+		Body     []byte
+		Received time.Time
+	}
+
+	// Template is a message template served by the templates endpoint.
+	Template struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Language string `json:"language"`
+		Status   string `json:"status"`
+		Category string `json:"category"`
+	}
+
+	// BusinessProfile is the profile served by the business profile endpoint.
+	BusinessProfile struct {
+		About             string   `json:"about,omitempty"`
+		Address           string   `json:"address,omitempty"`
+		Description       string   `json:"description,omitempty"`
+		Email             string   `json:"email,omitempty"`
+		MessagingProduct  string   `json:"messaging_product"`
+		ProfilePictureURL string   `json:"profile_picture_url,omitempty"`
+		Vertical          string   `json:"vertical,omitempty"`
+		Websites          []string `json:"websites,omitempty"`
+	}
+
+	media struct {
+		mimeType string
+		data     []byte
+	}
+
+	// Server is a mock Cloud API server. It is safe for concurrent use.
+	Server struct {
+		server *httptest.Server
+
+		mu        sync.Mutex
+		seq       int
+		latency   time.Duration
+		failures  map[string][]*Failure
+		requests  []*Request
+		media     map[string]*media
+		templates []*Template
+		profile   *BusinessProfile
+	}
+)
+
+// JSON decodes the body of the request into v.
+func (r *Request) JSON(v any) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("decode %s %s: %w", r.Method, r.Path, err)
+	}
+
+	return nil
+}
+
+// NewServer starts a server, it must be closed with Close.
+func NewServer() *Server {
+	server := &Server{
+		failures: make(map[string][]*Failure),
+		media:    make(map[string]*media),
+		templates: []*Template{{
+			ID: "1", Name: "hello_world", Language: "en_US", Status: "APPROVED", Category: "UTILITY",
+		}},
+		profile: &BusinessProfile{MessagingProduct: "whatsapp", About: "Hello from whatsapptest"},
+	}
+	server.server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+
+	return server
+}
+
+// URL returns the base URL of the server.
+func (server *Server) URL() string {
+	return server.server.URL
+}
+
+// Close shuts the server down.
+func (server *Server) Close() {
+	server.server.Close()
+}
+
+// ClientOptions returns the options that point a client at the server.
+func (server *Server) ClientOptions() []whatsapp.ClientOption {
+	return []whatsapp.ClientOption{
+		whatsapp.WithBaseURL(server.URL()),
+		whatsapp.WithAccessToken(AccessToken),
+		whatsapp.WithPhoneNumberID(PhoneNumberID),
+		whatsapp.WithBusinessAccountID(BusinessAccountID),
+	}
+}
+
+// SetLatency delays every response by latency.
+func (server *Server) SetLatency(latency time.Duration) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.latency = latency
+}
+
+// FailNext makes the next request to endpoint fail with failure. Calls are queued, each
+// failure is used once.
+func (server *Server) FailNext(endpoint string, failure *Failure) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.failures[endpoint] = append(server.failures[endpoint], failure)
+}
+
+// SetTemplates replaces the templates served by the templates endpoint.
+func (server *Server) SetTemplates(templates ...*Template) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.templates = templates
+}
+
+// SetProfile replaces the business profile.
+func (server *Server) SetProfile(profile *BusinessProfile) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.profile = profile
+}
+
+// Requests returns the requests received so far, in order.
+func (server *Server) Requests() []*Request {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	return append([]*Request(nil), server.requests...)
+}
+
+// RequestsTo returns the requests received by endpoint, in order.
+func (server *Server) RequestsTo(endpoint string) []*Request {
+	var requests []*Request
+	for _, request := range server.Requests() {
+		if request.Endpoint == endpoint {
+			requests = append(requests, request)
+		}
+	}
+
+	return requests
+}
+
+// Messages returns the messages accepted by the messages endpoint, in order. Read receipts
+// are not messages and are not returned.
+func (server *Server) Messages() []*models.Message {
+	var messages []*models.Message
+	for _, request := range server.RequestsTo(EndpointMessages) {
+		var message models.Message
+		if request.Method == http.MethodPost && request.JSON(&message) == nil && message.Type != "" {
+			messages = append(messages, &message)
+		}
+	}
+
+	return messages
+}
+
+// Reset forgets the requests received and the pending failures.
+func (server *Server) Reset() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.requests = nil
+	server.failures = make(map[string][]*Failure)
+}
+
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) > 1 && strings.HasPrefix(segments[0], "v") {
+		segments = segments[1:]
+	}
+	request := &Request{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Endpoint: segments[len(segments)-1],
+		Query:    r.URL.Query(),
+		Header:   r.Header.Clone(),
+		Body:     body,
+		Received: time.Now(),
+	}
+
+	server.mu.Lock()
+	server.requests = append(server.requests, request)
+	latency := server.latency
+	var failure *Failure
+	if queue := server.failures[request.Endpoint]; len(queue) > 0 {
+		failure, server.failures[request.Endpoint] = queue[0], queue[1:]
+	}
+	server.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if failure != nil {
+		writeFailure(w, failure)
+
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+AccessToken {
+		writeFailure(w, FailureInvalidToken)
+
+		return
+	}
+
+	server.route(w, r, request, segments)
+}
+
+//nolint:cyclop
+func (server *Server) route(w http.ResponseWriter, r *http.Request, request *Request, segments []string) {
+	switch {
+	case len(segments) == 2 && segments[0] == "download" && r.Method == http.MethodGet:
+		server.download(w, segments[1])
+	case len(segments) == 2 && request.Endpoint == EndpointMessages && r.Method == http.MethodPost:
+		server.message(w, request)
+	case len(segments) == 2 && request.Endpoint == EndpointMarketingMessages && r.Method == http.MethodPost:
+		server.message(w, request)
+	case len(segments) == 2 && request.Endpoint == EndpointMedia && r.Method == http.MethodPost:
+		server.upload(w, r, request)
+	case len(segments) == 2 && request.Endpoint == EndpointTemplates:
+		server.template(w, r, request)
+	case len(segments) == 2 && request.Endpoint == EndpointProfile:
+		server.businessProfile(w, r, request)
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		server.mediaInformation(w, segments[0])
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		server.deleteMedia(w, segments[0])
+	default:
+		writeFailure(w, &Failure{
+			Status: http.StatusBadRequest, Code: 100, Type: "GraphMethodException", //nolint:gomnd
+			Message: fmt.Sprintf("Unsupported %s request to %s", r.Method, r.URL.Path),
+		})
+	}
+}
+
+func (server *Server) next() string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.seq++
+
+	return strconv.Itoa(server.seq)
+}
+
+func (server *Server) message(w http.ResponseWriter, request *Request) {
+	var message struct {
+		models.Message
+		Status    string `json:"status"`
+		MessageID string `json:"message_id"`
+	}
+	if err := request.JSON(&message); err != nil {
+		writeInvalidParameter(w, "Invalid JSON payload")
+
+		return
+	}
+	switch {
+	case message.Status != "" && message.MessageID == "":
+		writeInvalidParameter(w, "The parameter message_id is required.")
+	case message.Status != "":
+		writeJSON(w, http.StatusOK, &whatsapp.StatusResponse{Success: true})
+	case message.Product != "whatsapp":
+		writeInvalidParameter(w, "The parameter messaging_product is required.")
+	case message.To == "":
+		writeInvalidParameter(w, "The parameter to is required.")
+	case message.Type == "":
+		writeInvalidParameter(w, "The parameter type is required.")
+	default:
+		var status string
+		if request.Endpoint == EndpointMarketingMessages {
+			status = whatsapp.MarketingMessageAccepted
+		}
+		writeJSON(w, http.StatusOK, &whatsapp.ResponseMessage{
+			Product:  "whatsapp",
+			Contacts: []*whatsapp.ResponseContact{{Input: message.To, WhatsappID: strings.TrimPrefix(message.To, "+")}},
+			Messages: []*whatsapp.MessageID{{ID: "wamid.whatsapptest." + server.next(), MessageStatus: status}},
+		})
+	}
+}
+
+func (server *Server) upload(w http.ResponseWriter, r *http.Request, request *Request) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		writeInvalidParameter(w, "The request must be multipart/form-data.")
+
+		return
+	}
+	reader := multipart.NewReader(strings.NewReader(string(request.Body)), params["boundary"])
+	uploaded := &media{}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		if part.FormName() == "file" {
+			uploaded.mimeType = part.Header.Get("Content-Type")
+			uploaded.data, _ = io.ReadAll(part)
+		}
+	}
+	if uploaded.data == nil {
+		writeInvalidParameter(w, "The parameter file is required.")
+
+		return
+	}
+
+	id := "media.whatsapptest." + server.next()
+	server.mu.Lock()
+	server.media[id] = uploaded
+	server.mu.Unlock()
+	writeJSON(w, http.StatusOK, &whatsapp.UploadMediaResponse{ID: id})
+}
+
+func (server *Server) lookupMedia(id string) (*media, bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	m, ok := server.media[id]
+
+	return m, ok
+}
+
+func (server *Server) mediaInformation(w http.ResponseWriter, id string) {
+	m, ok := server.lookupMedia(id)
+	if !ok {
+		writeFailure(w, mediaNotFound(id))
+
+		return
+	}
+	writeJSON(w, http.StatusOK, &whatsapp.MediaInformation{
+		MessagingProduct: "whatsapp",
+		URL:              server.URL() + "/download/" + id,
+		MimeType:         m.mimeType,
+		FileSize:         int64(len(m.data)),
+		ID:               id,
+	})
+}
+
+func (server *Server) download(w http.ResponseWriter, id string) {
+	m, ok := server.lookupMedia(id)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+	w.Header().Set("Content-Type", m.mimeType)
+	_, _ = w.Write(m.data)
+}
+
+func (server *Server) deleteMedia(w http.ResponseWriter, id string) {
+	server.mu.Lock()
+	_, ok := server.media[id]
+	delete(server.media, id)
+	server.mu.Unlock()
+	if !ok {
+		writeFailure(w, mediaNotFound(id))
+
+		return
+	}
+	writeJSON(w, http.StatusOK, &whatsapp.DeleteMediaResponse{Success: true})
+}
+
+func (server *Server) template(w http.ResponseWriter, r *http.Request, request *Request) {
+	if r.Method == http.MethodGet {
+		server.mu.Lock()
+		templates := append([]*Template(nil), server.templates...)
+		server.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]any{"data": templates})
+
+		return
+	}
+
+	var template Template
+	if err := request.JSON(&template); err != nil || template.Name == "" || template.Category == "" {
+		writeInvalidParameter(w, "The parameters name, language and category are required.")
+
+		return
+	}
+	template.ID = server.next()
+	template.Status = "PENDING"
+	server.mu.Lock()
+	server.templates = append(server.templates, &template)
+	server.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{"id": template.ID, "status": template.Status,
+		"category": template.Category})
+}
+
+func (server *Server) businessProfile(w http.ResponseWriter, r *http.Request, request *Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]any{"data": []*BusinessProfile{server.profile}})
+
+		return
+	}
+
+	profile := *server.profile
+	if err := request.JSON(&profile); err != nil {
+		writeInvalidParameter(w, "Invalid JSON payload")
+
+		return
+	}
+	server.profile = &profile
+	writeJSON(w, http.StatusOK, &whatsapp.StatusResponse{Success: true})
+}
+
+func mediaNotFound(id string) *Failure {
+	return &Failure{
+		Status: http.StatusBadRequest, Code: 100, Subcode: 33, Type: "GraphMethodException", //nolint:gomnd
+		Message: fmt.Sprintf("Unsupported get request. Object with ID '%s' does not exist", id),
+	}
+}
+
+func writeInvalidParameter(w http.ResponseWriter, message string) {
+	writeFailure(w, &Failure{
+		Status: http.StatusBadRequest, Code: 100, Type: "OAuthException", //nolint:gomnd
+		Message: "(#100) " + message,
+	})
+}
+
+func writeFailure(w http.ResponseWriter, failure *Failure) {
+	writeJSON(w, failure.Status, map[string]any{"error": &werrors.Error{
+		Message:   failure.Message,
+		Type:      failure.Type,
+		Code:      failure.Code,
+		Subcode:   failure.Subcode,
+		FBTraceID: "whatsapptest",
+	}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapptest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp"
+	werrors "github.com/SeamPay/whatsapp/errors"
+	"github.com/SeamPay/whatsapp/whatsapptest"
+)
+
+func TestServer_Messages(t *testing.T) {
+	t.Parallel()
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	client := whatsapp.NewClient(server.ClientOptions()...)
+	ctx := context.Background()
+
+	server.FailNext(whatsapptest.EndpointMessages, whatsapptest.FailurePairRateLimit)
+	_, err := client.SendText(ctx, "255700000000", "hello")
+	var apiErr *werrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != werrors.CodePairRateLimit {
+		t.Fatalf("SendText() error = %v, want code %d", err, werrors.CodePairRateLimit)
+	}
+
+	response, err := client.SendText(ctx, "255700000000", "hello")
+	if err != nil {
+		t.Fatalf("SendText() error = %v", err)
+	}
+	if len(response.Messages) != 1 || !strings.HasPrefix(response.Messages[0].ID, "wamid.") {
+		t.Errorf("SendText() response = %+v, want a message ID", response)
+	}
+	if _, err := client.MarkMessageRead(ctx, response.Messages[0].ID); err != nil {
+		t.Fatalf("MarkMessageRead() error = %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("Messages() = %d messages, want 2", len(messages))
+	}
+	if messages[1].To != "255700000000" || messages[1].Text == nil || messages[1].Text.Body != "hello" {
+		t.Errorf("Messages()[1] = %+v, want a text to 255700000000", messages[1])
+	}
+	if got := len(server.RequestsTo(whatsapptest.EndpointMessages)); got != 3 {
+		t.Errorf("RequestsTo(messages) = %d requests, want 3", got)
+	}
+	request := server.Requests()[0]
+	if got := request.Header.Get("Authorization"); got != "Bearer "+whatsapptest.AccessToken {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	server.Reset()
+	if got := len(server.Requests()); got != 0 {
+		t.Errorf("Requests() after Reset() = %d requests, want 0", got)
+	}
+}
+
+func TestServer_Media(t *testing.T) {
+	t.Parallel()
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	client := whatsapp.NewClient(server.ClientOptions()...)
+	ctx := context.Background()
+
+	uploaded, err := client.UploadMedia(ctx, whatsapp.MediaTypeImage, "cat.png", strings.NewReader("meow"))
+	if err != nil {
+		t.Fatalf("UploadMedia() error = %v", err)
+	}
+	downloaded, err := client.DownloadMedia(ctx, uploaded.ID, 0)
+	if err != nil {
+		t.Fatalf("DownloadMedia() error = %v", err)
+	}
+	if data, _ := io.ReadAll(downloaded.Body); string(data) != "meow" {
+		t.Errorf("DownloadMedia() body = %q, want %q", data, "meow")
+	}
+	if _, err := client.DeleteMedia(ctx, uploaded.ID); err != nil {
+		t.Fatalf("DeleteMedia() error = %v", err)
+	}
+	if _, err := client.GetMediaInformation(ctx, uploaded.ID); err == nil {
+		t.Error("GetMediaInformation() of deleted media, want an error")
+	}
+}
+
+func TestServer_Unauthorized(t *testing.T) {
+	t.Parallel()
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	client := whatsapp.NewClient(append(server.ClientOptions(), whatsapp.WithAccessToken("expired"))...)
+
+	_, err := client.SendText(context.Background(), "255700000000", "hello")
+	var apiErr *werrors.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != werrors.CodeAccessTokenInvalid {
+		t.Fatalf("SendText() error = %v, want code %d", err, werrors.CodeAccessTokenInvalid)
+	}
+}
+
+func TestServer_Latency(t *testing.T) {
+	t.Parallel()
+	server := whatsapptest.NewServer()
+	defer server.Close()
+	server.SetLatency(time.Second)
+	client := whatsapp.NewClient(server.ClientOptions()...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.SendText(ctx, "255700000000", "hello"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SendText() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestServer_Templates(t *testing.T) {
+	t.Parallel()
+	server := whatsapptest.NewServer()
+	defer server.Close()
+
+	url := server.URL() + "/v16.0/" + whatsapptest.BusinessAccountID + "/message_templates"
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, url,
+		strings.NewReader(`{"name":"order_update","language":"en_US","category":"UTILITY"}`))
+	request.Header.Set("Authorization", "Bearer "+whatsapptest.AccessToken)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("create template: status %d", response.StatusCode)
+	}
+
+	var created struct {
+		Name string `json:"name"`
+	}
+	if err := server.RequestsTo(whatsapptest.EndpointTemplates)[0].JSON(&created); err != nil {
+		t.Fatalf("JSON() error = %v", err)
+	}
+	if created.Name != "order_update" {
+		t.Errorf("captured template name = %q, want %q", created.Name, "order_update")
+	}
+
+	request, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	request.Header.Set("Authorization", "Bearer "+whatsapptest.AccessToken)
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("list templates: %v", err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	if !strings.Contains(string(body), `"order_update"`) || !strings.Contains(string(body), `"PENDING"`) {
+		t.Errorf("list templates = %s, want the pending order_update template", body)
+	}
+}
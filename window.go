@@ -0,0 +1,193 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+// CustomerServiceWindow is how long after the last message of a customer free-form
+// messages can be sent to them. Outside the window only template messages are delivered.
+const CustomerServiceWindow = 24 * time.Hour
+
+type (
+	// WindowStore stores the time of the last message received from each customer.
+	// Implementations must be safe for concurrent use, a shared store lets several
+	// instances that receive webhooks and send messages agree on the windows.
+	WindowStore interface {
+		// LastMessage returns the time of the last message received from waID. found is
+		// false if no message was received from waID.
+		LastMessage(ctx context.Context, waID string) (at time.Time, found bool, err error)
+
+		// SetLastMessage stores at as the time of the last message received from waID.
+		SetLastMessage(ctx context.Context, waID string, at time.Time) error
+	}
+
+	// WindowTrackerConfig configures a WindowTracker. Store defaults to a
+	// MemoryWindowStore, Clock to the system clock and Window to CustomerServiceWindow.
+	WindowTrackerConfig struct {
+		Store  WindowStore
+		Clock  clock.Clock
+		Window time.Duration
+	}
+
+	// WindowTracker tracks the customer service window of each customer from the messages
+	// they send, to tell whether a free-form message can be sent or a template is required.
+	// Feed it from the webhooks with its OnMessageReceived hook:
+	//
+	//	tracker := whatsapp.NewWindowTracker(nil)
+	//	listener.OnMessageReceived(tracker.OnMessageReceived)
+	//
+	//	open, err := tracker.IsWindowOpen(ctx, waID)
+	//
+	// The messages of a customer are recorded one at a time by a tracker, trackers of several
+	// processes sharing a store do not coordinate.
+	WindowTracker struct {
+		store  WindowStore
+		clock  clock.Clock
+		window time.Duration
+		locks  keyedMutex
+	}
+
+	// MemoryWindowStore is an in-memory WindowStore.
+	MemoryWindowStore struct {
+		mu      sync.Mutex
+		entries map[string]time.Time
+	}
+)
+
+// NewWindowTracker returns a WindowTracker configured with config, which may be nil.
+func NewWindowTracker(config *WindowTrackerConfig) *WindowTracker {
+	if config == nil {
+		config = &WindowTrackerConfig{}
+	}
+	tracker := &WindowTracker{
+		store:  config.Store,
+		clock:  clock.OrSystem(config.Clock),
+		window: config.Window,
+	}
+	if tracker.store == nil {
+		tracker.store = NewMemoryWindowStore()
+	}
+	if tracker.window <= 0 {
+		tracker.window = CustomerServiceWindow
+	}
+
+	return tracker
+}
+
+// Record records a message received from waID at the given time. Messages older than the
+// last recorded one are ignored, so that webhooks delivered out of order do not shorten the
+// window.
+func (tracker *WindowTracker) Record(ctx context.Context, waID string, at time.Time) error {
+	unlock := tracker.locks.Lock(waID)
+	defer unlock()
+
+	last, found, err := tracker.store.LastMessage(ctx, waID)
+	if err != nil {
+		return fmt.Errorf("window tracker: %w", err)
+	}
+	if found && !at.After(last) {
+		return nil
+	}
+	if err := tracker.store.SetLastMessage(ctx, waID, at); err != nil {
+		return fmt.Errorf("window tracker: %w", err)
+	}
+
+	return nil
+}
+
+// OnMessageReceived is a webhooks.OnMessageReceivedHook that records the messages sent by
+// customers. System messages, like a change of phone number, do not open a window.
+func (tracker *WindowTracker) OnMessageReceived(ctx context.Context, _ *webhooks.NotificationContext,
+	message *webhooks.Message,
+) error {
+	if message == nil || message.From == "" || webhooks.ParseMessageType(message.Type) == webhooks.SystemMessageType {
+		return nil
+	}
+
+	return tracker.Record(ctx, message.From, receivedAt(message, tracker.clock))
+}
+
+// receivedAt returns the time at which message was sent, or the current time when the
+// timestamp of the message is invalid.
+func receivedAt(message *webhooks.Message, clk clock.Clock) time.Time {
+	if seconds, err := strconv.ParseInt(message.Timestamp, 10, 64); err == nil {
+		return time.Unix(seconds, 0)
+	}
+
+	return clk.Now()
+}
+
+// TimeRemaining returns how long the customer service window of waID stays open, it is
+// zero when the window is closed or was never opened.
+func (tracker *WindowTracker) TimeRemaining(ctx context.Context, waID string) (time.Duration, error) {
+	last, found, err := tracker.store.LastMessage(ctx, waID)
+	if err != nil {
+		return 0, fmt.Errorf("window tracker: %w", err)
+	}
+	if !found {
+		return 0, nil
+	}
+	remaining := last.Add(tracker.window).Sub(tracker.clock.Now())
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// IsWindowOpen reports whether a free-form message can be sent to waID.
+func (tracker *WindowTracker) IsWindowOpen(ctx context.Context, waID string) (bool, error) {
+	remaining, err := tracker.TimeRemaining(ctx, waID)
+	if err != nil {
+		return false, err
+	}
+
+	return remaining > 0, nil
+}
+
+// NewMemoryWindowStore returns an empty MemoryWindowStore.
+func NewMemoryWindowStore() *MemoryWindowStore {
+	return &MemoryWindowStore{entries: make(map[string]time.Time)}
+}
+
+func (store *MemoryWindowStore) LastMessage(_ context.Context, waID string) (time.Time, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	at, ok := store.entries[waID]
+
+	return at, ok, nil
+}
+
+func (store *MemoryWindowStore) SetLastMessage(_ context.Context, waID string, at time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.entries[waID] = at
+
+	return nil
+}
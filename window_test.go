@@ -0,0 +1,118 @@
+/*
+ * Copyright 2023 Pius Alfred <me.pius1102@gmail.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy of this software
+ * and associated documentation files (the “Software”), to deal in the Software without restriction,
+ * including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense,
+ * and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so,
+ * subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all copies or substantial
+ * portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED “AS IS”, WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT
+ * LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+ * IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY,
+ * WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE
+ * SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+ */
+
+package whatsapp
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SeamPay/whatsapp/clock"
+	"github.com/SeamPay/whatsapp/webhooks"
+)
+
+func TestWindowTracker(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now)
+	tracker := NewWindowTracker(&WindowTrackerConfig{Clock: clk})
+
+	if open, err := tracker.IsWindowOpen(ctx, "255700000000"); err != nil || open {
+		t.Fatalf("IsWindowOpen() = %v, %v, want false before any message", open, err)
+	}
+
+	message := &webhooks.Message{
+		From:      "255700000000",
+		Type:      "text",
+		Timestamp: strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+	}
+	if err := tracker.OnMessageReceived(ctx, nil, message); err != nil {
+		t.Fatalf("OnMessageReceived() error = %v", err)
+	}
+	if remaining, err := tracker.TimeRemaining(ctx, "255700000000"); err != nil || remaining != 23*time.Hour {
+		t.Fatalf("TimeRemaining() = %v, %v, want 23h", remaining, err)
+	}
+
+	// an older message delivered late does not shorten the window
+	late := &webhooks.Message{
+		From:      "255700000000",
+		Type:      "text",
+		Timestamp: strconv.FormatInt(now.Add(-5*time.Hour).Unix(), 10),
+	}
+	if err := tracker.OnMessageReceived(ctx, nil, late); err != nil {
+		t.Fatalf("OnMessageReceived() error = %v", err)
+	}
+	if remaining, _ := tracker.TimeRemaining(ctx, "255700000000"); remaining != 23*time.Hour {
+		t.Errorf("TimeRemaining() after a late message = %v, want 23h", remaining)
+	}
+
+	// system messages do not open a window
+	system := &webhooks.Message{From: "255711111111", Type: "system"}
+	if err := tracker.OnMessageReceived(ctx, nil, system); err != nil {
+		t.Fatalf("OnMessageReceived() error = %v", err)
+	}
+	if open, _ := tracker.IsWindowOpen(ctx, "255711111111"); open {
+		t.Error("IsWindowOpen() after a system message = true, want false")
+	}
+
+	clk.Advance(23 * time.Hour)
+	if open, _ := tracker.IsWindowOpen(ctx, "255700000000"); open {
+		t.Error("IsWindowOpen() after 24h = true, want false")
+	}
+	if remaining, _ := tracker.TimeRemaining(ctx, "255700000000"); remaining != 0 {
+		t.Errorf("TimeRemaining() after 24h = %v, want 0", remaining)
+	}
+}
+
+// slowWindowStore widens the window between reading and storing the last message.
+type slowWindowStore struct {
+	*MemoryWindowStore
+}
+
+func (store slowWindowStore) LastMessage(ctx context.Context, waID string) (time.Time, bool, error) {
+	at, found, err := store.MemoryWindowStore.LastMessage(ctx, waID)
+	time.Sleep(10 * time.Millisecond)
+
+	return at, found, err
+}
+
+func TestWindowTrackerConcurrentRecord(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	latest := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewWindowTracker(&WindowTrackerConfig{Store: slowWindowStore{NewMemoryWindowStore()}})
+
+	done := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		at := latest.Add(-time.Duration(i) * time.Minute)
+		go func() { done <- tracker.Record(ctx, "255700000000", at) }()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	if at, _, _ := tracker.store.LastMessage(ctx, "255700000000"); !at.Equal(latest) {
+		t.Errorf("last message = %v, want %v", at, latest)
+	}
+}